@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+)
+
+// Example workload: GetItem of account metadata by primary key against
+// the configured DynamoDB. Copy this file as a starting point for your own key design.
+
+var (
+	balanceClient      *dynamodb.Client
+	balanceAccountKeys []string
+)
+
+func init() {
+	w := benchmark.Register("dynamodb-balance-lookup", setupDynamoBalanceLookup, getAccountItem, nil)
+	w.Database = "DynamoDB"
+	w.Operations = 5000
+	w.Concurrency = 20
+}
+
+func setupDynamoBalanceLookup(ctx context.Context) error {
+	var err error
+	balanceClient, err = connection.NewDynamoDBClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	output, err := balanceClient.Scan(ctx, &dynamodb.ScanInput{
+		TableName:        aws.String(connection.DynamoDBTable),
+		FilterExpression: aws.String("#t = :type"),
+		ExpressionAttributeNames: map[string]string{
+			"#t": "Type",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":type": &types.AttributeValueMemberS{Value: "Account"},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, item := range output.Items {
+		if id, ok := item["ID"].(*types.AttributeValueMemberS); ok && len(balanceAccountKeys) < 100 {
+			balanceAccountKeys = append(balanceAccountKeys, fmt.Sprintf("ACCOUNT#%s", id.Value))
+		}
+	}
+	if len(balanceAccountKeys) == 0 {
+		return errors.New("no accounts found; run `make seed-dynamodb` first")
+	}
+	return nil
+}
+
+func getAccountItem(ctx context.Context, worker, iteration int) error {
+	output, err := balanceClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(connection.DynamoDBTable),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: balanceAccountKeys[benchmark.Rand().Intn(len(balanceAccountKeys))]},
+			"SK": &types.AttributeValueMemberS{Value: "METADATA"},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if !benchmark.Expect(ctx, "account item returned", output.Item != nil) {
+		return nil
+	}
+	_, hasBalance := output.Item["Balance"].(*types.AttributeValueMemberN)
+	benchmark.Expect(ctx, "balance is a number", hasBalance)
+	return nil
+}
@@ -0,0 +1,74 @@
+// Command custom runs user-defined workloads registered with the benchmark
+// package. Add a file to this directory that calls benchmark.Register from an
+// init function and it is picked up automatically.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+)
+
+func main() {
+	names := flag.String("workload", "", "comma-separated workloads to run (default: all)")
+	operations := flag.Int("ops", 0, "operations per workload (default: the workload's own)")
+	concurrency := flag.Int("concurrency", 0, "concurrent workers (default: the workload's own)")
+	opTimeout := flag.Duration("op-timeout", 0, "deadline for each operation (default none)")
+	var retry benchmark.RetryPolicy
+	flag.IntVar(&retry.MaxRetries, "retries", 0, "retry operations failing with network errors up to this many times (default none)")
+	flag.DurationVar(&retry.BaseDelay, "retry-base", 10*time.Millisecond, "backoff before the first retry, doubled for each one after")
+	flag.DurationVar(&retry.MaxDelay, "retry-max", time.Second, "longest backoff between retries")
+	list := flag.Bool("list", false, "list registered workloads and exit")
+	connection.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	if *list {
+		for _, w := range benchmark.Workloads() {
+			fmt.Printf("%-40s %-12s %d ops, %d concurrent\n", w.Name, w.Database, w.Operations, w.Concurrency)
+		}
+		return
+	}
+
+	workloads := benchmark.Workloads()
+	if *names != "" {
+		workloads = workloads[:0]
+		for _, name := range strings.Split(*names, ",") {
+			w, ok := benchmark.Lookup(strings.TrimSpace(name))
+			if !ok {
+				benchmark.Fatal("Unknown workload; use -list to see registered workloads", "workload", name)
+			}
+			workloads = append(workloads, w)
+		}
+	}
+
+	benchmark.SetOpTimeout(*opTimeout)
+	benchmark.SetRetryPolicy(retry)
+	parameters := make(map[string]string)
+	flag.VisitAll(func(f *flag.Flag) {
+		if f.Name != "pg-dsn" {
+			parameters[f.Name] = f.Value.String()
+		}
+	})
+	benchmark.SetParameters(parameters)
+	ctx := benchmark.HandleInterrupts()
+	suite := benchmark.NewSuite("custom")
+
+	slog.Info("Running Custom Workloads")
+
+	for _, w := range workloads {
+		result, err := benchmark.Run(ctx, w, *operations, *concurrency)
+		if err != nil {
+			slog.Warn("Skipping workload", "workload", w.Name, "err", err)
+			continue
+		}
+		suite.Add(result)
+	}
+
+	benchmark.Save(suite, "custom")
+	benchmark.PrintSummary(suite)
+}
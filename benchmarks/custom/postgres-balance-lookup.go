@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/shopspring/decimal"
+
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+)
+
+// Example workload: point lookups of account balances by primary key. Copy
+// this file as a starting point for your own PostgreSQL access pattern.
+
+var (
+	balanceDB       *sql.DB
+	balanceAccounts []uuid.UUID
+)
+
+func init() {
+	w := benchmark.Register("postgres-balance-lookup", setupBalanceLookup, lookupBalance, teardownBalanceLookup)
+	w.Database = "PostgreSQL"
+	w.Operations = 5000
+	w.Concurrency = 20
+}
+
+func setupBalanceLookup(ctx context.Context) error {
+	connector, err := connection.PostgresConnector()
+	if err != nil {
+		return err
+	}
+	db := sql.OpenDB(connector)
+	db.SetMaxOpenConns(connection.PostgresPool.MaxOpen)
+	db.SetMaxIdleConns(connection.PostgresPool.MaxIdle)
+	db.SetConnMaxLifetime(connection.PostgresPool.MaxLifetime)
+
+	rows, err := db.QueryContext(ctx, "SELECT id FROM accounts LIMIT 100")
+	if err != nil {
+		db.Close()
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			db.Close()
+			return err
+		}
+		balanceAccounts = append(balanceAccounts, id)
+	}
+	if len(balanceAccounts) == 0 {
+		db.Close()
+		return errors.New("no accounts found; run `make seed-postgres` first")
+	}
+
+	balanceDB = db
+	return nil
+}
+
+func lookupBalance(ctx context.Context, worker, iteration int) error {
+	var balance decimal.Decimal
+	var currency string
+	accountID := balanceAccounts[benchmark.Rand().Intn(len(balanceAccounts))]
+	err := balanceDB.QueryRowContext(ctx, "SELECT balance, currency FROM accounts WHERE id = $1", accountID).Scan(&balance, &currency)
+	if err != nil {
+		return err
+	}
+
+	benchmark.Expect(ctx, "currency is an ISO code", len(currency) == 3)
+	return nil
+}
+
+func teardownBalanceLookup(ctx context.Context) error {
+	return balanceDB.Close()
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/scenario"
+)
+
+// Composite flows defined once with the scenario builder and registered for
+// both databases as "<scenario>/postgresql" and "<scenario>/dynamodb".
+
+func init() {
+	scenario.New("transfer-with-funds-check").
+		Then(scenario.ReadAccount(scenario.Debit)).
+		Then(scenario.If(scenario.SufficientFunds(scenario.Debit),
+			scenario.WriteTransaction(),
+			scenario.Expect(scenario.TransactionWritten()),
+			scenario.UpdateBalances(),
+			scenario.Expect(scenario.SameCurrency()),
+		)).
+		Register(scenario.NewPostgres(), scenario.NewDynamoDB())
+
+	scenario.New("blind-payment").
+		Then(scenario.WriteTransaction()).
+		Then(scenario.UpdateBalances()).
+		Register(scenario.NewPostgres(), scenario.NewDynamoDB())
+}
@@ -0,0 +1,672 @@
+package dynamodb
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// closePeriodDays is the accounting period covered by the close.
+	closePeriodDays = 30
+
+	closeScanSegments = 8
+
+	// OLTP mixed workload run alongside the close: account balance reads,
+	// recent activity reads and double-entry payments.
+	oltpConcurrency      = 20
+	oltpBaselineDuration = 30 * time.Second
+	oltpWriteRatio       = 0.2
+)
+
+type ledgerTxn struct {
+	merchantID      string
+	transactionType string
+	status          string
+	createdAt       time.Time
+	debits, credits decimal.Decimal
+}
+
+type ledgerLeg struct {
+	accountID string
+	legType   string
+	amount    decimal.Decimal
+}
+
+// ledgerSnapshot is the in-memory copy of the ledger every close step works
+// from. DynamoDB has no server-side aggregation, so the close reads every
+// header and leg once and derives all reports on the client.
+type ledgerSnapshot struct {
+	txns map[string]*ledgerTxn
+	legs []ledgerLeg
+}
+
+// closeStep is one stage of the month-end close. Steps run in order against
+// the snapshot and report the capacity they consumed.
+type closeStep struct {
+	name string
+	run  func(snap *ledgerSnapshot, runID string) (rcu, wcu float64, itemsScanned, itemsReturned int, err error)
+}
+
+var (
+	closeSteps = []closeStep{
+		{"Trial Balance", runTrialBalance},
+		{"Merchant Settlement", runMerchantSettlement},
+		{"Daily Summaries", runDailySummaries},
+		{"Exception Detection", runExceptionDetection},
+	}
+)
+
+func runClose(benchmark.Options) {
+	connect()
+	loadTestData()
+
+	suite := benchmark.NewSuite("dynamodb-close")
+
+	slog.Info("Running DynamoDB Month-End Close Simulation")
+
+	var closeAlone, closeUnderLoad []benchmark.Result
+	var oltpAlone, oltpUnderClose benchmark.Result
+	suite.RunAll(func() []benchmark.Result {
+		closeAlone = runMonthEndClose("Month-End Close")
+		return closeAlone
+	})
+
+	slog.Info("Running Close + Live Traffic Interference Test")
+
+	suite.Run(func() benchmark.Result {
+		oltpAlone = runOLTPFor("OLTP Mixed Workload - Baseline (no close)", oltpBaselineDuration)
+		return oltpAlone
+	})
+
+	suite.RunAll(func() []benchmark.Result {
+		closeUnderLoad, oltpUnderClose = runCloseWithLiveTraffic()
+		return append(closeUnderLoad, oltpUnderClose)
+	})
+
+	if len(closeAlone) > 0 && len(closeUnderLoad) > 0 {
+		logInterference(closeAlone[len(closeAlone)-1], closeUnderLoad[len(closeUnderLoad)-1], oltpAlone, oltpUnderClose)
+	}
+
+	benchmark.Save(suite, "dynamodb-close")
+	benchmark.PrintSummary(suite)
+}
+
+// runMonthEndClose takes a snapshot of the ledger, chains every close step
+// over it and returns a result per step followed by a result for the job as a
+// whole. Exception items written by the job are removed afterwards.
+func runMonthEndClose(label string) []benchmark.Result {
+	slog.Info("Running close", "job", label, "steps", len(closeSteps), "period_days", closePeriodDays)
+
+	runID := uuid.New().String()
+	defer deleteExceptions(runID)
+
+	results := make([]benchmark.Result, 0, len(closeSteps)+2)
+	total := benchmark.Result{
+		TestName:      fmt.Sprintf("%s - Total", label),
+		Database:      "DynamoDB",
+		Step:          "Total",
+		NumOperations: len(closeSteps) + 1,
+	}
+
+	jobStart := time.Now()
+
+	snapStart := time.Now()
+	snap, rcu, scanned, err := scanLedger(closeScanSegments)
+	snapDuration := time.Since(snapStart)
+
+	snapResult := benchmark.Result{
+		TestName:         fmt.Sprintf("%s - Ledger Snapshot (parallel scan, %d segments)", label, closeScanSegments),
+		Database:         "DynamoDB",
+		Step:             "Ledger Snapshot",
+		NumOperations:    1,
+		TotalDuration:    snapDuration,
+		AverageDuration:  snapDuration,
+		OperationsPerSec: 1.0 / snapDuration.Seconds(),
+		ConsumedRCU:      rcu,
+		ItemsScanned:     scanned,
+		ItemsReturned:    len(snap.txns) + len(snap.legs),
+		Timestamp:        time.Now(),
+	}
+	if err != nil {
+		slog.Error("Ledger snapshot failed", "err", err)
+		snapResult.ErrorCount = 1
+	} else {
+		snapResult.SuccessCount = 1
+	}
+	slog.Info("Ledger snapshot taken", "duration", snapDuration, "transactions", len(snap.txns),
+		"legs", len(snap.legs), "rcu", rcu)
+	results = append(results, snapResult)
+
+	for _, step := range closeSteps {
+		start := time.Now()
+		rcu, wcu, itemsScanned, itemsReturned, err := step.run(snap, runID)
+		duration := time.Since(start)
+
+		result := benchmark.Result{
+			TestName:         fmt.Sprintf("%s - %s", label, step.name),
+			Database:         "DynamoDB",
+			Step:             step.name,
+			NumOperations:    1,
+			TotalDuration:    duration,
+			AverageDuration:  duration,
+			OperationsPerSec: 1.0 / duration.Seconds(),
+			ConsumedRCU:      rcu,
+			ConsumedWCU:      wcu,
+			ItemsScanned:     itemsScanned,
+			ItemsReturned:    itemsReturned,
+			Timestamp:        time.Now(),
+		}
+		if err != nil {
+			slog.Error("Close step failed", "step", step.name, "err", err)
+			result.ErrorCount = 1
+		} else {
+			result.SuccessCount = 1
+		}
+
+		slog.Info("Close step finished", "step", step.name, "duration", duration,
+			"items", itemsReturned, "rcu", rcu, "wcu", wcu)
+		results = append(results, result)
+	}
+
+	for _, result := range results {
+		total.ConsumedRCU += result.ConsumedRCU
+		total.ConsumedWCU += result.ConsumedWCU
+		total.ItemsScanned += result.ItemsScanned
+		total.ItemsReturned += result.ItemsReturned
+		total.SuccessCount += result.SuccessCount
+		total.ErrorCount += result.ErrorCount
+	}
+
+	total.TotalDuration = time.Since(jobStart)
+	total.AverageDuration = total.TotalDuration / time.Duration(total.NumOperations)
+	total.OperationsPerSec = float64(total.NumOperations) / total.TotalDuration.Seconds()
+	withinWindow := total.TotalDuration <= closeOfDayWindow
+	total.WithinCloseWindow = &withinWindow
+	total.Timestamp = time.Now()
+
+	slog.Info("Close finished", "job", label, "duration", total.TotalDuration,
+		"rcu", total.ConsumedRCU, "wcu", total.ConsumedWCU)
+	if !withinWindow {
+		slog.Warn("Close exceeded the close-of-day window", "window", closeOfDayWindow)
+	}
+
+	return append(results, total)
+}
+
+// runCloseWithLiveTraffic runs the month-end close while the OLTP mixed
+// workload keeps running, and returns the close results together with the
+// OLTP latency observed for the duration of the close.
+func runCloseWithLiveTraffic() ([]benchmark.Result, benchmark.Result) {
+	testName := "OLTP Mixed Workload - During Month-End Close"
+	stop := make(chan struct{})
+	oltp := startOLTP(testName, stop)
+
+	// Let the OLTP workload reach a steady state before the close starts
+	time.Sleep(2 * time.Second)
+
+	closeResults := runMonthEndClose("Month-End Close (under OLTP load)")
+	close(stop)
+
+	return closeResults, oltp.result(testName)
+}
+
+func runOLTPFor(testName string, duration time.Duration) benchmark.Result {
+	stop := make(chan struct{})
+	time.AfterFunc(duration, func() { close(stop) })
+	return startOLTP(testName, stop).result(testName)
+}
+
+// oltpRun is the mixed workload running on its workers, and the capacity
+// its operations consume.
+type oltpRun struct {
+	pool     *benchmark.Pool
+	totalRCU float64
+	totalWCU float64
+}
+
+// startOLTP starts the mixed workload on oltpConcurrency workers, which run
+// until stop is closed.
+func startOLTP(testName string, stop <-chan struct{}) *oltpRun {
+	slog.Info("Running OLTP workload", "test", testName, "workers", oltpConcurrency,
+		"write_percent", oltpWriteRatio*100)
+
+	run := &oltpRun{}
+	run.pool = benchmark.StartWorkers(oltpConcurrency, func(worker int, local *benchmark.Recorder, rng *rand.Rand) benchmark.WorkerTally {
+		var tally benchmark.WorkerTally
+		readUnits, writeUnits := 0.0, 0.0
+		tally.Merge = func() {
+			run.totalRCU += readUnits
+			run.totalWCU += writeUnits
+		}
+		for {
+			select {
+			case <-stop:
+				return tally
+			default:
+			}
+
+			opStart := time.Now()
+			rcu, wcu, err := oltpOperation(rng)
+			local.Record(time.Since(opStart))
+			readUnits += rcu
+			writeUnits += wcu
+			if err != nil {
+				tally.Errors++
+			} else {
+				tally.Successes++
+			}
+		}
+	})
+	return run
+}
+
+// result waits for the workload to stop and summarizes its operations.
+func (r *oltpRun) result(testName string) benchmark.Result {
+	ran := r.pool.Wait()
+	result := ran.Summarize(testName, "DynamoDB", ran.Latencies.Count(), oltpConcurrency)
+	result.ConsumedRCU = r.totalRCU
+	result.ConsumedWCU = r.totalWCU
+	return result
+}
+
+func oltpOperation(rng *rand.Rand) (float64, float64, error) {
+	accountID := accountIDs[benchmark.Pick(rng, len(accountIDs))]
+
+	r := rng.Float64()
+	switch {
+	case r < oltpWriteRatio:
+		wcu, err := writePayment(rng)
+		return 0, wcu, err
+	case r < oltpWriteRatio+(1-oltpWriteRatio)/2:
+		output, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName: aws.String(connection.DynamoDBTable),
+			Key: map[string]types.AttributeValue{
+				"PK": &types.AttributeValueMemberS{Value: fmt.Sprintf("ACCOUNT#%s", accountID)},
+				"SK": &types.AttributeValueMemberS{Value: "METADATA"},
+			},
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+		if output.ConsumedCapacity != nil {
+			return *output.ConsumedCapacity.CapacityUnits, 0, nil
+		}
+		return 0, 0, nil
+	default:
+		output, err := client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(connection.DynamoDBTable),
+			IndexName:              aws.String("GSI1"),
+			KeyConditionExpression: aws.String("GSI1PK = :account AND begins_with(GSI1SK, :leg)"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":account": &types.AttributeValueMemberS{Value: fmt.Sprintf("ACCOUNT#%s", accountID)},
+				":leg":     &types.AttributeValueMemberS{Value: "LEG#"},
+			},
+			ScanIndexForward:       aws.Bool(false),
+			Limit:                  aws.Int32(10),
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+		if output.ConsumedCapacity != nil {
+			return *output.ConsumedCapacity.CapacityUnits, 0, nil
+		}
+		return 0, 0, nil
+	}
+}
+
+// writePayment writes a double-entry payment (header and two legs) in one
+// TransactWriteItems call, with the same index keys the seed data uses.
+func writePayment(rng *rand.Rand) (float64, error) {
+	txnID := uuid.New().String()
+	merchantID := merchantIDs[benchmark.Pick(rng, len(merchantIDs))]
+	debitAccount := accountIDs[benchmark.Pick(rng, len(accountIDs))]
+	creditAccount := accountIDs[benchmark.Pick(rng, len(accountIDs))]
+	amount := decimal.NewFromFloat(rng.Float64()*1000 + 1).StringFixed(4)
+	createdAt := time.Now().Format(time.RFC3339Nano)
+	pk := fmt.Sprintf("TXN#%s", txnID)
+
+	header := map[string]types.AttributeValue{
+		"PK":              &types.AttributeValueMemberS{Value: pk},
+		"SK":              &types.AttributeValueMemberS{Value: "METADATA"},
+		"GSI1PK":          &types.AttributeValueMemberS{Value: "STATUS#completed"},
+		"GSI1SK":          &types.AttributeValueMemberS{Value: fmt.Sprintf("CREATED#%s", createdAt)},
+		"GSI3PK":          &types.AttributeValueMemberS{Value: fmt.Sprintf("MERCHANT#%s", merchantID)},
+		"GSI3SK":          &types.AttributeValueMemberS{Value: fmt.Sprintf("CREATED#%s", createdAt)},
+		"Type":            &types.AttributeValueMemberS{Value: "Transaction"},
+		"ID":              &types.AttributeValueMemberS{Value: txnID},
+		"TransactionType": &types.AttributeValueMemberS{Value: "payment"},
+		"Status":          &types.AttributeValueMemberS{Value: "completed"},
+		"MerchantID":      &types.AttributeValueMemberS{Value: merchantID},
+		"Description":     &types.AttributeValueMemberS{Value: "Close interference transaction"},
+		"CreatedAt":       &types.AttributeValueMemberS{Value: createdAt},
+		"BenchmarkRunID":  &types.AttributeValueMemberS{Value: benchmark.RunID},
+	}
+
+	leg := func(accountID, legType string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"PK":             &types.AttributeValueMemberS{Value: pk},
+			"SK":             &types.AttributeValueMemberS{Value: fmt.Sprintf("LEG#%s", uuid.New().String())},
+			"GSI1PK":         &types.AttributeValueMemberS{Value: fmt.Sprintf("ACCOUNT#%s", accountID)},
+			"GSI1SK":         &types.AttributeValueMemberS{Value: fmt.Sprintf("LEG#%s#%s", createdAt, txnID)},
+			"Type":           &types.AttributeValueMemberS{Value: "TransactionLeg"},
+			"TransactionID":  &types.AttributeValueMemberS{Value: txnID},
+			"AccountID":      &types.AttributeValueMemberS{Value: accountID},
+			"LegType":        &types.AttributeValueMemberS{Value: legType},
+			"Amount":         &types.AttributeValueMemberN{Value: amount},
+			"Currency":       &types.AttributeValueMemberS{Value: "USD"},
+			"CreatedAt":      &types.AttributeValueMemberS{Value: createdAt},
+			"BenchmarkRunID": &types.AttributeValueMemberS{Value: benchmark.RunID},
+		}
+	}
+
+	output, err := client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{
+			{Put: &types.Put{TableName: aws.String(connection.DynamoDBTable), Item: header}},
+			{Put: &types.Put{TableName: aws.String(connection.DynamoDBTable), Item: leg(debitAccount, "debit")}},
+			{Put: &types.Put{TableName: aws.String(connection.DynamoDBTable), Item: leg(creditAccount, "credit")}},
+		},
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+	})
+
+	wcu := 0.0
+	if output != nil {
+		for _, cc := range output.ConsumedCapacity {
+			if cc.CapacityUnits != nil {
+				wcu += *cc.CapacityUnits
+			}
+		}
+	}
+
+	return wcu, err
+}
+
+func logInterference(closeAlone, closeUnderLoad, oltpAlone, oltpUnderClose benchmark.Result) {
+	if closeAlone.TotalDuration > 0 {
+		slog.Info("Interference: close duration under OLTP load", "alone", closeAlone.TotalDuration,
+			"under_load", closeUnderLoad.TotalDuration,
+			"slowdown", float64(closeUnderLoad.TotalDuration)/float64(closeAlone.TotalDuration))
+	}
+	if oltpAlone.P99Duration > 0 {
+		slog.Info("Interference: OLTP P99 during close", "alone", oltpAlone.P99Duration,
+			"during_close", oltpUnderClose.P99Duration,
+			"slowdown", float64(oltpUnderClose.P99Duration)/float64(oltpAlone.P99Duration))
+	}
+	if oltpAlone.OperationsPerSec > 0 {
+		slog.Info("Interference: OLTP throughput during close",
+			"alone_ops_per_sec", oltpAlone.OperationsPerSec,
+			"during_close_ops_per_sec", oltpUnderClose.OperationsPerSec)
+	}
+}
+
+// scanLedger reads every transaction header and leg with a parallel scan and
+// folds each leg into its transaction's running debit and credit totals.
+func scanLedger(totalSegments int) (*ledgerSnapshot, float64, int, error) {
+	snap := &ledgerSnapshot{
+		txns: make(map[string]*ledgerTxn),
+		legs: make([]ledgerLeg, 0),
+	}
+	totalRCU := 0.0
+	itemsScanned := 0
+	var firstErr error
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	txnFor := func(pk string) *ledgerTxn {
+		t, exists := snap.txns[pk]
+		if !exists {
+			t = &ledgerTxn{}
+			snap.txns[pk] = t
+		}
+		return t
+	}
+
+	for segment := 0; segment < totalSegments; segment++ {
+		wg.Add(1)
+		go func(seg int) {
+			defer wg.Done()
+
+			var lastEvaluatedKey map[string]types.AttributeValue
+			for {
+				output, err := client.Scan(ctx, &dynamodb.ScanInput{
+					TableName:            aws.String(connection.DynamoDBTable),
+					FilterExpression:     aws.String("#t IN (:txn, :leg)"),
+					ProjectionExpression: aws.String("PK, #t, MerchantID, TransactionType, #s, CreatedAt, AccountID, LegType, Amount"),
+					ExpressionAttributeNames: map[string]string{
+						"#t": "Type",
+						"#s": "Status",
+					},
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":txn": &types.AttributeValueMemberS{Value: "Transaction"},
+						":leg": &types.AttributeValueMemberS{Value: "TransactionLeg"},
+					},
+					Segment:                aws.Int32(int32(seg)),
+					TotalSegments:          aws.Int32(int32(totalSegments)),
+					ExclusiveStartKey:      lastEvaluatedKey,
+					ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+				})
+
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					return
+				}
+
+				itemsScanned += int(output.ScannedCount)
+				if output.ConsumedCapacity != nil {
+					totalRCU += *output.ConsumedCapacity.CapacityUnits
+				}
+
+				for _, item := range output.Items {
+					pk, ok := item["PK"].(*types.AttributeValueMemberS)
+					itemType, ok2 := item["Type"].(*types.AttributeValueMemberS)
+					if !ok || !ok2 {
+						continue
+					}
+
+					t := txnFor(pk.Value)
+					if itemType.Value == "Transaction" {
+						t.merchantID = stringAttr(item, "MerchantID")
+						t.transactionType = stringAttr(item, "TransactionType")
+						t.status = stringAttr(item, "Status")
+						t.createdAt, _ = time.Parse(time.RFC3339Nano, stringAttr(item, "CreatedAt"))
+						continue
+					}
+
+					amount, ok := item["Amount"].(*types.AttributeValueMemberN)
+					if !ok {
+						continue
+					}
+					leg := ledgerLeg{
+						accountID: stringAttr(item, "AccountID"),
+						legType:   stringAttr(item, "LegType"),
+						amount:    decimal.RequireFromString(amount.Value),
+					}
+					if leg.legType == "debit" {
+						t.debits = t.debits.Add(leg.amount)
+					} else {
+						t.credits = t.credits.Add(leg.amount)
+					}
+					snap.legs = append(snap.legs, leg)
+				}
+				mu.Unlock()
+
+				if output.LastEvaluatedKey == nil {
+					return
+				}
+				lastEvaluatedKey = output.LastEvaluatedKey
+			}
+		}(segment)
+	}
+
+	wg.Wait()
+	return snap, totalRCU, itemsScanned, firstErr
+}
+
+func stringAttr(item map[string]types.AttributeValue, name string) string {
+	if v, ok := item[name].(*types.AttributeValueMemberS); ok {
+		return v.Value
+	}
+	return ""
+}
+
+func runTrialBalance(snap *ledgerSnapshot, runID string) (float64, float64, int, int, error) {
+	type accountTotals struct {
+		debits, credits decimal.Decimal
+	}
+
+	totals := make(map[string]*accountTotals)
+	totalDebits := decimal.Zero
+	totalCredits := decimal.Zero
+	for _, leg := range snap.legs {
+		t, exists := totals[leg.accountID]
+		if !exists {
+			t = &accountTotals{}
+			totals[leg.accountID] = t
+		}
+		if leg.legType == "debit" {
+			t.debits = t.debits.Add(leg.amount)
+			totalDebits = totalDebits.Add(leg.amount)
+		} else {
+			t.credits = t.credits.Add(leg.amount)
+			totalCredits = totalCredits.Add(leg.amount)
+		}
+	}
+
+	if !totalDebits.Equal(totalCredits) {
+		slog.Warn("Ledger out of balance", "difference", totalDebits.Sub(totalCredits).StringFixed(4))
+	}
+
+	return 0, 0, len(snap.legs), len(totals), nil
+}
+
+func runMerchantSettlement(snap *ledgerSnapshot, runID string) (float64, float64, int, int, error) {
+	since := time.Now().AddDate(0, 0, -closePeriodDays)
+	settlements := make(map[string]decimal.Decimal)
+
+	for _, t := range snap.txns {
+		if t.merchantID == "" || t.status != "completed" || t.createdAt.Before(since) {
+			continue
+		}
+		settlements[t.merchantID] = settlements[t.merchantID].Add(t.debits)
+	}
+
+	return 0, 0, len(snap.txns), len(settlements), nil
+}
+
+func runDailySummaries(snap *ledgerSnapshot, runID string) (float64, float64, int, int, error) {
+	since := time.Now().AddDate(0, 0, -closePeriodDays)
+	summaries := make(map[string]decimal.Decimal)
+
+	for _, t := range snap.txns {
+		if t.createdAt.Before(since) {
+			continue
+		}
+		key := fmt.Sprintf("%s#%s", t.createdAt.Format("2006-01-02"), t.transactionType)
+		summaries[key] = summaries[key].Add(t.debits)
+	}
+
+	return 0, 0, len(snap.txns), len(summaries), nil
+}
+
+// runExceptionDetection flags every unbalanced transaction in the snapshot by
+// writing it to the EXCEPTIONS#<run_id> item collection.
+func runExceptionDetection(snap *ledgerSnapshot, runID string) (float64, float64, int, int, error) {
+	totalWCU := 0.0
+	flagged := 0
+	var firstErr error
+	requests := make([]types.WriteRequest, 0, 25)
+
+	flush := func() {
+		if len(requests) == 0 {
+			return
+		}
+		wcu, err := batchWriteWithRetry(requests)
+		totalWCU += wcu
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		requests = make([]types.WriteRequest, 0, 25)
+	}
+
+	for pk, t := range snap.txns {
+		if t.debits.Equal(t.credits) {
+			continue
+		}
+
+		requests = append(requests, types.WriteRequest{PutRequest: &types.PutRequest{Item: map[string]types.AttributeValue{
+			"PK":             &types.AttributeValueMemberS{Value: fmt.Sprintf("EXCEPTIONS#%s", runID)},
+			"SK":             &types.AttributeValueMemberS{Value: pk},
+			"Type":           &types.AttributeValueMemberS{Value: "SuspenseException"},
+			"RunID":          &types.AttributeValueMemberS{Value: runID},
+			"TransactionID":  &types.AttributeValueMemberS{Value: strings.TrimPrefix(pk, "TXN#")},
+			"TotalDebits":    &types.AttributeValueMemberN{Value: t.debits.String()},
+			"TotalCredits":   &types.AttributeValueMemberN{Value: t.credits.String()},
+			"Difference":     &types.AttributeValueMemberN{Value: t.debits.Sub(t.credits).String()},
+			"DetectedAt":     &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339Nano)},
+			"BenchmarkRunID": &types.AttributeValueMemberS{Value: benchmark.RunID},
+		}}})
+		flagged++
+
+		if len(requests) == 25 {
+			flush()
+		}
+	}
+	flush()
+
+	return 0, totalWCU, len(snap.txns), flagged, firstErr
+}
+
+// deleteExceptions removes the item collection written by a close run.
+func deleteExceptions(runID string) {
+	var lastEvaluatedKey map[string]types.AttributeValue
+	for {
+		output, err := client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(connection.DynamoDBTable),
+			KeyConditionExpression: aws.String("PK = :pk"),
+			ProjectionExpression:   aws.String("PK, SK"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("EXCEPTIONS#%s", runID)},
+			},
+			ExclusiveStartKey: lastEvaluatedKey,
+		})
+		if err != nil {
+			slog.Error("Failed to query close exceptions", "err", err)
+			return
+		}
+
+		for start := 0; start < len(output.Items); start += 25 {
+			end := min(start+25, len(output.Items))
+			requests := make([]types.WriteRequest, 0, end-start)
+			for _, key := range output.Items[start:end] {
+				requests = append(requests, types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: key}})
+			}
+
+			if _, err := batchWriteWithRetry(requests); err != nil {
+				slog.Error("Failed to remove close exceptions", "err", err)
+			}
+		}
+
+		if output.LastEvaluatedKey == nil {
+			return
+		}
+		lastEvaluatedKey = output.LastEvaluatedKey
+	}
+}
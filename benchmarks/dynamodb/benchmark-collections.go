@@ -0,0 +1,355 @@
+package dynamodb
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// collectionWarnLegs and collectionWarnPages flag accounts whose leg
+	// collection is approaching practical limits: full-history reads take
+	// one round trip per 1 MB page, and a single partition key caps out at
+	// 1,000 WCU / 3,000 RCU regardless of table capacity.
+	collectionWarnLegs  = 100000
+	collectionWarnPages = 50
+	recentLegReads      = 200
+	fullHistoryReads    = 5
+	growConcurrency     = 10
+)
+
+var (
+	// collectionSizes are the leg counts the synthetic account is grown
+	// through.
+	collectionSizes = []int{1000, 10000, 100000, 250000}
+)
+
+// collectionStats describes one account's leg collection in GSI1.
+type collectionStats struct {
+	accountID string
+	legs      int
+	pages     int
+	rcu       float64
+}
+
+func runCollections(opts benchmark.Options) {
+	connect()
+
+	loadTestData()
+
+	suite := benchmark.NewSuite("dynamodb-collections")
+
+	slog.Info("Running Per-Account Item Collection Benchmarks")
+
+	suite.Run(func() benchmark.Result { return monitorItemCollections() })
+
+	accountID := uuid.New().String()
+	var keys []map[string]types.AttributeValue
+	legs := 0
+	for _, size := range collectionSizes {
+		keys = append(keys, growCollection(accountID, legs, size)...)
+		legs = size
+
+		suite.Run(func() benchmark.Result { return benchmarkRecentLegs(accountID, legs, opts.Ops(recentLegReads)) })
+		suite.Run(func() benchmark.Result { return benchmarkFullHistory(accountID, legs, opts.Ops(fullHistoryReads)) })
+	}
+
+	slog.Info("Removing synthetic legs", "legs", len(keys))
+	deleteItems(keys)
+
+	benchmark.Save(suite, "dynamodb-collections")
+	benchmark.PrintSummary(suite)
+}
+
+// measureCollection counts an account's legs in GSI1 with Select COUNT. The
+// count still reads every item, so the page count is the number of round
+// trips a full-history read needs and the RCU is what it costs.
+func measureCollection(accountID string) (collectionStats, error) {
+	stats := collectionStats{accountID: accountID}
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(connection.DynamoDBTable),
+		IndexName:              aws.String("GSI1"),
+		KeyConditionExpression: aws.String("GSI1PK = :pk AND begins_with(GSI1SK, :leg)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":  &types.AttributeValueMemberS{Value: fmt.Sprintf("ACCOUNT#%s", accountID)},
+			":leg": &types.AttributeValueMemberS{Value: "LEG#"},
+		},
+		Select:                 types.SelectCount,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+	}
+
+	for {
+		output, err := client.Query(ctx, input)
+		if err != nil {
+			return stats, err
+		}
+
+		stats.legs += int(output.Count)
+		stats.pages++
+		if output.ConsumedCapacity != nil {
+			stats.rcu += *output.ConsumedCapacity.CapacityUnits
+		}
+
+		if output.LastEvaluatedKey == nil {
+			return stats, nil
+		}
+		input.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+}
+
+// monitorItemCollections measures every loaded account's leg collection and
+// flags those at or above the warning thresholds.
+func monitorItemCollections() benchmark.Result {
+	testName := "Per-Account Item Collection Sizes"
+	slog.Info("Benchmarking", "test", testName, "accounts", len(accountIDs))
+	benchmark.StartTest(testName)
+
+	durations := make([]time.Duration, 0, len(accountIDs))
+	successCount := 0
+	errorCount := 0
+	itemsScanned := 0
+	totalRCU := 0.0
+	largest := collectionStats{}
+	flagged := 0
+
+	start := time.Now()
+
+	for _, accountID := range accountIDs {
+		opStart := time.Now()
+		stats, err := measureCollection(accountID)
+		durations = append(durations, time.Since(opStart))
+
+		if err != nil {
+			errorCount++
+			continue
+		}
+		successCount++
+		itemsScanned += stats.legs
+		totalRCU += stats.rcu
+
+		if stats.legs > largest.legs {
+			largest = stats
+		}
+		if stats.legs >= collectionWarnLegs || stats.pages >= collectionWarnPages {
+			flagged++
+			slog.Warn("Large item collection", "account", stats.accountID, "legs", stats.legs,
+				"pages", stats.pages, "rcu_per_read", stats.rcu)
+		}
+	}
+
+	totalDuration := time.Since(start)
+
+	slog.Info("Largest collection", "account", largest.accountID, "legs", largest.legs, "pages", largest.pages)
+	slog.Info("Accounts at or above the collection warning size", "flagged", flagged,
+		"accounts", len(accountIDs), "warn_legs", collectionWarnLegs,
+		"warn_pages", collectionWarnPages)
+
+	result := calculateResults(testName, len(accountIDs), 1, durations, successCount, errorCount, totalDuration)
+	result.CollectionSize = largest.legs
+	result.ItemsScanned = itemsScanned
+	result.ConsumedRCU = totalRCU
+	return result
+}
+
+// growCollection writes legs [from, to) for the account, each under its own
+// synthetic transaction, and returns their primary keys.
+func growCollection(accountID string, from, to int) []map[string]types.AttributeValue {
+	slog.Info("Growing account", "account", accountID, "legs", to)
+
+	now := time.Now().UTC()
+	requests := make(chan []types.WriteRequest)
+	keys := make([]map[string]types.AttributeValue, 0, to-from)
+
+	var wg sync.WaitGroup
+	for g := 0; g < growConcurrency; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range requests {
+				batchWriteWithRetry(batch)
+			}
+		}()
+	}
+
+	batch := make([]types.WriteRequest, 0, 25)
+	for i := from; i < to; i++ {
+		txnID := uuid.New().String()
+		createdAt := now.Add(-time.Duration(i) * time.Second).Format(time.RFC3339Nano)
+		legType := "credit"
+		if i%2 == 0 {
+			legType = "debit"
+		}
+
+		key := map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: fmt.Sprintf("TXN#%s", txnID)},
+			"SK": &types.AttributeValueMemberS{Value: fmt.Sprintf("LEG#%s", uuid.New().String())},
+		}
+		keys = append(keys, key)
+
+		batch = append(batch, types.WriteRequest{PutRequest: &types.PutRequest{Item: map[string]types.AttributeValue{
+			"PK":             key["PK"],
+			"SK":             key["SK"],
+			"GSI1PK":         &types.AttributeValueMemberS{Value: fmt.Sprintf("ACCOUNT#%s", accountID)},
+			"GSI1SK":         &types.AttributeValueMemberS{Value: fmt.Sprintf("LEG#%s#%s", createdAt, txnID)},
+			"Type":           &types.AttributeValueMemberS{Value: "CollectionBenchmarkLeg"},
+			"BenchmarkRunID": &types.AttributeValueMemberS{Value: benchmark.RunID},
+			"TransactionID":  &types.AttributeValueMemberS{Value: txnID},
+			"AccountID":      &types.AttributeValueMemberS{Value: accountID},
+			"LegType":        &types.AttributeValueMemberS{Value: legType},
+			"Amount":         &types.AttributeValueMemberN{Value: "10.0000"},
+			"Currency":       &types.AttributeValueMemberS{Value: "USD"},
+			"CreatedAt":      &types.AttributeValueMemberS{Value: createdAt},
+		}}})
+
+		if len(batch) == 25 {
+			requests <- batch
+			batch = make([]types.WriteRequest, 0, 25)
+		}
+	}
+	if len(batch) > 0 {
+		requests <- batch
+	}
+	close(requests)
+	wg.Wait()
+
+	return keys
+}
+
+func benchmarkRecentLegs(accountID string, legs, count int) benchmark.Result {
+	testName := fmt.Sprintf("Recent 20 Legs (%d-leg account)", legs)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
+
+	durations := make([]time.Duration, 0, count)
+	successCount := 0
+	errorCount := 0
+	itemsReturned := 0
+	totalRCU := 0.0
+
+	start := time.Now()
+
+	for i := 0; i < count; i++ {
+		opStart := time.Now()
+		output, err := client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(connection.DynamoDBTable),
+			IndexName:              aws.String("GSI1"),
+			KeyConditionExpression: aws.String("GSI1PK = :pk AND begins_with(GSI1SK, :leg)"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":pk":  &types.AttributeValueMemberS{Value: fmt.Sprintf("ACCOUNT#%s", accountID)},
+				":leg": &types.AttributeValueMemberS{Value: "LEG#"},
+			},
+			ScanIndexForward:       aws.Bool(false),
+			Limit:                  aws.Int32(20),
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+		})
+		durations = append(durations, time.Since(opStart))
+
+		if err != nil {
+			errorCount++
+			continue
+		}
+		successCount++
+		itemsReturned += len(output.Items)
+		if output.ConsumedCapacity != nil {
+			totalRCU += *output.ConsumedCapacity.CapacityUnits
+		}
+	}
+
+	totalDuration := time.Since(start)
+
+	result := calculateResults(testName, count, 1, durations, successCount, errorCount, totalDuration)
+	result.CollectionSize = legs
+	result.ItemsReturned = itemsReturned
+	result.ConsumedRCU = totalRCU
+	return result
+}
+
+// benchmarkFullHistory pages through every leg and recomputes the account's
+// net position client-side, the read that grows linearly with the collection.
+func benchmarkFullHistory(accountID string, legs, count int) benchmark.Result {
+	testName := fmt.Sprintf("Full Leg History Aggregate (%d-leg account)", legs)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
+
+	durations := make([]time.Duration, 0, count)
+	successCount := 0
+	errorCount := 0
+	itemsScanned := 0
+	totalRCU := 0.0
+
+	start := time.Now()
+
+	for i := 0; i < count; i++ {
+		input := &dynamodb.QueryInput{
+			TableName:              aws.String(connection.DynamoDBTable),
+			IndexName:              aws.String("GSI1"),
+			KeyConditionExpression: aws.String("GSI1PK = :pk AND begins_with(GSI1SK, :leg)"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":pk":  &types.AttributeValueMemberS{Value: fmt.Sprintf("ACCOUNT#%s", accountID)},
+				":leg": &types.AttributeValueMemberS{Value: "LEG#"},
+			},
+			ProjectionExpression:   aws.String("LegType, Amount"),
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+		}
+
+		net := decimal.Zero
+		var err error
+
+		opStart := time.Now()
+		for {
+			var output *dynamodb.QueryOutput
+			output, err = client.Query(ctx, input)
+			if err != nil {
+				break
+			}
+
+			for _, item := range output.Items {
+				amount, _ := item["Amount"].(*types.AttributeValueMemberN)
+				legType, _ := item["LegType"].(*types.AttributeValueMemberS)
+				if amount == nil || legType == nil {
+					continue
+				}
+				value, _ := decimal.NewFromString(amount.Value)
+				if legType.Value == "credit" {
+					net = net.Add(value)
+				} else {
+					net = net.Sub(value)
+				}
+			}
+			itemsScanned += int(output.ScannedCount)
+			if output.ConsumedCapacity != nil {
+				totalRCU += *output.ConsumedCapacity.CapacityUnits
+			}
+
+			if output.LastEvaluatedKey == nil {
+				break
+			}
+			input.ExclusiveStartKey = output.LastEvaluatedKey
+		}
+		durations = append(durations, time.Since(opStart))
+
+		if err != nil {
+			errorCount++
+		} else {
+			successCount++
+		}
+	}
+
+	totalDuration := time.Since(start)
+
+	result := calculateResults(testName, count, 1, durations, successCount, errorCount, totalDuration)
+	result.CollectionSize = legs
+	result.ItemsScanned = itemsScanned
+	result.ConsumedRCU = totalRCU
+	return result
+}
@@ -0,0 +1,367 @@
+package dynamodb
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// exportPeriodDays is the window the partner-reporting feeds cover: the
+	// month to date a finance team regenerates every night.
+	exportPeriodDays = 30
+	// exportLegWorkers is how many transactions' legs are fetched at once.
+	exportLegWorkers = 16
+)
+
+// exportTxn is a completed transaction header an export found through a
+// GSI, with the day it falls on.
+type exportTxn struct {
+	key        string
+	merchantID string
+	day        string
+}
+
+// exportRow is one line of an export file: a partition value, the day, the
+// column beside it, and the transactions and debit volume they add up to.
+type exportRow struct {
+	partition, day, column string
+	transactions           int
+	amount                 decimal.Decimal
+}
+
+// exportCost is the capacity and items an export step consumed.
+type exportCost struct {
+	rcu     float64
+	scanned int
+	errors  int
+}
+
+func (c *exportCost) add(output *dynamodb.QueryOutput) {
+	c.scanned += int(output.ScannedCount)
+	if output.ConsumedCapacity != nil {
+		c.rcu += aws.ToFloat64(output.ConsumedCapacity.CapacityUnits)
+	}
+}
+
+// exportStep is one partner-reporting feed, written as a CSV file per value
+// of key. Its run finds the feed's transactions through a GSI and returns
+// its rows, the partition each belongs to first.
+type exportStep struct {
+	name   string
+	key    string
+	header []string
+	run    func(since time.Time, cost *exportCost) []exportRow
+}
+
+var exportSteps = []exportStep{
+	{"Merchant Export", "merchant", []string{"date", "currency", "transactions", "amount"}, exportByMerchant},
+	{"Currency Export", "currency", []string{"date", "merchant_id", "transactions", "amount"}, exportByCurrency},
+}
+
+// runExports produces the nightly partner-reporting feeds, a summary file
+// per merchant and per currency. With no GROUP BY, each feed walks a GSI to
+// its transactions, queries every transaction's legs and aggregates on the
+// client; each step reports its duration and the RCU it consumed.
+func runExports(benchmark.Options) {
+	connect()
+
+	suite := benchmark.NewSuite("dynamodb-exports")
+
+	slog.Info("Running DynamoDB Partner-Reporting Exports")
+
+	suite.RunAll(func() []benchmark.Result { return runExportJob("Partner-Reporting Exports") })
+
+	benchmark.Save(suite, "dynamodb-exports")
+	benchmark.PrintSummary(suite)
+}
+
+// runExportJob runs every export step and returns a result per step
+// followed by a result for the job as a whole.
+func runExportJob(label string) []benchmark.Result {
+	dir := benchmark.ExportDir("dynamodb-exports")
+	slog.Info("Running exports", "job", label, "feeds", len(exportSteps), "period_days", exportPeriodDays, "dir", dir)
+	benchmark.StartTest(label)
+
+	since := time.Now().Add(-exportPeriodDays * 24 * time.Hour)
+
+	results := make([]benchmark.Result, 0, len(exportSteps)+1)
+	total := benchmark.Result{
+		TestName:      fmt.Sprintf("%s - Total", label),
+		Database:      "DynamoDB",
+		Step:          "Total",
+		NumOperations: len(exportSteps),
+	}
+
+	jobStart := time.Now()
+	for _, step := range exportSteps {
+		start := time.Now()
+		var cost exportCost
+		rows := step.run(since, &cost)
+		files, bytes, err := writeExport(filepath.Join(dir, step.key), step, rows)
+		duration := time.Since(start)
+
+		transactions := 0
+		for _, row := range rows {
+			transactions += row.transactions
+		}
+
+		result := benchmark.Result{
+			TestName:         fmt.Sprintf("%s - %s", label, step.name),
+			Database:         "DynamoDB",
+			Step:             step.name,
+			NumOperations:    1,
+			TotalDuration:    duration,
+			AverageDuration:  duration,
+			OperationsPerSec: 1.0 / duration.Seconds(),
+			ConsumedRCU:      cost.rcu,
+			ItemsScanned:     cost.scanned,
+			ItemsReturned:    len(rows),
+			FilesWritten:     files,
+			BytesWritten:     bytes,
+			Timestamp:        time.Now(),
+		}
+		if err != nil || cost.errors > 0 {
+			slog.Error("Export failed", "feed", step.name, "request_errors", cost.errors, "err", err)
+			result.ErrorCount = 1
+		} else {
+			result.SuccessCount = 1
+		}
+
+		slog.Info("Export written", "feed", step.name, "duration", duration,
+			"transactions", transactions, "rows", len(rows), "files", files, "rcu", cost.rcu)
+
+		total.ConsumedRCU += result.ConsumedRCU
+		total.ItemsScanned += result.ItemsScanned
+		total.ItemsReturned += result.ItemsReturned
+		total.FilesWritten += result.FilesWritten
+		total.BytesWritten += result.BytesWritten
+		total.SuccessCount += result.SuccessCount
+		total.ErrorCount += result.ErrorCount
+		results = append(results, result)
+	}
+
+	total.TotalDuration = time.Since(jobStart)
+	total.AverageDuration = total.TotalDuration / time.Duration(len(exportSteps))
+	total.OperationsPerSec = float64(len(exportSteps)) / total.TotalDuration.Seconds()
+	total.Timestamp = time.Now()
+
+	slog.Info("Exports finished", "job", label, "duration", total.TotalDuration, "rcu", total.ConsumedRCU)
+	slog.Info("Every transaction needs its own Query to reach its legs; PostgreSQL joins and groups them in one statement")
+
+	return append(results, total)
+}
+
+// exportByMerchant lists the merchants with a filtered Scan, as no index
+// holds them, then walks each merchant's transactions in GSI3 and sums
+// their debit legs by day and currency.
+func exportByMerchant(since time.Time, cost *exportCost) []exportRow {
+	var merchants []string
+	var lastEvaluatedKey map[string]types.AttributeValue
+	for {
+		output, err := client.Scan(ctx, &dynamodb.ScanInput{
+			TableName:                aws.String(connection.DynamoDBTable),
+			FilterExpression:         aws.String("#t = :type"),
+			ProjectionExpression:     aws.String("ID"),
+			ExpressionAttributeNames: map[string]string{"#t": "Type"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":type": &types.AttributeValueMemberS{Value: "Merchant"},
+			},
+			ExclusiveStartKey:      lastEvaluatedKey,
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+		})
+		if err != nil {
+			cost.errors++
+			slog.Warn("Merchant scan error", "err", err)
+			break
+		}
+		cost.scanned += int(output.ScannedCount)
+		if output.ConsumedCapacity != nil {
+			cost.rcu += aws.ToFloat64(output.ConsumedCapacity.CapacityUnits)
+		}
+		for _, item := range output.Items {
+			merchants = append(merchants, stringAttr(item, "ID"))
+		}
+		if output.LastEvaluatedKey == nil || benchmark.Stopping() {
+			break
+		}
+		lastEvaluatedKey = output.LastEvaluatedKey
+	}
+
+	var txns []exportTxn
+	for _, merchantID := range merchants {
+		if benchmark.Stopping() {
+			break
+		}
+		txns = append(txns, queryExportTxns(cost, "GSI3", "GSI3PK = :pk AND GSI3SK >= :since", fmt.Sprintf("MERCHANT#%s", merchantID), since)...)
+	}
+
+	return aggregateDebits(txns, cost, func(txn exportTxn, currency string) (string, string) {
+		return txn.merchantID, currency
+	})
+}
+
+// exportByCurrency walks every completed transaction in the period in GSI1
+// and sums their debit legs by currency, day and merchant. Currency is only
+// on the legs, so no index can narrow the walk to one currency.
+func exportByCurrency(since time.Time, cost *exportCost) []exportRow {
+	txns := queryExportTxns(cost, "GSI1", "GSI1PK = :pk AND GSI1SK >= :since", "STATUS#completed", since)
+
+	return aggregateDebits(txns, cost, func(txn exportTxn, currency string) (string, string) {
+		return currency, txn.merchantID
+	})
+}
+
+// queryExportTxns pages through the completed transactions under pk in a
+// GSI sorted by CREATED#<time> from since on.
+func queryExportTxns(cost *exportCost, index, keyCondition, pk string, since time.Time) []exportTxn {
+	var txns []exportTxn
+	var lastEvaluatedKey map[string]types.AttributeValue
+	for {
+		output, err := client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(connection.DynamoDBTable),
+			IndexName:              aws.String(index),
+			KeyConditionExpression: aws.String(keyCondition),
+			FilterExpression:       aws.String("#s = :completed"),
+			ProjectionExpression:   aws.String("PK, MerchantID, CreatedAt"),
+			ExpressionAttributeNames: map[string]string{
+				"#s": "Status",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":pk":        &types.AttributeValueMemberS{Value: pk},
+				":since":     &types.AttributeValueMemberS{Value: fmt.Sprintf("CREATED#%s", since.Format(time.RFC3339Nano))},
+				":completed": &types.AttributeValueMemberS{Value: "completed"},
+			},
+			ExclusiveStartKey:      lastEvaluatedKey,
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+		})
+		if err != nil {
+			cost.errors++
+			slog.Warn("Query error", "index", index, "err", err)
+			break
+		}
+		cost.add(output)
+		for _, item := range output.Items {
+			txn := exportTxn{key: stringAttr(item, "PK"), merchantID: stringAttr(item, "MerchantID")}
+			if createdAt, err := time.Parse(time.RFC3339Nano, stringAttr(item, "CreatedAt")); err == nil {
+				txn.day = createdAt.UTC().Format(time.DateOnly)
+			}
+			txns = append(txns, txn)
+		}
+		if output.LastEvaluatedKey == nil || benchmark.Stopping() {
+			break
+		}
+		lastEvaluatedKey = output.LastEvaluatedKey
+	}
+	return txns
+}
+
+// aggregateDebits queries each transaction's debit legs from
+// exportLegWorkers workers and sums them into rows keyed by the partition
+// and column group returns for a transaction's legs in one currency.
+// A transaction counts once per row, however many debit legs it has.
+func aggregateDebits(txns []exportTxn, cost *exportCost, group func(txn exportTxn, currency string) (partition, column string)) []exportRow {
+	totals := make(map[[3]string]*exportRow)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	work := make(chan exportTxn)
+
+	for w := 0; w < exportLegWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for txn := range work {
+				output, err := client.Query(ctx, &dynamodb.QueryInput{
+					TableName:              aws.String(connection.DynamoDBTable),
+					KeyConditionExpression: aws.String("PK = :txn AND begins_with(SK, :prefix)"),
+					FilterExpression:       aws.String("LegType = :debit"),
+					ProjectionExpression:   aws.String("Currency, Amount"),
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":txn":    &types.AttributeValueMemberS{Value: txn.key},
+						":prefix": &types.AttributeValueMemberS{Value: "LEG#"},
+						":debit":  &types.AttributeValueMemberS{Value: "debit"},
+					},
+					ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+				})
+
+				mu.Lock()
+				if err != nil {
+					cost.errors++
+					mu.Unlock()
+					continue
+				}
+				cost.add(output)
+				debits := make(map[string]decimal.Decimal)
+				for _, leg := range output.Items {
+					amount, ok := leg["Amount"].(*types.AttributeValueMemberN)
+					if !ok {
+						continue
+					}
+					currency := stringAttr(leg, "Currency")
+					debits[currency] = debits[currency].Add(decimal.RequireFromString(amount.Value))
+				}
+				for currency, amount := range debits {
+					partition, column := group(txn, currency)
+					key := [3]string{partition, txn.day, column}
+					row, exists := totals[key]
+					if !exists {
+						row = &exportRow{partition: partition, day: txn.day, column: column}
+						totals[key] = row
+					}
+					row.transactions++
+					row.amount = row.amount.Add(amount)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, txn := range txns {
+		if benchmark.Stopping() {
+			break
+		}
+		work <- txn
+	}
+	close(work)
+	wg.Wait()
+
+	rows := make([]exportRow, 0, len(totals))
+	for _, row := range totals {
+		rows = append(rows, *row)
+	}
+	return rows
+}
+
+// writeExport sorts rows by partition, day and column, as PostgreSQL's
+// ORDER BY returns them, and writes them as a CSV file per partition.
+func writeExport(dir string, step exportStep, rows []exportRow) (files int, bytes int64, err error) {
+	slices.SortFunc(rows, func(a, b exportRow) int {
+		return strings.Compare(a.partition+"\x00"+a.day+"\x00"+a.column, b.partition+"\x00"+b.day+"\x00"+b.column)
+	})
+
+	w, err := benchmark.NewPartitionWriter(dir, step.key, step.header...)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, row := range rows {
+		if err = w.Write(row.partition, []string{row.day, row.column, fmt.Sprint(row.transactions), row.amount.StringFixed(4)}); err != nil {
+			break
+		}
+	}
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	return w.Files(), w.Bytes(), err
+}
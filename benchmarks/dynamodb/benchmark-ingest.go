@@ -0,0 +1,252 @@
+package dynamodb
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// ingestStepDuration is how long each concurrency level is sustained.
+	ingestStepDuration = 15 * time.Second
+	ingestLookups      = 1000
+	// ingestShards spreads each hour's writes over this many partitions.
+	ingestShards = 16
+)
+
+var (
+	// ingestConcurrency is the ramp each key design is driven through; the
+	// highest sustained ops/sec across the ramp is its ingest ceiling.
+	ingestConcurrency = []int{10, 25, 50, 100}
+)
+
+// ingestKeyDesign builds the item for one ingested transaction.
+//
+// The current design keys items by TXN#<id> and indexes every completed
+// transaction under GSI1PK = STATUS#completed, so one GSI partition absorbs
+// the whole write stream. The time-bucketed design writes to
+// INGEST#<shard>#<date-hour> with a GSI1 entry keyed by TXN#<id>, spreading
+// base-table and index writes across shards and giving up direct GetItem by
+// transaction ID in exchange.
+type ingestKeyDesign struct {
+	name     string
+	item     func(rng *rand.Rand, id string, createdAt time.Time) map[string]types.AttributeValue
+	bucketed bool
+}
+
+var ingestKeyDesigns = []ingestKeyDesign{
+	{"TXN#uuid", transactionKeyedItem, false},
+	{"Shard#Date-Hour", timeBucketedItem, true},
+}
+
+func runIngest(opts benchmark.Options) {
+	connect()
+
+	loadTestData()
+
+	suite := benchmark.NewSuite("dynamodb-ingest")
+
+	slog.Info("Running Sustained Ingest Benchmarks")
+
+	for _, design := range ingestKeyDesigns {
+		var keys []map[string]types.AttributeValue
+		var ceiling float64
+		for _, concurrency := range opts.ConcurrencyLevels(ingestConcurrency...) {
+			suite.Run(func() benchmark.Result {
+				result, written := benchmarkSustainedIngest(design, concurrency, opts.Step(ingestStepDuration))
+				keys = append(keys, written...)
+				ceiling = max(ceiling, result.OperationsPerSec)
+				return result
+			})
+		}
+		slog.Info("Ingest ceiling", "design", design.name, "ops_per_sec", ceiling)
+
+		suite.Run(func() benchmark.Result { return benchmarkIngestLookups(design, keys, opts.Ops(ingestLookups)) })
+
+		deleteIngestItems(keys)
+	}
+
+	benchmark.Save(suite, "dynamodb-ingest")
+	benchmark.PrintSummary(suite)
+}
+
+func ingestAttributes(rng *rand.Rand, id string, createdAt time.Time) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"Type":           &types.AttributeValueMemberS{Value: "IngestTransaction"},
+		"BenchmarkRunID": &types.AttributeValueMemberS{Value: benchmark.RunID},
+		"ID":             &types.AttributeValueMemberS{Value: id},
+		"AccountID":      &types.AttributeValueMemberS{Value: accountIDs[benchmark.Pick(rng, len(accountIDs))]},
+		"MerchantID":     &types.AttributeValueMemberS{Value: merchantIDs[benchmark.Pick(rng, len(merchantIDs))]},
+		"Amount":         &types.AttributeValueMemberN{Value: decimal.NewFromFloat(rng.Float64()*1000 + 1).StringFixed(4)},
+		"Currency":       &types.AttributeValueMemberS{Value: "USD"},
+		"Status":         &types.AttributeValueMemberS{Value: "completed"},
+		"CreatedAt":      &types.AttributeValueMemberS{Value: createdAt.Format(time.RFC3339Nano)},
+	}
+}
+
+func transactionKeyedItem(rng *rand.Rand, id string, createdAt time.Time) map[string]types.AttributeValue {
+	item := ingestAttributes(rng, id, createdAt)
+	item["PK"] = &types.AttributeValueMemberS{Value: fmt.Sprintf("TXN#%s", id)}
+	item["SK"] = &types.AttributeValueMemberS{Value: "METADATA"}
+	item["GSI1PK"] = &types.AttributeValueMemberS{Value: "STATUS#completed"}
+	item["GSI1SK"] = &types.AttributeValueMemberS{Value: fmt.Sprintf("CREATED#%s", createdAt.Format(time.RFC3339Nano))}
+	return item
+}
+
+func timeBucketedItem(rng *rand.Rand, id string, createdAt time.Time) map[string]types.AttributeValue {
+	item := ingestAttributes(rng, id, createdAt)
+	item["PK"] = &types.AttributeValueMemberS{Value: fmt.Sprintf("INGEST#%02d#%s", rng.Intn(ingestShards), createdAt.Format("2006-01-02-15"))}
+	item["SK"] = &types.AttributeValueMemberS{Value: fmt.Sprintf("TXN#%s#%s", createdAt.Format(time.RFC3339Nano), id)}
+	item["GSI1PK"] = &types.AttributeValueMemberS{Value: fmt.Sprintf("TXN#%s", id)}
+	item["GSI1SK"] = &types.AttributeValueMemberS{Value: "INGEST"}
+	return item
+}
+
+// benchmarkSustainedIngest writes as fast as concurrency workers allow for
+// the given duration and returns the primary keys it wrote.
+func benchmarkSustainedIngest(design ingestKeyDesign, concurrency int, duration time.Duration) (benchmark.Result, []map[string]types.AttributeValue) {
+	testName := fmt.Sprintf("Sustained Ingest - %s (%d concurrent)", design.name, concurrency)
+	slog.Info("Benchmarking", "test", testName, "duration", duration)
+	benchmark.StartTest(testName)
+
+	var keys []map[string]types.AttributeValue
+	throttledCount := 0
+	totalWCU := 0.0
+
+	deadline := time.Now().Add(duration)
+	ran := benchmark.RunWorkers(concurrency, func(worker int, local *benchmark.Recorder, rng *rand.Rand) benchmark.WorkerTally {
+		var tally benchmark.WorkerTally
+		var written []map[string]types.AttributeValue
+		throttled, consumed := 0, 0.0
+		for time.Now().Before(deadline) {
+			item := design.item(rng, uuid.New().String(), time.Now().UTC())
+
+			opStart := time.Now()
+			output, err := client.PutItem(ctx, &dynamodb.PutItemInput{
+				TableName:              aws.String(connection.DynamoDBTable),
+				Item:                   item,
+				ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+			})
+			local.Record(time.Since(opStart))
+			if err != nil {
+				tally.Errors++
+				if isThrottled(err) {
+					throttled++
+				}
+			} else {
+				tally.Successes++
+				written = append(written, map[string]types.AttributeValue{"PK": item["PK"], "SK": item["SK"]})
+				if output.ConsumedCapacity != nil {
+					consumed += *output.ConsumedCapacity.CapacityUnits
+				}
+			}
+		}
+		tally.Merge = func() {
+			keys = append(keys, written...)
+			throttledCount += throttled
+			totalWCU += consumed
+		}
+		return tally
+	})
+
+	result := ran.Summarize(testName, "DynamoDB", ran.Latencies.Count(), concurrency)
+	result.Layout = design.name
+	result.ConsumedWCU = totalWCU
+	result.ThrottledCount = throttledCount
+	return result, keys
+}
+
+// benchmarkIngestLookups fetches random ingested transactions by ID. The
+// TXN#uuid design answers with a GetItem; the time-bucketed design has to go
+// through GSI1, which is eventually consistent.
+func benchmarkIngestLookups(design ingestKeyDesign, keys []map[string]types.AttributeValue, count int) benchmark.Result {
+	testName := fmt.Sprintf("Ingest Lookup by Transaction ID - %s", design.name)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
+
+	durations := make([]time.Duration, 0, count)
+	successCount := 0
+	errorCount := 0
+	itemsReturned := 0
+	totalRCU := 0.0
+
+	if len(keys) == 0 {
+		return calculateResults(testName, count, 1, durations, 0, count, 0)
+	}
+
+	start := time.Now()
+
+	for i := 0; i < count; i++ {
+		key := keys[benchmark.Pick(benchmark.Rand(), len(keys))]
+
+		opStart := time.Now()
+		var capacity *types.ConsumedCapacity
+		var found bool
+		var err error
+		if design.bucketed {
+			id := key["SK"].(*types.AttributeValueMemberS).Value
+			id = id[len(id)-36:]
+
+			var output *dynamodb.QueryOutput
+			output, err = client.Query(ctx, &dynamodb.QueryInput{
+				TableName:              aws.String(connection.DynamoDBTable),
+				IndexName:              aws.String("GSI1"),
+				KeyConditionExpression: aws.String("GSI1PK = :pk"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("TXN#%s", id)},
+				},
+				ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+			})
+			if err == nil {
+				capacity = output.ConsumedCapacity
+				found = len(output.Items) > 0
+			}
+		} else {
+			var output *dynamodb.GetItemOutput
+			output, err = client.GetItem(ctx, &dynamodb.GetItemInput{
+				TableName:              aws.String(connection.DynamoDBTable),
+				Key:                    key,
+				ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+			})
+			if err == nil {
+				capacity = output.ConsumedCapacity
+				found = output.Item != nil
+			}
+		}
+		durations = append(durations, time.Since(opStart))
+
+		if err != nil {
+			errorCount++
+			continue
+		}
+		successCount++
+		if found {
+			itemsReturned++
+		}
+		if capacity != nil {
+			totalRCU += *capacity.CapacityUnits
+		}
+	}
+
+	totalDuration := time.Since(start)
+
+	result := calculateResults(testName, count, 1, durations, successCount, errorCount, totalDuration)
+	result.Layout = design.name
+	result.ConsumedRCU = totalRCU
+	result.ItemsReturned = itemsReturned
+	return result
+}
+
+func deleteIngestItems(keys []map[string]types.AttributeValue) {
+	slog.Info("Removing ingested items", "items", len(keys))
+	deleteItems(keys)
+}
@@ -0,0 +1,369 @@
+package dynamodb
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+)
+
+type isolationTransaction struct {
+	PK              string    `dynamodbav:"PK"`
+	SK              string    `dynamodbav:"SK"`
+	GSI1PK          string    `dynamodbav:"GSI1PK"`
+	GSI1SK          string    `dynamodbav:"GSI1SK"`
+	Type            string    `dynamodbav:"Type"`
+	ID              string    `dynamodbav:"ID"`
+	IdempotencyKey  string    `dynamodbav:"IdempotencyKey"`
+	TransactionType string    `dynamodbav:"TransactionType"`
+	Status          string    `dynamodbav:"Status"`
+	MerchantID      string    `dynamodbav:"MerchantID"`
+	Description     string    `dynamodbav:"Description"`
+	CreatedAt       time.Time `dynamodbav:"CreatedAt"`
+	BenchmarkRunID  string    `dynamodbav:"BenchmarkRunID"`
+}
+
+const (
+	// The first numNoisyMerchants loaded merchants play the role of
+	// high-volume merchants; the rest generate low-rate "quiet" traffic.
+	numNoisyMerchants       = 3
+	noisyWorkersPerMerchant = 20
+	quietOps                = 500
+	quietConcurrency        = 5
+)
+
+// tableRouter returns the table a merchant's transactions are written to.
+type tableRouter func(merchantID string) string
+
+func runIsolation(opts benchmark.Options) {
+	quietCount, quietWorkers := opts.Ops(quietOps), opts.Workers(quietConcurrency)
+	connect()
+
+	loadMerchants()
+
+	noisy := merchantIDs[:numNoisyMerchants]
+	quiet := merchantIDs[numNoisyMerchants:]
+
+	suite := benchmark.NewSuite("dynamodb-isolation")
+
+	slog.Info("Running DynamoDB Noisy-Neighbor Isolation Experiment")
+
+	singleTable := func(merchantID string) string { return connection.DynamoDBTable }
+
+	// 1. Quiet merchants alone establish the latency baseline
+	var baseline benchmark.Result
+	suite.Run(func() benchmark.Result {
+		baseline = benchmarkQuietTraffic("Single Table", quiet, singleTable, quietCount, quietWorkers)
+		baseline.TestName = "Quiet Merchants - Baseline (no noisy traffic)"
+		return baseline
+	})
+
+	// 2. Noisy merchants share the single table with everyone else
+	suite.RunAll(func() []benchmark.Result {
+		return runNoisyNeighborExperiment("Single Table", quiet, noisy, singleTable, quietCount, quietWorkers)
+	})
+
+	// 3. Noisy merchants are moved to dedicated tables
+	merchantTables := createMerchantTables(noisy)
+	defer deleteMerchantTables(merchantTables)
+
+	perMerchant := func(merchantID string) string {
+		if table, ok := merchantTables[merchantID]; ok {
+			return table
+		}
+		return connection.DynamoDBTable
+	}
+	suite.RunAll(func() []benchmark.Result {
+		return runNoisyNeighborExperiment("Per-Merchant Tables", quiet, noisy, perMerchant, quietCount, quietWorkers)
+	})
+
+	benchmark.Save(suite, "dynamodb-isolation")
+	printSummary(suite, baseline)
+}
+
+func loadMerchants() {
+	slog.Info("Loading test data from DynamoDB")
+
+	output, err := client.Scan(ctx, &dynamodb.ScanInput{
+		TableName:        aws.String(connection.DynamoDBTable),
+		FilterExpression: aws.String("#t = :type"),
+		ExpressionAttributeNames: map[string]string{
+			"#t": "Type",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":type": &types.AttributeValueMemberS{Value: "Merchant"},
+		},
+	})
+
+	if err == nil {
+		for _, item := range output.Items {
+			if id, ok := item["ID"].(*types.AttributeValueMemberS); ok {
+				merchantIDs = append(merchantIDs, id.Value)
+			}
+		}
+	}
+
+	slog.Info("Loaded merchants", "merchants", len(merchantIDs))
+
+	if len(merchantIDs) <= numNoisyMerchants {
+		benchmark.Fatal("Not enough merchants found in DynamoDB; seed first with `make seed-dynamodb` or `benchctl seed --db=dynamodb`")
+	}
+}
+
+func runNoisyNeighborExperiment(layout string, quiet, noisy []string, route tableRouter, quietCount, quietWorkers int) []benchmark.Result {
+	slog.Info("Running noisy-neighbor experiment", "layout", layout)
+
+	stop := make(chan struct{})
+	throttledCount := 0
+	totalWCU := 0.0
+
+	noisyWorkers := len(noisy) * noisyWorkersPerMerchant
+	noisyRun := benchmark.StartWorkers(noisyWorkers, func(worker int, local *benchmark.Recorder, rng *rand.Rand) benchmark.WorkerTally {
+		var tally benchmark.WorkerTally
+		throttled, consumed := 0, 0.0
+		tally.Merge = func() {
+			throttledCount += throttled
+			totalWCU += consumed
+		}
+		merchantID := noisy[worker/noisyWorkersPerMerchant]
+		for {
+			select {
+			case <-stop:
+				return tally
+			default:
+			}
+
+			opStart := time.Now()
+			wcu, err := putMerchantTransaction(route(merchantID), merchantID)
+			local.Record(time.Since(opStart))
+			if err != nil {
+				tally.Errors++
+				if isThrottled(err) {
+					throttled++
+				}
+			} else {
+				tally.Successes++
+				consumed += wcu
+			}
+		}
+	})
+
+	// Let the noisy merchants ramp up before measuring the quiet ones
+	time.Sleep(2 * time.Second)
+
+	quietResult := benchmarkQuietTraffic(layout, quiet, route, quietCount, quietWorkers)
+
+	close(stop)
+	noisyRan := noisyRun.Wait()
+
+	noisyResult := noisyRan.Summarize(
+		fmt.Sprintf("Noisy Merchants - %s (%d merchants x %d workers)", layout, len(noisy), noisyWorkersPerMerchant),
+		"DynamoDB", noisyRan.Latencies.Count(), noisyWorkers)
+	noisyResult.ConsumedWCU = totalWCU
+	noisyResult.Layout = layout
+	noisyResult.Role = "noisy"
+	noisyResult.ThrottledCount = throttledCount
+
+	return []benchmark.Result{quietResult, noisyResult}
+}
+
+func benchmarkQuietTraffic(layout string, quiet []string, route tableRouter, ops, concurrency int) benchmark.Result {
+	testName := fmt.Sprintf("Quiet Merchants - %s (%d ops, %d concurrent)", layout, ops, concurrency)
+	slog.Info("Benchmarking", "test", testName)
+	benchmark.StartTest(testName)
+
+	throttledCount := 0
+	totalWCU := 0.0
+
+	opsPerGoroutine := ops / concurrency
+	ran := benchmark.RunWorkers(concurrency, func(worker int, local *benchmark.Recorder, rng *rand.Rand) benchmark.WorkerTally {
+		var tally benchmark.WorkerTally
+		throttled, consumed := 0, 0.0
+		for i := 0; i < opsPerGoroutine && !benchmark.Stopping(); i++ {
+			merchantID := quiet[benchmark.Pick(rng, len(quiet))]
+
+			// A quiet operation is a write followed by a read-your-write lookup
+			opStart := time.Now()
+			wcu, err := putAndGetTransaction(route(merchantID), merchantID)
+			local.Record(time.Since(opStart))
+			if err != nil {
+				tally.Errors++
+				if isThrottled(err) {
+					throttled++
+				}
+			} else {
+				tally.Successes++
+				consumed += wcu
+			}
+		}
+		tally.Merge = func() {
+			throttledCount += throttled
+			totalWCU += consumed
+		}
+		return tally
+	})
+
+	result := ran.Summarize(testName, "DynamoDB", ops, concurrency)
+	result.ConsumedWCU = totalWCU
+	result.Layout = layout
+	result.Role = "quiet"
+	result.ThrottledCount = throttledCount
+	return result
+}
+
+func putMerchantTransaction(table, merchantID string) (float64, error) {
+	txnID := uuid.New().String()
+	createdAt := time.Now()
+
+	txn := isolationTransaction{
+		PK:              fmt.Sprintf("TXN#%s", txnID),
+		SK:              "METADATA",
+		GSI1PK:          "STATUS#completed",
+		GSI1SK:          fmt.Sprintf("CREATED#%s", createdAt.Format(time.RFC3339Nano)),
+		Type:            "Transaction",
+		ID:              txnID,
+		IdempotencyKey:  uuid.New().String(),
+		TransactionType: "payment",
+		Status:          "completed",
+		MerchantID:      merchantID,
+		Description:     "Isolation benchmark transaction",
+		CreatedAt:       createdAt,
+		BenchmarkRunID:  benchmark.RunID,
+	}
+
+	item, err := attributevalue.MarshalMap(txn)
+	if err != nil {
+		return 0, err
+	}
+
+	output, err := client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:              aws.String(table),
+		Item:                   item,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+	})
+
+	wcu := 0.0
+	if output != nil && output.ConsumedCapacity != nil {
+		wcu = *output.ConsumedCapacity.CapacityUnits
+	}
+
+	return wcu, err
+}
+
+func putAndGetTransaction(table, merchantID string) (float64, error) {
+	txnID := uuid.New().String()
+	createdAt := time.Now()
+
+	txn := isolationTransaction{
+		PK:              fmt.Sprintf("TXN#%s", txnID),
+		SK:              "METADATA",
+		GSI1PK:          "STATUS#completed",
+		GSI1SK:          fmt.Sprintf("CREATED#%s", createdAt.Format(time.RFC3339Nano)),
+		Type:            "Transaction",
+		ID:              txnID,
+		IdempotencyKey:  uuid.New().String(),
+		TransactionType: "payment",
+		Status:          "completed",
+		MerchantID:      merchantID,
+		Description:     "Isolation benchmark transaction",
+		CreatedAt:       createdAt,
+		BenchmarkRunID:  benchmark.RunID,
+	}
+
+	item, err := attributevalue.MarshalMap(txn)
+	if err != nil {
+		return 0, err
+	}
+
+	output, err := client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:              aws.String(table),
+		Item:                   item,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	wcu := 0.0
+	if output.ConsumedCapacity != nil {
+		wcu = *output.ConsumedCapacity.CapacityUnits
+	}
+
+	_, err = client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(table),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: fmt.Sprintf("TXN#%s", txnID)},
+			"SK": &types.AttributeValueMemberS{Value: "METADATA"},
+		},
+	})
+
+	return wcu, err
+}
+
+// createMerchantTables provisions one table per noisy merchant with the same
+// key schema and throughput as the shared table, so partition capacity is not
+// shared between a high-volume merchant and everyone else.
+func createMerchantTables(noisy []string) map[string]string {
+	tables := make(map[string]string, len(noisy))
+
+	for _, merchantID := range noisy {
+		name := fmt.Sprintf("%s-%s", connection.DynamoDBTable, merchantID[:8])
+		slog.Info("Creating dedicated table", "table", name)
+
+		_, err := client.CreateTable(ctx, &dynamodb.CreateTableInput{
+			TableName: aws.String(name),
+			KeySchema: []types.KeySchemaElement{
+				{AttributeName: aws.String("PK"), KeyType: types.KeyTypeHash},
+				{AttributeName: aws.String("SK"), KeyType: types.KeyTypeRange},
+			},
+			AttributeDefinitions: []types.AttributeDefinition{
+				{AttributeName: aws.String("PK"), AttributeType: types.ScalarAttributeTypeS},
+				{AttributeName: aws.String("SK"), AttributeType: types.ScalarAttributeTypeS},
+			},
+			BillingMode: types.BillingModeProvisioned,
+			ProvisionedThroughput: &types.ProvisionedThroughput{
+				ReadCapacityUnits:  aws.Int64(100),
+				WriteCapacityUnits: aws.Int64(100),
+			},
+		})
+		if err != nil {
+			benchmark.Fatal("Failed to create table", "table", name, "err", err)
+		}
+
+		waiter := dynamodb.NewTableExistsWaiter(client)
+		if err := waiter.Wait(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(name)}, 2*time.Minute); err != nil {
+			benchmark.Fatal("Table did not become active", "table", name, "err", err)
+		}
+
+		tables[merchantID] = name
+	}
+
+	return tables
+}
+
+func deleteMerchantTables(tables map[string]string) {
+	for _, name := range tables {
+		if _, err := client.DeleteTable(ctx, &dynamodb.DeleteTableInput{TableName: aws.String(name)}); err != nil {
+			slog.Error("Failed to delete table", "table", name, "err", err)
+		}
+	}
+}
+
+func printSummary(suite benchmark.Suite, baseline benchmark.Result) {
+	fmt.Print("\n=== Benchmark Summary ===\n\n")
+	for _, result := range suite.Results {
+		benchmark.PrintResult(result)
+		if result.Role == "quiet" && baseline.P99Duration > 0 {
+			fmt.Printf("  P99 vs Baseline: %.2fx\n", float64(result.P99Duration)/float64(baseline.P99Duration))
+		}
+		fmt.Println()
+	}
+}
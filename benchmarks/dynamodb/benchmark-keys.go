@@ -0,0 +1,241 @@
+package dynamodb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	keyInserts     = 10000
+	keyConcurrency = 20
+	keyPartitions  = 20
+	keyRangeReads  = 500
+	// keyRangeWindow is the slice of the insert run each range read covers.
+	keyRangeWindow = 2 * time.Second
+)
+
+// keyStrategy generates the sort key suffix of ledger entries stored under
+// one partition per account. v4 keys sort randomly, so a time-range read has
+// to fetch the whole partition and filter; v7 keys start with a millisecond
+// timestamp, so the same read is a KeyConditionExpression BETWEEN.
+type keyStrategy struct {
+	name        string
+	newID       func() uuid.UUID
+	timeOrdered bool
+}
+
+var (
+	keyStrategies = []keyStrategy{
+		{"UUIDv4", uuid.New, false},
+		{"UUIDv7", func() uuid.UUID { return uuid.Must(uuid.NewV7()) }, true},
+	}
+)
+
+func runKeys(opts benchmark.Options) {
+	connect()
+
+	suite := benchmark.NewSuite("dynamodb-keys")
+
+	slog.Info("Running Key Generation Strategy Benchmarks")
+
+	for _, strategy := range keyStrategies {
+		var keys []map[string]types.AttributeValue
+		var runStart, runEnd time.Time
+		suite.Run(func() benchmark.Result {
+			var insertResult benchmark.Result
+			insertResult, keys, runStart, runEnd = benchmarkKeyInserts(strategy, opts.Ops(keyInserts), opts.Workers(keyConcurrency))
+			return insertResult
+		})
+
+		suite.Run(func() benchmark.Result {
+			return benchmarkKeyRangeReads(strategy, opts.Ops(keyRangeReads), runStart, runEnd)
+		})
+
+		deleteKeyItems(keys)
+	}
+
+	benchmark.Save(suite, "dynamodb-keys")
+	benchmark.PrintSummary(suite)
+}
+
+func keyPartition(strategy keyStrategy, partition int) string {
+	return fmt.Sprintf("KEYBENCH#%s#%d", strategy.name, partition)
+}
+
+func benchmarkKeyInserts(strategy keyStrategy, count, concurrency int) (benchmark.Result, []map[string]types.AttributeValue, time.Time, time.Time) {
+	testName := fmt.Sprintf("Key Strategy Inserts - %s (%d concurrent)", strategy.name, concurrency)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
+
+	var keys []map[string]types.AttributeValue
+	totalWCU := 0.0
+
+	opsPerGoroutine := count / concurrency
+	start := time.Now()
+	ran := benchmark.RunWorkers(concurrency, func(worker int, local *benchmark.Recorder, rng *rand.Rand) benchmark.WorkerTally {
+		var tally benchmark.WorkerTally
+		var written []map[string]types.AttributeValue
+		consumed := 0.0
+		for i := 0; i < opsPerGoroutine && !benchmark.Stopping(); i++ {
+			key := map[string]types.AttributeValue{
+				"PK": &types.AttributeValueMemberS{Value: keyPartition(strategy, rng.Intn(keyPartitions))},
+				"SK": &types.AttributeValueMemberS{Value: fmt.Sprintf("ENTRY#%s", strategy.newID())},
+			}
+			item := map[string]types.AttributeValue{
+				"PK":             key["PK"],
+				"SK":             key["SK"],
+				"Type":           &types.AttributeValueMemberS{Value: "KeyBenchmarkEntry"},
+				"BenchmarkRunID": &types.AttributeValueMemberS{Value: benchmark.RunID},
+				"Amount":         &types.AttributeValueMemberN{Value: decimal.NewFromFloat(rng.Float64()*1000 + 1).StringFixed(4)},
+				"CreatedAt":      &types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339Nano)},
+			}
+
+			opStart := time.Now()
+			output, err := client.PutItem(ctx, &dynamodb.PutItemInput{
+				TableName:              aws.String(connection.DynamoDBTable),
+				Item:                   item,
+				ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+			})
+			local.Record(time.Since(opStart))
+			if err != nil {
+				tally.Errors++
+			} else {
+				tally.Successes++
+				written = append(written, key)
+				if output.ConsumedCapacity != nil {
+					consumed += *output.ConsumedCapacity.CapacityUnits
+				}
+			}
+		}
+		tally.Merge = func() {
+			keys = append(keys, written...)
+			totalWCU += consumed
+		}
+		return tally
+	})
+	end := time.Now()
+
+	result := ran.Summarize(testName, "DynamoDB", opsPerGoroutine*concurrency, concurrency)
+	result.KeyStrategy = strategy.name
+	result.ConsumedWCU = totalWCU
+	return result, keys, start, end
+}
+
+// benchmarkKeyRangeReads fetches one partition's entries created inside a
+// random window of the insert run.
+func benchmarkKeyRangeReads(strategy keyStrategy, count int, runStart, runEnd time.Time) benchmark.Result {
+	testName := fmt.Sprintf("Key Strategy Range Reads - %s (%v window)", strategy.name, keyRangeWindow)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
+
+	durations := make([]time.Duration, 0, count)
+	successCount := 0
+	errorCount := 0
+	itemsScanned := 0
+	itemsReturned := 0
+	totalRCU := 0.0
+
+	span := runEnd.Sub(runStart) - keyRangeWindow
+	if span <= 0 {
+		span = 1
+	}
+
+	start := time.Now()
+
+	for i := 0; i < count; i++ {
+		from := runStart.Add(time.Duration(benchmark.Rand().Int63n(int64(span))))
+		to := from.Add(keyRangeWindow)
+
+		input := &dynamodb.QueryInput{
+			TableName:              aws.String(connection.DynamoDBTable),
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+		}
+		if strategy.timeOrdered {
+			input.KeyConditionExpression = aws.String("PK = :pk AND SK BETWEEN :from AND :to")
+			input.ExpressionAttributeValues = map[string]types.AttributeValue{
+				":pk":   &types.AttributeValueMemberS{Value: keyPartition(strategy, benchmark.Rand().Intn(keyPartitions))},
+				":from": &types.AttributeValueMemberS{Value: fmt.Sprintf("ENTRY#%s", v7Bound(from, 0x00))},
+				":to":   &types.AttributeValueMemberS{Value: fmt.Sprintf("ENTRY#%s", v7Bound(to, 0xff))},
+			}
+		} else {
+			input.KeyConditionExpression = aws.String("PK = :pk")
+			input.FilterExpression = aws.String("CreatedAt BETWEEN :from AND :to")
+			input.ExpressionAttributeValues = map[string]types.AttributeValue{
+				":pk":   &types.AttributeValueMemberS{Value: keyPartition(strategy, benchmark.Rand().Intn(keyPartitions))},
+				":from": &types.AttributeValueMemberS{Value: from.UTC().Format(time.RFC3339Nano)},
+				":to":   &types.AttributeValueMemberS{Value: to.UTC().Format(time.RFC3339Nano)},
+			}
+		}
+
+		opStart := time.Now()
+		var err error
+		for {
+			var output *dynamodb.QueryOutput
+			output, err = client.Query(ctx, input)
+			if err != nil {
+				break
+			}
+
+			itemsScanned += int(output.ScannedCount)
+			itemsReturned += len(output.Items)
+			if output.ConsumedCapacity != nil {
+				totalRCU += *output.ConsumedCapacity.CapacityUnits
+			}
+
+			if output.LastEvaluatedKey == nil {
+				break
+			}
+			input.ExclusiveStartKey = output.LastEvaluatedKey
+		}
+		durations = append(durations, time.Since(opStart))
+
+		if err != nil {
+			errorCount++
+		} else {
+			successCount++
+		}
+	}
+
+	totalDuration := time.Since(start)
+
+	slog.Info("Key strategy scan", "strategy", strategy.name, "items_scanned", itemsScanned,
+		"items_returned", itemsReturned, "rcu", totalRCU)
+
+	result := calculateResults(testName, count, 1, durations, successCount, errorCount, totalDuration)
+	result.KeyStrategy = strategy.name
+	result.ConsumedRCU = totalRCU
+	result.ItemsScanned = itemsScanned
+	result.ItemsReturned = itemsReturned
+	return result
+}
+
+// v7Bound builds the smallest (fill 0x00) or largest (fill 0xff) UUID whose
+// 48-bit timestamp prefix is t. Its string form sorts the same way as the
+// bytes, so it works as a sort-key bound.
+func v7Bound(t time.Time, fill byte) uuid.UUID {
+	var id uuid.UUID
+	for i := range id {
+		id[i] = fill
+	}
+
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(t.UnixMilli()))
+	copy(id[:6], ms[2:])
+	return id
+}
+
+func deleteKeyItems(keys []map[string]types.AttributeValue) {
+	slog.Info("Removing key benchmark items", "items", len(keys))
+	deleteItems(keys)
+}
@@ -0,0 +1,540 @@
+package dynamodb
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+	"github.com/shopspring/decimal"
+)
+
+// runMarshal measures the client-side cost of converting a transaction and
+// its legs to and from DynamoDB items, which PostgreSQL's driver has no
+// equivalent of, and how much of a full write or read it accounts for.
+func runMarshal(opts benchmark.Options) {
+	connect()
+
+	loadTestData()
+
+	suite := benchmark.NewSuite("dynamodb-marshal")
+
+	slog.Info("Running DynamoDB Marshalling Benchmarks")
+
+	// End-to-end baselines the marshalling costs are compared with
+	var write, read benchmark.Result
+	suite.Run(func() benchmark.Result {
+		write = benchmarkWriteTransactionItems(opts.Ops(200))
+		return write
+	})
+	suite.Run(func() benchmark.Result {
+		read = benchmarkReadTransactionItems(opts.Ops(200))
+		return read
+	})
+
+	// The same transaction and legs through each marshaller
+	count := opts.Ops(10000)
+	for _, m := range marshallers {
+		suite.Run(func() benchmark.Result { return benchmarkMarshal(m, count, write.AverageDuration) })
+		suite.Run(func() benchmark.Result { return benchmarkUnmarshal(m, count, read.AverageDuration) })
+	}
+
+	benchmark.Save(suite, "dynamodb-marshal")
+	benchmark.PrintSummary(suite)
+}
+
+// marshaller converts the ledger structs to and from items.
+type marshaller struct {
+	name         string
+	marshalTxn   func(Transaction) (map[string]types.AttributeValue, error)
+	marshalLeg   func(TransactionLeg) (map[string]types.AttributeValue, error)
+	unmarshalTxn func(map[string]types.AttributeValue) (Transaction, error)
+	unmarshalLeg func(map[string]types.AttributeValue) (TransactionLeg, error)
+}
+
+var marshallers = []marshaller{
+	{
+		name:       "attributevalue",
+		marshalTxn: func(t Transaction) (map[string]types.AttributeValue, error) { return attributevalue.MarshalMap(t) },
+		marshalLeg: func(l TransactionLeg) (map[string]types.AttributeValue, error) { return attributevalue.MarshalMap(l) },
+		unmarshalTxn: func(item map[string]types.AttributeValue) (Transaction, error) {
+			var t Transaction
+			err := attributevalue.UnmarshalMap(item, &t)
+			return t, err
+		},
+		unmarshalLeg: func(item map[string]types.AttributeValue) (TransactionLeg, error) {
+			var l TransactionLeg
+			err := attributevalue.UnmarshalMap(item, &l)
+			return l, err
+		},
+	},
+	{
+		name:         "hand-written",
+		marshalTxn:   marshalTransaction,
+		marshalLeg:   marshalLeg,
+		unmarshalTxn: unmarshalTransaction,
+		unmarshalLeg: unmarshalLeg,
+	},
+}
+
+// sampleTransaction is a transaction and its two legs shaped like the
+// seeded ones, with fresh IDs.
+func sampleTransaction() (Transaction, []TransactionLeg) {
+	txnID := uuid.New().String()
+	createdAt := time.Now()
+	created := createdAt.Format(time.RFC3339Nano)
+	merchantID := uuid.New().String()
+	txn := Transaction{
+		PK:              fmt.Sprintf("TXN#%s", txnID),
+		SK:              "METADATA",
+		GSI1PK:          "STATUS#completed",
+		GSI1SK:          fmt.Sprintf("CREATED#%s", created),
+		GSI2PK:          fmt.Sprintf("IDEMPOTENCY#%s", uuid.New().String()),
+		GSI2SK:          "TXN",
+		GSI3PK:          fmt.Sprintf("MERCHANT#%s", merchantID),
+		GSI3SK:          fmt.Sprintf("CREATED#%s", created),
+		Type:            "Transaction",
+		ID:              txnID,
+		IdempotencyKey:  uuid.New().String(),
+		TransactionType: "payment",
+		Status:          "completed",
+		MerchantID:      merchantID,
+		Description:     "Benchmark transaction",
+		CreatedAt:       createdAt,
+		UpdatedAt:       createdAt,
+		CompletedAt:     createdAt,
+	}
+
+	amount := Decimal{decimal.RequireFromString("123.4567")}
+	legs := make([]TransactionLeg, 0, 2)
+	for _, legType := range []string{"debit", "credit"} {
+		accountID := uuid.New().String()
+		legs = append(legs, TransactionLeg{
+			PK:            txn.PK,
+			SK:            fmt.Sprintf("LEG#%s", uuid.New().String()),
+			GSI1PK:        fmt.Sprintf("ACCOUNT#%s", accountID),
+			GSI1SK:        fmt.Sprintf("LEG#%s#%s", created, txnID),
+			Type:          "TransactionLeg",
+			ID:            uuid.New().String(),
+			TransactionID: txnID,
+			AccountID:     accountID,
+			LegType:       legType,
+			Amount:        amount,
+			Currency:      "USD",
+			BalanceAfter:  Decimal{decimal.RequireFromString("5000.0000")},
+			CreatedAt:     createdAt,
+		})
+	}
+	return txn, legs
+}
+
+// marshalSample marshals a transaction and its legs into the items written
+// together.
+func marshalSample(m marshaller, txn Transaction, legs []TransactionLeg) ([]map[string]types.AttributeValue, error) {
+	items := make([]map[string]types.AttributeValue, 0, 1+len(legs))
+	item, err := m.marshalTxn(txn)
+	if err != nil {
+		return nil, err
+	}
+	items = append(items, item)
+	for _, leg := range legs {
+		item, err := m.marshalLeg(leg)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// unmarshalItems unmarshals a transaction's item collection, telling the
+// header from the legs by sort key.
+func unmarshalItems(m marshaller, items []map[string]types.AttributeValue) (Transaction, []TransactionLeg, error) {
+	var txn Transaction
+	legs := make([]TransactionLeg, 0, len(items))
+	for _, item := range items {
+		sk, _ := item["SK"].(*types.AttributeValueMemberS)
+		if sk != nil && strings.HasPrefix(sk.Value, "LEG#") {
+			leg, err := m.unmarshalLeg(item)
+			if err != nil {
+				return txn, nil, err
+			}
+			legs = append(legs, leg)
+			continue
+		}
+		var err error
+		if txn, err = m.unmarshalTxn(item); err != nil {
+			return txn, nil, err
+		}
+	}
+	return txn, legs, nil
+}
+
+func benchmarkWriteTransactionItems(count int) benchmark.Result {
+	testName := "TransactWriteItems - transaction + 2 legs, marshalled (end to end)"
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
+
+	durations := make([]time.Duration, 0, count)
+	successCount := 0
+	errorCount := 0
+	totalWCU := 0.0
+
+	write := func() (*dynamodb.TransactWriteItemsOutput, error) {
+		txn, legs := sampleTransaction()
+		items, err := marshalSample(marshallers[0], txn, legs)
+		if err != nil {
+			return nil, err
+		}
+		puts := make([]types.TransactWriteItem, 0, len(items))
+		for _, item := range items {
+			item["BenchmarkRunID"] = &types.AttributeValueMemberS{Value: benchmark.RunID}
+			puts = append(puts, types.TransactWriteItem{Put: &types.Put{TableName: aws.String(connection.DynamoDBTable), Item: item}})
+		}
+		return client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems:          puts,
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+		})
+	}
+	benchmark.WarmUp(1, func(*rand.Rand) error { _, err := write(); return err })
+
+	start := time.Now()
+
+	for i := 0; i < count; i++ {
+		opStart := time.Now()
+		output, err := write()
+		duration := time.Since(opStart)
+		durations = append(durations, duration)
+
+		if err != nil {
+			errorCount++
+		} else {
+			successCount++
+			for _, cc := range output.ConsumedCapacity {
+				if cc.CapacityUnits != nil {
+					totalWCU += *cc.CapacityUnits
+				}
+			}
+		}
+	}
+
+	totalDuration := time.Since(start)
+	return calculateWriteResults(testName, count, 1, durations, successCount, errorCount, totalDuration, totalWCU)
+}
+
+func benchmarkReadTransactionItems(count int) benchmark.Result {
+	testName := "Query - transaction + legs, unmarshalled (end to end)"
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
+
+	if len(transactionIDs) == 0 {
+		slog.Warn("No transactions loaded")
+		return benchmark.Result{TestName: testName, Database: "DynamoDB", ErrorCount: count}
+	}
+
+	durations := make([]time.Duration, 0, count)
+	successCount := 0
+	errorCount := 0
+	totalRCU := 0.0
+	itemsReturned := 0
+
+	read := func(rng *rand.Rand) (*dynamodb.QueryOutput, error) {
+		txnID := transactionIDs[benchmark.Pick(rng, len(transactionIDs))]
+
+		output, err := client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(connection.DynamoDBTable),
+			KeyConditionExpression: aws.String("PK = :pk"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("TXN#%s", txnID)},
+			},
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+		})
+		if err != nil {
+			return nil, err
+		}
+		_, _, err = unmarshalItems(marshallers[0], output.Items)
+		return output, err
+	}
+	benchmark.WarmUp(1, func(rng *rand.Rand) error { _, err := read(rng); return err })
+
+	start := time.Now()
+
+	for i := 0; i < count; i++ {
+		opStart := time.Now()
+		output, err := read(benchmark.Rand())
+		duration := time.Since(opStart)
+		durations = append(durations, duration)
+
+		if err != nil {
+			errorCount++
+		} else {
+			successCount++
+			itemsReturned += len(output.Items)
+			if output.ConsumedCapacity != nil {
+				totalRCU += *output.ConsumedCapacity.CapacityUnits
+			}
+		}
+	}
+
+	totalDuration := time.Since(start)
+	return calculateReadResults(testName, count, 1, durations, successCount, errorCount, totalDuration, totalRCU, itemsReturned)
+}
+
+func benchmarkMarshal(m marshaller, count int, endToEnd time.Duration) benchmark.Result {
+	testName := fmt.Sprintf("Marshal transaction + 2 legs (%s)", m.name)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
+
+	txn, legs := sampleTransaction()
+	durations := make([]time.Duration, 0, count)
+	successCount := 0
+	errorCount := 0
+
+	// Both marshallers must produce the same items, or the comparison
+	// means nothing.
+	want, _ := marshalSample(marshallers[0], txn, legs)
+	if got, err := marshalSample(m, txn, legs); err != nil || !reflect.DeepEqual(got, want) {
+		slog.Warn("Marshaller's items differ from attributevalue's", "marshaller", m.name, "err", err)
+	}
+
+	start := time.Now()
+
+	for i := 0; i < count; i++ {
+		opStart := time.Now()
+		_, err := marshalSample(m, txn, legs)
+		durations = append(durations, time.Since(opStart))
+
+		if err != nil {
+			errorCount++
+		} else {
+			successCount++
+		}
+	}
+
+	totalDuration := time.Since(start)
+	result := benchmark.Summarize(testName, "DynamoDB", count, 1, durations, successCount, errorCount, totalDuration)
+	// The histogram rounds each sample to whole microseconds, about the
+	// size of the samples themselves, so the mean comes from the loop.
+	result.AverageDuration = totalDuration / time.Duration(count)
+	result.EndToEndShare = endToEndShare(result.AverageDuration, endToEnd)
+	return result
+}
+
+func benchmarkUnmarshal(m marshaller, count int, endToEnd time.Duration) benchmark.Result {
+	testName := fmt.Sprintf("Unmarshal transaction + 2 legs (%s)", m.name)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
+
+	txn, legs := sampleTransaction()
+	items, err := marshalSample(marshallers[0], txn, legs)
+	if err != nil {
+		slog.Warn("Failed to marshal sample transaction", "err", err)
+		return benchmark.Result{TestName: testName, Database: "DynamoDB", ErrorCount: count}
+	}
+	durations := make([]time.Duration, 0, count)
+	successCount := 0
+	errorCount := 0
+
+	if gotTxn, gotLegs, err := unmarshalItems(m, items); err != nil || !sameTransaction(gotTxn, gotLegs, txn, legs) {
+		slog.Warn("Marshaller does not round-trip the sample transaction", "marshaller", m.name, "err", err)
+	}
+
+	start := time.Now()
+
+	for i := 0; i < count; i++ {
+		opStart := time.Now()
+		_, _, err := unmarshalItems(m, items)
+		durations = append(durations, time.Since(opStart))
+
+		if err != nil {
+			errorCount++
+		} else {
+			successCount++
+		}
+	}
+
+	totalDuration := time.Since(start)
+	result := benchmark.Summarize(testName, "DynamoDB", count, 1, durations, successCount, errorCount, totalDuration)
+	// The histogram rounds each sample to whole microseconds, about the
+	// size of the samples themselves, so the mean comes from the loop.
+	result.AverageDuration = totalDuration / time.Duration(count)
+	result.ItemsReturned = successCount * len(items)
+	result.EndToEndShare = endToEndShare(result.AverageDuration, endToEnd)
+	return result
+}
+
+// endToEndShare is part as a percentage of whole, or 0 when the end-to-end
+// test could not run.
+func endToEndShare(part, whole time.Duration) float64 {
+	if whole <= 0 {
+		return 0
+	}
+	return float64(part) / float64(whole) * 100
+}
+
+// sameTransaction compares unmarshalled values with the originals. Times
+// and decimals are compared by value: a round trip drops the monotonic
+// clock reading and may change a decimal's exponent.
+func sameTransaction(gotTxn Transaction, gotLegs []TransactionLeg, txn Transaction, legs []TransactionLeg) bool {
+	if len(gotLegs) != len(legs) || !gotTxn.CreatedAt.Equal(txn.CreatedAt) || gotTxn.ID != txn.ID || gotTxn.GSI3SK != txn.GSI3SK {
+		return false
+	}
+	for i, leg := range legs {
+		if gotLegs[i].SK != leg.SK || !gotLegs[i].Amount.Equal(leg.Amount.Decimal) || !gotLegs[i].BalanceAfter.Equal(leg.BalanceAfter.Decimal) {
+			return false
+		}
+	}
+	return true
+}
+
+// The hand-written marshaller builds and reads the items field by field,
+// with no reflection. It produces exactly what attributevalue does: times
+// as RFC 3339 strings with nanoseconds, decimals as numbers.
+
+func marshalTransaction(t Transaction) (map[string]types.AttributeValue, error) {
+	return map[string]types.AttributeValue{
+		"PK":              &types.AttributeValueMemberS{Value: t.PK},
+		"SK":              &types.AttributeValueMemberS{Value: t.SK},
+		"GSI1PK":          &types.AttributeValueMemberS{Value: t.GSI1PK},
+		"GSI1SK":          &types.AttributeValueMemberS{Value: t.GSI1SK},
+		"GSI2PK":          &types.AttributeValueMemberS{Value: t.GSI2PK},
+		"GSI2SK":          &types.AttributeValueMemberS{Value: t.GSI2SK},
+		"GSI3PK":          &types.AttributeValueMemberS{Value: t.GSI3PK},
+		"GSI3SK":          &types.AttributeValueMemberS{Value: t.GSI3SK},
+		"Type":            &types.AttributeValueMemberS{Value: t.Type},
+		"ID":              &types.AttributeValueMemberS{Value: t.ID},
+		"IdempotencyKey":  &types.AttributeValueMemberS{Value: t.IdempotencyKey},
+		"TransactionType": &types.AttributeValueMemberS{Value: t.TransactionType},
+		"Status":          &types.AttributeValueMemberS{Value: t.Status},
+		"MerchantID":      &types.AttributeValueMemberS{Value: t.MerchantID},
+		"Description":     &types.AttributeValueMemberS{Value: t.Description},
+		"CreatedAt":       &types.AttributeValueMemberS{Value: t.CreatedAt.Format(time.RFC3339Nano)},
+		"UpdatedAt":       &types.AttributeValueMemberS{Value: t.UpdatedAt.Format(time.RFC3339Nano)},
+		"CompletedAt":     &types.AttributeValueMemberS{Value: t.CompletedAt.Format(time.RFC3339Nano)},
+	}, nil
+}
+
+func marshalLeg(l TransactionLeg) (map[string]types.AttributeValue, error) {
+	return map[string]types.AttributeValue{
+		"PK":            &types.AttributeValueMemberS{Value: l.PK},
+		"SK":            &types.AttributeValueMemberS{Value: l.SK},
+		"GSI1PK":        &types.AttributeValueMemberS{Value: l.GSI1PK},
+		"GSI1SK":        &types.AttributeValueMemberS{Value: l.GSI1SK},
+		"Type":          &types.AttributeValueMemberS{Value: l.Type},
+		"ID":            &types.AttributeValueMemberS{Value: l.ID},
+		"TransactionID": &types.AttributeValueMemberS{Value: l.TransactionID},
+		"AccountID":     &types.AttributeValueMemberS{Value: l.AccountID},
+		"LegType":       &types.AttributeValueMemberS{Value: l.LegType},
+		"Amount":        &types.AttributeValueMemberN{Value: l.Amount.String()},
+		"Currency":      &types.AttributeValueMemberS{Value: l.Currency},
+		"BalanceAfter":  &types.AttributeValueMemberN{Value: l.BalanceAfter.String()},
+		"CreatedAt":     &types.AttributeValueMemberS{Value: l.CreatedAt.Format(time.RFC3339Nano)},
+	}, nil
+}
+
+func unmarshalTransaction(item map[string]types.AttributeValue) (Transaction, error) {
+	r := itemReader{item: item}
+	t := Transaction{
+		PK:              r.str("PK"),
+		SK:              r.str("SK"),
+		GSI1PK:          r.str("GSI1PK"),
+		GSI1SK:          r.str("GSI1SK"),
+		GSI2PK:          r.str("GSI2PK"),
+		GSI2SK:          r.str("GSI2SK"),
+		GSI3PK:          r.str("GSI3PK"),
+		GSI3SK:          r.str("GSI3SK"),
+		Type:            r.str("Type"),
+		ID:              r.str("ID"),
+		IdempotencyKey:  r.str("IdempotencyKey"),
+		TransactionType: r.str("TransactionType"),
+		Status:          r.str("Status"),
+		MerchantID:      r.str("MerchantID"),
+		Description:     r.str("Description"),
+		CreatedAt:       r.time("CreatedAt"),
+		UpdatedAt:       r.time("UpdatedAt"),
+		CompletedAt:     r.time("CompletedAt"),
+	}
+	return t, r.err
+}
+
+func unmarshalLeg(item map[string]types.AttributeValue) (TransactionLeg, error) {
+	r := itemReader{item: item}
+	l := TransactionLeg{
+		PK:            r.str("PK"),
+		SK:            r.str("SK"),
+		GSI1PK:        r.str("GSI1PK"),
+		GSI1SK:        r.str("GSI1SK"),
+		Type:          r.str("Type"),
+		ID:            r.str("ID"),
+		TransactionID: r.str("TransactionID"),
+		AccountID:     r.str("AccountID"),
+		LegType:       r.str("LegType"),
+		Amount:        r.decimal("Amount"),
+		Currency:      r.str("Currency"),
+		BalanceAfter:  r.decimal("BalanceAfter"),
+		CreatedAt:     r.time("CreatedAt"),
+	}
+	return l, r.err
+}
+
+// itemReader reads typed attributes from an item, keeping the first error.
+// Missing attributes are left at their zero value, as attributevalue does.
+type itemReader struct {
+	item map[string]types.AttributeValue
+	err  error
+}
+
+func (r *itemReader) str(name string) string {
+	switch v := r.item[name].(type) {
+	case nil:
+		return ""
+	case *types.AttributeValueMemberS:
+		return v.Value
+	default:
+		r.fail(name, v)
+		return ""
+	}
+}
+
+func (r *itemReader) time(name string) time.Time {
+	s := r.str(name)
+	if s == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil && r.err == nil {
+		r.err = fmt.Errorf("%s: %w", name, err)
+	}
+	return t
+}
+
+func (r *itemReader) decimal(name string) Decimal {
+	switch v := r.item[name].(type) {
+	case nil:
+		return Decimal{}
+	case *types.AttributeValueMemberN:
+		d, err := decimal.NewFromString(v.Value)
+		if err != nil && r.err == nil {
+			r.err = fmt.Errorf("%s: %w", name, err)
+		}
+		return Decimal{d}
+	default:
+		r.fail(name, v)
+		return Decimal{}
+	}
+}
+
+func (r *itemReader) fail(name string, v types.AttributeValue) {
+	if r.err == nil {
+		r.err = fmt.Errorf("%s: unexpected %T", name, v)
+	}
+}
@@ -0,0 +1,76 @@
+package dynamodb
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+)
+
+const (
+	// rampStepDuration is how long each worker count is sustained.
+	rampStepDuration = 30 * time.Second
+)
+
+var (
+	// rampConcurrency is the worker counts the ramp steps through, the
+	// same as the PostgreSQL ramp's.
+	rampConcurrency = []int{1, 5, 10, 25, 50, 100, 200}
+)
+
+// rampOp is one operation the ramp is run for.
+type rampOp struct {
+	name string
+	op   func(rng *rand.Rand) error
+}
+
+var rampOps = []rampOp{
+	{"Point Reads", func(rng *rand.Rand) error {
+		_, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName: aws.String(connection.DynamoDBTable),
+			Key: map[string]types.AttributeValue{
+				"PK": &types.AttributeValueMemberS{Value: fmt.Sprintf("TXN#%s", transactionIDs[benchmark.Pick(rng, len(transactionIDs))])},
+				"SK": &types.AttributeValueMemberS{Value: "METADATA"},
+			},
+		})
+		return err
+	}},
+	{"Single Inserts", func(rng *rand.Rand) error { _, err := writeSingleTransaction(rng); return err }},
+}
+
+// runRamp steps each operation through rising worker counts, sustaining
+// each for a set time, and logs the knee past which more workers stopped
+// raising throughput, where the SDK's transport or DynamoDB Local
+// saturates.
+func runRamp(opts benchmark.Options) {
+	connect()
+	loadTestData()
+
+	suite := benchmark.NewSuite("dynamodb-ramp")
+
+	slog.Info("Running Step-Load Concurrency Ramp Benchmarks")
+
+	for _, op := range rampOps {
+		var ramp benchmark.Ramp
+		run := benchmark.RetryingRand(op.op)
+		for _, workers := range opts.ConcurrencyLevels(rampConcurrency...) {
+			suite.Run(func() benchmark.Result {
+				testName := fmt.Sprintf("Step Load - %s (%d workers)", op.name, workers)
+				result := benchmark.Sustained(testName, "DynamoDB", workers, opts.Step(rampStepDuration), run)
+				result.Layout = op.name
+				return ramp.Add(result)
+			})
+		}
+		slog.Info("Throughput knee", "op", op.name, "workers", ramp.Knee.Concurrency,
+			"ops_per_sec", ramp.Knee.OperationsPerSec, "p99", ramp.Knee.P99Duration, "within_ramp", ramp.Saturated())
+	}
+
+	benchmark.Save(suite, "dynamodb-ramp")
+	benchmark.PrintSummary(suite)
+}
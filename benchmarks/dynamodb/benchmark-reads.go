@@ -1,197 +1,111 @@
-package main
+package dynamodb
 
 import (
-	"context"
-	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/rand"
-	"os"
 	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/dataset"
 )
 
-type BenchmarkResult struct {
-	TestName          string        `json:"test_name"`
-	Database          string        `json:"database"`
-	NumOperations     int           `json:"num_operations"`
-	Concurrency       int           `json:"concurrency"`
-	TotalDuration     time.Duration `json:"total_duration_ms"`
-	AverageDuration   time.Duration `json:"avg_duration_ms"`
-	MedianDuration    time.Duration `json:"median_duration_ms"`
-	P95Duration       time.Duration `json:"p95_duration_ms"`
-	P99Duration       time.Duration `json:"p99_duration_ms"`
-	OperationsPerSec  float64       `json:"operations_per_sec"`
-	SuccessCount      int           `json:"success_count"`
-	ErrorCount        int           `json:"error_count"`
-	ConsumedRCU       float64       `json:"consumed_rcu"`
-	ItemsReturned     int           `json:"items_returned"`
-	Timestamp         time.Time     `json:"timestamp"`
-}
-
-type BenchmarkSuite struct {
-	Results []BenchmarkResult `json:"results"`
-}
-
-var (
-	client         *dynamodb.Client
-	ctx            = context.Background()
-	accountIDs     []string
-	transactionIDs []string
-)
-
-func main() {
-	cfg, err := config.LoadDefaultConfig(ctx,
-		config.WithRegion("us-east-1"),
-		config.WithEndpointResolverWithOptions(aws.EndpointResolverWithOptionsFunc(
-			func(service, region string, options ...interface{}) (aws.Endpoint, error) {
-				return aws.Endpoint{URL: "http://localhost:8000"}, nil
-			})),
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("local", "local", "")),
-	)
-	if err != nil {
-		log.Fatal("Failed to load config:", err)
-	}
-
-	client = dynamodb.NewFromConfig(cfg)
-	log.Println("Connected to DynamoDB Local")
+func runReads(opts benchmark.Options) {
+	connect()
 
 	loadTestData()
 
-	suite := BenchmarkSuite{Results: make([]BenchmarkResult, 0)}
+	suite := benchmark.NewSuite("dynamodb-read")
 
-	log.Println("\n=== Running DynamoDB Read Performance Benchmarks ===\n")
+	slog.Info("Running DynamoDB Read Performance Benchmarks")
 
 	// Point lookups
-	suite.Results = append(suite.Results, benchmarkGetItem(1000, "transaction"))
-	suite.Results = append(suite.Results, benchmarkGetItem(1000, "account"))
-
-	// Batch reads
-	suite.Results = append(suite.Results, benchmarkBatchGetItem(100, 10))
-	suite.Results = append(suite.Results, benchmarkBatchGetItem(100, 25))
+	suite.Run(func() benchmark.Result { return benchmarkGetItem(opts.Ops(1000), "transaction") })
+	suite.Run(func() benchmark.Result { return benchmarkGetItem(opts.Ops(1000), "account") })
 
-	// Query operations
-	suite.Results = append(suite.Results, benchmarkQueryByStatus(100, 24))   // Last 24 hours
-	suite.Results = append(suite.Results, benchmarkQueryByStatus(100, 720))  // Last 30 days
-	suite.Results = append(suite.Results, benchmarkQueryAccountHistory(100, 100))
-
-	// Concurrent reads
-	suite.Results = append(suite.Results, benchmarkConcurrentReads(1000, 10))
-	suite.Results = append(suite.Results, benchmarkConcurrentReads(1000, 50))
-	suite.Results = append(suite.Results, benchmarkConcurrentReads(1000, 100))
-
-	// Strongly consistent vs eventually consistent
-	suite.Results = append(suite.Results, benchmarkConsistencyComparison(500))
+	// Header and legs together from the transaction's item collection
+	suite.Run(func() benchmark.Result { return benchmarkTransactionWithLegs(opts.Ops(1000)) })
 
-	saveResults(suite, "benchmarks/results/dynamodb-read-results.json")
-	printSummary(suite)
-}
-
-func loadTestData() {
-	log.Println("Loading test data from DynamoDB...")
-
-	// Scan for accounts
-	output, err := client.Scan(ctx, &dynamodb.ScanInput{
-		TableName:        aws.String("FinancialTransactions"),
-		FilterExpression: aws.String("#t = :type"),
-		ExpressionAttributeNames: map[string]string{
-			"#t": "Type",
-		},
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":type": &types.AttributeValueMemberS{Value: "Account"},
-		},
-		Limit: aws.Int32(100),
-	})
+	// Batch reads, the same number of items split into batches of each size
+	items := opts.Ops(1000)
+	for _, size := range opts.BatchSizes(10, 25) {
+		suite.Run(func() benchmark.Result { return benchmarkBatchGetItem(benchmark.Batches(items, size), size) })
+	}
 
-	if err == nil {
-		for _, item := range output.Items {
-			if id, ok := item["ID"].(*types.AttributeValueMemberS); ok {
-				accountIDs = append(accountIDs, id.Value)
-			}
-		}
+	// Query operations
+	suite.Run(func() benchmark.Result { return benchmarkQueryByStatus(opts.Ops(100), 24, opts.RowLimit(100)) })  // Last 24 hours
+	suite.Run(func() benchmark.Result { return benchmarkQueryByStatus(opts.Ops(100), 720, opts.RowLimit(100)) }) // Last 30 days
+	for _, status := range dataset.Statuses {
+		suite.Run(func() benchmark.Result { return benchmarkLatestByStatus(opts.Ops(100), status, opts.RowLimit(100)) })
 	}
+	suite.Run(func() benchmark.Result { return benchmarkQueryAccountHistory(opts.Ops(100), opts.RowLimit(100)) })
+	suite.Run(func() benchmark.Result { return benchmarkQueryByMerchant(opts.Ops(100), 7) })  // Last 7 days
+	suite.Run(func() benchmark.Result { return benchmarkQueryByMerchant(opts.Ops(100), 30) }) // Last 30 days
 
-	// Scan for transactions
-	output, err = client.Scan(ctx, &dynamodb.ScanInput{
-		TableName:        aws.String("FinancialTransactions"),
-		FilterExpression: aws.String("#t = :type"),
-		ExpressionAttributeNames: map[string]string{
-			"#t": "Type",
-		},
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":type": &types.AttributeValueMemberS{Value: "Transaction"},
-		},
-		Limit: aws.Int32(1000),
-	})
+	// User home screen: GSI1 Query for accounts, then fan out per account
+	suite.Run(func() benchmark.Result { return benchmarkUserDashboard(opts.Ops(100), 10) })
 
-	if err == nil {
-		for _, item := range output.Items {
-			if id, ok := item["ID"].(*types.AttributeValueMemberS); ok {
-				transactionIDs = append(transactionIDs, id.Value)
-			}
-		}
+	// Concurrent reads
+	for _, concurrency := range opts.ConcurrencyLevels(10, 50, 100) {
+		suite.Run(func() benchmark.Result { return benchmarkConcurrentReads(opts.Ops(1000), concurrency) })
 	}
 
-	log.Printf("Loaded %d accounts and %d transactions", len(accountIDs), len(transactionIDs))
+	// Strongly consistent vs eventually consistent
+	suite.Run(func() benchmark.Result { return benchmarkConsistencyComparison(opts.Ops(500)) })
 
-	// Validate that we have test data
-	if len(accountIDs) == 0 || len(transactionIDs) == 0 {
-		log.Fatal("\n❌ ERROR: No test data found in DynamoDB!\n\n" +
-			"The table is empty. Please seed data first:\n" +
-			"  1. Run: make seed-dynamodb\n" +
-			"  2. Or: go run benchmarks/dynamodb/seed-data.go\n\n" +
-			"This will create merchants, accounts, and transactions for benchmarking.\n")
-	}
+	benchmark.Save(suite, "dynamodb-read")
+	benchmark.PrintSummary(suite)
 }
 
-func benchmarkGetItem(count int, entityType string) BenchmarkResult {
+func benchmarkGetItem(count int, entityType string) benchmark.Result {
 	testName := fmt.Sprintf("GetItem - %s by ID", entityType)
-	log.Printf("Benchmarking %s (%d operations)...", testName, count)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
+
+	if (entityType == "transaction" && len(transactionIDs) == 0) || (entityType != "transaction" && len(accountIDs) == 0) {
+		slog.Warn("No IDs loaded", "entity", entityType)
+		return benchmark.Result{TestName: testName, Database: "DynamoDB", ErrorCount: count}
+	}
 
 	durations := make([]time.Duration, 0, count)
 	successCount := 0
 	errorCount := 0
 	totalRCU := 0.0
 	itemsReturned := 0
-	start := time.Now()
-
-	for i := 0; i < count; i++ {
-		opStart := time.Now()
 
+	get := func(rng *rand.Rand) (*dynamodb.GetItemOutput, error) {
 		var pk, sk string
 		if entityType == "transaction" {
-			if len(transactionIDs) == 0 {
-				errorCount++
-				continue
-			}
-			txnID := transactionIDs[rand.Intn(len(transactionIDs))]
+			txnID := transactionIDs[benchmark.Pick(rng, len(transactionIDs))]
 			pk = fmt.Sprintf("TXN#%s", txnID)
 			sk = "METADATA"
 		} else {
-			if len(accountIDs) == 0 {
-				errorCount++
-				continue
-			}
-			accountID := accountIDs[rand.Intn(len(accountIDs))]
+			accountID := accountIDs[benchmark.Pick(rng, len(accountIDs))]
 			pk = fmt.Sprintf("ACCOUNT#%s", accountID)
 			sk = "METADATA"
 		}
 
-		output, err := client.GetItem(ctx, &dynamodb.GetItemInput{
-			TableName: aws.String("FinancialTransactions"),
+		return client.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName: aws.String(connection.DynamoDBTable),
 			Key: map[string]types.AttributeValue{
 				"PK": &types.AttributeValueMemberS{Value: pk},
 				"SK": &types.AttributeValueMemberS{Value: sk},
 			},
 			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
 		})
+	}
+	benchmark.WarmUp(1, func(rng *rand.Rand) error { _, err := get(rng); return err })
+
+	start := time.Now()
 
+	for i := 0; i < count; i++ {
+		opStart := time.Now()
+		output, err := get(benchmark.Rand())
 		duration := time.Since(opStart)
 		durations = append(durations, duration)
 
@@ -209,47 +123,113 @@ func benchmarkGetItem(count int, entityType string) BenchmarkResult {
 	}
 
 	totalDuration := time.Since(start)
-	return calculateResults(testName, count, 1, durations, successCount, errorCount, totalDuration, totalRCU, itemsReturned)
+	return calculateReadResults(testName, count, 1, durations, successCount, errorCount, totalDuration, totalRCU, itemsReturned)
 }
 
-func benchmarkBatchGetItem(numBatches, batchSize int) BenchmarkResult {
-	testName := fmt.Sprintf("BatchGetItem (%d batches of %d)", numBatches, batchSize)
-	log.Printf("Benchmarking %s...", testName, numBatches, batchSize)
+// benchmarkTransactionWithLegs reads a whole transaction, its METADATA item
+// and every LEG# item, with one Query on TXN#<id>. This is what the item
+// collection is for: the GetItem test above returns only the header, and
+// PostgreSQL needs a join for the same answer.
+func benchmarkTransactionWithLegs(count int) benchmark.Result {
+	testName := "Transaction + Legs by ID (one Query on PK)"
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
 
-	if len(transactionIDs) < batchSize {
-		log.Printf("Warning: Not enough transactions loaded for batch size %d", batchSize)
-		return BenchmarkResult{TestName: testName, Database: "DynamoDB", ErrorCount: numBatches}
+	if len(transactionIDs) == 0 {
+		slog.Warn("No transactions loaded")
+		return benchmark.Result{TestName: testName, Database: "DynamoDB", ErrorCount: count}
 	}
 
-	durations := make([]time.Duration, 0, numBatches)
+	durations := make([]time.Duration, 0, count)
 	successCount := 0
 	errorCount := 0
 	totalRCU := 0.0
 	itemsReturned := 0
+
+	query := func(rng *rand.Rand) (*dynamodb.QueryOutput, error) {
+		txnID := transactionIDs[benchmark.Pick(rng, len(transactionIDs))]
+
+		return client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(connection.DynamoDBTable),
+			KeyConditionExpression: aws.String("PK = :pk"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("TXN#%s", txnID)},
+			},
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+		})
+	}
+	benchmark.WarmUp(1, func(rng *rand.Rand) error { _, err := query(rng); return err })
+
 	start := time.Now()
 
-	for i := 0; i < numBatches; i++ {
+	for i := 0; i < count; i++ {
 		opStart := time.Now()
+		output, err := query(benchmark.Rand())
+		duration := time.Since(opStart)
+		durations = append(durations, duration)
 
+		if err != nil {
+			errorCount++
+		} else {
+			successCount++
+			itemsReturned += len(output.Items)
+			if output.ConsumedCapacity != nil {
+				totalRCU += *output.ConsumedCapacity.CapacityUnits
+			}
+		}
+	}
+
+	totalDuration := time.Since(start)
+	return calculateReadResults(testName, count, 1, durations, successCount, errorCount, totalDuration, totalRCU, itemsReturned)
+}
+
+func benchmarkBatchGetItem(numBatches, batchSize int) benchmark.Result {
+	testName := fmt.Sprintf("BatchGetItem (%d batches of %d)", numBatches, batchSize)
+	slog.Info("Benchmarking", "test", testName)
+	benchmark.StartTest(testName)
+
+	if len(transactionIDs) < batchSize {
+		slog.Warn("Not enough transactions loaded for batch size", "batch_size", batchSize)
+		return benchmark.Result{TestName: testName, Database: "DynamoDB", ErrorCount: numBatches}
+	}
+	if batchSize > 100 {
+		slog.Warn("BatchGetItem accepts at most 100 keys, skipping batch size", "batch_size", batchSize)
+		return benchmark.Result{TestName: testName, Database: "DynamoDB", ErrorCount: numBatches}
+	}
+
+	durations := make([]time.Duration, 0, numBatches)
+	successCount := 0
+	errorCount := 0
+	totalRCU := 0.0
+	itemsReturned := 0
+
+	batchGet := func(rng *rand.Rand) (*dynamodb.BatchGetItemOutput, error) {
 		// Build batch request
 		keys := make([]map[string]types.AttributeValue, 0, batchSize)
 		for j := 0; j < batchSize; j++ {
-			txnID := transactionIDs[rand.Intn(len(transactionIDs))]
+			txnID := transactionIDs[benchmark.Pick(rng, len(transactionIDs))]
 			keys = append(keys, map[string]types.AttributeValue{
 				"PK": &types.AttributeValueMemberS{Value: fmt.Sprintf("TXN#%s", txnID)},
 				"SK": &types.AttributeValueMemberS{Value: "METADATA"},
 			})
 		}
 
-		output, err := client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+		return client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
 			RequestItems: map[string]types.KeysAndAttributes{
-				"FinancialTransactions": {
+				connection.DynamoDBTable: {
 					Keys: keys,
 				},
 			},
 			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
 		})
+	}
+	benchmark.WarmUp(1, func(rng *rand.Rand) error { _, err := batchGet(rng); return err })
 
+	start := time.Now()
+
+	for i := 0; i < numBatches; i++ {
+		opStart := time.Now()
+		output, err := batchGet(benchmark.Rand())
 		duration := time.Since(opStart)
 		durations = append(durations, duration)
 
@@ -257,7 +237,7 @@ func benchmarkBatchGetItem(numBatches, batchSize int) BenchmarkResult {
 			errorCount++
 		} else {
 			successCount++
-			if items, ok := output.Responses["FinancialTransactions"]; ok {
+			if items, ok := output.Responses[connection.DynamoDBTable]; ok {
 				itemsReturned += len(items)
 			}
 			if len(output.ConsumedCapacity) > 0 {
@@ -271,38 +251,43 @@ func benchmarkBatchGetItem(numBatches, batchSize int) BenchmarkResult {
 	}
 
 	totalDuration := time.Since(start)
-	return calculateResults(testName, numBatches*batchSize, 1, durations, successCount*batchSize, errorCount, totalDuration, totalRCU, itemsReturned)
+	return calculateReadResults(testName, numBatches*batchSize, 1, durations, successCount*batchSize, errorCount, totalDuration, totalRCU, itemsReturned)
 }
 
-func benchmarkQueryByStatus(count, hoursBack int) BenchmarkResult {
+func benchmarkQueryByStatus(count, hoursBack, limit int) benchmark.Result {
 	testName := fmt.Sprintf("Query by Status (last %d hours)", hoursBack)
-	log.Printf("Benchmarking %s (%d operations)...", testName, count)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
 
 	durations := make([]time.Duration, 0, count)
 	successCount := 0
 	errorCount := 0
 	totalRCU := 0.0
 	itemsReturned := 0
-	start := time.Now()
 
 	since := time.Now().Add(-time.Duration(hoursBack) * time.Hour)
 	sinceStr := since.Format(time.RFC3339Nano)
 
-	for i := 0; i < count; i++ {
-		opStart := time.Now()
-
-		output, err := client.Query(ctx, &dynamodb.QueryInput{
-			TableName:              aws.String("FinancialTransactions"),
+	query := func() (*dynamodb.QueryOutput, error) {
+		return client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(connection.DynamoDBTable),
 			IndexName:              aws.String("GSI1"),
 			KeyConditionExpression: aws.String("GSI1PK = :status AND GSI1SK >= :since"),
 			ExpressionAttributeValues: map[string]types.AttributeValue{
 				":status": &types.AttributeValueMemberS{Value: "STATUS#completed"},
 				":since":  &types.AttributeValueMemberS{Value: fmt.Sprintf("CREATED#%s", sinceStr)},
 			},
-			Limit:                  aws.Int32(100),
+			Limit:                  aws.Int32(int32(limit)),
 			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
 		})
+	}
+	benchmark.WarmUp(1, func(*rand.Rand) error { _, err := query(); return err })
 
+	start := time.Now()
+
+	for i := 0; i < count; i++ {
+		opStart := time.Now()
+		output, err := query()
 		duration := time.Since(opStart)
 		durations = append(durations, duration)
 
@@ -318,32 +303,82 @@ func benchmarkQueryByStatus(count, hoursBack int) BenchmarkResult {
 	}
 
 	totalDuration := time.Since(start)
-	return calculateResults(testName, count, 1, durations, successCount, errorCount, totalDuration, totalRCU, itemsReturned)
+	return calculateReadResults(testName, count, 1, durations, successCount, errorCount, totalDuration, totalRCU, itemsReturned)
 }
 
-func benchmarkQueryAccountHistory(count, limit int) BenchmarkResult {
-	testName := fmt.Sprintf("Query Account History (last %d items)", limit)
-	log.Printf("Benchmarking %s (%d operations)...", testName, count)
-
-	if len(accountIDs) == 0 {
-		log.Println("Warning: No accounts loaded")
-		return BenchmarkResult{TestName: testName, Database: "DynamoDB", ErrorCount: count}
-	}
+// benchmarkLatestByStatus queries the newest transactions in status from
+// its GSI1 partition, the pending-transactions screen and its failed and
+// reversed counterparts.
+func benchmarkLatestByStatus(count int, status string, limit int) benchmark.Result {
+	testName := fmt.Sprintf("Latest Transactions by Status (%s, %d rows)", status, limit)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
 
 	durations := make([]time.Duration, 0, count)
 	successCount := 0
 	errorCount := 0
 	totalRCU := 0.0
 	itemsReturned := 0
+
+	query := func() (*dynamodb.QueryOutput, error) {
+		return client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(connection.DynamoDBTable),
+			IndexName:              aws.String("GSI1"),
+			KeyConditionExpression: aws.String("GSI1PK = :status"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":status": &types.AttributeValueMemberS{Value: "STATUS#" + status},
+			},
+			ScanIndexForward:       aws.Bool(false),
+			Limit:                  aws.Int32(int32(limit)),
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+		})
+	}
+	benchmark.WarmUp(1, func(*rand.Rand) error { _, err := query(); return err })
+
 	start := time.Now()
 
 	for i := 0; i < count; i++ {
 		opStart := time.Now()
+		output, err := query()
+		duration := time.Since(opStart)
+		durations = append(durations, duration)
+
+		if err != nil {
+			errorCount++
+		} else {
+			successCount++
+			itemsReturned += len(output.Items)
+			if output.ConsumedCapacity != nil {
+				totalRCU += *output.ConsumedCapacity.CapacityUnits
+			}
+		}
+	}
 
-		accountID := accountIDs[rand.Intn(len(accountIDs))]
+	totalDuration := time.Since(start)
+	return calculateReadResults(testName, count, 1, durations, successCount, errorCount, totalDuration, totalRCU, itemsReturned)
+}
+
+func benchmarkQueryAccountHistory(count, limit int) benchmark.Result {
+	testName := fmt.Sprintf("Query Account History (last %d items)", limit)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
 
-		output, err := client.Query(ctx, &dynamodb.QueryInput{
-			TableName:              aws.String("FinancialTransactions"),
+	if len(accountIDs) == 0 {
+		slog.Warn("No accounts loaded")
+		return benchmark.Result{TestName: testName, Database: "DynamoDB", ErrorCount: count}
+	}
+
+	durations := make([]time.Duration, 0, count)
+	successCount := 0
+	errorCount := 0
+	totalRCU := 0.0
+	itemsReturned := 0
+
+	query := func(rng *rand.Rand) (*dynamodb.QueryOutput, error) {
+		accountID := accountIDs[benchmark.Pick(rng, len(accountIDs))]
+
+		return client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(connection.DynamoDBTable),
 			IndexName:              aws.String("GSI1"),
 			KeyConditionExpression: aws.String("GSI1PK = :account AND begins_with(GSI1SK, :prefix)"),
 			ExpressionAttributeValues: map[string]types.AttributeValue{
@@ -354,7 +389,18 @@ func benchmarkQueryAccountHistory(count, limit int) BenchmarkResult {
 			ScanIndexForward:       aws.Bool(false), // Descending order (newest first)
 			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
 		})
+	}
+	benchmark.WarmUp(1, func(rng *rand.Rand) error { _, err := query(rng); return err })
+
+	// Per successful query, for NormalizePerItem.
+	queryDurations := make([]time.Duration, 0, count)
+	sizes := make([]int, 0, count)
 
+	start := time.Now()
+
+	for i := 0; i < count; i++ {
+		opStart := time.Now()
+		output, err := query(benchmark.Rand())
 		duration := time.Since(opStart)
 		durations = append(durations, duration)
 
@@ -363,6 +409,8 @@ func benchmarkQueryAccountHistory(count, limit int) BenchmarkResult {
 		} else {
 			successCount++
 			itemsReturned += len(output.Items)
+			queryDurations = append(queryDurations, duration)
+			sizes = append(sizes, len(output.Items))
 			if output.ConsumedCapacity != nil {
 				totalRCU += *output.ConsumedCapacity.CapacityUnits
 			}
@@ -370,97 +418,297 @@ func benchmarkQueryAccountHistory(count, limit int) BenchmarkResult {
 	}
 
 	totalDuration := time.Since(start)
-	return calculateResults(testName, count, 1, durations, successCount, errorCount, totalDuration, totalRCU, itemsReturned)
+	result := calculateReadResults(testName, count, 1, durations, successCount, errorCount, totalDuration, totalRCU, itemsReturned)
+	result.NormalizePerItem(queryDurations, sizes)
+	return result
 }
 
-func benchmarkConcurrentReads(opsPerGoroutine, numGoroutines int) BenchmarkResult {
-	testName := fmt.Sprintf("Concurrent Reads (%d goroutines, %d ops each)", numGoroutines, opsPerGoroutine)
-	log.Printf("Benchmarking %s...", testName)
+func benchmarkQueryByMerchant(count, daysBack int) benchmark.Result {
+	testName := fmt.Sprintf("Query Merchant Transactions (last %d days)", daysBack)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
 
-	if len(transactionIDs) == 0 {
-		log.Println("Warning: No transactions loaded")
-		return BenchmarkResult{TestName: testName, Database: "DynamoDB", ErrorCount: opsPerGoroutine * numGoroutines}
+	if len(merchantIDs) == 0 {
+		slog.Warn("No merchants loaded")
+		return benchmark.Result{TestName: testName, Database: "DynamoDB", ErrorCount: count}
 	}
 
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-	durations := make([]time.Duration, 0, opsPerGoroutine*numGoroutines)
+	durations := make([]time.Duration, 0, count)
+	successCount := 0
+	errorCount := 0
+	totalRCU := 0.0
+	itemsReturned := 0
+
+	now := time.Now()
+	fromStr := fmt.Sprintf("CREATED#%s", now.Add(-time.Duration(daysBack)*24*time.Hour).Format(time.RFC3339Nano))
+	toStr := fmt.Sprintf("CREATED#%s", now.Format(time.RFC3339Nano))
+
+	query := func(rng *rand.Rand) (*dynamodb.QueryOutput, error) {
+		merchantID := merchantIDs[benchmark.Pick(rng, len(merchantIDs))]
+
+		return client.Query(ctx, &dynamodb.QueryInput{
+			TableName:              aws.String(connection.DynamoDBTable),
+			IndexName:              aws.String("GSI3"),
+			KeyConditionExpression: aws.String("GSI3PK = :merchant AND GSI3SK BETWEEN :from AND :to"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":merchant": &types.AttributeValueMemberS{Value: fmt.Sprintf("MERCHANT#%s", merchantID)},
+				":from":     &types.AttributeValueMemberS{Value: fromStr},
+				":to":       &types.AttributeValueMemberS{Value: toStr},
+			},
+			ScanIndexForward:       aws.Bool(false),
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+		})
+	}
+	benchmark.WarmUp(1, func(rng *rand.Rand) error { _, err := query(rng); return err })
+
+	start := time.Now()
+
+	for i := 0; i < count; i++ {
+		opStart := time.Now()
+		output, err := query(benchmark.Rand())
+		duration := time.Since(opStart)
+		durations = append(durations, duration)
+
+		if err != nil {
+			errorCount++
+		} else {
+			successCount++
+			itemsReturned += len(output.Items)
+			if output.ConsumedCapacity != nil {
+				totalRCU += *output.ConsumedCapacity.CapacityUnits
+			}
+		}
+	}
+
+	totalDuration := time.Since(start)
+	return calculateReadResults(testName, count, 1, durations, successCount, errorCount, totalDuration, totalRCU, itemsReturned)
+}
+
+func benchmarkUserDashboard(count, legsPerAccount int) benchmark.Result {
+	testName := fmt.Sprintf("User Dashboard (profile, accounts, last %d legs per account)", legsPerAccount)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
+
+	if len(userIDs) == 0 {
+		slog.Warn("No users loaded")
+		return benchmark.Result{TestName: testName, Database: "DynamoDB", ErrorCount: count}
+	}
+
+	durations := make([]time.Duration, 0, count)
 	successCount := 0
 	errorCount := 0
 	totalRCU := 0.0
 	itemsReturned := 0
 
+	benchmark.WarmUp(1, func(rng *rand.Rand) error {
+		_, _, err := queryUserDashboard(userIDs[benchmark.Pick(rng, len(userIDs))], legsPerAccount)
+		return err
+	})
+
 	start := time.Now()
 
-	for g := 0; g < numGoroutines; g++ {
+	for i := 0; i < count; i++ {
+		opStart := time.Now()
+
+		userID := userIDs[benchmark.Pick(benchmark.Rand(), len(userIDs))]
+		rcu, items, err := queryUserDashboard(userID, legsPerAccount)
+
+		duration := time.Since(opStart)
+		durations = append(durations, duration)
+
+		if err != nil {
+			errorCount++
+		} else {
+			successCount++
+			itemsReturned += items
+			totalRCU += rcu
+		}
+	}
+
+	totalDuration := time.Since(start)
+	return calculateReadResults(testName, count, 1, durations, successCount, errorCount, totalDuration, totalRCU, itemsReturned)
+}
+
+// queryUserDashboard fetches a user's profile and accounts from their GSI1
+// partition and then issues one account-history Query per account in
+// parallel, which is the DynamoDB equivalent of the PostgreSQL join.
+func queryUserDashboard(userID string, legsPerAccount int) (float64, int, error) {
+	output, err := client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(connection.DynamoDBTable),
+		IndexName:              aws.String("GSI1"),
+		KeyConditionExpression: aws.String("GSI1PK = :user"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":user": &types.AttributeValueMemberS{Value: fmt.Sprintf("USER#%s", userID)},
+		},
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	totalRCU := 0.0
+	if output.ConsumedCapacity != nil {
+		totalRCU += *output.ConsumedCapacity.CapacityUnits
+	}
+	itemsReturned := len(output.Items)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+
+	for _, item := range output.Items {
+		accountID, ok := item["ID"].(*types.AttributeValueMemberS)
+		if !ok || stringAttr(item, "Type") != "Account" {
+			continue
+		}
+
 		wg.Add(1)
-		go func() {
+		go func(accountID string) {
 			defer wg.Done()
-			for i := 0; i < opsPerGoroutine; i++ {
-				opStart := time.Now()
 
-				txnID := transactionIDs[rand.Intn(len(transactionIDs))]
-				output, err := client.GetItem(ctx, &dynamodb.GetItemInput{
-					TableName: aws.String("FinancialTransactions"),
-					Key: map[string]types.AttributeValue{
-						"PK": &types.AttributeValueMemberS{Value: fmt.Sprintf("TXN#%s", txnID)},
-						"SK": &types.AttributeValueMemberS{Value: "METADATA"},
-					},
-					ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
-				})
+			legs, err := client.Query(ctx, &dynamodb.QueryInput{
+				TableName:              aws.String(connection.DynamoDBTable),
+				IndexName:              aws.String("GSI1"),
+				KeyConditionExpression: aws.String("GSI1PK = :account AND begins_with(GSI1SK, :prefix)"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":account": &types.AttributeValueMemberS{Value: fmt.Sprintf("ACCOUNT#%s", accountID)},
+					":prefix":  &types.AttributeValueMemberS{Value: "LEG#"},
+				},
+				Limit:                  aws.Int32(int32(legsPerAccount)),
+				ScanIndexForward:       aws.Bool(false),
+				ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			itemsReturned += len(legs.Items)
+			if legs.ConsumedCapacity != nil {
+				totalRCU += *legs.ConsumedCapacity.CapacityUnits
+			}
+		}(accountID.Value)
+	}
+
+	wg.Wait()
+	return totalRCU, itemsReturned, firstErr
+}
+
+func benchmarkConcurrentReads(opsPerGoroutine, numGoroutines int) benchmark.Result {
+	testName := fmt.Sprintf("Concurrent Reads (%d goroutines, %d ops each)", numGoroutines, opsPerGoroutine)
+	slog.Info("Benchmarking", "test", testName)
+	benchmark.StartTest(testName)
+
+	if len(transactionIDs) == 0 {
+		slog.Warn("No transactions loaded")
+		return benchmark.Result{TestName: testName, Database: "DynamoDB", ErrorCount: opsPerGoroutine * numGoroutines}
+	}
 
-				duration := time.Since(opStart)
+	var mu sync.Mutex
+	totalRCU := 0.0
+	itemsReturned := 0
 
+	get := benchmark.RetryingResultRand(func(rng *rand.Rand) (*dynamodb.GetItemOutput, error) {
+		txnID := transactionIDs[benchmark.Pick(rng, len(transactionIDs))]
+		return client.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName: aws.String(connection.DynamoDBTable),
+			Key: map[string]types.AttributeValue{
+				"PK": &types.AttributeValueMemberS{Value: fmt.Sprintf("TXN#%s", txnID)},
+				"SK": &types.AttributeValueMemberS{Value: "METADATA"},
+			},
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+		})
+	})
+	benchmark.WarmUp(numGoroutines, func(rng *rand.Rand) error { _, err := get(rng); return err })
+
+	if rate := benchmark.TargetRate(); rate > 0 {
+		result := benchmark.Paced(fmt.Sprintf("%s at %.0f ops/sec", testName, rate), "DynamoDB", opsPerGoroutine*numGoroutines, numGoroutines, func(rng *rand.Rand) error {
+			output, err := get(rng)
+			if err == nil {
 				mu.Lock()
-				durations = append(durations, duration)
-				if err != nil {
-					errorCount++
-				} else {
-					successCount++
-					if output.Item != nil {
-						itemsReturned++
-					}
-					if output.ConsumedCapacity != nil {
-						totalRCU += *output.ConsumedCapacity.CapacityUnits
-					}
+				if output.Item != nil {
+					itemsReturned++
+				}
+				if output.ConsumedCapacity != nil {
+					totalRCU += *output.ConsumedCapacity.CapacityUnits
 				}
 				mu.Unlock()
 			}
-		}()
+			return err
+		})
+		result.ConsumedRCU = totalRCU
+		result.ItemsReturned = itemsReturned
+		return result
 	}
 
-	wg.Wait()
-	totalDuration := time.Since(start)
+	ran := benchmark.RunWorkers(numGoroutines, func(worker int, local *benchmark.Recorder, rng *rand.Rand) benchmark.WorkerTally {
+		var tally benchmark.WorkerTally
+		items, rcu := 0, 0.0
+		for i := 0; i < opsPerGoroutine && !benchmark.Stopping(); i++ {
+			opStart := time.Now()
+			output, err := get(rng)
+			local.Record(time.Since(opStart))
+			local.Think()
+			if err != nil {
+				tally.Errors++
+				continue
+			}
+			tally.Successes++
+			if output.Item != nil {
+				items++
+			}
+			if output.ConsumedCapacity != nil {
+				rcu += *output.ConsumedCapacity.CapacityUnits
+			}
+		}
+		tally.Merge = func() {
+			itemsReturned += items
+			totalRCU += rcu
+		}
+		return tally
+	})
 
-	return calculateResults(testName, opsPerGoroutine*numGoroutines, numGoroutines, durations, successCount, errorCount, totalDuration, totalRCU, itemsReturned)
+	result := ran.Summarize(testName, "DynamoDB", opsPerGoroutine*numGoroutines, numGoroutines)
+	result.ConsumedRCU = totalRCU
+	result.ItemsReturned = itemsReturned
+	return result
 }
 
-func benchmarkConsistencyComparison(count int) BenchmarkResult {
+func benchmarkConsistencyComparison(count int) benchmark.Result {
 	testName := "Strongly Consistent vs Eventually Consistent Reads"
-	log.Printf("Benchmarking %s (%d operations each)...", testName, count)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
 
 	if len(transactionIDs) == 0 {
-		log.Println("Warning: No transactions loaded")
-		return BenchmarkResult{TestName: testName, Database: "DynamoDB", ErrorCount: count * 2}
+		slog.Warn("No transactions loaded")
+		return benchmark.Result{TestName: testName, Database: "DynamoDB", ErrorCount: count * 2}
 	}
 
-	// Eventually consistent reads
-	eventualDurations := make([]time.Duration, 0, count)
-	eventualRCU := 0.0
-
-	for i := 0; i < count; i++ {
-		opStart := time.Now()
-		txnID := transactionIDs[rand.Intn(len(transactionIDs))]
-
-		output, err := client.GetItem(ctx, &dynamodb.GetItemInput{
-			TableName: aws.String("FinancialTransactions"),
+	get := func(consistent bool) (*dynamodb.GetItemOutput, error) {
+		txnID := transactionIDs[benchmark.Pick(benchmark.Rand(), len(transactionIDs))]
+		return client.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName: aws.String(connection.DynamoDBTable),
 			Key: map[string]types.AttributeValue{
 				"PK": &types.AttributeValueMemberS{Value: fmt.Sprintf("TXN#%s", txnID)},
 				"SK": &types.AttributeValueMemberS{Value: "METADATA"},
 			},
-			ConsistentRead:         aws.Bool(false), // Eventually consistent (default)
+			ConsistentRead:         aws.Bool(consistent),
 			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
 		})
+	}
+	benchmark.WarmUp(1, func(*rand.Rand) error { _, err := get(false); return err })
+
+	// Eventually consistent reads
+	eventualDurations := make([]time.Duration, 0, count)
+	eventualRCU := 0.0
+
+	for i := 0; i < count; i++ {
+		opStart := time.Now()
+		output, err := get(false) // Eventually consistent (default)
 
 		duration := time.Since(opStart)
 		eventualDurations = append(eventualDurations, duration)
@@ -476,17 +724,7 @@ func benchmarkConsistencyComparison(count int) BenchmarkResult {
 
 	for i := 0; i < count; i++ {
 		opStart := time.Now()
-		txnID := transactionIDs[rand.Intn(len(transactionIDs))]
-
-		output, err := client.GetItem(ctx, &dynamodb.GetItemInput{
-			TableName: aws.String("FinancialTransactions"),
-			Key: map[string]types.AttributeValue{
-				"PK": &types.AttributeValueMemberS{Value: fmt.Sprintf("TXN#%s", txnID)},
-				"SK": &types.AttributeValueMemberS{Value: "METADATA"},
-			},
-			ConsistentRead:         aws.Bool(true), // Strongly consistent
-			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
-		})
+		output, err := get(true) // Strongly consistent
 
 		duration := time.Since(opStart)
 		strongDurations = append(strongDurations, duration)
@@ -500,12 +738,12 @@ func benchmarkConsistencyComparison(count int) BenchmarkResult {
 	eventualAvg := calculateAverage(eventualDurations)
 	strongAvg := calculateAverage(strongDurations)
 
-	log.Printf("  Eventually Consistent: Avg=%.2fms, RCU=%.2f", float64(eventualAvg.Microseconds())/1000, eventualRCU)
-	log.Printf("  Strongly Consistent:   Avg=%.2fms, RCU=%.2f (2x cost)", float64(strongAvg.Microseconds())/1000, strongRCU)
+	slog.Info("Eventually consistent reads", "avg", eventualAvg, "rcu", eventualRCU)
+	slog.Info("Strongly consistent reads (2x cost)", "avg", strongAvg, "rcu", strongRCU)
 
 	// Return combined result
 	allDurations := append(eventualDurations, strongDurations...)
-	return calculateResults(testName, count*2, 1, allDurations, count*2, 0, eventualAvg+strongAvg, eventualRCU+strongRCU, count*2)
+	return calculateReadResults(testName, count*2, 1, allDurations, count*2, 0, eventualAvg+strongAvg, eventualRCU+strongRCU, count*2)
 }
 
 func calculateAverage(durations []time.Duration) time.Duration {
@@ -519,87 +757,9 @@ func calculateAverage(durations []time.Duration) time.Duration {
 	return sum / time.Duration(len(durations))
 }
 
-func calculateResults(testName string, totalOps, concurrency int, durations []time.Duration, success, errors int, totalDuration time.Duration, totalRCU float64, itemsReturned int) BenchmarkResult {
-	if len(durations) == 0 {
-		return BenchmarkResult{
-			TestName:      testName,
-			Database:      "DynamoDB",
-			NumOperations: totalOps,
-			ErrorCount:    errors,
-			Timestamp:     time.Now(),
-		}
-	}
-
-	sorted := make([]time.Duration, len(durations))
-	copy(sorted, durations)
-	for i := 0; i < len(sorted); i++ {
-		for j := i + 1; j < len(sorted); j++ {
-			if sorted[i] > sorted[j] {
-				sorted[i], sorted[j] = sorted[j], sorted[i]
-			}
-		}
-	}
-
-	var avgDuration time.Duration
-	if len(durations) > 0 {
-		var sum time.Duration
-		for _, d := range durations {
-			sum += d
-		}
-		avgDuration = sum / time.Duration(len(durations))
-	}
-
-	median := sorted[len(sorted)/2]
-	p95 := sorted[int(float64(len(sorted))*0.95)]
-	p99 := sorted[int(float64(len(sorted))*0.99)]
-	opsPerSec := float64(totalOps) / totalDuration.Seconds()
-
-	return BenchmarkResult{
-		TestName:         testName,
-		Database:         "DynamoDB",
-		NumOperations:    totalOps,
-		Concurrency:      concurrency,
-		TotalDuration:    totalDuration,
-		AverageDuration:  avgDuration,
-		MedianDuration:   median,
-		P95Duration:      p95,
-		P99Duration:      p99,
-		OperationsPerSec: opsPerSec,
-		SuccessCount:     success,
-		ErrorCount:       errors,
-		ConsumedRCU:      totalRCU,
-		ItemsReturned:    itemsReturned,
-		Timestamp:        time.Now(),
-	}
-}
-
-func saveResults(suite BenchmarkSuite, filename string) {
-	data, err := json.MarshalIndent(suite, "", "  ")
-	if err != nil {
-		log.Printf("Failed to marshal results: %v", err)
-		return
-	}
-
-	if err := os.WriteFile(filename, data, 0644); err != nil {
-		log.Printf("Failed to write results: %v", err)
-		return
-	}
-
-	log.Printf("\nResults saved to %s", filename)
-}
-
-func printSummary(suite BenchmarkSuite) {
-	fmt.Println("\n=== Benchmark Summary ===\n")
-	for _, result := range suite.Results {
-		fmt.Printf("Test: %s\n", result.TestName)
-		fmt.Printf("  Operations: %d (Success: %d, Errors: %d)\n", result.NumOperations, result.SuccessCount, result.ErrorCount)
-		fmt.Printf("  Total Duration: %v\n", result.TotalDuration)
-		fmt.Printf("  Ops/sec: %.2f\n", result.OperationsPerSec)
-		fmt.Printf("  Avg Latency: %v\n", result.AverageDuration)
-		fmt.Printf("  P95 Latency: %v\n", result.P95Duration)
-		fmt.Printf("  P99 Latency: %v\n", result.P99Duration)
-		fmt.Printf("  Total RCU: %.2f\n", result.ConsumedRCU)
-		fmt.Printf("  Items Returned: %d\n", result.ItemsReturned)
-		fmt.Println()
-	}
+func calculateReadResults(testName string, totalOps, concurrency int, durations []time.Duration, success, errors int, totalDuration time.Duration, totalRCU float64, itemsReturned int) benchmark.Result {
+	result := benchmark.Summarize(testName, "DynamoDB", totalOps, concurrency, durations, success, errors, totalDuration)
+	result.ConsumedRCU = totalRCU
+	result.ItemsReturned = itemsReturned
+	return result
 }
@@ -0,0 +1,69 @@
+package dynamodb
+
+import (
+	"log/slog"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+	"github.com/shopspring/decimal"
+)
+
+// replayWorkers is how many replayed operations may be in flight at once,
+// the same as the PostgreSQL replay's.
+const replayWorkers = 50
+
+// runReplay plays back the trace given with --replay, keeping its
+// inter-arrival times: a read gets an account's METADATA item and a write
+// adds its amount to the account's balance. --ops replays only the trace's
+// first operations.
+func runReplay(opts benchmark.Options) {
+	ops, speed := benchmark.ReplayTrace()
+	if ops == nil {
+		benchmark.Fatal("The replay suite needs a trace, such as --replay=trace.jsonl")
+	}
+
+	connect()
+	loadTestData()
+
+	suite := benchmark.NewSuite("dynamodb-replay")
+
+	slog.Info("Running Trace Replay Benchmarks")
+
+	ops = ops[:min(len(ops), opts.Ops(len(ops)))]
+	for _, workers := range opts.ConcurrencyLevels(replayWorkers) {
+		suite.Run(func() benchmark.Result {
+			return benchmark.Replay("DynamoDB", ops, len(accountIDs), workers, speed, replayOps())
+		})
+	}
+
+	benchmark.Save(suite, "dynamodb-replay")
+	benchmark.PrintSummary(suite)
+}
+
+// replayOps maps trace operations onto the seeded accounts.
+func replayOps() benchmark.ReplayOps {
+	return benchmark.ReplayOps{
+		Read: func(account int) error {
+			_, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+				TableName: aws.String(connection.DynamoDBTable),
+				Key:       accountKey(accountIDs[account]),
+			})
+			return err
+		},
+		Write: func(account int, amount decimal.Decimal) error {
+			_, err := client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+				TableName:        aws.String(connection.DynamoDBTable),
+				Key:              accountKey(accountIDs[account]),
+				UpdateExpression: aws.String("ADD Balance :amount, Version :one"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":amount": &types.AttributeValueMemberN{Value: amount.String()},
+					":one":    &types.AttributeValueMemberN{Value: "1"},
+				},
+			})
+			return err
+		},
+	}
+}
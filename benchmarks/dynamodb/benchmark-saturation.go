@@ -0,0 +1,137 @@
+package dynamodb
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+)
+
+const (
+	// saturationPoolSize caps the SDK client's connections to the
+	// endpoint, as http.Transport's MaxConnsPerHost. The SDK's default
+	// transport has no cap, and keeps only 10 idle.
+	saturationPoolSize = 50
+	// saturationStepDuration is how long each client count is sustained.
+	saturationStepDuration = 10 * time.Second
+)
+
+var (
+	// saturationLoad is each step's client count as a multiple of the pool
+	// size, matching the PostgreSQL steps' multiples of its free slots.
+	saturationLoad = []float64{0.5, 1, 1.5, 2, 4}
+)
+
+// runSaturation drives more clients through one SDK client than its
+// connection pool holds. DynamoDB itself has no connection limit, so the
+// clients past the pool queue inside the SDK's HTTP transport; the
+// PostgreSQL side of the comparison is refused or queues at the server.
+func runSaturation(opts benchmark.Options) {
+	connect()
+	loadTestData()
+
+	suite := benchmark.NewSuite("dynamodb-saturation")
+
+	slog.Info("Running Connection Saturation Benchmarks")
+
+	levels := make([]int, 0, len(saturationLoad))
+	for _, load := range saturationLoad {
+		levels = append(levels, max(1, int(saturationPoolSize*load)))
+	}
+
+	var baseline benchmark.Result
+	for _, clients := range opts.ConcurrencyLevels(levels...) {
+		suite.Run(func() benchmark.Result {
+			result := benchmarkSaturation(saturationPoolSize, clients, opts.Step(saturationStepDuration))
+			if baseline.NumOperations == 0 {
+				baseline = result
+			}
+			result.FailureMode = benchmark.FailureMode(baseline, result)
+			return result
+		})
+	}
+
+	benchmark.Save(suite, "dynamodb-saturation")
+	benchmark.PrintSummary(suite)
+}
+
+// benchmarkSaturation runs clients workers for duration reading account
+// items through a client of their own whose pool holds poolSize
+// connections, timing how long each request waits for one.
+func benchmarkSaturation(poolSize, clients int, duration time.Duration) benchmark.Result {
+	testName := fmt.Sprintf("Connection Saturation - SDK pool, %d clients (%d connections)", clients, poolSize)
+	slog.Info("Benchmarking", "test", testName, "duration", duration)
+	benchmark.StartTest(testName)
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxConnsPerHost = poolSize
+	transport.MaxIdleConnsPerHost = poolSize
+	defer transport.CloseIdleConnections()
+	pooled, err := connection.NewDynamoDBClient(ctx, append(clientOptions, func(o *dynamodb.Options) {
+		o.HTTPClient = &http.Client{Transport: transport}
+	})...)
+	if err != nil {
+		benchmark.Fatal("Failed to load config", "err", err)
+	}
+
+	totalRCU := 0.0
+	var waited atomic.Int64
+
+	deadline := time.Now().Add(duration)
+	ran := benchmark.RunWorkers(clients, func(worker int, local *benchmark.Recorder, rng *rand.Rand) benchmark.WorkerTally {
+		var tally benchmark.WorkerTally
+		consumed := 0.0
+		for time.Now().Before(deadline) && !benchmark.Stopping() {
+			accountID := accountIDs[benchmark.Pick(rng, len(accountIDs))]
+
+			// The transport asks for a connection on every attempt,
+			// and hands one over once the pool has one free.
+			var getConn time.Time
+			traced := httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+				GetConn: func(string) { getConn = time.Now() },
+				GotConn: func(httptrace.GotConnInfo) { waited.Add(int64(time.Since(getConn))) },
+			})
+
+			opStart := time.Now()
+			out, err := pooled.GetItem(traced, &dynamodb.GetItemInput{
+				TableName: aws.String(connection.DynamoDBTable),
+				Key: map[string]types.AttributeValue{
+					"PK": &types.AttributeValueMemberS{Value: fmt.Sprintf("ACCOUNT#%s", accountID)},
+					"SK": &types.AttributeValueMemberS{Value: "METADATA"},
+				},
+				ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+			})
+			local.Record(time.Since(opStart))
+			if err != nil {
+				tally.Errors++
+			} else {
+				tally.Successes++
+				if out.ConsumedCapacity != nil {
+					consumed += aws.ToFloat64(out.ConsumedCapacity.CapacityUnits)
+				}
+			}
+		}
+		tally.Merge = func() { totalRCU += consumed }
+		return tally
+	})
+
+	operations := ran.Latencies.Count()
+	result := ran.Summarize(testName, "DynamoDB", operations, clients)
+	result.ConsumedRCU = totalRCU
+	result.ItemsReturned = ran.Successes
+	result.Layout = "SDK pool"
+	result.ConnectionLimit = poolSize
+	if operations > 0 {
+		result.ConnectionWait = time.Duration(waited.Load()) / time.Duration(operations)
+	}
+	return result
+}
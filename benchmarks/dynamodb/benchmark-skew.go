@@ -0,0 +1,219 @@
+package dynamodb
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+)
+
+const (
+	// skewHotAccounts is how many accounts every leg is funneled into.
+	skewHotAccounts = 3
+	// skewStepDuration is how long each concurrency level is sustained.
+	skewStepDuration = 15 * time.Second
+)
+
+var (
+	// skewConcurrency is the ramp the hot accounts are driven through. Each
+	// leg is one PutItem plus an ADD on the account's balance item, so a
+	// single account partition sees two writes per leg: the base-table
+	// METADATA item and the ACCOUNT#<id> collection in GSI1. Once either
+	// passes the 1,000 WCU per-partition limit DynamoDB starts throttling,
+	// no matter how much capacity the table has.
+	skewConcurrency = []int{10, 25, 50, 100, 200, 400}
+)
+
+// runSkew funnels legs into a handful of accounts until DynamoDB throttles
+// them. DynamoDB Local never throttles, so point -ddb-endpoint at AWS for a
+// meaningful run; the ramp stops after the first step that is throttled.
+func runSkew(opts benchmark.Options) {
+	connect()
+
+	suite := benchmark.NewSuite("dynamodb-skew")
+
+	slog.Info("Running Skewed-Account Stress Test")
+	if connection.DynamoDBEndpoint != "" {
+		slog.Warn("Endpoint does not enforce partition throughput limits; throttling will not be observed",
+			"endpoint", connection.DynamoDBEndpoint)
+	}
+
+	hot := createHotAccounts(skewHotAccounts)
+	var keys []map[string]types.AttributeValue
+
+	runStart := time.Now()
+	legs := 0
+	for _, concurrency := range opts.ConcurrencyLevels(skewConcurrency...) {
+		stepStart := time.Since(runStart)
+		var result benchmark.Result
+		suite.Run(func() benchmark.Result {
+			var written []map[string]types.AttributeValue
+			result, written = benchmarkSkewedWrites(hot, concurrency, opts.Step(skewStepDuration))
+			keys = append(keys, written...)
+			return result
+		})
+
+		if result.ThrottleOnset > 0 {
+			slog.Info("Throttling began", "after", stepStart+result.ThrottleOnset, "legs", legs,
+				"concurrency", concurrency, "legs_per_sec", result.OperationsPerSec,
+				"legs_per_sec_per_account", result.OperationsPerSec/float64(len(hot)))
+			break
+		}
+		legs += result.SuccessCount
+	}
+
+	for _, accountID := range hot {
+		keys = append(keys, map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: fmt.Sprintf("ACCOUNT#%s", accountID)},
+			"SK": &types.AttributeValueMemberS{Value: "METADATA"},
+		})
+	}
+	slog.Info("Removing skew legs", "items", len(keys))
+	deleteItems(keys)
+
+	benchmark.Save(suite, "dynamodb-skew")
+	benchmark.PrintSummary(suite)
+}
+
+// createHotAccounts writes the synthetic accounts the stress test funnels
+// its legs into, so the seeded accounts' balances are left alone.
+func createHotAccounts(n int) []string {
+	ids := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		id := uuid.New().String()
+		_, err := client.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(connection.DynamoDBTable),
+			Item: map[string]types.AttributeValue{
+				"PK":             &types.AttributeValueMemberS{Value: fmt.Sprintf("ACCOUNT#%s", id)},
+				"SK":             &types.AttributeValueMemberS{Value: "METADATA"},
+				"Type":           &types.AttributeValueMemberS{Value: "SkewBenchmarkAccount"},
+				"BenchmarkRunID": &types.AttributeValueMemberS{Value: benchmark.RunID},
+				"ID":             &types.AttributeValueMemberS{Value: id},
+				"Currency":       &types.AttributeValueMemberS{Value: "USD"},
+				"Balance":        &types.AttributeValueMemberN{Value: "0"},
+			},
+		})
+		if err != nil {
+			benchmark.Fatal("Failed to create hot account", "err", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// benchmarkSkewedWrites writes legs to random hot accounts as fast as
+// concurrency workers allow for the given duration and returns the primary
+// keys of the legs it wrote.
+func benchmarkSkewedWrites(hot []string, concurrency int, duration time.Duration) (benchmark.Result, []map[string]types.AttributeValue) {
+	testName := fmt.Sprintf("Skewed Account Writes - %d hot accounts (%d concurrent)", len(hot), concurrency)
+	slog.Info("Benchmarking", "test", testName, "duration", duration)
+	benchmark.StartTest(testName)
+
+	var keys []map[string]types.AttributeValue
+	throttledCount := 0
+	totalWCU := 0.0
+	var onset time.Duration
+
+	start := time.Now()
+	deadline := start.Add(duration)
+	ran := benchmark.RunWorkers(concurrency, func(worker int, local *benchmark.Recorder, rng *rand.Rand) benchmark.WorkerTally {
+		var tally benchmark.WorkerTally
+		var legs []map[string]types.AttributeValue
+		throttled, consumed := 0, 0.0
+		var firstThrottle time.Duration
+		for time.Now().Before(deadline) {
+			accountID := hot[rng.Intn(len(hot))]
+			txnID := uuid.New().String()
+			createdAt := time.Now().UTC().Format(time.RFC3339Nano)
+			key := map[string]types.AttributeValue{
+				"PK": &types.AttributeValueMemberS{Value: fmt.Sprintf("TXN#%s", txnID)},
+				"SK": &types.AttributeValueMemberS{Value: fmt.Sprintf("LEG#%s", uuid.New().String())},
+			}
+
+			opStart := time.Now()
+			wcu := 0.0
+			written := false
+			put, err := client.PutItem(ctx, &dynamodb.PutItemInput{
+				TableName: aws.String(connection.DynamoDBTable),
+				Item: map[string]types.AttributeValue{
+					"PK":             key["PK"],
+					"SK":             key["SK"],
+					"GSI1PK":         &types.AttributeValueMemberS{Value: fmt.Sprintf("ACCOUNT#%s", accountID)},
+					"GSI1SK":         &types.AttributeValueMemberS{Value: fmt.Sprintf("LEG#%s#%s", createdAt, txnID)},
+					"Type":           &types.AttributeValueMemberS{Value: "SkewBenchmarkLeg"},
+					"BenchmarkRunID": &types.AttributeValueMemberS{Value: benchmark.RunID},
+					"TransactionID":  &types.AttributeValueMemberS{Value: txnID},
+					"AccountID":      &types.AttributeValueMemberS{Value: accountID},
+					"LegType":        &types.AttributeValueMemberS{Value: "credit"},
+					"Amount":         &types.AttributeValueMemberN{Value: "1.0000"},
+					"Currency":       &types.AttributeValueMemberS{Value: "USD"},
+					"CreatedAt":      &types.AttributeValueMemberS{Value: createdAt},
+				},
+				ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+			})
+			if err == nil {
+				written = true
+				if put.ConsumedCapacity != nil {
+					wcu += *put.ConsumedCapacity.CapacityUnits
+				}
+
+				var update *dynamodb.UpdateItemOutput
+				update, err = client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+					TableName: aws.String(connection.DynamoDBTable),
+					Key: map[string]types.AttributeValue{
+						"PK": &types.AttributeValueMemberS{Value: fmt.Sprintf("ACCOUNT#%s", accountID)},
+						"SK": &types.AttributeValueMemberS{Value: "METADATA"},
+					},
+					UpdateExpression: aws.String("ADD Balance :amount"),
+					ExpressionAttributeValues: map[string]types.AttributeValue{
+						":amount": &types.AttributeValueMemberN{Value: "1.0000"},
+					},
+					ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+				})
+				if err == nil && update.ConsumedCapacity != nil {
+					wcu += *update.ConsumedCapacity.CapacityUnits
+				}
+			}
+			local.Record(time.Since(opStart))
+			consumed += wcu
+			if err != nil {
+				tally.Errors++
+				if isThrottled(err) {
+					throttled++
+					if firstThrottle == 0 {
+						firstThrottle = time.Since(start)
+					}
+				}
+			} else {
+				tally.Successes++
+			}
+			// A leg whose balance update failed still has to be removed.
+			if written {
+				legs = append(legs, key)
+			}
+		}
+		tally.Merge = func() {
+			keys = append(keys, legs...)
+			throttledCount += throttled
+			totalWCU += consumed
+			if firstThrottle > 0 && (onset == 0 || firstThrottle < onset) {
+				onset = firstThrottle
+			}
+		}
+		return tally
+	})
+
+	result := ran.Summarize(testName, "DynamoDB", ran.Latencies.Count(), concurrency)
+	result.Role = "hot"
+	result.ConsumedWCU = totalWCU
+	result.ThrottledCount = throttledCount
+	result.ThrottleOnset = onset
+	return result, keys
+}
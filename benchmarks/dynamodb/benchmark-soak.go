@@ -0,0 +1,58 @@
+package dynamodb
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+)
+
+const (
+	// soakDuration is how long the soak runs, and soakInterval how often
+	// it snapshots its results, the same as the PostgreSQL soak's.
+	soakDuration = 2 * time.Hour
+	soakInterval = 10 * time.Minute
+	soakWorkers  = 25
+)
+
+// runSoak runs the soak mix for hours, saving a snapshot of the interval's
+// latency and throughput with the table's reported size every few minutes.
+// DynamoDB Local keeps its data in the JVM heap, so its memory growth shows
+// up as latency drift between snapshots.
+func runSoak(opts benchmark.Options) {
+	connect()
+	loadTestData()
+
+	suite := benchmark.NewSuite("dynamodb-soak")
+
+	slog.Info("Running Soak Benchmarks")
+
+	run := benchmark.RetryingRand(benchmark.SoakMix(ycsbOps(ycsbScanLength)))
+	for _, workers := range opts.ConcurrencyLevels(soakWorkers) {
+		suite.Run(func() benchmark.Result {
+			testName := fmt.Sprintf("Soak - Mixed Ledger (%d workers)", workers)
+			result := benchmark.Soak(testName, "DynamoDB", workers, opts.Soak(soakDuration), opts.Snapshots(soakInterval), run,
+				func(snapshot benchmark.Result) { suite.Snapshot(withTableSize(snapshot)) })
+			return withTableSize(result)
+		})
+	}
+
+	benchmark.Save(suite, "dynamodb-soak")
+	benchmark.PrintSummary(suite)
+}
+
+// withTableSize sets result's table size to what DescribeTable reports.
+// AWS refreshes it only every few hours; DynamoDB Local keeps it current.
+func withTableSize(result benchmark.Result) benchmark.Result {
+	table, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(connection.DynamoDBTable)})
+	if err != nil {
+		slog.Warn("Failed to describe table", "err", err)
+		return result
+	}
+	result.TableSizeBytes = aws.ToInt64(table.Table.TableSizeBytes)
+	return result
+}
@@ -1,53 +1,31 @@
-package main
+package dynamodb
 
 import (
-	"context"
-	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/rand"
-	"os"
 	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/google/uuid"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
 	"github.com/shopspring/decimal"
 )
 
-type BenchmarkResult struct {
-	TestName          string        `json:"test_name"`
-	Database          string        `json:"database"`
-	NumOperations     int           `json:"num_operations"`
-	Concurrency       int           `json:"concurrency"`
-	TotalDuration     time.Duration `json:"total_duration_ms"`
-	AverageDuration   time.Duration `json:"avg_duration_ms"`
-	MedianDuration    time.Duration `json:"median_duration_ms"`
-	P95Duration       time.Duration `json:"p95_duration_ms"`
-	P99Duration       time.Duration `json:"p99_duration_ms"`
-	OperationsPerSec  float64       `json:"operations_per_sec"`
-	SuccessCount      int           `json:"success_count"`
-	ErrorCount        int           `json:"error_count"`
-	ConsumedWCU       float64       `json:"consumed_wcu"`
-	Timestamp         time.Time     `json:"timestamp"`
-}
-
-type BenchmarkSuite struct {
-	Results []BenchmarkResult `json:"results"`
-}
-
-type Transaction struct {
+type benchmarkTransaction struct {
 	PK              string    `dynamodbav:"PK"`
 	SK              string    `dynamodbav:"SK"`
 	GSI1PK          string    `dynamodbav:"GSI1PK"`
 	GSI1SK          string    `dynamodbav:"GSI1SK"`
 	GSI2PK          string    `dynamodbav:"GSI2PK"`
 	GSI2SK          string    `dynamodbav:"GSI2SK"`
+	GSI3PK          string    `dynamodbav:"GSI3PK,omitempty"`
+	GSI3SK          string    `dynamodbav:"GSI3SK,omitempty"`
 	Type            string    `dynamodbav:"Type"`
 	ID              string    `dynamodbav:"ID"`
 	IdempotencyKey  string    `dynamodbav:"IdempotencyKey"`
@@ -56,135 +34,72 @@ type Transaction struct {
 	MerchantID      string    `dynamodbav:"MerchantID"`
 	Description     string    `dynamodbav:"Description"`
 	CreatedAt       time.Time `dynamodbav:"CreatedAt"`
+	BenchmarkRunID  string    `dynamodbav:"BenchmarkRunID"`
 }
 
-type TransactionLeg struct {
-	PK            string          `dynamodbav:"PK"`
-	SK            string          `dynamodbav:"SK"`
-	GSI1PK        string          `dynamodbav:"GSI1PK"`
-	GSI1SK        string          `dynamodbav:"GSI1SK"`
-	Type          string          `dynamodbav:"Type"`
-	ID            string          `dynamodbav:"ID"`
-	TransactionID string          `dynamodbav:"TransactionID"`
-	AccountID     string          `dynamodbav:"AccountID"`
-	LegType       string          `dynamodbav:"LegType"`
-	Amount        decimal.Decimal `dynamodbav:"Amount"`
-	Currency      string          `dynamodbav:"Currency"`
-	CreatedAt     time.Time       `dynamodbav:"CreatedAt"`
+type benchmarkTransactionLeg struct {
+	PK             string    `dynamodbav:"PK"`
+	SK             string    `dynamodbav:"SK"`
+	GSI1PK         string    `dynamodbav:"GSI1PK"`
+	GSI1SK         string    `dynamodbav:"GSI1SK"`
+	Type           string    `dynamodbav:"Type"`
+	ID             string    `dynamodbav:"ID"`
+	TransactionID  string    `dynamodbav:"TransactionID"`
+	AccountID      string    `dynamodbav:"AccountID"`
+	LegType        string    `dynamodbav:"LegType"`
+	Amount         Decimal   `dynamodbav:"Amount"`
+	Currency       string    `dynamodbav:"Currency"`
+	CreatedAt      time.Time `dynamodbav:"CreatedAt"`
+	BenchmarkRunID string    `dynamodbav:"BenchmarkRunID"`
 }
 
-var (
-	client      *dynamodb.Client
-	ctx         = context.Background()
-	accountIDs  []string
-	merchantIDs []string
-)
-
-func main() {
-	cfg, err := config.LoadDefaultConfig(ctx,
-		config.WithRegion("us-east-1"),
-		config.WithEndpointResolverWithOptions(aws.EndpointResolverWithOptionsFunc(
-			func(service, region string, options ...interface{}) (aws.Endpoint, error) {
-				return aws.Endpoint{URL: "http://localhost:8000"}, nil
-			})),
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("local", "local", "")),
-	)
-	if err != nil {
-		log.Fatal("Failed to load config:", err)
-	}
-
-	client = dynamodb.NewFromConfig(cfg)
-	log.Println("Connected to DynamoDB Local")
+func runWrites(opts benchmark.Options) {
+	connect()
 
 	loadTestData()
 
-	suite := BenchmarkSuite{Results: make([]BenchmarkResult, 0)}
+	suite := benchmark.NewSuite("dynamodb-write")
 
-	log.Println("\n=== Running DynamoDB Write Performance Benchmarks ===\n")
+	slog.Info("Running DynamoDB Write Performance Benchmarks")
 
-	suite.Results = append(suite.Results, benchmarkSingleWrites(1000))
-	suite.Results = append(suite.Results, benchmarkBatchWrites(100, 25))
-	suite.Results = append(suite.Results, benchmarkBatchWrites(10, 25))
-	suite.Results = append(suite.Results, benchmarkConcurrentWrites(1000, 10))
-	suite.Results = append(suite.Results, benchmarkConcurrentWrites(1000, 50))
-	suite.Results = append(suite.Results, benchmarkTransactWrites(1000, 1))
-	suite.Results = append(suite.Results, benchmarkTransactWrites(1000, 10))
-
-	saveResults(suite, "benchmarks/results/dynamodb-write-results.json")
-	printSummary(suite)
-}
+	suite.Run(func() benchmark.Result { return benchmarkSingleWrites(opts.Ops(1000)) })
 
-func loadTestData() {
-	log.Println("Loading test data from DynamoDB...")
-
-	// Scan for 100 accounts
-	output, err := client.Scan(ctx, &dynamodb.ScanInput{
-		TableName:        aws.String("FinancialTransactions"),
-		FilterExpression: aws.String("#t = :type"),
-		ExpressionAttributeNames: map[string]string{
-			"#t": "Type",
-		},
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":type": &types.AttributeValueMemberS{Value: "Account"},
-		},
-		Limit: aws.Int32(100),
-	})
-
-	if err == nil {
-		for _, item := range output.Items {
-			if id, ok := item["ID"].(*types.AttributeValueMemberS); ok {
-				accountIDs = append(accountIDs, id.Value)
-			}
-		}
+	// The same number of items split into batches of each size
+	items := opts.Ops(2500)
+	for _, size := range opts.BatchSizes(10, 25) {
+		suite.Run(func() benchmark.Result { return benchmarkBatchWrites(benchmark.Batches(items, size), size) })
 	}
 
-	// Scan for 100 merchants
-	output, err = client.Scan(ctx, &dynamodb.ScanInput{
-		TableName:        aws.String("FinancialTransactions"),
-		FilterExpression: aws.String("#t = :type"),
-		ExpressionAttributeNames: map[string]string{
-			"#t": "Type",
-		},
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":type": &types.AttributeValueMemberS{Value: "Merchant"},
-		},
-		// Limit: aws.Int32(100),
-	})
-
-	log.Printf("Scan for merchants error: %v", output)
-
-	if err == nil {
-		for _, item := range output.Items {
-			if id, ok := item["ID"].(*types.AttributeValueMemberS); ok {
-				merchantIDs = append(merchantIDs, id.Value)
-			}
-		}
+	for _, concurrency := range opts.ConcurrencyLevels(10, 50) {
+		suite.Run(func() benchmark.Result { return benchmarkConcurrentWrites(opts.Ops(1000), concurrency) })
 	}
-
-	log.Printf("Loaded %d accounts and %d merchants", len(accountIDs), len(merchantIDs))
-
-	// Validate that we have test data
-	if len(accountIDs) == 0 || len(merchantIDs) == 0 {
-		log.Fatal("\n❌ ERROR: No test data found in DynamoDB!\n\n" +
-			"The table is empty. Please seed data first:\n" +
-			"  1. Run: make seed-dynamodb\n" +
-			"  2. Or: go run benchmarks/dynamodb/seed-data.go\n\n" +
-			"This will create merchants, accounts, and transactions for benchmarking.\n")
+	for _, concurrency := range opts.ConcurrencyLevels(1, 10) {
+		suite.Run(func() benchmark.Result { return benchmarkTransactWrites(opts.Ops(1000), concurrency) })
+	}
+	// Multi-leg postings up to the 100 items one TransactWriteItems takes
+	for _, legs := range []int{3, 10, 50, 99} {
+		suite.Run(func() benchmark.Result { return benchmarkMultiLegTransactWrites(opts.Ops(200), legs) })
 	}
+	suite.RunAll(func() []benchmark.Result { return benchmarkMerchantIndexWriteCost(opts.Ops(1000)) })
+
+	benchmark.Save(suite, "dynamodb-write")
+	benchmark.PrintSummary(suite)
 }
 
-func benchmarkSingleWrites(count int) BenchmarkResult {
-	log.Printf("Benchmarking single PutItem operations (%d operations)...", count)
+func benchmarkSingleWrites(count int) benchmark.Result {
+	slog.Info("Benchmarking", "test", "Single PutItem Writes", "operations", count)
+	benchmark.StartTest("Single PutItem Writes")
 
 	durations := make([]time.Duration, 0, count)
 	successCount := 0
 	errorCount := 0
 	totalWCU := 0.0
+	benchmark.WarmUp(1, func(rng *rand.Rand) error { _, err := writeSingleTransaction(rng); return err })
 	start := time.Now()
 
 	for i := 0; i < count; i++ {
 		opStart := time.Now()
-		wcu, err := writeSingleTransaction()
+		wcu, err := writeSingleTransaction(benchmark.Rand())
 		duration := time.Since(opStart)
 		durations = append(durations, duration)
 
@@ -197,17 +112,70 @@ func benchmarkSingleWrites(count int) BenchmarkResult {
 	}
 
 	totalDuration := time.Since(start)
-	return calculateResults("Single PutItem Writes", count, 1, durations, successCount, errorCount, totalDuration, totalWCU)
+	return calculateWriteResults("Single PutItem Writes", count, 1, durations, successCount, errorCount, totalDuration, totalWCU)
+}
+
+func benchmarkMerchantIndexWriteCost(count int) []benchmark.Result {
+	slog.Info("Benchmarking", "test", "Merchant GSI write cost", "operations", count)
+
+	results := make([]benchmark.Result, 0, 2)
+	for _, indexMerchant := range []bool{false, true} {
+		testName := "PutItem Writes (without merchant GSI)"
+		if indexMerchant {
+			testName = "PutItem Writes (with merchant GSI)"
+		}
+		benchmark.StartTest(testName)
+
+		durations := make([]time.Duration, 0, count)
+		successCount := 0
+		errorCount := 0
+		totalWCU := 0.0
+		gsi3WCU := 0.0
+		benchmark.WarmUp(1, func(rng *rand.Rand) error { _, _, err := putTransaction(rng, indexMerchant); return err })
+		start := time.Now()
+
+		for i := 0; i < count; i++ {
+			opStart := time.Now()
+			wcu, indexWCU, err := putTransaction(benchmark.Rand(), indexMerchant)
+			duration := time.Since(opStart)
+			durations = append(durations, duration)
+
+			if err != nil {
+				errorCount++
+			} else {
+				successCount++
+				totalWCU += wcu
+				gsi3WCU += indexWCU
+			}
+		}
+
+		totalDuration := time.Since(start)
+		slog.Info("Write capacity", "test", testName, "wcu", totalWCU, "gsi3_wcu", gsi3WCU)
+		results = append(results, calculateWriteResults(testName, count, 1, durations, successCount, errorCount, totalDuration, totalWCU))
+	}
+
+	if results[0].ConsumedWCU > 0 {
+		slog.Info("Merchant GSI write amplification", "wcu_ratio", results[1].ConsumedWCU/results[0].ConsumedWCU)
+	}
+
+	return results
 }
 
-func benchmarkBatchWrites(numBatches, batchSize int) BenchmarkResult {
+func benchmarkBatchWrites(numBatches, batchSize int) benchmark.Result {
 	testName := fmt.Sprintf("BatchWriteItem (%d batches of %d)", numBatches, batchSize)
-	log.Printf("Benchmarking %s...", testName)
+	slog.Info("Benchmarking", "test", testName)
+	benchmark.StartTest(testName)
+
+	if batchSize > 25 {
+		slog.Warn("BatchWriteItem accepts at most 25 items, skipping batch size", "batch_size", batchSize)
+		return benchmark.Result{TestName: testName, Database: "DynamoDB", ErrorCount: numBatches}
+	}
 
 	durations := make([]time.Duration, 0, numBatches)
 	successCount := 0
 	errorCount := 0
 	totalWCU := 0.0
+	benchmark.WarmUp(1, func(*rand.Rand) error { _, err := writeBatch(batchSize); return err })
 	start := time.Now()
 
 	for i := 0; i < numBatches; i++ {
@@ -225,99 +193,238 @@ func benchmarkBatchWrites(numBatches, batchSize int) BenchmarkResult {
 	}
 
 	totalDuration := time.Since(start)
-	return calculateResults(testName, numBatches*batchSize, 1, durations, successCount*batchSize, errorCount, totalDuration, totalWCU)
+	return calculateWriteResults(testName, numBatches*batchSize, 1, durations, successCount*batchSize, errorCount, totalDuration, totalWCU)
 }
 
-func benchmarkConcurrentWrites(opsPerGoroutine, numGoroutines int) BenchmarkResult {
+func benchmarkConcurrentWrites(opsPerGoroutine, numGoroutines int) benchmark.Result {
 	testName := fmt.Sprintf("Concurrent Writes (%d goroutines, %d ops each)", numGoroutines, opsPerGoroutine)
-	log.Printf("Benchmarking %s...", testName)
+	slog.Info("Benchmarking", "test", testName)
+	benchmark.StartTest(testName)
 
-	var wg sync.WaitGroup
 	var mu sync.Mutex
-	durations := make([]time.Duration, 0, opsPerGoroutine*numGoroutines)
-	successCount := 0
-	errorCount := 0
 	totalWCU := 0.0
 
-	start := time.Now()
-
-	for g := 0; g < numGoroutines; g++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for i := 0; i < opsPerGoroutine; i++ {
-				opStart := time.Now()
-				wcu, err := writeSingleTransaction()
-				duration := time.Since(opStart)
-
+	write := benchmark.RetryingResultRand(writeSingleTransaction)
+	benchmark.WarmUp(numGoroutines, func(rng *rand.Rand) error { _, err := write(rng); return err })
+	if rate := benchmark.TargetRate(); rate > 0 {
+		result := benchmark.Paced(fmt.Sprintf("%s at %.0f ops/sec", testName, rate), "DynamoDB", opsPerGoroutine*numGoroutines, numGoroutines, func(rng *rand.Rand) error {
+			wcu, err := write(rng)
+			if err == nil {
 				mu.Lock()
-				durations = append(durations, duration)
-				if err != nil {
-					errorCount++
-				} else {
-					successCount++
-					totalWCU += wcu
-				}
+				totalWCU += wcu
 				mu.Unlock()
 			}
-		}()
+			return err
+		})
+		result.ConsumedWCU = totalWCU
+		return result
 	}
 
-	wg.Wait()
-	totalDuration := time.Since(start)
+	ran := benchmark.RunWorkers(numGoroutines, func(worker int, local *benchmark.Recorder, rng *rand.Rand) benchmark.WorkerTally {
+		var tally benchmark.WorkerTally
+		consumed := 0.0
+		for i := 0; i < opsPerGoroutine && !benchmark.Stopping(); i++ {
+			opStart := time.Now()
+			wcu, err := write(rng)
+			local.Record(time.Since(opStart))
+			local.Think()
+			if err != nil {
+				tally.Errors++
+			} else {
+				tally.Successes++
+				consumed += wcu
+			}
+		}
+		tally.Merge = func() { totalWCU += consumed }
+		return tally
+	})
 
-	return calculateResults(testName, opsPerGoroutine*numGoroutines, numGoroutines, durations, successCount, errorCount, totalDuration, totalWCU)
+	result := ran.Summarize(testName, "DynamoDB", opsPerGoroutine*numGoroutines, numGoroutines)
+	result.ConsumedWCU = totalWCU
+	return result
 }
 
-func benchmarkTransactWrites(count, concurrency int) BenchmarkResult {
+func benchmarkTransactWrites(count, concurrency int) benchmark.Result {
 	testName := fmt.Sprintf("TransactWriteItems (%d ops, %d concurrent)", count, concurrency)
-	log.Printf("Benchmarking %s...", testName)
+	slog.Info("Benchmarking", "test", testName)
+	benchmark.StartTest(testName)
 
-	var wg sync.WaitGroup
 	var mu sync.Mutex
+	totalWCU := 0.0
+
+	opsPerGoroutine := count / concurrency
+	write := benchmark.RetryingResultRand(writeTransactionalTransaction)
+	benchmark.WarmUp(concurrency, func(rng *rand.Rand) error { _, err := write(rng); return err })
+	if rate := benchmark.TargetRate(); rate > 0 {
+		result := benchmark.Paced(fmt.Sprintf("%s at %.0f ops/sec", testName, rate), "DynamoDB", count, concurrency, func(rng *rand.Rand) error {
+			wcu, err := write(rng)
+			if err == nil {
+				mu.Lock()
+				totalWCU += wcu
+				mu.Unlock()
+			}
+			return err
+		})
+		result.ConsumedWCU = totalWCU
+		return result
+	}
+
+	ran := benchmark.RunWorkers(concurrency, func(worker int, local *benchmark.Recorder, rng *rand.Rand) benchmark.WorkerTally {
+		var tally benchmark.WorkerTally
+		consumed := 0.0
+		for i := 0; i < opsPerGoroutine && !benchmark.Stopping(); i++ {
+			opStart := time.Now()
+			wcu, err := write(rng)
+			local.Record(time.Since(opStart))
+			local.Think()
+			if err != nil {
+				tally.Errors++
+			} else {
+				tally.Successes++
+				consumed += wcu
+			}
+		}
+		tally.Merge = func() { totalWCU += consumed }
+		return tally
+	})
+
+	result := ran.Summarize(testName, "DynamoDB", count, concurrency)
+	result.ConsumedWCU = totalWCU
+	return result
+}
+
+// benchmarkMultiLegTransactWrites writes transactions of legs legs each,
+// the header and every leg in one TransactWriteItems, which takes at most
+// 100 items, so 99 legs. A larger posting has to be split over several
+// requests and is no longer atomic.
+func benchmarkMultiLegTransactWrites(count, legs int) benchmark.Result {
+	testName := fmt.Sprintf("TransactWriteItems - Multi-Leg (%d legs)", legs)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
+
 	durations := make([]time.Duration, 0, count)
 	successCount := 0
 	errorCount := 0
 	totalWCU := 0.0
-
-	opsPerGoroutine := count / concurrency
+	write := benchmark.RetryingResultRand(func(rng *rand.Rand) (float64, error) { return writeMultiLegTransaction(rng, legs) })
+	benchmark.WarmUp(1, func(rng *rand.Rand) error { _, err := write(rng); return err })
 	start := time.Now()
 
-	for g := 0; g < concurrency; g++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for i := 0; i < opsPerGoroutine; i++ {
-				opStart := time.Now()
-				wcu, err := writeTransactionalTransaction()
-				duration := time.Since(opStart)
+	for i := 0; i < count && !benchmark.Stopping(); i++ {
+		opStart := time.Now()
+		wcu, err := write(benchmark.Rand())
+		duration := time.Since(opStart)
+		durations = append(durations, duration)
 
-				mu.Lock()
-				durations = append(durations, duration)
-				if err != nil {
-					errorCount++
-				} else {
-					successCount++
-					totalWCU += wcu
-				}
-				mu.Unlock()
-			}
-		}()
+		if err != nil {
+			errorCount++
+		} else {
+			successCount++
+			totalWCU += wcu
+		}
 	}
 
-	wg.Wait()
 	totalDuration := time.Since(start)
-
-	return calculateResults(testName, count, concurrency, durations, successCount, errorCount, totalDuration, totalWCU)
+	return calculateWriteResults(testName, count, 1, durations, successCount, errorCount, totalDuration, totalWCU)
 }
 
-func writeSingleTransaction() (float64, error) {
+// writeMultiLegTransaction writes a payment of legs legs, one debit and
+// legs-1 equal credits that balance it, as one TransactWriteItems.
+func writeMultiLegTransaction(rng *rand.Rand, legs int) (float64, error) {
 	txnID := uuid.New().String()
-	txn := Transaction{
+	idempotencyKey := uuid.New().String()
+	createdAt := time.Now()
+	created := createdAt.Format(time.RFC3339Nano)
+	share := decimal.NewFromFloat(rng.Float64()*100 + 0.01).Round(2)
+
+	txn := benchmarkTransaction{
+		PK:              fmt.Sprintf("TXN#%s", txnID),
+		SK:              "METADATA",
+		GSI1PK:          "STATUS#completed",
+		GSI1SK:          fmt.Sprintf("CREATED#%s", created),
+		GSI2PK:          fmt.Sprintf("IDEMPOTENCY#%s", idempotencyKey),
+		GSI2SK:          "TXN",
+		Type:            "Transaction",
+		ID:              txnID,
+		IdempotencyKey:  idempotencyKey,
+		TransactionType: "payment",
+		Status:          "completed",
+		CreatedAt:       createdAt,
+		BenchmarkRunID:  benchmark.RunID,
+	}
+	item, err := attributevalue.MarshalMap(txn)
+	if err != nil {
+		return 0, err
+	}
+	items := make([]types.TransactWriteItem, 0, legs+1)
+	items = append(items, types.TransactWriteItem{Put: &types.Put{TableName: aws.String(connection.DynamoDBTable), Item: item}})
+
+	for i := 0; i < legs; i++ {
+		legType, amount := "credit", share
+		if i == 0 {
+			legType, amount = "debit", share.Mul(decimal.NewFromInt(int64(legs-1)))
+		}
+		accountID := accountIDs[benchmark.Pick(rng, len(accountIDs))]
+		leg := benchmarkTransactionLeg{
+			PK:             fmt.Sprintf("TXN#%s", txnID),
+			SK:             fmt.Sprintf("LEG#%s", uuid.New().String()),
+			GSI1PK:         fmt.Sprintf("ACCOUNT#%s", accountID),
+			GSI1SK:         fmt.Sprintf("LEG#%s#%s", created, txnID),
+			Type:           "TransactionLeg",
+			TransactionID:  txnID,
+			AccountID:      accountID,
+			LegType:        legType,
+			Amount:         Decimal{amount},
+			Currency:       "USD",
+			CreatedAt:      createdAt,
+			BenchmarkRunID: benchmark.RunID,
+		}
+		item, err := attributevalue.MarshalMap(leg)
+		if err != nil {
+			return 0, err
+		}
+		items = append(items, types.TransactWriteItem{Put: &types.Put{TableName: aws.String(connection.DynamoDBTable), Item: item}})
+	}
+
+	output, err := client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems:          items,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+	})
+
+	wcu := 0.0
+	if output != nil {
+		for _, cc := range output.ConsumedCapacity {
+			if cc.CapacityUnits != nil {
+				wcu += *cc.CapacityUnits
+			}
+		}
+	}
+	return wcu, err
+}
+
+func writeSingleTransaction(rng *rand.Rand) (float64, error) {
+	wcu, _, err := putTransaction(rng, true)
+	return wcu, err
+}
+
+// putTransaction writes a single transaction header and returns the total WCU
+// and the share of it consumed by GSI3 (the merchant index). When
+// indexMerchant is false the GSI3 keys are omitted, so the item is not
+// projected into the merchant index at all.
+func putTransaction(rng *rand.Rand, indexMerchant bool) (float64, float64, error) {
+	return putTransactionWithID(rng, uuid.New().String(), indexMerchant)
+}
+
+// putTransactionWithID is putTransaction writing the header under txnID.
+func putTransactionWithID(rng *rand.Rand, txnID string, indexMerchant bool) (float64, float64, error) {
+	merchantID := merchantIDs[benchmark.Pick(rng, len(merchantIDs))]
+	createdAt := time.Now()
+
+	txn := benchmarkTransaction{
 		PK:              fmt.Sprintf("TXN#%s", txnID),
 		SK:              "METADATA",
 		GSI1PK:          "STATUS#completed",
-		GSI1SK:          fmt.Sprintf("CREATED#%s", time.Now().Format(time.RFC3339Nano)),
+		GSI1SK:          fmt.Sprintf("CREATED#%s", createdAt.Format(time.RFC3339Nano)),
 		GSI2PK:          fmt.Sprintf("IDEMPOTENCY#%s", uuid.New().String()),
 		GSI2SK:          "TXN",
 		Type:            "Transaction",
@@ -325,28 +432,38 @@ func writeSingleTransaction() (float64, error) {
 		IdempotencyKey:  uuid.New().String(),
 		TransactionType: "payment",
 		Status:          "completed",
-		MerchantID:      merchantIDs[rand.Intn(len(merchantIDs))],
+		MerchantID:      merchantID,
 		Description:     "Benchmark transaction",
-		CreatedAt:       time.Now(),
+		CreatedAt:       createdAt,
+		BenchmarkRunID:  benchmark.RunID,
+	}
+
+	if indexMerchant {
+		txn.GSI3PK = fmt.Sprintf("MERCHANT#%s", merchantID)
+		txn.GSI3SK = fmt.Sprintf("CREATED#%s", createdAt.Format(time.RFC3339Nano))
 	}
 
 	item, err := attributevalue.MarshalMap(txn)
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 
 	output, err := client.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName:              aws.String("FinancialTransactions"),
+		TableName:              aws.String(connection.DynamoDBTable),
 		Item:                   item,
-		ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+		ReturnConsumedCapacity: types.ReturnConsumedCapacityIndexes,
 	})
 
 	wcu := 0.0
+	gsi3WCU := 0.0
 	if output != nil && output.ConsumedCapacity != nil {
 		wcu = *output.ConsumedCapacity.CapacityUnits
+		if cc, ok := output.ConsumedCapacity.GlobalSecondaryIndexes["GSI3"]; ok && cc.CapacityUnits != nil {
+			gsi3WCU = *cc.CapacityUnits
+		}
 	}
 
-	return wcu, err
+	return wcu, gsi3WCU, err
 }
 
 func writeBatch(batchSize int) (float64, error) {
@@ -354,7 +471,7 @@ func writeBatch(batchSize int) (float64, error) {
 
 	for i := 0; i < batchSize; i++ {
 		txnID := uuid.New().String()
-		txn := Transaction{
+		txn := benchmarkTransaction{
 			PK:              fmt.Sprintf("TXN#%s", txnID),
 			SK:              "METADATA",
 			GSI1PK:          "STATUS#completed",
@@ -364,6 +481,7 @@ func writeBatch(batchSize int) (float64, error) {
 			TransactionType: "payment",
 			Status:          "completed",
 			CreatedAt:       time.Now(),
+			BenchmarkRunID:  benchmark.RunID,
 		}
 
 		item, _ := attributevalue.MarshalMap(txn)
@@ -372,26 +490,14 @@ func writeBatch(batchSize int) (float64, error) {
 		})
 	}
 
-	output, err := client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
-		RequestItems: map[string][]types.WriteRequest{
-			"FinancialTransactions": requests,
-		},
-		ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
-	})
-
-	wcu := 0.0
-	if output != nil && len(output.ConsumedCapacity) > 0 {
-		wcu = *output.ConsumedCapacity[0].CapacityUnits
-	}
-
-	return wcu, err
+	return batchWriteWithRetry(requests)
 }
 
-func writeTransactionalTransaction() (float64, error) {
+func writeTransactionalTransaction(rng *rand.Rand) (float64, error) {
 	txnID := uuid.New().String()
 	createdAt := time.Now()
 
-	txn := Transaction{
+	txn := benchmarkTransaction{
 		PK:              fmt.Sprintf("TXN#%s", txnID),
 		SK:              "METADATA",
 		Type:            "Transaction",
@@ -399,30 +505,33 @@ func writeTransactionalTransaction() (float64, error) {
 		TransactionType: "payment",
 		Status:          "completed",
 		CreatedAt:       createdAt,
+		BenchmarkRunID:  benchmark.RunID,
 	}
 
-	debitLeg := TransactionLeg{
-		PK:            fmt.Sprintf("TXN#%s", txnID),
-		SK:            fmt.Sprintf("LEG#%s", uuid.New().String()),
-		Type:          "TransactionLeg",
-		TransactionID: txnID,
-		AccountID:     accountIDs[rand.Intn(len(accountIDs))],
-		LegType:       "debit",
-		Amount:        decimal.NewFromFloat(rand.Float64() * 1000),
-		Currency:      "USD",
-		CreatedAt:     createdAt,
+	debitLeg := benchmarkTransactionLeg{
+		PK:             fmt.Sprintf("TXN#%s", txnID),
+		SK:             fmt.Sprintf("LEG#%s", uuid.New().String()),
+		Type:           "benchmarkTransactionLeg",
+		TransactionID:  txnID,
+		AccountID:      accountIDs[benchmark.Pick(rng, len(accountIDs))],
+		LegType:        "debit",
+		Amount:         Decimal{decimal.NewFromFloat(rng.Float64() * 1000)},
+		Currency:       "USD",
+		CreatedAt:      createdAt,
+		BenchmarkRunID: benchmark.RunID,
 	}
 
-	creditLeg := TransactionLeg{
-		PK:            fmt.Sprintf("TXN#%s", txnID),
-		SK:            fmt.Sprintf("LEG#%s", uuid.New().String()),
-		Type:          "TransactionLeg",
-		TransactionID: txnID,
-		AccountID:     accountIDs[rand.Intn(len(accountIDs))],
-		LegType:       "credit",
-		Amount:        debitLeg.Amount,
-		Currency:      "USD",
-		CreatedAt:     createdAt,
+	creditLeg := benchmarkTransactionLeg{
+		PK:             fmt.Sprintf("TXN#%s", txnID),
+		SK:             fmt.Sprintf("LEG#%s", uuid.New().String()),
+		Type:           "benchmarkTransactionLeg",
+		TransactionID:  txnID,
+		AccountID:      accountIDs[benchmark.Pick(rng, len(accountIDs))],
+		LegType:        "credit",
+		Amount:         debitLeg.Amount,
+		Currency:       "USD",
+		CreatedAt:      createdAt,
+		BenchmarkRunID: benchmark.RunID,
 	}
 
 	txnItem, _ := attributevalue.MarshalMap(txn)
@@ -431,9 +540,9 @@ func writeTransactionalTransaction() (float64, error) {
 
 	output, err := client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
 		TransactItems: []types.TransactWriteItem{
-			{Put: &types.Put{TableName: aws.String("FinancialTransactions"), Item: txnItem}},
-			{Put: &types.Put{TableName: aws.String("FinancialTransactions"), Item: debitItem}},
-			{Put: &types.Put{TableName: aws.String("FinancialTransactions"), Item: creditItem}},
+			{Put: &types.Put{TableName: aws.String(connection.DynamoDBTable), Item: txnItem}},
+			{Put: &types.Put{TableName: aws.String(connection.DynamoDBTable), Item: debitItem}},
+			{Put: &types.Put{TableName: aws.String(connection.DynamoDBTable), Item: creditItem}},
 		},
 		ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
 	})
@@ -450,75 +559,8 @@ func writeTransactionalTransaction() (float64, error) {
 	return wcu, err
 }
 
-func calculateResults(testName string, totalOps, concurrency int, durations []time.Duration, success, errors int, totalDuration time.Duration, totalWCU float64) BenchmarkResult {
-	sorted := make([]time.Duration, len(durations))
-	copy(sorted, durations)
-	for i := 0; i < len(sorted); i++ {
-		for j := i + 1; j < len(sorted); j++ {
-			if sorted[i] > sorted[j] {
-				sorted[i], sorted[j] = sorted[j], sorted[i]
-			}
-		}
-	}
-
-	var avgDuration time.Duration
-	if len(durations) > 0 {
-		var sum time.Duration
-		for _, d := range durations {
-			sum += d
-		}
-		avgDuration = sum / time.Duration(len(durations))
-	}
-
-	median := sorted[len(sorted)/2]
-	p95 := sorted[int(float64(len(sorted))*0.95)]
-	p99 := sorted[int(float64(len(sorted))*0.99)]
-	opsPerSec := float64(totalOps) / totalDuration.Seconds()
-
-	return BenchmarkResult{
-		TestName:         testName,
-		Database:         "DynamoDB",
-		NumOperations:    totalOps,
-		Concurrency:      concurrency,
-		TotalDuration:    totalDuration,
-		AverageDuration:  avgDuration,
-		MedianDuration:   median,
-		P95Duration:      p95,
-		P99Duration:      p99,
-		OperationsPerSec: opsPerSec,
-		SuccessCount:     success,
-		ErrorCount:       errors,
-		ConsumedWCU:      totalWCU,
-		Timestamp:        time.Now(),
-	}
-}
-
-func saveResults(suite BenchmarkSuite, filename string) {
-	data, err := json.MarshalIndent(suite, "", "  ")
-	if err != nil {
-		log.Printf("Failed to marshal results: %v", err)
-		return
-	}
-
-	if err := os.WriteFile(filename, data, 0644); err != nil {
-		log.Printf("Failed to write results: %v", err)
-		return
-	}
-
-	log.Printf("\nResults saved to %s", filename)
-}
-
-func printSummary(suite BenchmarkSuite) {
-	fmt.Println("\n=== Benchmark Summary ===\n")
-	for _, result := range suite.Results {
-		fmt.Printf("Test: %s\n", result.TestName)
-		fmt.Printf("  Operations: %d (Success: %d, Errors: %d)\n", result.NumOperations, result.SuccessCount, result.ErrorCount)
-		fmt.Printf("  Total Duration: %v\n", result.TotalDuration)
-		fmt.Printf("  Ops/sec: %.2f\n", result.OperationsPerSec)
-		fmt.Printf("  Avg Latency: %v\n", result.AverageDuration)
-		fmt.Printf("  P95 Latency: %v\n", result.P95Duration)
-		fmt.Printf("  P99 Latency: %v\n", result.P99Duration)
-		fmt.Printf("  Total WCU: %.2f\n", result.ConsumedWCU)
-		fmt.Println()
-	}
+func calculateWriteResults(testName string, totalOps, concurrency int, durations []time.Duration, success, errors int, totalDuration time.Duration, totalWCU float64) benchmark.Result {
+	result := benchmark.Summarize(testName, "DynamoDB", totalOps, concurrency, durations, success, errors, totalDuration)
+	result.ConsumedWCU = totalWCU
+	return result
 }
@@ -0,0 +1,154 @@
+package dynamodb
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ycsbOperations = 10000
+	ycsbWorkers    = 10
+	// ycsbScanLength is the longest history page a scan reads; each
+	// reads a uniformly random length up to it, as YCSB's scans do.
+	ycsbScanLength = 100
+)
+
+// runYCSB runs the YCSB core workloads selected with --workload, A to F by
+// default, against the ledger's account and transaction items.
+func runYCSB(opts benchmark.Options) {
+	connect()
+	loadTestData()
+
+	suite := benchmark.NewSuite("dynamodb-ycsb")
+
+	slog.Info("Running YCSB Workload Benchmarks")
+
+	ops := ycsbOps(opts.RowLimit(ycsbScanLength))
+	for _, w := range benchmark.SelectedYCSBWorkloads() {
+		for _, workers := range opts.ConcurrencyLevels(ycsbWorkers) {
+			suite.Run(func() benchmark.Result {
+				return benchmark.RunYCSB(w, "DynamoDB", opts.Ops(ycsbOperations), workers, ops)
+			})
+		}
+	}
+
+	benchmark.Save(suite, "dynamodb-ycsb")
+	benchmark.PrintSummary(suite)
+}
+
+// accountKey is the key of an account's METADATA item.
+func accountKey(accountID string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: fmt.Sprintf("ACCOUNT#%s", accountID)},
+		"SK": &types.AttributeValueMemberS{Value: "METADATA"},
+	}
+}
+
+// ycsbOps maps the YCSB operations onto the single-table design. Inserted
+// transactions are what read-latest reads, falling back to seeded ones
+// until the first insert.
+func ycsbOps(scanLength int) benchmark.YCSBOps {
+	var recent benchmark.Recent
+
+	return benchmark.YCSBOps{
+		Read: func(rng *rand.Rand) error {
+			_, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+				TableName: aws.String(connection.DynamoDBTable),
+				Key:       accountKey(accountIDs[benchmark.Pick(rng, len(accountIDs))]),
+			})
+			return err
+		},
+		ReadLatest: func(rng *rand.Rand) error {
+			id, ok := recent.Pick(rng)
+			if !ok {
+				id = transactionIDs[benchmark.Pick(rng, len(transactionIDs))]
+			}
+			_, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+				TableName: aws.String(connection.DynamoDBTable),
+				Key: map[string]types.AttributeValue{
+					"PK": &types.AttributeValueMemberS{Value: fmt.Sprintf("TXN#%s", id)},
+					"SK": &types.AttributeValueMemberS{Value: "METADATA"},
+				},
+			})
+			return err
+		},
+		Update: func(rng *rand.Rand) error {
+			_, err := client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+				TableName:        aws.String(connection.DynamoDBTable),
+				Key:              accountKey(accountIDs[benchmark.Pick(rng, len(accountIDs))]),
+				UpdateExpression: aws.String("ADD Balance :amount, Version :one"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":amount": &types.AttributeValueMemberN{Value: "1.0000"},
+					":one":    &types.AttributeValueMemberN{Value: "1"},
+				},
+			})
+			return err
+		},
+		Insert: func(rng *rand.Rand) error {
+			id := uuid.New().String()
+			if _, _, err := putTransactionWithID(rng, id, true); err != nil {
+				return err
+			}
+			recent.Add(id)
+			return nil
+		},
+		Scan: func(rng *rand.Rand) error {
+			_, err := client.Query(ctx, &dynamodb.QueryInput{
+				TableName:              aws.String(connection.DynamoDBTable),
+				IndexName:              aws.String("GSI1"),
+				KeyConditionExpression: aws.String("GSI1PK = :account AND begins_with(GSI1SK, :prefix)"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":account": &types.AttributeValueMemberS{Value: fmt.Sprintf("ACCOUNT#%s", accountIDs[benchmark.Pick(rng, len(accountIDs))])},
+					":prefix":  &types.AttributeValueMemberS{Value: "LEG#"},
+				},
+				ScanIndexForward: aws.Bool(false),
+				Limit:            aws.Int32(int32(1 + rng.Intn(scanLength))),
+			})
+			return err
+		},
+		// The write is conditional on the version read, so a conflicting
+		// write fails with ConditionalCheckFailedException rather than
+		// overwriting it.
+		ReadModifyWrite: func(rng *rand.Rand) error {
+			key := accountKey(accountIDs[benchmark.Pick(rng, len(accountIDs))])
+			out, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+				TableName:            aws.String(connection.DynamoDBTable),
+				Key:                  key,
+				ProjectionExpression: aws.String("Balance, Version"),
+			})
+			if err != nil {
+				return err
+			}
+			var account struct {
+				Balance Decimal `dynamodbav:"Balance"`
+				Version int     `dynamodbav:"Version"`
+			}
+			if err := attributevalue.UnmarshalMap(out.Item, &account); err != nil {
+				return err
+			}
+			_, err = client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+				TableName:           aws.String(connection.DynamoDBTable),
+				Key:                 key,
+				UpdateExpression:    aws.String("SET Balance = :balance, Version = :next"),
+				ConditionExpression: aws.String("Version = :version"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":balance": &types.AttributeValueMemberN{Value: account.Balance.Add(decimal.NewFromInt(1)).String()},
+					":version": &types.AttributeValueMemberN{Value: strconv.Itoa(account.Version)},
+					":next":    &types.AttributeValueMemberN{Value: strconv.Itoa(account.Version + 1)},
+				},
+			})
+			return err
+		},
+	}
+}
@@ -0,0 +1,127 @@
+package dynamodb
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+)
+
+// scanRunItems pages through every item tagged with BenchmarkRunID (runID,
+// or any run when empty), projecting the key, type and run ID.
+func scanRunItems(runID string, visit func(item map[string]types.AttributeValue)) {
+	input := &dynamodb.ScanInput{
+		TableName:                aws.String(connection.DynamoDBTable),
+		FilterExpression:         aws.String("attribute_exists(BenchmarkRunID)"),
+		ProjectionExpression:     aws.String("PK, SK, #t, BenchmarkRunID"),
+		ExpressionAttributeNames: map[string]string{"#t": "Type"},
+	}
+	if runID != "" {
+		input.FilterExpression = aws.String("BenchmarkRunID = :run")
+		input.ExpressionAttributeValues = map[string]types.AttributeValue{
+			":run": &types.AttributeValueMemberS{Value: runID},
+		}
+	}
+
+	for {
+		output, err := client.Scan(ctx, input)
+		if err != nil {
+			benchmark.Fatal("Failed to scan benchmark items", "err", err)
+		}
+		for _, item := range output.Items {
+			visit(item)
+		}
+		if output.LastEvaluatedKey == nil {
+			return
+		}
+		input.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+}
+
+// Cleanup deletes the items benchmark runs added to the seeded dataset:
+// those tagged with runID, or with any run ID when runID is empty. Seed data
+// is left alone, and running it again is harmless.
+func Cleanup(runID string) {
+	connect()
+
+	var keys []map[string]types.AttributeValue
+	scanRunItems(runID, func(item map[string]types.AttributeValue) {
+		keys = append(keys, map[string]types.AttributeValue{"PK": item["PK"], "SK": item["SK"]})
+	})
+	deleteItems(keys)
+
+	slog.Info("Removed benchmark items", "items", len(keys))
+}
+
+// Drift reports how far the table has grown past the seed: item counts by
+// type for each benchmark run that left items behind, against the seeded
+// transaction count.
+func Drift() {
+	connect()
+
+	perRun := make(map[string]map[string]int)
+	total := 0
+	scanRunItems("", func(item map[string]types.AttributeValue) {
+		run, itemType := stringAttr(item, "BenchmarkRunID"), stringAttr(item, "Type")
+		if perRun[run] == nil {
+			perRun[run] = make(map[string]int)
+		}
+		perRun[run][itemType]++
+		total++
+	})
+
+	seededTxns := 0
+	input := &dynamodb.ScanInput{
+		TableName:                aws.String(connection.DynamoDBTable),
+		FilterExpression:         aws.String("#t = :type AND attribute_not_exists(BenchmarkRunID)"),
+		ExpressionAttributeNames: map[string]string{"#t": "Type"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":type": &types.AttributeValueMemberS{Value: "Transaction"},
+		},
+		Select: types.SelectCount,
+	}
+	for {
+		output, err := client.Scan(ctx, input)
+		if err != nil {
+			benchmark.Fatal("Failed to count seeded transactions", "err", err)
+		}
+		seededTxns += int(output.Count)
+		if output.LastEvaluatedKey == nil {
+			break
+		}
+		input.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+
+	fmt.Print("\n=== DynamoDB Dataset Drift ===\n\n")
+	fmt.Printf("%-44s %8d transactions\n", "Seed data:", seededTxns)
+
+	runs := make([]string, 0, len(perRun))
+	for run := range perRun {
+		runs = append(runs, run)
+	}
+	sort.Strings(runs)
+	for _, run := range runs {
+		fmt.Printf("%-44s", "Run "+run+":")
+		itemTypes := make([]string, 0, len(perRun[run]))
+		for itemType := range perRun[run] {
+			itemTypes = append(itemTypes, itemType)
+		}
+		sort.Strings(itemTypes)
+		for _, itemType := range itemTypes {
+			fmt.Printf(" %8d %s", perRun[run][itemType], itemType)
+		}
+		fmt.Println()
+	}
+
+	if seededTxns > 0 {
+		fmt.Printf("\nBenchmark runs have added %d items (%.1f%% of the seeded transaction count).\n", total, float64(total)/float64(seededTxns)*100)
+	}
+	if total > 0 {
+		fmt.Println("Remove them with `benchctl cleanup --db=dynamodb` (all runs) or `--run=<id>` (one run).")
+	}
+}
@@ -0,0 +1,698 @@
+// Package dynamodb contains the DynamoDB seeder and benchmark suites. Each
+// suite connects to the table configured in the connection package, runs its
+// tests and publishes the results through the configured sinks; benchctl
+// picks one from Suites.
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/capacity"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+)
+
+// Suites maps benchctl suite names to their runners. Each runner scales
+// its tests by the given options.
+var Suites = map[string]func(benchmark.Options){
+	"writes":      runWrites,
+	"reads":       runReads,
+	"scans":       runScans,
+	"isolation":   runIsolation,
+	"close":       runClose,
+	"keys":        runKeys,
+	"ingest":      runIngest,
+	"collections": runCollections,
+	"skew":        runSkew,
+	"marshal":     runMarshal,
+	"saturation":  runSaturation,
+	"exports":     runExports,
+	"ramp":        runRamp,
+	"soak":        runSoak,
+	"replay":      runReplay,
+	"ycsb":        runYCSB,
+}
+
+var (
+	client *dynamodb.Client
+	ctx    = benchmark.Context()
+
+	// Test data loaded by loadTestData and shared by the suites.
+	accountIDs     []string
+	transactionIDs []string
+	merchantIDs    []string
+	userIDs        []string
+	// anomalies are the seeded transactions injected with each anomaly,
+	// from the seeded-ID file.
+	anomalies map[string][]string
+)
+
+// clientOptions are the middlewares every benchmark client is built with.
+var clientOptions = []func(*dynamodb.Options){withRetryPolicy, withOpTimeout, withConsistentReads, withPool, withCapacityPredictions, withErrorTypes, withLatencyPhases, withTrace, withCostCeiling}
+
+func connect() *dynamodb.Client {
+	var err error
+	client, err = connection.NewDynamoDBClient(ctx, clientOptions...)
+	if err != nil {
+		benchmark.Fatal("Failed to load config", "err", err)
+	}
+
+	// Before Seed creates the table there is nothing to predict for. The
+	// item count DynamoDB reports is refreshed about every six hours, so on
+	// a freshly seeded table it may still be zero.
+	info := benchmark.DatabaseInfo{Name: "dynamodb", Target: connection.DynamoDBTarget(), Driver: "aws-sdk-go-v2"}
+	if table, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(connection.DynamoDBTable)}); err == nil {
+		model := capacity.ModelFor(table.Table)
+		capacityModel.Store(&model)
+		info.RowCounts = map[string]int64{connection.DynamoDBTable: aws.ToInt64(table.Table.ItemCount)}
+	}
+	benchmark.SetDatabaseInfo(info)
+
+	benchmark.SetRetryable(isTransient)
+	benchmark.SetPoolMeter(readPool)
+
+	slog.Info("Connected to DynamoDB", "target", connection.DynamoDBTarget(), "table", connection.DynamoDBTable)
+	return client
+}
+
+// withRetryPolicy turns the SDK's own retries off when the benchmark retry
+// layer is on, so DynamoDB requests are retried by the same policy as
+// PostgreSQL statements and every retry is accounted for.
+func withRetryPolicy(o *dynamodb.Options) {
+	if benchmark.RetriesEnabled() {
+		o.Retryer = aws.NopRetryer{}
+	}
+}
+
+// withOpTimeout gives every API call, retries included, the operation
+// timeout, and counts the calls that run out of it.
+func withOpTimeout(o *dynamodb.Options) {
+	if benchmark.OpTimeout() == 0 {
+		return
+	}
+	o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("OpTimeout",
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				opCtx, cancel := benchmark.OpContext(ctx)
+				defer cancel()
+				out, metadata, err := next.HandleInitialize(opCtx, in)
+				if err != nil && errors.Is(opCtx.Err(), context.DeadlineExceeded) {
+					benchmark.CountTimeout()
+				}
+				return out, metadata, err
+			}), middleware.Before)
+	})
+}
+
+// capacityModel describes the table for withCapacityPredictions, once
+// connect has found it.
+var capacityModel atomic.Pointer[capacity.Model]
+
+// withCapacityPredictions predicts the capacity of every request that
+// returns its ConsumedCapacity from the items it read or wrote, and records
+// the prediction against what was consumed.
+func withCapacityPredictions(o *dynamodb.Options) {
+	o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("CapacityPredictions",
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				out, metadata, err := next.HandleInitialize(ctx, in)
+				if model := capacityModel.Load(); err == nil && model != nil {
+					if operation, predicted, consumed, ok := model.Predict(in.Parameters, out.Result); ok {
+						benchmark.RecordCapacity(operation, predicted, consumed)
+					}
+				}
+				return out, metadata, err
+			}), middleware.After)
+	})
+}
+
+// withConsistentReads makes reads that leave ConsistentRead unset strongly
+// consistent when benchmark.SetConsistency asked for it. Queries and scans
+// of a global secondary index are left alone, as those cannot be.
+func withConsistentReads(o *dynamodb.Options) {
+	if !benchmark.ConsistentReads() {
+		return
+	}
+	o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("ConsistentReads",
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				consistentRead(in.Parameters)
+				return next.HandleInitialize(ctx, in)
+			}), middleware.Before)
+	})
+}
+
+// consistentRead sets ConsistentRead on a read request that leaves it unset
+// and can be strongly consistent.
+func consistentRead(input any) {
+	switch in := input.(type) {
+	case *dynamodb.GetItemInput:
+		if in.ConsistentRead == nil {
+			in.ConsistentRead = aws.Bool(true)
+		}
+	case *dynamodb.QueryInput:
+		if in.ConsistentRead == nil && in.IndexName == nil {
+			in.ConsistentRead = aws.Bool(true)
+		}
+	case *dynamodb.ScanInput:
+		if in.ConsistentRead == nil && in.IndexName == nil {
+			in.ConsistentRead = aws.Bool(true)
+		}
+	case *dynamodb.BatchGetItemInput:
+		for table, keys := range in.RequestItems {
+			if keys.ConsistentRead == nil {
+				keys.ConsistentRead = aws.Bool(true)
+				in.RequestItems[table] = keys
+			}
+		}
+	}
+}
+
+// withPool tunes the SDK's HTTP client with connection.DynamoDBHTTP, and
+// turns keep-alives off when benchmark.SetPool asked for unpooled
+// connections, so every API call opens a connection of its own. A client
+// set in its place, such as the tests' mock, is left as it is when pooled.
+// Every request attempt is traced for the pool meter.
+func withPool(o *dynamodb.Options) {
+	settings := connection.DynamoDBHTTP
+	if c, ok := o.HTTPClient.(*awshttp.BuildableClient); ok {
+		c = c.WithTransportOptions(func(t *http.Transport) {
+			t.DisableKeepAlives = !benchmark.Pooled()
+			if settings.MaxIdle > 0 {
+				t.MaxIdleConns, t.MaxIdleConnsPerHost = settings.MaxIdle, settings.MaxIdle
+			}
+			if settings.MaxConns > 0 {
+				t.MaxConnsPerHost = settings.MaxConns
+			}
+			if settings.IdleTimeout > 0 {
+				t.IdleConnTimeout = settings.IdleTimeout
+			}
+		})
+		if settings.Timeout > 0 {
+			c = c.WithTimeout(settings.Timeout)
+		}
+		o.HTTPClient = c
+	} else if !benchmark.Pooled() {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DisableKeepAlives = true
+		o.HTTPClient = &http.Client{Transport: transport}
+	}
+
+	o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+		return stack.Finalize.Add(middleware.FinalizeMiddlewareFunc("PoolTrace",
+			func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+				return next.HandleFinalize(httptrace.WithClientTrace(ctx, poolTrace()), in)
+			}), middleware.After)
+	})
+}
+
+// The pool meter's running totals: requests that found no idle connection,
+// their wait for one, and the connections dialed.
+var (
+	poolWaits    atomic.Int64
+	poolWaitedNs atomic.Int64
+	poolOpened   atomic.Int64
+)
+
+// poolTrace counts one request attempt's connection into the pool meter's
+// totals.
+func poolTrace() *httptrace.ClientTrace {
+	var getConn time.Time
+	return &httptrace.ClientTrace{
+		GetConn: func(string) { getConn = time.Now() },
+		GotConn: func(info httptrace.GotConnInfo) {
+			if !info.Reused {
+				poolOpened.Add(1)
+			}
+			if !info.WasIdle {
+				poolWaits.Add(1)
+				poolWaitedNs.Add(int64(time.Since(getConn)))
+			}
+		},
+	}
+}
+
+// readPool reads the pool meter's totals.
+func readPool() benchmark.PoolStats {
+	return benchmark.PoolStats{
+		WaitCount:    poolWaits.Load(),
+		WaitDuration: time.Duration(poolWaitedNs.Load()),
+		Opened:       poolOpened.Load(),
+		MaxOpen:      connection.DynamoDBHTTP.MaxConns,
+	}
+}
+
+// withErrorTypes counts every failed API call by its exception name, once
+// the SDK has given up retrying it.
+func withErrorTypes(o *dynamodb.Options) {
+	o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("ErrorTypes",
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				out, metadata, err := next.HandleInitialize(ctx, in)
+				if err != nil {
+					benchmark.CountError(errorType(err))
+				}
+				return out, metadata, err
+			}), middleware.After)
+	})
+}
+
+// withTrace adds every API call to the operation trace, when one is being
+// written (see benchmark.SetTrace), with the key it addressed and the
+// capacity it consumed. Like withErrorTypes it sees each call once, after
+// the SDK's retries.
+func withTrace(o *dynamodb.Options) {
+	if !benchmark.TraceEnabled() {
+		return
+	}
+	o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("Trace",
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				start := time.Now()
+				out, metadata, err := next.HandleInitialize(ctx, in)
+				consumed, ok := capacity.Consumed(out.Result)
+				if !ok {
+					consumed = -1
+				}
+				kind := ""
+				if err != nil {
+					kind = errorType(err)
+				}
+				benchmark.TraceOp(awsmiddleware.GetOperationName(ctx), traceKey(in.Parameters), start, err, kind, consumed)
+				return out, metadata, err
+			}), middleware.After)
+	})
+}
+
+// withCostCeiling charges every API call's consumed capacity against the
+// ceiling benchmark.SetCostCeiling set, asking each to return it. Requests
+// that fail are charged what they report, which is usually nothing.
+func withCostCeiling(o *dynamodb.Options) {
+	if benchmark.CostCeiling() <= 0 {
+		return
+	}
+	o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("CostCeiling",
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				write, ok := capacity.RequestConsumed(in.Parameters)
+				out, metadata, err := next.HandleInitialize(ctx, in)
+				if consumed, charged := capacity.Consumed(out.Result); ok && charged {
+					benchmark.ChargeCapacity(consumed, write)
+				}
+				return out, metadata, err
+			}), middleware.After)
+	})
+}
+
+// traceKey is the key a request addresses, as PK/SK, or for a query its
+// partition key value. Batches and transactions give their first item's
+// key and how many more there are.
+func traceKey(input any) string {
+	switch in := input.(type) {
+	case *dynamodb.GetItemInput:
+		return itemKey(in.Key)
+	case *dynamodb.PutItemInput:
+		return itemKey(in.Item)
+	case *dynamodb.UpdateItemInput:
+		return itemKey(in.Key)
+	case *dynamodb.DeleteItemInput:
+		return itemKey(in.Key)
+	case *dynamodb.QueryInput:
+		expr := aws.ToString(in.KeyConditionExpression)
+		if i := strings.Index(expr, "= :"); i >= 0 {
+			name, _, _ := strings.Cut(expr[i+2:], " ")
+			if v, ok := in.ExpressionAttributeValues[name].(*types.AttributeValueMemberS); ok {
+				return v.Value
+			}
+		}
+	case *dynamodb.TransactWriteItemsInput:
+		if len(in.TransactItems) == 0 {
+			return ""
+		}
+		var key string
+		switch item := in.TransactItems[0]; {
+		case item.Put != nil:
+			key = itemKey(item.Put.Item)
+		case item.Update != nil:
+			key = itemKey(item.Update.Key)
+		case item.Delete != nil:
+			key = itemKey(item.Delete.Key)
+		case item.ConditionCheck != nil:
+			key = itemKey(item.ConditionCheck.Key)
+		}
+		return moreKeys(key, len(in.TransactItems))
+	case *dynamodb.BatchWriteItemInput:
+		for _, requests := range in.RequestItems {
+			if len(requests) > 0 && requests[0].PutRequest != nil {
+				return moreKeys(itemKey(requests[0].PutRequest.Item), len(requests))
+			}
+			if len(requests) > 0 && requests[0].DeleteRequest != nil {
+				return moreKeys(itemKey(requests[0].DeleteRequest.Key), len(requests))
+			}
+		}
+	case *dynamodb.BatchGetItemInput:
+		for _, keys := range in.RequestItems {
+			if len(keys.Keys) > 0 {
+				return moreKeys(itemKey(keys.Keys[0]), len(keys.Keys))
+			}
+		}
+	}
+	return ""
+}
+
+// itemKey is an item's PK/SK.
+func itemKey(item map[string]types.AttributeValue) string {
+	pk, _ := item["PK"].(*types.AttributeValueMemberS)
+	sk, _ := item["SK"].(*types.AttributeValueMemberS)
+	if pk == nil {
+		return ""
+	}
+	if sk == nil {
+		return pk.Value
+	}
+	return pk.Value + "/" + sk.Value
+}
+
+// moreKeys notes that key is the first of n.
+func moreKeys(key string, n int) string {
+	if key == "" || n <= 1 {
+		return key
+	}
+	return fmt.Sprintf("%s (+%d)", key, n-1)
+}
+
+// errorType is the exception name DynamoDB failed a call with, such as
+// ProvisionedThroughputExceededException or ConditionalCheckFailedException,
+// or what kept the call from getting a response.
+func errorType(err error) string {
+	var apiErr smithy.APIError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr.ErrorCode()
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.As(err, &netErr):
+		return "connection"
+	}
+	return "other"
+}
+
+// Context keys for withLatencyPhases.
+type (
+	marshalStartKey struct{}
+	httpTimeKey     struct{}
+)
+
+// withLatencyPhases times each API call's phases when the breakdown is on
+// (see benchmark.SetPhases): marshal is the serialize step, building the
+// JSON request; http is the round trip to the response headers, once per
+// attempt; unmarshal is the rest of the deserialize step, reading and
+// decoding the response body.
+func withLatencyPhases(o *dynamodb.Options) {
+	if !benchmark.PhasesEnabled() {
+		return
+	}
+	o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+		err := stack.Serialize.Add(middleware.SerializeMiddlewareFunc("PhaseMarshalStart",
+			func(ctx context.Context, in middleware.SerializeInput, next middleware.SerializeHandler) (middleware.SerializeOutput, middleware.Metadata, error) {
+				return next.HandleSerialize(context.WithValue(ctx, marshalStartKey{}, time.Now()), in)
+			}), middleware.Before)
+		if err != nil {
+			return err
+		}
+		err = stack.Build.Add(middleware.BuildMiddlewareFunc("PhaseMarshalEnd",
+			func(ctx context.Context, in middleware.BuildInput, next middleware.BuildHandler) (middleware.BuildOutput, middleware.Metadata, error) {
+				if start, ok := ctx.Value(marshalStartKey{}).(time.Time); ok {
+					benchmark.RecordPhase("marshal", time.Since(start))
+				}
+				return next.HandleBuild(ctx, in)
+			}), middleware.Before)
+		if err != nil {
+			return err
+		}
+		err = stack.Deserialize.Add(middleware.DeserializeMiddlewareFunc("PhaseUnmarshal",
+			func(ctx context.Context, in middleware.DeserializeInput, next middleware.DeserializeHandler) (middleware.DeserializeOutput, middleware.Metadata, error) {
+				var http time.Duration
+				start := time.Now()
+				out, metadata, err := next.HandleDeserialize(context.WithValue(ctx, httpTimeKey{}, &http), in)
+				benchmark.RecordPhase("http", http)
+				benchmark.RecordPhase("unmarshal", time.Since(start)-http)
+				return out, metadata, err
+			}), middleware.Before)
+		if err != nil {
+			return err
+		}
+		// Added last with After, this runs innermost, right around the
+		// HTTP client.
+		return stack.Deserialize.Add(middleware.DeserializeMiddlewareFunc("PhaseHTTP",
+			func(ctx context.Context, in middleware.DeserializeInput, next middleware.DeserializeHandler) (middleware.DeserializeOutput, middleware.Metadata, error) {
+				start := time.Now()
+				out, metadata, err := next.HandleDeserialize(ctx, in)
+				if http, ok := ctx.Value(httpTimeKey{}).(*time.Duration); ok {
+					*http += time.Since(start)
+				}
+				return out, metadata, err
+			}), middleware.After)
+	})
+}
+
+// Clean deletes the benchmark table and everything in it, and recreates it
+// empty from schema.json, ready for the next Seed.
+func Clean() {
+	connect()
+	recreateTable()
+
+	if err := benchmark.RemoveIDs("dynamodb"); err != nil {
+		slog.Error("Failed to remove seeded IDs", "err", err)
+	}
+
+	slog.Info("Removed all DynamoDB benchmark data")
+}
+
+// deleteTable drops the benchmark table if it exists.
+func deleteTable() {
+	_, err := client.DeleteTable(ctx, &dynamodb.DeleteTableInput{TableName: aws.String(connection.DynamoDBTable)})
+	var notFound *types.ResourceNotFoundException
+	if err != nil && !errors.As(err, &notFound) {
+		benchmark.Fatal("Failed to delete table", "err", err)
+	}
+}
+
+// recreateTable drops the benchmark table, waits for it to be gone and
+// creates it again, empty.
+func recreateTable() {
+	deleteTable()
+	waiter := dynamodb.NewTableNotExistsWaiter(client)
+	if err := waiter.Wait(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(connection.DynamoDBTable)}, 2*time.Minute); err != nil {
+		benchmark.Fatal("Failed waiting for table deletion", "err", err)
+	}
+	createTable(ctx, client)
+}
+
+func loadTestData() {
+	slog.Info("Loading test data from DynamoDB")
+
+	accountIDs, transactionIDs, merchantIDs, userIDs, anomalies = nil, nil, nil, nil, nil
+
+	seeded, err := benchmark.LoadIDs("dynamodb")
+	if err != nil {
+		benchmark.Fatal("Failed to load seeded IDs", "err", err)
+	}
+	if seeded != nil {
+		accountIDs = benchmark.SampleBy(seeded.Accounts, seeded.AccountLegs, 100)
+		transactionIDs = benchmark.SampleNewest(seeded.Transactions, seeded.TransactionAgeDays, 1000)
+		merchantIDs = benchmark.SampleBy(seeded.Merchants, seeded.MerchantTransactions, 100)
+		userIDs = benchmark.Sample(seeded.Users, 100)
+		anomalies = seeded.Anomalies
+	} else {
+		if benchmark.Stratified() {
+			slog.Info("Stratified sampling needs the seeded-ID file; sampling uniformly from the table")
+		}
+		for _, entity := range []struct {
+			itemType string
+			ids      *[]string
+			limit    int
+		}{
+			{"Account", &accountIDs, 100},
+			{"Transaction", &transactionIDs, 1000},
+			{"Merchant", &merchantIDs, 100},
+		} {
+			sampleItems(entity.itemType, entity.limit, func(item map[string]types.AttributeValue) {
+				*entity.ids = append(*entity.ids, stringAttr(item, "ID"))
+				if userID := stringAttr(item, "UserID"); userID != "" {
+					userIDs = append(userIDs, userID)
+				}
+			})
+		}
+	}
+
+	slog.Info("Loaded test data", "accounts", len(accountIDs), "transactions", len(transactionIDs),
+		"merchants", len(merchantIDs), "users", len(userIDs))
+
+	if len(accountIDs) == 0 || len(merchantIDs) == 0 {
+		benchmark.Fatal("No test data found in DynamoDB; run `benchctl seed --db=dynamodb` first")
+	}
+}
+
+// sampleSegments is how finely sampleItems splits the table. Each segment
+// holds a hash-distributed, so effectively random, slice of the keys.
+const sampleSegments = 64
+
+// sampleItems visits up to limit seeded items of itemType, drawn uniformly
+// from the whole table: it scans randomly chosen parallel-scan segments until
+// it has enough, instead of taking whatever the first page of a full scan
+// returns. Only seeded items qualify, so earlier runs' writes never become
+// test targets.
+func sampleItems(itemType string, limit int, visit func(item map[string]types.AttributeValue)) {
+	seen := 0
+	for _, segment := range benchmark.Rand().Perm(sampleSegments) {
+		input := &dynamodb.ScanInput{
+			TableName:                aws.String(connection.DynamoDBTable),
+			FilterExpression:         aws.String("#t = :type AND attribute_not_exists(BenchmarkRunID)"),
+			ProjectionExpression:     aws.String("ID, UserID"),
+			ExpressionAttributeNames: map[string]string{"#t": "Type"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":type": &types.AttributeValueMemberS{Value: itemType},
+			},
+			Segment:       aws.Int32(int32(segment)),
+			TotalSegments: aws.Int32(sampleSegments),
+		}
+		for {
+			output, err := client.Scan(ctx, input)
+			if err != nil {
+				benchmark.Fatal("Failed to load test data", "err", err)
+			}
+			for _, item := range output.Items {
+				if seen == limit {
+					return
+				}
+				visit(item)
+				seen++
+			}
+			if output.LastEvaluatedKey == nil {
+				break
+			}
+			input.ExclusiveStartKey = output.LastEvaluatedKey
+		}
+	}
+}
+
+// batchWriteWithRetry issues a BatchWriteItem and retries unprocessed items,
+// backing off exponentially, until DynamoDB accepts them all or
+// batchWriteAttempts run out, and returns the capacity every attempt
+// consumed. Items still unprocessed then, or when a retry fails, are
+// counted as dropped (see benchmark.CountDropped) and fail it.
+func batchWriteWithRetry(requests []types.WriteRequest) (float64, error) {
+	pending := map[string][]types.WriteRequest{connection.DynamoDBTable: requests}
+	backoff := 50 * time.Millisecond
+	wcu := 0.0
+
+	for attempt := 0; attempt < batchWriteAttempts; attempt++ {
+		output, err := client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems:           pending,
+			ReturnConsumedCapacity: types.ReturnConsumedCapacityTotal,
+		})
+		if err != nil {
+			// The earlier attempts wrote the rest of the batch, so only
+			// the retried items are lost.
+			if attempt > 0 {
+				benchmark.CountDropped(len(pending[connection.DynamoDBTable]))
+			}
+			return wcu, err
+		}
+		for _, cc := range output.ConsumedCapacity {
+			wcu += aws.ToFloat64(cc.CapacityUnits)
+		}
+		pending = output.UnprocessedItems
+		if len(pending) == 0 {
+			return wcu, nil
+		}
+		time.Sleep(backoff)
+		backoff = min(2*backoff, 5*time.Second)
+	}
+	dropped := len(pending[connection.DynamoDBTable])
+	benchmark.CountDropped(dropped)
+	return wcu, fmt.Errorf("%d items still unprocessed after %d attempts", dropped, batchWriteAttempts)
+}
+
+// batchWriteAttempts bounds how many times batchWriteWithRetry sends a
+// batch, about 25 seconds of backoff in all.
+const batchWriteAttempts = 12
+
+// deleteItems removes the given primary keys in batches of 25.
+func deleteItems(keys []map[string]types.AttributeValue) {
+	for start := 0; start < len(keys); start += 25 {
+		end := min(start+25, len(keys))
+
+		requests := make([]types.WriteRequest, 0, end-start)
+		for _, key := range keys[start:end] {
+			requests = append(requests, types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: key}})
+		}
+		if _, err := batchWriteWithRetry(requests); err != nil {
+			slog.Error("Failed to write batch", "err", err)
+		}
+	}
+}
+
+// isTransient reports whether err is worth retrying (see
+// benchmark.SetRetryPolicy): throttling, transaction conflicts, server
+// errors and connection failures, including transactions cancelled for
+// any of those.
+func isTransient(err error) bool {
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		for _, reason := range canceled.CancellationReasons {
+			switch aws.ToString(reason.Code) {
+			case "TransactionConflict", "ThrottlingError", "ProvisionedThroughputExceeded":
+				return true
+			}
+		}
+		return false
+	}
+
+	var apiErr smithy.APIError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &apiErr):
+		switch apiErr.ErrorCode() {
+		case "ProvisionedThroughputExceededException", "RequestLimitExceeded", "ThrottlingException",
+			"TransactionConflictException", "InternalServerError", "ServiceUnavailable":
+			return true
+		}
+		return false
+	case errors.Is(err, context.DeadlineExceeded):
+		return false
+	case errors.As(err, &netErr):
+		return true
+	}
+	return false
+}
+
+func isThrottled(err error) bool {
+	var throughputErr *types.ProvisionedThroughputExceededException
+	var limitErr *types.RequestLimitExceeded
+	return errors.As(err, &throughputErr) || errors.As(err, &limitErr)
+}
+
+func calculateResults(testName string, totalOps, concurrency int, durations []time.Duration, success, errors int, totalDuration time.Duration) benchmark.Result {
+	return benchmark.Summarize(testName, "DynamoDB", totalOps, concurrency, durations, success, errors, totalDuration)
+}
@@ -0,0 +1,480 @@
+package dynamodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/capacity"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+)
+
+// mockDynamoDB is an HTTP client that answers DynamoDB API calls with
+// scripted replies, so the SDK's serializers, the benchmark middlewares and
+// the retry layer all run as they do against a real table.
+type mockDynamoDB struct {
+	mu      sync.Mutex
+	replies map[string][]mockReply
+	calls   map[string]int
+}
+
+type mockReply struct {
+	status int
+	body   string
+}
+
+func okReply(body string) mockReply {
+	return mockReply{http.StatusOK, body}
+}
+
+func exception(status int, name, message string) mockReply {
+	return mockReply{status, fmt.Sprintf(`{"__type":"com.amazonaws.dynamodb.v20120810#%s","message":%q}`, name, message)}
+}
+
+// reply queues replies to operation, in order. The last one repeats.
+func (m *mockDynamoDB) reply(operation string, replies ...mockReply) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.replies[operation] = append(m.replies[operation], replies...)
+}
+
+func (m *mockDynamoDB) callsTo(operation string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls[operation]
+}
+
+func (m *mockDynamoDB) Do(req *http.Request) (*http.Response, error) {
+	operation := strings.TrimPrefix(req.Header.Get("X-Amz-Target"), "DynamoDB_20120810.")
+
+	m.mu.Lock()
+	m.calls[operation]++
+	queued := m.replies[operation]
+	if len(queued) == 0 {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("mock: no reply for %s", operation)
+	}
+	r := queued[0]
+	if len(queued) > 1 {
+		m.replies[operation] = queued[1:]
+	}
+	m.mu.Unlock()
+
+	return &http.Response{
+		StatusCode: r.status,
+		Header:     http.Header{"Content-Type": {"application/x-amz-json-1.0"}},
+		Body:       io.NopCloser(bytes.NewReader([]byte(r.body))),
+		Request:    req,
+	}, nil
+}
+
+// newMock points the package client at a mock with the middlewares connect
+// installs, and resets the stats the tests read when the test finishes.
+// Set the retry policy, and start any trace, before calling it, as benchctl
+// does before connect.
+func newMock(t *testing.T) *mockDynamoDB {
+	t.Helper()
+	mock := &mockDynamoDB{replies: make(map[string][]mockReply), calls: make(map[string]int)}
+	client = dynamodb.New(dynamodb.Options{
+		Region:       "us-east-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("mock", "mock", ""),
+		BaseEndpoint: aws.String("http://dynamodb.mock"),
+		HTTPClient:   mock,
+	}, clientOptions...)
+	benchmark.SetRetryable(isTransient)
+
+	t.Cleanup(func() {
+		client = nil
+		capacityModel.Store(nil)
+		benchmark.SetRetryPolicy(benchmark.RetryPolicy{})
+		takeStats()
+	})
+	takeStats()
+	return mock
+}
+
+// takeStats summarizes one operation and adds it to a suite, which takes
+// and resets everything tallied since the last test: error types, capacity
+// predictions, timeouts and retries.
+func takeStats() benchmark.Result {
+	var suite benchmark.Suite
+	suite.Add(benchmark.Summarize("test", "DynamoDB", 1, 1, []time.Duration{time.Millisecond}, 0, 1, time.Millisecond))
+	return suite.Results[0]
+}
+
+func getItem() error {
+	_, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(connection.DynamoDBTable),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: "ACCOUNT#1"},
+			"SK": &types.AttributeValueMemberS{Value: "METADATA"},
+		},
+	})
+	return err
+}
+
+func TestThrottlingRetriedByRetryLayer(t *testing.T) {
+	benchmark.SetRetryPolicy(benchmark.RetryPolicy{MaxRetries: 3, BaseDelay: time.Microsecond})
+	mock := newMock(t)
+	throttled := exception(http.StatusBadRequest, "ProvisionedThroughputExceededException", "The level of configured provisioned throughput for the table was exceeded.")
+	mock.reply("GetItem", throttled, throttled, okReply(`{}`))
+
+	if err := benchmark.Retrying(getItem)(); err != nil {
+		t.Fatalf("retried GetItem failed: %v", err)
+	}
+
+	// The SDK's retries are off, so each retry is one request.
+	if got := mock.callsTo("GetItem"); got != 3 {
+		t.Errorf("GetItem requests = %d, want 3", got)
+	}
+	result := takeStats()
+	if result.Retries != 2 {
+		t.Errorf("Retries = %d, want 2", result.Retries)
+	}
+	if got := result.ErrorsByType["ProvisionedThroughputExceededException"]; got != 2 {
+		t.Errorf("ErrorsByType = %v, want 2 ProvisionedThroughputExceededException", result.ErrorsByType)
+	}
+}
+
+func TestConditionalCheckNotRetried(t *testing.T) {
+	benchmark.SetRetryPolicy(benchmark.RetryPolicy{MaxRetries: 3, BaseDelay: time.Microsecond})
+	mock := newMock(t)
+	mock.reply("PutItem", exception(http.StatusBadRequest, "ConditionalCheckFailedException", "The conditional request failed"))
+
+	err := benchmark.Retrying(func() error {
+		_, err := client.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName:           aws.String(connection.DynamoDBTable),
+			Item:                map[string]types.AttributeValue{"PK": &types.AttributeValueMemberS{Value: "TXN#1"}, "SK": &types.AttributeValueMemberS{Value: "METADATA"}},
+			ConditionExpression: aws.String("attribute_not_exists(PK)"),
+		})
+		return err
+	})()
+	var conditional *types.ConditionalCheckFailedException
+	if !errors.As(err, &conditional) {
+		t.Fatalf("PutItem error = %v, want ConditionalCheckFailedException", err)
+	}
+
+	if got := mock.callsTo("PutItem"); got != 1 {
+		t.Errorf("PutItem requests = %d, want 1", got)
+	}
+	result := takeStats()
+	if result.Retries != 0 || result.ErrorsByType["ConditionalCheckFailedException"] != 1 {
+		t.Errorf("Retries = %d, ErrorsByType = %v, want 0 and 1 ConditionalCheckFailedException", result.Retries, result.ErrorsByType)
+	}
+}
+
+func TestCancelledTransactionRetriedOnConflict(t *testing.T) {
+	benchmark.SetRetryPolicy(benchmark.RetryPolicy{MaxRetries: 3, BaseDelay: time.Microsecond})
+	mock := newMock(t)
+	canceled := func(reasons ...string) mockReply {
+		codes := make([]string, len(reasons))
+		for i, r := range reasons {
+			codes[i] = fmt.Sprintf(`{"Code":%q}`, r)
+		}
+		return mockReply{http.StatusBadRequest, fmt.Sprintf(`{"__type":"com.amazonaws.dynamodb.v20120810#TransactionCanceledException","message":"Transaction cancelled","CancellationReasons":[%s]}`, strings.Join(codes, ","))}
+	}
+	mock.reply("TransactWriteItems", canceled("None", "TransactionConflict"), canceled("ConditionalCheckFailed", "None"))
+
+	err := benchmark.Retrying(func() error {
+		_, err := client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: []types.TransactWriteItem{{
+			Put: &types.Put{
+				TableName: aws.String(connection.DynamoDBTable),
+				Item:      map[string]types.AttributeValue{"PK": &types.AttributeValueMemberS{Value: "TXN#1"}, "SK": &types.AttributeValueMemberS{Value: "METADATA"}},
+			},
+		}}})
+		return err
+	})()
+	if err == nil {
+		t.Fatal("TransactWriteItems succeeded")
+	}
+
+	// The conflict is retried; the failed condition that follows is not.
+	if got := mock.callsTo("TransactWriteItems"); got != 2 {
+		t.Errorf("TransactWriteItems requests = %d, want 2", got)
+	}
+	result := takeStats()
+	if result.Retries != 1 || result.ErrorsByType["TransactionCanceledException"] != 2 {
+		t.Errorf("Retries = %d, ErrorsByType = %v, want 1 and 2 TransactionCanceledException", result.Retries, result.ErrorsByType)
+	}
+}
+
+func TestFailedBatchRetryCountsDropped(t *testing.T) {
+	mock := newMock(t)
+	unprocessed := fmt.Sprintf(`{"UnprocessedItems":{%q:[{"PutRequest":{"Item":{"PK":{"S":"TXN#2"},"SK":{"S":"METADATA"}}}},{"PutRequest":{"Item":{"PK":{"S":"TXN#3"},"SK":{"S":"METADATA"}}}}]}}`, connection.DynamoDBTable)
+	mock.reply("BatchWriteItem", okReply(unprocessed), exception(http.StatusBadRequest, "ResourceNotFoundException", "Requested resource not found"))
+
+	put := func(pk string) types.WriteRequest {
+		return types.WriteRequest{PutRequest: &types.PutRequest{Item: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: "METADATA"},
+		}}}
+	}
+	if _, err := batchWriteWithRetry([]types.WriteRequest{put("TXN#1"), put("TXN#2"), put("TXN#3")}); err == nil {
+		t.Fatal("batch write succeeded though its retry failed")
+	}
+
+	if got := mock.callsTo("BatchWriteItem"); got != 2 {
+		t.Errorf("BatchWriteItem requests = %d, want 2", got)
+	}
+	if result := takeStats(); result.DroppedItems != 2 {
+		t.Errorf("DroppedItems = %d, want the 2 items the failed retry carried", result.DroppedItems)
+	}
+}
+
+func TestGetItemResults(t *testing.T) {
+	mock := newMock(t)
+	capacityModel.Store(&capacity.Model{})
+	transactionIDs = []string{"1"}
+	t.Cleanup(func() { transactionIDs = nil })
+
+	item := `{"Item":{"PK":{"S":"TXN#1"},"SK":{"S":"METADATA"},"Status":{"S":"COMPLETED"}},"ConsumedCapacity":{"TableName":"financial-transactions","CapacityUnits":0.5}}`
+	mock.reply("GetItem", okReply(item), okReply(item), exception(http.StatusBadRequest, "ResourceNotFoundException", "Requested resource not found"))
+
+	result := benchmarkGetItem(3, "transaction")
+	if result.SuccessCount != 2 || result.ErrorCount != 1 {
+		t.Errorf("SuccessCount, ErrorCount = %d, %d, want 2, 1", result.SuccessCount, result.ErrorCount)
+	}
+	if result.ItemsReturned != 2 || result.ConsumedRCU != 1 {
+		t.Errorf("ItemsReturned, ConsumedRCU = %d, %v, want 2, 1", result.ItemsReturned, result.ConsumedRCU)
+	}
+
+	var suite benchmark.Suite
+	suite.Add(result)
+	added := suite.Results[0]
+	if added.ErrorsByType["ResourceNotFoundException"] != 1 {
+		t.Errorf("ErrorsByType = %v, want 1 ResourceNotFoundException", added.ErrorsByType)
+	}
+	if len(added.CapacityPredictions) != 1 {
+		t.Fatalf("CapacityPredictions = %v, want one for GetItem", added.CapacityPredictions)
+	}
+	p := added.CapacityPredictions[0]
+	if p.Operation != "GetItem" || p.Requests != 2 || p.PredictedUnits != 1 || p.ConsumedUnits != 1 {
+		t.Errorf("CapacityPredictions = %+v, want 2 GetItems predicted and consuming 1 RCU", p)
+	}
+}
+
+func TestAPICallsTraced(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.csv")
+	if err := benchmark.SetTrace(path); err != nil {
+		t.Fatal(err)
+	}
+	defer benchmark.SetTrace("")
+	mock := newMock(t)
+	item := `{"Item":{"PK":{"S":"ACCOUNT#1"},"SK":{"S":"METADATA"}},"ConsumedCapacity":{"TableName":"financial-transactions","CapacityUnits":0.5}}`
+	mock.reply("GetItem", okReply(item), exception(http.StatusBadRequest, "ResourceNotFoundException", "Requested resource not found"))
+
+	getItem()
+	getItem()
+	if err := benchmark.SetTrace(""); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("trace has %d rows, want a header and 2 calls:\n%s", len(rows), data)
+	}
+	// op, error_type, key and consumed_capacity.
+	for i, want := range [][4]string{
+		{"GetItem", "", "ACCOUNT#1/METADATA", "0.5"},
+		{"GetItem", "ResourceNotFoundException", "ACCOUNT#1/METADATA", ""},
+	} {
+		row := rows[i+1]
+		if got := [4]string{row[2], row[4], row[6], row[7]}; got != want {
+			t.Errorf("row %d = %v, want %v", i+1, got, want)
+		}
+	}
+}
+
+func TestTraceKey(t *testing.T) {
+	for _, tc := range []struct {
+		input any
+		want  string
+	}{
+		{&dynamodb.QueryInput{
+			KeyConditionExpression:    aws.String("GSI1PK = :account AND begins_with(GSI1SK, :leg)"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{":account": &types.AttributeValueMemberS{Value: "ACCOUNT#7"}},
+		}, "ACCOUNT#7"},
+		{&dynamodb.PutItemInput{Item: map[string]types.AttributeValue{"PK": &types.AttributeValueMemberS{Value: "TXN#1"}}}, "TXN#1"},
+		{&dynamodb.TransactWriteItemsInput{TransactItems: []types.TransactWriteItem{
+			{Update: &types.Update{Key: map[string]types.AttributeValue{
+				"PK": &types.AttributeValueMemberS{Value: "ACCOUNT#1"},
+				"SK": &types.AttributeValueMemberS{Value: "METADATA"},
+			}}},
+			{Put: &types.Put{}},
+			{Put: &types.Put{}},
+		}}, "ACCOUNT#1/METADATA (+2)"},
+		{&dynamodb.ScanInput{}, ""},
+	} {
+		if got := traceKey(tc.input); got != tc.want {
+			t.Errorf("traceKey(%T) = %q, want %q", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestConsistentRead(t *testing.T) {
+	get := &dynamodb.GetItemInput{}
+	eventual := &dynamodb.GetItemInput{ConsistentRead: aws.Bool(false)}
+	query := &dynamodb.QueryInput{}
+	gsi := &dynamodb.QueryInput{IndexName: aws.String("GSI1")}
+	batch := &dynamodb.BatchGetItemInput{RequestItems: map[string]types.KeysAndAttributes{"ledger": {}}}
+	for _, input := range []any{get, eventual, query, gsi, batch} {
+		consistentRead(input)
+	}
+
+	if !aws.ToBool(get.ConsistentRead) || !aws.ToBool(query.ConsistentRead) || !aws.ToBool(batch.RequestItems["ledger"].ConsistentRead) {
+		t.Error("reads that left ConsistentRead unset are not strongly consistent")
+	}
+	if aws.ToBool(eventual.ConsistentRead) {
+		t.Error("a read that asked for eventual consistency was made strong")
+	}
+	if gsi.ConsistentRead != nil {
+		t.Error("a global secondary index query was made strongly consistent")
+	}
+}
+
+func TestErrorType(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&types.ProvisionedThroughputExceededException{}, "ProvisionedThroughputExceededException"},
+		{&types.TransactionCanceledException{}, "TransactionCanceledException"},
+		{context.DeadlineExceeded, "timeout"},
+		{context.Canceled, "canceled"},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, "connection"},
+		{errors.New("boom"), "other"},
+	}
+	for _, tt := range tests {
+		if got := errorType(tt.err); got != tt.want {
+			t.Errorf("errorType(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	canceled := func(code string) error {
+		return &types.TransactionCanceledException{CancellationReasons: []types.CancellationReason{{Code: aws.String("None")}, {Code: aws.String(code)}}}
+	}
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&types.ProvisionedThroughputExceededException{}, true},
+		{&types.RequestLimitExceeded{}, true},
+		{&types.TransactionConflictException{}, true},
+		{&types.InternalServerError{}, true},
+		{canceled("TransactionConflict"), true},
+		{canceled("ThrottlingError"), true},
+		{canceled("ConditionalCheckFailed"), false},
+		{&types.ConditionalCheckFailedException{}, false},
+		{&types.ResourceNotFoundException{}, false},
+		{context.DeadlineExceeded, false},
+		{&net.OpError{Op: "read", Err: errors.New("connection reset by peer")}, true},
+		{errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		if got := isTransient(tt.err); got != tt.want {
+			t.Errorf("isTransient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestSeedParallelRetriesUnprocessedItems(t *testing.T) {
+	mock := newMock(t)
+	unprocessed := fmt.Sprintf(`{"UnprocessedItems":{%q:[{"PutRequest":{"Item":{"PK":{"S":"MERCHANT#7"}}}}]}}`, connection.DynamoDBTable)
+	mock.reply("BatchWriteItem", okReply(unprocessed), okReply(`{"UnprocessedItems":{}}`))
+
+	written := seedParallel("merchants", 60, 4, func(i int) []any {
+		return []any{Merchant{PK: fmt.Sprintf("MERCHANT#%d", i), SK: "METADATA"}}
+	})
+	for i, ok := range written {
+		if !ok {
+			t.Fatalf("merchant %d not written", i)
+		}
+	}
+	// Three batches of up to 25, one sent again for its unprocessed item.
+	if calls := mock.callsTo("BatchWriteItem"); calls != 4 {
+		t.Errorf("%d BatchWriteItem calls, want 4", calls)
+	}
+
+	// A transaction's three items share a batch: eight to a batch.
+	seedParallel("transactions", 10, 1, func(i int) []any {
+		return []any{Transaction{PK: "TXN"}, TransactionLeg{SK: "LEG#1"}, TransactionLeg{SK: "LEG#2"}}
+	})
+	if calls := mock.callsTo("BatchWriteItem") - 4; calls != 2 {
+		t.Errorf("%d BatchWriteItem calls for ten transactions, want 2", calls)
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	mock := newMock(t)
+	mock.reply("DescribeTable", okReply(`{"Table":{"TableStatus":"ACTIVE","GlobalSecondaryIndexes":[
+		{"IndexName":"GSI1","IndexStatus":"ACTIVE"},{"IndexName":"GSI2","IndexStatus":"ACTIVE"},{"IndexName":"GSI3","IndexStatus":"CREATING"}]}}`))
+	// One seeded item a page: the first four types need five pages, and
+	// the legs run out one short.
+	more := okReply(`{"Count":1,"ScannedCount":100,"LastEvaluatedKey":{"PK":{"S":"ACCOUNT#1"},"SK":{"S":"METADATA"}}}`)
+	mock.reply("Scan", more, more, more, more, more, more, okReply(`{"Count":0,"ScannedCount":100}`))
+
+	failed := make(map[string]string)
+	for _, check := range preflight() {
+		if check.Err != nil {
+			failed[check.Name] = check.Err.Error()
+		}
+	}
+	if len(failed) != 2 || !strings.Contains(failed["Global secondary indexes"], "inactive GSI3;") ||
+		!strings.HasPrefix(failed["Seeded TransactionLeg items (at least 2)"], "found 1;") {
+		t.Errorf("failed checks %v, want the GSI3 index and the legs", failed)
+	}
+	if calls := mock.callsTo("Scan"); calls != 7 {
+		t.Errorf("%d Scan calls, want 7", calls)
+	}
+
+	mock.replies["DescribeTable"] = []mockReply{exception(http.StatusBadRequest, "ResourceNotFoundException", "Requested resource not found")}
+	if checks := preflight(); len(checks) != 1 || checks[0].Err == nil || !strings.Contains(checks[0].Err.Error(), "benchctl seed --db=dynamodb") {
+		t.Errorf("missing table gave %+v", checks)
+	}
+}
+
+func TestTableInputBillingMode(t *testing.T) {
+	defer benchmark.SetBillingMode("")
+	input, err := tableInput()
+	if err != nil || input.BillingMode != types.BillingModeProvisioned || input.ProvisionedThroughput == nil {
+		t.Fatalf("provisioned table %+v, %v", input, err)
+	}
+
+	benchmark.SetBillingMode("on-demand")
+	if input, err = tableInput(); err != nil {
+		t.Fatal(err)
+	}
+	if input.BillingMode != types.BillingModePayPerRequest || input.ProvisionedThroughput != nil || len(input.GlobalSecondaryIndexes) != 3 {
+		t.Errorf("on-demand table has billing mode %s, throughput %+v, %d indexes", input.BillingMode, input.ProvisionedThroughput, len(input.GlobalSecondaryIndexes))
+	}
+	for _, index := range input.GlobalSecondaryIndexes {
+		if index.ProvisionedThroughput != nil {
+			t.Errorf("on-demand index %s has throughput %+v", aws.ToString(index.IndexName), index.ProvisionedThroughput)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package dynamodb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+)
+
+// seededMinimums are the fewest seeded items of each type the suites can
+// sample from, as dataset.Volumes.Validate allows.
+var seededMinimums = []struct {
+	itemType string
+	min      int32
+}{
+	{"Merchant", 1},
+	{"User", 1},
+	{"Account", 2},
+	{"Transaction", 1},
+	{"TransactionLeg", 2},
+}
+
+// Preflight prints whether DynamoDB is ready for the suites and reports
+// whether it is.
+func Preflight() bool {
+	checks := []benchmark.PreflightCheck{{Name: "DynamoDB client"}}
+	var err error
+	if client, err = connection.NewDynamoDBClient(ctx, clientOptions...); err != nil {
+		checks[0].Err = err
+	} else {
+		checks = preflight()
+	}
+	return benchmark.PrintPreflight(os.Stdout, "DynamoDB", checks)
+}
+
+// preflight checks that DynamoDB answers at connection.DynamoDBEndpoint,
+// that the table and the indexes in schema.json exist and are active, and
+// that the table has been seeded, stopping at the first check the later
+// ones depend on.
+func preflight() []benchmark.PreflightCheck {
+	table := benchmark.PreflightCheck{Name: "Table " + connection.DynamoDBTable}
+	output, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(connection.DynamoDBTable)})
+	var notFound *types.ResourceNotFoundException
+	switch {
+	case errors.As(err, &notFound):
+		table.Err = fmt.Errorf("no such table at %s; `benchctl init --db=dynamodb` creates it, and `benchctl seed --db=dynamodb` creates and seeds it", connection.DynamoDBTarget())
+	case err != nil:
+		table.Err = fmt.Errorf("cannot reach DynamoDB at %s (%w); start DynamoDB Local with `make start`, or point BENCH_DDB_ENDPOINT or --ddb-endpoint at a running one", connection.DynamoDBTarget(), err)
+	case output.Table.TableStatus != types.TableStatusActive:
+		table.Err = fmt.Errorf("table is %s; wait for it to become ACTIVE", output.Table.TableStatus)
+	}
+	checks := []benchmark.PreflightCheck{table}
+	if table.Err != nil {
+		return checks
+	}
+
+	indexes := benchmark.PreflightCheck{Name: "Global secondary indexes"}
+	if missing, err := missingIndexes(output.Table); err != nil {
+		indexes.Err = err
+	} else if len(missing) > 0 {
+		indexes.Err = fmt.Errorf("missing or inactive %s; `benchctl clean --db=dynamodb` recreates the table from schema.json, deleting its data", strings.Join(missing, ", "))
+	}
+	checks = append(checks, indexes)
+
+	for _, m := range seededMinimums {
+		check := benchmark.PreflightCheck{Name: fmt.Sprintf("Seeded %s items (at least %d)", m.itemType, m.min)}
+		n, err := countSeeded(m.itemType, m.min)
+		switch {
+		case err != nil:
+			check.Err = err
+		case n < m.min:
+			check.Err = fmt.Errorf("found %d; seed the table with `benchctl seed --db=dynamodb`", n)
+		}
+		checks = append(checks, check)
+	}
+	return append(checks, benchmark.PreflightIDs("dynamodb"))
+}
+
+// missingIndexes returns the global secondary indexes schema.json declares
+// that table does not have, or has but not yet active.
+func missingIndexes(table *types.TableDescription) ([]string, error) {
+	var want dynamodb.CreateTableInput
+	if err := json.Unmarshal(schema, &want); err != nil {
+		return nil, fmt.Errorf("schema.json: %w", err)
+	}
+	active := make(map[string]bool)
+	for _, index := range table.GlobalSecondaryIndexes {
+		active[aws.ToString(index.IndexName)] = index.IndexStatus == types.IndexStatusActive
+	}
+
+	var missing []string
+	for _, index := range want.GlobalSecondaryIndexes {
+		if name := aws.ToString(index.IndexName); !active[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
+
+// countSeeded counts seeded items of itemType, scanning only until it has
+// found atLeast of them.
+func countSeeded(itemType string, atLeast int32) (int32, error) {
+	input := &dynamodb.ScanInput{
+		TableName:                aws.String(connection.DynamoDBTable),
+		Select:                   types.SelectCount,
+		FilterExpression:         aws.String("#type = :type AND attribute_not_exists(BenchmarkRunID)"),
+		ExpressionAttributeNames: map[string]string{"#type": "Type"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":type": &types.AttributeValueMemberS{Value: itemType},
+		},
+	}
+	var n int32
+	paginator := dynamodb.NewScanPaginator(client, input)
+	for paginator.HasMorePages() && n < atLeast {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return n, err
+		}
+		n += page.Count
+	}
+	return n, nil
+}
@@ -1,35 +1,41 @@
-package main
+package dynamodb
 
 import (
 	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
-	"math/rand"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/google/uuid"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/dataset"
 	"github.com/shopspring/decimal"
 )
 
-const (
-	NumMerchants    = 1000
-	NumAccounts     = 10000
-	NumTransactions = 100000
-	BatchSize       = 25 // DynamoDB batch write limit
-)
+const BatchSize = 25 // DynamoDB batch write limit
 
-var (
-	merchantCategories = []string{"Restaurant", "Retail", "Gas Station", "Grocery", "Entertainment", "Travel", "Healthcare", "Utility"}
-	accountTypes       = []string{"checking", "savings", "credit"}
-	transactionTypes   = []string{"payment", "transfer", "refund", "fee"}
-	currencies         = []string{"USD", "EUR", "GBP"}
-)
+// Conversion rates into the USD reporting currency
+var usdRates = map[string]string{"USD": "1.0", "EUR": "1.08", "GBP": "1.27"}
+
+type ExchangeRate struct {
+	PK            string  `dynamodbav:"PK"`
+	SK            string  `dynamodbav:"SK"`
+	Type          string  `dynamodbav:"Type"`
+	FromCurrency  string  `dynamodbav:"FromCurrency"`
+	ToCurrency    string  `dynamodbav:"ToCurrency"`
+	Rate          Decimal `dynamodbav:"Rate"`
+	EffectiveDate string  `dynamodbav:"EffectiveDate"`
+}
 
 type Merchant struct {
 	PK        string    `dynamodbav:"PK"`
@@ -41,21 +47,35 @@ type Merchant struct {
 	CreatedAt time.Time `dynamodbav:"CreatedAt"`
 }
 
+// User is an account holder's profile. It shares GSI1's USER# partition
+// with their accounts, so one Query returns both.
+type User struct {
+	PK        string    `dynamodbav:"PK"`
+	SK        string    `dynamodbav:"SK"`
+	GSI1PK    string    `dynamodbav:"GSI1PK"`
+	GSI1SK    string    `dynamodbav:"GSI1SK"`
+	Type      string    `dynamodbav:"Type"`
+	ID        string    `dynamodbav:"ID"`
+	Name      string    `dynamodbav:"Name"`
+	Email     string    `dynamodbav:"Email"`
+	CreatedAt time.Time `dynamodbav:"CreatedAt"`
+}
+
 type Account struct {
-	PK          string          `dynamodbav:"PK"`
-	SK          string          `dynamodbav:"SK"`
-	GSI1PK      string          `dynamodbav:"GSI1PK"`
-	GSI1SK      string          `dynamodbav:"GSI1SK"`
-	Type        string          `dynamodbav:"Type"`
-	ID          string          `dynamodbav:"ID"`
-	UserID      string          `dynamodbav:"UserID"`
-	AccountType string          `dynamodbav:"AccountType"`
-	Currency    string          `dynamodbav:"Currency"`
-	Balance     decimal.Decimal `dynamodbav:"Balance"`
-	Status      string          `dynamodbav:"Status"`
-	Version     int             `dynamodbav:"Version"`
-	CreatedAt   time.Time       `dynamodbav:"CreatedAt"`
-	UpdatedAt   time.Time       `dynamodbav:"UpdatedAt"`
+	PK          string    `dynamodbav:"PK"`
+	SK          string    `dynamodbav:"SK"`
+	GSI1PK      string    `dynamodbav:"GSI1PK"`
+	GSI1SK      string    `dynamodbav:"GSI1SK"`
+	Type        string    `dynamodbav:"Type"`
+	ID          string    `dynamodbav:"ID"`
+	UserID      string    `dynamodbav:"UserID"`
+	AccountType string    `dynamodbav:"AccountType"`
+	Currency    string    `dynamodbav:"Currency"`
+	Balance     Decimal   `dynamodbav:"Balance"`
+	Status      string    `dynamodbav:"Status"`
+	Version     int       `dynamodbav:"Version"`
+	CreatedAt   time.Time `dynamodbav:"CreatedAt"`
+	UpdatedAt   time.Time `dynamodbav:"UpdatedAt"`
 }
 
 type Transaction struct {
@@ -65,6 +85,8 @@ type Transaction struct {
 	GSI1SK          string    `dynamodbav:"GSI1SK"`
 	GSI2PK          string    `dynamodbav:"GSI2PK"`
 	GSI2SK          string    `dynamodbav:"GSI2SK"`
+	GSI3PK          string    `dynamodbav:"GSI3PK"`
+	GSI3SK          string    `dynamodbav:"GSI3SK"`
 	Type            string    `dynamodbav:"Type"`
 	ID              string    `dynamodbav:"ID"`
 	IdempotencyKey  string    `dynamodbav:"IdempotencyKey"`
@@ -74,116 +96,327 @@ type Transaction struct {
 	Description     string    `dynamodbav:"Description"`
 	CreatedAt       time.Time `dynamodbav:"CreatedAt"`
 	UpdatedAt       time.Time `dynamodbav:"UpdatedAt"`
-	CompletedAt     time.Time `dynamodbav:"CompletedAt"`
+	CompletedAt     time.Time `dynamodbav:"CompletedAt,omitempty"`
 }
 
 type TransactionLeg struct {
-	PK            string          `dynamodbav:"PK"`
-	SK            string          `dynamodbav:"SK"`
-	GSI1PK        string          `dynamodbav:"GSI1PK"`
-	GSI1SK        string          `dynamodbav:"GSI1SK"`
-	Type          string          `dynamodbav:"Type"`
-	ID            string          `dynamodbav:"ID"`
-	TransactionID string          `dynamodbav:"TransactionID"`
-	AccountID     string          `dynamodbav:"AccountID"`
-	LegType       string          `dynamodbav:"LegType"`
-	Amount        decimal.Decimal `dynamodbav:"Amount"`
-	Currency      string          `dynamodbav:"Currency"`
-	BalanceAfter  decimal.Decimal `dynamodbav:"BalanceAfter"`
-	CreatedAt     time.Time       `dynamodbav:"CreatedAt"`
+	PK            string    `dynamodbav:"PK"`
+	SK            string    `dynamodbav:"SK"`
+	GSI1PK        string    `dynamodbav:"GSI1PK"`
+	GSI1SK        string    `dynamodbav:"GSI1SK"`
+	Type          string    `dynamodbav:"Type"`
+	ID            string    `dynamodbav:"ID"`
+	TransactionID string    `dynamodbav:"TransactionID"`
+	AccountID     string    `dynamodbav:"AccountID"`
+	LegType       string    `dynamodbav:"LegType"`
+	Amount        Decimal   `dynamodbav:"Amount"`
+	Currency      string    `dynamodbav:"Currency"`
+	BalanceAfter  Decimal   `dynamodbav:"BalanceAfter"`
+	CreatedAt     time.Time `dynamodbav:"CreatedAt"`
 }
 
-func main() {
-	ctx := context.Background()
-
-	// Configure AWS SDK for local DynamoDB
-	cfg, err := config.LoadDefaultConfig(ctx,
-		config.WithRegion("us-east-1"),
-		config.WithEndpointResolverWithOptions(aws.EndpointResolverWithOptionsFunc(
-			func(service, region string, options ...interface{}) (aws.Endpoint, error) {
-				return aws.Endpoint{URL: "http://localhost:8000"}, nil
-			})),
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("local", "local", "")),
-	)
+// Decimal is a decimal.Decimal stored as a DynamoDB number. decimal.Decimal
+// has no attributevalue methods of its own, so MarshalMap would store its
+// unexported fields: an empty map, losing the amount.
+type Decimal struct {
+	decimal.Decimal
+}
+
+func (d Decimal) MarshalDynamoDBAttributeValue() (types.AttributeValue, error) {
+	return &types.AttributeValueMemberN{Value: d.String()}, nil
+}
+
+func (d *Decimal) UnmarshalDynamoDBAttributeValue(av types.AttributeValue) error {
+	n, ok := av.(*types.AttributeValueMemberN)
+	if !ok {
+		return fmt.Errorf("decimal: want a number, got %T", av)
+	}
+	value, err := decimal.NewFromString(n.Value)
 	if err != nil {
-		log.Fatal("Failed to load config:", err)
+		return err
 	}
+	d.Decimal = value
+	return nil
+}
 
-	client := dynamodb.NewFromConfig(cfg)
+// schema is the table definition, also accepted by
+// `aws dynamodb create-table --cli-input-json`.
+//
+//go:embed schema.json
+var schema []byte
+
+// tableInput is the table schema.json describes, named after
+// connection.DynamoDBTable and on-demand if benchmark.SetBillingMode asked
+// for it.
+func tableInput() (*dynamodb.CreateTableInput, error) {
+	var input dynamodb.CreateTableInput
+	if err := json.Unmarshal(schema, &input); err != nil {
+		return nil, err
+	}
+	input.TableName = aws.String(connection.DynamoDBTable)
+	if benchmark.OnDemand() {
+		input.BillingMode = types.BillingModePayPerRequest
+		input.ProvisionedThroughput = nil
+		for i := range input.GlobalSecondaryIndexes {
+			input.GlobalSecondaryIndexes[i].ProvisionedThroughput = nil
+		}
+	}
+	return &input, nil
+}
 
-	log.Println("Connected to DynamoDB Local")
+// createTable creates the table tableInput describes and waits for it to
+// become active. An existing table is left as is; createTable reports
+// whether it created one.
+func createTable(ctx context.Context, client *dynamodb.Client) bool {
+	input, err := tableInput()
+	if err != nil {
+		benchmark.Fatal("Failed to parse schema.json", "err", err)
+	}
 
-	// Seed data
-	merchantIDs := seedMerchants(ctx, client)
-	log.Printf("Created %d merchants", len(merchantIDs))
+	_, err = client.CreateTable(ctx, input)
+	var inUse *types.ResourceInUseException
+	if errors.As(err, &inUse) {
+		return false
+	}
+	if err != nil {
+		benchmark.Fatal("Failed to create table", "err", err)
+	}
 
-	accountIDs := seedAccounts(ctx, client)
-	log.Printf("Created %d accounts", len(accountIDs))
+	waiter := dynamodb.NewTableExistsWaiter(client)
+	if err := waiter.Wait(ctx, &dynamodb.DescribeTableInput{TableName: input.TableName}, 2*time.Minute); err != nil {
+		benchmark.Fatal("Failed waiting for table", "err", err)
+	}
+	slog.Info("Created table", "table", *input.TableName, "billing", input.BillingMode)
+	return true
+}
 
-	seedTransactions(ctx, client, accountIDs, merchantIDs)
-	log.Printf("Created %d transactions", NumTransactions)
+// Init creates the benchmark table and its indexes from schema.json, empty,
+// without seeding it. An existing table is kept, data and all, and Init
+// only warns if its billing mode or indexes differ from what was asked for.
+func Init() {
+	client := connect()
+	if createTable(ctx, client) {
+		return
+	}
 
-	log.Println("Seeding completed successfully!")
+	output, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(connection.DynamoDBTable)})
+	if err != nil {
+		benchmark.Fatal("Failed to describe table", "err", err)
+	}
+	billing := types.BillingModeProvisioned
+	if output.Table.BillingModeSummary != nil {
+		billing = output.Table.BillingModeSummary.BillingMode
+	}
+	slog.Info("Table already exists", "table", connection.DynamoDBTable, "billing", billing)
+	if (billing == types.BillingModePayPerRequest) != benchmark.OnDemand() {
+		slog.Warn("Table has a different billing mode; `benchctl clean --db=dynamodb` recreates it, deleting its data", "billing", billing)
+	}
+	missing, err := missingIndexes(output.Table)
+	if err != nil {
+		benchmark.Fatal("Failed to parse schema.json", "err", err)
+	}
+	if len(missing) > 0 {
+		slog.Warn("Table lacks, or is still building, indexes schema.json declares; `benchctl clean --db=dynamodb` recreates it, deleting its data", "missing", missing)
+	}
 }
 
-func seedMerchants(ctx context.Context, client *dynamodb.Client) []string {
-	log.Println("Seeding merchants...")
-	merchantIDs := make([]string, 0, NumMerchants)
-	items := make([]types.WriteRequest, 0, BatchSize)
+// Seed creates the benchmark table if it does not exist yet and fills it
+// with opts' merchants, users, accounts and transactions, and the exchange rates,
+// keeping opts.Workers batch writes in flight.
+func Seed(opts dataset.Options) {
+	client := connect()
+	createTable(ctx, client)
 
-	for i := 0; i < NumMerchants; i++ {
-		id := uuid.New().String()
-		merchant := Merchant{
-			PK:        fmt.Sprintf("MERCHANT#%s", id),
-			SK:        "METADATA",
-			Type:      "Merchant",
-			ID:        id,
-			Name:      fmt.Sprintf("Merchant_%d", i),
-			Category:  merchantCategories[rand.Intn(len(merchantCategories))],
-			CreatedAt: time.Now(),
+	// Seed data
+	seedExchangeRates()
+	slog.Info("Created exchange rates", "rates", len(usdRates))
+
+	// The generator yields the same records PostgreSQL is seeded with for
+	// the same --seed.
+	gen := dataset.New(benchmark.Seed(), opts.Volumes)
+	workers := max(1, opts.Workers)
+	merchantIDs := seedMerchants(gen, workers)
+	slog.Info("Created merchants", "merchants", len(merchantIDs))
+
+	userIDs := seedUsers(gen, workers)
+	slog.Info("Created users", "users", len(userIDs))
+
+	accountIDs := seedAccounts(gen, workers)
+	slog.Info("Created accounts", "accounts", len(accountIDs))
+
+	transactions := seedTransactions(gen, workers)
+	slog.Info("Created transactions", "transactions", len(transactions))
+
+	ids := seededIDs(accountIDs, userIDs, merchantIDs, transactions)
+	ids.Partial = benchmark.Stopping()
+	if err := benchmark.SaveIDs("dynamodb", ids); err != nil {
+		slog.Error("Failed to save seeded IDs, suites will sample them from the table", "err", err)
+	}
+
+	if ids.Partial {
+		slog.Info("Seeding interrupted; the IDs seeded so far are saved as partial")
+		return
+	}
+	slog.Info("Seeding completed")
+}
+
+func seedExchangeRates() {
+	slog.Info("Seeding exchange rates")
+	items := make([]types.WriteRequest, 0, len(usdRates))
+
+	for currency, rate := range usdRates {
+		exchangeRate := ExchangeRate{
+			PK:            fmt.Sprintf("RATE#%s", currency),
+			SK:            "TO#USD",
+			Type:          "ExchangeRate",
+			FromCurrency:  currency,
+			ToCurrency:    "USD",
+			Rate:          Decimal{decimal.RequireFromString(rate)},
+			EffectiveDate: time.Now().Format("2006-01-02"),
 		}
 
-		item, err := attributevalue.MarshalMap(merchant)
+		item, err := attributevalue.MarshalMap(exchangeRate)
 		if err != nil {
-			log.Printf("Failed to marshal merchant: %v", err)
+			slog.Error("Failed to marshal exchange rate", "err", err)
 			continue
 		}
 
 		items = append(items, types.WriteRequest{
 			PutRequest: &types.PutRequest{Item: item},
 		})
+	}
 
-		merchantIDs = append(merchantIDs, id)
+	if _, err := batchWriteWithRetry(items); err != nil {
+		slog.Error("Failed to batch write exchange rates", "err", err)
+	}
+}
 
-		if len(items) == BatchSize || i == NumMerchants-1 {
-			_, err := client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
-				RequestItems: map[string][]types.WriteRequest{
-					"FinancialTransactions": items,
-				},
-			})
-			if err != nil {
-				log.Printf("Failed to batch write merchants: %v", err)
+// seedBatch is the items of seeded records [first, first+count).
+type seedBatch struct {
+	first, count int
+	requests     []types.WriteRequest
+}
+
+// seedParallel writes records [0, n) of kind, each one's items built by
+// item, packed into batches of up to BatchSize that workers write at once,
+// unprocessed items retried. It reports which records were written in
+// full; a record's items always share a batch.
+func seedParallel(kind string, n, workers int, item func(i int) []any) []bool {
+	written := make([]bool, n)
+	batches := make(chan seedBatch, workers)
+	var wg sync.WaitGroup
+	var done atomic.Int64
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range batches {
+				if _, err := batchWriteWithRetry(b.requests); err != nil {
+					slog.Error("Failed to batch write seed records", "records", kind, "count", b.count, "err", err)
+					continue
+				}
+				// Each batch owns its records' flags.
+				for i := b.first; i < b.first+b.count; i++ {
+					written[i] = true
+				}
+				if total := done.Add(int64(b.count)); total/10000 != (total-int64(b.count))/10000 {
+					slog.Debug("Seeding progress", "records", kind, "written", total)
+				}
+			}
+		}()
+	}
+
+	batch := seedBatch{requests: make([]types.WriteRequest, 0, BatchSize)}
+	for i := 0; i < n && !benchmark.Stopping(); i++ {
+		var puts []types.WriteRequest
+		var err error
+		for _, record := range item(i) {
+			var av map[string]types.AttributeValue
+			if av, err = attributevalue.MarshalMap(record); err != nil {
+				break
 			}
-			items = make([]types.WriteRequest, 0, BatchSize)
+			puts = append(puts, types.WriteRequest{PutRequest: &types.PutRequest{Item: av}})
+		}
+		if err != nil {
+			slog.Error("Failed to marshal seed record", "records", kind, "index", i, "err", err)
+			continue
+		}
 
-			if (i+1)%100 == 0 {
-				log.Printf("Created %d merchants...", i+1)
+		if len(batch.requests)+len(puts) > BatchSize || i != batch.first+batch.count {
+			if batch.count > 0 {
+				batches <- batch
 			}
+			batch = seedBatch{first: i, requests: make([]types.WriteRequest, 0, BatchSize)}
 		}
+		batch.requests = append(batch.requests, puts...)
+		batch.count++
 	}
+	if batch.count > 0 {
+		batches <- batch
+	}
+	close(batches)
+	wg.Wait()
+	return written
+}
 
+func seedMerchants(gen *dataset.Generator, workers int) []string {
+	slog.Info("Seeding merchants")
+	written := seedParallel("merchants", gen.Volumes().Merchants, workers, func(i int) []any {
+		m := gen.Merchant(i)
+		id := m.ID.String()
+		return []any{Merchant{
+			PK:        fmt.Sprintf("MERCHANT#%s", id),
+			SK:        "METADATA",
+			Type:      "Merchant",
+			ID:        id,
+			Name:      m.Name,
+			Category:  m.Category,
+			CreatedAt: time.Now(),
+		}}
+	})
+
+	merchantIDs := make([]string, 0, len(written))
+	for i, ok := range written {
+		if ok {
+			merchantIDs = append(merchantIDs, gen.Merchant(i).ID.String())
+		}
+	}
 	return merchantIDs
 }
 
-func seedAccounts(ctx context.Context, client *dynamodb.Client) []string {
-	log.Println("Seeding accounts...")
-	accountIDs := make([]string, 0, NumAccounts)
-	items := make([]types.WriteRequest, 0, BatchSize)
+func seedUsers(gen *dataset.Generator, workers int) []string {
+	slog.Info("Seeding users")
+	written := seedParallel("users", gen.Volumes().Users, workers, func(i int) []any {
+		u := gen.User(i)
+		id := u.ID.String()
+		return []any{User{
+			PK:        fmt.Sprintf("USER#%s", id),
+			SK:        "PROFILE",
+			GSI1PK:    fmt.Sprintf("USER#%s", id),
+			GSI1SK:    "PROFILE",
+			Type:      "User",
+			ID:        id,
+			Name:      u.Name,
+			Email:     u.Email,
+			CreatedAt: time.Now(),
+		}}
+	})
+
+	userIDs := make([]string, 0, len(written))
+	for i, ok := range written {
+		if ok {
+			userIDs = append(userIDs, gen.User(i).ID.String())
+		}
+	}
+	return userIDs
+}
 
-	for i := 0; i < NumAccounts; i++ {
-		id := uuid.New().String()
-		userID := uuid.New().String()
-		account := Account{
+func seedAccounts(gen *dataset.Generator, workers int) []string {
+	slog.Info("Seeding accounts")
+	written := seedParallel("accounts", gen.Volumes().Accounts, workers, func(i int) []any {
+		a := gen.Account(i)
+		id, userID := a.ID.String(), a.UserID.String()
+		return []any{Account{
 			PK:          fmt.Sprintf("ACCOUNT#%s", id),
 			SK:          "METADATA",
 			GSI1PK:      fmt.Sprintf("USER#%s", userID),
@@ -191,134 +424,141 @@ func seedAccounts(ctx context.Context, client *dynamodb.Client) []string {
 			Type:        "Account",
 			ID:          id,
 			UserID:      userID,
-			AccountType: accountTypes[rand.Intn(len(accountTypes))],
-			Currency:    currencies[rand.Intn(len(currencies))],
-			Balance:     decimal.NewFromFloat(rand.Float64() * 10000),
+			AccountType: a.AccountType,
+			Currency:    a.Currency,
+			Balance:     Decimal{a.Balance},
 			Status:      "active",
 			Version:     0,
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
-		}
+		}}
+	})
 
-		item, err := attributevalue.MarshalMap(account)
-		if err != nil {
-			log.Printf("Failed to marshal account: %v", err)
-			continue
-		}
-
-		items = append(items, types.WriteRequest{
-			PutRequest: &types.PutRequest{Item: item},
-		})
-
-		accountIDs = append(accountIDs, id)
-
-		if len(items) == BatchSize || i == NumAccounts-1 {
-			_, err := client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
-				RequestItems: map[string][]types.WriteRequest{
-					"FinancialTransactions": items,
-				},
-			})
-			if err != nil {
-				log.Printf("Failed to batch write accounts: %v", err)
-			}
-			items = make([]types.WriteRequest, 0, BatchSize)
-
-			if (i+1)%1000 == 0 {
-				log.Printf("Created %d accounts...", i+1)
-			}
+	accountIDs := make([]string, 0, len(written))
+	for i, ok := range written {
+		if ok {
+			accountIDs = append(accountIDs, gen.Account(i).ID.String())
 		}
 	}
-
 	return accountIDs
 }
 
-func seedTransactions(ctx context.Context, client *dynamodb.Client, accountIDs, merchantIDs []string) {
-	log.Println("Seeding transactions...")
+// seededTransaction is what seedTransactions remembers about each
+// transaction for the seeded-ID file.
+type seededTransaction struct {
+	id, merchant string
+	accounts     []string // one per leg
+	ageDays      int
+	anomaly      string
+}
 
-	for i := 0; i < NumTransactions; i++ {
-		txnID := uuid.New().String()
-		idempotencyKey := uuid.New().String()
-		merchantID := merchantIDs[rand.Intn(len(merchantIDs))]
-		createdAt := time.Now().Add(-time.Duration(rand.Intn(90)) * 24 * time.Hour)
+func seeded(t dataset.Transaction) seededTransaction {
+	accounts := make([]string, len(t.Legs))
+	for i, leg := range t.Legs {
+		accounts[i] = leg.Account.String()
+	}
+	return seededTransaction{t.ID.String(), t.MerchantID.String(), accounts, t.AgeDays, t.Anomaly}
+}
+
+func seedTransactions(gen *dataset.Generator, workers int) []seededTransaction {
+	slog.Info("Seeding transactions")
+	written := seedParallel("transactions", gen.Volumes().Transactions, workers, func(i int) []any {
+		t := gen.Transaction(i)
+		txnID := t.ID.String()
+		idempotencyKey := t.IdempotencyKey.String()
+		createdAt := t.CreatedAt
 
-		// Create transaction header
+		// Transaction header
 		txn := Transaction{
 			PK:              fmt.Sprintf("TXN#%s", txnID),
 			SK:              "METADATA",
-			GSI1PK:          "STATUS#completed",
+			GSI1PK:          fmt.Sprintf("STATUS#%s", t.Status),
 			GSI1SK:          fmt.Sprintf("CREATED#%s", createdAt.Format(time.RFC3339Nano)),
 			GSI2PK:          fmt.Sprintf("IDEMPOTENCY#%s", idempotencyKey),
 			GSI2SK:          "TXN",
+			GSI3PK:          fmt.Sprintf("MERCHANT#%s", t.MerchantID),
+			GSI3SK:          fmt.Sprintf("CREATED#%s", createdAt.Format(time.RFC3339Nano)),
 			Type:            "Transaction",
 			ID:              txnID,
 			IdempotencyKey:  idempotencyKey,
-			TransactionType: transactionTypes[rand.Intn(len(transactionTypes))],
-			Status:          "completed",
-			MerchantID:      merchantID,
-			Description:     fmt.Sprintf("Transaction %d", i),
+			TransactionType: t.Type,
+			Status:          t.Status,
+			MerchantID:      t.MerchantID.String(),
+			Description:     t.Description,
 			CreatedAt:       createdAt,
 			UpdatedAt:       createdAt,
-			CompletedAt:     createdAt,
 		}
-
-		// Create transaction legs
-		amount := decimal.NewFromFloat(rand.Float64() * 1000)
-		currency := currencies[rand.Intn(len(currencies))]
-		debitAccountID := accountIDs[rand.Intn(len(accountIDs))]
-		creditAccountID := accountIDs[rand.Intn(len(accountIDs))]
-
-		debitLeg := TransactionLeg{
-			PK:            fmt.Sprintf("TXN#%s", txnID),
-			SK:            fmt.Sprintf("LEG#%s", uuid.New().String()),
-			GSI1PK:        fmt.Sprintf("ACCOUNT#%s", debitAccountID),
-			GSI1SK:        fmt.Sprintf("LEG#%s#%s", createdAt.Format(time.RFC3339Nano), txnID),
-			Type:          "TransactionLeg",
-			ID:            uuid.New().String(),
-			TransactionID: txnID,
-			AccountID:     debitAccountID,
-			LegType:       "debit",
-			Amount:        amount,
-			Currency:      currency,
-			CreatedAt:     createdAt,
+		if dataset.Settled(t.Status) {
+			txn.CompletedAt = createdAt
 		}
 
-		creditLeg := TransactionLeg{
-			PK:            fmt.Sprintf("TXN#%s", txnID),
-			SK:            fmt.Sprintf("LEG#%s", uuid.New().String()),
-			GSI1PK:        fmt.Sprintf("ACCOUNT#%s", creditAccountID),
-			GSI1SK:        fmt.Sprintf("LEG#%s#%s", createdAt.Format(time.RFC3339Nano), txnID),
-			Type:          "TransactionLeg",
-			ID:            uuid.New().String(),
-			TransactionID: txnID,
-			AccountID:     creditAccountID,
-			LegType:       "credit",
-			Amount:        amount,
-			Currency:      currency,
-			CreatedAt:     createdAt,
+		// Transaction legs, written in the header's batch
+		leg := func(id uuid.UUID, account uuid.UUID, legType string, amount decimal.Decimal) TransactionLeg {
+			return TransactionLeg{
+				PK:            fmt.Sprintf("TXN#%s", txnID),
+				SK:            fmt.Sprintf("LEG#%s", id),
+				GSI1PK:        fmt.Sprintf("ACCOUNT#%s", account),
+				GSI1SK:        fmt.Sprintf("LEG#%s#%s", createdAt.Format(time.RFC3339Nano), txnID),
+				Type:          "TransactionLeg",
+				ID:            id.String(),
+				TransactionID: txnID,
+				AccountID:     account.String(),
+				LegType:       legType,
+				Amount:        Decimal{amount},
+				Currency:      t.Currency,
+				CreatedAt:     createdAt,
+			}
 		}
+		// Nothing stops legs being written without their header, or two
+		// headers sharing an idempotency key, so every injected anomaly is
+		// seeded.
+		items := []any{txn}
+		if t.Anomaly == dataset.OrphanLegs {
+			items = nil
+		}
+		for _, l := range t.Legs {
+			items = append(items, leg(l.ID, l.Account, l.Type, l.Amount))
+		}
+		return items
+	})
 
-		// Batch write all items
-		txnItem, _ := attributevalue.MarshalMap(txn)
-		debitItem, _ := attributevalue.MarshalMap(debitLeg)
-		creditItem, _ := attributevalue.MarshalMap(creditLeg)
-
-		_, err := client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
-			RequestItems: map[string][]types.WriteRequest{
-				"FinancialTransactions": {
-					{PutRequest: &types.PutRequest{Item: txnItem}},
-					{PutRequest: &types.PutRequest{Item: debitItem}},
-					{PutRequest: &types.PutRequest{Item: creditItem}},
-				},
-			},
-		})
-
-		if err != nil {
-			log.Printf("Failed to write transaction: %v", err)
-			continue
+	transactions := make([]seededTransaction, 0, len(written))
+	for i, ok := range written {
+		if ok {
+			transactions = append(transactions, seeded(gen.Transaction(i)))
 		}
+	}
+	return transactions
+}
 
-		if (i+1)%1000 == 0 {
-			log.Printf("Created %d transactions...", i+1)
+// seededIDs builds the seeded-ID file's contents, counting legs per account
+// and transactions per merchant for stratified sampling.
+func seededIDs(accountIDs, userIDs, merchantIDs []string, transactions []seededTransaction) benchmark.IDs {
+	legs := make(map[string]int)
+	merchantTxns := make(map[string]int)
+	ids := benchmark.IDs{Accounts: accountIDs, Merchants: merchantIDs, Users: userIDs}
+	for _, txn := range transactions {
+		if txn.anomaly != "" {
+			if ids.Anomalies == nil {
+				ids.Anomalies = make(map[string][]string)
+			}
+			ids.Anomalies[txn.anomaly] = append(ids.Anomalies[txn.anomaly], txn.id)
+		}
+		for _, account := range txn.accounts {
+			legs[account]++
 		}
+		if txn.anomaly == dataset.OrphanLegs {
+			continue // no header to read
+		}
+		ids.Transactions = append(ids.Transactions, txn.id)
+		ids.TransactionAgeDays = append(ids.TransactionAgeDays, txn.ageDays)
+		merchantTxns[txn.merchant]++
+	}
+	for _, id := range accountIDs {
+		ids.AccountLegs = append(ids.AccountLegs, legs[id])
+	}
+	for _, id := range merchantIDs {
+		ids.MerchantTransactions = append(ids.MerchantTransactions, merchantTxns[id])
 	}
+	return ids
 }
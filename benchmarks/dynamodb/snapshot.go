@@ -0,0 +1,264 @@
+package dynamodb
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+)
+
+// snapshotSegments is how many parallel scan segments Snapshot reads and
+// how many writers Restore loads with.
+const snapshotSegments = 8
+
+// Snapshot dumps every item in the table to path as gzipped JSON Lines in
+// DynamoDB JSON, the format DynamoDB's own exports use, so it works against
+// DynamoDB Local as well as AWS. Take it right after Seed so Restore can
+// reset to the seeded state.
+func Snapshot(path string) {
+	connect()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		benchmark.Fatal("Failed to create snapshot directory", "err", err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		benchmark.Fatal("Failed to create snapshot", "err", err)
+	}
+	defer file.Close()
+	zw := gzip.NewWriter(file)
+	buffered := bufio.NewWriter(zw)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	count := 0
+	for segment := 0; segment < snapshotSegments; segment++ {
+		wg.Add(1)
+		go func(segment int) {
+			defer wg.Done()
+			input := &dynamodb.ScanInput{
+				TableName:     aws.String(connection.DynamoDBTable),
+				Segment:       aws.Int32(int32(segment)),
+				TotalSegments: aws.Int32(snapshotSegments),
+			}
+			for {
+				output, err := client.Scan(ctx, input)
+				if err != nil {
+					benchmark.Fatal("Failed to scan table for snapshot", "err", err)
+				}
+
+				mu.Lock()
+				for _, item := range output.Items {
+					line, err := json.Marshal(encodeItem(item))
+					if err != nil {
+						benchmark.Fatal("Failed to encode item", "err", err)
+					}
+					buffered.Write(append(line, '\n'))
+				}
+				count += len(output.Items)
+				mu.Unlock()
+
+				if output.LastEvaluatedKey == nil {
+					return
+				}
+				input.ExclusiveStartKey = output.LastEvaluatedKey
+			}
+		}(segment)
+	}
+	wg.Wait()
+
+	if err := buffered.Flush(); err != nil {
+		benchmark.Fatal("Failed to write snapshot", "err", err)
+	}
+	if err := zw.Close(); err != nil {
+		benchmark.Fatal("Failed to write snapshot", "err", err)
+	}
+	slog.Info("Snapshot written", "items", count, "path", path, "duration", time.Since(start).Round(time.Millisecond))
+}
+
+// Restore replaces the table with the snapshot at path: it drops the table,
+// recreates it from schema.json and batch-writes the snapshot's items back
+// in parallel. Items added by benchmark runs since the snapshot are gone
+// afterwards.
+func Restore(path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		benchmark.Fatal("No DynamoDB snapshot; run `benchctl seed --db=dynamodb` then `benchctl snapshot --db=dynamodb`",
+			"path", path, "err", err)
+	}
+	defer file.Close()
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		benchmark.Fatal("Not a DynamoDB snapshot", "path", path, "err", err)
+	}
+
+	start := time.Now()
+	connect()
+	recreateTable()
+
+	batches := make(chan []types.WriteRequest, snapshotSegments)
+	var wg sync.WaitGroup
+	for w := 0; w < snapshotSegments; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				batchWriteWithRetry(batch)
+			}
+		}()
+	}
+
+	scanner := bufio.NewScanner(zr)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	count := 0
+	batch := make([]types.WriteRequest, 0, 25)
+	for line := 1; scanner.Scan(); line++ {
+		var encoded map[string]json.RawMessage
+		if err := json.Unmarshal(scanner.Bytes(), &encoded); err != nil {
+			benchmark.Fatal("Invalid snapshot line", "path", path, "line", line, "err", err)
+		}
+		item, err := decodeItem(encoded)
+		if err != nil {
+			benchmark.Fatal("Invalid snapshot line", "path", path, "line", line, "err", err)
+		}
+
+		batch = append(batch, types.WriteRequest{PutRequest: &types.PutRequest{Item: item}})
+		count++
+		if len(batch) == 25 {
+			batches <- batch
+			batch = make([]types.WriteRequest, 0, 25)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		benchmark.Fatal("Failed to read snapshot", "path", path, "err", err)
+	}
+	if len(batch) > 0 {
+		batches <- batch
+	}
+	close(batches)
+	wg.Wait()
+
+	slog.Info("Restored snapshot", "items", count, "path", path, "duration", time.Since(start).Round(time.Millisecond))
+}
+
+// encodeItem converts an item to DynamoDB JSON ({"S": "..."}, {"N": "..."}
+// and so on), which json.Marshal cannot produce from types.AttributeValue.
+func encodeItem(item map[string]types.AttributeValue) map[string]any {
+	encoded := make(map[string]any, len(item))
+	for name, value := range item {
+		encoded[name] = encodeValue(value)
+	}
+	return encoded
+}
+
+func encodeValue(value types.AttributeValue) map[string]any {
+	switch v := value.(type) {
+	case *types.AttributeValueMemberS:
+		return map[string]any{"S": v.Value}
+	case *types.AttributeValueMemberN:
+		return map[string]any{"N": v.Value}
+	case *types.AttributeValueMemberB:
+		return map[string]any{"B": v.Value}
+	case *types.AttributeValueMemberBOOL:
+		return map[string]any{"BOOL": v.Value}
+	case *types.AttributeValueMemberNULL:
+		return map[string]any{"NULL": true}
+	case *types.AttributeValueMemberSS:
+		return map[string]any{"SS": v.Value}
+	case *types.AttributeValueMemberNS:
+		return map[string]any{"NS": v.Value}
+	case *types.AttributeValueMemberBS:
+		return map[string]any{"BS": v.Value}
+	case *types.AttributeValueMemberM:
+		return map[string]any{"M": encodeItem(v.Value)}
+	case *types.AttributeValueMemberL:
+		list := make([]map[string]any, len(v.Value))
+		for i, element := range v.Value {
+			list[i] = encodeValue(element)
+		}
+		return map[string]any{"L": list}
+	default:
+		benchmark.Fatal("Cannot snapshot attribute value", "type", fmt.Sprintf("%T", value))
+		return nil
+	}
+}
+
+// decodeItem is the inverse of encodeItem.
+func decodeItem(encoded map[string]json.RawMessage) (map[string]types.AttributeValue, error) {
+	item := make(map[string]types.AttributeValue, len(encoded))
+	for name, raw := range encoded {
+		value, err := decodeValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %w", name, err)
+		}
+		item[name] = value
+	}
+	return item, nil
+}
+
+func decodeValue(raw json.RawMessage) (types.AttributeValue, error) {
+	var typed struct {
+		S    *string
+		N    *string
+		B    []byte
+		BOOL *bool
+		NULL *bool
+		SS   []string
+		NS   []string
+		BS   [][]byte
+		M    map[string]json.RawMessage
+		L    []json.RawMessage
+	}
+	if err := json.Unmarshal(raw, &typed); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case typed.S != nil:
+		return &types.AttributeValueMemberS{Value: *typed.S}, nil
+	case typed.N != nil:
+		return &types.AttributeValueMemberN{Value: *typed.N}, nil
+	case typed.B != nil:
+		return &types.AttributeValueMemberB{Value: typed.B}, nil
+	case typed.BOOL != nil:
+		return &types.AttributeValueMemberBOOL{Value: *typed.BOOL}, nil
+	case typed.NULL != nil:
+		return &types.AttributeValueMemberNULL{Value: true}, nil
+	case typed.SS != nil:
+		return &types.AttributeValueMemberSS{Value: typed.SS}, nil
+	case typed.NS != nil:
+		return &types.AttributeValueMemberNS{Value: typed.NS}, nil
+	case typed.BS != nil:
+		return &types.AttributeValueMemberBS{Value: typed.BS}, nil
+	case typed.M != nil:
+		m, err := decodeItem(typed.M)
+		if err != nil {
+			return nil, err
+		}
+		return &types.AttributeValueMemberM{Value: m}, nil
+	case typed.L != nil:
+		list := make([]types.AttributeValue, len(typed.L))
+		for i, element := range typed.L {
+			value, err := decodeValue(element)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = value
+		}
+		return &types.AttributeValueMemberL{Value: list}, nil
+	default:
+		return nil, fmt.Errorf("unrecognized attribute value %s", raw)
+	}
+}
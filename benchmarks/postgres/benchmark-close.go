@@ -0,0 +1,456 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// closePeriodDays is the accounting period covered by the close.
+	closePeriodDays = 30
+
+	// OLTP mixed workload run alongside the close: account balance reads,
+	// recent activity reads and double-entry payments.
+	oltpConcurrency      = 20
+	oltpBaselineDuration = 30 * time.Second
+	oltpWriteRatio       = 0.2
+)
+
+// closeStep is one stage of the month-end close. Steps run in order on a
+// single connection so buffer statistics can be attributed to each of them.
+type closeStep struct {
+	name string
+	run  func(conn *sql.Conn, runID uuid.UUID) (rowsScanned int64, rowsReturned int, err error)
+}
+
+var closeSteps = []closeStep{
+	{"Trial Balance", runTrialBalance},
+	{"Merchant Settlement", runMerchantSettlement},
+	{"Daily Summaries", runDailySummaries},
+	{"Exception Detection", runExceptionDetection},
+}
+
+func runClose(benchmark.Options) {
+	db := connect()
+	defer db.Close()
+	loadTestData(db)
+
+	suite := benchmark.NewSuite("postgres-close")
+
+	slog.Info("Running PostgreSQL Month-End Close Simulation")
+
+	var closeAlone, closeUnderLoad []benchmark.Result
+	var oltpAlone, oltpUnderClose benchmark.Result
+	suite.RunAll(func() []benchmark.Result {
+		closeAlone = runMonthEndClose(db, "Month-End Close")
+		return closeAlone
+	})
+
+	slog.Info("Running Close + Live Traffic Interference Test")
+
+	suite.Run(func() benchmark.Result {
+		oltpAlone = runOLTPFor(db, "OLTP Mixed Workload - Baseline (no close)", oltpBaselineDuration)
+		return oltpAlone
+	})
+
+	suite.RunAll(func() []benchmark.Result {
+		closeUnderLoad, oltpUnderClose = runCloseWithLiveTraffic(db)
+		return append(closeUnderLoad, oltpUnderClose)
+	})
+
+	if len(closeAlone) > 0 && len(closeUnderLoad) > 0 {
+		logInterference(closeAlone[len(closeAlone)-1], closeUnderLoad[len(closeUnderLoad)-1], oltpAlone, oltpUnderClose)
+	}
+
+	benchmark.Save(suite, "postgres-close")
+	benchmark.PrintSummary(suite)
+}
+
+// runMonthEndClose chains every close step into one job and returns a result
+// per step followed by a result for the job as a whole. Exceptions written by
+// the job are removed afterwards so repeated runs start from the same state.
+func runMonthEndClose(db *sql.DB, label string) []benchmark.Result {
+	slog.Info("Running close", "job", label, "steps", len(closeSteps), "period_days", closePeriodDays)
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		benchmark.Fatal("Failed to acquire connection", "err", err)
+	}
+	defer conn.Close()
+
+	runID := uuid.New()
+	defer func() {
+		if _, err := db.ExecContext(ctx, "DELETE FROM reconciliation_exceptions WHERE run_id = $1", runID); err != nil {
+			slog.Error("Failed to remove close exceptions", "err", err)
+		}
+	}()
+
+	results := make([]benchmark.Result, 0, len(closeSteps)+1)
+	total := benchmark.Result{
+		TestName:      fmt.Sprintf("%s - Total", label),
+		Database:      "PostgreSQL",
+		Step:          "Total",
+		NumOperations: len(closeSteps),
+	}
+
+	jobStart := time.Now()
+	for _, step := range closeSteps {
+		hitBefore, readBefore := readBufferStats(conn)
+
+		start := time.Now()
+		rowsScanned, rowsReturned, err := step.run(conn, runID)
+		duration := time.Since(start)
+
+		hitAfter, readAfter := readBufferStats(conn)
+
+		result := benchmark.Result{
+			TestName:         fmt.Sprintf("%s - %s", label, step.name),
+			Database:         "PostgreSQL",
+			Step:             step.name,
+			NumOperations:    1,
+			TotalDuration:    duration,
+			AverageDuration:  duration,
+			OperationsPerSec: 1.0 / duration.Seconds(),
+			RowsScanned:      rowsScanned,
+			RowsReturned:     rowsReturned,
+			BuffersHit:       hitAfter - hitBefore,
+			BuffersRead:      readAfter - readBefore,
+			Timestamp:        time.Now(),
+		}
+		if err != nil {
+			slog.Error("Close step failed", "step", step.name, "err", err)
+			result.ErrorCount = 1
+		} else {
+			result.SuccessCount = 1
+		}
+
+		slog.Info("Close step finished", "step", step.name, "duration", duration,
+			"rows", rowsReturned, "buffers_hit", result.BuffersHit,
+			"buffers_read", result.BuffersRead)
+
+		total.RowsScanned += result.RowsScanned
+		total.RowsReturned += result.RowsReturned
+		total.BuffersHit += result.BuffersHit
+		total.BuffersRead += result.BuffersRead
+		total.SuccessCount += result.SuccessCount
+		total.ErrorCount += result.ErrorCount
+		results = append(results, result)
+	}
+
+	total.TotalDuration = time.Since(jobStart)
+	total.AverageDuration = total.TotalDuration / time.Duration(len(closeSteps))
+	total.OperationsPerSec = float64(len(closeSteps)) / total.TotalDuration.Seconds()
+	withinWindow := total.TotalDuration <= closeOfDayWindow
+	total.WithinCloseWindow = &withinWindow
+	total.Timestamp = time.Now()
+
+	slog.Info("Close finished", "job", label, "duration", total.TotalDuration)
+	if !withinWindow {
+		slog.Warn("Close exceeded the close-of-day window", "window", closeOfDayWindow)
+	}
+
+	return append(results, total)
+}
+
+// runCloseWithLiveTraffic runs the month-end close while the OLTP mixed
+// workload keeps running, and returns the close results together with the
+// OLTP latency observed for the duration of the close. Buffer counters are
+// database-wide, so under load they include the OLTP traffic too.
+func runCloseWithLiveTraffic(db *sql.DB) ([]benchmark.Result, benchmark.Result) {
+	testName := "OLTP Mixed Workload - During Month-End Close"
+	stop := make(chan struct{})
+	oltp := startOLTP(db, testName, stop)
+
+	// Let the OLTP workload reach a steady state before the close starts
+	time.Sleep(2 * time.Second)
+
+	closeResults := runMonthEndClose(db, "Month-End Close (under OLTP load)")
+	close(stop)
+
+	return closeResults, oltpResult(testName, oltp.Wait())
+}
+
+func runOLTPFor(db *sql.DB, testName string, duration time.Duration) benchmark.Result {
+	stop := make(chan struct{})
+	time.AfterFunc(duration, func() { close(stop) })
+	return oltpResult(testName, startOLTP(db, testName, stop).Wait())
+}
+
+// startOLTP starts the mixed workload on oltpConcurrency workers, which run
+// until stop is closed.
+func startOLTP(db *sql.DB, testName string, stop <-chan struct{}) *benchmark.Pool {
+	slog.Info("Running OLTP workload", "test", testName, "workers", oltpConcurrency,
+		"write_percent", oltpWriteRatio*100)
+
+	return benchmark.StartWorkers(oltpConcurrency, func(worker int, local *benchmark.Recorder, rng *rand.Rand) benchmark.WorkerTally {
+		var tally benchmark.WorkerTally
+		for {
+			select {
+			case <-stop:
+				return tally
+			default:
+			}
+
+			opStart := time.Now()
+			err := oltpOperation(db, rng)
+			local.Record(time.Since(opStart))
+			if err != nil {
+				tally.Errors++
+			} else {
+				tally.Successes++
+			}
+		}
+	})
+}
+
+// oltpResult summarizes the operations the OLTP workload's workers ran.
+func oltpResult(testName string, ran benchmark.Workers) benchmark.Result {
+	return ran.Summarize(testName, "PostgreSQL", ran.Latencies.Count(), oltpConcurrency)
+}
+
+func oltpOperation(db *sql.DB, rng *rand.Rand) error {
+	accountID := accountIDs[benchmark.Pick(rng, len(accountIDs))]
+
+	r := rng.Float64()
+	switch {
+	case r < oltpWriteRatio:
+		return insertPayment(db, rng)
+	case r < oltpWriteRatio+(1-oltpWriteRatio)/2:
+		var balance decimal.Decimal
+		return db.QueryRowContext(ctx, "SELECT balance FROM accounts WHERE id = $1", accountID).Scan(&balance)
+	default:
+		rows, err := db.QueryContext(ctx, `
+			SELECT t.id, t.status, tl.amount
+			FROM transaction_legs tl
+			JOIN transactions t ON t.id = tl.transaction_id
+			WHERE tl.account_id = $1
+			ORDER BY tl.created_at DESC
+			LIMIT 10
+		`, accountID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+		}
+		return rows.Err()
+	}
+}
+
+func insertPayment(db *sql.DB, rng *rand.Rand) error {
+	txnID := uuid.New()
+	merchantID := merchantIDs[benchmark.Pick(rng, len(merchantIDs))]
+	amount := decimal.NewFromFloat(rng.Float64()*1000 + 1)
+	debitAccount := accountIDs[benchmark.Pick(rng, len(accountIDs))]
+	creditAccount := accountIDs[benchmark.Pick(rng, len(accountIDs))]
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO transactions (id, idempotency_key, transaction_type, status, merchant_id, description, benchmark_run_id)
+		VALUES ($1, $2, 'payment', 'completed', $3, 'Close interference transaction', $4)
+	`, txnID, uuid.New().String(), merchantID, benchmark.RunID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO transaction_legs (transaction_id, account_id, leg_type, amount, currency, benchmark_run_id)
+		VALUES ($1, $2, 'debit', $3, 'USD', $5), ($1, $4, 'credit', $3, 'USD', $5)
+	`, txnID, debitAccount, amount, creditAccount, benchmark.RunID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func logInterference(closeAlone, closeUnderLoad, oltpAlone, oltpUnderClose benchmark.Result) {
+	if closeAlone.TotalDuration > 0 {
+		slog.Info("Interference: close duration under OLTP load", "alone", closeAlone.TotalDuration,
+			"under_load", closeUnderLoad.TotalDuration,
+			"slowdown", float64(closeUnderLoad.TotalDuration)/float64(closeAlone.TotalDuration))
+	}
+	if oltpAlone.P99Duration > 0 {
+		slog.Info("Interference: OLTP P99 during close", "alone", oltpAlone.P99Duration,
+			"during_close", oltpUnderClose.P99Duration,
+			"slowdown", float64(oltpUnderClose.P99Duration)/float64(oltpAlone.P99Duration))
+	}
+	if oltpAlone.OperationsPerSec > 0 {
+		slog.Info("Interference: OLTP throughput during close",
+			"alone_ops_per_sec", oltpAlone.OperationsPerSec,
+			"during_close_ops_per_sec", oltpUnderClose.OperationsPerSec)
+	}
+}
+
+// readBufferStats returns the database-wide shared buffer hit and read
+// counters after flushing this backend's pending statistics.
+func readBufferStats(conn *sql.Conn) (hit, read int64) {
+	if _, err := conn.ExecContext(ctx, "SELECT pg_stat_force_next_flush()"); err != nil {
+		return 0, 0
+	}
+	if err := conn.QueryRowContext(ctx, `
+		SELECT blks_hit, blks_read
+		FROM pg_stat_database
+		WHERE datname = current_database()
+	`).Scan(&hit, &read); err != nil {
+		return 0, 0
+	}
+	return hit, read
+}
+
+func runTrialBalance(conn *sql.Conn, runID uuid.UUID) (int64, int, error) {
+	rows, err := conn.QueryContext(ctx, `
+		SELECT
+			account_id,
+			COALESCE(SUM(amount) FILTER (WHERE leg_type = 'debit'), 0) as total_debits,
+			COALESCE(SUM(amount) FILTER (WHERE leg_type = 'credit'), 0) as total_credits,
+			COUNT(*) as leg_count
+		FROM transaction_legs
+		GROUP BY account_id
+	`)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+
+	var legsScanned int64
+	accounts := 0
+	totalDebits := decimal.Zero
+	totalCredits := decimal.Zero
+	for rows.Next() {
+		var accountID uuid.UUID
+		var debits, credits decimal.Decimal
+		var legCount int64
+		if err := rows.Scan(&accountID, &debits, &credits, &legCount); err != nil {
+			return legsScanned, accounts, err
+		}
+		totalDebits = totalDebits.Add(debits)
+		totalCredits = totalCredits.Add(credits)
+		legsScanned += legCount
+		accounts++
+	}
+	if !totalDebits.Equal(totalCredits) {
+		slog.Warn("Ledger out of balance", "difference", totalDebits.Sub(totalCredits).StringFixed(4))
+	}
+
+	return legsScanned, accounts, rows.Err()
+}
+
+func runMerchantSettlement(conn *sql.Conn, runID uuid.UUID) (int64, int, error) {
+	rows, err := conn.QueryContext(ctx, `
+		SELECT
+			t.merchant_id,
+			COUNT(*) as transaction_count,
+			SUM(tl.amount) as settlement_amount
+		FROM transactions t
+		JOIN transaction_legs tl ON t.id = tl.transaction_id
+		WHERE t.merchant_id IS NOT NULL
+			AND t.status = 'completed'
+			AND tl.leg_type = 'debit'
+			AND t.created_at >= NOW() - make_interval(days => $1)
+		GROUP BY t.merchant_id
+	`, closePeriodDays)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+
+	var txnsScanned int64
+	merchants := 0
+	for rows.Next() {
+		var merchantID uuid.UUID
+		var count int64
+		var amount decimal.Decimal
+		if err := rows.Scan(&merchantID, &count, &amount); err != nil {
+			return txnsScanned, merchants, err
+		}
+		txnsScanned += count
+		merchants++
+	}
+
+	return txnsScanned, merchants, rows.Err()
+}
+
+func runDailySummaries(conn *sql.Conn, runID uuid.UUID) (int64, int, error) {
+	rows, err := conn.QueryContext(ctx, `
+		SELECT
+			DATE(t.created_at) as date,
+			t.transaction_type,
+			COUNT(*) as count,
+			SUM(tl.amount) as total_amount
+		FROM transactions t
+		JOIN transaction_legs tl ON t.id = tl.transaction_id
+		WHERE t.created_at >= NOW() - make_interval(days => $1)
+			AND tl.leg_type = 'debit'
+		GROUP BY DATE(t.created_at), t.transaction_type
+		ORDER BY date DESC
+	`, closePeriodDays)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+
+	var txnsScanned int64
+	summaries := 0
+	for rows.Next() {
+		var date time.Time
+		var txnType string
+		var count int64
+		var total decimal.Decimal
+		if err := rows.Scan(&date, &txnType, &count, &total); err != nil {
+			return txnsScanned, summaries, err
+		}
+		txnsScanned += count
+		summaries++
+	}
+
+	return txnsScanned, summaries, rows.Err()
+}
+
+// runExceptionDetection flags unbalanced transactions across the whole ledger
+// with a single INSERT ... SELECT into reconciliation_exceptions.
+func runExceptionDetection(conn *sql.Conn, runID uuid.UUID) (int64, int, error) {
+	res, err := conn.ExecContext(ctx, `
+		INSERT INTO reconciliation_exceptions (run_id, transaction_id, total_debits, total_credits, difference)
+		SELECT
+			$1,
+			t.id,
+			COALESCE(SUM(CASE WHEN tl.leg_type = 'debit' THEN tl.amount END), 0),
+			COALESCE(SUM(CASE WHEN tl.leg_type = 'credit' THEN tl.amount END), 0),
+			COALESCE(SUM(CASE WHEN tl.leg_type = 'debit' THEN tl.amount END), 0) -
+			COALESCE(SUM(CASE WHEN tl.leg_type = 'credit' THEN tl.amount END), 0)
+		FROM transactions t
+		LEFT JOIN transaction_legs tl ON t.id = tl.transaction_id
+		GROUP BY t.id
+		HAVING COALESCE(SUM(CASE WHEN tl.leg_type = 'debit' THEN tl.amount END), 0) !=
+			   COALESCE(SUM(CASE WHEN tl.leg_type = 'credit' THEN tl.amount END), 0)
+	`, runID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	flagged, err := res.RowsAffected()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var examined int64
+	if err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM transactions").Scan(&examined); err != nil {
+		return 0, int(flagged), err
+	}
+
+	return examined, int(flagged), nil
+}
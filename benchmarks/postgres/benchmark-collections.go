@@ -0,0 +1,241 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// collectionWarnLegs is the per-account leg count reported as
+	// approaching the point where full-history reads stop being interactive.
+	collectionWarnLegs = 100000
+	collectionTopN     = 10
+	recentLegReads     = 200
+	fullHistoryReads   = 5
+)
+
+// collectionSizes are the leg counts the synthetic account is grown through.
+var collectionSizes = []int{1000, 10000, 100000, 250000}
+
+func runCollections(opts benchmark.Options) {
+	db := connect()
+	defer db.Close()
+
+	suite := benchmark.NewSuite("postgres-collections")
+
+	slog.Info("Running Per-Account Collection Size Benchmarks")
+
+	suite.Run(func() benchmark.Result { return monitorAccountRowCounts(db) })
+
+	accountID, txnID := createSyntheticAccount(db)
+	defer deleteSyntheticAccount(db, accountID, txnID)
+
+	legs := 0
+	for _, size := range collectionSizes {
+		growCollection(db, accountID, txnID, legs, size)
+		legs = size
+
+		suite.Run(func() benchmark.Result { return benchmarkRecentLegs(db, accountID, legs, opts.Ops(recentLegReads)) })
+		suite.Run(func() benchmark.Result { return benchmarkFullHistory(db, accountID, legs, opts.Ops(fullHistoryReads)) })
+	}
+
+	benchmark.Save(suite, "postgres-collections")
+	benchmark.PrintSummary(suite)
+}
+
+// monitorAccountRowCounts reports the accounts with the most legs and flags
+// any at or above collectionWarnLegs.
+func monitorAccountRowCounts(db *sql.DB) benchmark.Result {
+	testName := "Per-Account Leg Counts"
+	slog.Info("Benchmarking", "test", testName)
+	benchmark.StartTest(testName)
+
+	start := time.Now()
+	rows, err := db.QueryContext(ctx, `
+		SELECT account_id, COUNT(*)
+		FROM transaction_legs
+		GROUP BY account_id
+		ORDER BY COUNT(*) DESC
+		LIMIT $1
+	`, collectionTopN)
+	if err != nil {
+		slog.Error("Failed to count legs per account", "err", err)
+		return calculateResults(testName, 1, 1, nil, 0, 1, time.Since(start))
+	}
+	defer rows.Close()
+
+	largest := 0
+	flagged := 0
+	for rows.Next() {
+		var accountID uuid.UUID
+		var legs int
+		if err := rows.Scan(&accountID, &legs); err != nil {
+			continue
+		}
+
+		largest = max(largest, legs)
+		if legs >= collectionWarnLegs {
+			flagged++
+			slog.Warn("Large account collection", "account", accountID, "legs", legs, "threshold", collectionWarnLegs)
+		} else {
+			slog.Info("Account collection", "account", accountID, "legs", legs)
+		}
+	}
+	duration := time.Since(start)
+
+	slog.Info("Top accounts at or above the collection warning size", "flagged", flagged,
+		"top", collectionTopN, "warn_legs", collectionWarnLegs)
+
+	result := calculateResults(testName, 1, 1, []time.Duration{duration}, 1, 0, duration)
+	result.CollectionSize = largest
+	return result
+}
+
+// createSyntheticAccount inserts an account and one transaction header that
+// all of the account's synthetic legs hang off.
+func createSyntheticAccount(db *sql.DB) (uuid.UUID, uuid.UUID) {
+	accountID := uuid.New()
+	txnID := uuid.New()
+
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO accounts (id, user_id, account_type, currency, balance, status, benchmark_run_id)
+		VALUES ($1, $2, 'checking', 'USD', 0, 'active', $3)
+	`, accountID, uuid.New(), benchmark.RunID)
+	if err != nil {
+		benchmark.Fatal("Failed to create synthetic account", "err", err)
+	}
+
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO transactions (id, idempotency_key, transaction_type, status, description, benchmark_run_id)
+		VALUES ($1, $2, 'transfer', 'completed', 'Collection size benchmark', $3)
+	`, txnID, fmt.Sprintf("collections-%s", txnID), benchmark.RunID)
+	if err != nil {
+		benchmark.Fatal("Failed to create synthetic transaction", "err", err)
+	}
+
+	return accountID, txnID
+}
+
+// growCollection adds legs [from, to) to the account, alternating debits and
+// credits and spacing them one second apart going back in time.
+func growCollection(db *sql.DB, accountID, txnID uuid.UUID, from, to int) {
+	slog.Info("Growing account", "account", accountID, "legs", to)
+
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO transaction_legs (transaction_id, account_id, leg_type, amount, currency, created_at, benchmark_run_id)
+		SELECT $1, $2,
+			CASE WHEN g % 2 = 0 THEN 'debit' ELSE 'credit' END,
+			10.0000, 'USD',
+			CURRENT_TIMESTAMP - make_interval(secs => g),
+			$5
+		FROM generate_series($3::int, $4::int - 1) AS g
+	`, txnID, accountID, from, to, benchmark.RunID)
+	if err != nil {
+		benchmark.Fatal("Failed to grow collection", "err", err)
+	}
+
+	if _, err := db.ExecContext(ctx, "ANALYZE transaction_legs"); err != nil {
+		slog.Error("Failed to analyze transaction_legs", "err", err)
+	}
+}
+
+func benchmarkRecentLegs(db *sql.DB, accountID uuid.UUID, legs, count int) benchmark.Result {
+	testName := fmt.Sprintf("Recent 20 Legs (%d-leg account)", legs)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
+
+	durations := make([]time.Duration, 0, count)
+	successCount := 0
+	errorCount := 0
+	rowsReturned := 0
+
+	start := time.Now()
+
+	for i := 0; i < count; i++ {
+		opStart := time.Now()
+		rows, err := db.QueryContext(ctx, `
+			SELECT id, leg_type, amount, created_at
+			FROM transaction_legs
+			WHERE account_id = $1
+			ORDER BY created_at DESC
+			LIMIT 20
+		`, accountID)
+		if err == nil {
+			for rows.Next() {
+				rowsReturned++
+			}
+			rows.Close()
+			successCount++
+		} else {
+			errorCount++
+		}
+		durations = append(durations, time.Since(opStart))
+	}
+
+	totalDuration := time.Since(start)
+
+	result := calculateResults(testName, count, 1, durations, successCount, errorCount, totalDuration)
+	result.CollectionSize = legs
+	result.RowsReturned = rowsReturned
+	return result
+}
+
+// benchmarkFullHistory recomputes the account's net position from every leg,
+// the read that grows linearly with the collection.
+func benchmarkFullHistory(db *sql.DB, accountID uuid.UUID, legs, count int) benchmark.Result {
+	testName := fmt.Sprintf("Full Leg History Aggregate (%d-leg account)", legs)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
+
+	durations := make([]time.Duration, 0, count)
+	successCount := 0
+	errorCount := 0
+	var rowsScanned int64
+
+	start := time.Now()
+
+	for i := 0; i < count; i++ {
+		var net decimal.Decimal
+		var scanned int64
+
+		opStart := time.Now()
+		err := db.QueryRowContext(ctx, `
+			SELECT COALESCE(SUM(CASE WHEN leg_type = 'credit' THEN amount ELSE -amount END), 0), COUNT(*)
+			FROM transaction_legs
+			WHERE account_id = $1
+		`, accountID).Scan(&net, &scanned)
+		durations = append(durations, time.Since(opStart))
+
+		if err != nil {
+			errorCount++
+		} else {
+			successCount++
+			rowsScanned += scanned
+		}
+	}
+
+	totalDuration := time.Since(start)
+
+	result := calculateResults(testName, count, 1, durations, successCount, errorCount, totalDuration)
+	result.CollectionSize = legs
+	result.RowsScanned = rowsScanned
+	return result
+}
+
+func deleteSyntheticAccount(db *sql.DB, accountID, txnID uuid.UUID) {
+	slog.Info("Removing synthetic account")
+
+	if _, err := db.ExecContext(ctx, "DELETE FROM transactions WHERE id = $1", txnID); err != nil {
+		slog.Error("Failed to delete synthetic transaction", "err", err)
+	}
+	if _, err := db.ExecContext(ctx, "DELETE FROM accounts WHERE id = $1", accountID); err != nil {
+		slog.Error("Failed to delete synthetic account", "err", err)
+	}
+}
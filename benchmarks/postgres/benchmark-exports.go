@@ -0,0 +1,209 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/shopspring/decimal"
+)
+
+// exportPeriodDays is the window the partner-reporting feeds cover: the
+// month to date a finance team regenerates every night.
+const exportPeriodDays = 30
+
+// exportStep is one partner-reporting feed: completed debit volume by day,
+// partitioned into a CSV file per value of key. Its query returns the
+// partition, the day, the column beside it, the transaction count and the
+// amount, ordered by partition so each file is written in one go.
+type exportStep struct {
+	name   string
+	key    string
+	header []string
+	query  string
+}
+
+var exportSteps = []exportStep{
+	{
+		name:   "Merchant Export",
+		key:    "merchant",
+		header: []string{"date", "currency", "transactions", "amount"},
+		query: `
+			SELECT
+				t.merchant_id::text,
+				to_char(t.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') as day,
+				tl.currency,
+				COUNT(DISTINCT t.id) as transaction_count,
+				SUM(tl.amount) as amount
+			FROM transactions t
+			JOIN transaction_legs tl ON t.id = tl.transaction_id
+			WHERE t.merchant_id IS NOT NULL
+				AND t.status = 'completed'
+				AND tl.leg_type = 'debit'
+				AND t.created_at >= NOW() - make_interval(days => $1)
+			GROUP BY 1, 2, 3
+			ORDER BY 1, 2, 3
+		`,
+	},
+	{
+		name:   "Currency Export",
+		key:    "currency",
+		header: []string{"date", "merchant_id", "transactions", "amount"},
+		query: `
+			SELECT
+				tl.currency,
+				to_char(t.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') as day,
+				COALESCE(t.merchant_id::text, '') as merchant_id,
+				COUNT(DISTINCT t.id) as transaction_count,
+				SUM(tl.amount) as amount
+			FROM transactions t
+			JOIN transaction_legs tl ON t.id = tl.transaction_id
+			WHERE t.status = 'completed'
+				AND tl.leg_type = 'debit'
+				AND t.created_at >= NOW() - make_interval(days => $1)
+			GROUP BY 1, 2, 3
+			ORDER BY 1, 2, 3
+		`,
+	},
+}
+
+// runExports produces the nightly partner-reporting feeds, a summary file
+// per merchant and per currency, and reports each feed's duration, rows and
+// shared buffers alongside the job's total.
+func runExports(benchmark.Options) {
+	db := connect()
+	defer db.Close()
+
+	suite := benchmark.NewSuite("postgres-exports")
+
+	slog.Info("Running PostgreSQL Partner-Reporting Exports")
+
+	suite.RunAll(func() []benchmark.Result { return runExportJob(db, "Partner-Reporting Exports") })
+
+	benchmark.Save(suite, "postgres-exports")
+	benchmark.PrintSummary(suite)
+}
+
+// runExportJob runs every export step on a single connection, so buffer
+// statistics can be attributed to each, and returns a result per step
+// followed by a result for the job as a whole.
+func runExportJob(db *sql.DB, label string) []benchmark.Result {
+	dir := benchmark.ExportDir("postgres-exports")
+	slog.Info("Running exports", "job", label, "feeds", len(exportSteps), "period_days", exportPeriodDays, "dir", dir)
+	benchmark.StartTest(label)
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		benchmark.Fatal("Failed to acquire connection", "err", err)
+	}
+	defer conn.Close()
+
+	results := make([]benchmark.Result, 0, len(exportSteps)+1)
+	total := benchmark.Result{
+		TestName:      fmt.Sprintf("%s - Total", label),
+		Database:      "PostgreSQL",
+		Step:          "Total",
+		NumOperations: len(exportSteps),
+	}
+
+	jobStart := time.Now()
+	for _, step := range exportSteps {
+		hitBefore, readBefore := readBufferStats(conn)
+
+		start := time.Now()
+		w, err := benchmark.NewPartitionWriter(filepath.Join(dir, step.key), step.key, step.header...)
+		var txns int64
+		var rows int
+		if err == nil {
+			txns, rows, err = exportPartitions(conn, w, step.query)
+			if closeErr := w.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		duration := time.Since(start)
+
+		hitAfter, readAfter := readBufferStats(conn)
+
+		result := benchmark.Result{
+			TestName:         fmt.Sprintf("%s - %s", label, step.name),
+			Database:         "PostgreSQL",
+			Step:             step.name,
+			NumOperations:    1,
+			TotalDuration:    duration,
+			AverageDuration:  duration,
+			OperationsPerSec: 1.0 / duration.Seconds(),
+			RowsScanned:      txns,
+			RowsReturned:     rows,
+			BuffersHit:       hitAfter - hitBefore,
+			BuffersRead:      readAfter - readBefore,
+			Timestamp:        time.Now(),
+		}
+		if w != nil {
+			result.FilesWritten, result.BytesWritten = w.Files(), w.Bytes()
+		}
+		if err != nil {
+			slog.Error("Export failed", "feed", step.name, "err", err)
+			result.ErrorCount = 1
+		} else {
+			result.SuccessCount = 1
+		}
+
+		slog.Info("Export written", "feed", step.name, "duration", duration, "rows", rows,
+			"files", result.FilesWritten, "buffers_hit", result.BuffersHit,
+			"buffers_read", result.BuffersRead)
+
+		total.RowsScanned += result.RowsScanned
+		total.RowsReturned += result.RowsReturned
+		total.BuffersHit += result.BuffersHit
+		total.BuffersRead += result.BuffersRead
+		total.FilesWritten += result.FilesWritten
+		total.BytesWritten += result.BytesWritten
+		total.SuccessCount += result.SuccessCount
+		total.ErrorCount += result.ErrorCount
+		results = append(results, result)
+	}
+
+	total.TotalDuration = time.Since(jobStart)
+	total.AverageDuration = total.TotalDuration / time.Duration(len(exportSteps))
+	total.OperationsPerSec = float64(len(exportSteps)) / total.TotalDuration.Seconds()
+	total.Timestamp = time.Now()
+
+	slog.Info("Exports finished", "job", label, "duration", total.TotalDuration)
+
+	return append(results, total)
+}
+
+// exportPartitions streams query's grouped rows into w as they arrive and
+// returns the transactions they summarize and the rows written.
+// database/sql has no COPY TO STDOUT, so the rows come back as an ordinary
+// result set and are encoded as CSV on the client; the aggregation and the
+// ordering stay in the GROUP BY.
+func exportPartitions(conn *sql.Conn, w *benchmark.PartitionWriter, query string) (int64, int, error) {
+	rows, err := conn.QueryContext(ctx, query, exportPeriodDays)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+
+	var txns int64
+	written := 0
+	for rows.Next() {
+		var partition, day, column string
+		var count int64
+		var amount decimal.Decimal
+		if err := rows.Scan(&partition, &day, &column, &count, &amount); err != nil {
+			return txns, written, err
+		}
+		if err := w.Write(partition, []string{day, column, fmt.Sprint(count), amount.StringFixed(4)}); err != nil {
+			return txns, written, err
+		}
+		txns += count
+		written++
+	}
+
+	return txns, written, rows.Err()
+}
@@ -0,0 +1,215 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	fillfactorTable       = "fillfactor_accounts"
+	fillfactorAccounts    = 10000
+	fillfactorUpdates     = 50000
+	fillfactorConcurrency = 20
+	// fillfactorStatsWait bounds the wait for every worker's backend to
+	// report its updates to pg_stat_user_tables, which each does once it
+	// goes idle.
+	fillfactorStatsWait = 15 * time.Second
+)
+
+// fillfactorLayout is one set of indexes on the accounts copy. An update
+// is HOT (heap-only tuple) when it changes no indexed column and the new
+// row version fits on the old one's page, so it adds no index entries.
+type fillfactorLayout struct {
+	name    string
+	indexes []string
+}
+
+var (
+	fillfactors = []int{100, 90, 70, 50}
+
+	// The schema's accounts table indexes updated_at, which its trigger
+	// sets on every update, so no balance update there can be HOT however
+	// much free space its pages keep. The second layout drops that index
+	// to show what fillfactor buys once updates can be.
+	fillfactorLayouts = []fillfactorLayout{
+		{"schema indexes", []string{"user_id", "status", "updated_at"}},
+		{"no updated_at index", []string{"user_id", "status"}},
+	}
+)
+
+// runFillfactor updates account balances in copies of the accounts table
+// built at each fillfactor, measuring the share of updates that were HOT,
+// their latency and the table's growth. This is the update-in-place cost
+// DynamoDB's writes, which always write a whole new item, do not have.
+func runFillfactor(opts benchmark.Options) {
+	db := connect()
+	defer db.Close()
+
+	suite := benchmark.NewSuite("postgres-fillfactor")
+
+	slog.Info("Running HOT Update and Fillfactor Experiment")
+
+	for _, layout := range fillfactorLayouts {
+		for _, fillfactor := range fillfactors {
+			ids := setupFillfactorTable(db, layout, fillfactor, fillfactorAccounts)
+			suite.Run(func() benchmark.Result {
+				return benchmarkBalanceUpdates(db, layout, fillfactor, ids, opts.Ops(fillfactorUpdates), opts.Workers(fillfactorConcurrency))
+			})
+		}
+	}
+
+	benchmark.Save(suite, "postgres-fillfactor")
+	benchmark.PrintSummary(suite)
+}
+
+// setupFillfactorTable (re)creates the accounts copy with the layout's
+// indexes, the updated_at trigger and the given fillfactor, fills it with
+// count accounts and returns their IDs.
+func setupFillfactorTable(db *sql.DB, layout fillfactorLayout, fillfactor, count int) []uuid.UUID {
+	slog.Info("Creating fillfactor table", "table", fillfactorTable, "fillfactor", fillfactor, "layout", layout.name)
+
+	statements := []string{
+		fmt.Sprintf("DROP TABLE IF EXISTS %s", fillfactorTable),
+		fmt.Sprintf(`
+			CREATE TABLE %s (
+				id UUID PRIMARY KEY,
+				user_id UUID NOT NULL,
+				account_type VARCHAR(50) NOT NULL,
+				currency VARCHAR(3) DEFAULT 'USD',
+				balance DECIMAL(19, 4) NOT NULL DEFAULT 0,
+				version INTEGER NOT NULL DEFAULT 0,
+				status VARCHAR(20) DEFAULT 'active',
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+			) WITH (fillfactor = %d)
+		`, fillfactorTable, fillfactor),
+		fmt.Sprintf(`
+			CREATE TRIGGER %s_updated_at BEFORE UPDATE ON %s
+				FOR EACH ROW EXECUTE FUNCTION update_updated_at_column()
+		`, fillfactorTable, fillfactorTable),
+	}
+	for _, column := range layout.indexes {
+		statements = append(statements, fmt.Sprintf("CREATE INDEX %s_%s ON %s(%s)", fillfactorTable, column, fillfactorTable, column))
+	}
+	statements = append(statements,
+		fmt.Sprintf(`
+			INSERT INTO %s (id, user_id, account_type, balance)
+			SELECT gen_random_uuid(), gen_random_uuid(), 'checking', 1000
+			FROM generate_series(1, %d)
+		`, fillfactorTable, count),
+		fmt.Sprintf("VACUUM ANALYZE %s", fillfactorTable),
+	)
+
+	for _, stmt := range statements {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			benchmark.Fatal("Failed to create fillfactor table", "err", err)
+		}
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT id FROM %s", fillfactorTable))
+	if err != nil {
+		benchmark.Fatal("Failed to load fillfactor accounts", "err", err)
+	}
+	defer rows.Close()
+	ids := make([]uuid.UUID, 0, count)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			benchmark.Fatal("Failed to load fillfactor accounts", "err", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// benchmarkBalanceUpdates applies the ledger's balance update to random
+// accounts of the copy from concurrency workers, then reads how many of
+// the updates were HOT from the table's statistics.
+func benchmarkBalanceUpdates(db *sql.DB, layout fillfactorLayout, fillfactor int, ids []uuid.UUID, count, concurrency int) benchmark.Result {
+	testName := fmt.Sprintf("Balance Updates - fillfactor %d, %s (%d concurrent)", fillfactor, layout.name, concurrency)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
+
+	update := func(rng *rand.Rand) error {
+		_, err := db.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE %s SET balance = balance + $2, version = version + 1
+			WHERE id = $1
+		`, fillfactorTable), ids[benchmark.Pick(rng, len(ids))], decimal.NewFromFloat(rng.Float64()*100-50).Round(4))
+		return err
+	}
+	// The warm-up's updates are left out once they reach the statistics.
+	var warmed atomic.Int64
+	benchmark.WarmUp(concurrency, func(rng *rand.Rand) error {
+		if err := update(rng); err != nil {
+			return err
+		}
+		warmed.Add(1)
+		return nil
+	})
+	updatedBefore, hotBefore := waitForUpdateStats(db, warmed.Load())
+
+	opsPerGoroutine := count / concurrency
+	ran := benchmark.RunWorkers(concurrency, func(worker int, local *benchmark.Recorder, rng *rand.Rand) benchmark.WorkerTally {
+		var tally benchmark.WorkerTally
+		for i := 0; i < opsPerGoroutine && !benchmark.Stopping(); i++ {
+			opStart := time.Now()
+			err := update(rng)
+			local.Record(time.Since(opStart))
+			if err != nil {
+				tally.Errors++
+			} else {
+				tally.Successes++
+			}
+		}
+		return tally
+	})
+
+	result := ran.Summarize(testName, "PostgreSQL", opsPerGoroutine*concurrency, concurrency)
+	result.Layout = layout.name
+	result.FillFactor = fillfactor
+
+	updated, hot := waitForUpdateStats(db, updatedBefore+int64(ran.Successes))
+	if updated > updatedBefore {
+		result.HOTUpdatePercent = float64(hot-hotBefore) / float64(updated-updatedBefore) * 100
+	}
+	db.QueryRowContext(ctx, "SELECT pg_relation_size($1::regclass), pg_indexes_size($1::regclass)", fillfactorTable).
+		Scan(&result.TableSizeBytes, &result.IndexSizeBytes)
+
+	slog.Info("Fillfactor results", "fillfactor", fillfactor, "layout", layout.name,
+		"hot_update_percent", result.HOTUpdatePercent, "table_bytes", result.TableSizeBytes,
+		"index_bytes", result.IndexSizeBytes)
+	return result
+}
+
+// updateStats reads the copy's updated and HOT-updated row counts.
+func updateStats(db *sql.DB) (updated, hot int64) {
+	db.QueryRowContext(ctx, `
+		SELECT n_tup_upd, n_tup_hot_upd
+		FROM pg_stat_user_tables
+		WHERE relname = $1
+	`, fillfactorTable).Scan(&updated, &hot)
+	return updated, hot
+}
+
+// waitForUpdateStats waits until the copy's statistics count want updated
+// rows, or fillfactorStatsWait passes, and returns the counts. The copy is
+// new, so its counts start from 0.
+func waitForUpdateStats(db *sql.DB, want int64) (updated, hot int64) {
+	deadline := time.Now().Add(fillfactorStatsWait)
+	for {
+		updated, hot = updateStats(db)
+		if updated >= want || time.Now().After(deadline) {
+			return updated, hot
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
@@ -0,0 +1,204 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// ingestStepDuration is how long each concurrency level is sustained.
+	ingestStepDuration = 15 * time.Second
+	ingestLookups      = 1000
+	// ingestPartitionHours is how many hourly partitions are created around
+	// the run, so inserts never fall outside the partitioned range.
+	ingestPartitionHours = 6
+)
+
+var (
+	// ingestConcurrency is the ramp each layout is driven through; the
+	// highest sustained ops/sec across the ramp is its ingest ceiling.
+	ingestConcurrency = []int{10, 25, 50, 100}
+)
+
+// ingestLayout is one of the compared table layouts. Both hold the same
+// narrow transaction row; the partitioned layout splits it into hourly range
+// partitions on created_at, which keeps each insert's indexes small and lets
+// old hours be detached instead of deleted.
+type ingestLayout struct {
+	name        string
+	table       string
+	partitioned bool
+}
+
+var ingestLayouts = []ingestLayout{
+	{"Single Table", "ingest_flat", false},
+	{"Hourly Partitions", "ingest_partitioned", true},
+}
+
+func runIngest(opts benchmark.Options) {
+	db := connect()
+	defer db.Close()
+	db.SetMaxIdleConns(100)
+	loadTestData(db)
+
+	suite := benchmark.NewSuite("postgres-ingest")
+
+	slog.Info("Running Sustained Ingest Benchmarks")
+
+	for _, layout := range ingestLayouts {
+		setupIngestTable(db, layout)
+
+		var ids []uuid.UUID
+		var ceiling float64
+		for _, concurrency := range opts.ConcurrencyLevels(ingestConcurrency...) {
+			suite.Run(func() benchmark.Result {
+				result, written := benchmarkSustainedIngest(db, layout, concurrency, opts.Step(ingestStepDuration))
+				ids = append(ids, written...)
+				ceiling = max(ceiling, result.OperationsPerSec)
+				return result
+			})
+		}
+		slog.Info("Ingest ceiling", "layout", layout.name, "ops_per_sec", ceiling)
+
+		suite.Run(func() benchmark.Result { return benchmarkIngestLookups(db, layout, ids, opts.Ops(ingestLookups)) })
+
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", layout.table)); err != nil {
+			slog.Error("Failed to drop ingest table", "table", layout.table, "err", err)
+		}
+	}
+
+	benchmark.Save(suite, "postgres-ingest")
+	benchmark.PrintSummary(suite)
+}
+
+// setupIngestTable (re)creates the layout's table. A partitioned table's
+// primary key has to include the partition column, so by-transaction lookups
+// on it cannot be pruned and probe every partition's index.
+func setupIngestTable(db *sql.DB, layout ingestLayout) {
+	slog.Info("Creating ingest table", "table", layout.table)
+
+	statements := []string{fmt.Sprintf("DROP TABLE IF EXISTS %s", layout.table)}
+	if layout.partitioned {
+		statements = append(statements, fmt.Sprintf(`
+			CREATE TABLE %s (
+				id UUID NOT NULL,
+				account_id UUID NOT NULL,
+				merchant_id UUID,
+				amount DECIMAL(19, 4) NOT NULL,
+				currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+				created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (id, created_at)
+			) PARTITION BY RANGE (created_at)
+		`, layout.table))
+
+		hour := time.Now().UTC().Truncate(time.Hour).Add(-time.Hour)
+		for i := 0; i < ingestPartitionHours; i++ {
+			from := hour.Add(time.Duration(i) * time.Hour)
+			to := from.Add(time.Hour)
+			statements = append(statements, fmt.Sprintf(
+				"CREATE TABLE %s_%s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')",
+				layout.table, from.Format("2006010215"), layout.table, from.Format(time.RFC3339), to.Format(time.RFC3339)))
+		}
+	} else {
+		statements = append(statements, fmt.Sprintf(`
+			CREATE TABLE %s (
+				id UUID PRIMARY KEY,
+				account_id UUID NOT NULL,
+				merchant_id UUID,
+				amount DECIMAL(19, 4) NOT NULL,
+				currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+				created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+			)
+		`, layout.table))
+	}
+	statements = append(statements,
+		fmt.Sprintf("CREATE INDEX %s_account_created ON %s(account_id, created_at)", layout.table, layout.table))
+
+	for _, stmt := range statements {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			benchmark.Fatal("Failed to create ingest table", "err", err)
+		}
+	}
+}
+
+// benchmarkSustainedIngest inserts as fast as concurrency workers allow for
+// the given duration and returns the IDs it wrote.
+func benchmarkSustainedIngest(db *sql.DB, layout ingestLayout, concurrency int, duration time.Duration) (benchmark.Result, []uuid.UUID) {
+	testName := fmt.Sprintf("Sustained Ingest - %s (%d concurrent)", layout.name, concurrency)
+	slog.Info("Benchmarking", "test", testName, "duration", duration)
+	benchmark.StartTest(testName)
+
+	var ids []uuid.UUID
+	deadline := time.Now().Add(duration)
+	ran := benchmark.RunWorkers(concurrency, func(worker int, local *benchmark.Recorder, rng *rand.Rand) benchmark.WorkerTally {
+		var tally benchmark.WorkerTally
+		var written []uuid.UUID
+		for time.Now().Before(deadline) {
+			id := uuid.New()
+			opStart := time.Now()
+			_, err := db.ExecContext(ctx, fmt.Sprintf(`
+				INSERT INTO %s (id, account_id, merchant_id, amount)
+				VALUES ($1, $2, $3, $4)
+			`, layout.table), id, accountIDs[benchmark.Pick(rng, len(accountIDs))], merchantIDs[benchmark.Pick(rng, len(merchantIDs))],
+				decimal.NewFromFloat(rng.Float64()*1000+1))
+			local.Record(time.Since(opStart))
+			if err != nil {
+				tally.Errors++
+			} else {
+				tally.Successes++
+				written = append(written, id)
+			}
+		}
+		tally.Merge = func() { ids = append(ids, written...) }
+		return tally
+	})
+
+	result := ran.Summarize(testName, "PostgreSQL", ran.Latencies.Count(), concurrency)
+	result.Layout = layout.name
+	return result, ids
+}
+
+// benchmarkIngestLookups fetches random ingested transactions by ID, the
+// access pattern time partitioning makes more expensive.
+func benchmarkIngestLookups(db *sql.DB, layout ingestLayout, ids []uuid.UUID, count int) benchmark.Result {
+	testName := fmt.Sprintf("Ingest Lookup by Transaction ID - %s", layout.name)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
+
+	durations := make([]time.Duration, 0, count)
+	successCount := 0
+	errorCount := 0
+
+	if len(ids) == 0 {
+		return calculateResults(testName, count, 1, durations, 0, count, 0)
+	}
+
+	start := time.Now()
+
+	for i := 0; i < count; i++ {
+		var amount decimal.Decimal
+		opStart := time.Now()
+		err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT amount FROM %s WHERE id = $1", layout.table), ids[benchmark.Pick(benchmark.Rand(), len(ids))]).Scan(&amount)
+		durations = append(durations, time.Since(opStart))
+
+		if err != nil {
+			errorCount++
+		} else {
+			successCount++
+		}
+	}
+
+	totalDuration := time.Since(start)
+
+	result := calculateResults(testName, count, 1, durations, successCount, errorCount, totalDuration)
+	result.Layout = layout.name
+	return result
+}
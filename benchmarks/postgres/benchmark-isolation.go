@@ -0,0 +1,203 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+)
+
+const (
+	// The first numNoisyMerchants loaded merchants play the role of
+	// high-volume merchants; the rest generate low-rate "quiet" traffic.
+	numNoisyMerchants       = 3
+	noisyWorkersPerMerchant = 20
+	quietOps                = 500
+	quietConcurrency        = 5
+	numHashPartitions       = 8
+)
+
+func runIsolation(opts benchmark.Options) {
+	quietCount, quietWorkers := opts.Ops(quietOps), opts.Workers(quietConcurrency)
+	db := connect()
+	defer db.Close()
+	loadTestData(db)
+	if len(merchantIDs) <= numNoisyMerchants {
+		benchmark.Fatal("Not enough merchants found; run `make seed-postgres` first")
+	}
+	setupPartitionedTable(db)
+
+	noisy := merchantIDs[:numNoisyMerchants]
+	quiet := merchantIDs[numNoisyMerchants:]
+
+	suite := benchmark.NewSuite("postgres-isolation")
+
+	slog.Info("Running Noisy-Neighbor Isolation Experiment")
+
+	for _, layout := range []struct {
+		name  string
+		table string
+	}{
+		{"Single Table", "transactions"},
+		{"Hash-Partitioned by Merchant", "transactions_by_merchant"},
+	} {
+		var baseline benchmark.Result
+		suite.Run(func() benchmark.Result {
+			baseline = benchmarkQuietTraffic(db, layout.name, layout.table, quiet, quietCount, quietWorkers)
+			baseline.TestName = fmt.Sprintf("Quiet Merchants - %s Baseline (no noisy traffic)", layout.name)
+			return baseline
+		})
+
+		var results []benchmark.Result
+		suite.RunAll(func() []benchmark.Result {
+			results = runNoisyNeighborExperiment(db, layout.name, layout.table, quiet, noisy, quietCount, quietWorkers)
+			return results
+		})
+
+		if baseline.P99Duration > 0 && len(results) > 0 {
+			slog.Info("Quiet P99 under noise", "layout", layout.name,
+				"baseline", baseline.P99Duration, "under_noise", results[0].P99Duration,
+				"slowdown", float64(results[0].P99Duration)/float64(baseline.P99Duration))
+		}
+	}
+
+	benchmark.Save(suite, "postgres-isolation")
+	benchmark.PrintSummary(suite)
+}
+
+// setupPartitionedTable creates a copy of the transactions header table that is
+// hash-partitioned on merchant_id, so each merchant's rows, indexes and locks
+// live in their own partition.
+func setupPartitionedTable(db *sql.DB) {
+	slog.Info("Creating transactions_by_merchant", "hash_partitions", numHashPartitions)
+
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS transactions_by_merchant (
+			id UUID NOT NULL,
+			idempotency_key VARCHAR(255) NOT NULL,
+			transaction_type VARCHAR(50) NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			merchant_id UUID NOT NULL,
+			description TEXT,
+			benchmark_run_id UUID,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (merchant_id, id),
+			UNIQUE (merchant_id, idempotency_key)
+		) PARTITION BY HASH (merchant_id)
+	`)
+	if err != nil {
+		benchmark.Fatal("Failed to create partitioned table", "err", err)
+	}
+
+	for i := 0; i < numHashPartitions; i++ {
+		_, err := db.ExecContext(ctx, fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS transactions_by_merchant_p%d
+			PARTITION OF transactions_by_merchant
+			FOR VALUES WITH (MODULUS %d, REMAINDER %d)
+		`, i, numHashPartitions, i))
+		if err != nil {
+			benchmark.Fatal("Failed to create partition", "err", err)
+		}
+	}
+
+	// A table left behind by a run that predates benchmark_run_id lacks it.
+	if _, err := db.ExecContext(ctx, "ALTER TABLE transactions_by_merchant ADD COLUMN IF NOT EXISTS benchmark_run_id UUID"); err != nil {
+		benchmark.Fatal("Failed to add benchmark_run_id column", "err", err)
+	}
+
+	if _, err := db.ExecContext(ctx, "TRUNCATE transactions_by_merchant"); err != nil {
+		benchmark.Fatal("Failed to truncate partitioned table", "err", err)
+	}
+}
+
+func runNoisyNeighborExperiment(db *sql.DB, layout, table string, quiet, noisy []uuid.UUID, quietCount, quietWorkers int) []benchmark.Result {
+	slog.Info("Running noisy-neighbor experiment", "layout", layout)
+
+	stop := make(chan struct{})
+	noisyWorkers := len(noisy) * noisyWorkersPerMerchant
+	noisyRun := benchmark.StartWorkers(noisyWorkers, func(worker int, local *benchmark.Recorder, rng *rand.Rand) benchmark.WorkerTally {
+		var tally benchmark.WorkerTally
+		merchantID := noisy[worker/noisyWorkersPerMerchant]
+		for {
+			select {
+			case <-stop:
+				return tally
+			default:
+			}
+
+			opStart := time.Now()
+			_, err := insertMerchantTransaction(db, table, merchantID)
+			local.Record(time.Since(opStart))
+			if err != nil {
+				tally.Errors++
+			} else {
+				tally.Successes++
+			}
+		}
+	})
+
+	// Let the noisy merchants ramp up before measuring the quiet ones
+	time.Sleep(2 * time.Second)
+
+	quietResult := benchmarkQuietTraffic(db, layout, table, quiet, quietCount, quietWorkers)
+
+	close(stop)
+	noisyRan := noisyRun.Wait()
+
+	noisyResult := noisyRan.Summarize(
+		fmt.Sprintf("Noisy Merchants - %s (%d merchants x %d workers)", layout, len(noisy), noisyWorkersPerMerchant),
+		"PostgreSQL", noisyRan.Latencies.Count(), noisyWorkers)
+	noisyResult.Layout = layout
+	noisyResult.Role = "noisy"
+
+	return []benchmark.Result{quietResult, noisyResult}
+}
+
+func benchmarkQuietTraffic(db *sql.DB, layout, table string, quiet []uuid.UUID, ops, concurrency int) benchmark.Result {
+	testName := fmt.Sprintf("Quiet Merchants - %s (%d ops, %d concurrent)", layout, ops, concurrency)
+	slog.Info("Benchmarking", "test", testName)
+	benchmark.StartTest(testName)
+
+	opsPerGoroutine := ops / concurrency
+	ran := benchmark.RunWorkers(concurrency, func(worker int, local *benchmark.Recorder, rng *rand.Rand) benchmark.WorkerTally {
+		var tally benchmark.WorkerTally
+		for i := 0; i < opsPerGoroutine && !benchmark.Stopping(); i++ {
+			merchantID := quiet[benchmark.Pick(rng, len(quiet))]
+
+			// A quiet operation is a write followed by a read-your-write lookup
+			opStart := time.Now()
+			txnID, err := insertMerchantTransaction(db, table, merchantID)
+			if err == nil {
+				var status string
+				err = db.QueryRowContext(ctx, fmt.Sprintf("SELECT status FROM %s WHERE merchant_id = $1 AND id = $2", table),
+					merchantID, txnID).Scan(&status)
+			}
+			local.Record(time.Since(opStart))
+			if err != nil {
+				tally.Errors++
+			} else {
+				tally.Successes++
+			}
+		}
+		return tally
+	})
+
+	result := ran.Summarize(testName, "PostgreSQL", ops, concurrency)
+	result.Layout = layout
+	result.Role = "quiet"
+	return result
+}
+
+func insertMerchantTransaction(db *sql.DB, table string, merchantID uuid.UUID) (uuid.UUID, error) {
+	txnID := uuid.New()
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s (id, idempotency_key, transaction_type, status, merchant_id, description, benchmark_run_id)
+		VALUES ($1, $2, 'payment', 'completed', $3, 'Isolation benchmark transaction', $4)
+	`, table), txnID, uuid.New().String(), merchantID, benchmark.RunID)
+	return txnID, err
+}
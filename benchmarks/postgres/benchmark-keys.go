@@ -0,0 +1,247 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	keyInserts     = 50000
+	keyConcurrency = 20
+	keyRangeReads  = 500
+	// keyRangeWindow is the slice of the insert run each range read covers.
+	keyRangeWindow = 2 * time.Second
+)
+
+// keyStrategy generates primary keys for one of the compared tables. v4 keys
+// are random, so every insert lands on a random B-tree leaf; v7 keys start
+// with a millisecond timestamp, so inserts append to the right-most leaf and
+// the key itself orders rows by creation time.
+type keyStrategy struct {
+	name  string
+	table string
+	newID func() uuid.UUID
+	// timeOrdered strategies answer time-range reads from the primary key;
+	// the others need a secondary index on created_at.
+	timeOrdered bool
+}
+
+var (
+	keyStrategies = []keyStrategy{
+		{"UUIDv4", "key_strategy_v4", uuid.New, false},
+		{"UUIDv7", "key_strategy_v7", func() uuid.UUID { return uuid.Must(uuid.NewV7()) }, true},
+	}
+)
+
+func runKeys(opts benchmark.Options) {
+	db := connect()
+	defer db.Close()
+	loadTestData(db)
+
+	// pgstatindex reports leaf density and fragmentation; the run still
+	// works without it, just with those fields left empty.
+	if _, err := db.ExecContext(ctx, "CREATE EXTENSION IF NOT EXISTS pgstattuple"); err != nil {
+		slog.Warn("pgstattuple unavailable, skipping leaf statistics", "err", err)
+	}
+
+	suite := benchmark.NewSuite("postgres-keys")
+
+	slog.Info("Running Key Generation Strategy Benchmarks")
+
+	for _, strategy := range keyStrategies {
+		setupKeyTable(db, strategy)
+
+		var runStart, runEnd time.Time
+		suite.Run(func() benchmark.Result {
+			var insertResult benchmark.Result
+			insertResult, runStart, runEnd = benchmarkKeyInserts(db, strategy, opts.Ops(keyInserts), opts.Workers(keyConcurrency))
+			collectIndexStats(db, strategy, &insertResult)
+			return insertResult
+		})
+
+		suite.Run(func() benchmark.Result {
+			return benchmarkKeyRangeReads(db, strategy, opts.Ops(keyRangeReads), runStart, runEnd)
+		})
+	}
+
+	benchmark.Save(suite, "postgres-keys")
+	benchmark.PrintSummary(suite)
+}
+
+// setupKeyTable (re)creates a narrow ledger-entry table keyed by the
+// strategy's IDs. Only the random-key table gets a created_at index, because
+// that is what it needs to serve time-range reads.
+func setupKeyTable(db *sql.DB, strategy keyStrategy) {
+	slog.Info("Creating key strategy table", "table", strategy.table)
+
+	statements := []string{
+		fmt.Sprintf("DROP TABLE IF EXISTS %s", strategy.table),
+		fmt.Sprintf(`
+			CREATE TABLE %s (
+				id UUID PRIMARY KEY,
+				account_id UUID NOT NULL,
+				amount DECIMAL(19, 4) NOT NULL,
+				created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+			)
+		`, strategy.table),
+	}
+	if !strategy.timeOrdered {
+		statements = append(statements, fmt.Sprintf("CREATE INDEX %s_created_at ON %s(created_at)", strategy.table, strategy.table))
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			benchmark.Fatal("Failed to create key strategy table", "err", err)
+		}
+	}
+}
+
+func benchmarkKeyInserts(db *sql.DB, strategy keyStrategy, count, concurrency int) (benchmark.Result, time.Time, time.Time) {
+	testName := fmt.Sprintf("Key Strategy Inserts - %s (%d concurrent)", strategy.name, concurrency)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
+
+	walStart := currentWALPosition(db)
+
+	opsPerGoroutine := count / concurrency
+	start := time.Now()
+	ran := benchmark.RunWorkers(concurrency, func(worker int, local *benchmark.Recorder, rng *rand.Rand) benchmark.WorkerTally {
+		var tally benchmark.WorkerTally
+		for i := 0; i < opsPerGoroutine && !benchmark.Stopping(); i++ {
+			opStart := time.Now()
+			_, err := db.ExecContext(ctx, fmt.Sprintf(`
+				INSERT INTO %s (id, account_id, amount)
+				VALUES ($1, $2, $3)
+			`, strategy.table), strategy.newID(), accountIDs[benchmark.Pick(rng, len(accountIDs))], decimal.NewFromFloat(rng.Float64()*1000+1))
+			local.Record(time.Since(opStart))
+			if err != nil {
+				tally.Errors++
+			} else {
+				tally.Successes++
+			}
+		}
+		return tally
+	})
+	end := time.Now()
+
+	result := ran.Summarize(testName, "PostgreSQL", opsPerGoroutine*concurrency, concurrency)
+	result.KeyStrategy = strategy.name
+	result.WALBytes = walBytesSince(db, walStart)
+
+	return result, start, end
+}
+
+// collectIndexStats records the on-disk size of the table and of every index
+// on it, plus B-tree leaf statistics for the primary key.
+func collectIndexStats(db *sql.DB, strategy keyStrategy, result *benchmark.Result) {
+	db.QueryRowContext(ctx, "SELECT pg_relation_size($1::regclass), pg_indexes_size($1::regclass)", strategy.table).
+		Scan(&result.TableSizeBytes, &result.IndexSizeBytes)
+
+	db.QueryRowContext(ctx, "SELECT avg_leaf_density, leaf_fragmentation FROM pgstatindex($1)", strategy.table+"_pkey").
+		Scan(&result.AvgLeafDensity, &result.LeafFragmentation)
+
+	slog.Info("Key strategy storage", "strategy", strategy.name,
+		"table_bytes", result.TableSizeBytes, "index_bytes", result.IndexSizeBytes,
+		"leaf_density_percent", result.AvgLeafDensity,
+		"fragmentation_percent", result.LeafFragmentation, "wal_bytes", result.WALBytes)
+}
+
+// benchmarkKeyRangeReads fetches rows created inside random windows of the
+// insert run. Time-ordered keys answer this from the primary key with bounds
+// derived from the window; random keys go through the created_at index.
+func benchmarkKeyRangeReads(db *sql.DB, strategy keyStrategy, count int, runStart, runEnd time.Time) benchmark.Result {
+	testName := fmt.Sprintf("Key Strategy Range Reads - %s (%v window)", strategy.name, keyRangeWindow)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
+
+	durations := make([]time.Duration, 0, count)
+	successCount := 0
+	errorCount := 0
+	rowsReturned := 0
+
+	span := runEnd.Sub(runStart) - keyRangeWindow
+	if span <= 0 {
+		span = 1
+	}
+
+	start := time.Now()
+
+	for i := 0; i < count; i++ {
+		from := runStart.Add(time.Duration(benchmark.Rand().Int63n(int64(span))))
+		to := from.Add(keyRangeWindow)
+
+		var rows *sql.Rows
+		var err error
+
+		opStart := time.Now()
+		if strategy.timeOrdered {
+			rows, err = db.QueryContext(ctx, fmt.Sprintf(`
+				SELECT id, account_id, amount FROM %s
+				WHERE id BETWEEN $1 AND $2
+				ORDER BY id
+			`, strategy.table), v7Bound(from, 0x00), v7Bound(to, 0xff))
+		} else {
+			rows, err = db.QueryContext(ctx, fmt.Sprintf(`
+				SELECT id, account_id, amount FROM %s
+				WHERE created_at BETWEEN $1 AND $2
+				ORDER BY created_at
+			`, strategy.table), from, to)
+		}
+
+		if err == nil {
+			for rows.Next() {
+				rowsReturned++
+			}
+			rows.Close()
+			successCount++
+		} else {
+			errorCount++
+		}
+		durations = append(durations, time.Since(opStart))
+	}
+
+	totalDuration := time.Since(start)
+
+	result := calculateResults(testName, count, 1, durations, successCount, errorCount, totalDuration)
+	result.KeyStrategy = strategy.name
+	result.RowsReturned = rowsReturned
+	return result
+}
+
+// v7Bound builds the smallest (fill 0x00) or largest (fill 0xff) UUID whose
+// 48-bit timestamp prefix is t, for use as a range bound on v7 keys.
+func v7Bound(t time.Time, fill byte) uuid.UUID {
+	var id uuid.UUID
+	for i := range id {
+		id[i] = fill
+	}
+
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(t.UnixMilli()))
+	copy(id[:6], ms[2:])
+	return id
+}
+
+func currentWALPosition(db *sql.DB) string {
+	var lsn string
+	db.QueryRowContext(ctx, "SELECT pg_current_wal_lsn()").Scan(&lsn)
+	return lsn
+}
+
+func walBytesSince(db *sql.DB, lsn string) int64 {
+	var bytes int64
+	if lsn == "" {
+		return 0
+	}
+	db.QueryRowContext(ctx, "SELECT pg_wal_lsn_diff(pg_current_wal_lsn(), $1)", lsn).Scan(&bytes)
+	return bytes
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+)
+
+const (
+	// rampStepDuration is how long each worker count is sustained.
+	rampStepDuration = 30 * time.Second
+)
+
+var (
+	// rampConcurrency is the worker counts the ramp steps through, past
+	// the pool's 100 connections at the top.
+	rampConcurrency = []int{1, 5, 10, 25, 50, 100, 200}
+)
+
+// rampOp is one operation the ramp is run for.
+type rampOp struct {
+	name string
+	op   func(db *sql.DB, rng *rand.Rand) error
+}
+
+var rampOps = []rampOp{
+	{"Point Reads", func(db *sql.DB, rng *rand.Rand) error {
+		var id uuid.UUID
+		var status string
+		return db.QueryRowContext(ctx, "SELECT id, status FROM transactions WHERE id = $1",
+			transactionIDs[benchmark.Pick(rng, len(transactionIDs))]).Scan(&id, &status)
+	}},
+	{"Single Inserts", insertTransaction},
+}
+
+// runRamp steps each operation through rising worker counts, sustaining
+// each for a set time, and logs the knee past which more workers stopped
+// raising throughput, where the connection pool or the server saturates.
+func runRamp(opts benchmark.Options) {
+	db := connect()
+	defer db.Close()
+	if benchmark.Pooled() {
+		db.SetMaxIdleConns(100)
+	}
+	loadTestData(db)
+
+	suite := benchmark.NewSuite("postgres-ramp")
+
+	slog.Info("Running Step-Load Concurrency Ramp Benchmarks")
+
+	for _, op := range rampOps {
+		var ramp benchmark.Ramp
+		run := benchmark.RetryingRand(func(rng *rand.Rand) error { return op.op(db, rng) })
+		for _, workers := range opts.ConcurrencyLevels(rampConcurrency...) {
+			suite.Run(func() benchmark.Result {
+				testName := fmt.Sprintf("Step Load - %s (%d workers)", op.name, workers)
+				result := benchmark.Sustained(testName, "PostgreSQL", workers, opts.Step(rampStepDuration), run)
+				result.Layout = op.name
+				return ramp.Add(result)
+			})
+		}
+		slog.Info("Throughput knee", "op", op.name, "workers", ramp.Knee.Concurrency,
+			"ops_per_sec", ramp.Knee.OperationsPerSec, "p99", ramp.Knee.P99Duration, "within_ramp", ramp.Saturated())
+	}
+
+	benchmark.Save(suite, "postgres-ramp")
+	benchmark.PrintSummary(suite)
+}
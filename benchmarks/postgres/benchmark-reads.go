@@ -1,144 +1,105 @@
-package main
+package postgres
 
 import (
 	"database/sql"
-	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/rand"
-	"os"
-	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	_ "github.com/lib/pq"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/dataset"
 )
 
-type BenchmarkResult struct {
-	TestName          string        `json:"test_name"`
-	Database          string        `json:"database"`
-	NumOperations     int           `json:"num_operations"`
-	Concurrency       int           `json:"concurrency"`
-	TotalDuration     time.Duration `json:"total_duration_ms"`
-	AverageDuration   time.Duration `json:"avg_duration_ms"`
-	MedianDuration    time.Duration `json:"median_duration_ms"`
-	P95Duration       time.Duration `json:"p95_duration_ms"`
-	P99Duration       time.Duration `json:"p99_duration_ms"`
-	OperationsPerSec  float64       `json:"operations_per_sec"`
-	SuccessCount      int           `json:"success_count"`
-	ErrorCount        int           `json:"error_count"`
-	Timestamp         time.Time     `json:"timestamp"`
-}
-
-type BenchmarkSuite struct {
-	Results []BenchmarkResult `json:"results"`
-}
-
-var (
-	accountIDs []uuid.UUID
-	transactionIDs []uuid.UUID
-)
-
-func main() {
-	connStr := "host=localhost port=5432 user=benchmark password=benchmark123 dbname=financial_benchmark sslmode=disable"
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
-	}
+func runReads(opts benchmark.Options) {
+	db := connect()
 	defer db.Close()
-
-	db.SetMaxOpenConns(100)
-	db.SetMaxIdleConns(10)
-
-	if err := db.Ping(); err != nil {
-		log.Fatal("Failed to ping database:", err)
-	}
-
-	log.Println("Connected to PostgreSQL")
 	loadTestData(db)
 
-	suite := BenchmarkSuite{Results: make([]BenchmarkResult, 0)}
+	suite := benchmark.NewSuite("postgres-read")
 
-	log.Println("\n=== Running Read Performance Benchmarks ===\n")
+	slog.Info("Running Read Performance Benchmarks")
 
 	// Single record lookups
-	suite.Results = append(suite.Results, benchmarkPointReads(db, 1000, "transaction"))
-	suite.Results = append(suite.Results, benchmarkPointReads(db, 1000, "account"))
+	suite.Run(func() benchmark.Result { return benchmarkPointReads(db, opts.Ops(1000), "transaction") })
+	suite.Run(func() benchmark.Result { return benchmarkPointReads(db, opts.Ops(1000), "account") })
+
+	// Transaction header with its legs
+	suite.Run(func() benchmark.Result { return benchmarkTransactionWithLegs(db, opts.Ops(1000)) })
 
 	// Range queries
-	suite.Results = append(suite.Results, benchmarkRangeQuery(db, 100, 24))  // Last 24 hours
-	suite.Results = append(suite.Results, benchmarkRangeQuery(db, 100, 720)) // Last 30 days
+	suite.Run(func() benchmark.Result { return benchmarkRangeQuery(db, opts.Ops(100), 24, opts.RowLimit(100)) })  // Last 24 hours
+	suite.Run(func() benchmark.Result { return benchmarkRangeQuery(db, opts.Ops(100), 720, opts.RowLimit(100)) }) // Last 30 days
+
+	// Newest transactions in each status
+	for _, status := range dataset.Statuses {
+		suite.Run(func() benchmark.Result { return benchmarkStatusQuery(db, opts.Ops(100), status, opts.RowLimit(100)) })
+	}
 
 	// Account balance lookups
-	suite.Results = append(suite.Results, benchmarkAccountBalance(db, 1000))
+	suite.Run(func() benchmark.Result { return benchmarkAccountBalance(db, opts.Ops(1000)) })
 
 	// Transaction history for account
-	suite.Results = append(suite.Results, benchmarkAccountHistory(db, 100, 100))
+	suite.Run(func() benchmark.Result { return benchmarkAccountHistory(db, opts.Ops(100), opts.RowLimit(100)) })
 
-	// Concurrent reads
-	suite.Results = append(suite.Results, benchmarkConcurrentReads(db, 1000, 10))
-	suite.Results = append(suite.Results, benchmarkConcurrentReads(db, 1000, 50))
-	suite.Results = append(suite.Results, benchmarkConcurrentReads(db, 1000, 100))
-
-	saveResults(suite, "benchmarks/results/postgres-read-results.json")
-	printSummary(suite)
-}
-
-func loadTestData(db *sql.DB) {
-	log.Println("Loading test data...")
-
-	rows, err := db.Query("SELECT id FROM accounts LIMIT 100")
-	if err != nil {
-		log.Fatal("Failed to load accounts:", err)
+	// The same reads mapped into structs by hand and by sqlx
+	mappers := rowMappers(db)
+	for _, m := range mappers {
+		suite.Run(func() benchmark.Result { return benchmarkHistoryMapping(m, opts.Ops(100), opts.RowLimit(100)) })
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var id uuid.UUID
-		rows.Scan(&id)
-		accountIDs = append(accountIDs, id)
+	for _, m := range mappers {
+		suite.Run(func() benchmark.Result { return benchmarkTransactionLegsMapping(m, opts.Ops(1000)) })
 	}
 
-	rows, err = db.Query("SELECT id FROM transactions LIMIT 1000")
-	if err != nil {
-		log.Fatal("Failed to load transactions:", err)
-	}
-	defer rows.Close()
+	// Merchant transactions in a date range
+	suite.Run(func() benchmark.Result { return benchmarkMerchantRangeQuery(db, opts.Ops(100), 7) })  // Last 7 days
+	suite.Run(func() benchmark.Result { return benchmarkMerchantRangeQuery(db, opts.Ops(100), 30) }) // Last 30 days
 
-	for rows.Next() {
-		var id uuid.UUID
-		rows.Scan(&id)
-		transactionIDs = append(transactionIDs, id)
+	// User home screen: all accounts plus recent activity
+	suite.Run(func() benchmark.Result { return benchmarkUserDashboard(db, opts.Ops(100), 10) })
+
+	// Concurrent reads
+	for _, concurrency := range opts.ConcurrencyLevels(10, 50, 100) {
+		suite.Run(func() benchmark.Result { return benchmarkConcurrentReads(db, opts.Ops(1000), concurrency) })
 	}
 
-	log.Printf("Loaded %d accounts and %d transactions", len(accountIDs), len(transactionIDs))
+	benchmark.Save(suite, "postgres-read")
+	benchmark.PrintSummary(suite)
 }
 
-func benchmarkPointReads(db *sql.DB, count int, entityType string) BenchmarkResult {
+func benchmarkPointReads(db *sql.DB, count int, entityType string) benchmark.Result {
 	testName := fmt.Sprintf("Point Reads - %s by ID", entityType)
-	log.Printf("Benchmarking %s (%d operations)...", testName, count)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
 
 	durations := make([]time.Duration, 0, count)
 	successCount := 0
 	errorCount := 0
-	start := time.Now()
-
-	for i := 0; i < count; i++ {
-		opStart := time.Now()
 
+	read := func(rng *rand.Rand) error {
 		var err error
 		if entityType == "transaction" {
-			txnID := transactionIDs[rand.Intn(len(transactionIDs))]
+			txnID := transactionIDs[benchmark.Pick(rng, len(transactionIDs))]
 			var id uuid.UUID
 			var status string
-			err = db.QueryRow("SELECT id, status FROM transactions WHERE id = $1", txnID).Scan(&id, &status)
+			err = db.QueryRowContext(ctx, "SELECT id, status FROM transactions WHERE id = $1", txnID).Scan(&id, &status)
 		} else {
-			accountID := accountIDs[rand.Intn(len(accountIDs))]
+			accountID := accountIDs[benchmark.Pick(rng, len(accountIDs))]
 			var id uuid.UUID
 			var balance float64
-			err = db.QueryRow("SELECT id, balance FROM accounts WHERE id = $1", accountID).Scan(&id, &balance)
+			err = db.QueryRowContext(ctx, "SELECT id, balance FROM accounts WHERE id = $1", accountID).Scan(&id, &balance)
 		}
+		return err
+	}
+	benchmark.WarmUp(1, read)
 
+	start := time.Now()
+
+	for i := 0; i < count; i++ {
+		opStart := time.Now()
+		err := read(benchmark.Rand())
 		duration := time.Since(opStart)
 		durations = append(durations, duration)
 
@@ -153,26 +114,87 @@ func benchmarkPointReads(db *sql.DB, count int, entityType string) BenchmarkResu
 	return calculateResults(testName, count, 1, durations, successCount, errorCount, totalDuration)
 }
 
-func benchmarkRangeQuery(db *sql.DB, count, hoursBack int) BenchmarkResult {
-	testName := fmt.Sprintf("Range Query - Last %d hours", hoursBack)
-	log.Printf("Benchmarking %s (%d operations)...", testName, count)
+// benchmarkTransactionWithLegs reads a transaction's header and legs in one
+// query, the counterpart to DynamoDB's Query on the transaction's item
+// collection.
+func benchmarkTransactionWithLegs(db *sql.DB, count int) benchmark.Result {
+	testName := "Transaction + Legs by ID (header and legs join)"
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
 
 	durations := make([]time.Duration, 0, count)
 	successCount := 0
 	errorCount := 0
+	rowsReturned := 0
+
+	read := func(rng *rand.Rand) (int, error) {
+		txnID := transactionIDs[benchmark.Pick(rng, len(transactionIDs))]
+		rows, err := db.QueryContext(ctx, `
+			SELECT t.id, t.status, t.created_at, tl.account_id, tl.leg_type, tl.amount, tl.currency
+			FROM transactions t
+			JOIN transaction_legs tl ON tl.transaction_id = t.id
+			WHERE t.id = $1
+		`, txnID)
+		if err != nil {
+			return 0, err
+		}
+		defer rows.Close()
+
+		n := 0
+		for rows.Next() {
+			var id, accountID uuid.UUID
+			var status, legType, currency string
+			var createdAt time.Time
+			var amount float64
+			if err := rows.Scan(&id, &status, &createdAt, &accountID, &legType, &amount, &currency); err != nil {
+				return n, err
+			}
+			n++
+		}
+		return n, rows.Err()
+	}
+	benchmark.WarmUp(1, func(rng *rand.Rand) error { _, err := read(rng); return err })
+
 	start := time.Now()
 
 	for i := 0; i < count; i++ {
 		opStart := time.Now()
+		n, err := read(benchmark.Rand())
+		duration := time.Since(opStart)
+		durations = append(durations, duration)
 
+		if err != nil {
+			errorCount++
+		} else {
+			successCount++
+			rowsReturned += n
+		}
+	}
+
+	totalDuration := time.Since(start)
+	result := calculateResults(testName, count, 1, durations, successCount, errorCount, totalDuration)
+	result.RowsReturned = rowsReturned
+	return result
+}
+
+func benchmarkRangeQuery(db *sql.DB, count, hoursBack, limit int) benchmark.Result {
+	testName := fmt.Sprintf("Range Query - Last %d hours", hoursBack)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
+
+	durations := make([]time.Duration, 0, count)
+	successCount := 0
+	errorCount := 0
+
+	read := func(rng *rand.Rand) error {
 		since := time.Now().Add(-time.Duration(hoursBack) * time.Hour)
-		rows, err := db.Query(`
+		rows, err := db.QueryContext(ctx, `
 			SELECT t.id, t.status, t.created_at
 			FROM transactions t
-			WHERE t.created_at >= $1
+			WHERE t.status = 'completed' AND t.created_at >= $1
 			ORDER BY t.created_at DESC
-			LIMIT 100
-		`, since)
+			LIMIT $2
+		`, since, limit)
 
 		if err == nil {
 			rowCount := 0
@@ -185,7 +207,15 @@ func benchmarkRangeQuery(db *sql.DB, count, hoursBack int) BenchmarkResult {
 			}
 			rows.Close()
 		}
+		return err
+	}
+	benchmark.WarmUp(1, read)
+
+	start := time.Now()
 
+	for i := 0; i < count; i++ {
+		opStart := time.Now()
+		err := read(benchmark.Rand())
 		duration := time.Since(opStart)
 		durations = append(durations, duration)
 
@@ -200,29 +230,96 @@ func benchmarkRangeQuery(db *sql.DB, count, hoursBack int) BenchmarkResult {
 	return calculateResults(testName, count, 1, durations, successCount, errorCount, totalDuration)
 }
 
-func benchmarkAccountBalance(db *sql.DB, count int) BenchmarkResult {
-	testName := "Account Balance Lookup"
-	log.Printf("Benchmarking %s (%d operations)...", testName, count)
+// benchmarkStatusQuery reads the newest transactions in status, the
+// pending-transactions screen and its failed and reversed counterparts,
+// from the (status, created_at) index.
+func benchmarkStatusQuery(db *sql.DB, count int, status string, limit int) benchmark.Result {
+	testName := fmt.Sprintf("Latest Transactions by Status (%s, %d rows)", status, limit)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
 
 	durations := make([]time.Duration, 0, count)
 	successCount := 0
 	errorCount := 0
+	rowsReturned := 0
+
+	read := func() (int, error) {
+		rows, err := db.QueryContext(ctx, `
+			SELECT t.id, t.created_at
+			FROM transactions t
+			WHERE t.status = $1
+			ORDER BY t.created_at DESC
+			LIMIT $2
+		`, status, limit)
+		if err != nil {
+			return 0, err
+		}
+		defer rows.Close()
+
+		rowCount := 0
+		for rows.Next() {
+			var id uuid.UUID
+			var createdAt time.Time
+			if err := rows.Scan(&id, &createdAt); err != nil {
+				return rowCount, err
+			}
+			rowCount++
+		}
+		return rowCount, rows.Err()
+	}
+	benchmark.WarmUp(1, func(*rand.Rand) error { _, err := read(); return err })
+
 	start := time.Now()
 
 	for i := 0; i < count; i++ {
 		opStart := time.Now()
+		n, err := read()
+		duration := time.Since(opStart)
+		durations = append(durations, duration)
+
+		if err != nil {
+			errorCount++
+		} else {
+			successCount++
+			rowsReturned += n
+		}
+	}
+
+	totalDuration := time.Since(start)
+	result := calculateResults(testName, count, 1, durations, successCount, errorCount, totalDuration)
+	result.RowsReturned = rowsReturned
+	return result
+}
+
+func benchmarkAccountBalance(db *sql.DB, count int) benchmark.Result {
+	testName := "Account Balance Lookup"
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
+
+	durations := make([]time.Duration, 0, count)
+	successCount := 0
+	errorCount := 0
 
-		accountID := accountIDs[rand.Intn(len(accountIDs))]
+	read := func(rng *rand.Rand) error {
+		accountID := accountIDs[benchmark.Pick(rng, len(accountIDs))]
 		var balance float64
 		var txnCount int
-		err := db.QueryRow(`
+		err := db.QueryRowContext(ctx, `
 			SELECT a.balance, COUNT(tl.id)
 			FROM accounts a
 			LEFT JOIN transaction_legs tl ON a.id = tl.account_id
 			WHERE a.id = $1
 			GROUP BY a.id, a.balance
 		`, accountID).Scan(&balance, &txnCount)
+		return err
+	}
+	benchmark.WarmUp(1, read)
 
+	start := time.Now()
+
+	for i := 0; i < count; i++ {
+		opStart := time.Now()
+		err := read(benchmark.Rand())
 		duration := time.Since(opStart)
 		durations = append(durations, duration)
 
@@ -237,38 +334,114 @@ func benchmarkAccountBalance(db *sql.DB, count int) BenchmarkResult {
 	return calculateResults(testName, count, 1, durations, successCount, errorCount, totalDuration)
 }
 
-func benchmarkAccountHistory(db *sql.DB, count, limit int) BenchmarkResult {
+func benchmarkAccountHistory(db *sql.DB, count, limit int) benchmark.Result {
 	testName := fmt.Sprintf("Account Transaction History (last %d txns)", limit)
-	log.Printf("Benchmarking %s (%d operations)...", testName, count)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
 
 	durations := make([]time.Duration, 0, count)
 	successCount := 0
 	errorCount := 0
+	// Per successful read, for NormalizePerItem.
+	readDurations := make([]time.Duration, 0, count)
+	sizes := make([]int, 0, count)
+
+	query := `
+		SELECT tl.transaction_id, tl.leg_type, tl.amount, tl.created_at
+		FROM transaction_legs tl
+		WHERE tl.account_id = $1
+		ORDER BY tl.created_at DESC
+		LIMIT $2
+	`
+	read := func(rng *rand.Rand) (int, error) {
+		accountID := accountIDs[benchmark.Pick(rng, len(accountIDs))]
+		rows, err := db.QueryContext(ctx, query, accountID, limit)
+		if err != nil {
+			return 0, err
+		}
+		defer rows.Close()
+
+		n := 0
+		for rows.Next() {
+			var txnID uuid.UUID
+			var legType string
+			var amount float64
+			var createdAt time.Time
+			rows.Scan(&txnID, &legType, &amount, &createdAt)
+			n++
+		}
+		return n, rows.Err()
+	}
+	benchmark.WarmUp(1, func(rng *rand.Rand) error { _, err := read(rng); return err })
+
 	start := time.Now()
 
 	for i := 0; i < count; i++ {
 		opStart := time.Now()
+		n, err := read(benchmark.Rand())
+		duration := time.Since(opStart)
+		durations = append(durations, duration)
 
-		accountID := accountIDs[rand.Intn(len(accountIDs))]
-		rows, err := db.Query(`
-			SELECT tl.transaction_id, tl.leg_type, tl.amount, tl.created_at
-			FROM transaction_legs tl
-			WHERE tl.account_id = $1
-			ORDER BY tl.created_at DESC
-			LIMIT $2
-		`, accountID, limit)
+		if err != nil {
+			errorCount++
+		} else {
+			successCount++
+			readDurations = append(readDurations, duration)
+			sizes = append(sizes, n)
+		}
+	}
+
+	totalDuration := time.Since(start)
+	result := calculateResults(testName, count, 1, durations, successCount, errorCount, totalDuration)
+	result.NormalizePerItem(readDurations, sizes)
+	for _, n := range sizes {
+		result.RowsReturned += n
+	}
+	result.RowsScanned = estimateRowsScanned(db, successCount, query, func() []any {
+		return []any{accountIDs[benchmark.Pick(benchmark.Rand(), len(accountIDs))], limit}
+	})
+	return result
+}
+
+func benchmarkMerchantRangeQuery(db *sql.DB, count, daysBack int) benchmark.Result {
+	testName := fmt.Sprintf("Merchant Transactions (last %d days)", daysBack)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
+
+	durations := make([]time.Duration, 0, count)
+	successCount := 0
+	errorCount := 0
+
+	read := func(rng *rand.Rand) error {
+		merchantID := merchantIDs[benchmark.Pick(rng, len(merchantIDs))]
+		to := time.Now()
+		from := to.Add(-time.Duration(daysBack) * 24 * time.Hour)
+		rows, err := db.QueryContext(ctx, `
+			SELECT t.id, t.transaction_type, t.status, t.created_at
+			FROM transactions t
+			WHERE t.merchant_id = $1
+				AND t.created_at BETWEEN $2 AND $3
+			ORDER BY t.created_at DESC
+		`, merchantID, from, to)
 
 		if err == nil {
 			for rows.Next() {
-				var txnID uuid.UUID
-				var legType string
-				var amount float64
+				var id uuid.UUID
+				var txnType, status string
 				var createdAt time.Time
-				rows.Scan(&txnID, &legType, &amount, &createdAt)
+				rows.Scan(&id, &txnType, &status, &createdAt)
 			}
 			rows.Close()
 		}
+		return err
+	}
+	benchmark.WarmUp(1, read)
 
+	start := time.Now()
+
+	for i := 0; i < count; i++ {
+		opStart := time.Now()
+		err := read(benchmark.Rand())
 		duration := time.Since(opStart)
 		durations = append(durations, duration)
 
@@ -283,117 +456,100 @@ func benchmarkAccountHistory(db *sql.DB, count, limit int) BenchmarkResult {
 	return calculateResults(testName, count, 1, durations, successCount, errorCount, totalDuration)
 }
 
-func benchmarkConcurrentReads(db *sql.DB, opsPerGoroutine, numGoroutines int) BenchmarkResult {
-	testName := fmt.Sprintf("Concurrent Reads (%d goroutines, %d ops each)", numGoroutines, opsPerGoroutine)
-	log.Printf("Benchmarking %s...", testName)
+func benchmarkUserDashboard(db *sql.DB, count, legsPerAccount int) benchmark.Result {
+	testName := fmt.Sprintf("User Dashboard (profile, accounts, last %d legs per account)", legsPerAccount)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
 
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-	durations := make([]time.Duration, 0, opsPerGoroutine*numGoroutines)
+	durations := make([]time.Duration, 0, count)
 	successCount := 0
 	errorCount := 0
 
-	start := time.Now()
-
-	for g := 0; g < numGoroutines; g++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for i := 0; i < opsPerGoroutine; i++ {
-				opStart := time.Now()
+	read := func(rng *rand.Rand) error {
+		userID := userIDs[benchmark.Pick(rng, len(userIDs))]
+		rows, err := db.QueryContext(ctx, `
+			SELECT u.name, u.email, a.id, a.account_type, a.balance, a.currency,
+				recent.transaction_id, recent.leg_type, recent.amount, recent.created_at
+			FROM users u
+			JOIN accounts a ON a.user_id = u.id
+			LEFT JOIN LATERAL (
+				SELECT tl.transaction_id, tl.leg_type, tl.amount, tl.created_at
+				FROM transaction_legs tl
+				WHERE tl.account_id = a.id
+				ORDER BY tl.created_at DESC
+				LIMIT $2
+			) recent ON true
+			WHERE u.id = $1
+			ORDER BY a.id, recent.created_at DESC
+		`, userID, legsPerAccount)
 
-				txnID := transactionIDs[rand.Intn(len(transactionIDs))]
-				var id uuid.UUID
-				var status string
-				err := db.QueryRow("SELECT id, status FROM transactions WHERE id = $1", txnID).Scan(&id, &status)
-
-				duration := time.Since(opStart)
-
-				mu.Lock()
-				durations = append(durations, duration)
-				if err != nil {
-					errorCount++
-				} else {
-					successCount++
-				}
-				mu.Unlock()
+		if err == nil {
+			for rows.Next() {
+				var accountID uuid.UUID
+				var name, email, accountType, currency string
+				var balance float64
+				var txnID uuid.NullUUID
+				var legType sql.NullString
+				var amount sql.NullFloat64
+				var createdAt sql.NullTime
+				rows.Scan(&name, &email, &accountID, &accountType, &balance, &currency, &txnID, &legType, &amount, &createdAt)
 			}
-		}()
+			rows.Close()
+		}
+		return err
 	}
+	benchmark.WarmUp(1, read)
 
-	wg.Wait()
-	totalDuration := time.Since(start)
-
-	return calculateResults(testName, opsPerGoroutine*numGoroutines, numGoroutines, durations, successCount, errorCount, totalDuration)
-}
+	start := time.Now()
 
-func calculateResults(testName string, totalOps, concurrency int, durations []time.Duration, success, errors int, totalDuration time.Duration) BenchmarkResult {
-	sorted := make([]time.Duration, len(durations))
-	copy(sorted, durations)
-	for i := 0; i < len(sorted); i++ {
-		for j := i + 1; j < len(sorted); j++ {
-			if sorted[i] > sorted[j] {
-				sorted[i], sorted[j] = sorted[j], sorted[i]
-			}
-		}
-	}
+	for i := 0; i < count; i++ {
+		opStart := time.Now()
+		err := read(benchmark.Rand())
+		duration := time.Since(opStart)
+		durations = append(durations, duration)
 
-	var avgDuration time.Duration
-	if len(durations) > 0 {
-		var sum time.Duration
-		for _, d := range durations {
-			sum += d
+		if err != nil {
+			errorCount++
+		} else {
+			successCount++
 		}
-		avgDuration = sum / time.Duration(len(durations))
 	}
 
-	median := sorted[len(sorted)/2]
-	p95 := sorted[int(float64(len(sorted))*0.95)]
-	p99 := sorted[int(float64(len(sorted))*0.99)]
-	opsPerSec := float64(totalOps) / totalDuration.Seconds()
-
-	return BenchmarkResult{
-		TestName:         testName,
-		Database:         "PostgreSQL",
-		NumOperations:    totalOps,
-		Concurrency:      concurrency,
-		TotalDuration:    totalDuration,
-		AverageDuration:  avgDuration,
-		MedianDuration:   median,
-		P95Duration:      p95,
-		P99Duration:      p99,
-		OperationsPerSec: opsPerSec,
-		SuccessCount:     success,
-		ErrorCount:       errors,
-		Timestamp:        time.Now(),
-	}
+	totalDuration := time.Since(start)
+	return calculateResults(testName, count, 1, durations, successCount, errorCount, totalDuration)
 }
 
-func saveResults(suite BenchmarkSuite, filename string) {
-	data, err := json.MarshalIndent(suite, "", "  ")
-	if err != nil {
-		log.Printf("Failed to marshal results: %v", err)
-		return
-	}
+func benchmarkConcurrentReads(db *sql.DB, opsPerGoroutine, numGoroutines int) benchmark.Result {
+	testName := fmt.Sprintf("Concurrent Reads (%d goroutines, %d ops each)", numGoroutines, opsPerGoroutine)
+	slog.Info("Benchmarking", "test", testName)
+	benchmark.StartTest(testName)
 
-	if err := os.WriteFile(filename, data, 0644); err != nil {
-		log.Printf("Failed to write results: %v", err)
-		return
+	read := benchmark.RetryingRand(func(rng *rand.Rand) error {
+		txnID := transactionIDs[benchmark.Pick(rng, len(transactionIDs))]
+		var id uuid.UUID
+		var status string
+		return db.QueryRowContext(ctx, "SELECT id, status FROM transactions WHERE id = $1", txnID).Scan(&id, &status)
+	})
+	benchmark.WarmUp(numGoroutines, read)
+	if rate := benchmark.TargetRate(); rate > 0 {
+		return benchmark.Paced(fmt.Sprintf("%s at %.0f ops/sec", testName, rate), "PostgreSQL", opsPerGoroutine*numGoroutines, numGoroutines, read)
 	}
 
-	log.Printf("\nResults saved to %s", filename)
-}
+	ran := benchmark.RunWorkers(numGoroutines, func(worker int, local *benchmark.Recorder, rng *rand.Rand) benchmark.WorkerTally {
+		var tally benchmark.WorkerTally
+		for i := 0; i < opsPerGoroutine && !benchmark.Stopping(); i++ {
+			opStart := time.Now()
+			err := read(rng)
+			local.Record(time.Since(opStart))
+			local.Think()
+			if err != nil {
+				tally.Errors++
+			} else {
+				tally.Successes++
+			}
+		}
+		return tally
+	})
 
-func printSummary(suite BenchmarkSuite) {
-	fmt.Println("\n=== Benchmark Summary ===\n")
-	for _, result := range suite.Results {
-		fmt.Printf("Test: %s\n", result.TestName)
-		fmt.Printf("  Operations: %d (Success: %d, Errors: %d)\n", result.NumOperations, result.SuccessCount, result.ErrorCount)
-		fmt.Printf("  Total Duration: %v\n", result.TotalDuration)
-		fmt.Printf("  Ops/sec: %.2f\n", result.OperationsPerSec)
-		fmt.Printf("  Avg Latency: %v\n", result.AverageDuration)
-		fmt.Printf("  P95 Latency: %v\n", result.P95Duration)
-		fmt.Printf("  P99 Latency: %v\n", result.P99Duration)
-		fmt.Println()
-	}
+	return ran.Summarize(testName, "PostgreSQL", opsPerGoroutine*numGoroutines, numGoroutines)
 }
@@ -1,107 +1,72 @@
-package main
+package postgres
 
 import (
 	"database/sql"
-	"encoding/json"
 	"fmt"
-	"log"
-	"math/rand"
-	"os"
+	"log/slog"
 	"time"
 
 	"github.com/google/uuid"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/dataset"
+	"github.com/shopspring/decimal"
 )
 
-type BenchmarkResult struct {
-	TestName          string        `json:"test_name"`
-	Database          string        `json:"database"`
-	NumOperations     int           `json:"num_operations"`
-	TotalDuration     time.Duration `json:"total_duration_ms"`
-	AverageDuration   time.Duration `json:"avg_duration_ms"`
-	OperationsPerSec  float64       `json:"operations_per_sec"`
-	RowsScanned       int64         `json:"rows_scanned"`
-	RowsReturned      int           `json:"rows_returned"`
-	SuccessCount      int           `json:"success_count"`
-	ErrorCount        int           `json:"error_count"`
-	Timestamp         time.Time     `json:"timestamp"`
-}
-
-type BenchmarkSuite struct {
-	Results []BenchmarkResult `json:"results"`
-}
-
-var accountIDs []uuid.UUID
+// closeOfDayWindow is the time a batch job typically gets between end of
+// business and the next day's opening to produce ledger reports.
+const closeOfDayWindow = 30 * time.Minute
 
-func main() {
-	connStr := "host=localhost port=5432 user=benchmark password=benchmark123 dbname=financial_benchmark sslmode=disable"
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
-	}
+func runReconciliation(opts benchmark.Options) {
+	db := connect()
 	defer db.Close()
-
-	if err := db.Ping(); err != nil {
-		log.Fatal("Failed to ping database:", err)
-	}
-
-	log.Println("Connected to PostgreSQL")
 	loadTestData(db)
 
-	suite := BenchmarkSuite{Results: make([]BenchmarkResult, 0)}
+	suite := benchmark.NewSuite("postgres-reconciliation")
 
-	log.Println("\n=== Running Reconciliation & Complex Query Benchmarks ===\n")
+	slog.Info("Running Reconciliation & Complex Query Benchmarks")
 
-	suite.Results = append(suite.Results, benchmarkAccountReconciliation(db, 100))
-	suite.Results = append(suite.Results, benchmarkDailySummary(db, 10))
-	suite.Results = append(suite.Results, benchmarkMerchantAnalysis(db, 50))
-	suite.Results = append(suite.Results, benchmarkTopAccounts(db, 100))
-	suite.Results = append(suite.Results, benchmarkBalanceVerification(db, 50))
-	suite.Results = append(suite.Results, benchmarkJoinQuery(db, 100))
-
-	saveResults(suite, "benchmarks/results/postgres-reconciliation-results.json")
-	printSummary(suite)
-}
-
-func loadTestData(db *sql.DB) {
-	rows, err := db.Query("SELECT id FROM accounts LIMIT 100")
-	if err != nil {
-		log.Fatal("Failed to load accounts:", err)
-	}
-	defer rows.Close()
+	suite.Run(func() benchmark.Result { return benchmarkAccountReconciliation(db, opts.Ops(100)) })
+	suite.Run(func() benchmark.Result { return benchmarkDailySummary(db, opts.Ops(10)) })
+	suite.Run(func() benchmark.Result { return benchmarkMerchantAnalysis(db, opts.Ops(50), opts.RowLimit(50)) })
+	suite.Run(func() benchmark.Result { return benchmarkTopAccounts(db, opts.Ops(100), opts.RowLimit(100)) })
+	suite.Run(func() benchmark.Result { return benchmarkBalanceVerification(db, opts.Ops(50), opts.RowLimit(100)) })
+	suite.RunAll(func() []benchmark.Result { return benchmarkSuspenseDetectionJob(db, 1000) })
+	suite.Run(func() benchmark.Result { return benchmarkTrialBalance(db) })
+	suite.Run(func() benchmark.Result { return benchmarkJoinQuery(db, opts.Ops(100), opts.RowLimit(100)) })
+	suite.Run(func() benchmark.Result { return benchmarkCurrencyConversionReport(db, opts.Ops(50), 24) })
+	suite.Run(func() benchmark.Result { return benchmarkCurrencyConversionReport(db, opts.Ops(10), 720) })
 
-	for rows.Next() {
-		var id uuid.UUID
-		rows.Scan(&id)
-		accountIDs = append(accountIDs, id)
-	}
-	log.Printf("Loaded %d accounts", len(accountIDs))
+	benchmark.Save(suite, "postgres-reconciliation")
+	benchmark.PrintSummary(suite)
 }
 
-func benchmarkAccountReconciliation(db *sql.DB, count int) BenchmarkResult {
+func benchmarkAccountReconciliation(db *sql.DB, count int) benchmark.Result {
 	testName := "Account Reconciliation (SUM by account)"
-	log.Printf("Benchmarking %s (%d operations)...", testName, count)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
 
 	successCount := 0
 	errorCount := 0
 	var totalRows int64
+	query := `
+		SELECT
+			leg_type,
+			COUNT(*) as count,
+			SUM(amount) as total,
+			AVG(amount) as average,
+			MIN(amount) as min,
+			MAX(amount) as max
+		FROM transaction_legs
+		WHERE account_id = $1
+		GROUP BY leg_type
+	`
 	start := time.Now()
 
 	for i := 0; i < count; i++ {
-		accountID := accountIDs[rand.Intn(len(accountIDs))]
-
-		rows, err := db.Query(`
-			SELECT
-				leg_type,
-				COUNT(*) as count,
-				SUM(amount) as total,
-				AVG(amount) as average,
-				MIN(amount) as min,
-				MAX(amount) as max
-			FROM transaction_legs
-			WHERE account_id = $1
-			GROUP BY leg_type
-		`, accountID)
+		accountID := accountIDs[benchmark.Pick(benchmark.Rand(), len(accountIDs))]
+
+		rows, err := db.QueryContext(ctx, query, accountID)
 
 		if err == nil {
 			for rows.Next() {
@@ -120,15 +85,18 @@ func benchmarkAccountReconciliation(db *sql.DB, count int) BenchmarkResult {
 	totalDuration := time.Since(start)
 	avgDuration := totalDuration / time.Duration(count)
 	opsPerSec := float64(count) / totalDuration.Seconds()
+	rowsScanned := estimateRowsScanned(db, successCount, query, func() []any {
+		return []any{accountIDs[benchmark.Pick(benchmark.Rand(), len(accountIDs))]}
+	})
 
-	return BenchmarkResult{
+	return benchmark.Result{
 		TestName:         testName,
 		Database:         "PostgreSQL",
 		NumOperations:    count,
 		TotalDuration:    totalDuration,
 		AverageDuration:  avgDuration,
 		OperationsPerSec: opsPerSec,
-		RowsScanned:      totalRows,
+		RowsScanned:      rowsScanned,
 		RowsReturned:     int(totalRows),
 		SuccessCount:     successCount,
 		ErrorCount:       errorCount,
@@ -136,29 +104,31 @@ func benchmarkAccountReconciliation(db *sql.DB, count int) BenchmarkResult {
 	}
 }
 
-func benchmarkDailySummary(db *sql.DB, count int) BenchmarkResult {
+func benchmarkDailySummary(db *sql.DB, count int) benchmark.Result {
 	testName := "Daily Transaction Summary (GROUP BY date)"
-	log.Printf("Benchmarking %s (%d operations)...", testName, count)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
 
 	successCount := 0
 	errorCount := 0
 	var totalRows int64
+	query := `
+		SELECT
+			DATE(t.created_at) as date,
+			t.transaction_type,
+			COUNT(*) as count,
+			SUM(tl.amount) as total_amount
+		FROM transactions t
+		JOIN transaction_legs tl ON t.id = tl.transaction_id
+		WHERE t.created_at >= NOW() - INTERVAL '30 days'
+			AND tl.leg_type = 'debit'
+		GROUP BY DATE(t.created_at), t.transaction_type
+		ORDER BY date DESC
+	`
 	start := time.Now()
 
 	for i := 0; i < count; i++ {
-		rows, err := db.Query(`
-			SELECT
-				DATE(t.created_at) as date,
-				t.transaction_type,
-				COUNT(*) as count,
-				SUM(tl.amount) as total_amount
-			FROM transactions t
-			JOIN transaction_legs tl ON t.id = tl.transaction_id
-			WHERE t.created_at >= NOW() - INTERVAL '30 days'
-				AND tl.leg_type = 'debit'
-			GROUP BY DATE(t.created_at), t.transaction_type
-			ORDER BY date DESC
-		`)
+		rows, err := db.QueryContext(ctx, query)
 
 		if err == nil {
 			for rows.Next() {
@@ -180,14 +150,14 @@ func benchmarkDailySummary(db *sql.DB, count int) BenchmarkResult {
 	avgDuration := totalDuration / time.Duration(count)
 	opsPerSec := float64(count) / totalDuration.Seconds()
 
-	return BenchmarkResult{
+	return benchmark.Result{
 		TestName:         testName,
 		Database:         "PostgreSQL",
 		NumOperations:    count,
 		TotalDuration:    totalDuration,
 		AverageDuration:  avgDuration,
 		OperationsPerSec: opsPerSec,
-		RowsScanned:      totalRows,
+		RowsScanned:      estimateRowsScanned(db, successCount, query, func() []any { return nil }),
 		RowsReturned:     int(totalRows),
 		SuccessCount:     successCount,
 		ErrorCount:       errorCount,
@@ -195,33 +165,35 @@ func benchmarkDailySummary(db *sql.DB, count int) BenchmarkResult {
 	}
 }
 
-func benchmarkMerchantAnalysis(db *sql.DB, count int) BenchmarkResult {
+func benchmarkMerchantAnalysis(db *sql.DB, count, limit int) benchmark.Result {
 	testName := "Merchant Analysis (JOIN with aggregation)"
-	log.Printf("Benchmarking %s (%d operations)...", testName, count)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
 
 	successCount := 0
 	errorCount := 0
 	var totalRows int64
+	query := `
+		SELECT
+			m.id,
+			m.name,
+			m.category,
+			COUNT(t.id) as transaction_count,
+			SUM(tl.amount) as total_volume
+		FROM merchants m
+		JOIN transactions t ON m.id = t.merchant_id
+		JOIN transaction_legs tl ON t.id = tl.transaction_id
+		WHERE tl.leg_type = 'debit'
+			AND t.created_at >= NOW() - INTERVAL '7 days'
+		GROUP BY m.id, m.name, m.category
+		HAVING COUNT(t.id) > 5
+		ORDER BY total_volume DESC
+		LIMIT $1
+	`
 	start := time.Now()
 
 	for i := 0; i < count; i++ {
-		rows, err := db.Query(`
-			SELECT
-				m.id,
-				m.name,
-				m.category,
-				COUNT(t.id) as transaction_count,
-				SUM(tl.amount) as total_volume
-			FROM merchants m
-			JOIN transactions t ON m.id = t.merchant_id
-			JOIN transaction_legs tl ON t.id = tl.transaction_id
-			WHERE tl.leg_type = 'debit'
-				AND t.created_at >= NOW() - INTERVAL '7 days'
-			GROUP BY m.id, m.name, m.category
-			HAVING COUNT(t.id) > 5
-			ORDER BY total_volume DESC
-			LIMIT 50
-		`)
+		rows, err := db.QueryContext(ctx, query, limit)
 
 		if err == nil {
 			for rows.Next() {
@@ -243,14 +215,14 @@ func benchmarkMerchantAnalysis(db *sql.DB, count int) BenchmarkResult {
 	avgDuration := totalDuration / time.Duration(count)
 	opsPerSec := float64(count) / totalDuration.Seconds()
 
-	return BenchmarkResult{
+	return benchmark.Result{
 		TestName:         testName,
 		Database:         "PostgreSQL",
 		NumOperations:    count,
 		TotalDuration:    totalDuration,
 		AverageDuration:  avgDuration,
 		OperationsPerSec: opsPerSec,
-		RowsScanned:      totalRows,
+		RowsScanned:      estimateRowsScanned(db, successCount, query, func() []any { return []any{limit} }),
 		RowsReturned:     int(totalRows),
 		SuccessCount:     successCount,
 		ErrorCount:       errorCount,
@@ -258,31 +230,33 @@ func benchmarkMerchantAnalysis(db *sql.DB, count int) BenchmarkResult {
 	}
 }
 
-func benchmarkTopAccounts(db *sql.DB, count int) BenchmarkResult {
+func benchmarkTopAccounts(db *sql.DB, count, limit int) benchmark.Result {
 	testName := "Top N Accounts by Activity"
-	log.Printf("Benchmarking %s (%d operations)...", testName, count)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
 
 	successCount := 0
 	errorCount := 0
 	var totalRows int64
+	query := `
+		SELECT
+			a.id,
+			a.account_type,
+			a.balance,
+			COUNT(tl.id) as transaction_count,
+			SUM(CASE WHEN tl.leg_type = 'debit' THEN tl.amount ELSE 0 END) as total_debits,
+			SUM(CASE WHEN tl.leg_type = 'credit' THEN tl.amount ELSE 0 END) as total_credits
+		FROM accounts a
+		LEFT JOIN transaction_legs tl ON a.id = tl.account_id
+		WHERE tl.created_at >= NOW() - INTERVAL '30 days'
+		GROUP BY a.id, a.account_type, a.balance
+		ORDER BY transaction_count DESC
+		LIMIT $1
+	`
 	start := time.Now()
 
 	for i := 0; i < count; i++ {
-		rows, err := db.Query(`
-			SELECT
-				a.id,
-				a.account_type,
-				a.balance,
-				COUNT(tl.id) as transaction_count,
-				SUM(CASE WHEN tl.leg_type = 'debit' THEN tl.amount ELSE 0 END) as total_debits,
-				SUM(CASE WHEN tl.leg_type = 'credit' THEN tl.amount ELSE 0 END) as total_credits
-			FROM accounts a
-			LEFT JOIN transaction_legs tl ON a.id = tl.account_id
-			WHERE tl.created_at >= NOW() - INTERVAL '30 days'
-			GROUP BY a.id, a.account_type, a.balance
-			ORDER BY transaction_count DESC
-			LIMIT 100
-		`)
+		rows, err := db.QueryContext(ctx, query, limit)
 
 		if err == nil {
 			for rows.Next() {
@@ -305,14 +279,14 @@ func benchmarkTopAccounts(db *sql.DB, count int) BenchmarkResult {
 	avgDuration := totalDuration / time.Duration(count)
 	opsPerSec := float64(count) / totalDuration.Seconds()
 
-	return BenchmarkResult{
+	return benchmark.Result{
 		TestName:         testName,
 		Database:         "PostgreSQL",
 		NumOperations:    count,
 		TotalDuration:    totalDuration,
 		AverageDuration:  avgDuration,
 		OperationsPerSec: opsPerSec,
-		RowsScanned:      totalRows,
+		RowsScanned:      estimateRowsScanned(db, successCount, query, func() []any { return []any{limit} }),
 		RowsReturned:     int(totalRows),
 		SuccessCount:     successCount,
 		ErrorCount:       errorCount,
@@ -320,30 +294,32 @@ func benchmarkTopAccounts(db *sql.DB, count int) BenchmarkResult {
 	}
 }
 
-func benchmarkBalanceVerification(db *sql.DB, count int) BenchmarkResult {
+func benchmarkBalanceVerification(db *sql.DB, count, limit int) benchmark.Result {
 	testName := "Balance Verification (debits = credits)"
-	log.Printf("Benchmarking %s (%d operations)...", testName, count)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
 
 	successCount := 0
 	errorCount := 0
 	var totalRows int64
+	query := `
+		SELECT
+			t.id,
+			SUM(CASE WHEN tl.leg_type = 'debit' THEN tl.amount ELSE 0 END) as total_debits,
+			SUM(CASE WHEN tl.leg_type = 'credit' THEN tl.amount ELSE 0 END) as total_credits,
+			SUM(CASE WHEN tl.leg_type = 'debit' THEN tl.amount ELSE 0 END) -
+			SUM(CASE WHEN tl.leg_type = 'credit' THEN tl.amount ELSE 0 END) as difference
+		FROM transactions t
+		JOIN transaction_legs tl ON t.id = tl.transaction_id
+		GROUP BY t.id
+		HAVING SUM(CASE WHEN tl.leg_type = 'debit' THEN tl.amount ELSE 0 END) !=
+			   SUM(CASE WHEN tl.leg_type = 'credit' THEN tl.amount ELSE 0 END)
+		LIMIT $1
+	`
 	start := time.Now()
 
 	for i := 0; i < count; i++ {
-		rows, err := db.Query(`
-			SELECT
-				t.id,
-				SUM(CASE WHEN tl.leg_type = 'debit' THEN tl.amount ELSE 0 END) as total_debits,
-				SUM(CASE WHEN tl.leg_type = 'credit' THEN tl.amount ELSE 0 END) as total_credits,
-				SUM(CASE WHEN tl.leg_type = 'debit' THEN tl.amount ELSE 0 END) -
-				SUM(CASE WHEN tl.leg_type = 'credit' THEN tl.amount ELSE 0 END) as difference
-			FROM transactions t
-			JOIN transaction_legs tl ON t.id = tl.transaction_id
-			GROUP BY t.id
-			HAVING SUM(CASE WHEN tl.leg_type = 'debit' THEN tl.amount ELSE 0 END) !=
-				   SUM(CASE WHEN tl.leg_type = 'credit' THEN tl.amount ELSE 0 END)
-			LIMIT 100
-		`)
+		rows, err := db.QueryContext(ctx, query, limit)
 
 		if err == nil {
 			for rows.Next() {
@@ -363,14 +339,14 @@ func benchmarkBalanceVerification(db *sql.DB, count int) BenchmarkResult {
 	avgDuration := totalDuration / time.Duration(count)
 	opsPerSec := float64(count) / totalDuration.Seconds()
 
-	return BenchmarkResult{
+	return benchmark.Result{
 		TestName:         testName,
 		Database:         "PostgreSQL",
 		NumOperations:    count,
 		TotalDuration:    totalDuration,
 		AverageDuration:  avgDuration,
 		OperationsPerSec: opsPerSec,
-		RowsScanned:      totalRows,
+		RowsScanned:      estimateRowsScanned(db, successCount, query, func() []any { return []any{limit} }),
 		RowsReturned:     int(totalRows),
 		SuccessCount:     successCount,
 		ErrorCount:       errorCount,
@@ -378,35 +354,288 @@ func benchmarkBalanceVerification(db *sql.DB, count int) BenchmarkResult {
 	}
 }
 
-func benchmarkJoinQuery(db *sql.DB, count int) BenchmarkResult {
+// benchmarkSuspenseDetectionJob runs balance verification the way a nightly job
+// would: over the entire ledger with no LIMIT, persisting every unbalanced
+// transaction to reconciliation_exceptions. numFixtures deliberately
+// unbalanced transactions are inserted first so the flagging write path is
+// exercised, and removed again afterwards. Unbalanced transactions injected
+// at seed time must all be found.
+func benchmarkSuspenseDetectionJob(db *sql.DB, numFixtures int) []benchmark.Result {
+	slog.Info("Benchmarking", "test", "Suspense Detection Job", "fixtures", numFixtures)
+	benchmark.StartTest("Suspense Detection Job")
+
+	fixtureIDs := insertSuspenseFixtures(db, numFixtures)
+	defer deleteSuspenseFixtures(db, fixtureIDs)
+
+	runID := uuid.New()
+	errorCount := 0
+
+	var examined int64
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM transactions").Scan(&examined); err != nil {
+		errorCount++
+	}
+
+	// Phase 1: detect
+	type exception struct {
+		txnID           uuid.UUID
+		debits, credits decimal.Decimal
+	}
+	flagged := make([]exception, 0)
+
+	detectStart := time.Now()
+	rows, err := db.QueryContext(ctx, `
+		SELECT
+			t.id,
+			COALESCE(SUM(CASE WHEN tl.leg_type = 'debit' THEN tl.amount END), 0) as total_debits,
+			COALESCE(SUM(CASE WHEN tl.leg_type = 'credit' THEN tl.amount END), 0) as total_credits
+		FROM transactions t
+		LEFT JOIN transaction_legs tl ON t.id = tl.transaction_id
+		GROUP BY t.id
+		HAVING COALESCE(SUM(CASE WHEN tl.leg_type = 'debit' THEN tl.amount END), 0) !=
+			   COALESCE(SUM(CASE WHEN tl.leg_type = 'credit' THEN tl.amount END), 0)
+	`)
+	if err != nil {
+		errorCount++
+	} else {
+		for rows.Next() {
+			var e exception
+			if err := rows.Scan(&e.txnID, &e.debits, &e.credits); err != nil {
+				errorCount++
+				continue
+			}
+			flagged = append(flagged, e)
+		}
+		rows.Close()
+	}
+	detectDuration := time.Since(detectStart)
+
+	slog.Info("Suspense detection examined", "transactions", examined, "flagged", len(flagged),
+		"duration", detectDuration)
+	if len(flagged) < numFixtures {
+		slog.Warn("Fewer transactions flagged than fixtures written", "fixtures", numFixtures, "flagged", len(flagged))
+	}
+
+	// Phase 2: flag (COPY into the exceptions table in one transaction)
+	flagStart := time.Now()
+	flagErrors := 0
+	tx, err := beginCopy(db)
+	if err != nil {
+		flagErrors++
+	} else {
+		err := copyRows(tx, "reconciliation_exceptions", []string{"run_id", "transaction_id", "total_debits", "total_credits", "difference"}, func(row func(...any) error) error {
+			for _, e := range flagged {
+				if err := row(runID, e.txnID, e.debits, e.credits, e.debits.Sub(e.credits)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			flagErrors++
+			tx.Rollback()
+		} else if err := tx.Commit(); err != nil {
+			flagErrors++
+		}
+	}
+	flagDuration := time.Since(flagStart)
+
+	slog.Info("Wrote exceptions", "exceptions", len(flagged), "duration", flagDuration)
+
+	if _, err := db.ExecContext(ctx, "DELETE FROM reconciliation_exceptions WHERE run_id = $1", runID); err != nil {
+		slog.Error("Failed to clean up exceptions", "err", err)
+	}
+
+	flagOpsPerSec := 0.0
+	var flagAvg time.Duration
+	if len(flagged) > 0 {
+		flagOpsPerSec = float64(len(flagged)) / flagDuration.Seconds()
+		flagAvg = flagDuration / time.Duration(len(flagged))
+	}
+
+	results := []benchmark.Result{
+		{
+			TestName:         "Suspense Detection Job - Detect (full ledger)",
+			Database:         "PostgreSQL",
+			NumOperations:    1,
+			TotalDuration:    detectDuration,
+			AverageDuration:  detectDuration,
+			OperationsPerSec: 1.0 / detectDuration.Seconds(),
+			RowsScanned:      examined,
+			RowsReturned:     len(flagged),
+			SuccessCount:     1 - min(errorCount, 1),
+			ErrorCount:       errorCount,
+			Timestamp:        time.Now(),
+		},
+		{
+			TestName:         "Suspense Detection Job - Flag (write exceptions)",
+			Database:         "PostgreSQL",
+			NumOperations:    len(flagged),
+			TotalDuration:    flagDuration,
+			AverageDuration:  flagAvg,
+			OperationsPerSec: flagOpsPerSec,
+			RowsReturned:     len(flagged),
+			SuccessCount:     len(flagged) - flagErrors,
+			ErrorCount:       flagErrors,
+			Timestamp:        time.Now(),
+		},
+	}
+
+	found := make(map[string]bool, len(flagged))
+	for _, e := range flagged {
+		found[e.txnID.String()] = true
+	}
+	benchmark.ExpectFound(&results[0], dataset.Unbalanced, anomalies[dataset.Unbalanced], func(id string) bool { return found[id] })
+	return results
+}
+
+// insertSuspenseFixtures creates transactions with only a debit leg, which the
+// detection job must find.
+func insertSuspenseFixtures(db *sql.DB, count int) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, count)
+
+	for i := 0; i < count; i++ {
+		txnID := uuid.New()
+		accountID := accountIDs[benchmark.Pick(benchmark.Rand(), len(accountIDs))]
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO transactions (id, idempotency_key, transaction_type, status, description, benchmark_run_id)
+			VALUES ($1, $2, 'payment', 'completed', 'Suspense fixture', $3)
+		`, txnID, uuid.New().String(), benchmark.RunID)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO transaction_legs (transaction_id, account_id, leg_type, amount, currency, benchmark_run_id)
+				VALUES ($1, $2, 'debit', $3, 'USD', $4)
+			`, txnID, accountID, decimal.NewFromFloat(benchmark.Rand().Float64()*1000+1), benchmark.RunID)
+		}
+
+		if err != nil {
+			tx.Rollback()
+			continue
+		}
+		if err := tx.Commit(); err == nil {
+			ids = append(ids, txnID)
+		}
+	}
+
+	return ids
+}
+
+func deleteSuspenseFixtures(db *sql.DB, ids []uuid.UUID) {
+	// transaction_legs rows go with them via ON DELETE CASCADE
+	if _, err := db.ExecContext(ctx, "DELETE FROM transactions WHERE id = ANY($1)", pq.Array(ids)); err != nil {
+		slog.Error("Failed to delete suspense fixtures", "err", err)
+	}
+}
+
+// benchmarkTrialBalance produces a full trial balance: total debits and credits
+// per account across the entire ledger in a single aggregate, then checks that
+// the ledger as a whole balances.
+func benchmarkTrialBalance(db *sql.DB) benchmark.Result {
+	testName := "Trial Balance (full ledger, per account)"
+	slog.Info("Benchmarking", "test", testName)
+	benchmark.StartTest(testName)
+
+	start := time.Now()
+	errorCount := 0
+	accounts := 0
+	var legsScanned int64
+	totalDebits := decimal.Zero
+	totalCredits := decimal.Zero
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT
+			account_id,
+			COALESCE(SUM(amount) FILTER (WHERE leg_type = 'debit'), 0) as total_debits,
+			COALESCE(SUM(amount) FILTER (WHERE leg_type = 'credit'), 0) as total_credits,
+			COUNT(*) as leg_count
+		FROM transaction_legs
+		GROUP BY account_id
+	`)
+	if err != nil {
+		errorCount++
+		slog.Warn("Trial balance error", "err", err)
+	} else {
+		for rows.Next() {
+			var accountID uuid.UUID
+			var debits, credits decimal.Decimal
+			var legCount int64
+			if err := rows.Scan(&accountID, &debits, &credits, &legCount); err != nil {
+				errorCount++
+				break
+			}
+			totalDebits = totalDebits.Add(debits)
+			totalCredits = totalCredits.Add(credits)
+			legsScanned += legCount
+			accounts++
+		}
+		rows.Close()
+	}
+
+	totalDuration := time.Since(start)
+	withinWindow := totalDuration <= closeOfDayWindow
+
+	slog.Info("Trial balance", "accounts", accounts, "legs", legsScanned,
+		"debits", totalDebits.StringFixed(4), "credits", totalCredits.StringFixed(4),
+		"duration", totalDuration)
+	if !totalDebits.Equal(totalCredits) {
+		slog.Warn("Ledger out of balance", "difference", totalDebits.Sub(totalCredits).StringFixed(4))
+	}
+	if !withinWindow {
+		slog.Warn("Trial balance exceeded the close-of-day window", "window", closeOfDayWindow)
+	}
+
+	return benchmark.Result{
+		TestName:          testName,
+		Database:          "PostgreSQL",
+		NumOperations:     1,
+		TotalDuration:     totalDuration,
+		AverageDuration:   totalDuration,
+		OperationsPerSec:  1.0 / totalDuration.Seconds(),
+		RowsScanned:       legsScanned,
+		RowsReturned:      accounts,
+		WithinCloseWindow: &withinWindow,
+		SuccessCount:      1 - min(errorCount, 1),
+		ErrorCount:        errorCount,
+		Timestamp:         time.Now(),
+	}
+}
+
+func benchmarkJoinQuery(db *sql.DB, count, limit int) benchmark.Result {
 	testName := "Multi-table JOIN Query"
-	log.Printf("Benchmarking %s (%d operations)...", testName, count)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
 
 	successCount := 0
 	errorCount := 0
 	var totalRows int64
+	query := `
+		SELECT
+			t.id,
+			t.transaction_type,
+			t.status,
+			m.name as merchant_name,
+			m.category as merchant_category,
+			a.account_type,
+			tl.leg_type,
+			tl.amount,
+			t.created_at
+		FROM transactions t
+		JOIN merchants m ON t.merchant_id = m.id
+		JOIN transaction_legs tl ON t.id = tl.transaction_id
+		JOIN accounts a ON tl.account_id = a.id
+		WHERE t.created_at >= NOW() - INTERVAL '7 days'
+		ORDER BY t.created_at DESC
+		LIMIT $1
+	`
 	start := time.Now()
 
 	for i := 0; i < count; i++ {
-		rows, err := db.Query(`
-			SELECT
-				t.id,
-				t.transaction_type,
-				t.status,
-				m.name as merchant_name,
-				m.category as merchant_category,
-				a.account_type,
-				tl.leg_type,
-				tl.amount,
-				t.created_at
-			FROM transactions t
-			JOIN merchants m ON t.merchant_id = m.id
-			JOIN transaction_legs tl ON t.id = tl.transaction_id
-			JOIN accounts a ON tl.account_id = a.id
-			WHERE t.created_at >= NOW() - INTERVAL '7 days'
-			ORDER BY t.created_at DESC
-			LIMIT 100
-		`)
+		rows, err := db.QueryContext(ctx, query, limit)
 
 		if err == nil {
 			for rows.Next() {
@@ -428,14 +657,14 @@ func benchmarkJoinQuery(db *sql.DB, count int) BenchmarkResult {
 	avgDuration := totalDuration / time.Duration(count)
 	opsPerSec := float64(count) / totalDuration.Seconds()
 
-	return BenchmarkResult{
+	return benchmark.Result{
 		TestName:         testName,
 		Database:         "PostgreSQL",
 		NumOperations:    count,
 		TotalDuration:    totalDuration,
 		AverageDuration:  avgDuration,
 		OperationsPerSec: opsPerSec,
-		RowsScanned:      totalRows,
+		RowsScanned:      estimateRowsScanned(db, successCount, query, func() []any { return []any{limit} }),
 		RowsReturned:     int(totalRows),
 		SuccessCount:     successCount,
 		ErrorCount:       errorCount,
@@ -443,30 +672,68 @@ func benchmarkJoinQuery(db *sql.DB, count int) BenchmarkResult {
 	}
 }
 
-func saveResults(suite BenchmarkSuite, filename string) {
-	data, err := json.MarshalIndent(suite, "", "  ")
-	if err != nil {
-		log.Printf("Failed to marshal results: %v", err)
-		return
-	}
+func benchmarkCurrencyConversionReport(db *sql.DB, count, hoursBack int) benchmark.Result {
+	testName := fmt.Sprintf("Currency Conversion Report (last %d hours, JOIN rates)", hoursBack)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
 
-	if err := os.WriteFile(filename, data, 0644); err != nil {
-		log.Printf("Failed to write results: %v", err)
-		return
+	successCount := 0
+	errorCount := 0
+	var totalRows int64
+	query := `
+		SELECT
+			tl.currency,
+			COUNT(*) as leg_count,
+			SUM(tl.amount) as native_volume,
+			SUM(tl.amount * r.rate) as reporting_volume
+		FROM transaction_legs tl
+		JOIN transactions t ON t.id = tl.transaction_id
+		JOIN exchange_rates r ON r.from_currency = tl.currency
+			AND r.to_currency = 'USD'
+			AND r.effective_date = (
+				SELECT MAX(effective_date) FROM exchange_rates
+				WHERE from_currency = tl.currency AND to_currency = 'USD'
+			)
+		WHERE tl.leg_type = 'debit'
+			AND t.created_at >= NOW() - make_interval(hours => $1)
+		GROUP BY tl.currency
+		ORDER BY reporting_volume DESC
+	`
+	start := time.Now()
+
+	for i := 0; i < count; i++ {
+		rows, err := db.QueryContext(ctx, query, hoursBack)
+
+		if err == nil {
+			for rows.Next() {
+				var currency string
+				var count int
+				var nativeVolume, reportingVolume float64
+				rows.Scan(&currency, &count, &nativeVolume, &reportingVolume)
+				totalRows++
+			}
+			rows.Close()
+			successCount++
+		} else {
+			errorCount++
+		}
 	}
 
-	log.Printf("\nResults saved to %s", filename)
-}
+	totalDuration := time.Since(start)
+	avgDuration := totalDuration / time.Duration(count)
+	opsPerSec := float64(count) / totalDuration.Seconds()
 
-func printSummary(suite BenchmarkSuite) {
-	fmt.Println("\n=== Benchmark Summary ===\n")
-	for _, result := range suite.Results {
-		fmt.Printf("Test: %s\n", result.TestName)
-		fmt.Printf("  Operations: %d (Success: %d, Errors: %d)\n", result.NumOperations, result.SuccessCount, result.ErrorCount)
-		fmt.Printf("  Total Duration: %v\n", result.TotalDuration)
-		fmt.Printf("  Avg Duration: %v\n", result.AverageDuration)
-		fmt.Printf("  Ops/sec: %.2f\n", result.OperationsPerSec)
-		fmt.Printf("  Rows Scanned/Returned: %d\n", result.RowsScanned)
-		fmt.Println()
+	return benchmark.Result{
+		TestName:         testName,
+		Database:         "PostgreSQL",
+		NumOperations:    count,
+		TotalDuration:    totalDuration,
+		AverageDuration:  avgDuration,
+		OperationsPerSec: opsPerSec,
+		RowsScanned:      estimateRowsScanned(db, successCount, query, func() []any { return []any{hoursBack} }),
+		RowsReturned:     int(totalRows),
+		SuccessCount:     successCount,
+		ErrorCount:       errorCount,
+		Timestamp:        time.Now(),
 	}
 }
@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"database/sql"
+	"log/slog"
+
+	_ "github.com/lib/pq"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/shopspring/decimal"
+)
+
+// replayWorkers is how many replayed operations may be in flight at once.
+const replayWorkers = 50
+
+// runReplay plays back the trace given with --replay, keeping its
+// inter-arrival times: a read is an account balance read and a write posts
+// its amount to the account's balance. --ops replays only the trace's first
+// operations.
+func runReplay(opts benchmark.Options) {
+	ops, speed := benchmark.ReplayTrace()
+	if ops == nil {
+		benchmark.Fatal("The replay suite needs a trace, such as --replay=trace.jsonl")
+	}
+
+	db := connect()
+	defer db.Close()
+	loadTestData(db)
+
+	suite := benchmark.NewSuite("postgres-replay")
+
+	slog.Info("Running Trace Replay Benchmarks")
+
+	ops = ops[:min(len(ops), opts.Ops(len(ops)))]
+	for _, workers := range opts.ConcurrencyLevels(replayWorkers) {
+		suite.Run(func() benchmark.Result {
+			return benchmark.Replay("PostgreSQL", ops, len(accountIDs), workers, speed, replayOps(db))
+		})
+	}
+
+	benchmark.Save(suite, "postgres-replay")
+	benchmark.PrintSummary(suite)
+}
+
+// replayOps maps trace operations onto the seeded accounts.
+func replayOps(db *sql.DB) benchmark.ReplayOps {
+	return benchmark.ReplayOps{
+		Read: func(account int) error {
+			var balance decimal.Decimal
+			return db.QueryRowContext(ctx, "SELECT balance FROM accounts WHERE id = $1", accountIDs[account]).Scan(&balance)
+		},
+		Write: func(account int, amount decimal.Decimal) error {
+			_, err := db.ExecContext(ctx, `
+				UPDATE accounts SET balance = balance + $2, version = version + 1
+				WHERE id = $1
+			`, accountIDs[account], amount)
+			return err
+		},
+	}
+}
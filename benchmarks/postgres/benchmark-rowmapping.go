@@ -0,0 +1,172 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/shopspring/decimal"
+)
+
+// Row mapping compares scanning result columns by hand with sqlx's struct
+// scanning, which matches columns to `db` tags by reflection. Both build the
+// same slice of structs from the same query, so the difference is what the
+// convenience costs per row.
+
+// historyLeg is one row of an account's leg history.
+type historyLeg struct {
+	TransactionID uuid.UUID       `db:"transaction_id"`
+	LegType       string          `db:"leg_type"`
+	Amount        decimal.Decimal `db:"amount"`
+	CreatedAt     time.Time       `db:"created_at"`
+}
+
+// transactionLegRow is one row of a transaction header joined with a leg.
+type transactionLegRow struct {
+	ID        uuid.UUID       `db:"id"`
+	Status    string          `db:"status"`
+	CreatedAt time.Time       `db:"created_at"`
+	AccountID uuid.UUID       `db:"account_id"`
+	LegType   string          `db:"leg_type"`
+	Amount    decimal.Decimal `db:"amount"`
+	Currency  string          `db:"currency"`
+}
+
+const (
+	historyQuery = `
+		SELECT tl.transaction_id, tl.leg_type, tl.amount, tl.created_at
+		FROM transaction_legs tl
+		WHERE tl.account_id = $1
+		ORDER BY tl.created_at DESC
+		LIMIT $2
+	`
+	transactionLegsQuery = `
+		SELECT t.id, t.status, t.created_at, tl.account_id, tl.leg_type, tl.amount, tl.currency
+		FROM transactions t
+		JOIN transaction_legs tl ON tl.transaction_id = t.id
+		WHERE t.id = $1
+	`
+)
+
+// rowMapper runs a query and maps its rows into structs.
+type rowMapper struct {
+	name            string
+	history         func(accountID uuid.UUID, limit int) ([]historyLeg, error)
+	transactionLegs func(txnID uuid.UUID) ([]transactionLegRow, error)
+}
+
+func rowMappers(db *sql.DB) []rowMapper {
+	dbx := sqlx.NewDb(db, "postgres")
+	return []rowMapper{
+		{
+			name: "hand-written",
+			history: func(accountID uuid.UUID, limit int) ([]historyLeg, error) {
+				rows, err := db.QueryContext(ctx, historyQuery, accountID, limit)
+				if err != nil {
+					return nil, err
+				}
+				defer rows.Close()
+
+				var legs []historyLeg
+				for rows.Next() {
+					var leg historyLeg
+					if err := rows.Scan(&leg.TransactionID, &leg.LegType, &leg.Amount, &leg.CreatedAt); err != nil {
+						return legs, err
+					}
+					legs = append(legs, leg)
+				}
+				return legs, rows.Err()
+			},
+			transactionLegs: func(txnID uuid.UUID) ([]transactionLegRow, error) {
+				rows, err := db.QueryContext(ctx, transactionLegsQuery, txnID)
+				if err != nil {
+					return nil, err
+				}
+				defer rows.Close()
+
+				var legs []transactionLegRow
+				for rows.Next() {
+					var r transactionLegRow
+					if err := rows.Scan(&r.ID, &r.Status, &r.CreatedAt, &r.AccountID, &r.LegType, &r.Amount, &r.Currency); err != nil {
+						return legs, err
+					}
+					legs = append(legs, r)
+				}
+				return legs, rows.Err()
+			},
+		},
+		{
+			name: "sqlx Select",
+			history: func(accountID uuid.UUID, limit int) ([]historyLeg, error) {
+				var legs []historyLeg
+				err := dbx.Select(&legs, historyQuery, accountID, limit)
+				return legs, err
+			},
+			transactionLegs: func(txnID uuid.UUID) ([]transactionLegRow, error) {
+				var legs []transactionLegRow
+				err := dbx.Select(&legs, transactionLegsQuery, txnID)
+				return legs, err
+			},
+		},
+	}
+}
+
+func benchmarkHistoryMapping(m rowMapper, count, limit int) benchmark.Result {
+	testName := fmt.Sprintf("Row Mapping - account history, last %d legs (%s)", limit, m.name)
+	return benchmarkRowMapping(testName, count, func() (int, error) {
+		legs, err := m.history(accountIDs[benchmark.Pick(benchmark.Rand(), len(accountIDs))], limit)
+		return len(legs), err
+	})
+}
+
+func benchmarkTransactionLegsMapping(m rowMapper, count int) benchmark.Result {
+	testName := fmt.Sprintf("Row Mapping - transaction with legs (%s)", m.name)
+	return benchmarkRowMapping(testName, count, func() (int, error) {
+		legs, err := m.transactionLegs(transactionIDs[benchmark.Pick(benchmark.Rand(), len(transactionIDs))])
+		return len(legs), err
+	})
+}
+
+func benchmarkRowMapping(testName string, count int, read func() (int, error)) benchmark.Result {
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
+
+	durations := make([]time.Duration, 0, count)
+	successCount := 0
+	errorCount := 0
+	rowsReturned := 0
+	// Per successful read, for NormalizePerItem.
+	readDurations := make([]time.Duration, 0, count)
+	sizes := make([]int, 0, count)
+
+	benchmark.WarmUp(1, func(*rand.Rand) error { _, err := read(); return err })
+
+	start := time.Now()
+
+	for i := 0; i < count; i++ {
+		opStart := time.Now()
+		n, err := read()
+		duration := time.Since(opStart)
+		durations = append(durations, duration)
+
+		if err != nil {
+			errorCount++
+		} else {
+			successCount++
+			rowsReturned += n
+			readDurations = append(readDurations, duration)
+			sizes = append(sizes, n)
+		}
+	}
+
+	totalDuration := time.Since(start)
+	result := calculateResults(testName, count, 1, durations, successCount, errorCount, totalDuration)
+	result.NormalizePerItem(readDurations, sizes)
+	result.RowsReturned = rowsReturned
+	return result
+}
@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+)
+
+const (
+	// saturationStepDuration is how long each client count is sustained.
+	saturationStepDuration = 10 * time.Second
+	// saturationHold is how long each operation keeps its connection, as a
+	// short transaction would.
+	saturationHold = 20 * time.Millisecond
+)
+
+var (
+	// saturationLoad is each step's client count as a multiple of the free
+	// connection slots, from well within the limit to four times over it.
+	saturationLoad = []float64{0.5, 1, 1.5, 2, 4}
+)
+
+// saturationPool is how the clients reach the server: each with a
+// connection of its own, as separate application instances without a
+// pooler would, or sharing a pool capped at the free slots.
+type saturationPool struct {
+	name   string
+	capped bool
+}
+
+var saturationPools = []saturationPool{
+	{"unpooled", false},
+	{"pooled", true},
+}
+
+// runSaturation drives more clients at PostgreSQL than max_connections
+// allows. Unpooled, the clients past the limit are refused with "too many
+// clients"; pooled, they queue for a connection. Each step records its
+// failure mode against the step within the limit.
+func runSaturation(opts benchmark.Options) {
+	db := connect()
+	defer db.Close()
+	loadTestData(db)
+
+	suite := benchmark.NewSuite("postgres-saturation")
+
+	slog.Info("Running Connection Saturation Benchmarks")
+
+	limit := freeConnectionSlots(db)
+	slog.Info("Free connection slots", "slots", limit)
+
+	levels := make([]int, 0, len(saturationLoad))
+	for _, load := range saturationLoad {
+		levels = append(levels, max(1, int(float64(limit)*load)))
+	}
+
+	for _, pool := range saturationPools {
+		var baseline benchmark.Result
+		for _, clients := range opts.ConcurrencyLevels(levels...) {
+			suite.Run(func() benchmark.Result {
+				result := benchmarkSaturation(pool, limit, clients, opts.Step(saturationStepDuration))
+				if baseline.NumOperations == 0 {
+					baseline = result
+				}
+				result.FailureMode = benchmark.FailureMode(baseline, result)
+				return result
+			})
+		}
+	}
+
+	benchmark.Save(suite, "postgres-saturation")
+	benchmark.PrintSummary(suite)
+}
+
+// freeConnectionSlots is how many more connections ordinary users can
+// open: max_connections less the slots reserved for superusers and those
+// already in use.
+func freeConnectionSlots(db *sql.DB) int {
+	var slots int
+	err := db.QueryRowContext(ctx, `
+		SELECT current_setting('max_connections')::int
+			- current_setting('superuser_reserved_connections')::int
+			- (SELECT count(*) FROM pg_stat_activity WHERE backend_type = 'client backend')
+	`).Scan(&slots)
+	if err != nil {
+		benchmark.Fatal("Failed to read max_connections", "err", err)
+	}
+	return max(1, slots)
+}
+
+// benchmarkSaturation runs clients workers for duration against a pool of
+// their own, each holding a connection for saturationHold per operation.
+func benchmarkSaturation(pool saturationPool, limit, clients int, duration time.Duration) benchmark.Result {
+	testName := fmt.Sprintf("Connection Saturation - %s, %d clients (%d slots)", pool.name, clients, limit)
+	slog.Info("Benchmarking", "test", testName, "duration", duration)
+	benchmark.StartTest(testName)
+
+	clientDB, err := openDB()
+	if err != nil {
+		benchmark.Fatal("Failed to connect to database", "err", err)
+	}
+	defer clientDB.Close()
+	if pool.capped {
+		clientDB.SetMaxOpenConns(limit)
+	}
+	clientDB.SetMaxIdleConns(clients)
+
+	deadline := time.Now().Add(duration)
+	ran := benchmark.RunWorkers(clients, func(worker int, local *benchmark.Recorder, rng *rand.Rand) benchmark.WorkerTally {
+		var tally benchmark.WorkerTally
+		for time.Now().Before(deadline) && !benchmark.Stopping() {
+			accountID := accountIDs[benchmark.Pick(rng, len(accountIDs))]
+
+			opStart := time.Now()
+			var balance string
+			err := clientDB.QueryRowContext(ctx, `
+				SELECT balance FROM accounts, pg_sleep($2) WHERE id = $1
+			`, accountID, saturationHold.Seconds()).Scan(&balance)
+			local.Record(time.Since(opStart))
+			if err != nil {
+				tally.Errors++
+				// A refused client backs off before it tries again,
+				// rather than spinning on the postmaster.
+				time.Sleep(saturationHold)
+			} else {
+				tally.Successes++
+			}
+		}
+		return tally
+	})
+
+	operations := ran.Latencies.Count()
+	result := ran.Summarize(testName, "PostgreSQL", operations, clients)
+	result.Layout = pool.name
+	result.ConnectionLimit = limit
+	if operations > 0 {
+		result.ConnectionWait = clientDB.Stats().WaitDuration / time.Duration(operations)
+	}
+	return result
+}
@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+)
+
+const (
+	// skewHotAccounts is how many accounts every leg is funneled into.
+	skewHotAccounts = 3
+	// skewStepDuration is how long each concurrency level is sustained.
+	skewStepDuration = 15 * time.Second
+)
+
+var (
+	// skewConcurrency is the ramp the hot accounts are driven through, capped
+	// at the connection pool size. PostgreSQL never rejects the load; every
+	// leg's balance update queues on the account's row lock instead, so the
+	// hot-entity ceiling shows up as flat throughput and climbing latency.
+	skewConcurrency = []int{10, 25, 50, 100}
+)
+
+// hotAccount is a synthetic account and the transaction header its legs
+// hang off.
+type hotAccount struct {
+	accountID uuid.UUID
+	txnID     uuid.UUID
+}
+
+// runSkew funnels legs into a handful of accounts, the PostgreSQL side of the
+// DynamoDB partition-throttling stress test.
+func runSkew(opts benchmark.Options) {
+	db := connect()
+	defer db.Close()
+
+	suite := benchmark.NewSuite("postgres-skew")
+
+	slog.Info("Running Skewed-Account Stress Test")
+
+	hot := make([]hotAccount, 0, skewHotAccounts)
+	for i := 0; i < skewHotAccounts; i++ {
+		accountID, txnID := createSyntheticAccount(db)
+		hot = append(hot, hotAccount{accountID, txnID})
+	}
+
+	var ceiling benchmark.Result
+	for _, concurrency := range opts.ConcurrencyLevels(skewConcurrency...) {
+		suite.Run(func() benchmark.Result {
+			result := benchmarkSkewedWrites(db, hot, concurrency, opts.Step(skewStepDuration))
+			if result.OperationsPerSec > ceiling.OperationsPerSec {
+				ceiling = result
+			}
+			return result
+		})
+	}
+	slog.Info("Hot-account ceiling", "legs_per_sec", ceiling.OperationsPerSec,
+		"legs_per_sec_per_account", ceiling.OperationsPerSec/float64(len(hot)),
+		"concurrency", ceiling.Concurrency, "p99", ceiling.P99Duration)
+
+	for _, account := range hot {
+		deleteSyntheticAccount(db, account.accountID, account.txnID)
+	}
+
+	benchmark.Save(suite, "postgres-skew")
+	benchmark.PrintSummary(suite)
+}
+
+// benchmarkSkewedWrites inserts a leg and updates the balance of a random hot
+// account in one transaction, as fast as concurrency workers allow for the
+// given duration.
+func benchmarkSkewedWrites(db *sql.DB, hot []hotAccount, concurrency int, duration time.Duration) benchmark.Result {
+	testName := fmt.Sprintf("Skewed Account Writes - %d hot accounts (%d concurrent)", len(hot), concurrency)
+	slog.Info("Benchmarking", "test", testName, "duration", duration)
+	benchmark.StartTest(testName)
+
+	deadline := time.Now().Add(duration)
+	ran := benchmark.RunWorkers(concurrency, func(worker int, local *benchmark.Recorder, rng *rand.Rand) benchmark.WorkerTally {
+		var tally benchmark.WorkerTally
+		for time.Now().Before(deadline) {
+			account := hot[rng.Intn(len(hot))]
+
+			opStart := time.Now()
+			err := writeHotLeg(db, account)
+			local.Record(time.Since(opStart))
+			if err != nil {
+				tally.Errors++
+			} else {
+				tally.Successes++
+			}
+		}
+		return tally
+	})
+
+	result := ran.Summarize(testName, "PostgreSQL", ran.Latencies.Count(), concurrency)
+	result.Role = "hot"
+	return result
+}
+
+func writeHotLeg(db *sql.DB, account hotAccount) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO transaction_legs (transaction_id, account_id, leg_type, amount, currency, benchmark_run_id)
+		VALUES ($1, $2, 'credit', 1.0000, 'USD', $3)
+	`, account.txnID, account.accountID, benchmark.RunID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE accounts SET balance = balance + 1.0000, version = version + 1
+		WHERE id = $1
+	`, account.accountID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
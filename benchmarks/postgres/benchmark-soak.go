@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+)
+
+const (
+	// soakDuration is how long the soak runs, and soakInterval how often
+	// it snapshots its results.
+	soakDuration = 2 * time.Hour
+	soakInterval = 10 * time.Minute
+	soakWorkers  = 25
+)
+
+// runSoak runs the soak mix for hours, saving a snapshot of the interval's
+// latency and throughput with the ledger tables' size and dead tuples every
+// few minutes, so bloat and autovacuum falling behind show up as the drift
+// between snapshots.
+func runSoak(opts benchmark.Options) {
+	db := connect()
+	defer db.Close()
+	loadTestData(db)
+
+	suite := benchmark.NewSuite("postgres-soak")
+
+	slog.Info("Running Soak Benchmarks")
+
+	run := benchmark.RetryingRand(benchmark.SoakMix(ycsbOps(db, ycsbScanLength)))
+	for _, workers := range opts.ConcurrencyLevels(soakWorkers) {
+		suite.Run(func() benchmark.Result {
+			testName := fmt.Sprintf("Soak - Mixed Ledger (%d workers)", workers)
+			result := benchmark.Soak(testName, "PostgreSQL", workers, opts.Soak(soakDuration), opts.Snapshots(soakInterval), run,
+				func(snapshot benchmark.Result) { suite.Snapshot(withStorage(db, snapshot)) })
+			return withStorage(db, result)
+		})
+	}
+
+	benchmark.Save(suite, "postgres-soak")
+	benchmark.PrintSummary(suite)
+}
+
+// withStorage sets result's table and index sizes and dead tuples to the
+// ledger tables' current totals.
+func withStorage(db *sql.DB, result benchmark.Result) benchmark.Result {
+	err := db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(pg_relation_size(relid)), 0), COALESCE(SUM(pg_indexes_size(relid)), 0), COALESCE(SUM(n_dead_tup), 0)
+		FROM pg_stat_user_tables
+		WHERE relname IN ('accounts', 'transactions', 'transaction_legs')
+	`).Scan(&result.TableSizeBytes, &result.IndexSizeBytes, &result.DeadTuples)
+	if err != nil {
+		slog.Warn("Failed to read table storage", "err", err)
+	}
+	return result
+}
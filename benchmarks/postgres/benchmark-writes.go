@@ -1,139 +1,73 @@
-package main
+package postgres
 
 import (
 	"database/sql"
-	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/rand"
-	"os"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	_ "github.com/lib/pq"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
 	"github.com/shopspring/decimal"
 )
 
-type BenchmarkResult struct {
-	TestName          string        `json:"test_name"`
-	Database          string        `json:"database"`
-	NumOperations     int           `json:"num_operations"`
-	Concurrency       int           `json:"concurrency"`
-	TotalDuration     time.Duration `json:"total_duration_ms"`
-	AverageDuration   time.Duration `json:"avg_duration_ms"`
-	MedianDuration    time.Duration `json:"median_duration_ms"`
-	P95Duration       time.Duration `json:"p95_duration_ms"`
-	P99Duration       time.Duration `json:"p99_duration_ms"`
-	OperationsPerSec  float64       `json:"operations_per_sec"`
-	SuccessCount      int           `json:"success_count"`
-	ErrorCount        int           `json:"error_count"`
-	Timestamp         time.Time     `json:"timestamp"`
-}
-
-type BenchmarkSuite struct {
-	Results []BenchmarkResult `json:"results"`
-}
-
-var (
-	accountIDs []uuid.UUID
-	merchantIDs []uuid.UUID
-)
-
-func main() {
-	connStr := "host=localhost port=5432 user=benchmark password=benchmark123 dbname=financial_benchmark sslmode=disable"
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
-	}
+func runWrites(opts benchmark.Options) {
+	db := connect()
 	defer db.Close()
 
-	db.SetMaxOpenConns(100)
-	db.SetMaxIdleConns(10)
-
-	if err := db.Ping(); err != nil {
-		log.Fatal("Failed to ping database:", err)
-	}
-
-	log.Println("Connected to PostgreSQL")
-
 	// Load existing accounts and merchants for testing
 	loadTestData(db)
 
-	suite := BenchmarkSuite{Results: make([]BenchmarkResult, 0)}
+	suite := benchmark.NewSuite("postgres-write")
 
 	// Run benchmarks
-	log.Println("\n=== Running Write Performance Benchmarks ===\n")
+	slog.Info("Running Write Performance Benchmarks")
 
 	// 1. Single transaction inserts
-	suite.Results = append(suite.Results, benchmarkSingleInserts(db, 1000))
-
-	// 2. Batch inserts
-	suite.Results = append(suite.Results, benchmarkBatchInserts(db, 100, 100))
-	suite.Results = append(suite.Results, benchmarkBatchInserts(db, 10, 1000))
-	suite.Results = append(suite.Results, benchmarkBatchInserts(db, 1, 10000))
-
-	// 3. Concurrent writes
-	suite.Results = append(suite.Results, benchmarkConcurrentWrites(db, 1000, 10))
-	suite.Results = append(suite.Results, benchmarkConcurrentWrites(db, 1000, 50))
-	suite.Results = append(suite.Results, benchmarkConcurrentWrites(db, 1000, 100))
-
-	// 4. Double-entry atomic writes
-	suite.Results = append(suite.Results, benchmarkDoubleEntryWrites(db, 1000, 1))
-	suite.Results = append(suite.Results, benchmarkDoubleEntryWrites(db, 1000, 10))
-
-	// Save results
-	saveResults(suite, "benchmarks/results/postgres-write-results.json")
-	printSummary(suite)
-}
-
-func loadTestData(db *sql.DB) {
-	log.Println("Loading test data...")
+	suite.Run(func() benchmark.Result { return benchmarkSingleInserts(db, opts.Ops(1000)) })
 
-	// Load 100 random accounts
-	rows, err := db.Query("SELECT id FROM accounts LIMIT 100")
-	if err != nil {
-		log.Fatal("Failed to load accounts:", err)
+	// 2. Batch inserts, the same number of rows split into batches of each size
+	rows := opts.Ops(10000)
+	for _, size := range opts.BatchSizes(100, 1000, 10000) {
+		suite.Run(func() benchmark.Result { return benchmarkBatchInserts(db, benchmark.Batches(rows, size), size) })
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var id uuid.UUID
-		if err := rows.Scan(&id); err != nil {
-			log.Fatal("Failed to scan account:", err)
-		}
-		accountIDs = append(accountIDs, id)
+	// 3. Concurrent writes
+	for _, concurrency := range opts.ConcurrencyLevels(10, 50, 100) {
+		suite.Run(func() benchmark.Result { return benchmarkConcurrentWrites(db, opts.Ops(1000), concurrency) })
 	}
 
-	// Load 100 random merchants
-	rows, err = db.Query("SELECT id FROM merchants LIMIT 100")
-	if err != nil {
-		log.Fatal("Failed to load merchants:", err)
+	// 4. Double-entry atomic writes
+	for _, concurrency := range opts.ConcurrencyLevels(1, 10) {
+		suite.Run(func() benchmark.Result { return benchmarkDoubleEntryWrites(db, opts.Ops(1000), concurrency) })
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var id uuid.UUID
-		if err := rows.Scan(&id); err != nil {
-			log.Fatal("Failed to scan merchant:", err)
-		}
-		merchantIDs = append(merchantIDs, id)
+	// 5. Multi-leg postings (payment, fee, tax and split legs) of each size
+	for _, legs := range []int{3, 10, 50, 99} {
+		suite.Run(func() benchmark.Result { return benchmarkMultiLegWrites(db, opts.Ops(200), legs) })
 	}
 
-	log.Printf("Loaded %d accounts and %d merchants", len(accountIDs), len(merchantIDs))
+	// Save results
+	benchmark.Save(suite, "postgres-write")
+	benchmark.PrintSummary(suite)
 }
 
-func benchmarkSingleInserts(db *sql.DB, count int) BenchmarkResult {
-	log.Printf("Benchmarking single transaction inserts (%d operations)...", count)
+func benchmarkSingleInserts(db *sql.DB, count int) benchmark.Result {
+	slog.Info("Benchmarking", "test", "Single Transaction Inserts", "operations", count)
+	benchmark.StartTest("Single Transaction Inserts")
 
 	durations := make([]time.Duration, 0, count)
 	successCount := 0
 	errorCount := 0
+	benchmark.WarmUp(1, func(rng *rand.Rand) error { return insertTransaction(db, rng) })
 	start := time.Now()
 
 	for i := 0; i < count; i++ {
 		opStart := time.Now()
-		err := insertTransaction(db)
+		err := insertTransaction(db, benchmark.Rand())
 		duration := time.Since(opStart)
 		durations = append(durations, duration)
 
@@ -149,18 +83,20 @@ func benchmarkSingleInserts(db *sql.DB, count int) BenchmarkResult {
 	return calculateResults("Single Transaction Inserts", count, 1, durations, successCount, errorCount, totalDuration)
 }
 
-func benchmarkBatchInserts(db *sql.DB, numBatches, batchSize int) BenchmarkResult {
+func benchmarkBatchInserts(db *sql.DB, numBatches, batchSize int) benchmark.Result {
 	testName := fmt.Sprintf("Batch Inserts (%d batches of %d)", numBatches, batchSize)
-	log.Printf("Benchmarking %s...", testName)
+	slog.Info("Benchmarking", "test", testName)
+	benchmark.StartTest(testName)
 
 	durations := make([]time.Duration, 0, numBatches)
 	successCount := 0
 	errorCount := 0
+	benchmark.WarmUp(1, func(rng *rand.Rand) error { return insertBatch(db, rng, batchSize) })
 	start := time.Now()
 
 	for i := 0; i < numBatches; i++ {
 		opStart := time.Now()
-		err := insertBatch(db, batchSize)
+		err := insertBatch(db, benchmark.Rand(), batchSize)
 		duration := time.Since(opStart)
 		durations = append(durations, duration)
 
@@ -176,111 +112,129 @@ func benchmarkBatchInserts(db *sql.DB, numBatches, batchSize int) BenchmarkResul
 	return calculateResults(testName, numBatches*batchSize, 1, durations, successCount*batchSize, errorCount, totalDuration)
 }
 
-func benchmarkConcurrentWrites(db *sql.DB, opsPerGoroutine, numGoroutines int) BenchmarkResult {
+func benchmarkConcurrentWrites(db *sql.DB, opsPerGoroutine, numGoroutines int) benchmark.Result {
 	testName := fmt.Sprintf("Concurrent Writes (%d goroutines, %d ops each)", numGoroutines, opsPerGoroutine)
-	log.Printf("Benchmarking %s...", testName)
+	slog.Info("Benchmarking", "test", testName)
+	benchmark.StartTest(testName)
 
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-	durations := make([]time.Duration, 0, opsPerGoroutine*numGoroutines)
-	successCount := 0
-	errorCount := 0
-
-	start := time.Now()
+	insert := benchmark.RetryingRand(func(rng *rand.Rand) error { return insertTransaction(db, rng) })
+	benchmark.WarmUp(numGoroutines, insert)
+	if rate := benchmark.TargetRate(); rate > 0 {
+		return benchmark.Paced(fmt.Sprintf("%s at %.0f ops/sec", testName, rate), "PostgreSQL", opsPerGoroutine*numGoroutines, numGoroutines, insert)
+	}
 
-	for g := 0; g < numGoroutines; g++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for i := 0; i < opsPerGoroutine; i++ {
-				opStart := time.Now()
-				err := insertTransaction(db)
-				duration := time.Since(opStart)
-
-				mu.Lock()
-				durations = append(durations, duration)
-				if err != nil {
-					errorCount++
-				} else {
-					successCount++
-				}
-				mu.Unlock()
+	ran := benchmark.RunWorkers(numGoroutines, func(worker int, local *benchmark.Recorder, rng *rand.Rand) benchmark.WorkerTally {
+		var tally benchmark.WorkerTally
+		for i := 0; i < opsPerGoroutine && !benchmark.Stopping(); i++ {
+			opStart := time.Now()
+			err := insert(rng)
+			local.Record(time.Since(opStart))
+			local.Think()
+			if err != nil {
+				tally.Errors++
+			} else {
+				tally.Successes++
 			}
-		}()
+		}
+		return tally
+	})
+
+	return ran.Summarize(testName, "PostgreSQL", opsPerGoroutine*numGoroutines, numGoroutines)
+}
+
+func benchmarkDoubleEntryWrites(db *sql.DB, count, concurrency int) benchmark.Result {
+	testName := fmt.Sprintf("Double-Entry Atomic Writes (%d ops, %d concurrent)", count, concurrency)
+	slog.Info("Benchmarking", "test", testName)
+	benchmark.StartTest(testName)
+
+	opsPerGoroutine := count / concurrency
+	insert := benchmark.RetryingRand(func(rng *rand.Rand) error { return insertDoubleEntryTransaction(db, rng) })
+	benchmark.WarmUp(concurrency, insert)
+	if rate := benchmark.TargetRate(); rate > 0 {
+		return benchmark.Paced(fmt.Sprintf("%s at %.0f ops/sec", testName, rate), "PostgreSQL", count, concurrency, insert)
 	}
 
-	wg.Wait()
-	totalDuration := time.Since(start)
+	ran := benchmark.RunWorkers(concurrency, func(worker int, local *benchmark.Recorder, rng *rand.Rand) benchmark.WorkerTally {
+		var tally benchmark.WorkerTally
+		for i := 0; i < opsPerGoroutine && !benchmark.Stopping(); i++ {
+			opStart := time.Now()
+			err := insert(rng)
+			local.Record(time.Since(opStart))
+			local.Think()
+			if err != nil {
+				tally.Errors++
+			} else {
+				tally.Successes++
+			}
+		}
+		return tally
+	})
 
-	return calculateResults(testName, opsPerGoroutine*numGoroutines, numGoroutines, durations, successCount, errorCount, totalDuration)
+	return ran.Summarize(testName, "PostgreSQL", count, concurrency)
 }
 
-func benchmarkDoubleEntryWrites(db *sql.DB, count, concurrency int) BenchmarkResult {
-	testName := fmt.Sprintf("Double-Entry Atomic Writes (%d ops, %d concurrent)", count, concurrency)
-	log.Printf("Benchmarking %s...", testName)
+// benchmarkMultiLegWrites commits transactions of legs legs each, the
+// header and every leg in one database transaction.
+func benchmarkMultiLegWrites(db *sql.DB, count, legs int) benchmark.Result {
+	testName := fmt.Sprintf("Multi-Leg Atomic Writes (%d legs)", legs)
+	slog.Info("Benchmarking", "test", testName, "operations", count)
+	benchmark.StartTest(testName)
 
-	var wg sync.WaitGroup
-	var mu sync.Mutex
 	durations := make([]time.Duration, 0, count)
 	successCount := 0
 	errorCount := 0
-
-	opsPerGoroutine := count / concurrency
+	insert := benchmark.RetryingRand(func(rng *rand.Rand) error { return insertMultiLegTransaction(db, rng, legs) })
+	benchmark.WarmUp(1, insert)
 	start := time.Now()
 
-	for g := 0; g < concurrency; g++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for i := 0; i < opsPerGoroutine; i++ {
-				opStart := time.Now()
-				err := insertDoubleEntryTransaction(db)
-				duration := time.Since(opStart)
-
-				mu.Lock()
-				durations = append(durations, duration)
-				if err != nil {
-					errorCount++
-				} else {
-					successCount++
-				}
-				mu.Unlock()
-			}
-		}()
+	for i := 0; i < count && !benchmark.Stopping(); i++ {
+		opStart := time.Now()
+		err := insert(benchmark.Rand())
+		duration := time.Since(opStart)
+		durations = append(durations, duration)
+
+		if err != nil {
+			errorCount++
+		} else {
+			successCount++
+		}
 	}
 
-	wg.Wait()
 	totalDuration := time.Since(start)
+	return calculateResults(testName, count, 1, durations, successCount, errorCount, totalDuration)
+}
 
-	return calculateResults(testName, count, concurrency, durations, successCount, errorCount, totalDuration)
+func insertTransaction(db *sql.DB, rng *rand.Rand) error {
+	return insertTransactionWithID(db, rng, uuid.New())
 }
 
-func insertTransaction(db *sql.DB) error {
-	txnID := uuid.New()
+// insertTransactionWithID inserts a payment with its two legs under txnID,
+// its merchant, accounts and amount drawn from rng.
+func insertTransactionWithID(db *sql.DB, rng *rand.Rand, txnID uuid.UUID) error {
 	idempotencyKey := uuid.New().String()
-	merchantID := merchantIDs[rand.Intn(len(merchantIDs))]
-	amount := decimal.NewFromFloat(rand.Float64() * 1000)
-	debitAccount := accountIDs[rand.Intn(len(accountIDs))]
-	creditAccount := accountIDs[rand.Intn(len(accountIDs))]
+	merchantID := merchantIDs[benchmark.Pick(rng, len(merchantIDs))]
+	amount := decimal.NewFromFloat(rng.Float64() * 1000)
+	debitAccount := accountIDs[benchmark.Pick(rng, len(accountIDs))]
+	creditAccount := accountIDs[benchmark.Pick(rng, len(accountIDs))]
 
-	tx, err := db.Begin()
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	_, err = tx.Exec(`
-		INSERT INTO transactions (id, idempotency_key, transaction_type, status, merchant_id, description)
-		VALUES ($1, $2, 'payment', 'completed', $3, 'Benchmark transaction')
-	`, txnID, idempotencyKey, merchantID)
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO transactions (id, idempotency_key, transaction_type, status, merchant_id, description, benchmark_run_id)
+		VALUES ($1, $2, 'payment', 'completed', $3, 'Benchmark transaction', $4)
+	`, txnID, idempotencyKey, merchantID, benchmark.RunID)
 	if err != nil {
 		return err
 	}
 
-	_, err = tx.Exec(`
-		INSERT INTO transaction_legs (transaction_id, account_id, leg_type, amount, currency)
-		VALUES ($1, $2, 'debit', $3, 'USD'), ($4, $5, 'credit', $6, 'USD')
-	`, txnID, debitAccount, amount, txnID, creditAccount, amount)
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO transaction_legs (transaction_id, account_id, leg_type, amount, currency, benchmark_run_id)
+		VALUES ($1, $2, 'debit', $3, 'USD', $7), ($4, $5, 'credit', $6, 'USD', $7)
+	`, txnID, debitAccount, amount, txnID, creditAccount, amount, benchmark.RunID)
 	if err != nil {
 		return err
 	}
@@ -288,25 +242,25 @@ func insertTransaction(db *sql.DB) error {
 	return tx.Commit()
 }
 
-func insertBatch(db *sql.DB, batchSize int) error {
-	tx, err := db.Begin()
+func insertBatch(db *sql.DB, rng *rand.Rand, batchSize int) error {
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.Prepare(`
-		INSERT INTO transactions (id, idempotency_key, transaction_type, status, merchant_id, description)
-		VALUES ($1, $2, 'payment', 'completed', $3, 'Batch benchmark transaction')
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO transactions (id, idempotency_key, transaction_type, status, merchant_id, description, benchmark_run_id)
+		VALUES ($1, $2, 'payment', 'completed', $3, 'Batch benchmark transaction', $4)
 	`)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	legStmt, err := tx.Prepare(`
-		INSERT INTO transaction_legs (transaction_id, account_id, leg_type, amount, currency)
-		VALUES ($1, $2, $3, $4, 'USD')
+	legStmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO transaction_legs (transaction_id, account_id, leg_type, amount, currency, benchmark_run_id)
+		VALUES ($1, $2, $3, $4, 'USD', $5)
 	`)
 	if err != nil {
 		return err
@@ -316,23 +270,23 @@ func insertBatch(db *sql.DB, batchSize int) error {
 	for i := 0; i < batchSize; i++ {
 		txnID := uuid.New()
 		idempotencyKey := uuid.New().String()
-		merchantID := merchantIDs[rand.Intn(len(merchantIDs))]
-		amount := decimal.NewFromFloat(rand.Float64() * 1000)
+		merchantID := merchantIDs[benchmark.Pick(rng, len(merchantIDs))]
+		amount := decimal.NewFromFloat(rng.Float64() * 1000)
 
-		_, err = stmt.Exec(txnID, idempotencyKey, merchantID)
+		_, err = stmt.ExecContext(ctx, txnID, idempotencyKey, merchantID, benchmark.RunID)
 		if err != nil {
 			return err
 		}
 
-		debitAccount := accountIDs[rand.Intn(len(accountIDs))]
-		creditAccount := accountIDs[rand.Intn(len(accountIDs))]
+		debitAccount := accountIDs[benchmark.Pick(rng, len(accountIDs))]
+		creditAccount := accountIDs[benchmark.Pick(rng, len(accountIDs))]
 
-		_, err = legStmt.Exec(txnID, debitAccount, "debit", amount)
+		_, err = legStmt.ExecContext(ctx, txnID, debitAccount, "debit", amount, benchmark.RunID)
 		if err != nil {
 			return err
 		}
 
-		_, err = legStmt.Exec(txnID, creditAccount, "credit", amount)
+		_, err = legStmt.ExecContext(ctx, txnID, creditAccount, "credit", amount, benchmark.RunID)
 		if err != nil {
 			return err
 		}
@@ -341,79 +295,48 @@ func insertBatch(db *sql.DB, batchSize int) error {
 	return tx.Commit()
 }
 
-func insertDoubleEntryTransaction(db *sql.DB) error {
-	return insertTransaction(db) // Same as single insert with ACID guarantees
-}
+// insertMultiLegTransaction inserts a payment of legs legs: one debit and
+// legs-1 equal credits that balance it, the legs in one multi-row INSERT.
+func insertMultiLegTransaction(db *sql.DB, rng *rand.Rand, legs int) error {
+	txnID := uuid.New()
+	share := decimal.NewFromFloat(rng.Float64()*100 + 0.01).Round(2)
+	credits := legs - 1
 
-func calculateResults(testName string, totalOps, concurrency int, durations []time.Duration, success, errors int, totalDuration time.Duration) BenchmarkResult {
-	// Sort durations for percentile calculations
-	sorted := make([]time.Duration, len(durations))
-	copy(sorted, durations)
-	for i := 0; i < len(sorted); i++ {
-		for j := i + 1; j < len(sorted); j++ {
-			if sorted[i] > sorted[j] {
-				sorted[i], sorted[j] = sorted[j], sorted[i]
-			}
-		}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
-	var avgDuration time.Duration
-	if len(durations) > 0 {
-		var sum time.Duration
-		for _, d := range durations {
-			sum += d
-		}
-		avgDuration = sum / time.Duration(len(durations))
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO transactions (id, idempotency_key, transaction_type, status, merchant_id, description, benchmark_run_id)
+		VALUES ($1, $2, 'payment', 'completed', $3, 'Multi-leg benchmark transaction', $4)
+	`, txnID, uuid.New().String(), merchantIDs[benchmark.Pick(rng, len(merchantIDs))], benchmark.RunID)
+	if err != nil {
+		return err
 	}
 
-	median := sorted[len(sorted)/2]
-	p95 := sorted[int(float64(len(sorted))*0.95)]
-	p99 := sorted[int(float64(len(sorted))*0.99)]
-
-	opsPerSec := float64(totalOps) / totalDuration.Seconds()
-
-	return BenchmarkResult{
-		TestName:         testName,
-		Database:         "PostgreSQL",
-		NumOperations:    totalOps,
-		Concurrency:      concurrency,
-		TotalDuration:    totalDuration,
-		AverageDuration:  avgDuration,
-		MedianDuration:   median,
-		P95Duration:      p95,
-		P99Duration:      p99,
-		OperationsPerSec: opsPerSec,
-		SuccessCount:     success,
-		ErrorCount:       errors,
-		Timestamp:        time.Now(),
+	values := make([]string, 0, legs)
+	args := make([]any, 0, legs*5)
+	add := func(account uuid.UUID, legType string, amount decimal.Decimal) {
+		n := len(args)
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, 'USD', $%d)", n+1, n+2, n+3, n+4, n+5))
+		args = append(args, txnID, account, legType, amount, benchmark.RunID)
 	}
-}
-
-func saveResults(suite BenchmarkSuite, filename string) {
-	data, err := json.MarshalIndent(suite, "", "  ")
-	if err != nil {
-		log.Printf("Failed to marshal results: %v", err)
-		return
+	add(accountIDs[benchmark.Pick(rng, len(accountIDs))], "debit", share.Mul(decimal.NewFromInt(int64(credits))))
+	for i := 0; i < credits; i++ {
+		add(accountIDs[benchmark.Pick(rng, len(accountIDs))], "credit", share)
 	}
-
-	if err := os.WriteFile(filename, data, 0644); err != nil {
-		log.Printf("Failed to write results: %v", err)
-		return
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO transaction_legs (transaction_id, account_id, leg_type, amount, currency, benchmark_run_id)
+		VALUES `+strings.Join(values, ", "), args...)
+	if err != nil {
+		return err
 	}
 
-	log.Printf("\nResults saved to %s", filename)
+	return tx.Commit()
 }
 
-func printSummary(suite BenchmarkSuite) {
-	fmt.Println("\n=== Benchmark Summary ===\n")
-	for _, result := range suite.Results {
-		fmt.Printf("Test: %s\n", result.TestName)
-		fmt.Printf("  Operations: %d (Success: %d, Errors: %d)\n", result.NumOperations, result.SuccessCount, result.ErrorCount)
-		fmt.Printf("  Total Duration: %v\n", result.TotalDuration)
-		fmt.Printf("  Ops/sec: %.2f\n", result.OperationsPerSec)
-		fmt.Printf("  Avg Latency: %v\n", result.AverageDuration)
-		fmt.Printf("  P95 Latency: %v\n", result.P95Duration)
-		fmt.Printf("  P99 Latency: %v\n", result.P99Duration)
-		fmt.Println()
-	}
+func insertDoubleEntryTransaction(db *sql.DB, rng *rand.Rand) error {
+	return insertTransaction(db, rng) // Same as single insert with ACID guarantees
 }
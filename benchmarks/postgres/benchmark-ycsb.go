@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"math/rand"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ycsbOperations = 10000
+	ycsbWorkers    = 10
+	// ycsbScanLength is the longest history page a scan reads; each
+	// reads a uniformly random length up to it, as YCSB's scans do.
+	ycsbScanLength = 100
+)
+
+// errVersionConflict is a read-modify-write that lost the race for the
+// account to another worker's write.
+var errVersionConflict = errors.New("account version changed between read and write")
+
+// runYCSB runs the YCSB core workloads selected with --workload, A to F by
+// default, against the ledger's accounts and transactions.
+func runYCSB(opts benchmark.Options) {
+	db := connect()
+	defer db.Close()
+	loadTestData(db)
+
+	suite := benchmark.NewSuite("postgres-ycsb")
+
+	slog.Info("Running YCSB Workload Benchmarks")
+
+	ops := ycsbOps(db, opts.RowLimit(ycsbScanLength))
+	for _, w := range benchmark.SelectedYCSBWorkloads() {
+		for _, workers := range opts.ConcurrencyLevels(ycsbWorkers) {
+			suite.Run(func() benchmark.Result {
+				return benchmark.RunYCSB(w, "PostgreSQL", opts.Ops(ycsbOperations), workers, ops)
+			})
+		}
+	}
+
+	benchmark.Save(suite, "postgres-ycsb")
+	benchmark.PrintSummary(suite)
+}
+
+// ycsbOps maps the YCSB operations onto the ledger. Inserted transactions
+// are what read-latest reads, falling back to seeded ones until the first
+// insert.
+func ycsbOps(db *sql.DB, scanLength int) benchmark.YCSBOps {
+	var recent benchmark.Recent
+	readTransaction := func(id string) error {
+		var status string
+		return db.QueryRowContext(ctx, "SELECT status FROM transactions WHERE id = $1", id).Scan(&status)
+	}
+
+	return benchmark.YCSBOps{
+		Read: func(rng *rand.Rand) error {
+			var balance decimal.Decimal
+			return db.QueryRowContext(ctx, "SELECT balance FROM accounts WHERE id = $1",
+				accountIDs[benchmark.Pick(rng, len(accountIDs))]).Scan(&balance)
+		},
+		ReadLatest: func(rng *rand.Rand) error {
+			id, ok := recent.Pick(rng)
+			if !ok {
+				id = transactionIDs[benchmark.Pick(rng, len(transactionIDs))].String()
+			}
+			return readTransaction(id)
+		},
+		Update: func(rng *rand.Rand) error {
+			_, err := db.ExecContext(ctx, `
+				UPDATE accounts SET balance = balance + 1.0000, version = version + 1
+				WHERE id = $1
+			`, accountIDs[benchmark.Pick(rng, len(accountIDs))])
+			return err
+		},
+		Insert: func(rng *rand.Rand) error {
+			id := uuid.New()
+			if err := insertTransactionWithID(db, rng, id); err != nil {
+				return err
+			}
+			recent.Add(id.String())
+			return nil
+		},
+		Scan: func(rng *rand.Rand) error {
+			rows, err := db.QueryContext(ctx, `
+				SELECT transaction_id, amount FROM transaction_legs
+				WHERE account_id = $1
+				ORDER BY created_at DESC
+				LIMIT $2
+			`, accountIDs[benchmark.Pick(rng, len(accountIDs))], 1+rng.Intn(scanLength))
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+			for rows.Next() {
+				var txnID uuid.UUID
+				var amount decimal.Decimal
+				if err := rows.Scan(&txnID, &amount); err != nil {
+					return err
+				}
+			}
+			return rows.Err()
+		},
+		// The write is conditional on the version read, the schema's
+		// optimistic lock, so a conflicting write fails rather than
+		// overwriting it.
+		ReadModifyWrite: func(rng *rand.Rand) error {
+			accountID := accountIDs[benchmark.Pick(rng, len(accountIDs))]
+			var balance decimal.Decimal
+			var version int
+			if err := db.QueryRowContext(ctx, "SELECT balance, version FROM accounts WHERE id = $1", accountID).Scan(&balance, &version); err != nil {
+				return err
+			}
+			res, err := db.ExecContext(ctx, `
+				UPDATE accounts SET balance = $2, version = version + 1
+				WHERE id = $1 AND version = $3
+			`, accountID, balance.Add(decimal.NewFromInt(1)), version)
+			if err != nil {
+				return err
+			}
+			if n, err := res.RowsAffected(); err == nil && n == 0 {
+				return errVersionConflict
+			}
+			return nil
+		},
+	}
+}
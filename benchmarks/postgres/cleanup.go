@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+)
+
+// ensureRunColumns adds the benchmark_run_id columns to databases created
+// from a schema.sql that predates them.
+func ensureRunColumns(db *sql.DB) {
+	statements := []string{
+		"ALTER TABLE accounts ADD COLUMN IF NOT EXISTS benchmark_run_id UUID",
+		"ALTER TABLE transactions ADD COLUMN IF NOT EXISTS benchmark_run_id UUID",
+		"ALTER TABLE transaction_legs ADD COLUMN IF NOT EXISTS benchmark_run_id UUID",
+		"CREATE INDEX IF NOT EXISTS idx_accounts_benchmark_run_id ON accounts(benchmark_run_id) WHERE benchmark_run_id IS NOT NULL",
+		"CREATE INDEX IF NOT EXISTS idx_transactions_benchmark_run_id ON transactions(benchmark_run_id) WHERE benchmark_run_id IS NOT NULL",
+		"CREATE INDEX IF NOT EXISTS idx_transaction_legs_benchmark_run_id ON transaction_legs(benchmark_run_id) WHERE benchmark_run_id IS NOT NULL",
+	}
+	for _, stmt := range statements {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			benchmark.Fatal("Failed to add benchmark_run_id columns", "err", err)
+		}
+	}
+}
+
+// Cleanup deletes the rows benchmark runs added to the seeded dataset: those
+// tagged with runID, or with any run ID when runID is empty. Legs are
+// deleted first, since a run may hang them off a transaction it did not
+// write; the rest go with their transactions via ON DELETE CASCADE. Seed
+// data is left alone, and running it again is harmless.
+func Cleanup(runID string) {
+	db := connect()
+	defer db.Close()
+
+	filter, args := "benchmark_run_id IS NOT NULL", []any{}
+	if runID != "" {
+		filter, args = "benchmark_run_id = $1", []any{runID}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		benchmark.Fatal("Failed to clean up benchmark rows", "err", err)
+	}
+	defer tx.Rollback()
+
+	var deleted []int64
+	for _, table := range []string{"transaction_legs", "transactions", "accounts"} {
+		res, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s", table, filter), args...)
+		if err != nil {
+			benchmark.Fatal("Failed to clean up benchmark rows", "table", table, "err", err)
+		}
+		n, _ := res.RowsAffected()
+		deleted = append(deleted, n)
+	}
+	if err := tx.Commit(); err != nil {
+		benchmark.Fatal("Failed to clean up benchmark rows", "err", err)
+	}
+
+	slog.Info("Removed benchmark rows", "legs", deleted[0], "transactions", deleted[1], "accounts", deleted[2])
+}
+
+// Drift reports how far the dataset has grown past the seed: row counts for
+// seed data, then for each benchmark run that left rows behind.
+func Drift() {
+	db := connect()
+	defer db.Close()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT run_id, SUM(accounts), SUM(transactions), SUM(legs), MIN(first_write)
+		FROM (
+			SELECT benchmark_run_id AS run_id, COUNT(*) AS accounts, 0 AS transactions, 0 AS legs, MIN(created_at) AS first_write
+			FROM accounts GROUP BY benchmark_run_id
+			UNION ALL
+			SELECT benchmark_run_id, 0, COUNT(*), 0, MIN(created_at)
+			FROM transactions GROUP BY benchmark_run_id
+			UNION ALL
+			SELECT benchmark_run_id, 0, 0, COUNT(*), MIN(created_at)
+			FROM transaction_legs GROUP BY benchmark_run_id
+		) counts
+		GROUP BY run_id
+		ORDER BY run_id IS NOT NULL, MIN(first_write)
+	`)
+	if err != nil {
+		benchmark.Fatal("Failed to measure dataset drift", "err", err)
+	}
+	defer rows.Close()
+
+	fmt.Print("\n=== PostgreSQL Dataset Drift ===\n\n")
+	var seeded, added int64
+	for rows.Next() {
+		var runID sql.NullString
+		var accounts, transactions, legs int64
+		var firstWrite sql.NullTime
+		if err := rows.Scan(&runID, &accounts, &transactions, &legs, &firstWrite); err != nil {
+			benchmark.Fatal("Failed to measure dataset drift", "err", err)
+		}
+
+		label := "Seed data"
+		if runID.Valid {
+			label = "Run " + runID.String
+			if firstWrite.Valid {
+				label += firstWrite.Time.Format(" (2006-01-02 15:04)")
+			}
+			added += transactions
+		} else {
+			seeded = transactions
+		}
+		fmt.Printf("%-62s %8d accounts, %8d transactions, %9d legs\n", label+":", accounts, transactions, legs)
+	}
+	if err := rows.Err(); err != nil {
+		benchmark.Fatal("Failed to measure dataset drift", "err", err)
+	}
+
+	if seeded > 0 {
+		fmt.Printf("\nBenchmark runs have added %d transactions (%.1f%% of the seeded %d).\n", added, float64(added)/float64(seeded)*100, seeded)
+	}
+	if added > 0 {
+		fmt.Println("Remove them with `benchctl cleanup --db=postgres` (all runs) or `--run=<id>` (one run).")
+	}
+}
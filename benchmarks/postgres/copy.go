@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"bufio"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/lib/pq"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+)
+
+// copyTx is a transaction COPY FROM can run in through either driver. lib/pq
+// copies through a statement prepared on the transaction; pgx copies on its
+// own connection, which database/sql only hands out from a sql.Conn, so the
+// transaction keeps one to itself.
+type copyTx struct {
+	*sql.Tx
+	conn *sql.Conn
+}
+
+// beginCopy starts a copyTx on a connection from db.
+func beginCopy(db *sql.DB) (copyTx, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return copyTx{}, err
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		conn.Close()
+		return copyTx{}, err
+	}
+	return copyTx{Tx: tx, conn: conn}, nil
+}
+
+// Commit commits the transaction and returns its connection to the pool.
+func (tx copyTx) Commit() error {
+	defer tx.conn.Close()
+	return tx.Tx.Commit()
+}
+
+// Rollback rolls the transaction back and returns its connection to the
+// pool.
+func (tx copyTx) Rollback() error {
+	defer tx.conn.Close()
+	return tx.Tx.Rollback()
+}
+
+// copyRows streams the rows rows yields into table's columns with COPY FROM
+// within tx.
+func copyRows(tx copyTx, table string, columns []string, rows func(row func(...any) error) error) error {
+	if connection.PostgresDriver == "pgx" {
+		return tx.conn.Raw(func(conn any) error {
+			if c, ok := conn.(benchConn); ok {
+				conn = c.Conn
+			}
+			pgxConn, ok := conn.(interface{ Conn() *pgx.Conn })
+			if !ok {
+				return fmt.Errorf("COPY FROM through pgx on a %T connection", conn)
+			}
+			return copyText(pgxConn.Conn(), table, columns, rows)
+		})
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, columns...))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	err = rows(func(values ...any) error {
+		_, err := stmt.ExecContext(ctx, values...)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = stmt.ExecContext(ctx)
+	return err
+}
+
+// copyText streams rows to the server in COPY's text format, as lib/pq's
+// COPY does, rather than through pgx's CopyFrom, whose binary format needs
+// every value in a Go type pgx knows the column's binary encoding for.
+func copyText(conn *pgx.Conn, table string, columns []string, rows func(row func(...any) error) error) error {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = pq.QuoteIdentifier(column)
+	}
+	statement := fmt.Sprintf("COPY %s (%s) FROM STDIN", pq.QuoteIdentifier(table), strings.Join(quoted, ", "))
+
+	r, w := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := bufio.NewWriter(w)
+		err := rows(func(values ...any) error {
+			line, err := copyLine(values)
+			if err != nil {
+				return err
+			}
+			_, err = buf.WriteString(line)
+			return err
+		})
+		if err == nil {
+			err = buf.Flush()
+		}
+		w.CloseWithError(err)
+	}()
+	_, err := conn.PgConn().CopyFrom(ctx, r, statement)
+	// A COPY the server failed stops reading; unblock the rows still coming.
+	r.CloseWithError(err)
+	<-done
+	return err
+}
+
+// copyLine encodes one row in COPY's text format, converting each value as
+// database/sql would for a statement argument.
+func copyLine(values []any) (string, error) {
+	var b strings.Builder
+	for i, v := range values {
+		if i > 0 {
+			b.WriteByte('\t')
+		}
+		value, err := driver.DefaultParameterConverter.ConvertValue(v)
+		if err != nil {
+			return "", err
+		}
+		switch value := value.(type) {
+		case nil:
+			b.WriteString(`\N`)
+		case string:
+			b.WriteString(copyEscaper.Replace(value))
+		case []byte:
+			b.WriteString(`\\x` + hex.EncodeToString(value))
+		case time.Time:
+			b.WriteString(value.Format(time.RFC3339Nano))
+		case int64:
+			b.WriteString(strconv.FormatInt(value, 10))
+		case float64:
+			b.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+		case bool:
+			b.WriteString(strconv.FormatBool(value))
+		default:
+			return "", fmt.Errorf("COPY value of type %T", value)
+		}
+	}
+	b.WriteByte('\n')
+	return b.String(), nil
+}
+
+// copyEscaper escapes the characters COPY's text format gives a meaning to.
+var copyEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
@@ -0,0 +1,26 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestCopyLine(t *testing.T) {
+	id := uuid.MustParse("0b8a4c2e-6f1d-4e6a-9c3b-2d5e7f9a1b3c")
+	at := time.Date(2024, 3, 1, 12, 30, 0, 500_000_000, time.UTC)
+	line, err := copyLine([]any{id, "tab\there\\", nil, decimal.RequireFromString("12.50"), at, int64(7), []byte{0xde, 0xad}, true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "0b8a4c2e-6f1d-4e6a-9c3b-2d5e7f9a1b3c\ttab\\there\\\\\t\\N\t12.5\t2024-03-01T12:30:00.5Z\t7\t\\\\xdead\ttrue\n"
+	if line != want {
+		t.Errorf("copyLine = %q, want %q", line, want)
+	}
+
+	if _, err := copyLine([]any{struct{}{}}); err == nil {
+		t.Error("copyLine accepted a value database/sql cannot convert")
+	}
+}
@@ -0,0 +1,323 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+)
+
+// openDB opens the connection pool through benchConnector. With an
+// operation timeout set, benchConn runs each statement under it (see
+// benchmark.OpContext), as the DynamoDB client does each API call, and
+// every connection gets it as its statement_timeout too, so the server
+// enforces it on prepared statements and COPY as well. With an isolation
+// level set, it is every connection's default_transaction_isolation, so the
+// suites' transactions run at it without passing it to each Begin.
+func openDB() (*sql.DB, error) {
+	connector, err := connection.PostgresConnector()
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(benchConnector{Connector: connector, timeout: benchmark.OpTimeout(), isolation: benchmark.Isolation()}), nil
+}
+
+// connsOpened counts the connections benchConnector has opened, for the
+// pool meter.
+var connsOpened atomic.Int64
+
+// benchConnector sets statement_timeout and default_transaction_isolation,
+// when there are any, on each connection it opens, and counts the
+// connections the server refuses, such as with too many clients, by type.
+type benchConnector struct {
+	driver.Connector
+	timeout   time.Duration
+	isolation string
+}
+
+func (c benchConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	defer phase("acquire", time.Now())
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		countError(ctx, err)
+		return nil, err
+	}
+	var settings []string
+	if c.timeout > 0 {
+		settings = append(settings, fmt.Sprintf("SET statement_timeout = %d", max(1, c.timeout.Milliseconds())))
+	}
+	if c.isolation != "" {
+		settings = append(settings, fmt.Sprintf("SET default_transaction_isolation = '%s'", c.isolation))
+	}
+	for _, set := range settings {
+		if _, err := conn.(driver.ExecerContext).ExecContext(ctx, set, nil); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	connsOpened.Add(1)
+	return benchConn{conn}, nil
+}
+
+// benchConn gives each statement the operation timeout, counts the
+// statements and commits that fail, by type, and those that run out of
+// time, times them for the latency phases and traces them. The embedded driver.Conn only passes on Prepare, Close and Begin:
+// each optional interface database/sql looks for on a connection is
+// declared here and handed to the driver's connection, which for
+// driver.NamedValueChecker is how pgx converts arguments itself rather
+// than through database/sql's default converter.
+type benchConn struct {
+	driver.Conn
+}
+
+// QueryContext holds the statement's timeout until its rows are closed,
+// since the driver reads them under the same context.
+func (c benchConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	opCtx, cancel := opContext(ctx)
+	start := time.Now()
+	rows, err := c.Conn.(driver.QueryerContext).QueryContext(opCtx, query, args)
+	phase("execute", start)
+	countError(opCtx, err)
+	trace(statementVerb(query), args, start, err)
+	if err != nil {
+		if cancel != nil {
+			cancel()
+		}
+		return nil, err
+	}
+	if cancel != nil || benchmark.PhasesEnabled() {
+		rows = benchRows{Rows: rows, cancel: cancel}
+	}
+	return rows, nil
+}
+
+func (c benchConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	opCtx, cancel := opContext(ctx)
+	if cancel != nil {
+		defer cancel()
+	}
+	start := time.Now()
+	defer phase("execute", start)
+	result, err := c.Conn.(driver.ExecerContext).ExecContext(opCtx, query, args)
+	countError(opCtx, err)
+	trace(statementVerb(query), args, start, err)
+	return result, err
+}
+
+// opContext derives a statement's context from ctx with the operation
+// timeout, and a nil cancel when there is none to apply.
+func opContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if benchmark.OpTimeout() == 0 {
+		return ctx, nil
+	}
+	return benchmark.OpContext(ctx)
+}
+
+func (c benchConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+}
+
+func (c benchConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	start := time.Now()
+	defer phase("execute", start)
+	tx, err := c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+	countError(ctx, err)
+	trace("BEGIN", nil, start, err)
+	if err != nil {
+		return nil, err
+	}
+	return benchTx{tx}, nil
+}
+
+func (c benchConn) Ping(ctx context.Context) error {
+	return c.Conn.(driver.Pinger).Ping(ctx)
+}
+
+// ResetSession and IsValid pass through when the driver has them; pq does,
+// the sqlmock driver the unit tests run on doesn't.
+func (c benchConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c benchConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// CheckNamedValue lets the driver convert statement arguments, as pgx's
+// does; without one, driver.ErrSkip leaves them to database/sql.
+func (c benchConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// benchTx counts failed commits, where serialization failures under
+// SERIALIZABLE usually surface, and traces commits and rollbacks.
+type benchTx struct {
+	driver.Tx
+}
+
+func (t benchTx) Commit() error {
+	start := time.Now()
+	defer phase("execute", start)
+	err := t.Tx.Commit()
+	countError(context.Background(), err)
+	trace("COMMIT", nil, start, err)
+	return err
+}
+
+func (t benchTx) Rollback() error {
+	start := time.Now()
+	defer phase("execute", start)
+	err := t.Tx.Rollback()
+	trace("ROLLBACK", nil, start, err)
+	return err
+}
+
+// benchRows times reading result rows off the connection, the scan phase,
+// including the rows Close drains. pq returns from the query once the
+// server's first reply has arrived, so execute covers the round trip to it
+// and scan the rest of the transfer. Close releases the query's operation
+// timeout, when it has one.
+type benchRows struct {
+	driver.Rows
+	cancel context.CancelFunc
+}
+
+func (r benchRows) Next(dest []driver.Value) error {
+	defer phase("scan", time.Now())
+	return r.Rows.Next(dest)
+}
+
+func (r benchRows) Close() error {
+	defer phase("scan", time.Now())
+	err := r.Rows.Close()
+	if r.cancel != nil {
+		r.cancel()
+	}
+	return err
+}
+
+// phase records the time since start in a latency phase, when the
+// breakdown is on (see benchmark.SetPhases). Acquire is new connections
+// here plus the pool's wait time, which connect reads from the pool.
+func phase(name string, start time.Time) {
+	if benchmark.PhasesEnabled() {
+		benchmark.RecordPhase(name, time.Since(start))
+	}
+}
+
+// trace adds a statement that started at start to the operation trace, when
+// one is being written (see benchmark.SetTrace), keyed by its first
+// argument, which is the ID the statement looks up or writes in most of
+// the suites' queries.
+func trace(verb string, args []driver.NamedValue, start time.Time, err error) {
+	if !benchmark.TraceEnabled() {
+		return
+	}
+	key := ""
+	if len(args) > 0 {
+		key = fmt.Sprint(args[0].Value)
+	}
+	kind := ""
+	if err != nil {
+		kind = errorType(err)
+	}
+	benchmark.TraceOp(verb, key, start, err, kind, -1)
+}
+
+// statementVerb is the SQL command a statement starts with, such as SELECT
+// or INSERT, or WITH for a statement opening with a CTE.
+func statementVerb(query string) string {
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return ""
+	}
+	return strings.ToUpper(words[0])
+}
+
+// countError counts err by its SQLSTATE class, and as a timeout too if the
+// statement ran past ctx's deadline or PostgreSQL cancelled it for running
+// past statement_timeout, as opposed to any other query_canceled. Bad
+// connections are left to database/sql, which retries them on another
+// connection.
+func countError(ctx context.Context, err error) {
+	if err == nil || errors.Is(err, driver.ErrBadConn) {
+		return
+	}
+	code, message, ok := sqlState(err)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) || ok && code == "57014" && strings.Contains(message, "statement timeout") {
+		benchmark.CountTimeout()
+	}
+	benchmark.CountError(errorType(err))
+}
+
+// errorType names err's SQLSTATE class, e.g. "40 transaction_rollback" for
+// serialization failures and deadlocks.
+func errorType(err error) string {
+	code, _, isPostgres := sqlState(err)
+	var netErr net.Error
+	switch {
+	case isPostgres:
+		return fmt.Sprintf("%s %s", code.Class(), code.Class().Name())
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.As(err, &netErr):
+		return "connection"
+	}
+	return "other"
+}
+
+// isTransient reports whether err is worth retrying (see
+// benchmark.SetRetryPolicy): serialization failures, deadlocks, lock waits
+// given up under lock_timeout, connection failures and a full server.
+func isTransient(err error) bool {
+	code, _, isPostgres := sqlState(err)
+	var netErr net.Error
+	switch {
+	case isPostgres:
+		switch code {
+		case "40001", "40P01", "55P03", "53300":
+			return true
+		}
+		return code.Class() == "08"
+	case errors.Is(err, context.DeadlineExceeded):
+		return false
+	case errors.As(err, &netErr):
+		return true
+	}
+	return false
+}
+
+// sqlState is the SQLSTATE code and message of the server error in err,
+// from whichever driver returned it.
+func sqlState(err error) (code pq.ErrorCode, message string, ok bool) {
+	var pqErr *pq.Error
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.As(err, &pqErr):
+		return pqErr.Code, pqErr.Message, true
+	case errors.As(err, &pgErr):
+		return pq.ErrorCode(pgErr.Code), pgErr.Message, true
+	}
+	return "", "", false
+}
@@ -0,0 +1,423 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+)
+
+// mockConnector opens sqlmock connections in place of pq, so benchConnector
+// and benchConn wrap them exactly as they wrap a live server's.
+type mockConnector struct {
+	dsn string
+	drv driver.Driver
+}
+
+func (c mockConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.drv.Open(c.dsn)
+}
+
+func (c mockConnector) Driver() driver.Driver {
+	return c.drv
+}
+
+// openMock returns a pool on a sqlmock connection behind benchConnector,
+// with retries of transient errors on, and resets the stats the tests read
+// when it finishes.
+func openMock(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	dsn := "postgres-" + t.Name()
+	mockDB, mock, err := sqlmock.NewWithDSN(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(benchConnector{Connector: mockConnector{dsn: dsn, drv: mockDB.Driver()}})
+	db.SetMaxOpenConns(1)
+
+	benchmark.SetRetryable(isTransient)
+	benchmark.SetRetryPolicy(benchmark.RetryPolicy{MaxRetries: 3, BaseDelay: time.Microsecond})
+	t.Cleanup(func() {
+		db.Close()
+		mockDB.Close()
+		benchmark.SetRetryPolicy(benchmark.RetryPolicy{})
+		takeStats()
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+	takeStats()
+	return db, mock
+}
+
+// takeStats summarizes one operation and adds it to a suite, which takes
+// and resets everything tallied since the last test: error types, timeouts
+// and retries.
+func takeStats() benchmark.Result {
+	var suite benchmark.Suite
+	suite.Add(benchmark.Summarize("test", "PostgreSQL", 1, 1, []time.Duration{time.Millisecond}, 0, 1, time.Millisecond))
+	return suite.Results[0]
+}
+
+func serializationFailure() error {
+	return &pq.Error{Code: "40001", Message: "could not serialize access due to concurrent update"}
+}
+
+func TestRetriedSerializationFailure(t *testing.T) {
+	db, mock := openMock(t)
+	mock.ExpectExec("UPDATE accounts").WillReturnError(serializationFailure())
+	mock.ExpectExec("UPDATE accounts").WillReturnError(serializationFailure())
+	mock.ExpectExec("UPDATE accounts").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := benchmark.Retrying(func() error {
+		_, err := db.Exec("UPDATE accounts SET balance = balance + 1")
+		return err
+	})()
+	if err != nil {
+		t.Fatalf("retried update failed: %v", err)
+	}
+
+	result := takeStats()
+	if result.Retries != 2 {
+		t.Errorf("Retries = %d, want 2", result.Retries)
+	}
+	if got := result.ErrorsByType["40 transaction_rollback"]; got != 2 {
+		t.Errorf("ErrorsByType = %v, want 2 transaction_rollback", result.ErrorsByType)
+	}
+}
+
+func TestPermanentErrorNotRetried(t *testing.T) {
+	db, mock := openMock(t)
+	mock.ExpectExec("INSERT INTO transactions").WillReturnError(&pq.Error{Code: "23505", Message: "duplicate key value violates unique constraint"})
+
+	err := benchmark.Retrying(func() error {
+		_, err := db.Exec("INSERT INTO transactions (id) VALUES ($1)", uuid.New())
+		return err
+	})()
+	if err == nil {
+		t.Fatal("unique violation succeeded")
+	}
+
+	result := takeStats()
+	if result.Retries != 0 {
+		t.Errorf("Retries = %d, want 0", result.Retries)
+	}
+	if got := result.ErrorsByType["23 integrity_constraint_violation"]; got != 1 {
+		t.Errorf("ErrorsByType = %v, want 1 integrity_constraint_violation", result.ErrorsByType)
+	}
+}
+
+func TestRetriesExhausted(t *testing.T) {
+	db, mock := openMock(t)
+	for i := 0; i < 4; i++ {
+		mock.ExpectExec("UPDATE accounts").WillReturnError(&pq.Error{Code: "40P01", Message: "deadlock detected"})
+	}
+
+	err := benchmark.Retrying(func() error {
+		_, err := db.Exec("UPDATE accounts SET balance = balance - 1")
+		return err
+	})()
+	if err == nil {
+		t.Fatal("update succeeded after every attempt deadlocked")
+	}
+
+	result := takeStats()
+	if result.Retries != 3 {
+		t.Errorf("Retries = %d, want 3", result.Retries)
+	}
+	if got := result.ErrorsByType["40 transaction_rollback"]; got != 4 {
+		t.Errorf("ErrorsByType = %v, want 4 transaction_rollback", result.ErrorsByType)
+	}
+}
+
+func TestFailedCommitCounted(t *testing.T) {
+	db, mock := openMock(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO transaction_legs").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit().WillReturnError(serializationFailure())
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("INSERT INTO transaction_legs (id) VALUES (1), (2)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err == nil {
+		t.Fatal("commit succeeded")
+	}
+
+	result := takeStats()
+	if got := result.ErrorsByType["40 transaction_rollback"]; got != 1 {
+		t.Errorf("ErrorsByType = %v, want 1 transaction_rollback", result.ErrorsByType)
+	}
+}
+
+// refusingConnector stands in for a server with no connection slots left.
+type refusingConnector struct{}
+
+func (refusingConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, &pq.Error{Code: "53300", Message: "sorry, too many clients already"}
+}
+
+func (refusingConnector) Driver() driver.Driver {
+	return nil
+}
+
+func TestRefusedConnectionCounted(t *testing.T) {
+	db := sql.OpenDB(benchConnector{Connector: refusingConnector{}})
+	defer db.Close()
+	if _, err := db.Exec("SELECT 1"); err == nil {
+		t.Fatal("statement ran without a connection")
+	}
+
+	result := takeStats()
+	if got := result.ErrorsByType["53 insufficient_resources"]; got != 1 {
+		t.Errorf("ErrorsByType = %v, want 1 insufficient_resources", result.ErrorsByType)
+	}
+}
+
+func TestStatementsTraced(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.csv")
+	if err := benchmark.SetTrace(path); err != nil {
+		t.Fatal(err)
+	}
+	defer benchmark.SetTrace("")
+	db, mock := openMock(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE accounts").WithArgs(42, 100).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit().WillReturnError(serializationFailure())
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("\n\t\tUPDATE accounts SET balance = balance - $2 WHERE id = $1", 42, 100); err != nil {
+		t.Fatal(err)
+	}
+	tx.Commit()
+	if err := benchmark.SetTrace(""); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("trace has %d rows, want a header and 3 statements:\n%s", len(rows), data)
+	}
+	// op, error_type, key and consumed_capacity.
+	for i, want := range [][4]string{
+		{"BEGIN", "", "", ""},
+		{"UPDATE", "", "42", ""},
+		{"COMMIT", "40 transaction_rollback", "", ""},
+	} {
+		row := rows[i+1]
+		if got := [4]string{row[2], row[4], row[6], row[7]}; got != want {
+			t.Errorf("row %d = %v, want %v", i+1, got, want)
+		}
+	}
+}
+
+func TestStatementTimeoutCounted(t *testing.T) {
+	db, mock := openMock(t)
+	mock.ExpectQuery("SELECT").WillReturnError(&pq.Error{Code: "57014", Message: "canceling statement due to statement timeout"})
+	mock.ExpectQuery("SELECT").WillReturnError(&pq.Error{Code: "57014", Message: "canceling statement due to user request"})
+
+	for i := 0; i < 2; i++ {
+		if _, err := db.Query("SELECT 1"); err == nil {
+			t.Fatal("cancelled query succeeded")
+		}
+	}
+
+	// Only the first was a statement_timeout; both are query_canceled.
+	var suite benchmark.Suite
+	suite.Add(benchmark.Summarize("test", "PostgreSQL", 2, 1, []time.Duration{time.Millisecond, time.Millisecond}, 0, 2, time.Millisecond))
+	result := suite.Results[0]
+	if result.TimeoutCount != 1 || result.ErrorCount != 1 {
+		t.Errorf("TimeoutCount, ErrorCount = %d, %d, want 1, 1", result.TimeoutCount, result.ErrorCount)
+	}
+	if got := result.ErrorsByType["57 operator_intervention"]; got != 2 {
+		t.Errorf("ErrorsByType = %v, want 2 operator_intervention", result.ErrorsByType)
+	}
+}
+
+func TestOpTimeoutCounted(t *testing.T) {
+	benchmark.SetOpTimeout(5 * time.Millisecond)
+	defer benchmark.SetOpTimeout(0)
+	db, mock := openMock(t)
+	mock.ExpectQuery("SELECT").WillDelayFor(time.Second).WillReturnRows(sqlmock.NewRows([]string{"n"}).AddRow(1))
+	mock.ExpectExec("UPDATE").WillReturnError(serializationFailure())
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"n"}).AddRow(1).AddRow(2))
+
+	if _, err := db.QueryContext(ctx, "SELECT 1"); err == nil {
+		t.Fatal("query past its operation timeout succeeded")
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE accounts SET version = version + 1"); err == nil {
+		t.Fatal("failed update succeeded")
+	}
+
+	// A query inside its timeout reads all of its rows.
+	rows, err := db.QueryContext(ctx, "SELECT n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := 0
+	for rows.Next() {
+		read++
+	}
+	if err := rows.Close(); err != nil || read != 2 {
+		t.Errorf("read %d rows (err %v), want 2", read, err)
+	}
+
+	var suite benchmark.Suite
+	suite.Add(benchmark.Summarize("test", "PostgreSQL", 3, 1, []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond}, 1, 2, time.Millisecond))
+	result := suite.Results[0]
+	if result.TimeoutCount != 1 || result.ErrorCount != 1 {
+		t.Errorf("TimeoutCount, ErrorCount = %d, %d, want 1, 1", result.TimeoutCount, result.ErrorCount)
+	}
+}
+
+func TestIsolationSetOnConnect(t *testing.T) {
+	dsn := "postgres-" + t.Name()
+	mockDB, mock, err := sqlmock.NewWithDSN(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mockDB.Close()
+	db := sql.OpenDB(benchConnector{Connector: mockConnector{dsn: dsn, drv: mockDB.Driver()}, isolation: "serializable"})
+	defer db.Close()
+
+	mock.ExpectExec("SET default_transaction_isolation = 'serializable'").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"n"}).AddRow(1))
+	var n int
+	if err := db.QueryRow("SELECT 1").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+// point is an argument only the driver can convert, as pgx converts Go
+// types database/sql's default converter rejects.
+type point struct{ x, y int }
+
+type pointConverter struct{}
+
+func (pointConverter) ConvertValue(v any) (driver.Value, error) {
+	if p, ok := v.(point); ok {
+		return fmt.Sprintf("(%d,%d)", p.x, p.y), nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(v)
+}
+
+func TestArgumentsConvertedByDriver(t *testing.T) {
+	dsn := "postgres-" + t.Name()
+	mockDB, mock, err := sqlmock.NewWithDSN(dsn, sqlmock.ValueConverterOption(pointConverter{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mockDB.Close()
+	db := sql.OpenDB(benchConnector{Connector: mockConnector{dsn: dsn, drv: mockDB.Driver()}})
+	defer db.Close()
+
+	mock.ExpectExec("INSERT").WithArgs("(1,2)").WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := db.Exec("INSERT INTO points VALUES ($1)", point{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRowMappingResults(t *testing.T) {
+	db, mock := openMock(t)
+	accountIDs = []uuid.UUID{uuid.New()}
+	t.Cleanup(func() { accountIDs = nil })
+
+	columns := []string{"transaction_id", "leg_type", "amount", "created_at"}
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery("FROM transaction_legs").WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(uuid.New().String(), "DEBIT", "10.00", now).
+			AddRow(uuid.New().String(), "CREDIT", "2.50", now))
+	}
+	mock.ExpectQuery("FROM transaction_legs").WillReturnError(&pq.Error{Code: "53300", Message: "too many connections"})
+
+	result := benchmarkHistoryMapping(rowMappers(db)[0], 3, 2)
+	if result.SuccessCount != 2 || result.ErrorCount != 1 {
+		t.Errorf("SuccessCount, ErrorCount = %d, %d, want 2, 1", result.SuccessCount, result.ErrorCount)
+	}
+	if result.RowsReturned != 4 {
+		t.Errorf("RowsReturned = %d, want 4", result.RowsReturned)
+	}
+	if result.P99Duration < result.MedianDuration {
+		t.Errorf("P99 %v below median %v", result.P99Duration, result.MedianDuration)
+	}
+}
+
+func TestErrorType(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{serializationFailure(), "40 transaction_rollback"},
+		{&pq.Error{Code: "08006"}, "08 connection_exception"},
+		{&pq.Error{Code: "23503"}, "23 integrity_constraint_violation"},
+		{fmt.Errorf("insert: %w", &pgconn.PgError{Code: "23505"}), "23 integrity_constraint_violation"},
+		{context.DeadlineExceeded, "timeout"},
+		{context.Canceled, "canceled"},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, "connection"},
+		{errors.New("boom"), "other"},
+	}
+	for _, tt := range tests {
+		if got := errorType(tt.err); got != tt.want {
+			t.Errorf("errorType(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{serializationFailure(), true},
+		{&pq.Error{Code: "40P01"}, true},
+		{&pq.Error{Code: "55P03"}, true},
+		{&pq.Error{Code: "53300"}, true},
+		{&pq.Error{Code: "08006"}, true},
+		{&pq.Error{Code: "23505"}, false},
+		{&pq.Error{Code: "57014"}, false},
+		{&pgconn.PgError{Code: "40001"}, true},
+		{&pgconn.PgError{Code: "23505"}, false},
+		{context.DeadlineExceeded, false},
+		{&net.OpError{Op: "read", Err: errors.New("connection reset by peer")}, true},
+		{errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		if got := isTransient(tt.err); got != tt.want {
+			t.Errorf("isTransient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
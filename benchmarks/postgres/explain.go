@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+)
+
+// explainSamples is how many of a test's operations are re-run under
+// EXPLAIN ANALYZE, after the timed loop, to count the rows they examine.
+const explainSamples = 5
+
+// planNode is the part of an EXPLAIN (FORMAT JSON) plan node that row
+// counting needs. Row counts are per loop, as EXPLAIN prints them.
+type planNode struct {
+	RelationName         string     `json:"Relation Name"`
+	ActualRows           float64    `json:"Actual Rows"`
+	ActualLoops          float64    `json:"Actual Loops"`
+	RowsRemovedByFilter  float64    `json:"Rows Removed by Filter"`
+	RowsRemovedByRecheck float64    `json:"Rows Removed by Index Recheck"`
+	Plans                []planNode `json:"Plans"`
+}
+
+// examined is the number of table rows the node and its children read,
+// counting rows a filter threw away as well as those it kept. Only nodes
+// reading a relation count, so joins, sorts and aggregates, which work on
+// rows their children already read, don't count them twice, and neither do
+// bitmap index scans under the heap scans that fetch their rows.
+func (n planNode) examined() float64 {
+	var rows float64
+	if n.RelationName != "" {
+		rows = (n.ActualRows + n.RowsRemovedByFilter + n.RowsRemovedByRecheck) * n.ActualLoops
+	}
+	for _, child := range n.Plans {
+		rows += child.examined()
+	}
+	return rows
+}
+
+// rowsExamined runs query under EXPLAIN ANALYZE and returns how many table
+// rows it read, the counterpart of DynamoDB's ScannedCount. The query really
+// runs, so it must be a read.
+func rowsExamined(db *sql.DB, query string, args ...any) (int64, error) {
+	var plan []byte
+	if err := db.QueryRowContext(ctx, "EXPLAIN (ANALYZE, FORMAT JSON) "+query, args...).Scan(&plan); err != nil {
+		return 0, err
+	}
+
+	var explained []struct {
+		Plan planNode `json:"Plan"`
+	}
+	if err := json.Unmarshal(plan, &explained); err != nil {
+		return 0, err
+	}
+
+	var rows float64
+	for _, e := range explained {
+		rows += e.Plan.examined()
+	}
+	return int64(rows), nil
+}
+
+// estimateRowsScanned samples explainSamples runs of query, each with the
+// arguments args returns, and scales the mean rows examined up to ops
+// operations. Suites report it as RowsScanned next to the RowsReturned they
+// count themselves, so reads that filter most of what they touch stand out
+// the way DynamoDB scans do. It returns 0 if the query can't be explained.
+func estimateRowsScanned(db *sql.DB, ops int, query string, args func() []any) int64 {
+	if ops == 0 {
+		return 0
+	}
+
+	var total int64
+	samples := min(ops, explainSamples)
+	for i := 0; i < samples; i++ {
+		rows, err := rowsExamined(db, query, args()...)
+		if err != nil {
+			slog.Error("Failed to count rows examined", "err", err)
+			return 0
+		}
+		total += rows
+	}
+	return total * int64(ops) / int64(samples)
+}
@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"database/sql"
+	_ "embed"
+	"log/slog"
+
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+)
+
+// schemaSQL is the schema docker-compose applies to a new volume, and the
+// first migration Init applies.
+//
+//go:embed schema.sql
+var schemaSQL string
+
+// migration is one versioned change to the schema. Init applies each once,
+// in order, and records it in schema_migrations.
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// migrations are every change to the schema, oldest first. Add a change to
+// an existing database as a new migration rather than by editing
+// schema.sql alone, which only new databases run.
+var migrations = []migration{
+	{1, "schema.sql", schemaSQL},
+}
+
+// Init brings the database's schema up to date by applying the migrations
+// it has not had yet, each in its own transaction. A database whose schema
+// docker-compose applied has the tables but no schema_migrations; Init
+// records schema.sql as applied there rather than run it again, since it
+// starts by dropping the tables, and refuses to run it over only some of
+// them.
+func Init() {
+	db, err := openDB()
+	if err != nil {
+		benchmark.Fatal("Failed to connect to database", "err", err)
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		benchmark.Fatal("Failed to create schema_migrations", "err", err)
+	}
+
+	applied := appliedMigrations(db)
+	if len(applied) == 0 {
+		missing, err := missingTables(db)
+		if err != nil {
+			benchmark.Fatal("Failed to look up tables", "err", err)
+		}
+		switch len(missing) {
+		case 0:
+			recordMigration(db, migrations[0])
+			applied[migrations[0].version] = true
+			slog.Info("Adopted existing schema", "migration", migrations[0].name)
+		case len(schemaTables):
+			// A new database, which schema.sql creates from scratch.
+		default:
+			// schema.sql would drop the tables that are there, with their data.
+			benchmark.Fatal("Database has only part of the schema; add the missing tables by hand, or start over with `make clean start`", "missing", missing)
+		}
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			benchmark.Fatal("Failed to apply migration", "migration", m.name, "err", err)
+		}
+		if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+			tx.Rollback()
+			benchmark.Fatal("Failed to apply migration", "migration", m.name, "err", err)
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.version, m.name); err != nil {
+			tx.Rollback()
+			benchmark.Fatal("Failed to record migration", "migration", m.name, "err", err)
+		}
+		if err := tx.Commit(); err != nil {
+			benchmark.Fatal("Failed to apply migration", "migration", m.name, "err", err)
+		}
+		slog.Info("Applied migration", "version", m.version, "migration", m.name)
+	}
+
+	ensureRunColumns(db)
+	slog.Info("Schema up to date", "version", migrations[len(migrations)-1].version)
+}
+
+// appliedMigrations returns the versions schema_migrations records.
+func appliedMigrations(db *sql.DB) map[int]bool {
+	rows, err := db.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		benchmark.Fatal("Failed to read schema_migrations", "err", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			benchmark.Fatal("Failed to read schema_migrations", "err", err)
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		benchmark.Fatal("Failed to read schema_migrations", "err", err)
+	}
+	return applied
+}
+
+// recordMigration marks m applied without running it.
+func recordMigration(db *sql.DB, m migration) {
+	if _, err := db.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.version, m.name); err != nil {
+		benchmark.Fatal("Failed to record migration", "migration", m.name, "err", err)
+	}
+}
@@ -0,0 +1,252 @@
+// Package postgres contains the PostgreSQL seeder and benchmark suites. Each
+// suite connects with connection.PostgresDSN, runs its tests and publishes the results through
+// the configured sinks; benchctl picks one from Suites.
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+)
+
+// Suites maps benchctl suite names to their runners. Each runner scales
+// its tests by the given options.
+var Suites = map[string]func(benchmark.Options){
+	"writes":         runWrites,
+	"reads":          runReads,
+	"reconciliation": runReconciliation,
+	"isolation":      runIsolation,
+	"close":          runClose,
+	"keys":           runKeys,
+	"ingest":         runIngest,
+	"collections":    runCollections,
+	"skew":           runSkew,
+	"fillfactor":     runFillfactor,
+	"saturation":     runSaturation,
+	"exports":        runExports,
+	"ramp":           runRamp,
+	"soak":           runSoak,
+	"replay":         runReplay,
+	"ycsb":           runYCSB,
+}
+
+// ctx is the context every statement runs under, cancelled when the run is
+// interrupted; benchConn gives each statement its operation timeout.
+var ctx = benchmark.Context()
+
+// Test data loaded by loadTestData and shared by the suites.
+var (
+	accountIDs     []uuid.UUID
+	transactionIDs []uuid.UUID
+	merchantIDs    []uuid.UUID
+	userIDs        []uuid.UUID
+	// anomalies are the seeded transactions injected with each anomaly,
+	// from the seeded-ID file.
+	anomalies map[string][]string
+)
+
+func connect() *sql.DB {
+	db, err := openDB()
+	if err != nil {
+		benchmark.Fatal("Failed to connect to database", "err", err)
+	}
+
+	db.SetMaxOpenConns(connection.PostgresPool.MaxOpen)
+	db.SetMaxIdleConns(connection.PostgresPool.MaxIdle)
+	db.SetConnMaxLifetime(connection.PostgresPool.MaxLifetime)
+	if !benchmark.Pooled() {
+		// Every operation opens, and closes, a connection of its own.
+		db.SetMaxIdleConns(0)
+	}
+	benchmark.SetRetryable(isTransient)
+	if benchmark.PhasesEnabled() {
+		benchmark.SetPhaseCounter("acquire", func() time.Duration { return db.Stats().WaitDuration })
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		benchmark.Fatal("Failed to ping database", "err", err)
+	}
+	ensureRunColumns(db)
+	describeDatabase(db)
+	benchmark.SetWorkMeter(func() (benchmark.PhysicalWork, error) { return readPhysicalWork(db) })
+	benchmark.SetPoolMeter(func() benchmark.PoolStats {
+		stats := db.Stats()
+		return benchmark.PoolStats{WaitCount: stats.WaitCount, WaitDuration: stats.WaitDuration, Opened: connsOpened.Load(), MaxOpen: stats.MaxOpenConnections}
+	})
+
+	slog.Info("Connected to PostgreSQL")
+	return db
+}
+
+// readPhysicalWork reads the database's shared buffer and WAL counters for
+// the work meter. Buffer counters are database-wide and flushed by each
+// backend when it goes idle, so a test's can include a little of the
+// previous test's and other clients' work. Pages written come from pg_stat_io
+// on PostgreSQL 16 and later, and stay 0 before that.
+func readPhysicalWork(db *sql.DB) (benchmark.PhysicalWork, error) {
+	var work benchmark.PhysicalWork
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return work, err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "SELECT pg_stat_force_next_flush()"); err != nil {
+		return work, err
+	}
+	if err := conn.QueryRowContext(ctx, `
+		SELECT blks_hit, blks_read, pg_wal_lsn_diff(pg_current_wal_lsn(), '0/0')::bigint
+		FROM pg_stat_database
+		WHERE datname = current_database()
+	`).Scan(&work.BuffersHit, &work.BuffersRead, &work.WALBytes); err != nil {
+		return work, err
+	}
+	conn.QueryRowContext(ctx, "SELECT coalesce(sum(writes), 0)::bigint FROM pg_stat_io").Scan(&work.BuffersWritten)
+	return work, nil
+}
+
+// describeDatabase records the server and its tables' sizes for the
+// metadata of the suites run next. Row counts are the planner's live-row
+// estimates, since counting every table exactly could take minutes at the
+// larger scales.
+func describeDatabase(db *sql.DB) {
+	info := benchmark.DatabaseInfo{Name: "postgres", Driver: connection.PostgresDriver, RowCounts: make(map[string]int64)}
+	if err := db.QueryRowContext(ctx, "SELECT current_setting('server_version'), current_database()").Scan(&info.ServerVersion, &info.Target); err != nil {
+		slog.Error("Failed to read server version", "err", err)
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT relname, n_live_tup FROM pg_stat_user_tables")
+	if err != nil {
+		slog.Error("Failed to read table sizes", "err", err)
+	} else {
+		defer rows.Close()
+		for rows.Next() {
+			var table string
+			var count int64
+			if err := rows.Scan(&table, &count); err == nil {
+				info.RowCounts[table] = count
+			}
+		}
+	}
+	benchmark.SetDatabaseInfo(info)
+}
+
+// Clean removes all seeded data and any tables left behind by the
+// experiments, leaving the schema in place for the next Seed.
+func Clean() {
+	db := connect()
+	defer db.Close()
+
+	statements := []string{
+		"TRUNCATE reconciliation_exceptions, exchange_rates, transaction_legs, transactions, accounts, merchants, users CASCADE",
+		"DROP TABLE IF EXISTS transactions_by_merchant CASCADE",
+		"DROP TABLE IF EXISTS key_strategy_v4, key_strategy_v7",
+		"DROP TABLE IF EXISTS ingest_flat, ingest_partitioned CASCADE",
+		"DROP TABLE IF EXISTS fillfactor_accounts",
+	}
+	for _, stmt := range statements {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			benchmark.Fatal("Failed to clean database", "err", err)
+		}
+	}
+
+	if err := benchmark.RemoveIDs("postgres"); err != nil {
+		slog.Error("Failed to remove seeded IDs", "err", err)
+	}
+
+	slog.Info("Removed all PostgreSQL benchmark data")
+}
+
+func loadTestData(db *sql.DB) {
+	slog.Info("Loading test data")
+
+	seeded, err := benchmark.LoadIDs("postgres")
+	if err != nil {
+		benchmark.Fatal("Failed to load seeded IDs", "err", err)
+	}
+	anomalies = nil
+	if seeded != nil {
+		accountIDs = parseUUIDs(benchmark.SampleBy(seeded.Accounts, seeded.AccountLegs, 100))
+		transactionIDs = parseUUIDs(benchmark.SampleNewest(seeded.Transactions, seeded.TransactionAgeDays, 1000))
+		merchantIDs = parseUUIDs(benchmark.SampleBy(seeded.Merchants, seeded.MerchantTransactions, 100))
+		userIDs = parseUUIDs(benchmark.Sample(seeded.Users, 100))
+		anomalies = seeded.Anomalies
+	} else if benchmark.Stratified() {
+		accountIDs = loadIDs(db, "accounts", stratifiedQuery(`
+			SELECT a.id, COUNT(tl.id) AS attribute
+			FROM accounts a LEFT JOIN transaction_legs tl ON tl.account_id = a.id
+			WHERE a.benchmark_run_id IS NULL
+			GROUP BY a.id`, 100))
+		transactionIDs = loadIDs(db, "transactions", stratifiedQuery(`
+			SELECT id, created_at AS attribute FROM transactions WHERE benchmark_run_id IS NULL`, 1000))
+		merchantIDs = loadIDs(db, "merchants", stratifiedQuery(`
+			SELECT m.id, COUNT(t.id) AS attribute
+			FROM merchants m LEFT JOIN transactions t ON t.merchant_id = m.id AND t.benchmark_run_id IS NULL
+			GROUP BY m.id`, 100))
+		userIDs = loadIDs(db, "users", "SELECT id FROM users ORDER BY random() LIMIT 100")
+	} else {
+		// Uniform samples of seeded rows only, so earlier runs' writes never
+		// become test targets.
+		accountIDs = loadIDs(db, "accounts", "SELECT id FROM accounts WHERE benchmark_run_id IS NULL ORDER BY random() LIMIT 100")
+		transactionIDs = loadIDs(db, "transactions", "SELECT id FROM transactions WHERE benchmark_run_id IS NULL ORDER BY random() LIMIT 1000")
+		merchantIDs = loadIDs(db, "merchants", "SELECT id FROM merchants ORDER BY random() LIMIT 100")
+		userIDs = loadIDs(db, "users", "SELECT id FROM users ORDER BY random() LIMIT 100")
+	}
+
+	slog.Info("Loaded test data", "accounts", len(accountIDs), "transactions", len(transactionIDs),
+		"merchants", len(merchantIDs), "users", len(userIDs))
+}
+
+// stratifiedQuery wraps a query returning (id, attribute) rows so it returns
+// limit IDs, an equal share from each of benchmark.Strata equal-population
+// strata of the attribute, in random order.
+func stratifiedQuery(candidates string, limit int) string {
+	return fmt.Sprintf(`
+		SELECT id FROM (
+			SELECT id, ROW_NUMBER() OVER (PARTITION BY stratum ORDER BY random()) AS pick
+			FROM (SELECT id, NTILE(%[1]d) OVER (ORDER BY attribute) AS stratum FROM (%[2]s) candidates) strata
+		) picks
+		WHERE pick <= CEIL(%[3]d::numeric / %[1]d)
+		ORDER BY random()
+		LIMIT %[3]d`, benchmark.Strata, candidates, limit)
+}
+
+// parseUUIDs converts IDs from the seeder's ID file.
+func parseUUIDs(strs []string) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(strs))
+	for _, s := range strs {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			benchmark.Fatal("Invalid seeded ID", "id", s, "path", benchmark.IDsPath("postgres"), "err", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func loadIDs(db *sql.DB, entity, query string) []uuid.UUID {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		benchmark.Fatal("Failed to load test IDs", "entity", entity, "err", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			benchmark.Fatal("Failed to scan test IDs", "entity", entity, "err", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func calculateResults(testName string, totalOps, concurrency int, durations []time.Duration, success, errors int, totalDuration time.Duration) benchmark.Result {
+	return benchmark.Summarize(testName, "PostgreSQL", totalOps, concurrency, durations, success, errors, totalDuration)
+}
@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+)
+
+// schemaTables are the tables schema.sql creates and the suites use.
+var schemaTables = []string{
+	"merchants", "users", "accounts", "transactions", "transaction_legs",
+	"exchange_rates", "reconciliation_exceptions",
+}
+
+// seededMinimums are the fewest seeded rows in each table the suites can
+// sample from, as dataset.Volumes.Validate allows. Only accounts and
+// transactions mark rows benchmark runs wrote.
+var seededMinimums = []struct {
+	table, seeded string
+	min           int
+}{
+	{"merchants", "TRUE", 1},
+	{"users", "TRUE", 1},
+	{"accounts", "benchmark_run_id IS NULL", 2},
+	{"transactions", "benchmark_run_id IS NULL", 1},
+	{"transaction_legs", "TRUE", 2},
+}
+
+// Preflight prints whether PostgreSQL is ready for the suites and reports
+// whether it is.
+func Preflight() bool {
+	return benchmark.PrintPreflight(os.Stdout, "PostgreSQL", preflight())
+}
+
+// preflight checks that PostgreSQL answers at connection.PostgresDSN, that
+// schema.sql has been applied and that the database has been seeded,
+// stopping at the first check the later ones depend on.
+func preflight() []benchmark.PreflightCheck {
+	db, err := openDB()
+	if err != nil {
+		return []benchmark.PreflightCheck{{Name: "PostgreSQL connection", Err: fmt.Errorf("%w; fix the DSN in BENCH_PG_DSN or --pg-dsn", err)}}
+	}
+	defer db.Close()
+
+	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	checks := []benchmark.PreflightCheck{{Name: "PostgreSQL connection"}}
+	if err := db.PingContext(pingCtx); err != nil {
+		checks[0].Err = fmt.Errorf("%w; start PostgreSQL with `make start`, or point BENCH_PG_DSN or --pg-dsn at a running server", err)
+		return checks
+	}
+
+	schema := benchmark.PreflightCheck{Name: "Schema"}
+	missing, err := missingTables(db)
+	switch {
+	case err != nil:
+		schema.Err = err
+	case len(missing) > 0:
+		schema.Err = fmt.Errorf("missing tables %s; create them with `benchctl init --db=postgres`", strings.Join(missing, ", "))
+	}
+	checks = append(checks, schema)
+	if schema.Err != nil {
+		return checks
+	}
+
+	for _, m := range seededMinimums {
+		check := benchmark.PreflightCheck{Name: fmt.Sprintf("Seeded %s (at least %d)", m.table, m.min)}
+		var n int
+		err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM (SELECT 1 FROM %s WHERE %s LIMIT %d) seeded", m.table, m.seeded, m.min)).Scan(&n)
+		switch {
+		case err != nil:
+			check.Err = err
+		case n < m.min:
+			check.Err = fmt.Errorf("found %d; seed the database with `benchctl seed --db=postgres`", n)
+		}
+		checks = append(checks, check)
+	}
+	return append(checks, benchmark.PreflightIDs("postgres"))
+}
+
+// missingTables returns the schemaTables the database does not have.
+func missingTables(db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT t FROM unnest($1::text[]) t WHERE to_regclass(t) IS NULL", pq.Array(schemaTables))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var missing []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, err
+		}
+		missing = append(missing, table)
+	}
+	return missing, rows.Err()
+}
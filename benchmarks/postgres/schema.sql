@@ -1,10 +1,13 @@
 -- PostgreSQL Schema for Financial Transactions Benchmark
 
 -- Drop existing tables if they exist
+DROP TABLE IF EXISTS reconciliation_exceptions CASCADE;
+DROP TABLE IF EXISTS exchange_rates CASCADE;
 DROP TABLE IF EXISTS transaction_legs CASCADE;
 DROP TABLE IF EXISTS transactions CASCADE;
 DROP TABLE IF EXISTS accounts CASCADE;
 DROP TABLE IF EXISTS merchants CASCADE;
+DROP TABLE IF EXISTS users CASCADE;
 
 -- Merchants table
 CREATE TABLE merchants (
@@ -14,15 +17,24 @@ CREATE TABLE merchants (
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Users table (account holders)
+CREATE TABLE users (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) UNIQUE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
 -- Accounts table
 CREATE TABLE accounts (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-    user_id UUID NOT NULL,
+    user_id UUID NOT NULL, -- users(id) for seed data; no FK so benchmark runs can open accounts for new users
     account_type VARCHAR(50) NOT NULL, -- checking, savings, credit, etc.
     currency VARCHAR(3) DEFAULT 'USD',
     balance DECIMAL(19, 4) NOT NULL DEFAULT 0,
     version INTEGER NOT NULL DEFAULT 0, -- Optimistic locking
     status VARCHAR(20) DEFAULT 'active',
+    benchmark_run_id UUID, -- Set on rows written by a benchmark run, NULL for seed data
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
@@ -36,6 +48,7 @@ CREATE TABLE transactions (
     merchant_id UUID REFERENCES merchants(id),
     description TEXT,
     metadata JSONB,
+    benchmark_run_id UUID, -- Set on rows written by a benchmark run, NULL for seed data
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     completed_at TIMESTAMP WITH TIME ZONE
@@ -50,9 +63,31 @@ CREATE TABLE transaction_legs (
     amount DECIMAL(19, 4) NOT NULL CHECK (amount > 0),
     currency VARCHAR(3) DEFAULT 'USD',
     balance_after DECIMAL(19, 4),
+    benchmark_run_id UUID, -- Set on rows written by a benchmark run, NULL for seed data
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Exchange rates for converting volumes into a reporting currency
+CREATE TABLE exchange_rates (
+    from_currency VARCHAR(3) NOT NULL,
+    to_currency VARCHAR(3) NOT NULL,
+    rate DECIMAL(19, 8) NOT NULL CHECK (rate > 0),
+    effective_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    PRIMARY KEY (from_currency, to_currency, effective_date)
+);
+
+-- Transactions flagged by the suspense/unbalanced detection job
+CREATE TABLE reconciliation_exceptions (
+    id BIGSERIAL PRIMARY KEY,
+    run_id UUID NOT NULL,
+    transaction_id UUID NOT NULL,
+    total_debits DECIMAL(19, 4) NOT NULL,
+    total_credits DECIMAL(19, 4) NOT NULL,
+    difference DECIMAL(19, 4) NOT NULL,
+    detected_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (run_id, transaction_id)
+);
+
 -- Indexes for performance
 CREATE INDEX idx_accounts_user_id ON accounts(user_id);
 CREATE INDEX idx_accounts_status ON accounts(status);
@@ -68,8 +103,15 @@ CREATE INDEX idx_transaction_legs_account_id ON transaction_legs(account_id);
 CREATE INDEX idx_transaction_legs_created_at ON transaction_legs(created_at DESC);
 CREATE INDEX idx_transaction_legs_account_created ON transaction_legs(account_id, created_at DESC);
 
+CREATE INDEX idx_accounts_benchmark_run_id ON accounts(benchmark_run_id) WHERE benchmark_run_id IS NOT NULL;
+CREATE INDEX idx_transactions_benchmark_run_id ON transactions(benchmark_run_id) WHERE benchmark_run_id IS NOT NULL;
+CREATE INDEX idx_transaction_legs_benchmark_run_id ON transaction_legs(benchmark_run_id) WHERE benchmark_run_id IS NOT NULL;
+
+CREATE INDEX idx_reconciliation_exceptions_transaction_id ON reconciliation_exceptions(transaction_id);
+
 -- Composite index for common queries
 CREATE INDEX idx_transactions_status_created ON transactions(status, created_at DESC);
+CREATE INDEX idx_transactions_merchant_created ON transactions(merchant_id, created_at DESC);
 
 -- Function to update updated_at timestamp
 CREATE OR REPLACE FUNCTION update_updated_at_column()
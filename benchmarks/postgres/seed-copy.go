@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"database/sql"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/dataset"
+)
+
+// copyChunk is how many records the COPY path commits at once, so an
+// interrupt or failure loses at most one chunk.
+const copyChunk = 10000
+
+// copyMerchants seeds the merchants with COPY FROM.
+func copyMerchants(db *sql.DB, gen *dataset.Generator) []uuid.UUID {
+	slog.Info("Seeding merchants", "method", "copy")
+	written := copyChunks(db, "merchants", gen.Volumes().Merchants, func(tx copyTx, first, end int) error {
+		return copyRows(tx, "merchants", []string{"id", "name", "category"}, func(row func(...any) error) error {
+			for i := first; i < end; i++ {
+				m := gen.Merchant(i)
+				if err := row(m.ID, m.Name, m.Category); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+
+	merchantIDs := make([]uuid.UUID, 0, len(written))
+	for i, ok := range written {
+		if ok {
+			merchantIDs = append(merchantIDs, gen.Merchant(i).ID)
+		}
+	}
+	return merchantIDs
+}
+
+// copyUsers seeds the users with COPY FROM.
+func copyUsers(db *sql.DB, gen *dataset.Generator) []uuid.UUID {
+	slog.Info("Seeding users", "method", "copy")
+	written := copyChunks(db, "users", gen.Volumes().Users, func(tx copyTx, first, end int) error {
+		return copyRows(tx, "users", []string{"id", "name", "email"}, func(row func(...any) error) error {
+			for i := first; i < end; i++ {
+				u := gen.User(i)
+				if err := row(u.ID, u.Name, u.Email); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+
+	userIDs := make([]uuid.UUID, 0, len(written))
+	for i, ok := range written {
+		if ok {
+			userIDs = append(userIDs, gen.User(i).ID)
+		}
+	}
+	return userIDs
+}
+
+// copyAccounts seeds the accounts with COPY FROM.
+func copyAccounts(db *sql.DB, gen *dataset.Generator) []uuid.UUID {
+	slog.Info("Seeding accounts", "method", "copy")
+	columns := []string{"id", "user_id", "account_type", "currency", "balance", "status"}
+	written := copyChunks(db, "accounts", gen.Volumes().Accounts, func(tx copyTx, first, end int) error {
+		return copyRows(tx, "accounts", columns, func(row func(...any) error) error {
+			for i := first; i < end; i++ {
+				a := gen.Account(i)
+				if err := row(a.ID, a.UserID, a.AccountType, a.Currency, a.Balance, "active"); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+
+	accountIDs := make([]uuid.UUID, 0, len(written))
+	for i, ok := range written {
+		if ok {
+			accountIDs = append(accountIDs, gen.Account(i).ID)
+		}
+	}
+	return accountIDs
+}
+
+// copyTransactions seeds the transactions with COPY FROM, each chunk's
+// headers and then all their legs in one database transaction.
+func copyTransactions(db *sql.DB, gen *dataset.Generator) []seededTransaction {
+	slog.Info("Seeding transactions", "method", "copy")
+	headers := []string{"id", "idempotency_key", "transaction_type", "status", "merchant_id", "description", "created_at", "completed_at"}
+	legs := []string{"id", "transaction_id", "account_id", "leg_type", "amount", "currency", "created_at"}
+	written := copyChunks(db, "transactions", gen.Volumes().Transactions, func(tx copyTx, first, end int) error {
+		err := copyRows(tx, "transactions", headers, func(row func(...any) error) error {
+			for i := first; i < end; i++ {
+				t := gen.Transaction(i)
+				if rejectedBySchema(t) {
+					continue
+				}
+				createdAt := t.CreatedAt
+				if err := row(t.ID, t.IdempotencyKey.String(), t.Type, t.Status, t.MerchantID, t.Description, createdAt, completedAt(t, createdAt)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return copyRows(tx, "transaction_legs", legs, func(row func(...any) error) error {
+			for i := first; i < end; i++ {
+				t := gen.Transaction(i)
+				if rejectedBySchema(t) {
+					continue
+				}
+				createdAt := t.CreatedAt
+				for _, leg := range t.Legs {
+					if err := row(leg.ID, t.ID, leg.Account, leg.Type, leg.Amount, t.Currency, createdAt); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+	})
+
+	transactions := make([]seededTransaction, 0, len(written))
+	for i, ok := range written {
+		if t := gen.Transaction(i); ok && !rejectedBySchema(t) {
+			transactions = append(transactions, seeded(t))
+		}
+	}
+	return transactions
+}
+
+// copyChunks loads records [0, n) of kind copyChunk at a time, each chunk
+// by load in a transaction of its own, and reports which records were
+// committed.
+func copyChunks(db *sql.DB, kind string, n int, load func(tx copyTx, first, end int) error) []bool {
+	written := make([]bool, n)
+	for first := 0; first < n && !benchmark.Stopping(); first += copyChunk {
+		end := min(first+copyChunk, n)
+		tx, err := beginCopy(db)
+		if err != nil {
+			slog.Error("Failed to begin transaction", "err", err)
+			continue
+		}
+		if err := load(tx, first, end); err != nil {
+			tx.Rollback()
+			slog.Error("Failed to copy seed rows", "records", kind, "first", first, "count", end-first, "err", err)
+			continue
+		}
+		if err := tx.Commit(); err != nil {
+			slog.Error("Failed to commit seed rows", "records", kind, "first", first, "count", end-first, "err", err)
+			continue
+		}
+		for i := first; i < end; i++ {
+			written[i] = true
+		}
+		slog.Debug("Seeding progress", "records", kind, "written", end)
+	}
+	return written
+}
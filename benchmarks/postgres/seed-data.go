@@ -1,196 +1,310 @@
-package main
+package postgres
 
 import (
 	"database/sql"
-	"fmt"
-	"log"
-	"math/rand"
+	"log/slog"
 	"time"
 
 	"github.com/google/uuid"
 	_ "github.com/lib/pq"
-	"github.com/shopspring/decimal"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/dataset"
 )
 
-const (
-	NumMerchants = 1000
-	NumAccounts  = 10000
-	NumTransactions = 100000
-)
-
-var (
-	merchantCategories = []string{"Restaurant", "Retail", "Gas Station", "Grocery", "Entertainment", "Travel", "Healthcare", "Utility"}
-	accountTypes = []string{"checking", "savings", "credit"}
-	transactionTypes = []string{"payment", "transfer", "refund", "fee"}
-	currencies = []string{"USD", "EUR", "GBP"}
-)
+// Conversion rates into the USD reporting currency
+var usdRates = map[string]string{"USD": "1.0", "EUR": "1.08", "GBP": "1.27"}
 
-func main() {
-	connStr := "host=localhost port=5432 user=benchmark password=benchmark123 dbname=financial_benchmark sslmode=disable"
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
-	}
+// Seed fills the database with opts' merchants, users, accounts and
+// transactions, and the exchange rates.
+func Seed(opts dataset.Options) {
+	db := connect()
 	defer db.Close()
 
-	if err := db.Ping(); err != nil {
-		log.Fatal("Failed to ping database:", err)
+	seedExchangeRates(db)
+	slog.Info("Created exchange rates", "rates", len(usdRates))
+
+	// Seed in order due to foreign key constraints. The generator yields
+	// the same records DynamoDB is seeded with for the same --seed.
+	gen := dataset.New(benchmark.Seed(), opts.Volumes)
+	merchants, users, accounts, txns := seedMerchants, seedUsers, seedAccounts, seedTransactions
+	if opts.Method != "insert" {
+		merchants, users, accounts, txns = copyMerchants, copyUsers, copyAccounts, copyTransactions
 	}
 
-	log.Println("Connected to PostgreSQL")
+	// Each table's elapsed time and row rate are logged so the two methods
+	// can be compared.
+	start := time.Now()
+	merchantIDs := merchants(db, gen)
+	slog.Info("Created merchants", "merchants", len(merchantIDs), "duration", time.Since(start), "per_sec", perSecond(len(merchantIDs), start))
 
-	// Seed in order due to foreign key constraints
-	merchantIDs := seedMerchants(db)
-	log.Printf("Created %d merchants", len(merchantIDs))
+	start = time.Now()
+	userIDs := users(db, gen)
+	slog.Info("Created users", "users", len(userIDs), "duration", time.Since(start), "per_sec", perSecond(len(userIDs), start))
 
-	accountIDs := seedAccounts(db)
-	log.Printf("Created %d accounts", len(accountIDs))
+	start = time.Now()
+	accountIDs := accounts(db, gen)
+	slog.Info("Created accounts", "accounts", len(accountIDs), "duration", time.Since(start), "per_sec", perSecond(len(accountIDs), start))
 
-	seedTransactions(db, accountIDs, merchantIDs)
-	log.Printf("Created %d transactions", NumTransactions)
+	if opts.Anomalies > 0 {
+		slog.Info("Injecting unbalanced transactions only; the schema's constraints reject orphan legs and duplicate idempotency keys", "unbalanced", opts.Anomalies)
+	}
+	start = time.Now()
+	transactions := txns(db, gen)
+	slog.Info("Created transactions", "transactions", len(transactions), "duration", time.Since(start), "per_sec", perSecond(len(transactions), start))
+
+	ids := seededIDs(accountIDs, userIDs, merchantIDs, transactions)
+	ids.Partial = benchmark.Stopping()
+	if err := benchmark.SaveIDs("postgres", ids); err != nil {
+		slog.Error("Failed to save seeded IDs, suites will sample them from the database", "err", err)
+	}
 
-	log.Println("Seeding completed successfully!")
+	if ids.Partial {
+		slog.Info("Seeding interrupted; the IDs seeded so far are saved as partial")
+		return
+	}
+	slog.Info("Seeding completed")
+}
+
+// perSecond is the rate n records were written at since start.
+func perSecond(n int, start time.Time) int {
+	return int(float64(n) / time.Since(start).Seconds())
+}
+
+func seedExchangeRates(db *sql.DB) {
+	slog.Info("Seeding exchange rates")
+
+	for currency, rate := range usdRates {
+		_, err := db.ExecContext(ctx, `
+			INSERT INTO exchange_rates (from_currency, to_currency, rate)
+			VALUES ($1, 'USD', $2)
+			ON CONFLICT (from_currency, to_currency, effective_date) DO UPDATE SET rate = EXCLUDED.rate
+		`, currency, rate)
+		if err != nil {
+			slog.Error("Failed to insert exchange rate", "err", err)
+		}
+	}
 }
 
-func seedMerchants(db *sql.DB) []uuid.UUID {
-	log.Println("Seeding merchants...")
-	merchantIDs := make([]uuid.UUID, 0, NumMerchants)
+func seedMerchants(db *sql.DB, gen *dataset.Generator) []uuid.UUID {
+	slog.Info("Seeding merchants")
+	merchantIDs := make([]uuid.UUID, 0, gen.Volumes().Merchants)
 
-	stmt, err := db.Prepare(`
+	stmt, err := db.PrepareContext(ctx, `
 		INSERT INTO merchants (id, name, category)
 		VALUES ($1, $2, $3)
 	`)
 	if err != nil {
-		log.Fatal("Failed to prepare merchant insert:", err)
+		benchmark.Fatal("Failed to prepare merchant insert", "err", err)
 	}
 	defer stmt.Close()
 
-	for i := 0; i < NumMerchants; i++ {
-		id := uuid.New()
-		name := fmt.Sprintf("Merchant_%d", i)
-		category := merchantCategories[rand.Intn(len(merchantCategories))]
-
-		_, err := stmt.Exec(id, name, category)
+	for i := 0; i < gen.Volumes().Merchants && !benchmark.Stopping(); i++ {
+		m := gen.Merchant(i)
+		_, err := stmt.ExecContext(ctx, m.ID, m.Name, m.Category)
 		if err != nil {
-			log.Printf("Failed to insert merchant: %v", err)
+			slog.Error("Failed to insert merchant", "err", err)
 			continue
 		}
 
-		merchantIDs = append(merchantIDs, id)
+		merchantIDs = append(merchantIDs, m.ID)
 
 		if (i+1)%100 == 0 {
-			log.Printf("Created %d merchants...", i+1)
+			slog.Debug("Created merchants", "merchants", i+1)
 		}
 	}
 
 	return merchantIDs
 }
 
-func seedAccounts(db *sql.DB) []uuid.UUID {
-	log.Println("Seeding accounts...")
-	accountIDs := make([]uuid.UUID, 0, NumAccounts)
+func seedUsers(db *sql.DB, gen *dataset.Generator) []uuid.UUID {
+	slog.Info("Seeding users")
+	userIDs := make([]uuid.UUID, 0, gen.Volumes().Users)
 
-	stmt, err := db.Prepare(`
-		INSERT INTO accounts (id, user_id, account_type, currency, balance, status)
-		VALUES ($1, $2, $3, $4, $5, $6)
+	stmt, err := db.PrepareContext(ctx, `
+		INSERT INTO users (id, name, email)
+		VALUES ($1, $2, $3)
 	`)
 	if err != nil {
-		log.Fatal("Failed to prepare account insert:", err)
+		benchmark.Fatal("Failed to prepare user insert", "err", err)
 	}
 	defer stmt.Close()
 
-	for i := 0; i < NumAccounts; i++ {
-		id := uuid.New()
-		userID := uuid.New()
-		accountType := accountTypes[rand.Intn(len(accountTypes))]
-		currency := currencies[rand.Intn(len(currencies))]
-		balance := decimal.NewFromFloat(rand.Float64() * 10000)
-		status := "active"
-
-		_, err := stmt.Exec(id, userID, accountType, currency, balance, status)
+	for i := 0; i < gen.Volumes().Users && !benchmark.Stopping(); i++ {
+		u := gen.User(i)
+		_, err := stmt.ExecContext(ctx, u.ID, u.Name, u.Email)
 		if err != nil {
-			log.Printf("Failed to insert account: %v", err)
+			slog.Error("Failed to insert user", "err", err)
 			continue
 		}
 
-		accountIDs = append(accountIDs, id)
+		userIDs = append(userIDs, u.ID)
 
 		if (i+1)%1000 == 0 {
-			log.Printf("Created %d accounts...", i+1)
+			slog.Debug("Created users", "users", i+1)
 		}
 	}
 
-	return accountIDs
+	return userIDs
 }
 
-func seedTransactions(db *sql.DB, accountIDs, merchantIDs []uuid.UUID) {
-	log.Println("Seeding transactions...")
+func seedAccounts(db *sql.DB, gen *dataset.Generator) []uuid.UUID {
+	slog.Info("Seeding accounts")
+	accountIDs := make([]uuid.UUID, 0, gen.Volumes().Accounts)
 
-	for i := 0; i < NumTransactions; i++ {
-		tx, err := db.Begin()
+	stmt, err := db.PrepareContext(ctx, `
+		INSERT INTO accounts (id, user_id, account_type, currency, balance, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`)
+	if err != nil {
+		benchmark.Fatal("Failed to prepare account insert", "err", err)
+	}
+	defer stmt.Close()
+
+	for i := 0; i < gen.Volumes().Accounts && !benchmark.Stopping(); i++ {
+		a := gen.Account(i)
+		_, err := stmt.ExecContext(ctx, a.ID, a.UserID, a.AccountType, a.Currency, a.Balance, "active")
 		if err != nil {
-			log.Printf("Failed to begin transaction: %v", err)
+			slog.Error("Failed to insert account", "err", err)
 			continue
 		}
 
-		// Create transaction header
-		txnID := uuid.New()
-		idempotencyKey := uuid.New().String()
-		txnType := transactionTypes[rand.Intn(len(transactionTypes))]
-		merchantID := merchantIDs[rand.Intn(len(merchantIDs))]
-		status := "completed"
-		description := fmt.Sprintf("Transaction %d", i)
+		accountIDs = append(accountIDs, a.ID)
 
-		createdAt := time.Now().Add(-time.Duration(rand.Intn(90)) * 24 * time.Hour)
+		if (i+1)%1000 == 0 {
+			slog.Debug("Created accounts", "accounts", i+1)
+		}
+	}
 
-		_, err = tx.Exec(`
-			INSERT INTO transactions (id, idempotency_key, transaction_type, status, merchant_id, description, created_at, completed_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		`, txnID, idempotencyKey, txnType, status, merchantID, description, createdAt, createdAt)
+	return accountIDs
+}
+
+// seededTransaction is what seedTransactions remembers about each
+// transaction for the seeded-ID file.
+type seededTransaction struct {
+	id, merchant uuid.UUID
+	accounts     []uuid.UUID // one per leg
+	ageDays      int
+	anomaly      string
+}
+
+func seeded(t dataset.Transaction) seededTransaction {
+	accounts := make([]uuid.UUID, len(t.Legs))
+	for i, leg := range t.Legs {
+		accounts[i] = leg.Account
+	}
+	return seededTransaction{t.ID, t.MerchantID, accounts, t.AgeDays, t.Anomaly}
+}
 
+// rejectedBySchema reports whether t carries an anomaly the schema rules
+// out, which the seeders leave unwritten: orphan legs break the legs'
+// foreign key and a duplicate idempotency key its unique index.
+func rejectedBySchema(t dataset.Transaction) bool {
+	return t.Anomaly == dataset.OrphanLegs || t.Anomaly == dataset.DuplicateKey
+}
+
+func seedTransactions(db *sql.DB, gen *dataset.Generator) []seededTransaction {
+	slog.Info("Seeding transactions")
+	transactions := make([]seededTransaction, 0, gen.Volumes().Transactions)
+
+	for i := 0; i < gen.Volumes().Transactions && !benchmark.Stopping(); i++ {
+		t := gen.Transaction(i)
+		if rejectedBySchema(t) {
+			continue
+		}
+		tx, err := db.BeginTx(ctx, nil)
 		if err != nil {
-			tx.Rollback()
-			log.Printf("Failed to insert transaction: %v", err)
+			slog.Error("Failed to begin transaction", "err", err)
 			continue
 		}
 
-		// Create transaction legs (double-entry)
-		amount := decimal.NewFromFloat(rand.Float64() * 1000)
-		currency := currencies[rand.Intn(len(currencies))]
+		// Create transaction header
+		createdAt := t.CreatedAt
 
-		// Debit leg
-		debitAccount := accountIDs[rand.Intn(len(accountIDs))]
-		_, err = tx.Exec(`
-			INSERT INTO transaction_legs (transaction_id, account_id, leg_type, amount, currency, created_at)
-			VALUES ($1, $2, 'debit', $3, $4, $5)
-		`, txnID, debitAccount, amount, currency, createdAt)
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO transactions (id, idempotency_key, transaction_type, status, merchant_id, description, created_at, completed_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, t.ID, t.IdempotencyKey.String(), t.Type, t.Status, t.MerchantID, t.Description, createdAt, completedAt(t, createdAt))
 
 		if err != nil {
 			tx.Rollback()
-			log.Printf("Failed to insert debit leg: %v", err)
+			slog.Error("Failed to insert transaction", "err", err)
 			continue
 		}
 
-		// Credit leg
-		creditAccount := accountIDs[rand.Intn(len(accountIDs))]
-		_, err = tx.Exec(`
-			INSERT INTO transaction_legs (transaction_id, account_id, leg_type, amount, currency, created_at)
-			VALUES ($1, $2, 'credit', $3, $4, $5)
-		`, txnID, creditAccount, amount, currency, createdAt)
+		// Create transaction legs (double-entry), debit first
+		for _, leg := range t.Legs {
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO transaction_legs (id, transaction_id, account_id, leg_type, amount, currency, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+			`, leg.ID, t.ID, leg.Account, leg.Type, leg.Amount, t.Currency, createdAt)
+			if err != nil {
+				break
+			}
+		}
 
 		if err != nil {
 			tx.Rollback()
-			log.Printf("Failed to insert credit leg: %v", err)
+			slog.Error("Failed to insert transaction leg", "err", err)
 			continue
 		}
 
 		if err := tx.Commit(); err != nil {
-			log.Printf("Failed to commit transaction: %v", err)
+			slog.Error("Failed to commit transaction", "err", err)
 			continue
 		}
+		transactions = append(transactions, seeded(t))
 
 		if (i+1)%1000 == 0 {
-			log.Printf("Created %d transactions...", i+1)
+			slog.Debug("Created transactions", "transactions", i+1)
+		}
+	}
+
+	return transactions
+}
+
+// completedAt is when a seeded transaction finished, NULL while it is
+// pending.
+func completedAt(t dataset.Transaction, createdAt time.Time) sql.NullTime {
+	return sql.NullTime{Time: createdAt, Valid: dataset.Settled(t.Status)}
+}
+
+// seededIDs builds the seeded-ID file's contents, counting legs per account
+// and transactions per merchant for stratified sampling.
+func seededIDs(accountIDs, userIDs, merchantIDs []uuid.UUID, transactions []seededTransaction) benchmark.IDs {
+	legs := make(map[uuid.UUID]int)
+	merchantTxns := make(map[uuid.UUID]int)
+	ids := benchmark.IDs{
+		Accounts:  uuidStrings(accountIDs),
+		Merchants: uuidStrings(merchantIDs),
+		Users:     uuidStrings(userIDs),
+	}
+	for _, txn := range transactions {
+		if txn.anomaly != "" {
+			if ids.Anomalies == nil {
+				ids.Anomalies = make(map[string][]string)
+			}
+			ids.Anomalies[txn.anomaly] = append(ids.Anomalies[txn.anomaly], txn.id.String())
 		}
+		ids.Transactions = append(ids.Transactions, txn.id.String())
+		ids.TransactionAgeDays = append(ids.TransactionAgeDays, txn.ageDays)
+		for _, account := range txn.accounts {
+			legs[account]++
+		}
+		merchantTxns[txn.merchant]++
+	}
+	for _, id := range accountIDs {
+		ids.AccountLegs = append(ids.AccountLegs, legs[id])
+	}
+	for _, id := range merchantIDs {
+		ids.MerchantTransactions = append(ids.MerchantTransactions, merchantTxns[id])
+	}
+	return ids
+}
+
+func uuidStrings(ids []uuid.UUID) []string {
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = id.String()
 	}
+	return strs
 }
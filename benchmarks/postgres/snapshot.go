@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+)
+
+// Snapshot dumps the whole database, schema and data, to path in pg_dump's
+// custom format. Take it right after Seed so Restore can reset to the seeded
+// state. pg_dump must be on PATH and no older than the server.
+func Snapshot(path string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		benchmark.Fatal("Failed to create snapshot directory", "err", err)
+	}
+
+	start := time.Now()
+	runTool("pg_dump", "--format=custom", "--compress=1", "--file="+path, "--dbname="+toolConnString())
+
+	info, err := os.Stat(path)
+	if err != nil {
+		benchmark.Fatal("Failed to read snapshot", "err", err)
+	}
+	slog.Info("Snapshot written", "path", path, "mb", float64(info.Size())/(1<<20),
+		"duration", time.Since(start).Round(time.Millisecond))
+}
+
+// Restore replaces the database's contents with the snapshot at path. Every
+// object in the dump is dropped and recreated, so rows added by benchmark
+// runs and experiment tables created since the snapshot go with it. Tables
+// and indexes are rebuilt in parallel, which is much faster than seeding.
+func Restore(path string) {
+	if _, err := os.Stat(path); err != nil {
+		benchmark.Fatal("No PostgreSQL snapshot; run `benchctl seed --db=postgres` then `benchctl snapshot --db=postgres`",
+			"path", path, "err", err)
+	}
+
+	start := time.Now()
+	runTool("pg_restore", "--clean", "--if-exists", "--no-owner",
+		"--jobs="+strconv.Itoa(min(runtime.NumCPU(), 8)),
+		"--dbname="+toolConnString(), path)
+	slog.Info("Restored snapshot", "path", path, "duration", time.Since(start).Round(time.Millisecond))
+}
+
+// toolConnString is the connection string the client programs get, with
+// the TLS settings applied.
+func toolConnString() string {
+	dsn, err := connection.PostgresConnString()
+	if err != nil {
+		benchmark.Fatal("Invalid PostgreSQL DSN", "err", err)
+	}
+	return dsn
+}
+
+// runTool runs one of the PostgreSQL client programs, passing its output
+// through.
+func runTool(name string, args ...string) {
+	if _, err := exec.LookPath(name); err != nil {
+		benchmark.Fatal("PostgreSQL client tool not found; install the client tools matching the server version",
+			"tool", name)
+	}
+
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// An IAM token goes in the environment rather than on the command line,
+	// where other users' ps could read it.
+	password, err := connection.PostgresPassword(ctx)
+	if err != nil {
+		benchmark.Fatal("Failed to sign RDS IAM token", "err", err)
+	}
+	if password != "" {
+		cmd.Env = append(os.Environ(), "PGPASSWORD="+password)
+	}
+	if err := cmd.Run(); err != nil {
+		benchmark.Fatal("PostgreSQL client tool failed", "tool", name, "err", err)
+	}
+}
@@ -212,6 +212,45 @@ def plot_concurrency_scaling():
     print("Generated: benchmarks/results/concurrency-scaling.png")
 
 
+def plot_latency_curves():
+    """Plot full HdrHistogram percentile curves for the concurrent tests."""
+    curves = []
+    for filename, color in [('postgres-read-results.json', '#336791'),
+                            ('postgres-write-results.json', '#5cb85c'),
+                            ('dynamodb-read-results.json', '#ff9900'),
+                            ('dynamodb-write-results.json', '#d9534f')]:
+        for r in load_results(filename):
+            curve = (r.get('latency_distribution') or {}).get('curve')
+            if curve and 'Concurrent' in r['test_name']:
+                curves.append((f"{r['database']}: {r['test_name']}", color, curve))
+
+    if not curves:
+        return
+
+    fig, ax = plt.subplots(figsize=(12, 6))
+    for label, color, curve in curves:
+        # Plot against 1/(1-p) so the tail (P99, P99.9) gets room.
+        points = [p for p in curve if p['percentile'] < 100]
+        x = [1 / (1 - p['percentile'] / 100) for p in points]
+        y = [p['latency_ns'] / 1000000 for p in points]
+        ax.plot(x, y, linewidth=1.5, color=color, alpha=0.7, label=label)
+
+    ticks = [50, 90, 99, 99.9, 99.99]
+    ax.set_xscale('log')
+    ax.set_xticks([1 / (1 - t / 100) for t in ticks])
+    ax.set_xticklabels([f"P{t:g}" for t in ticks])
+    ax.set_xlabel('Percentile')
+    ax.set_ylabel('Latency (ms)')
+    ax.set_title('Latency Percentile Curves (concurrent tests)')
+    ax.legend(fontsize=7)
+    ax.grid(alpha=0.3)
+
+    plt.tight_layout()
+    plt.savefig('benchmarks/results/latency-curves.png',
+                dpi=300, bbox_inches='tight')
+    print("Generated: benchmarks/results/latency-curves.png")
+
+
 def generate_summary_table():
     """Generate a summary table of all benchmark results."""
     pg_writes = load_results('postgres-write-results.json')
@@ -258,6 +297,7 @@ def main():
     plot_latency_comparison()
     plot_cost_analysis()
     plot_concurrency_scaling()
+    plot_latency_curves()
     generate_summary_table()
 
     print("\nAll charts generated successfully!")
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+)
+
+// daemonMetricsAddr is where the daemon serves its metrics when --metrics is
+// not given: the trends are the point of running it.
+const daemonMetricsAddr = ":9464"
+
+// daemon runs the suites, or the --config matrix, every --every until it is
+// interrupted. Each round is a run of its own, with a new run ID, and the
+// history sink is added to BENCH_SINKS so every round is kept next to the
+// earlier ones. Rounds start on the schedule; one that overruns the
+// interval pushes the next back rather than overlapping it. An interrupt
+// during a round saves it as partial and stops; between rounds it stops at
+// once.
+func daemon(stopped context.Context, opts options, positional []string) {
+	sinks := strings.Split(os.Getenv("BENCH_SINKS"), ",")
+	if sinks[0] == "" {
+		sinks = []string{"file"}
+	}
+	if !slices.Contains(sinks, "history") {
+		os.Setenv("BENCH_SINKS", strings.Join(append(sinks, "history"), ","))
+	}
+
+	next := time.Now()
+	for round := 1; ; round++ {
+		if round > 1 {
+			benchmark.NewRun()
+		}
+		slog.Info("Daemon round", "round", round, "run_id", benchmark.RunID)
+		runSuites(opts, positional)
+		reportSLOs()
+		if stopped.Err() != nil {
+			return
+		}
+
+		next = next.Add(opts.every)
+		if now := time.Now(); next.Before(now) {
+			slog.Warn("Round overran the interval", "round", round, "every", opts.every)
+			next = now
+		}
+		slog.Info("Next daemon round", "at", next.Format(time.RFC3339))
+		select {
+		case <-time.After(time.Until(next)):
+		case <-stopped.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+)
+
+// generatorStartDelay is how far ahead the coordinator schedules a suite's
+// start, long enough for every worker to receive it first.
+const generatorStartDelay = 5 * time.Second
+
+// workSpec is the suite the coordinator hands each worker, with the
+// settings that must match across workers for their results to merge.
+// Connection flags, timeouts and retries stay each worker's own.
+type workSpec struct {
+	RunID       string            `json:"run_id"`
+	DB          string            `json:"db"`
+	Suite       string            `json:"suite"`
+	Scale       benchmark.Options `json:"scale"`
+	Keys        string            `json:"keys"`
+	Warmup      string            `json:"warmup"`
+	Workload    string            `json:"workload"`
+	Isolation   string            `json:"isolation"`
+	Consistency string            `json:"consistency"`
+	Pool        string            `json:"pool"`
+	Rate        float64           `json:"rate"`
+	Think       string            `json:"think"`
+	// StartAt is when every worker starts the suite, so their load
+	// overlaps; workers' clocks should be kept in sync.
+	StartAt time.Time `json:"start_at"`
+}
+
+// serveWorker serves POST /run on --addr for a coordinator, running one
+// suite at a time and answering with its results and latency histograms.
+// A second request while a suite runs is refused. An interrupt while idle
+// stops the worker at once; during a suite it stops as run does.
+func serveWorker(stopped context.Context, opts options) {
+	os.Setenv("BENCH_SINKS", "file")
+	var busy sync.Mutex
+	mux := http.NewServeMux()
+	mux.HandleFunc("/run", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "POST a work spec", http.StatusMethodNotAllowed)
+			return
+		}
+		if !busy.TryLock() {
+			http.Error(w, "a suite is already running", http.StatusConflict)
+			return
+		}
+		defer busy.Unlock()
+
+		var spec workSpec
+		if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		report, err := runWork(opts, spec)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			slog.Error("Failed to send results", "err", err)
+		}
+	})
+
+	server := &http.Server{Addr: opts.addr, Handler: mux}
+	go func() {
+		<-stopped.Done()
+		busy.Lock()
+		server.Close()
+	}()
+
+	slog.Info("Serving as a load generator", "url", "http://"+opts.addr+"/run")
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		benchmark.Fatal("Failed to serve as a load generator", "err", err)
+	}
+	benchmark.ExitIfStopped()
+}
+
+// runWork runs spec's suite at its start time, with results saved to a
+// scratch directory, and returns them with their histograms.
+func runWork(opts options, spec workSpec) (benchmark.GeneratorReport, error) {
+	report := benchmark.GeneratorReport{Generator: opts.addr}
+	db, ok := databases[spec.DB]
+	if !ok {
+		return report, fmt.Errorf("db must be postgres or dynamodb, not %q", spec.DB)
+	}
+	suite, ok := db.suites[spec.Suite]
+	if !ok {
+		return report, fmt.Errorf("unknown %s suite %q (available: %s)", spec.DB, spec.Suite, strings.Join(suiteNames(db), ", "))
+	}
+	if err := benchmark.SetWarmup(spec.Warmup); err != nil {
+		return report, err
+	}
+	if err := benchmark.SetYCSBWorkloads(spec.Workload); err != nil {
+		return report, err
+	}
+	for _, set := range []struct {
+		apply func(string) error
+		value string
+	}{
+		{benchmark.SetKeyDistribution, spec.Keys},
+		{benchmark.SetIsolation, spec.Isolation},
+		{benchmark.SetConsistency, spec.Consistency},
+		{benchmark.SetPool, spec.Pool},
+		{benchmark.SetThinkTime, spec.Think},
+	} {
+		if err := set.apply(set.value); err != nil {
+			return report, err
+		}
+	}
+	benchmark.SetRate(spec.Rate)
+
+	dir, err := os.MkdirTemp("", "benchctl-worker-")
+	if err != nil {
+		return report, err
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("BENCH_RESULTS_DIR", dir)
+
+	benchmark.RunID = spec.RunID
+	benchmark.SetParameters(withParameters(opts.parameters, map[string]string{"suite": spec.Suite, "db": spec.DB, "coordinated": "true"}))
+	slog.Info("Suite scheduled", "db", spec.DB, "suite", spec.Suite, "run_id", spec.RunID, "start_at", spec.StartAt.Format(time.RFC3339Nano))
+	time.Sleep(time.Until(spec.StartAt))
+
+	benchmark.CaptureHistograms()
+	suite(spec.Scale)
+	report.Histograms = benchmark.TakeHistograms()
+	report.Results, err = benchmark.LoadResults(spec.DB)
+	return report, err
+}
+
+// coordinate runs each named suite on every worker in --workers at once,
+// under this run's ID, then merges their results (see
+// benchmark.MergeGenerators) and saves them as <db>-<suite>-distributed.
+// A worker that fails is left out of the merge; the suite fails only when
+// every one does.
+func coordinate(opts options, positional []string) {
+	if len(opts.workers) == 0 {
+		benchmark.Fatal("coordinate needs --workers, such as --workers=host1:7070,host2:7070")
+	}
+	db := databases[opts.db]
+	if len(positional) == 0 {
+		benchmark.Fatal("coordinate needs a suite", "suites", strings.Join(suiteNames(db), "|"))
+	}
+	for _, name := range positional {
+		if _, ok := db.suites[name]; !ok {
+			benchmark.Fatal("Unknown suite", "db", opts.db, "suite", name,
+				"available", strings.Join(suiteNames(db), ", "))
+		}
+	}
+
+	slog.Info("Coordinated run", "run_id", benchmark.RunID, "workers", len(opts.workers))
+	for _, name := range positional {
+		spec := workSpec{
+			RunID: benchmark.RunID, DB: opts.db, Suite: name, Scale: opts.scale,
+			Keys: opts.keys, Warmup: opts.warmup, Workload: opts.workload,
+			Isolation: opts.isolation, Consistency: opts.consistency, Pool: opts.pool, Rate: opts.rate, Think: opts.think,
+			StartAt: time.Now().Add(generatorStartDelay),
+		}
+		reports := dispatch(opts.workers, spec)
+		if len(reports) == 0 {
+			benchmark.Fatal("Every worker failed", "suite", name)
+		}
+
+		benchmark.SetParameters(withParameters(opts.parameters, map[string]string{
+			"suite": name, "generators": strconv.Itoa(len(reports)),
+		}))
+		saveName := fmt.Sprintf("%s-%s-distributed", opts.db, name)
+		suite := benchmark.NewSuite(saveName)
+		suite.Add(benchmark.MergeGenerators(reports)...)
+		benchmark.Save(suite, saveName)
+		benchmark.PrintSummary(suite)
+	}
+	if opts.cleanup {
+		db.cleanup(benchmark.RunID)
+	}
+}
+
+// dispatch sends spec to every worker at once and returns the reports of
+// those that ran it.
+func dispatch(workers []string, spec workSpec) []benchmark.GeneratorReport {
+	body, err := json.Marshal(spec)
+	if err != nil {
+		benchmark.Fatal("Failed to encode the work spec", "err", err)
+	}
+
+	var wg sync.WaitGroup
+	reports := make([]*benchmark.GeneratorReport, len(workers))
+	for i, addr := range workers {
+		wg.Add(1)
+		go func(i int, addr string) {
+			defer wg.Done()
+			report, err := requestWork(addr, body)
+			if err != nil {
+				slog.Error("Worker failed", "worker", addr, "suite", spec.Suite, "err", err)
+				return
+			}
+			report.Generator = addr
+			slog.Info("Worker finished", "worker", addr, "suite", spec.Suite, "results", len(report.Results))
+			reports[i] = &report
+		}(i, addr)
+	}
+	wg.Wait()
+
+	var done []benchmark.GeneratorReport
+	for _, r := range reports {
+		if r != nil {
+			done = append(done, *r)
+		}
+	}
+	return done
+}
+
+// requestWork posts a work spec to the worker at addr and waits, however
+// long the suite takes, for its report.
+func requestWork(addr string, spec []byte) (benchmark.GeneratorReport, error) {
+	var report benchmark.GeneratorReport
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	resp, err := http.Post(strings.TrimSuffix(addr, "/")+"/run", "application/json", bytes.NewReader(spec))
+	if err != nil {
+		return report, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return report, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	err = json.NewDecoder(resp.Body).Decode(&report)
+	return report, err
+}
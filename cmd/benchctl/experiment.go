@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+)
+
+// experiment runs the same suites under every combination of the levels
+// of its factors, loaded from YAML or JSON:
+//
+//	name: isolation-pooling
+//	db: postgres
+//	suites: [writes, reads]
+//	ops: 2000
+//	concurrency: [50]
+//	factors:
+//	  - name: isolation
+//	    levels: [read-committed, serializable]
+//	  - name: pool
+//	    levels: [pooled, unpooled]
+//
+// Scale fields left out fall back to the command-line flags.
+type experiment struct {
+	Name        string             `json:"name" yaml:"name"`
+	DB          string             `json:"db" yaml:"db"`
+	Suites      []string           `json:"suites" yaml:"suites"`
+	Ops         int                `json:"ops" yaml:"ops"`
+	Concurrency []int              `json:"concurrency" yaml:"concurrency"`
+	BatchSize   []int              `json:"batch_size" yaml:"batch_size"`
+	Limit       int                `json:"limit" yaml:"limit"`
+	Factors     []benchmark.Factor `json:"factors" yaml:"factors"`
+}
+
+// experimentFactors are the settings an experiment can vary, each applying
+// one level to the settings the backends read when a suite connects.
+var experimentFactors = map[string]func(level string) error{
+	"isolation":   benchmark.SetIsolation,
+	"consistency": benchmark.SetConsistency,
+	"pool":        benchmark.SetPool,
+	"keys":        benchmark.SetKeyDistribution,
+	"think":       benchmark.SetThinkTime,
+	"rate": func(level string) error {
+		rate, err := strconv.ParseFloat(level, 64)
+		if err != nil || rate < 0 {
+			return fmt.Errorf("rate %q is not a non-negative number", level)
+		}
+		benchmark.SetRate(rate)
+		return nil
+	},
+}
+
+// loadExperiment reads an experiment file, choosing the format by
+// extension, and checks every factor level before anything runs.
+func loadExperiment(path string, opts options) (experiment, error) {
+	var e experiment
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return e, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, &e)
+	default:
+		decoder := json.NewDecoder(strings.NewReader(string(data)))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&e)
+	}
+	if err != nil {
+		return e, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if e.Name == "" {
+		e.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	e.DB = firstNonEmpty(e.DB, opts.db)
+	db, ok := databases[e.DB]
+	if !ok {
+		return e, fmt.Errorf("%s: db must be postgres or dynamodb", path)
+	}
+	if len(e.Suites) == 0 {
+		return e, fmt.Errorf("%s: no suites", path)
+	}
+	for _, suite := range e.Suites {
+		if _, ok := db.suites[suite]; !ok {
+			return e, fmt.Errorf("%s: unknown %s suite %q (available: %s)",
+				path, e.DB, suite, strings.Join(suiteNames(db), ", "))
+		}
+	}
+
+	if len(e.Factors) == 0 {
+		return e, fmt.Errorf("%s: no factors", path)
+	}
+	seen := make(map[string]bool)
+	for _, f := range e.Factors {
+		apply, ok := experimentFactors[f.Name]
+		if !ok {
+			return e, fmt.Errorf("%s: unknown factor %q (available: %s)", path, f.Name, strings.Join(factorNames(), ", "))
+		}
+		if seen[f.Name] {
+			return e, fmt.Errorf("%s: factor %q listed twice", path, f.Name)
+		}
+		seen[f.Name] = true
+		if len(f.Levels) < 2 {
+			return e, fmt.Errorf("%s: factor %q needs at least two levels", path, f.Name)
+		}
+		for _, level := range f.Levels {
+			if err := apply(level); err != nil {
+				return e, fmt.Errorf("%s: factor %q: %w", path, f.Name, err)
+			}
+		}
+	}
+
+	if e.Ops == 0 {
+		e.Ops = opts.scale.Operations
+	}
+	if len(e.Concurrency) == 0 {
+		e.Concurrency = opts.scale.Concurrency
+	}
+	if len(e.BatchSize) == 0 {
+		e.BatchSize = opts.scale.BatchSize
+	}
+	if e.Limit == 0 {
+		e.Limit = opts.scale.Limit
+	}
+	return e, nil
+}
+
+func factorNames() []string {
+	names := make([]string, 0, len(experimentFactors))
+	for name := range experimentFactors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runExperiment runs every suite of the experiment under each
+// configuration in turn, saving each configuration's results under its own
+// label, restoring the database from its snapshot first when --restore is
+// set. The settings the factors changed are put back to the flags' values
+// afterwards.
+func runExperiment(e experiment, opts options) {
+	configs := benchmark.Configurations(e.Factors)
+	for i, config := range configs {
+		slog.Info("Experiment configuration", "configuration", i+1, "of", len(configs),
+			"levels", benchmark.ConfigurationName(e.Factors, config))
+
+		parameters := map[string]string{"db": e.DB, "experiment": e.Name}
+		for _, f := range e.Factors {
+			if err := experimentFactors[f.Name](config[f.Name]); err != nil {
+				benchmark.Fatal("Invalid experiment", "err", err)
+			}
+			parameters[f.Name] = config[f.Name]
+		}
+		benchmark.Factors = config
+		benchmark.Label = benchmark.ConfigurationLabel(e.Factors, config)
+		for _, suite := range e.Suites {
+			if opts.restore {
+				restore(opts, e.DB)
+			}
+			parameters["suite"] = suite
+			benchmark.SetParameters(withParameters(opts.parameters, parameters))
+			databases[e.DB].suites[suite](benchmark.Options{
+				Operations:       e.Ops,
+				Concurrency:      e.Concurrency,
+				BatchSize:        e.BatchSize,
+				Limit:            e.Limit,
+				StepDuration:     opts.scale.StepDuration,
+				SoakDuration:     opts.scale.SoakDuration,
+				SnapshotInterval: opts.scale.SnapshotInterval,
+			})
+		}
+		applySettings(opts)
+	}
+	benchmark.Factors = nil
+	benchmark.Label = ""
+
+	if opts.cleanup {
+		databases[e.DB].cleanup(benchmark.RunID)
+	}
+}
+
+// writeExperiment writes the factorial report of this run's experiment
+// results to --out, or stdout.
+func writeExperiment(e experiment, opts options) error {
+	saved, err := benchmark.LoadResults(e.DB)
+	if err != nil {
+		return err
+	}
+	var results []benchmark.Result
+	for _, r := range saved {
+		if r.ExperimentRunID == benchmark.RunID {
+			results = append(results, r)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if opts.out != "" {
+		f, err := os.Create(opts.out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	} else {
+		fmt.Println()
+	}
+	if err := benchmark.WriteFactorial(w, e.Name, benchmark.Factorial(e.Factors, results)); err != nil {
+		return err
+	}
+	if opts.out != "" {
+		slog.Info("Experiment report written", "file", opts.out)
+	}
+	return nil
+}
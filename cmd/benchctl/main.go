@@ -0,0 +1,1118 @@
+// Command benchctl seeds the test databases, runs the benchmark suites
+// against them, summarizes saved results and cleans up afterwards:
+//
+//	benchctl init --db=dynamodb --billing=on-demand
+//	benchctl seed --db=dynamodb --aws --ddb-region=eu-west-1 --confirm-table=FinancialTransactions --max-cost=5
+//	benchctl seed --db=postgres
+//	benchctl preflight --db=dynamodb
+//	benchctl run reads --db=dynamodb
+//	benchctl run writes reads --db=postgres
+//	benchctl run reads --db=postgres --ops=10000 --concurrency=50,200 --limit=500
+//	benchctl run reads --db=dynamodb --keys=zipf
+//	benchctl run writes --db=postgres --warmup=30s
+//	benchctl run reads --db=dynamodb --rate=2000
+//	benchctl run writes --db=postgres --concurrency=500 --think=exp:100ms
+//	benchctl run writes --db=dynamodb --seed=42
+//	benchctl run reads --db=postgres --op-timeout=2s
+//	benchctl run writes --db=dynamodb --retries=5 --retry-base=20ms
+//	benchctl run --config=benchmarks/matrix.example.yaml
+//	benchctl run --self-check
+//	benchctl run reads --db=postgres --runs=5
+//	benchctl run ramp --db=dynamodb --step=10s
+//	benchctl run ycsb --db=postgres --workload=B
+//	benchctl run soak --db=postgres --soak=8h --snapshot-every=15m
+//	benchctl run replay --db=dynamodb --replay=trace.jsonl --speed=2
+//	benchctl run writes --db=postgres --isolation=serializable --pool=unpooled
+//	benchctl run reads writes --db=postgres --slo='point_read.p99 < 10ms' --slo='double_entry.ops >= 500'
+//	benchctl experiment benchmarks/experiment.example.yaml
+//	benchctl worker --addr=:7070
+//	benchctl coordinate writes --db=postgres --workers=gen1:7070,gen2:7070
+//	benchctl report --db=dynamodb
+//	benchctl report compare --format=html --out=comparison.html
+//	benchctl report charts --out=report.html
+//	benchctl report diff --baseline=old.json --current=new.json --threshold=5
+//	benchctl clean --db=postgres
+//	benchctl drift --db=postgres
+//	benchctl cleanup --db=dynamodb --run=<run id>
+//	benchctl snapshot --db=postgres
+//	benchctl run reads writes --db=postgres --restore
+//	benchctl analyze trace.jsonl
+//	benchctl audit
+//	benchctl recover benchmarks/results/postgres-read-results.jsonl
+//
+// Results are published through the sinks selected by BENCH_SINKS.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/benchmarks/dynamodb"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/benchmarks/postgres"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/access"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/dataset"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/trace"
+)
+
+// database is one backend benchctl can drive.
+type database struct {
+	// init creates the schema, or table and indexes, seed fills.
+	init    func()
+	seed    func(dataset.Options)
+	clean   func()
+	cleanup func(runID string)
+	drift   func()
+	// preflight checks the database is up, has its schema and is seeded,
+	// and reports whether it is.
+	preflight func() bool
+	// snapshot and restore save and reload the whole dataset; snapshotFile
+	// is the default file name under benchmarks/snapshots.
+	snapshot     func(path string)
+	restore      func(path string)
+	snapshotFile string
+	suites       map[string]func(benchmark.Options)
+}
+
+var databases = map[string]database{
+	"postgres": {
+		init: postgres.Init, seed: postgres.Seed, clean: postgres.Clean, cleanup: postgres.Cleanup, drift: postgres.Drift,
+		preflight: postgres.Preflight, snapshot: postgres.Snapshot, restore: postgres.Restore, snapshotFile: "postgres.dump",
+		suites: postgres.Suites,
+	},
+	"dynamodb": {
+		init: dynamodb.Init, seed: dynamodb.Seed, clean: dynamodb.Clean, cleanup: dynamodb.Cleanup, drift: dynamodb.Drift,
+		preflight: dynamodb.Preflight, snapshot: dynamodb.Snapshot, restore: dynamodb.Restore, snapshotFile: "dynamodb.jsonl.gz",
+		suites: dynamodb.Suites,
+	},
+}
+
+// options are the flags shared by every subcommand.
+type options struct {
+	db         string
+	resultsDir string
+	keys       string
+	warmup     string
+	// isolation, consistency and pool are the connection settings an
+	// experiment's factors vary.
+	isolation   string
+	consistency string
+	pool        string
+	// billing is the capacity mode of a DynamoDB table benchctl creates.
+	billing    string
+	workload   string
+	rate       float64
+	think      string
+	seed       int64
+	seeding    dataset.Options
+	replay     string
+	speed      float64
+	slos       []string
+	opTimeout  time.Duration
+	trace      string
+	dashboard  bool
+	metrics    string
+	addr       string
+	workers    []string
+	every      time.Duration
+	maxRuntime time.Duration
+	retry      benchmark.RetryPolicy
+	phases     bool
+	ids        string
+	stratify   bool
+	config     string
+	format     string
+	out        string
+	runID      string
+	cleanup    bool
+	snapshot   string
+	restore    bool
+	selfCheck  bool
+	runs       int
+	baseline   string
+	current    string
+	threshold  float64
+	logFormat  string
+	logLevel   slog.Level
+	quiet      bool
+	scale      benchmark.Options
+	// maxCost and confirmTable guard commands against DynamoDB on AWS.
+	maxCost      float64
+	confirmTable string
+	// parameters are every flag's value, for the run metadata.
+	parameters map[string]string
+}
+
+// intList is a comma-separated list of positive integers.
+type intList []int
+
+func (l *intList) String() string {
+	parts := make([]string, len(*l))
+	for i, n := range *l {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (l *intList) Set(value string) error {
+	*l = nil
+	for _, part := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n <= 0 {
+			return fmt.Errorf("%q is not a positive integer", part)
+		}
+		*l = append(*l, n)
+	}
+	return nil
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	command, args := os.Args[1], os.Args[2:]
+	if command == "help" || command == "-h" || command == "--help" {
+		usage()
+		return
+	}
+	if command == "analyze" {
+		if len(args) != 1 {
+			benchmark.Fatal("analyze needs one trace file")
+		}
+		if err := analyze(args[0]); err != nil {
+			benchmark.Fatal("Failed to analyze trace", "err", err)
+		}
+		return
+	}
+	if command == "audit" {
+		findings, err := access.Audit(".")
+		if err != nil {
+			benchmark.Fatal("Failed to audit access patterns", "err", err)
+		}
+		access.Print(os.Stdout, findings)
+		for _, f := range findings {
+			if f.Coverage == access.Uncovered {
+				os.Exit(1)
+			}
+		}
+		return
+	}
+	if command == "recover" {
+		if len(args) == 0 {
+			benchmark.Fatal("recover needs one or more journal files")
+		}
+		for _, path := range args {
+			suite, name, err := benchmark.Recover(path)
+			if err != nil {
+				benchmark.Fatal("Failed to recover results", "err", err)
+			}
+			slog.Info("Recovered results", "results", len(suite.Results), "journal", path)
+			benchmark.Save(suite, name)
+		}
+		return
+	}
+
+	opts, positional, err := parseArgs(command, args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	level := opts.logLevel
+	if opts.quiet {
+		level = max(level, slog.LevelError)
+	}
+	if err := benchmark.SetLogging(opts.logFormat, level); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	db := databases[opts.db]
+	var stopped context.Context
+	if command == "seed" || command == "run" || command == "daemon" || command == "experiment" || command == "worker" {
+		stopped = benchmark.HandleInterrupts()
+	}
+	benchmark.SetSeed(opts.seed)
+	if err := benchmark.SetBillingMode(opts.billing); err != nil {
+		benchmark.Fatal("Invalid --billing", "err", err)
+	}
+	if opts.db != "postgres" && connection.OnAWS() {
+		guardAWS(command, opts)
+	}
+
+	switch command {
+	case "seed":
+		opts.seeding.Volumes = opts.seeding.WithDefaults()
+		if err := opts.seeding.Validate(); err != nil {
+			benchmark.Fatal("Invalid dataset volumes", "err", err)
+		}
+		if opts.seeding.Workers < 1 {
+			benchmark.Fatal("--seed-workers must be at least 1", "seed_workers", opts.seeding.Workers)
+		}
+		if !slices.Contains(dataset.Methods, opts.seeding.Method) {
+			benchmark.Fatal("--seed-method must be copy or insert", "seed_method", opts.seeding.Method)
+		}
+		slog.Info("Seeding", "merchants", opts.seeding.Merchants, "accounts", opts.seeding.Accounts, "users", opts.seeding.Users, "transactions", opts.seeding.Transactions, "statuses", opts.seeding.Statuses.String(), "skew", opts.seeding.Skew, "flat_traffic", opts.seeding.Traffic.Flat, "spikes", len(opts.seeding.Traffic.Spikes), "max_legs", opts.seeding.Legs, "anomalies", opts.seeding.Anomalies)
+		db.seed(opts.seeding)
+		benchmark.ExitIfStopped()
+	case "run", "daemon", "experiment", "worker":
+		slog.Info("Benchmark run", "run_id", benchmark.RunID)
+		if err := benchmark.SetIDSource(opts.ids); err != nil {
+			benchmark.Fatal("Invalid --ids", "err", err)
+		}
+		benchmark.SetStratified(opts.stratify)
+		benchmark.SetOpTimeout(opts.opTimeout)
+		benchmark.SetMaxRuntime(opts.maxRuntime)
+		benchmark.SetRetryPolicy(opts.retry)
+		benchmark.SetPhases(opts.phases)
+		if err := benchmark.SetYCSBWorkloads(opts.workload); err != nil {
+			benchmark.Fatal("Invalid --workload", "err", err)
+		}
+		applySettings(opts)
+		if err := benchmark.SetReplay(opts.replay, opts.speed); err != nil {
+			benchmark.Fatal("Invalid --replay", "err", err)
+		}
+		if command == "daemon" {
+			if opts.every <= 0 {
+				benchmark.Fatal("daemon needs --every, such as --every=6h")
+			}
+			if opts.selfCheck {
+				benchmark.Fatal("--self-check does not combine with daemon")
+			}
+			if opts.metrics == "" {
+				opts.metrics = daemonMetricsAddr
+			}
+		}
+		if command == "experiment" && (opts.selfCheck || opts.config != "" || opts.runs > 1) {
+			benchmark.Fatal("--self-check, --config and --runs do not combine with experiment")
+		}
+		if opts.runs > 1 && (opts.config != "" || opts.selfCheck) {
+			benchmark.Fatal("--runs does not combine with --config or --self-check")
+		}
+		if (opts.trace != "" || opts.dashboard) && opts.selfCheck {
+			benchmark.Fatal("--trace and --dashboard do not combine with --self-check")
+		}
+		if err := benchmark.SetTrace(opts.trace); err != nil {
+			benchmark.Fatal("Invalid --trace", "err", err)
+		}
+		if opts.dashboard {
+			benchmark.StartDashboard(os.Stderr)
+		}
+		if opts.metrics != "" {
+			if err := benchmark.ServeMetrics(opts.metrics); err != nil {
+				benchmark.Fatal("Failed to serve metrics", "err", err)
+			}
+		}
+		if command == "worker" && (opts.selfCheck || opts.config != "" || opts.runs > 1 || opts.dashboard) {
+			benchmark.Fatal("--self-check, --config, --runs and --dashboard do not combine with worker")
+		}
+		if len(opts.slos) > 0 && (opts.selfCheck || command == "worker") {
+			benchmark.Fatal("--slo does not combine with --self-check or worker")
+		}
+		if err := benchmark.SetSLOs(opts.slos); err != nil {
+			benchmark.Fatal("Invalid --slo", "err", err)
+		}
+		if opts.selfCheck {
+			if !selfCheck(opts, positional) {
+				os.Exit(1)
+			}
+			return
+		}
+		switch command {
+		case "daemon":
+			daemon(stopped, opts, positional)
+		case "worker":
+			serveWorker(stopped, opts)
+		case "experiment":
+			if len(positional) != 1 {
+				benchmark.Fatal("experiment needs one experiment file")
+			}
+			e, err := loadExperiment(positional[0], opts)
+			if err != nil {
+				benchmark.Fatal("Failed to load experiment", "err", err)
+			}
+			runExperiment(e, opts)
+			if err := writeExperiment(e, opts); err != nil {
+				slog.Error("Failed to report the experiment", "err", err)
+			}
+		default:
+			runSuites(opts, positional)
+		}
+		finishRun()
+	case "coordinate":
+		coordinate(opts, positional)
+	case "report":
+		if len(positional) > 0 && positional[0] == "compare" {
+			if err := compare(opts); err != nil {
+				benchmark.Fatal("Failed to compare results", "err", err)
+			}
+			return
+		}
+		if len(positional) > 0 && positional[0] == "charts" {
+			if err := chartResults(opts); err != nil {
+				benchmark.Fatal("Failed to chart results", "err", err)
+			}
+			return
+		}
+		if len(positional) > 0 && positional[0] == "diff" {
+			regressed, err := diff(opts)
+			if err != nil {
+				benchmark.Fatal("Failed to diff results", "err", err)
+			}
+			if regressed {
+				os.Exit(1)
+			}
+			return
+		}
+		if err := report(opts); err != nil {
+			benchmark.Fatal("Failed to read results", "err", err)
+		}
+	case "init":
+		db.init()
+	case "clean":
+		db.clean()
+	case "cleanup":
+		db.cleanup(opts.runID)
+	case "drift":
+		db.drift()
+	case "preflight":
+		if !db.preflight() {
+			os.Exit(1)
+		}
+	case "snapshot":
+		db.snapshot(snapshotPath(opts, opts.db))
+		keepIDs(opts, opts.db, true)
+	case "restore":
+		restore(opts, opts.db)
+	case "serve":
+		slog.Info("Serving results", "dir", benchmark.ResultsDir(), "url", "http://"+opts.addr)
+		if err := http.ListenAndServe(opts.addr, benchmark.ResultsHandler()); err != nil {
+			benchmark.Fatal("Failed to serve results", "err", err)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", command)
+		usage()
+		os.Exit(2)
+	}
+}
+
+// runSuites runs the matrix in --config, or the named suites on --db
+// (--runs times over when set), then removes the rows they wrote if
+// --cleanup is set.
+func runSuites(opts options, positional []string) {
+	if opts.config != "" {
+		if len(positional) > 0 {
+			benchmark.Fatal("run takes either suites or --config, not both")
+		}
+		m, err := loadMatrix(opts.config, opts)
+		if err != nil {
+			benchmark.Fatal("Failed to load matrix", "err", err)
+		}
+		if err := benchmark.SetSLOs(append(opts.slos, m.SLOs...)); err != nil {
+			benchmark.Fatal("Invalid matrix SLOs", "err", err)
+		}
+		runMatrix(m, opts)
+		if opts.cleanup {
+			cleaned := make(map[string]bool)
+			for _, run := range m.Runs {
+				if !cleaned[run.DB] {
+					databases[run.DB].cleanup(benchmark.RunID)
+					cleaned[run.DB] = true
+				}
+			}
+		}
+		return
+	}
+
+	db := databases[opts.db]
+	if len(positional) == 0 {
+		benchmark.Fatal("run needs a suite", "suites", strings.Join(suiteNames(db), "|"))
+	}
+	if err := benchmark.SetWarmup(opts.warmup); err != nil {
+		benchmark.Fatal("Invalid --warmup", "err", err)
+	}
+	for _, name := range positional {
+		if _, ok := db.suites[name]; !ok {
+			benchmark.Fatal("Unknown suite", "db", opts.db, "suite", name,
+				"available", strings.Join(suiteNames(db), ", "))
+		}
+	}
+	// Each run of a multi-run goes through every suite in turn, so
+	// drift over the session spreads across the suites, and saves
+	// under its own label.
+	for repeat := 1; repeat <= opts.runs; repeat++ {
+		if opts.runs > 1 {
+			slog.Info("Run", "run", repeat, "of", opts.runs)
+			benchmark.Repeat, benchmark.Label = repeat, fmt.Sprintf("run%d", repeat)
+		}
+		for _, name := range positional {
+			if opts.restore {
+				restore(opts, opts.db)
+			}
+			benchmark.SetParameters(withParameters(opts.parameters, map[string]string{"suite": name}))
+			db.suites[name](opts.scale)
+		}
+	}
+	if opts.runs > 1 {
+		benchmark.Repeat, benchmark.Label = 0, ""
+		if err := printRepeats(opts.db); err != nil {
+			slog.Error("Failed to summarize the runs", "err", err)
+		}
+	}
+	if opts.cleanup {
+		db.cleanup(benchmark.RunID)
+	}
+}
+
+// applySettings applies the flags for the settings an experiment's factors
+// vary, which its configurations override in turn.
+func applySettings(opts options) {
+	if err := benchmark.SetKeyDistribution(opts.keys); err != nil {
+		benchmark.Fatal("Invalid --keys", "err", err)
+	}
+	benchmark.SetRate(opts.rate)
+	if err := benchmark.SetThinkTime(opts.think); err != nil {
+		benchmark.Fatal("Invalid --think", "err", err)
+	}
+	if err := benchmark.SetIsolation(opts.isolation); err != nil {
+		benchmark.Fatal("Invalid --isolation", "err", err)
+	}
+	if err := benchmark.SetConsistency(opts.consistency); err != nil {
+		benchmark.Fatal("Invalid --consistency", "err", err)
+	}
+	if err := benchmark.SetPool(opts.pool); err != nil {
+		benchmark.Fatal("Invalid --pool", "err", err)
+	}
+}
+
+// parseArgs parses the shared flags, which may appear before or after the
+// subcommand's positional arguments.
+func parseArgs(command string, args []string) (options, []string, error) {
+	var opts options
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	fs.StringVar(&opts.db, "db", "", "database to target: postgres|dynamodb")
+	fs.IntVar(&opts.scale.Operations, "ops", 0, "operations per test (default: each test's own)")
+	fs.Var((*intList)(&opts.scale.Concurrency), "concurrency", "comma-separated worker counts for concurrent tests (default: each test's own)")
+	fs.Var((*intList)(&opts.scale.BatchSize), "batch-size", "comma-separated batch sizes for batch tests (default: each test's own)")
+	fs.IntVar(&opts.scale.Limit, "limit", 0, "row limit for range and history queries (default: each query's own)")
+	fs.DurationVar(&opts.scale.StepDuration, "step", 0, "how long each step of the ramp, ingest, skew and saturation suites is sustained (default: each suite's own)")
+	fs.DurationVar(&opts.scale.SoakDuration, "soak", 0, "how long the soak suite runs (default 2h)")
+	fs.DurationVar(&opts.scale.SnapshotInterval, "snapshot-every", 0, "how often the soak suite snapshots its results (default 10m)")
+	fs.StringVar(&opts.keys, "keys", "uniform", "key distribution: "+strings.Join(benchmark.KeyDistributions, "|"))
+	fs.StringVar(&opts.warmup, "warmup", "", "unmeasured warm-up before each read/write test: an op count (500) or a duration (30s)")
+	fs.StringVar(&opts.ids, "ids", "auto", "where suites get test IDs: "+strings.Join(benchmark.IDSources, "|"))
+	fs.BoolVar(&opts.stratify, "stratify", false, "sample test IDs evenly across age, activity and merchant-size quartiles")
+	fs.StringVar(&opts.workload, "workload", "", "comma-separated YCSB workloads A-F the ycsb suite runs (default all)")
+	fs.StringVar(&opts.isolation, "isolation", "default", "PostgreSQL transaction isolation: "+strings.Join(benchmark.Isolations, "|"))
+	fs.StringVar(&opts.consistency, "consistency", "eventual", "DynamoDB read consistency: "+strings.Join(benchmark.Consistencies, "|"))
+	fs.Float64Var(&opts.maxCost, "max-cost", 10, "USD the DynamoDB requests of one command may cost on AWS before it stops, 0 for no limit")
+	fs.StringVar(&opts.confirmTable, "confirm-table", "", "name of the AWS DynamoDB table a command may write to or delete from")
+	fs.StringVar(&opts.billing, "billing", "provisioned", "DynamoDB table capacity mode when init, seed or clean creates it: "+strings.Join(benchmark.BillingModes, "|"))
+	fs.StringVar(&opts.pool, "pool", "pooled", "keep connections between operations: "+strings.Join(benchmark.Pools, "|"))
+	fs.Float64Var(&opts.rate, "rate", 0, "open-loop target ops/sec for concurrent tests (default closed-loop)")
+	fs.StringVar(&opts.think, "think", "", "pause between each closed-loop worker's operations: fixed (50ms) or exponential with that mean (exp:50ms) (default none)")
+	fs.Int64Var(&opts.seed, "seed", 0, "seed every random key, amount and operation choice, so runs repeat the same operations (default unseeded)")
+	fs.StringVar(&opts.replay, "replay", "", "JSON Lines trace of operations the replay suite plays back (see analyze)")
+	fs.Float64Var(&opts.speed, "speed", 1, "how many times faster than recorded the replay suite plays its trace")
+	fs.DurationVar(&opts.opTimeout, "op-timeout", 0, "deadline for each statement or API call; timeouts are counted apart from errors (default none)")
+	fs.DurationVar(&opts.maxRuntime, "max-runtime", 0, "time limit for each suite, after which its remaining tests are recorded as not run (run only, default none)")
+	fs.IntVar(&opts.retry.MaxRetries, "retries", 0, "retry transient errors in concurrent tests and workloads up to this many times (default none)")
+	fs.DurationVar(&opts.retry.BaseDelay, "retry-base", 10*time.Millisecond, "backoff before the first retry, doubled for each one after")
+	fs.DurationVar(&opts.retry.MaxDelay, "retry-max", time.Second, "longest backoff between retries")
+	fs.StringVar(&opts.metrics, "metrics", "", "serve Prometheus metrics at /metrics on this address, e.g. :9464, while the suites run (run only)")
+	fs.BoolVar(&opts.dashboard, "dashboard", false, "redraw live ops/sec, rolling P99, error rate, ETA and per-worker progress below the log every second (run only)")
+	fs.StringVar(&opts.trace, "trace", "", "write every statement or API call, with its latency, error, key and consumed capacity, to this CSV file (run only)")
+	fs.BoolVar(&opts.phases, "phases", false, "break each test's average latency down into acquire/execute/scan or marshal/http/unmarshal phases")
+	fs.BoolVar(&opts.cleanup, "cleanup", false, "delete the rows this run wrote once its suites finish (run only)")
+	fs.StringVar(&opts.runID, "run", "", "run ID to remove, default every run (cleanup only)")
+	fs.IntVar(&opts.seeding.Merchants, "merchants", 0, fmt.Sprintf("merchants to seed (seed only, default %d)", dataset.DefaultVolumes.Merchants))
+	fs.IntVar(&opts.seeding.Accounts, "accounts", 0, fmt.Sprintf("accounts to seed (seed only, default %d)", dataset.DefaultVolumes.Accounts))
+	fs.IntVar(&opts.seeding.Users, "users", 0, "users owning the seeded accounts, at most one per account (seed only, default two per five accounts)")
+	fs.IntVar(&opts.seeding.Transactions, "transactions", 0, fmt.Sprintf("transactions to seed (seed only, default %d)", dataset.DefaultVolumes.Transactions))
+	fs.Func("status-mix", fmt.Sprintf("weight of each seeded transaction status, such as %s (seed only)", dataset.DefaultStatusMix), func(value string) error {
+		mix, err := dataset.ParseStatusMix(value)
+		opts.seeding.Statuses = mix
+		return err
+	})
+	fs.Float64Var(&opts.seeding.Skew, "seed-skew", dataset.DefaultSkew, "Zipf exponent seeded transactions pick merchants and accounts with, 0 for uniform (seed only)")
+	fs.IntVar(&opts.seeding.Anomalies, "inject-anomalies", 0, "seed this many unbalanced transactions, orphan legs and duplicate idempotency keys each, for the suspense detection jobs to find (seed only)")
+	fs.IntVar(&opts.seeding.Legs, "max-legs", dataset.DefaultLegs, fmt.Sprintf("most legs a seeded transaction has, 2 to %d; 2 seeds double entry only (seed only)", dataset.MaxLegs))
+	fs.Func("traffic", "when seeded transactions were created: business (weekday business hours, the default) or flat (seed only)", func(value string) error {
+		switch value {
+		case "business", "flat":
+			opts.seeding.Traffic.Flat = value == "flat"
+			return nil
+		}
+		return fmt.Errorf("traffic must be business or flat, not %q", value)
+	})
+	fs.Func("spike", "a day of heavier seeded traffic as <days ago>:<factor>, such as 7:5; repeat for each (seed only)", func(value string) error {
+		spike, err := dataset.ParseSpike(value)
+		opts.seeding.Traffic.Spikes = append(opts.seeding.Traffic.Spikes, spike)
+		return err
+	})
+	fs.IntVar(&opts.seeding.Workers, "seed-workers", dataset.DefaultWorkers, "batch writes DynamoDB seeding keeps in flight (seed only)")
+	fs.StringVar(&opts.seeding.Method, "seed-method", "copy", "how PostgreSQL seeding loads rows: copy or insert (seed only)")
+	fs.StringVar(&opts.snapshot, "snapshot", "", "snapshot file (default benchmarks/snapshots/<db file>)")
+	fs.BoolVar(&opts.restore, "restore", false, "restore the snapshot before each suite (run only)")
+	fs.StringVar(&opts.config, "config", "", "YAML or JSON matrix of suite runs (run only)")
+	fs.IntVar(&opts.runs, "runs", 1, "run the suites this many times and report the spread across runs (run only)")
+	fs.BoolVar(&opts.selfCheck, "self-check", false, "run every suite at 10 ops per test and check each result, exiting non-zero on any problem (run only)")
+	fs.Func("slo", "SLO every matching test must meet, such as 'point_read.p99 < 10ms'; repeat for each (run, daemon and experiment)", func(value string) error {
+		opts.slos = append(opts.slos, value)
+		return nil
+	})
+	fs.StringVar(&opts.format, "format", "markdown", "comparison format: markdown|html (report compare only)")
+	fs.DurationVar(&opts.every, "every", 0, "how often the daemon runs the suites, e.g. 6h (daemon only)")
+	fs.StringVar(&opts.addr, "addr", "localhost:8080", "address to serve the results web UI, or a worker's /run endpoint, on (serve and worker only)")
+	fs.Func("workers", "comma-separated host:port of each benchctl worker (coordinate only)", func(value string) error {
+		opts.workers = nil
+		for _, addr := range strings.Split(value, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				opts.workers = append(opts.workers, addr)
+			}
+		}
+		return nil
+	})
+	fs.StringVar(&opts.out, "out", "", "file to write the comparison, charts, diff or experiment report to (default stdout; report compare, charts and diff, and experiment only)")
+	fs.StringVar(&opts.baseline, "baseline", "", "result file to diff against (report diff only)")
+	fs.StringVar(&opts.current, "current", "", "result file to check for regressions (report diff only)")
+	fs.Float64Var(&opts.threshold, "threshold", 10, "percent a test's P95 may rise or its ops/sec fall before it counts as a regression (report diff only)")
+	fs.StringVar(&opts.logFormat, "log-format", "text", "log format: text|json")
+	fs.TextVar(&opts.logLevel, "log-level", slog.LevelInfo, "lowest level logged: debug|info|warn|error")
+	fs.BoolVar(&opts.quiet, "quiet", false, "log only errors, leaving the result summaries")
+	connection.RegisterFlags(fs)
+	fs.StringVar(&opts.resultsDir, "results-dir", "", "directory for result files (default $BENCH_RESULTS_DIR or benchmarks/results)")
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return opts, nil, err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	opts.parameters = make(map[string]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		// The DSN can carry a password.
+		if f.Name != "pg-dsn" {
+			opts.parameters[f.Name] = f.Value.String()
+		}
+	})
+
+	// A matrix names the database per run, so --db is only a default there.
+	// A comparison reads both databases' results, and charts and the
+	// self-check cover both unless --db picks one. A diff reads the two
+	// files it is given, and serve every result file. An experiment file
+	// may name its database, and a worker is told it with each suite.
+	matrixRun := (command == "run" || command == "daemon") && opts.config != "" || command == "experiment"
+	selfCheckRun := command == "run" && opts.selfCheck
+	serveRun := command == "serve" || command == "worker"
+	anyDBReport := command == "report" && len(positional) > 0 &&
+		(positional[0] == "compare" || positional[0] == "charts" || positional[0] == "diff")
+	if _, ok := databases[opts.db]; !ok && !((matrixRun || selfCheckRun || anyDBReport || serveRun) && opts.db == "") {
+		return opts, nil, fmt.Errorf("%s: --db must be postgres or dynamodb", command)
+	}
+
+	if opts.runs < 1 {
+		return opts, nil, fmt.Errorf("%s: --runs must be at least 1", command)
+	}
+
+	if opts.resultsDir != "" {
+		os.Setenv("BENCH_RESULTS_DIR", opts.resultsDir)
+	}
+	return opts, positional, nil
+}
+
+// report prints a summary of every result file saved for the database.
+func report(opts options) error {
+	dir := benchmark.ResultsDir()
+	files, err := filepath.Glob(filepath.Join(dir, opts.db+"-*-results.json"))
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no %s results in %s; run `benchctl run <suite> --db=%s` first", opts.db, dir, opts.db)
+	}
+
+	for _, file := range files {
+		suite, err := readSuite(file)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\n##### %s #####\n", filepath.Base(file))
+		benchmark.PrintSummary(suite)
+	}
+	return nil
+}
+
+// readSuite reads a saved result file.
+func readSuite(file string) (benchmark.Suite, error) {
+	var suite benchmark.Suite
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return suite, err
+	}
+	if err := json.Unmarshal(data, &suite); err != nil {
+		return suite, fmt.Errorf("%s: %w", file, err)
+	}
+	return suite, nil
+}
+
+// sloMissedExit is benchctl's exit status when a run missed an SLO, apart
+// from the 1 of a failed test so a pipeline can tell the two apart.
+const sloMissedExit = 3
+
+// finishRun stops the dashboard and writes out the operation trace, if
+// there are any, and prints the SLO checks. Once every suite has had its
+// turn it exits 1 if any test panicked and was recorded as failed, or
+// sloMissedExit if any result missed an SLO.
+func finishRun() {
+	benchmark.StopDashboard()
+	if err := benchmark.CloseTrace(); err != nil {
+		slog.Error("Failed to write the operation trace", "err", err)
+	}
+	missed := reportSLOs()
+	if n := benchmark.Failures(); n > 0 {
+		slog.Error("Tests failed; see the failed entries in the results", "tests", n)
+		os.Exit(1)
+	}
+	if missed > 0 {
+		os.Exit(sloMissedExit)
+	}
+}
+
+// reportSLOs prints the SLOs checked against the results saved since the
+// last report, if any were set, and returns how many checks missed.
+func reportSLOs() int {
+	checks := benchmark.CheckSLOs()
+	if checks == nil {
+		return 0
+	}
+	if err := benchmark.WriteSLOs(os.Stdout, checks); err != nil {
+		slog.Error("Failed to print the SLO checks", "err", err)
+	}
+	missed := benchmark.SLOsMissed(checks)
+	if missed > 0 {
+		slog.Error("SLOs missed", "missed", missed, "checks", len(checks))
+	}
+	return missed
+}
+
+// printRepeats prints the spread of every test across the runs of this
+// multi-run.
+func printRepeats(db string) error {
+	saved, err := benchmark.LoadResults(db)
+	if err != nil {
+		return err
+	}
+	var runs []benchmark.Result
+	for _, r := range saved {
+		if r.RepeatRunID == benchmark.RunID {
+			runs = append(runs, r)
+		}
+	}
+	fmt.Print("\n=== Across Runs ===\n\n")
+	return benchmark.WriteRepeats(os.Stdout, benchmark.Repeats(runs))
+}
+
+// compare writes a side-by-side table of the tests saved for both
+// databases.
+func compare(opts options) error {
+	postgresResults, err := benchmark.LoadResults("postgres")
+	if err != nil {
+		return err
+	}
+	dynamodbResults, err := benchmark.LoadResults("dynamodb")
+	if err != nil {
+		return err
+	}
+	comparisons := benchmark.Compare(postgresResults, dynamodbResults)
+	if len(comparisons) == 0 {
+		return fmt.Errorf("no test in %s has results for both databases; run the same suites with --db=postgres and --db=dynamodb first", benchmark.ResultsDir())
+	}
+
+	write := benchmark.WriteMarkdown
+	switch opts.format {
+	case "markdown", "md":
+	case "html":
+		write = benchmark.WriteHTML
+	default:
+		return fmt.Errorf("unknown format %q (want markdown or html)", opts.format)
+	}
+
+	err = writeOut(opts.out, func(w io.Writer) error { return write(w, comparisons) })
+	if err == nil && opts.out != "" {
+		slog.Info("Compared tests", "tests", len(comparisons), "out", opts.out)
+	}
+	return err
+}
+
+// chartResults writes an HTML page charting the results saved for the
+// database, or for both.
+func chartResults(opts options) error {
+	names := []string{"postgres", "dynamodb"}
+	if opts.db != "" {
+		names = []string{opts.db}
+	}
+	var results []benchmark.Result
+	for _, name := range names {
+		saved, err := benchmark.LoadResults(name)
+		if err != nil {
+			return err
+		}
+		results = append(results, saved...)
+	}
+	if len(results) == 0 {
+		return fmt.Errorf("no results in %s; run some suites first", benchmark.ResultsDir())
+	}
+
+	err := writeOut(opts.out, func(w io.Writer) error { return benchmark.WriteCharts(w, results) })
+	if err == nil && opts.out != "" {
+		slog.Info("Charted results", "results", len(results), "out", opts.out)
+	}
+	return err
+}
+
+// diff writes the changes between the --baseline and --current result
+// files and reports whether any test regressed beyond --threshold.
+func diff(opts options) (bool, error) {
+	if opts.baseline == "" || opts.current == "" {
+		return false, fmt.Errorf("report diff needs --baseline and --current result files")
+	}
+	if opts.threshold < 0 {
+		return false, fmt.Errorf("--threshold must not be negative, got %g", opts.threshold)
+	}
+	baseline, err := readSuite(opts.baseline)
+	if err != nil {
+		return false, err
+	}
+	current, err := readSuite(opts.current)
+	if err != nil {
+		return false, err
+	}
+
+	threshold := opts.threshold / 100
+	changes := benchmark.Diff(baseline.Results, current.Results, threshold)
+	if len(changes) == 0 {
+		return false, fmt.Errorf("%s has no complete results to diff against", opts.baseline)
+	}
+	if err := writeOut(opts.out, func(w io.Writer) error { return benchmark.WriteDiff(w, changes, threshold) }); err != nil {
+		return false, err
+	}
+	regressions := benchmark.Regressions(changes)
+	if regressions > 0 {
+		slog.Warn("Tests regressed", "regressed", regressions, "tests", len(changes),
+			"threshold_percent", opts.threshold)
+	}
+	return regressions > 0, nil
+}
+
+// writeOut writes to the file at path, or to stdout if path is empty.
+func writeOut(path string, write func(io.Writer) error) error {
+	if path == "" {
+		return write(os.Stdout)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// analyze prints the operation mix of a recorded trace and the suites that
+// best reproduce it.
+func analyze(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	ops, err := trace.Read(f)
+	if err != nil {
+		return err
+	}
+	if len(ops) == 0 {
+		return fmt.Errorf("%s has no operations", path)
+	}
+	p := trace.Analyze(ops)
+
+	fmt.Print("\n=== Trace Profile ===\n\n")
+	fmt.Printf("Operations: %d over %v\n", p.Operations, p.Duration)
+	fmt.Printf("Read/Write: %.1f%% reads, %.1f%% writes\n", p.ReadRatio*100, (1-p.ReadRatio)*100)
+	fmt.Printf("Key Skew: %d distinct keys, top 1%% take %.1f%% of ops, hottest key %s takes %.1f%%\n",
+		p.DistinctKeys, p.TopKeyShare*100, p.HottestKey, p.HottestKeyShare*100)
+	fmt.Printf("Burstiness: %.2f ops/sec mean, %.0f ops/sec peak second (%.1fx)\n", p.MeanRate, p.PeakRate, p.Burstiness)
+	if p.AvgBytes > 0 {
+		fmt.Printf("Item Size: %d bytes avg, %d bytes P99, %d bytes max\n", p.AvgBytes, p.P99Bytes, p.MaxBytes)
+		if p.MaxBytes > 400*1024 {
+			fmt.Println("  ⚠️  Items over 400 KB exceed the DynamoDB item size limit")
+		}
+	}
+
+	fmt.Print("\nClosest suites:\n")
+	for i, match := range trace.Suggest(p)[:3] {
+		fmt.Printf("  %d. %-12s %s (distance %.2f)\n", i+1, match.Preset.Suite, match.Preset.Description, match.Distance)
+	}
+	return nil
+}
+
+// withParameters returns a copy of parameters with overrides applied.
+func withParameters(parameters, overrides map[string]string) map[string]string {
+	out := make(map[string]string, len(parameters)+len(overrides))
+	for name, value := range parameters {
+		out[name] = value
+	}
+	for name, value := range overrides {
+		out[name] = value
+	}
+	return out
+}
+
+func suiteNames(db database) []string {
+	names := make([]string, 0, len(db.suites))
+	for name := range db.suites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: benchctl <command> --db=postgres|dynamodb [flags]
+
+Commands:
+  init            Apply the PostgreSQL migrations, or create the DynamoDB table and its GSIs
+  seed            Load merchants, users, accounts, transactions and exchange
+                  rates (-merchants, -users, -accounts and -transactions set
+                  how many)
+  run <suite>...  Run one or more benchmark suites
+  run --config=f  Run every suite listed in a YAML or JSON matrix file
+  run --self-check [suite...]
+                  Run every suite (or those named) at 10 ops per test on --db, or both,
+                  and check each result; exits non-zero on any problem
+  report          Summarize saved results
+  report compare  Side-by-side Markdown or HTML table of both databases' saved results
+  report charts   HTML page charting saved results for --db, or both databases
+  report diff     Compare --current with --baseline; exits non-zero if any test regressed
+  clean           Remove all benchmark data, leaving empty tables
+  cleanup         Remove rows benchmark runs wrote, keeping the seed (--run=<id> for one run)
+  preflight       Check the database is reachable, has its schema and indexes, and is
+                  seeded; exits non-zero with how to fix each failed check
+  drift           Count rows benchmark runs have added to the seeded dataset
+  snapshot        Save the dataset (after seed) to a snapshot file
+  restore         Replace the dataset with the snapshot
+  analyze <file>  Profile a JSON Lines workload trace and suggest matching suites
+  audit           Check the declared access patterns against both schemas' indexes
+  recover <file>  Save the results journaled by a suite that did not finish
+  daemon <suite>... --every=6h
+                  Run the suites, or a --config matrix, on a schedule, recording every
+                  round in the results history and serving trends on -metrics
+  experiment <file>
+                  Run the suites of a YAML or JSON experiment under every combination of
+                  its factors' levels and print a factorial comparison report
+  serve           Serve a web UI over the results: runs, per-run charts and the comparison
+  worker          Serve as a load generator on --addr, running the suites a coordinator sends
+  coordinate <suite>... --workers=host:port,...
+                  Run the suites on every worker at once and merge their histograms and
+                  counts into one <db>-<suite>-distributed result set
+
+Suites:
+  postgres: %s
+  dynamodb: %s
+
+Run flags:
+  -ops           Operations per test; total items for batch tests
+  -concurrency   Comma-separated worker counts for concurrent tests and ramps
+  -batch-size    Comma-separated batch sizes for batch tests
+  -limit         Row limit for range and history queries
+  -step          How long each step of a timed ramp is sustained, e.g. 30s
+  -soak          How long the soak suite runs, e.g. 8h (default 2h)
+  -snapshot-every
+                How often the soak suite snapshots its results (default 10m)
+  -keys          Key distribution: uniform, zipf, hotspot, latest or hotkey (default uniform)
+  -warmup        Unmeasured warm-up per read/write test: op count or duration
+  -ids           Test IDs from the seeder's ID file (file), a database sample (db) or either (auto)
+  -stratify      Sample test IDs evenly across age, activity and size quartiles
+  -rate          Open-loop target ops/sec for concurrent tests (default closed-loop)
+  -think         Pause between each closed-loop worker's operations, 50ms or exp:50ms (default none)
+  -seed          Seed random keys, amounts and operation choices to repeat a run (default unseeded)
+  -workload      YCSB workloads for the ycsb suite, e.g. B or A,F (default A to F)
+  -replay=f      JSON Lines trace the replay suite plays back, keeping its timing
+  -speed         Replay the trace this many times faster than recorded (default 1)
+  -isolation     PostgreSQL isolation: default, read-committed, repeatable-read or serializable
+  -consistency   DynamoDB reads, eventual or strong (default eventual)
+  -billing       DynamoDB table created provisioned, as in schema.json, or on-demand (default provisioned)
+  -pool          pooled, or unpooled for a new connection per operation (default pooled)
+  -op-timeout    Deadline for each statement or API call, e.g. 2s (default none)
+  -max-runtime   Time limit for each suite, e.g. 2h; later tests are recorded as not run (default none)
+  -retries       Retries of transient errors in concurrent tests, with jittered backoff (default none)
+  -retry-base    Backoff before the first retry, doubling after (default 10ms)
+  -retry-max     Longest backoff between retries (default 1s)
+  -phases        Break average latency into acquire/execute/scan or marshal/http/unmarshal
+  -trace=f.csv   Write every statement or API call to a CSV file (run only)
+  -dashboard     Show live throughput, P99, errors, ETA and workers in the terminal (run only)
+  -metrics=:9464 Serve Prometheus metrics at /metrics while the suites run (run only)
+  -runs          Run the suites N times; reports mean, stddev and 95%% CI per test (default 1)
+  -config        Matrix file; its runs override the flags above
+  -slo           SLO such as 'point_read.p99 < 10ms', repeatable; a miss exits 3
+  -cleanup       Remove the rows this run wrote once its suites finish
+  -restore       Restore the snapshot before each suite
+  -snapshot      Snapshot file for snapshot, restore and -restore
+  -merchants     Merchants to seed (seed only, default 1000)
+  -accounts      Accounts to seed (seed only, default 10000)
+  -users         Users owning the seeded accounts (seed only, default two per
+                five accounts)
+  -transactions  Transactions to seed (seed only, default 100000)
+  -status-mix    Weight of each seeded transaction status (seed only,
+                default pending=5,completed=90,failed=3,reversed=2)
+  -seed-skew     Zipf exponent for seeded merchant and account activity, 0 for
+                uniform (seed only, default 1)
+  -max-legs      Most legs a seeded transaction has, 2 to 24 (seed only, default 4)
+  -inject-anomalies
+                Seed this many unbalanced transactions, orphan legs and
+                duplicate idempotency keys each (seed only, default 0)
+  -traffic       When seeded transactions were created: business or flat
+                (seed only, default business)
+  -spike         Multiply one seeded day's traffic, as <days ago>:<factor>,
+                such as 7:5; repeatable (seed only)
+  -seed-workers  Batch writes DynamoDB seeding keeps in flight (seed only, default 16)
+  -seed-method   How PostgreSQL seeding loads rows: copy or insert (seed only, default copy)
+  -every         Interval between daemon rounds, e.g. 6h (daemon only)
+
+Report flags:
+  -format        markdown or html (report compare; default markdown)
+  -out           File to write the comparison, charts, diff or experiment report to (default stdout)
+  -baseline      Result file to diff against (report diff)
+  -current       Result file to check for regressions (report diff)
+  -threshold     Percent P95 may rise or ops/sec fall before a test regresses (default 10)
+  -addr          Address for serve and worker (default localhost:8080)
+  -workers       Comma-separated worker addresses (coordinate)
+
+Flags:
+  -pg-dsn        PostgreSQL connection string (env BENCH_PG_DSN)
+  -pg-driver     pq or pgx (env BENCH_PG_DRIVER, default pq)
+  -pg-sslmode    disable, require, verify-ca or verify-full, overriding the DSN's (env BENCH_PG_SSLMODE)
+  -pg-sslrootcert
+                 PEM root certificates to verify the server with, such as the RDS bundle
+                 (env BENCH_PG_SSLROOTCERT)
+  -pg-iam        Authenticate to RDS or Aurora with IAM tokens (env BENCH_PG_IAM=true)
+  -pg-region     Region IAM tokens are signed for (env BENCH_PG_REGION, default the host name's)
+  -pg-max-open   Most open PostgreSQL connections (env BENCH_PG_MAX_OPEN, default 100, 0 for none)
+  -pg-max-idle   Idle PostgreSQL connections kept for reuse (env BENCH_PG_MAX_IDLE, default 10)
+  -pg-conn-lifetime
+                 Close PostgreSQL connections this old, e.g. 5m (env BENCH_PG_CONN_LIFETIME)
+  -ddb-endpoint  DynamoDB endpoint URL, empty for AWS (env BENCH_DDB_ENDPOINT, default %s)
+  -ddb-region    DynamoDB region (env BENCH_DDB_REGION, default %s)
+  -ddb-table     DynamoDB table name (env BENCH_DDB_TABLE, default %s)
+  -aws           Target real DynamoDB in -ddb-region, as an empty -ddb-endpoint does
+  -aws-profile   AWS shared config profile for real DynamoDB (env BENCH_AWS_PROFILE)
+  -ddb-max-idle  Idle DynamoDB HTTP connections kept for reuse (env BENCH_DDB_MAX_IDLE)
+  -ddb-max-conns Most open DynamoDB HTTP connections (env BENCH_DDB_MAX_CONNS, default none)
+  -ddb-timeout   DynamoDB HTTP request timeout (env BENCH_DDB_TIMEOUT)
+  -ddb-idle-timeout
+                 Close idle DynamoDB HTTP connections after this (env BENCH_DDB_IDLE_TIMEOUT)
+  -confirm-table Name of the AWS table; required by commands that write to it
+  -max-cost      USD a command's DynamoDB requests may cost on AWS, at on-demand
+                 prices, before it stops and saves partial results (default 10, 0 for none)
+  -results-dir   Directory for result files (env BENCH_RESULTS_DIR)
+  -log-format    text or json log lines on stderr (default text)
+  -log-level     debug, info, warn or error (default info; debug adds seeding progress)
+  -quiet         Log only errors; result summaries still print to stdout
+`, strings.Join(suiteNames(databases["postgres"]), ", "), strings.Join(suiteNames(databases["dynamodb"]), ", "),
+		connection.DynamoDBEndpoint, connection.DynamoDBRegion, connection.DynamoDBTable)
+}
+
+// awsWrites are the commands that write to or delete from the DynamoDB
+// table.
+var awsWrites = []string{"init", "seed", "run", "daemon", "experiment", "worker", "clean", "cleanup", "restore"}
+
+// guardAWS caps what the command's requests to DynamoDB on AWS may cost at
+// --max-cost, and keeps it from writing to a table it was not pointed at on
+// purpose: one that writes must name the table in --confirm-table.
+func guardAWS(command string, opts options) {
+	if opts.maxCost < 0 {
+		benchmark.Fatal("--max-cost must not be negative", "max_cost", opts.maxCost)
+	}
+	benchmark.SetCostCeiling(opts.maxCost)
+	if !slices.Contains(awsWrites, command) {
+		return
+	}
+	if opts.confirmTable != connection.DynamoDBTable {
+		benchmark.Fatal("Refusing to write to a DynamoDB table on AWS without --confirm-table naming it",
+			"table", connection.DynamoDBTable, "target", connection.DynamoDBTarget(), "confirm_table", opts.confirmTable)
+	}
+	slog.Info("Writing to DynamoDB on AWS", "table", connection.DynamoDBTable, "target", connection.DynamoDBTarget(), "max_cost_usd", opts.maxCost)
+}
+
+// snapshotPath is the snapshot file for the named database: --snapshot if
+// given, otherwise its default under benchmarks/snapshots.
+func snapshotPath(opts options, db string) string {
+	if opts.snapshot != "" {
+		return opts.snapshot
+	}
+	return filepath.Join("benchmarks", "snapshots", databases[db].snapshotFile)
+}
+
+// restore resets the named database from its snapshot, along with the
+// seeded-ID file saved with it.
+func restore(opts options, db string) {
+	databases[db].restore(snapshotPath(opts, db))
+	keepIDs(opts, db, false)
+}
+
+// keepIDs copies the seeder's ID file next to the snapshot (save) or back
+// from it, so suites sample IDs that exist in the restored dataset even if
+// the database was re-seeded after the snapshot was taken.
+func keepIDs(opts options, db string, save bool) {
+	live, kept := benchmark.IDsPath(db), snapshotPath(opts, db)+".ids.json"
+	from, to := kept, live
+	if save {
+		from, to = live, kept
+	}
+
+	data, err := os.ReadFile(from)
+	if os.IsNotExist(err) {
+		if !save {
+			// An older snapshot without IDs: fall back to sampling.
+			benchmark.RemoveIDs(db)
+		}
+		return
+	}
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(to), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(to, data, 0644)
+	}
+	if err != nil {
+		slog.Error("Failed to copy seeded IDs", "to", to, "err", err)
+	}
+}
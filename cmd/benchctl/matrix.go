@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+)
+
+// matrix is a declarative list of suite runs, loaded from YAML or JSON:
+//
+//	db: postgres
+//	runs:
+//	  - suite: writes
+//	    concurrency: [1, 10, 25, 50, 100, 200]
+//	  - suite: reads
+//	    ops: 5000
+//	    warmup: 30s
+//	    keys: zipf
+//	    label: zipf
+//	  - suite: writes
+//	    rate: 500
+//	    label: rate-500
+//	  - db: dynamodb
+//	    suite: reads
+//	    batch_size: [10, 25, 100]
+//	slos:
+//	  - point_read.p99 < 10ms
+//	  - double_entry.ops >= 500
+//
+// Fields left out of a run fall back to the matrix-level value, then to the
+// command-line flags. The SLOs are checked, with any --slo flags, against
+// every result the runs save (see benchmark.ParseSLO).
+type matrix struct {
+	DB     string      `json:"db" yaml:"db"`
+	Keys   string      `json:"keys" yaml:"keys"`
+	Warmup string      `json:"warmup" yaml:"warmup"`
+	Rate   float64     `json:"rate" yaml:"rate"`
+	Runs   []matrixRun `json:"runs" yaml:"runs"`
+	SLOs   []string    `json:"slos" yaml:"slos"`
+}
+
+// matrixRun is one suite invocation in a matrix.
+type matrixRun struct {
+	DB          string  `json:"db" yaml:"db"`
+	Suite       string  `json:"suite" yaml:"suite"`
+	Ops         int     `json:"ops" yaml:"ops"`
+	Concurrency []int   `json:"concurrency" yaml:"concurrency"`
+	BatchSize   []int   `json:"batch_size" yaml:"batch_size"`
+	Limit       int     `json:"limit" yaml:"limit"`
+	Keys        string  `json:"keys" yaml:"keys"`
+	Warmup      string  `json:"warmup" yaml:"warmup"`
+	Rate        float64 `json:"rate" yaml:"rate"`
+	// Label is appended to the saved result name, so the same suite can
+	// appear more than once without overwriting its results.
+	Label string `json:"label" yaml:"label"`
+}
+
+// loadMatrix reads a matrix file, choosing the format by extension, and
+// fills in every run's defaults from the matrix and the flags.
+func loadMatrix(path string, opts options) (matrix, error) {
+	var m matrix
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return m, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, &m)
+	default:
+		decoder := json.NewDecoder(strings.NewReader(string(data)))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&m)
+	}
+	if err != nil {
+		return m, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(m.Runs) == 0 {
+		return m, fmt.Errorf("%s: no runs", path)
+	}
+	for _, spec := range m.SLOs {
+		if _, err := benchmark.ParseSLO(spec); err != nil {
+			return m, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	for i := range m.Runs {
+		run := &m.Runs[i]
+		run.DB = firstNonEmpty(run.DB, m.DB, opts.db)
+		run.Keys = firstNonEmpty(run.Keys, m.Keys, opts.keys)
+		run.Warmup = firstNonEmpty(run.Warmup, m.Warmup, opts.warmup)
+		if run.Rate == 0 {
+			run.Rate = m.Rate
+		}
+		if run.Rate == 0 {
+			run.Rate = opts.rate
+		}
+		if run.Rate < 0 {
+			return m, fmt.Errorf("%s: run %d: rate must not be negative", path, i+1)
+		}
+
+		db, ok := databases[run.DB]
+		if !ok {
+			return m, fmt.Errorf("%s: run %d: db must be postgres or dynamodb", path, i+1)
+		}
+		if _, ok := db.suites[run.Suite]; !ok {
+			return m, fmt.Errorf("%s: run %d: unknown %s suite %q (available: %s)",
+				path, i+1, run.DB, run.Suite, strings.Join(suiteNames(db), ", "))
+		}
+		if err := benchmark.SetKeyDistribution(run.Keys); err != nil {
+			return m, fmt.Errorf("%s: run %d: %w", path, i+1, err)
+		}
+		if err := benchmark.SetWarmup(run.Warmup); err != nil {
+			return m, fmt.Errorf("%s: run %d: %w", path, i+1, err)
+		}
+
+		if run.Ops == 0 {
+			run.Ops = opts.scale.Operations
+		}
+		if len(run.Concurrency) == 0 {
+			run.Concurrency = opts.scale.Concurrency
+		}
+		if len(run.BatchSize) == 0 {
+			run.BatchSize = opts.scale.BatchSize
+		}
+		if run.Limit == 0 {
+			run.Limit = opts.scale.Limit
+		}
+	}
+	return m, nil
+}
+
+// runMatrix runs every suite in the matrix in order, restoring each run's
+// database from its snapshot first when --restore is set.
+func runMatrix(m matrix, opts options) {
+	for i, run := range m.Runs {
+		slog.Info("Matrix run", "run", i+1, "of", len(m.Runs), "suite", run.Suite, "db", run.DB)
+
+		if err := benchmark.SetKeyDistribution(run.Keys); err != nil {
+			benchmark.Fatal("Invalid matrix run", "err", err)
+		}
+		if err := benchmark.SetWarmup(run.Warmup); err != nil {
+			benchmark.Fatal("Invalid matrix run", "err", err)
+		}
+		if opts.restore {
+			restore(opts, run.DB)
+		}
+		benchmark.SetRate(run.Rate)
+		benchmark.Label = run.Label
+		benchmark.SetParameters(withParameters(opts.parameters, map[string]string{
+			"db":          run.DB,
+			"suite":       run.Suite,
+			"ops":         strconv.Itoa(run.Ops),
+			"concurrency": (*intList)(&run.Concurrency).String(),
+			"batch-size":  (*intList)(&run.BatchSize).String(),
+			"limit":       strconv.Itoa(run.Limit),
+			"keys":        run.Keys,
+			"warmup":      run.Warmup,
+			"rate":        strconv.FormatFloat(run.Rate, 'g', -1, 64),
+			"label":       run.Label,
+		}))
+
+		databases[run.DB].suites[run.Suite](benchmark.Options{
+			Operations:  run.Ops,
+			Concurrency: run.Concurrency,
+			BatchSize:   run.BatchSize,
+			Limit:       run.Limit,
+			// Step and soak durations come from the flags alone.
+			StepDuration:     opts.scale.StepDuration,
+			SoakDuration:     opts.scale.SoakDuration,
+			SnapshotInterval: opts.scale.SnapshotInterval,
+		})
+	}
+	benchmark.SetRate(0)
+	benchmark.Label = ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
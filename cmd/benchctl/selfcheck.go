@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/trace"
+)
+
+// selfCheckScale is the size of every test in a self-check: enough
+// operations to fill in each result, few enough that every suite finishes
+// in minutes, with timed ramp steps cut to a second and soaks to two
+// one-second snapshots.
+var selfCheckScale = benchmark.Options{
+	Operations: 10, Concurrency: []int{2}, BatchSize: []int{5}, Limit: 10,
+	StepDuration: time.Second, SoakDuration: 2 * time.Second, SnapshotInterval: time.Second,
+}
+
+// selfCheck runs the named suites, or every suite, on each database at
+// selfCheckScale and checks every result they save (see benchmark.Check),
+// so a broken test shows up before a multi-hour run. Results go to a
+// scratch directory through the file sink only, and the rows the run wrote
+// are cleaned up afterwards. It reports whether every suite passed.
+func selfCheck(opts options, names []string) bool {
+	dir, err := os.MkdirTemp("", "benchctl-self-check-")
+	if err != nil {
+		benchmark.Fatal("Failed to create the self-check results directory", "err", err)
+	}
+	os.Setenv("BENCH_RESULTS_DIR", dir)
+	os.Setenv("BENCH_SINKS", "file")
+	if err := benchmark.SetWarmup(""); err != nil {
+		benchmark.Fatal("Failed to configure the self-check", "err", err)
+	}
+	benchmark.SetRate(0)
+	benchmark.SetThinkTime("")
+	benchmark.UseReplayTrace(selfCheckTrace(), 1)
+
+	dbNames := []string{"postgres", "dynamodb"}
+	if opts.db != "" {
+		dbNames = []string{opts.db}
+	}
+
+	checked := make(map[string]bool)
+	var report []string
+	passed := true
+	for _, dbName := range dbNames {
+		db := databases[dbName]
+		suites := names
+		if len(suites) == 0 {
+			suites = suiteNames(db)
+		}
+		for _, name := range suites {
+			suite, ok := db.suites[name]
+			if !ok {
+				benchmark.Fatal("Unknown suite", "db", dbName, "suite", name,
+					"available", strings.Join(suiteNames(db), ", "))
+			}
+			slog.Info("Self-check", "db", dbName, "suite", name)
+			benchmark.SetParameters(withParameters(opts.parameters, map[string]string{
+				"suite": name, "db": dbName, "self-check": "true", "ops": strconv.Itoa(selfCheckScale.Operations),
+			}))
+			suite(selfCheckScale)
+
+			results, problems := checkSaved(dir, checked)
+			if results == 0 {
+				problems = append(problems, "saved no results")
+			}
+			passed = passed && len(problems) == 0
+			status := fmt.Sprintf("ok (%d results)", results)
+			if len(problems) > 0 {
+				status = "FAILED"
+			}
+			report = append(report, fmt.Sprintf("%-8s %-15s %s", dbName, name, status))
+			for _, p := range problems {
+				report = append(report, "    "+p)
+			}
+			if benchmark.Stopping() {
+				break
+			}
+		}
+		db.cleanup(benchmark.RunID)
+	}
+
+	fmt.Print("\n=== Self-Check ===\n\n")
+	for _, line := range report {
+		fmt.Println(line)
+	}
+	fmt.Println()
+	if passed {
+		os.RemoveAll(dir)
+	} else {
+		slog.Error("Self-check failed; the results it checked are kept", "dir", dir)
+	}
+	return passed && !benchmark.Stopping()
+}
+
+// selfCheckTrace is the trace the replay suites play back in a
+// self-check, whatever --replay says: a write after every read, a tenth of
+// a second apart, over a few accounts.
+func selfCheckTrace() []trace.Op {
+	start := time.Now()
+	ops := make([]trace.Op, selfCheckScale.Operations)
+	for i := range ops {
+		ops[i] = trace.Op{Time: start.Add(time.Duration(i) * 100 * time.Millisecond), Kind: "read", Key: fmt.Sprintf("ACCOUNT#%d", i%3)}
+		if i%2 == 1 {
+			ops[i].Kind = "write"
+		}
+	}
+	return ops
+}
+
+// checkSaved checks the results in every result file in dir not checked
+// before, returning how many there were and what is wrong with them.
+func checkSaved(dir string, checked map[string]bool) (int, []string) {
+	files, err := filepath.Glob(filepath.Join(dir, "*-results.json"))
+	if err != nil {
+		return 0, []string{err.Error()}
+	}
+
+	count := 0
+	var problems []string
+	for _, file := range files {
+		if checked[file] {
+			continue
+		}
+		checked[file] = true
+
+		suite, err := readSuite(file)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		for _, result := range suite.Results {
+			count++
+			for _, p := range benchmark.Check(result) {
+				problems = append(problems, fmt.Sprintf("%s: %s", result.TestName, p))
+			}
+		}
+	}
+	return count, problems
+}
@@ -0,0 +1,153 @@
+// Package access declares the access patterns the benchmarks exercise and
+// audits both schemas against them: each pattern must be served by an index
+// in benchmarks/postgres/schema.sql and by the base table or a GSI in
+// benchmarks/dynamodb/schema.json, with the DynamoDB items actually written
+// under matching keys. Uncovered patterns come with the index or GSI
+// definition that would cover them.
+package access
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// Pattern is one way the benchmarks look data up, described for each
+// database.
+type Pattern struct {
+	Name     string
+	Postgres PostgresKey
+	DynamoDB DynamoDBKey
+}
+
+// PostgresKey is a lookup by equality on Equal, optionally ordered by Order.
+type PostgresKey struct {
+	Table      string
+	Equal      []string
+	Order      string
+	Descending bool
+}
+
+// DynamoDBKey is a Query on items of type Entity (their Type attribute)
+// whose partition key starts with Partition and, when Sort is set, whose
+// sort key starts with Sort.
+type DynamoDBKey struct {
+	Entity    string
+	Partition string
+	Sort      string
+}
+
+// Patterns are the declared access patterns. Add one here when a benchmark
+// starts relying on a new lookup, and `benchctl audit` will say whether the
+// schemas keep up.
+var Patterns = []Pattern{
+	{
+		Name:     "Transactions by status, newest first",
+		Postgres: PostgresKey{Table: "transactions", Equal: []string{"status"}, Order: "created_at", Descending: true},
+		DynamoDB: DynamoDBKey{Entity: "Transaction", Partition: "STATUS#", Sort: "CREATED#"},
+	},
+	{
+		Name:     "Legs by account, newest first",
+		Postgres: PostgresKey{Table: "transaction_legs", Equal: []string{"account_id"}, Order: "created_at", Descending: true},
+		DynamoDB: DynamoDBKey{Entity: "TransactionLeg", Partition: "ACCOUNT#", Sort: "LEG#"},
+	},
+	{
+		Name:     "Transaction by idempotency key",
+		Postgres: PostgresKey{Table: "transactions", Equal: []string{"idempotency_key"}},
+		DynamoDB: DynamoDBKey{Entity: "Transaction", Partition: "IDEMPOTENCY#"},
+	},
+	{
+		Name:     "Transactions by merchant and date",
+		Postgres: PostgresKey{Table: "transactions", Equal: []string{"merchant_id"}, Order: "created_at", Descending: true},
+		DynamoDB: DynamoDBKey{Entity: "Transaction", Partition: "MERCHANT#", Sort: "CREATED#"},
+	},
+	{
+		Name:     "User profile by ID",
+		Postgres: PostgresKey{Table: "users", Equal: []string{"id"}},
+		DynamoDB: DynamoDBKey{Entity: "User", Partition: "USER#", Sort: "PROFILE"},
+	},
+	{
+		Name:     "Accounts by user",
+		Postgres: PostgresKey{Table: "accounts", Equal: []string{"user_id"}},
+		DynamoDB: DynamoDBKey{Entity: "Account", Partition: "USER#"},
+	},
+	{
+		Name:     "Legs by transaction",
+		Postgres: PostgresKey{Table: "transaction_legs", Equal: []string{"transaction_id"}},
+		DynamoDB: DynamoDBKey{Entity: "TransactionLeg", Partition: "TXN#", Sort: "LEG#"},
+	},
+}
+
+// Coverage is how well a schema serves a pattern.
+type Coverage int
+
+const (
+	// Uncovered patterns need a full scan.
+	Uncovered Coverage = iota
+	// Unordered patterns find their rows through an index but have to
+	// sort or filter them afterwards.
+	Unordered
+	// Covered patterns are answered straight from an index.
+	Covered
+)
+
+func (c Coverage) String() string {
+	switch c {
+	case Covered:
+		return "covered"
+	case Unordered:
+		return "unordered"
+	default:
+		return "UNCOVERED"
+	}
+}
+
+// Finding is the audit of one pattern against one database.
+type Finding struct {
+	Pattern  string
+	Database string
+	Coverage Coverage
+	// Index is what serves the pattern, when something does.
+	Index string
+	// Suggestion is the index or GSI definition that would cover the
+	// pattern, when it isn't.
+	Suggestion string
+}
+
+// Audit checks every pattern against the schemas under root, the
+// repository's top directory.
+func Audit(root string) ([]Finding, error) {
+	pg, err := loadPostgresSchema(filepath.Join(root, "benchmarks", "postgres", "schema.sql"))
+	if err != nil {
+		return nil, err
+	}
+	ddb, err := loadDynamoDBSchema(filepath.Join(root, "benchmarks", "dynamodb"))
+	if err != nil {
+		return nil, err
+	}
+
+	findings := make([]Finding, 0, 2*len(Patterns))
+	for _, p := range Patterns {
+		findings = append(findings, pg.audit(p), ddb.audit(p))
+	}
+	return findings, nil
+}
+
+// Print writes findings as a table followed by the suggested definitions.
+func Print(w io.Writer, findings []Finding) {
+	fmt.Fprintf(w, "%-40s %-11s %-10s %s\n", "Access pattern", "Database", "Coverage", "Served by")
+	for _, f := range findings {
+		index := f.Index
+		if index == "" {
+			index = "-"
+		}
+		fmt.Fprintf(w, "%-40s %-11s %-10s %s\n", f.Pattern, f.Database, f.Coverage, index)
+	}
+
+	for _, f := range findings {
+		if f.Suggestion == "" {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s (%s):\n%s\n", f.Pattern, f.Database, f.Suggestion)
+	}
+}
@@ -0,0 +1,276 @@
+package access
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// dynamodbIndex is the base table or a GSI from schema.json.
+type dynamodbIndex struct {
+	name            string
+	partition, sort string
+}
+
+// dynamodbItem is the key attributes an item literal in the dynamodb
+// package sets: attribute name to the constant prefix of its value.
+type dynamodbItem struct {
+	entity string
+	keys   map[string]string
+}
+
+type dynamodbSchema struct {
+	indexes []dynamodbIndex
+	items   []dynamodbItem
+}
+
+// gsiAttribute matches the GSI key attributes the single-table design uses.
+var gsiAttribute = regexp.MustCompile(`^GSI(\d+)(PK|SK)$`)
+
+// loadDynamoDBSchema reads the base table and GSI keys from schema.json in
+// dir, and the keys items are written with from the composite literals in
+// dir's Go files: every struct literal with a Type field is an item of that
+// entity type.
+func loadDynamoDBSchema(dir string) (dynamodbSchema, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "schema.json"))
+	if err != nil {
+		return dynamodbSchema{}, err
+	}
+
+	type keySchema []struct{ AttributeName, KeyType string }
+	var table struct {
+		KeySchema              keySchema
+		GlobalSecondaryIndexes []struct {
+			IndexName string
+			KeySchema keySchema
+		}
+	}
+	if err := json.Unmarshal(data, &table); err != nil {
+		return dynamodbSchema{}, fmt.Errorf("schema.json: %w", err)
+	}
+
+	index := func(name string, keys keySchema) dynamodbIndex {
+		i := dynamodbIndex{name: name}
+		for _, key := range keys {
+			if key.KeyType == "HASH" {
+				i.partition = key.AttributeName
+			} else {
+				i.sort = key.AttributeName
+			}
+		}
+		return i
+	}
+	var schema dynamodbSchema
+	schema.indexes = append(schema.indexes, index("base table", table.KeySchema))
+	for _, gsi := range table.GlobalSecondaryIndexes {
+		schema.indexes = append(schema.indexes, index(gsi.IndexName, gsi.KeySchema))
+	}
+
+	schema.items, err = itemLiterals(dir)
+	return schema, err
+}
+
+// itemLiterals parses the Go files in dir for item literals.
+func itemLiterals(dir string) ([]dynamodbItem, error) {
+	fset := token.NewFileSet()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]*ast.File, 0, len(paths))
+	for _, path := range paths {
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	// Struct fields are mapped to attribute names through their
+	// dynamodbav tags.
+	attributes := make(map[string]map[string]string)
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			spec, ok := n.(*ast.TypeSpec)
+			if !ok {
+				return true
+			}
+			st, ok := spec.Type.(*ast.StructType)
+			if !ok {
+				return true
+			}
+			fields := make(map[string]string)
+			for _, field := range st.Fields.List {
+				for _, name := range field.Names {
+					fields[name.Name] = attributeName(name.Name, field.Tag)
+				}
+			}
+			attributes[spec.Name.Name] = fields
+			return true
+		})
+	}
+
+	var items []dynamodbItem
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			lit, ok := n.(*ast.CompositeLit)
+			if !ok {
+				return true
+			}
+			typeName, ok := lit.Type.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			item := dynamodbItem{keys: make(map[string]string)}
+			for _, elt := range lit.Elts {
+				kv, ok := elt.(*ast.KeyValueExpr)
+				if !ok {
+					continue
+				}
+				field, ok := kv.Key.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				name := field.Name
+				if mapped, ok := attributes[typeName.Name][name]; ok {
+					name = mapped
+				}
+				prefix, ok := constantPrefix(kv.Value)
+				switch {
+				case name == "Type" && ok:
+					item.entity = prefix
+				case (name == "PK" || name == "SK" || gsiAttribute.MatchString(name)) && ok:
+					item.keys[name] = prefix
+				}
+			}
+			if item.entity != "" && len(item.keys) > 0 {
+				items = append(items, item)
+			}
+			return true
+		})
+	}
+	return items, nil
+}
+
+func attributeName(field string, tag *ast.BasicLit) string {
+	if tag == nil {
+		return field
+	}
+	unquoted, err := strconv.Unquote(tag.Value)
+	if err != nil {
+		return field
+	}
+	name, _, _ := strings.Cut(reflect.StructTag(unquoted).Get("dynamodbav"), ",")
+	if name == "" {
+		return field
+	}
+	return name
+}
+
+// constantPrefix is the constant start of a key value: a string literal,
+// the text before the first verb of a fmt.Sprintf format, or the left-most
+// literal of a concatenation.
+func constantPrefix(expr ast.Expr) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.STRING {
+			return "", false
+		}
+		s, err := strconv.Unquote(e.Value)
+		return s, err == nil
+	case *ast.BinaryExpr:
+		if e.Op == token.ADD {
+			return constantPrefix(e.X)
+		}
+	case *ast.CallExpr:
+		if sel, ok := e.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Sprintf" && len(e.Args) > 0 {
+			format, ok := constantPrefix(e.Args[0])
+			if !ok {
+				return "", false
+			}
+			if at := strings.Index(format, "%"); at >= 0 {
+				format = format[:at]
+			}
+			return format, true
+		}
+	}
+	return "", false
+}
+
+// audit looks for an index whose partition key the pattern's items are
+// written under with the pattern's prefix, and whose sort key carries the
+// sort prefix.
+func (s dynamodbSchema) audit(p Pattern) Finding {
+	key := p.DynamoDB
+	finding := Finding{Pattern: p.Name, Database: "DynamoDB"}
+
+	for _, index := range s.indexes {
+		for _, item := range s.items {
+			if item.entity != key.Entity || !strings.HasPrefix(item.keys[index.partition], key.Partition) {
+				continue
+			}
+			if key.Sort == "" || (index.sort != "" && strings.HasPrefix(item.keys[index.sort], key.Sort)) {
+				finding.Coverage = Covered
+				finding.Index = index.name
+				return finding
+			}
+			if finding.Coverage == Uncovered {
+				finding.Coverage = Unordered
+				finding.Index = index.name
+			}
+		}
+	}
+
+	finding.Suggestion = s.createGSI(key)
+	return finding
+}
+
+// createGSI is the GSI definition that covers k, under the next unused GSI
+// number, for schema.json's GlobalSecondaryIndexes, with the key attributes
+// the entity's items then need.
+func (s dynamodbSchema) createGSI(k DynamoDBKey) string {
+	next := 1
+	for _, index := range s.indexes {
+		if m := gsiAttribute.FindStringSubmatch(index.partition); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			next = max(next, n+1)
+		}
+	}
+	for _, item := range s.items {
+		for name := range item.keys {
+			if m := gsiAttribute.FindStringSubmatch(name); m != nil {
+				n, _ := strconv.Atoi(m[1])
+				next = max(next, n+1)
+			}
+		}
+	}
+
+	name := fmt.Sprintf("GSI%d", next)
+	keys := []map[string]string{{"AttributeName": name + "PK", "KeyType": "HASH"}}
+	attributes := []map[string]string{{"AttributeName": name + "PK", "AttributeType": "S"}}
+	write := fmt.Sprintf("%sPK = %s<...>", name, k.Partition)
+	if k.Sort != "" {
+		keys = append(keys, map[string]string{"AttributeName": name + "SK", "KeyType": "RANGE"})
+		attributes = append(attributes, map[string]string{"AttributeName": name + "SK", "AttributeType": "S"})
+		write += fmt.Sprintf(" and %sSK = %s<...>", name, k.Sort)
+	}
+
+	gsi, _ := json.MarshalIndent(map[string]any{
+		"IndexName":             name,
+		"KeySchema":             keys,
+		"Projection":            map[string]string{"ProjectionType": "ALL"},
+		"ProvisionedThroughput": map[string]int{"ReadCapacityUnits": 100, "WriteCapacityUnits": 100},
+	}, "", "  ")
+	defs, _ := json.MarshalIndent(attributes, "", "  ")
+	return fmt.Sprintf("GlobalSecondaryIndexes entry:\n%s\nAttributeDefinitions entries:\n%s\nThen write %s on %s items.",
+		gsi, defs, write, k.Entity)
+}
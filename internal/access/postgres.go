@@ -0,0 +1,135 @@
+package access
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// postgresIndex is an index, primary key or unique constraint in schema.sql.
+type postgresIndex struct {
+	name    string
+	table   string
+	columns []string
+}
+
+type postgresSchema struct {
+	indexes []postgresIndex
+}
+
+var (
+	createTable = regexp.MustCompile(`(?is)CREATE TABLE (\w+) \((.*?)\n\);`)
+	createIndex = regexp.MustCompile(`(?i)CREATE (?:UNIQUE )?INDEX (\w+) ON (\w+)\s*\(([^)]*)\)([^;]*);`)
+	tableKey    = regexp.MustCompile(`(?i)^\s*(PRIMARY KEY|UNIQUE)\s*\(([^)]*)\)`)
+	columnKey   = regexp.MustCompile(`(?i)^\s*(\w+)\s.*\b(PRIMARY KEY|UNIQUE)\b`)
+)
+
+// loadPostgresSchema reads the indexes, primary keys and unique constraints
+// declared in schema.sql. Partial indexes are left out, since they only
+// serve queries repeating their WHERE clause.
+func loadPostgresSchema(path string) (postgresSchema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return postgresSchema{}, err
+	}
+	sql := stripComments(string(data))
+
+	var schema postgresSchema
+	for _, table := range createTable.FindAllStringSubmatch(sql, -1) {
+		for _, line := range strings.Split(table[2], "\n") {
+			if m := tableKey.FindStringSubmatch(line); m != nil {
+				schema.indexes = append(schema.indexes, postgresIndex{
+					name:    fmt.Sprintf("%s %s", table[1], strings.ToLower(m[1])),
+					table:   table[1],
+					columns: splitColumns(m[2]),
+				})
+			} else if m := columnKey.FindStringSubmatch(line); m != nil {
+				schema.indexes = append(schema.indexes, postgresIndex{
+					name:    fmt.Sprintf("%s.%s %s", table[1], m[1], strings.ToLower(m[2])),
+					table:   table[1],
+					columns: []string{m[1]},
+				})
+			}
+		}
+	}
+	for _, m := range createIndex.FindAllStringSubmatch(sql, -1) {
+		if strings.Contains(strings.ToUpper(m[4]), "WHERE") {
+			continue
+		}
+		schema.indexes = append(schema.indexes, postgresIndex{name: m[1], table: m[2], columns: splitColumns(m[3])})
+	}
+	return schema, nil
+}
+
+func stripComments(sql string) string {
+	lines := strings.Split(sql, "\n")
+	for i, line := range lines {
+		if at := strings.Index(line, "--"); at >= 0 {
+			lines[i] = line[:at]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// splitColumns turns "account_id, created_at DESC" into its column names.
+func splitColumns(list string) []string {
+	var columns []string
+	for _, part := range strings.Split(list, ",") {
+		if fields := strings.Fields(part); len(fields) > 0 {
+			columns = append(columns, fields[0])
+		}
+	}
+	return columns
+}
+
+// audit finds the index that serves p best: one leading with p's equality
+// columns, in any order, then its sort column.
+func (s postgresSchema) audit(p Pattern) Finding {
+	key := p.Postgres
+	finding := Finding{Pattern: p.Name, Database: "PostgreSQL"}
+
+	for _, index := range s.indexes {
+		if index.table != key.Table || len(index.columns) < len(key.Equal) {
+			continue
+		}
+		leading := slices.Clone(index.columns[:len(key.Equal)])
+		slices.Sort(leading)
+		equal := slices.Clone(key.Equal)
+		slices.Sort(equal)
+		if !slices.Equal(leading, equal) {
+			continue
+		}
+
+		if key.Order == "" || (len(index.columns) > len(key.Equal) && index.columns[len(key.Equal)] == key.Order) {
+			finding.Coverage = Covered
+			finding.Index = index.name
+			return finding
+		}
+		if finding.Coverage == Uncovered {
+			finding.Coverage = Unordered
+			finding.Index = index.name
+		}
+	}
+
+	finding.Suggestion = key.createIndex()
+	return finding
+}
+
+// createIndex is the CREATE INDEX statement that covers k, named the way
+// schema.sql names its indexes.
+func (k PostgresKey) createIndex() string {
+	columns := slices.Clone(k.Equal)
+	names := slices.Clone(k.Equal)
+	if k.Order != "" {
+		order := k.Order
+		if k.Descending {
+			order += " DESC"
+		}
+		columns = append(columns, order)
+		names = append(names, strings.TrimSuffix(k.Order, "_at"))
+	}
+	return fmt.Sprintf("CREATE INDEX idx_%s_%s ON %s(%s);",
+		k.Table, strings.Join(names, "_"), k.Table, strings.Join(columns, ", "))
+}
@@ -0,0 +1,130 @@
+package benchmark
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// PhysicalWork is a running total of the I/O a database has done: shared
+// buffer pages hit, read and written, and WAL bytes generated.
+type PhysicalWork struct {
+	BuffersHit     int64
+	BuffersRead    int64
+	BuffersWritten int64
+	WALBytes       int64
+}
+
+var (
+	// workMu guards the meter and its reading when the running test
+	// started, or after its warm-up.
+	workMu    sync.Mutex
+	workMeter func() (PhysicalWork, error)
+	workBase  *PhysicalWork
+)
+
+// SetWorkMeter reads the database's physical work from a running total
+// before and after every test Suite.Run runs, replacing any meter set
+// before, or stops with nil. The growth, less the test's warm-up, fills in
+// the buffers and WAL a test's result reports where the test did not
+// measure them itself, so Amplification can set it against the
+// transactions the test processed. Tests that report several results are
+// not metered.
+func SetWorkMeter(read func() (PhysicalWork, error)) {
+	workMu.Lock()
+	defer workMu.Unlock()
+	workMeter, workBase = read, nil
+}
+
+// startWork reads the meter, if one is set, as the base the running test's
+// work is counted from.
+func startWork() {
+	workMu.Lock()
+	defer workMu.Unlock()
+	workBase = nil
+	if work, ok := readWorkLocked(); ok {
+		workBase = &work
+	}
+}
+
+// addWork fills in the work done since startWork on the test's result, if
+// it has just one and that has none of its own.
+func addWork(results []Result) {
+	workMu.Lock()
+	defer workMu.Unlock()
+	before := workBase
+	workBase = nil
+	if before == nil || len(results) != 1 {
+		return
+	}
+	r := &results[0]
+	after, ok := readWorkLocked()
+	if !ok {
+		return
+	}
+	if r.BuffersHit == 0 && r.BuffersRead == 0 && r.BuffersWritten == 0 {
+		r.BuffersHit = max(0, after.BuffersHit-before.BuffersHit)
+		r.BuffersRead = max(0, after.BuffersRead-before.BuffersRead)
+		r.BuffersWritten = max(0, after.BuffersWritten-before.BuffersWritten)
+	}
+	if r.WALBytes == 0 {
+		r.WALBytes = max(0, after.WALBytes-before.WALBytes)
+	}
+}
+
+// restartWork moves the running test's base past its warm-up.
+func restartWork() {
+	workMu.Lock()
+	running := workBase != nil
+	workMu.Unlock()
+	if running {
+		startWork()
+	}
+}
+
+func readWorkLocked() (PhysicalWork, bool) {
+	if workMeter == nil {
+		return PhysicalWork{}, false
+	}
+	work, err := workMeter()
+	if err != nil {
+		slog.Error("Failed to read physical work", "err", err)
+		return PhysicalWork{}, false
+	}
+	return work, true
+}
+
+// Amplification is the physical work the result's test did per successful
+// operation, in the database's own unit: 8 KB shared buffer pages hit,
+// read or written for PostgreSQL, and capacity units consumed for
+// DynamoDB. An operation that touches the index and heap pages of every
+// row it writes, or reads an item larger than 4 KB, scores above 1; it is
+// 0 where the test's work was not measured.
+func (r Result) Amplification() float64 {
+	ops := r.successfulOps()
+	if ops == 0 {
+		return 0
+	}
+	if capacity := r.ConsumedRCU + r.ConsumedWCU; capacity > 0 {
+		return capacity / ops
+	}
+	return float64(r.BuffersHit+r.BuffersRead+r.BuffersWritten) / ops
+}
+
+// WALPerOp is the WAL bytes the result's test generated per successful
+// operation, or 0.
+func (r Result) WALPerOp() float64 {
+	ops := r.successfulOps()
+	if ops == 0 {
+		return 0
+	}
+	return float64(r.WALBytes) / ops
+}
+
+// successfulOps is the result's successful operations, or all of them for
+// a test such as a close step that does not count successes.
+func (r Result) successfulOps() float64 {
+	if r.SuccessCount == 0 && r.ErrorCount == 0 {
+		return float64(r.NumOperations)
+	}
+	return float64(r.SuccessCount)
+}
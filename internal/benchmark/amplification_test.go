@@ -0,0 +1,72 @@
+package benchmark
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestSuiteRunMetersWork(t *testing.T) {
+	var total PhysicalWork
+	SetWorkMeter(func() (PhysicalWork, error) { return total, nil })
+	defer SetWorkMeter(nil)
+	if err := SetWarmup("10"); err != nil {
+		t.Fatal(err)
+	}
+	defer SetWarmup("0")
+
+	suite := &Suite{name: "postgres-write"}
+	suite.Run(func() Result {
+		// The warm-up's work is not the test's.
+		total.BuffersHit += 500
+		WarmUp(1, func(*rand.Rand) error { return nil })
+		total.BuffersHit += 900
+		total.BuffersRead += 50
+		total.BuffersWritten += 50
+		total.WALBytes += 200_000
+		return fixtureResult("PostgreSQL", "Single Transaction Inserts", 1000, time.Millisecond, time.Millisecond, 1000)
+	})
+	suite.Run(func() Result {
+		// Buffers the test measured itself are kept.
+		total.BuffersHit += 7000
+		r := fixtureResult("PostgreSQL", "Account Transaction History (last 100 txns)", 1000, time.Millisecond, time.Millisecond, 1000)
+		r.BuffersHit = 3000
+		return r
+	})
+
+	inserts, history := suite.Results[0], suite.Results[1]
+	if inserts.BuffersHit != 900 || inserts.BuffersRead != 50 || inserts.BuffersWritten != 50 || inserts.WALBytes != 200_000 {
+		t.Errorf("inserts metered %+v", inserts)
+	}
+	if got := inserts.Amplification(); got != 1 {
+		t.Errorf("inserts Amplification = %v, want 1", got)
+	}
+	if got := inserts.WALPerOp(); got != 200 {
+		t.Errorf("inserts WALPerOp = %v, want 200", got)
+	}
+	if history.BuffersHit != 3000 || history.WALBytes != 0 {
+		t.Errorf("history metered %+v, want its own 3000 buffers hit", history)
+	}
+}
+
+func TestSuiteRunMeterFails(t *testing.T) {
+	SetWorkMeter(func() (PhysicalWork, error) { return PhysicalWork{}, errors.New("connection refused") })
+	defer SetWorkMeter(nil)
+
+	suite := &Suite{name: "postgres-read"}
+	suite.Run(func() Result {
+		return fixtureResult("PostgreSQL", "Point Reads - transaction by ID", 1000, time.Millisecond, time.Millisecond, 1000)
+	})
+	if r := suite.Results[0]; r.Amplification() != 0 {
+		t.Errorf("Amplification = %v without a meter reading, want 0", r.Amplification())
+	}
+}
+
+func TestAmplificationCapacity(t *testing.T) {
+	r := fixtureResult("DynamoDB", "TransactWriteItems (1000 ops, 1 concurrent)", 1000, time.Millisecond, time.Millisecond, 1000)
+	r.ConsumedWCU = 6000
+	if got := r.Amplification(); got != 6 {
+		t.Errorf("Amplification = %v, want 6 WCU per transaction", got)
+	}
+}
@@ -0,0 +1,33 @@
+package benchmark
+
+import "log/slog"
+
+// ExpectFound checks that a detection job flagged every transaction seeded
+// with an anomaly of kind, logging how many of the injected ones it found.
+// The job is one operation of result, which a miss turns from a success
+// into an assertion failure, so the self-check fails on it.
+func ExpectFound(result *Result, kind string, injected []string, flagged func(id string) bool) {
+	if len(injected) == 0 {
+		return
+	}
+	missed := 0
+	for _, id := range injected {
+		if !flagged(id) {
+			missed++
+		}
+	}
+	slog.Info("Injected anomalies found", "test", result.TestName, "anomaly", kind,
+		"injected", len(injected), "found", len(injected)-missed)
+	if missed == 0 {
+		return
+	}
+	slog.Warn("Detection missed injected anomalies", "test", result.TestName, "anomaly", kind, "missed", missed)
+	if result.FailedAssertions == nil {
+		result.FailedAssertions = make(map[string]int)
+	}
+	result.FailedAssertions["found every "+kind+" anomaly"] += missed
+	if result.AssertionFailures == 0 && result.SuccessCount > 0 {
+		result.SuccessCount--
+		result.AssertionFailures++
+	}
+}
@@ -0,0 +1,17 @@
+package benchmark
+
+import "testing"
+
+func TestExpectFound(t *testing.T) {
+	found := map[string]bool{"a": true, "b": true}
+	r := Result{NumOperations: 1, SuccessCount: 1}
+	ExpectFound(&r, "unbalanced", []string{"a", "b"}, func(id string) bool { return found[id] })
+	if r.SuccessCount != 1 || r.AssertionFailures != 0 {
+		t.Errorf("finding every anomaly left %d successes, %d assertion failures", r.SuccessCount, r.AssertionFailures)
+	}
+	ExpectFound(&r, "orphan-legs", []string{"a", "c", "d"}, func(id string) bool { return found[id] })
+	ExpectFound(&r, "duplicate-key", []string{"e"}, func(id string) bool { return found[id] })
+	if r.SuccessCount != 0 || r.AssertionFailures != 1 || r.FailedAssertions["found every orphan-legs anomaly"] != 2 || r.FailedAssertions["found every duplicate-key anomaly"] != 1 {
+		t.Errorf("misses left %d successes, %d assertion failures %v", r.SuccessCount, r.AssertionFailures, r.FailedAssertions)
+	}
+}
@@ -0,0 +1,42 @@
+package benchmark
+
+import (
+	"context"
+	"sync"
+)
+
+type assertionKey struct{}
+
+// assertions collects the checks that failed during one operation.
+type assertions struct {
+	mu     sync.Mutex
+	failed []string
+}
+
+// Expect records a correctness check inside an OpFn. When ok is false the
+// operation is counted as an assertion failure instead of a success, separate
+// from transport errors, and name is tallied in the result's
+// FailedAssertions. Execution continues either way; the return value lets the
+// op bail out early:
+//
+//	if !benchmark.Expect(ctx, "account item returned", output.Item != nil) {
+//		return nil
+//	}
+//
+// Outside the runner Expect just returns ok.
+func Expect(ctx context.Context, name string, ok bool) bool {
+	if ok {
+		return true
+	}
+	if a, found := ctx.Value(assertionKey{}).(*assertions); found {
+		a.mu.Lock()
+		a.failed = append(a.failed, name)
+		a.mu.Unlock()
+	}
+	return false
+}
+
+func withAssertions(ctx context.Context) (context.Context, *assertions) {
+	a := &assertions{}
+	return context.WithValue(ctx, assertionKey{}, a), a
+}
@@ -0,0 +1,322 @@
+// Package benchmark is the harness shared by every runner: the Result type,
+// percentile calculation (Summarize), result publishing (Save) and the
+// console summary. It also lets users plug their own access patterns into
+// the framework. A workload registers a setup, an operation and a teardown
+// function; the runner takes care of concurrency, latency statistics and
+// reporting.
+//
+//	func init() {
+//		w := benchmark.Register("postgres-balance-lookup", setup, lookupBalance, teardown)
+//		w.Database = "PostgreSQL"
+//	}
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SetupFn prepares a workload (open connections, load IDs, create tables)
+// before any operation runs.
+type SetupFn func(ctx context.Context) error
+
+// OpFn performs one operation. worker is the index of the goroutine running
+// it and iteration is a unique, increasing operation number. A returned error
+// counts as a transport error; correctness checks go through Expect.
+type OpFn func(ctx context.Context, worker, iteration int) error
+
+// TeardownFn releases whatever SetupFn acquired. It runs even if the
+// operations failed.
+type TeardownFn func(ctx context.Context) error
+
+// Workload is a registered benchmark. Operations and Concurrency are the
+// defaults used when the runner is not told otherwise.
+type Workload struct {
+	Name        string
+	Database    string
+	Operations  int
+	Concurrency int
+
+	Setup    SetupFn
+	Op       OpFn
+	Teardown TeardownFn
+}
+
+var (
+	mu        sync.Mutex
+	workloads = make(map[string]*Workload)
+)
+
+// Register adds a workload to the registry and returns it so callers can
+// adjust its defaults. setup and teardown may be nil. Registering the same
+// name twice panics, as it almost certainly means two files collide.
+func Register(name string, setup SetupFn, op OpFn, teardown TeardownFn) *Workload {
+	if op == nil {
+		panic(fmt.Sprintf("benchmark: workload %q registered without an operation", name))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, exists := workloads[name]; exists {
+		panic(fmt.Sprintf("benchmark: workload %q registered twice", name))
+	}
+
+	w := &Workload{
+		Name:        name,
+		Operations:  1000,
+		Concurrency: 10,
+		Setup:       setup,
+		Op:          op,
+		Teardown:    teardown,
+	}
+	workloads[name] = w
+	return w
+}
+
+// Lookup returns the workload registered under name.
+func Lookup(name string) (*Workload, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	w, ok := workloads[name]
+	return w, ok
+}
+
+// Workloads returns every registered workload sorted by name.
+func Workloads() []*Workload {
+	mu.Lock()
+	defer mu.Unlock()
+
+	list := make([]*Workload, 0, len(workloads))
+	for _, w := range workloads {
+		list = append(list, w)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Run executes a workload: setup, then operations spread over concurrency
+// workers, then teardown. Non-positive operations or concurrency fall back to
+// the workload's defaults.
+func Run(ctx context.Context, w *Workload, operations, concurrency int) (Result, error) {
+	if operations <= 0 {
+		operations = w.Operations
+	}
+	if concurrency <= 0 {
+		concurrency = w.Concurrency
+	}
+
+	testName := fmt.Sprintf("%s (%d ops, %d concurrent)", w.Name, operations, concurrency)
+	slog.Info("Benchmarking", "test", testName)
+	StartTest(testName)
+
+	if w.Setup != nil {
+		if err := w.Setup(ctx); err != nil {
+			return Result{}, fmt.Errorf("setup %s: %w", w.Name, err)
+		}
+	}
+	if w.Teardown != nil {
+		defer func() {
+			if err := w.Teardown(ctx); err != nil {
+				slog.Error("Teardown failed", "workload", w.Name, "err", err)
+			}
+		}()
+	}
+
+	// Iterations are handed to workers as they free up (closed-loop), or
+	// admitted on a token-bucket schedule when a target rate is set
+	// (open-loop; see Paced).
+	iterations := make(chan admission)
+	if targetRate > 0 {
+		iterations = make(chan admission, operations)
+		slog.Info("Open-loop", "target_ops_per_sec", targetRate, "workers", concurrency)
+	}
+
+	assertionFailures := 0
+	failedAssertions := make(map[string]int)
+
+	// The dispatcher stops early if the workers do, after a panic, and a
+	// panic of its own is raised once they have drained what it admitted.
+	var dispatcher workerPanics
+	dispatched := make(chan struct{})
+	stop := make(chan struct{})
+	go func() {
+		defer close(dispatched)
+		defer dispatcher.catch()
+		defer close(iterations)
+		var bucket *tokenBucket
+		if targetRate > 0 {
+			bucket = newTokenBucket(targetRate)
+		}
+		for iteration := 0; iteration < operations && ctx.Err() == nil && !Stopping(); iteration++ {
+			next := admission{iteration: iteration}
+			if bucket != nil {
+				next.intended = bucket.take()
+			}
+			select {
+			case iterations <- next:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	defer close(stop)
+
+	ran := RunWorkers(concurrency, func(worker int, local *Recorder, _ *rand.Rand) WorkerTally {
+		var tally WorkerTally
+		failures := 0
+		failed := make(map[string]int)
+		for next := range iterations {
+			// Each attempt gets its own timeout and checks; the
+			// operation is judged by the last one.
+			var checks *assertions
+			op := Retrying(func() error {
+				opCtx, cancel := OpContext(ctx)
+				defer cancel()
+				opCtx, checks = withAssertions(opCtx)
+				err := w.Op(opCtx, worker, next.iteration)
+				if err != nil && errors.Is(opCtx.Err(), context.DeadlineExceeded) {
+					CountTimeout()
+				}
+				return err
+			})
+
+			opStart := time.Now()
+			err := op()
+			local.Record(time.Since(opStart))
+			if !next.intended.IsZero() {
+				local.RecordResponse(time.Since(next.intended))
+			} else {
+				local.Think()
+			}
+			switch {
+			case err != nil:
+				tally.Errors++
+			case len(checks.failed) > 0:
+				failures++
+			default:
+				tally.Successes++
+			}
+			for _, name := range checks.failed {
+				failed[name]++
+			}
+		}
+		tally.Merge = func() {
+			assertionFailures += failures
+			for name, n := range failed {
+				failedAssertions[name] += n
+			}
+		}
+		return tally
+	})
+
+	<-dispatched
+	dispatcher.raise()
+
+	result := ran.Summarize(testName, w.Database, ran.Latencies.Count(), concurrency)
+	if targetRate > 0 {
+		result.TargetOpsPerSec = targetRate
+		slog.Info("Achieved rate", "ops_per_sec", result.OperationsPerSec,
+			"target_ops_per_sec", targetRate, "percent", result.OperationsPerSec/targetRate*100)
+	}
+	result.AssertionFailures = assertionFailures
+	if len(failedAssertions) > 0 {
+		result.FailedAssertions = failedAssertions
+		slog.Warn("Operations failed assertions", "operations", assertionFailures, "assertions", failedAssertions)
+	}
+	return result, nil
+}
+
+// Workers is what the workers RunWorkers ran recorded between them.
+type Workers struct {
+	Latencies *Recorder
+	Stats     []WorkerStats
+	Successes int
+	Errors    int
+	Duration  time.Duration
+}
+
+// WorkerTally is one worker's count of its operations. Merge, when set,
+// adds whatever else the worker tallied to the test's totals.
+type WorkerTally struct {
+	Successes int
+	Errors    int
+	Merge     func()
+}
+
+// RunWorkers runs n workers at once, each calling work with its number, a
+// Recorder of its own and its own source of random values (see WorkerRand),
+// and waits for them all. A worker tallies locally
+// and is merged into the totals once, when work returns, so workers never
+// wait on each other between operations. Merge runs under the lock the
+// totals are merged under, so a worker's own counters can be added to the
+// test's without another lock. A worker that panics stops, and RunWorkers
+// panics with it when the rest are done, so Suite.Run records the test as
+// failed rather than the process ending.
+func RunWorkers(n int, work func(worker int, local *Recorder, rng *rand.Rand) WorkerTally) Workers {
+	return StartWorkers(n, work).Wait()
+}
+
+// Pool is the workers StartWorkers started.
+type Pool struct {
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	ran    Workers
+	panics workerPanics
+}
+
+// StartWorkers starts n workers as RunWorkers does without waiting for
+// them, for load that keeps running while a test measures something else,
+// such as noisy neighbours. Wait waits for them.
+func StartWorkers(n int, work func(worker int, local *Recorder, rng *rand.Rand) WorkerTally) *Pool {
+	p := &Pool{ran: Workers{Latencies: NewRecorder(), Stats: make([]WorkerStats, 0, n)}}
+	start := time.Now()
+	for worker := 0; worker < n; worker++ {
+		p.wg.Add(1)
+		go func(worker int) {
+			defer p.wg.Done()
+			defer p.panics.catch()
+			rng := WorkerRand(worker)
+			local := NewRecorder()
+			local.rng = rng
+			tally := work(worker, local, rng)
+			stats := local.Worker(worker, tally.Errors, time.Since(start))
+
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.ran.Latencies.Merge(local)
+			p.ran.Stats = append(p.ran.Stats, stats)
+			p.ran.Successes += tally.Successes
+			p.ran.Errors += tally.Errors
+			p.ran.Duration = max(p.ran.Duration, time.Since(start))
+			if tally.Merge != nil {
+				tally.Merge()
+			}
+		}(worker)
+	}
+	return p
+}
+
+// Wait waits for the pool's workers to finish and returns what they
+// recorded, timed from when they started to when the last finished. If a
+// worker panicked, Wait panics with it once the others have finished.
+func (p *Pool) Wait() Workers {
+	p.wg.Wait()
+	p.panics.raise()
+	return p.ran
+}
+
+// Summarize summarizes the workers' operations as Recorder.Summarize does,
+// with each worker's stats.
+func (w Workers) Summarize(testName, database string, totalOps, concurrency int) Result {
+	result := w.Latencies.Summarize(testName, database, totalOps, concurrency, w.Successes, w.Errors, w.Duration)
+	result.SetWorkers(w.Stats)
+	return result
+}
@@ -0,0 +1,83 @@
+package benchmark
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// CapacityPrediction compares the capacity units predicted for one kind of
+// DynamoDB request from item sizes (see package capacity) with what
+// DynamoDB reported consuming.
+type CapacityPrediction struct {
+	Operation      string  `json:"operation"`
+	Requests       int     `json:"requests"`
+	PredictedUnits float64 `json:"predicted_units"`
+	ConsumedUnits  float64 `json:"consumed_units"`
+	// ErrorPercent is the error of the total prediction, relative to what
+	// was consumed. MeanAbsErrorPercent averages each request's error, so
+	// over- and under-predictions don't cancel out.
+	ErrorPercent        float64 `json:"error_percent"`
+	MeanAbsErrorPercent float64 `json:"mean_abs_error_percent"`
+}
+
+var (
+	// predictionsMu guards predictions, the tally since the last test was
+	// added to a suite.
+	predictionsMu sync.Mutex
+	predictions   = make(map[string]*predictionTally)
+)
+
+type predictionTally struct {
+	requests            int
+	predicted, consumed float64
+	absErrorPercent     float64
+}
+
+// RecordCapacity tallies one request's predicted and consumed capacity
+// units. The DynamoDB backend calls it for every request it can predict,
+// and Add attaches the tally to the test it ran under.
+func RecordCapacity(operation string, predicted, consumed float64) {
+	predictionsMu.Lock()
+	defer predictionsMu.Unlock()
+	t := predictions[operation]
+	if t == nil {
+		t = &predictionTally{}
+		predictions[operation] = t
+	}
+	t.requests++
+	t.predicted += predicted
+	t.consumed += consumed
+	if consumed > 0 {
+		t.absErrorPercent += math.Abs(predicted-consumed) / consumed * 100
+	}
+}
+
+// takeCapacityPredictions returns the predictions tallied since the last
+// call, by operation, and resets the tally.
+func takeCapacityPredictions() []CapacityPrediction {
+	predictionsMu.Lock()
+	defer predictionsMu.Unlock()
+	if len(predictions) == 0 {
+		return nil
+	}
+
+	out := make([]CapacityPrediction, 0, len(predictions))
+	for operation, t := range predictions {
+		p := CapacityPrediction{
+			Operation:      operation,
+			Requests:       t.requests,
+			PredictedUnits: t.predicted,
+			ConsumedUnits:  t.consumed,
+		}
+		if t.consumed > 0 {
+			p.ErrorPercent = (t.predicted - t.consumed) / t.consumed * 100
+			p.MeanAbsErrorPercent = t.absErrorPercent / float64(t.requests)
+		}
+		out = append(out, p)
+	}
+	clear(predictions)
+	slices.SortFunc(out, func(a, b CapacityPrediction) int { return strings.Compare(a.Operation, b.Operation) })
+	return out
+}
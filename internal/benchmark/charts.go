@@ -0,0 +1,181 @@
+package benchmark
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/components"
+	"github.com/go-echarts/go-echarts/v2/opts"
+)
+
+// percentileLabels are the latency chart's points, in Distribution order.
+var percentileLabels = []string{"P50", "P90", "P95", "P99", "P99.9", "Max"}
+
+// WriteCharts writes results as a standalone HTML page of charts, grouped
+// by database: throughput per test, each test's latency percentiles and,
+// where DynamoDB reported them, the capacity units each test consumed. When
+// a test was run more than once the latest run is charted. The page loads
+// ECharts from the go-echarts asset host, so it needs a connection to view
+// but nothing else.
+func WriteCharts(w io.Writer, results []Result) error {
+	page := components.NewPage()
+	page.SetPageTitle("PostgreSQL vs DynamoDB benchmark results")
+	page.SetLayout(components.PageFlexLayout)
+
+	for _, group := range byDatabase(results) {
+		page.AddCharts(throughputChart(group.database, group.results), latencyChart(group.database, group.results))
+		if chart := capacityChart(group.database, group.results); chart != nil {
+			page.AddCharts(chart)
+		}
+	}
+	return page.Render(w)
+}
+
+type databaseResults struct {
+	database string
+	results  []Result
+}
+
+// byDatabase groups results by database, in the order each database and
+// test first appears, keeping the latest complete run of each test. Failed
+// and skipped tests are left out.
+func byDatabase(results []Result) []databaseResults {
+	var groups []databaseResults
+	groupOf := make(map[string]int)
+	// testOf indexes each group's results by database and test name.
+	testOf := make(map[[2]string]int)
+	for _, result := range results {
+		if result.Partial || result.Failure != "" || result.Skipped != "" {
+			continue
+		}
+		g, ok := groupOf[result.Database]
+		if !ok {
+			g = len(groups)
+			groupOf[result.Database] = g
+			groups = append(groups, databaseResults{database: result.Database})
+		}
+		key := [2]string{result.Database, result.TestName}
+		if i, ok := testOf[key]; ok {
+			if result.Timestamp.After(groups[g].results[i].Timestamp) {
+				groups[g].results[i] = result
+			}
+			continue
+		}
+		testOf[key] = len(groups[g].results)
+		groups[g].results = append(groups[g].results, result)
+	}
+	return groups
+}
+
+// chartID names a chart by database and kind, so the page renders the
+// same for the same results. go-echarts also names the chart's script
+// variable after it, so it must be a valid identifier.
+func chartID(database, kind string) string {
+	return strings.ToLower(database) + "_" + kind
+}
+
+func testNames(results []Result) []string {
+	names := make([]string, len(results))
+	for i, r := range results {
+		names[i] = r.TestName
+	}
+	return names
+}
+
+// barHeight fits one bar per test, with room for the axes.
+func barHeight(tests int) string {
+	return fmt.Sprintf("%dpx", max(300, 32*tests+120))
+}
+
+// horizontalBar is a bar chart with one row per test, for long test names.
+func horizontalBar(database, kind, title string, results []Result) *charts.Bar {
+	bar := charts.NewBar()
+	bar.SetGlobalOptions(
+		charts.WithInitializationOpts(opts.Initialization{ChartID: chartID(database, kind), Width: "1000px", Height: barHeight(len(results))}),
+		charts.WithTitleOpts(opts.Title{Title: database + ": " + title}),
+		charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true), Trigger: "axis"}),
+		charts.WithGridOpts(opts.Grid{Left: "2%", Right: "4%", Top: "60", ContainLabel: opts.Bool(true)}),
+	)
+	bar.SetXAxis(testNames(results))
+	bar.XYReversal()
+	return bar
+}
+
+func throughputChart(database string, results []Result) *charts.Bar {
+	bar := horizontalBar(database, "throughput", "throughput (ops/sec)", results)
+	data := make([]opts.BarData, len(results))
+	for i, r := range results {
+		data[i] = opts.BarData{Value: round2(r.OperationsPerSec)}
+	}
+	bar.AddSeries("ops/sec", data)
+	return bar
+}
+
+// latencyChart plots each test's latency percentiles on a log scale.
+// Results saved before the full distribution was recorded only have the
+// median, P95 and P99.
+func latencyChart(database string, results []Result) *charts.Line {
+	line := charts.NewLine()
+	line.SetGlobalOptions(
+		charts.WithInitializationOpts(opts.Initialization{ChartID: chartID(database, "latency"), Width: "1000px", Height: "560px"}),
+		charts.WithTitleOpts(opts.Title{Title: database + ": latency percentiles (ms)"}),
+		charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true), Trigger: "axis"}),
+		charts.WithLegendOpts(opts.Legend{Show: opts.Bool(true), Type: "scroll", Top: "bottom"}),
+		charts.WithGridOpts(opts.Grid{Left: "2%", Right: "4%", Top: "60", Bottom: "80", ContainLabel: opts.Bool(true)}),
+		charts.WithYAxisOpts(opts.YAxis{Type: "log", Name: "ms"}),
+	)
+	line.SetXAxis(percentileLabels)
+	for _, r := range results {
+		points := []time.Duration{r.MedianDuration, 0, r.P95Duration, r.P99Duration, 0, 0}
+		if d := r.Latency; d != nil {
+			points = []time.Duration{d.P50, d.P90, d.P95, d.P99, d.P999, d.Max}
+		}
+		data := make([]opts.LineData, len(points))
+		for i, p := range points {
+			// ECharts leaves a gap for "-".
+			data[i] = opts.LineData{Value: "-"}
+			if p > 0 {
+				data[i].Value = round3(float64(p) / float64(time.Millisecond))
+			}
+		}
+		line.AddSeries(r.TestName, data, charts.WithLineChartOpts(opts.LineChart{ConnectNulls: opts.Bool(true)}))
+	}
+	return line
+}
+
+// capacityChart charts the RCU and WCU each test consumed, or returns nil
+// if none reported any; PostgreSQL never does.
+func capacityChart(database string, results []Result) *charts.Bar {
+	var consumed []Result
+	for _, r := range results {
+		if r.ConsumedRCU > 0 || r.ConsumedWCU > 0 {
+			consumed = append(consumed, r)
+		}
+	}
+	if len(consumed) == 0 {
+		return nil
+	}
+
+	bar := horizontalBar(database, "capacity", "capacity units consumed", consumed)
+	bar.SetGlobalOptions(charts.WithLegendOpts(opts.Legend{Show: opts.Bool(true), Right: "4%"}))
+	rcu := make([]opts.BarData, len(consumed))
+	wcu := make([]opts.BarData, len(consumed))
+	for i, r := range consumed {
+		rcu[i] = opts.BarData{Value: round2(r.ConsumedRCU)}
+		wcu[i] = opts.BarData{Value: round2(r.ConsumedWCU)}
+	}
+	bar.AddSeries("RCU", rcu).AddSeries("WCU", wcu)
+	return bar
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func round3(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
@@ -0,0 +1,98 @@
+package benchmark
+
+import (
+	"fmt"
+	"time"
+)
+
+// Check reports what is wrong with a result: fields every test should fill
+// in that are missing, operation counts that don't add up, and percentiles
+// out of order. benchctl's self-check runs it over every result of a tiny
+// run, where any problem means a broken test rather than a slow database.
+func Check(r Result) []string {
+	var problems []string
+	fail := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if r.TestName == "" || r.Database == "" {
+		fail("test name or database missing")
+	}
+	if r.Failure != "" {
+		fail("failed: %s", r.Failure)
+		return problems
+	}
+	if r.Skipped != "" {
+		fail("not run: %s", r.Skipped)
+		return problems
+	}
+	if r.Partial {
+		fail("cut short by an interrupt")
+	}
+	if r.Timestamp.IsZero() {
+		fail("no timestamp")
+	}
+	if r.NumOperations <= 0 {
+		fail("no operations")
+	}
+	if r.SuccessCount <= 0 {
+		fail("no operation succeeded (%d errors, %d timeouts)", r.ErrorCount, r.TimeoutCount)
+	}
+	if done := r.SuccessCount + r.ErrorCount + r.TimeoutCount + r.AssertionFailures; done != r.NumOperations {
+		fail("%d successes + %d errors + %d timeouts + %d assertion failures = %d, not the %d operations run",
+			r.SuccessCount, r.ErrorCount, r.TimeoutCount, r.AssertionFailures, done, r.NumOperations)
+	}
+	if r.AssertionFailures > 0 {
+		fail("%d operations failed assertions %v", r.AssertionFailures, r.FailedAssertions)
+	}
+	if r.DroppedItems > 0 {
+		fail("%d items left unprocessed by batch writes", r.DroppedItems)
+	}
+	if r.TotalDuration <= 0 || r.OperationsPerSec <= 0 {
+		fail("no duration or throughput (%v, %.2f ops/sec)", r.TotalDuration, r.OperationsPerSec)
+	}
+	if r.AverageDuration <= 0 || r.MedianDuration <= 0 {
+		fail("no latency (avg %v, median %v)", r.AverageDuration, r.MedianDuration)
+	}
+
+	ordered := func(name string, percentiles []time.Duration) {
+		for i := 1; i < len(percentiles); i++ {
+			if percentiles[i] < percentiles[i-1] {
+				fail("%s percentiles out of order: %v", name, percentiles)
+				return
+			}
+		}
+	}
+	ordered("median/P95/P99", []time.Duration{r.MedianDuration, r.P95Duration, r.P99Duration})
+	if d := r.Latency; d != nil {
+		ordered("P50/P90/P95/P99/P99.9/max", []time.Duration{d.P50, d.P90, d.P95, d.P99, d.P999, d.Max})
+		if r.AverageDuration > d.Max {
+			fail("average latency %v above the maximum %v", r.AverageDuration, d.Max)
+		}
+	}
+	if r.CorrectedP99Duration > 0 {
+		ordered("corrected P95/P99", []time.Duration{r.CorrectedP95Duration, r.CorrectedP99Duration})
+	}
+
+	if len(r.Workers) > 0 {
+		ops := 0
+		for _, w := range r.Workers {
+			ops += w.Operations
+		}
+		if ops != r.NumOperations {
+			fail("workers ran %d operations, not %d", ops, r.NumOperations)
+		}
+	}
+	for errorType, n := range r.ErrorsByType {
+		if n <= 0 {
+			fail("error type %q counted %d times", errorType, n)
+		}
+	}
+	if r.ConsumedRCU < 0 || r.ConsumedWCU < 0 {
+		fail("negative capacity (%.2f RCU, %.2f WCU)", r.ConsumedRCU, r.ConsumedWCU)
+	}
+	if r.HOTUpdatePercent < 0 || r.HOTUpdatePercent > 100 {
+		fail("HOT update share %.1f%% outside 0-100%%", r.HOTUpdatePercent)
+	}
+	return problems
+}
@@ -0,0 +1,49 @@
+package benchmark
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheck(t *testing.T) {
+	valid := func() Result {
+		r := fixtureResult("PostgreSQL", "Point Reads - transaction by ID", 10, 420*time.Microsecond, 1100*time.Microsecond, 2350)
+		r.Latency = &Distribution{P50: 380 * time.Microsecond, P90: 700 * time.Microsecond, P95: 770 * time.Microsecond,
+			P99: 1100 * time.Microsecond, P999: 1100 * time.Microsecond, Max: 1100 * time.Microsecond}
+		r.Workers = []WorkerStats{{Worker: 0, Operations: 6}, {Worker: 1, Operations: 4}}
+		return r
+	}
+	if problems := Check(valid()); len(problems) != 0 {
+		t.Fatalf("Check(valid) = %q, want no problems", problems)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*Result)
+		want   string
+	}{
+		{"counts", func(r *Result) { r.ErrorCount = 2 }, "not the 10 operations run"},
+		{"no successes", func(r *Result) { r.SuccessCount, r.ErrorCount = 0, 10 }, "no operation succeeded"},
+		{"percentiles", func(r *Result) { r.P95Duration = 2 * r.P99Duration }, "median/P95/P99 percentiles out of order"},
+		{"distribution", func(r *Result) { r.Latency.P90 = r.Latency.Max * 2 }, "P50/P90/P95/P99/P99.9/max percentiles out of order"},
+		{"workers", func(r *Result) { r.Workers[1].Operations = 3 }, "workers ran 9 operations, not 10"},
+		{"partial", func(r *Result) { r.Partial = true }, "cut short"},
+		{"dropped", func(r *Result) { r.DroppedItems = 3 }, "3 items left unprocessed"},
+		{"assertions", func(r *Result) {
+			r.SuccessCount, r.AssertionFailures, r.FailedAssertions = 9, 1, map[string]int{"balance matches": 1}
+		}, "1 operations failed assertions map[balance matches:1]"},
+		{"no latency", func(r *Result) { r.AverageDuration = 0 }, "no latency"},
+		{"HOT share", func(r *Result) { r.HOTUpdatePercent = 120 }, "HOT update share 120.0% outside 0-100%"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid()
+			tt.mutate(&r)
+			problems := Check(r)
+			if len(problems) != 1 || !strings.Contains(problems[0], tt.want) {
+				t.Errorf("Check = %q, want one problem containing %q", problems, tt.want)
+			}
+		})
+	}
+}
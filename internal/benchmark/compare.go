@@ -0,0 +1,306 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// equivalent names a pair of tests, one per database, that answer the same
+// question. The patterns' capture groups are the test's parameters (hours,
+// batch sizes, worker counts); a PostgreSQL and a DynamoDB result are
+// compared when their parameters match, and the parameters fill in label.
+type equivalent struct {
+	label    string
+	postgres *regexp.Regexp
+	dynamodb *regexp.Regexp
+}
+
+var equivalents = []equivalent{
+	{"Point read: transaction", regexp.MustCompile(`^Point Reads - transaction by ID$`), regexp.MustCompile(`^GetItem - transaction by ID$`)},
+	{"Point read: account", regexp.MustCompile(`^Point Reads - account by ID$`), regexp.MustCompile(`^GetItem - account by ID$`)},
+	{"Transaction + legs", regexp.MustCompile(`^Transaction \+ Legs by ID`), regexp.MustCompile(`^Transaction \+ Legs by ID`)},
+	{"Completed transactions, last %s hours", regexp.MustCompile(`^Range Query - Last (\d+) hours$`), regexp.MustCompile(`^Query by Status \(last (\d+) hours\)$`)},
+	{"Newest %s transactions, %s rows", regexp.MustCompile(`^Latest Transactions by Status \((\w+), (\d+) rows\)$`), regexp.MustCompile(`^Latest Transactions by Status \((\w+), (\d+) rows\)$`)},
+	{"Account history, last %s legs", regexp.MustCompile(`^Account Transaction History \(last (\d+) txns\)$`), regexp.MustCompile(`^Query Account History \(last (\d+) items\)$`)},
+	{"Merchant transactions, last %s days", regexp.MustCompile(`^Merchant Transactions \(last (\d+) days\)$`), regexp.MustCompile(`^Query Merchant Transactions \(last (\d+) days\)$`)},
+	{"User dashboard, %s legs per account", regexp.MustCompile(`^User Dashboard \(profile, accounts, last (\d+) legs per account\)$`), regexp.MustCompile(`^User Dashboard \(profile, accounts, last (\d+) legs per account\)$`)},
+	{"Concurrent reads, %s workers x %s ops", regexp.MustCompile(`^Concurrent Reads \((\d+) goroutines, (\d+) ops each\)$`), regexp.MustCompile(`^Concurrent Reads \((\d+) goroutines, (\d+) ops each\)$`)},
+	{"Single writes", regexp.MustCompile(`^Single Transaction Inserts$`), regexp.MustCompile(`^PutItem Writes \(with merchant GSI\)$`)},
+	{"Batch writes, %s batches of %s", regexp.MustCompile(`^Batch Inserts \((\d+) batches of (\d+)\)$`), regexp.MustCompile(`^BatchWriteItem \((\d+) batches of (\d+)\)$`)},
+	{"Concurrent writes, %s workers x %s ops", regexp.MustCompile(`^Concurrent Writes \((\d+) goroutines, (\d+) ops each\)$`), regexp.MustCompile(`^Concurrent Writes \((\d+) goroutines, (\d+) ops each\)$`)},
+	{"Multi-leg writes, %s legs", regexp.MustCompile(`^Multi-Leg Atomic Writes \((\d+) legs\)$`), regexp.MustCompile(`^TransactWriteItems - Multi-Leg \((\d+) legs\)$`)},
+	{"Double-entry writes, %s ops at %s concurrent", regexp.MustCompile(`^Double-Entry Atomic Writes \((\d+) ops, (\d+) concurrent\)$`), regexp.MustCompile(`^TransactWriteItems \((\d+) ops, (\d+) concurrent\)$`)},
+}
+
+// Comparison is one test run on both databases. The ratios are DynamoDB's
+// figure over PostgreSQL's: a latency ratio below 1 or a throughput ratio
+// above 1 favours DynamoDB.
+type Comparison struct {
+	Test            string
+	Postgres        Result
+	DynamoDB        Result
+	LatencyRatio    float64
+	P99Ratio        float64
+	ThroughputRatio float64
+	// Difference, set when both results came from multi-runs of at least
+	// two runs, is whether the runs differ significantly.
+	Difference *Difference
+}
+
+// LoadResults reads every saved result file for database (postgres or
+// dynamodb) from ResultsDir.
+func LoadResults(database string) ([]Result, error) {
+	files, err := filepath.Glob(filepath.Join(ResultsDir(), database+"-*-results.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var suite Suite
+		if err := json.Unmarshal(data, &suite); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		results = append(results, suite.Results...)
+	}
+	return results, nil
+}
+
+// Compare pairs PostgreSQL and DynamoDB results of equivalent tests, in the
+// order the equivalents are listed. When a test was run more than once,
+// across several result files, the latest run is used. PostgreSQL results
+// from more than one driver are each compared, labelled with their driver.
+func Compare(postgres, dynamodb []Result) []Comparison {
+	byDriver := make(map[string][]Result)
+	for _, r := range postgres {
+		byDriver[postgresDriver(r)] = append(byDriver[postgresDriver(r)], r)
+	}
+	drivers := make([]string, 0, len(byDriver))
+	for driver := range byDriver {
+		drivers = append(drivers, driver)
+	}
+	slices.Sort(drivers)
+
+	var comparisons []Comparison
+	for _, e := range equivalents {
+		ddb := latestByParameters(e.dynamodb, dynamodb)
+		pg := make(map[string]map[string]Result, len(drivers))
+		var keys []string
+		for _, driver := range drivers {
+			pg[driver] = latestByParameters(e.postgres, byDriver[driver])
+			for key := range pg[driver] {
+				if _, ok := ddb[key]; ok && !slices.Contains(keys, key) {
+					keys = append(keys, key)
+				}
+			}
+		}
+		slices.SortFunc(keys, compareParameters)
+
+		for _, key := range keys {
+			for _, driver := range drivers {
+				result, ok := pg[driver][key]
+				if !ok {
+					continue
+				}
+				c := Comparison{Test: e.label, Postgres: result, DynamoDB: ddb[key]}
+				if key != "" {
+					params := strings.Split(key, "\x00")
+					args := make([]any, len(params))
+					for i, p := range params {
+						args[i] = p
+					}
+					c.Test = fmt.Sprintf(e.label, args...)
+				}
+				if len(drivers) > 1 {
+					c.Test += " (" + driver + ")"
+				}
+				c.LatencyRatio = ratio(float64(c.DynamoDB.AverageDuration), float64(c.Postgres.AverageDuration))
+				c.P99Ratio = ratio(float64(c.DynamoDB.P99Duration), float64(c.Postgres.P99Duration))
+				c.ThroughputRatio = ratio(c.DynamoDB.OperationsPerSec, c.Postgres.OperationsPerSec)
+				c.Difference = difference(c.Postgres, postgres, c.DynamoDB, dynamodb)
+				comparisons = append(comparisons, c)
+			}
+		}
+	}
+	return comparisons
+}
+
+// postgresDriver is the driver a PostgreSQL result was measured through;
+// results saved before the driver was recorded all came through pq.
+func postgresDriver(r Result) string {
+	if r.Driver == "" {
+		return "pq"
+	}
+	return r.Driver
+}
+
+// latestByParameters indexes the results matching pattern by their
+// parameters, keeping the latest of each.
+func latestByParameters(pattern *regexp.Regexp, results []Result) map[string]Result {
+	byKey := make(map[string]Result)
+	for _, result := range results {
+		match := pattern.FindStringSubmatch(result.TestName)
+		if match == nil || result.Partial || result.Failure != "" || result.Skipped != "" {
+			continue
+		}
+		key := strings.Join(match[1:], "\x00")
+		if prev, ok := byKey[key]; !ok || result.Timestamp.After(prev.Timestamp) {
+			byKey[key] = result
+		}
+	}
+	return byKey
+}
+
+// compareParameters orders parameter keys numerically, field by field.
+func compareParameters(a, b string) int {
+	as, bs := strings.Split(a, "\x00"), strings.Split(b, "\x00")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if len(as[i]) != len(bs[i]) {
+			return len(as[i]) - len(bs[i])
+		}
+		if c := strings.Compare(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	return len(as) - len(bs)
+}
+
+func ratio(dynamodb, postgres float64) float64 {
+	if postgres <= 0 {
+		return 0
+	}
+	return dynamodb / postgres
+}
+
+const compareNote = "Ratios are DynamoDB over PostgreSQL: a latency ratio below 1x or a throughput ratio above 1x favours DynamoDB. Amplification is physical work per successful operation: 8 KB pages hit, read or written for PostgreSQL, capacity units for DynamoDB."
+
+var differenceNote = fmt.Sprintf("Tests run several times on both databases (benchctl run --runs), as mean ± standard deviation over the runs. Differences are tested with the Mann–Whitney U test and significant at p < %g.", Significance)
+
+// differences is the comparisons with repeated runs on both databases.
+func differences(comparisons []Comparison) []Comparison {
+	var repeated []Comparison
+	for _, c := range comparisons {
+		if c.Difference != nil {
+			repeated = append(repeated, c)
+		}
+	}
+	return repeated
+}
+
+// WriteMarkdown writes the comparisons as a Markdown table.
+func WriteMarkdown(w io.Writer, comparisons []Comparison) error {
+	var b strings.Builder
+	b.WriteString("# PostgreSQL vs DynamoDB\n\n")
+	b.WriteString(compareNote + "\n\n")
+	b.WriteString("| Test | PostgreSQL avg | DynamoDB avg | Latency ratio | PostgreSQL P99 | DynamoDB P99 | P99 ratio | PostgreSQL ops/sec | DynamoDB ops/sec | Throughput ratio | PostgreSQL amplification | DynamoDB amplification |\n")
+	b.WriteString("|---|---:|---:|---:|---:|---:|---:|---:|---:|---:|---:|---:|\n")
+	for _, c := range comparisons {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s | %.2f | %.2f | %s | %s | %s |\n",
+			c.Test,
+			formatLatency(c.Postgres.AverageDuration), formatLatency(c.DynamoDB.AverageDuration), formatRatio(c.LatencyRatio),
+			formatLatency(c.Postgres.P99Duration), formatLatency(c.DynamoDB.P99Duration), formatRatio(c.P99Ratio),
+			c.Postgres.OperationsPerSec, c.DynamoDB.OperationsPerSec, formatRatio(c.ThroughputRatio),
+			formatAmplification(c.Postgres), formatAmplification(c.DynamoDB))
+	}
+
+	if repeated := differences(comparisons); len(repeated) > 0 {
+		b.WriteString("\n## Repeated runs\n\n")
+		b.WriteString(differenceNote + "\n\n")
+		b.WriteString("| Test | Runs | PostgreSQL ops/sec | DynamoDB ops/sec | Throughput difference | PostgreSQL P95 ms | DynamoDB P95 ms | P95 difference |\n")
+		b.WriteString("|---|---:|---:|---:|---|---:|---:|---|\n")
+		for _, c := range repeated {
+			d := c.Difference
+			fmt.Fprintf(&b, "| %s | %d / %d | %s | %s | %s | %s | %s | %s |\n",
+				c.Test, d.Postgres.Throughput.N, d.DynamoDB.Throughput.N,
+				formatSpread(d.Postgres.Throughput, 2), formatSpread(d.DynamoDB.Throughput, 2), formatSignificance(d.ThroughputP, d.ThroughputSignificant),
+				formatSpread(d.Postgres.P95, 3), formatSpread(d.DynamoDB.P95, 3), formatSignificance(d.P95P, d.P95Significant))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var compareHTML = template.Must(template.New("compare").Funcs(template.FuncMap{
+	"latency":       formatLatency,
+	"ratio":         formatRatio,
+	"spread":        formatSpread,
+	"significance":  formatSignificance,
+	"amplification": formatAmplification,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>PostgreSQL vs DynamoDB</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; }
+td.num { text-align: right; }
+</style>
+</head>
+<body>
+<h1>PostgreSQL vs DynamoDB</h1>
+<p>{{.Note}}</p>
+<table>
+<tr><th>Test</th><th>PostgreSQL avg</th><th>DynamoDB avg</th><th>Latency ratio</th><th>PostgreSQL P99</th><th>DynamoDB P99</th><th>P99 ratio</th><th>PostgreSQL ops/sec</th><th>DynamoDB ops/sec</th><th>Throughput ratio</th><th>PostgreSQL amplification</th><th>DynamoDB amplification</th></tr>
+{{- range .Comparisons}}
+<tr><td>{{.Test}}</td><td class="num">{{latency .Postgres.AverageDuration}}</td><td class="num">{{latency .DynamoDB.AverageDuration}}</td><td class="num">{{ratio .LatencyRatio}}</td><td class="num">{{latency .Postgres.P99Duration}}</td><td class="num">{{latency .DynamoDB.P99Duration}}</td><td class="num">{{ratio .P99Ratio}}</td><td class="num">{{printf "%.2f" .Postgres.OperationsPerSec}}</td><td class="num">{{printf "%.2f" .DynamoDB.OperationsPerSec}}</td><td class="num">{{ratio .ThroughputRatio}}</td><td class="num">{{amplification .Postgres}}</td><td class="num">{{amplification .DynamoDB}}</td></tr>
+{{- end}}
+</table>
+{{- if .Repeated}}
+<h2>Repeated runs</h2>
+<p>{{.DifferenceNote}}</p>
+<table>
+<tr><th>Test</th><th>Runs</th><th>PostgreSQL ops/sec</th><th>DynamoDB ops/sec</th><th>Throughput difference</th><th>PostgreSQL P95 ms</th><th>DynamoDB P95 ms</th><th>P95 difference</th></tr>
+{{- range .Repeated}}
+{{- $d := .Difference}}
+<tr><td>{{.Test}}</td><td class="num">{{$d.Postgres.Throughput.N}} / {{$d.DynamoDB.Throughput.N}}</td><td class="num">{{spread $d.Postgres.Throughput 2}}</td><td class="num">{{spread $d.DynamoDB.Throughput 2}}</td><td>{{significance $d.ThroughputP $d.ThroughputSignificant}}</td><td class="num">{{spread $d.Postgres.P95 3}}</td><td class="num">{{spread $d.DynamoDB.P95 3}}</td><td>{{significance $d.P95P $d.P95Significant}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes the comparisons as a standalone HTML page.
+func WriteHTML(w io.Writer, comparisons []Comparison) error {
+	return compareHTML.Execute(w, struct {
+		Note           string
+		Comparisons    []Comparison
+		DifferenceNote string
+		Repeated       []Comparison
+	}{compareNote, comparisons, differenceNote, differences(comparisons)})
+}
+
+func formatLatency(d time.Duration) string {
+	return d.Round(time.Microsecond).String()
+}
+
+// formatAmplification is the result's Amplification, or - where its work
+// was not measured.
+func formatAmplification(r Result) string {
+	a := r.Amplification()
+	if a == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", a)
+}
+
+func formatRatio(r float64) string {
+	if r == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2fx", r)
+}
@@ -0,0 +1,61 @@
+package benchmark
+
+import (
+	"log/slog"
+	"sync"
+)
+
+var (
+	// costMu guards spentUSD, what the command's DynamoDB requests have cost
+	// so far, once SetCostCeiling has set a ceiling.
+	costMu      sync.Mutex
+	costCeiling float64
+	spentUSD    float64
+)
+
+// SetCostCeiling sets the most, in USD, one benchctl command's DynamoDB
+// requests may cost at Pricing's on-demand request prices before it is
+// stopped. 0 leaves it unlimited.
+func SetCostCeiling(usd float64) {
+	costMu.Lock()
+	defer costMu.Unlock()
+	costCeiling, spentUSD = usd, 0
+}
+
+// CostCeiling is the ceiling SetCostCeiling set, or 0.
+func CostCeiling() float64 {
+	costMu.Lock()
+	defer costMu.Unlock()
+	return costCeiling
+}
+
+// ChargeCapacity adds the capacity units one DynamoDB request consumed to
+// the command's spend. Once the spend passes the ceiling the command stops
+// as if interrupted: the test in progress ends early and the results so far
+// are saved as partial.
+func ChargeCapacity(units float64, write bool) {
+	price := Pricing.DynamoDBRead
+	if write {
+		price = Pricing.DynamoDBWrite
+	}
+
+	costMu.Lock()
+	defer costMu.Unlock()
+	if costCeiling <= 0 {
+		return
+	}
+	before := spentUSD
+	spentUSD += units * price / 1e6
+	if before <= costCeiling && spentUSD > costCeiling {
+		slog.Error("DynamoDB cost ceiling reached; stopping", "spent_usd", spentUSD, "ceiling_usd", costCeiling)
+		stop()
+	}
+}
+
+// Spent is what the command's DynamoDB requests have cost so far, counted
+// only while a ceiling is set.
+func Spent() float64 {
+	costMu.Lock()
+	defer costMu.Unlock()
+	return spentUSD
+}
@@ -0,0 +1,28 @@
+package benchmark
+
+import (
+	"context"
+	"testing"
+)
+
+func TestChargeCapacity(t *testing.T) {
+	saved, savedStop := stopped, stop
+	stopped, stop = context.WithCancel(context.Background())
+	defer func() { stopped, stop = saved, savedStop }()
+	defer SetCostCeiling(0)
+
+	ChargeCapacity(1e6, true)
+	if Spent() != 0 {
+		t.Errorf("spent %g with no ceiling", Spent())
+	}
+
+	SetCostCeiling(1)
+	ChargeCapacity(2e6, false)
+	if Spent() != 2*Pricing.DynamoDBRead || interrupted() {
+		t.Errorf("spent %g and stopped %v after 2M read units", Spent(), interrupted())
+	}
+	ChargeCapacity(2e6, true)
+	if !interrupted() {
+		t.Errorf("spent %g of a $1 ceiling without stopping", Spent())
+	}
+}
@@ -0,0 +1,343 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+const (
+	// dashboardWindow is how many seconds the rolling P99 and error rate
+	// cover.
+	dashboardWindow = 10
+	// workerIdleAfter is how long a worker goes without an operation
+	// before the dashboard shows it idle.
+	workerIdleAfter = 2 * time.Second
+	// dashboardWorkers is how many workers the dashboard lists.
+	dashboardWorkers = 24
+)
+
+// dash is the running dashboard, or nil.
+var dash atomic.Pointer[dashboard]
+
+// dashboard redraws a block of live progress below the log every second.
+// Log lines are written through it, so they land above the block instead
+// of being drawn over.
+type dashboard struct {
+	// mu guards everything below and the terminal.
+	mu   sync.Mutex
+	out  io.Writer
+	stop chan struct{}
+	done chan struct{}
+	// lines is the height of the block last drawn, to erase it by.
+	lines int
+
+	suite      string
+	suiteStart time.Time
+	// lastRun is how long the suite's previous saved run took, or 0.
+	lastRun  time.Duration
+	tests    int
+	lastTest string
+
+	// Each slot of the window holds one second of requests: the second
+	// it is for, their latencies and how many failed.
+	seconds  [dashboardWindow]int64
+	requests [dashboardWindow]*hdrhistogram.Histogram
+	errors   [dashboardWindow]int64
+
+	// gen counts the tests added, so workers of a finished test are
+	// dropped from the list.
+	gen     atomic.Int64
+	workers []*workerProgress
+}
+
+// workerProgress is one Recorder's operations in the running test. In the
+// concurrent tests each worker records to its own.
+type workerProgress struct {
+	gen     int64
+	started time.Time
+	ops     atomic.Int64
+	// last is the latest operation's latency and lastAt when it was
+	// recorded, in Unix nanoseconds.
+	last   atomic.Int64
+	lastAt atomic.Int64
+}
+
+// StartDashboard draws a live dashboard on out, usually a terminal's
+// standard error, until StopDashboard: the running suite and test, the
+// requests per second the backends are sending, their rolling P99 latency
+// and error rate over the last ten seconds, an ETA from the suite's last
+// saved run, and each worker's operations. The log is redirected through
+// the dashboard while it runs. It expects a terminal that understands ANSI
+// cursor movement.
+func StartDashboard(out io.Writer) {
+	d := &dashboard{out: out, stop: make(chan struct{}), done: make(chan struct{})}
+	for i := range d.requests {
+		d.requests[i] = newHistogram()
+	}
+	setLogOutput(d)
+	dash.Store(d)
+
+	go func() {
+		defer close(d.done)
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.mu.Lock()
+				d.redraw()
+				d.mu.Unlock()
+			case <-d.stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopDashboard stops the dashboard, leaving its last frame on screen, and
+// points the log back at standard error.
+func StopDashboard() {
+	d := dash.Swap(nil)
+	if d == nil {
+		return
+	}
+	close(d.stop)
+	<-d.done
+	setLogOutput(os.Stderr)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.redraw()
+}
+
+// DashboardEnabled reports whether the dashboard is running, so backends
+// report their requests when it is.
+func DashboardEnabled() bool {
+	return dash.Load() != nil
+}
+
+// Write writes a log line above the dashboard.
+func (d *dashboard) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.erase()
+	n, err := d.out.Write(p)
+	d.draw()
+	return n, err
+}
+
+func (d *dashboard) erase() {
+	if d.lines > 0 {
+		fmt.Fprintf(d.out, "\x1b[%dA\x1b[J", d.lines)
+		d.lines = 0
+	}
+}
+
+func (d *dashboard) redraw() {
+	d.erase()
+	d.draw()
+}
+
+func (d *dashboard) draw() {
+	frame := d.frame(time.Now())
+	io.WriteString(d.out, frame)
+	d.lines = strings.Count(frame, "\n")
+}
+
+// frame renders the dashboard as of now.
+func (d *dashboard) frame(now time.Time) string {
+	var b strings.Builder
+	if d.suite == "" {
+		b.WriteString("── waiting for a suite to start ──\n")
+		return b.String()
+	}
+
+	// The suite should take about as long as it did last time, and no
+	// longer than its time limit.
+	elapsed := now.Sub(d.suiteStart)
+	eta := "unknown"
+	if d.lastRun > 0 {
+		eta = fmt.Sprintf("%v (last run took %v)", max(0, d.lastRun-elapsed).Round(time.Second), d.lastRun.Round(time.Second))
+	}
+	if limit := deadline.Load(); limit != 0 {
+		if left := time.Unix(0, limit).Sub(now); d.lastRun == 0 || left < d.lastRun-elapsed {
+			eta = fmt.Sprintf("%v (--max-runtime)", max(0, left).Round(time.Second))
+		}
+	}
+	fmt.Fprintf(&b, "── %s ── %v elapsed, ETA %s ──\n", d.suite, elapsed.Round(time.Second), eta)
+
+	fmt.Fprintf(&b, "Test %d, %d done", d.tests+1, d.tests)
+	if d.lastTest != "" {
+		fmt.Fprintf(&b, ", last: %s", truncate(d.lastTest, 60))
+	}
+	b.WriteString("\n")
+
+	second := now.Unix()
+	window := newHistogram()
+	var failed int64
+	current := int64(0)
+	for i, s := range d.seconds {
+		if s <= second-dashboardWindow || s > second {
+			continue
+		}
+		window.Merge(d.requests[i])
+		failed += d.errors[i]
+		if s == second-1 {
+			current = d.requests[i].TotalCount()
+		}
+	}
+	errorRate := 0.0
+	if total := window.TotalCount(); total > 0 {
+		errorRate = float64(failed) / float64(total) * 100
+	}
+	fmt.Fprintf(&b, "Requests: %d/s  P99 (%ds): %s  Errors (%ds): %.1f%%\n",
+		current, dashboardWindow, formatLatency(percentile(window, 99)), dashboardWindow, errorRate)
+
+	gen := d.gen.Load()
+	var workers []*workerProgress
+	running := 0
+	for _, w := range d.workers {
+		if w.gen != gen {
+			continue
+		}
+		workers = append(workers, w)
+		if now.UnixNano()-w.lastAt.Load() < int64(workerIdleAfter) {
+			running++
+		}
+	}
+	if len(workers) == 0 {
+		return b.String()
+	}
+	fmt.Fprintf(&b, "Workers: %d running, %d idle\n", running, len(workers)-running)
+	for i, w := range workers {
+		if i == dashboardWorkers {
+			fmt.Fprintf(&b, "  and %d more\n", len(workers)-i)
+			break
+		}
+		ops := w.ops.Load()
+		rate := 0.0
+		if secs := now.Sub(w.started).Seconds(); secs > 0 {
+			rate = float64(ops) / secs
+		}
+		status := "running"
+		if now.UnixNano()-w.lastAt.Load() >= int64(workerIdleAfter) {
+			status = "idle"
+		}
+		fmt.Fprintf(&b, "  #%-3d %8d ops %9.1f/s  last %-10s %s", i+1, ops, rate, formatLatency(time.Duration(w.last.Load())), status)
+		if i%2 == 1 || i == len(workers)-1 {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-1] + "…"
+}
+
+// dashboardSuite starts showing a new suite, with an ETA from the last
+// saved run of it.
+func dashboardSuite(name string) {
+	d := dash.Load()
+	if d == nil {
+		return
+	}
+	lastRun := lastRunTime(name)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.suite, d.suiteStart, d.lastRun = name, time.Now(), lastRun
+	d.tests, d.lastTest = 0, ""
+	d.gen.Add(1)
+}
+
+// lastRunTime is how long the saved run of the named suite in the results
+// directory took, from its start to its last result, or 0 if there isn't
+// one.
+func lastRunTime(name string) time.Duration {
+	data, err := os.ReadFile(filepath.Join(ResultsDir(), name+"-results.json"))
+	if err != nil {
+		return 0
+	}
+	var suite Suite
+	if json.Unmarshal(data, &suite) != nil || suite.Metadata == nil || len(suite.Results) == 0 {
+		return 0
+	}
+	return max(0, suite.Results[len(suite.Results)-1].Timestamp.Sub(suite.Metadata.StartedAt))
+}
+
+// dashboardAdded counts tests added to the running suite.
+func dashboardAdded(results []Result) {
+	d := dash.Load()
+	if d == nil || len(results) == 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.tests += len(results)
+	d.lastTest = results[len(results)-1].TestName
+	d.gen.Add(1)
+}
+
+// dashboardRequest counts one request a backend sent.
+func dashboardRequest(latency time.Duration, err error) {
+	d := dash.Load()
+	if d == nil {
+		return
+	}
+	second := time.Now().Unix()
+	slot := int(second % dashboardWindow)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.seconds[slot] != second {
+		d.seconds[slot] = second
+		d.requests[slot].Reset()
+		d.errors[slot] = 0
+	}
+	d.requests[slot].RecordValue(toMicros(latency))
+	if err != nil {
+		d.errors[slot]++
+	}
+}
+
+// dashboardRecorded counts one operation a Recorder recorded, adding the
+// Recorder to the running test's workers the first time. The caller holds
+// r.mu.
+func dashboardRecorded(r *Recorder, latency time.Duration) {
+	d := dash.Load()
+	if d == nil {
+		return
+	}
+	now := time.Now()
+	gen := d.gen.Load()
+	if r.progress == nil || r.progress.gen != gen {
+		r.progress = &workerProgress{gen: gen, started: now}
+		d.mu.Lock()
+		d.workers = append(currentWorkers(d.workers, gen), r.progress)
+		d.mu.Unlock()
+	}
+	r.progress.ops.Add(1)
+	r.progress.last.Store(int64(latency))
+	r.progress.lastAt.Store(now.UnixNano())
+}
+
+// currentWorkers drops the workers of tests before gen.
+func currentWorkers(workers []*workerProgress, gen int64) []*workerProgress {
+	kept := workers[:0]
+	for _, w := range workers {
+		if w.gen == gen {
+			kept = append(kept, w)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,56 @@
+package benchmark
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDashboardFrame(t *testing.T) {
+	t.Setenv("BENCH_RESULTS_DIR", t.TempDir())
+	d := &dashboard{}
+	for i := range d.requests {
+		d.requests[i] = newHistogram()
+	}
+	dash.Store(d)
+	defer dash.Store(nil)
+
+	dashboardSuite("postgres-write")
+	for i := 0; i < 99; i++ {
+		dashboardRequest(time.Millisecond, nil)
+	}
+	dashboardRequest(50*time.Millisecond, errors.New("serialization failure"))
+	workers := []*Recorder{NewRecorder(), NewRecorder()}
+	for _, r := range workers {
+		r.Record(2 * time.Millisecond)
+	}
+
+	// Drawn in the second after the requests were counted in.
+	var counted int64
+	for _, second := range d.seconds {
+		counted = max(counted, second)
+	}
+	next := time.Unix(counted+1, 0)
+	frame := d.frame(next)
+	for _, want := range []string{
+		"── postgres-write ── ",
+		"ETA unknown",
+		"Test 1, 0 done\n",
+		"Requests: 100/s  P99 (10s): 1ms  Errors (10s): 1.0%\n",
+		"Workers: 2 running, 0 idle\n",
+		"#1 ", "#2 ",
+	} {
+		if !strings.Contains(frame, want) {
+			t.Errorf("frame lacks %q:\n%s", want, frame)
+		}
+	}
+
+	// Adding the test's result clears its workers from the list.
+	var suite Suite
+	suite.Add(fixtureResult("PostgreSQL", "Concurrent Writes", 2, 2*time.Millisecond, 2*time.Millisecond, 1000))
+	frame = d.frame(next)
+	if !strings.Contains(frame, "Test 2, 1 done, last: Concurrent Writes\n") || strings.Contains(frame, "Workers:") {
+		t.Errorf("frame after the test was added:\n%s", frame)
+	}
+}
@@ -0,0 +1,66 @@
+package benchmark
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	maxRuntime time.Duration
+	// deadline is when the running suite's time is up, in Unix
+	// nanoseconds, or 0 for none. Workers read it through Stopping.
+	deadline atomic.Int64
+)
+
+// SetMaxRuntime limits how long each suite may run, from NewSuite, or
+// removes the limit with 0. Once a suite is past it, the test in progress
+// stops at its next Stopping check and is marked partial, and the tests
+// after it are not started: Suite.Run records each as not run (see
+// Result.Skipped) so the suite is still saved with what it got through.
+func SetMaxRuntime(d time.Duration) {
+	maxRuntime = max(0, d)
+}
+
+// startDeadline starts the limit set by SetMaxRuntime for a new suite.
+func startDeadline() {
+	if maxRuntime > 0 {
+		deadline.Store(time.Now().Add(maxRuntime).UnixNano())
+	} else {
+		deadline.Store(0)
+	}
+}
+
+// clearDeadline lifts the running suite's limit once it has been saved.
+func clearDeadline() {
+	deadline.Store(0)
+}
+
+// pastDeadline reports whether the running suite has used up its time.
+func pastDeadline() bool {
+	d := deadline.Load()
+	return d != 0 && time.Now().UnixNano() >= d
+}
+
+// notRun is the result recorded in place of a test the suite had no time
+// left to start. Without running it there is no test name, so it is named
+// after its place in the suite and where the test is called from.
+func (s *Suite) notRun(test any) Result {
+	name := fmt.Sprintf("test %d", len(s.Results)+1)
+	if f := runtime.FuncForPC(reflect.ValueOf(test).Pointer()); f != nil {
+		file, line := f.FileLine(f.Entry())
+		name = fmt.Sprintf("%s at %s:%d", name, filepath.Base(file), line)
+	}
+	reason := fmt.Sprintf("suite ran past its %v limit", maxRuntime)
+	slog.Warn("Skipping test", "test", name, "reason", reason)
+	return Result{
+		TestName:  name + " (not run)",
+		Database:  s.database(),
+		Skipped:   reason,
+		Timestamp: time.Now(),
+	}
+}
@@ -0,0 +1,52 @@
+package benchmark
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSuiteRunSkipsPastDeadline(t *testing.T) {
+	SetMaxRuntime(time.Hour)
+	defer SetMaxRuntime(0)
+	defer clearDeadline()
+	startDeadline()
+
+	suite := &Suite{name: "dynamodb-read"}
+	ran := 0
+	test := func() Result {
+		ran++
+		return fixtureResult("DynamoDB", "Point Reads", 1, time.Millisecond, time.Millisecond, 1000)
+	}
+	suite.Run(test)
+	if Stopping() {
+		t.Fatal("Stopping before the deadline")
+	}
+
+	deadline.Store(time.Now().Add(-time.Second).UnixNano())
+	if !Stopping() {
+		t.Fatal("not Stopping past the deadline")
+	}
+	suite.Run(test)
+	suite.RunAll(func() []Result { return []Result{test()} })
+
+	if ran != 1 {
+		t.Errorf("%d tests ran, want only the one before the deadline", ran)
+	}
+	if len(suite.Results) != 3 {
+		t.Fatalf("got %d results, want 3", len(suite.Results))
+	}
+	for _, r := range suite.Results[1:] {
+		if !strings.HasPrefix(r.TestName, "test ") || !strings.Contains(r.TestName, "deadline_test.go:") || !strings.HasSuffix(r.TestName, " (not run)") {
+			t.Errorf("skipped test named %q, want test N at deadline_test.go:<line> (not run)", r.TestName)
+		}
+		if r.Skipped != "suite ran past its 1h0m0s limit" || r.Database != "DynamoDB" {
+			t.Errorf("skipped result = %q on %q", r.Skipped, r.Database)
+		}
+	}
+
+	clearDeadline()
+	if Stopping() {
+		t.Error("still Stopping once the suite's deadline is cleared")
+	}
+}
@@ -0,0 +1,111 @@
+package benchmark
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Change is one test's result in a baseline run and in a later one. The
+// changes are the current figure over the baseline's, less one: a P95
+// change above zero or a throughput change below zero is a slowdown.
+type Change struct {
+	Test             string
+	Database         string
+	Baseline         Result
+	Current          Result
+	P95Change        float64
+	ThroughputChange float64
+	// Missing is set for a baseline test the current run does not have.
+	Missing   bool
+	Regressed bool
+}
+
+// Diff pairs the tests of a baseline run with the same tests, by database
+// and name, in a later run, in baseline order, and marks those whose P95
+// rose or whose ops/sec fell by more than threshold (0.1 for 10%). When a
+// test was run more than once the latest run is used. Tests only the
+// current run has are left out; baseline tests it lacks are kept, marked
+// Missing, but are not regressions.
+func Diff(baseline, current []Result, threshold float64) []Change {
+	latest := make(map[[2]string]Result)
+	for _, group := range byDatabase(current) {
+		for _, r := range group.results {
+			latest[[2]string{r.Database, r.TestName}] = r
+		}
+	}
+
+	var changes []Change
+	for _, group := range byDatabase(baseline) {
+		for _, base := range group.results {
+			c := Change{Test: base.TestName, Database: base.Database, Baseline: base}
+			cur, ok := latest[[2]string{base.Database, base.TestName}]
+			if !ok {
+				c.Missing = true
+				changes = append(changes, c)
+				continue
+			}
+			c.Current = cur
+			c.P95Change = change(float64(cur.P95Duration), float64(base.P95Duration))
+			c.ThroughputChange = change(cur.OperationsPerSec, base.OperationsPerSec)
+			c.Regressed = c.P95Change > threshold || c.ThroughputChange < -threshold
+			changes = append(changes, c)
+		}
+	}
+	return changes
+}
+
+// change is current over baseline less one, or 0 without a baseline.
+func change(current, baseline float64) float64 {
+	if baseline <= 0 {
+		return 0
+	}
+	return current/baseline - 1
+}
+
+// Regressions counts the changes marked as regressions.
+func Regressions(changes []Change) int {
+	n := 0
+	for _, c := range changes {
+		if c.Regressed {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteDiff writes the changes as a Markdown table, noting the threshold
+// they were judged by.
+func WriteDiff(w io.Writer, changes []Change, threshold float64) error {
+	var b strings.Builder
+	b.WriteString("# Benchmark diff\n\n")
+	fmt.Fprintf(&b, "Changes are the current run against the baseline. A test regressed when its P95 rose or its ops/sec fell by more than %s.\n\n", formatPercent(threshold))
+	b.WriteString("| Test | Database | Baseline P95 | Current P95 | P95 change | Baseline ops/sec | Current ops/sec | Throughput change | |\n")
+	b.WriteString("|---|---|---:|---:|---:|---:|---:|---:|---|\n")
+	for _, c := range changes {
+		if c.Missing {
+			fmt.Fprintf(&b, "| %s | %s | %s | - | - | %.2f | - | - | not run |\n",
+				c.Test, c.Database, formatLatency(c.Baseline.P95Duration), c.Baseline.OperationsPerSec)
+			continue
+		}
+		status := ""
+		if c.Regressed {
+			status = "**REGRESSED**"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %.2f | %.2f | %s | %s |\n",
+			c.Test, c.Database,
+			formatLatency(c.Baseline.P95Duration), formatLatency(c.Current.P95Duration), formatChange(c.P95Change),
+			c.Baseline.OperationsPerSec, c.Current.OperationsPerSec, formatChange(c.ThroughputChange), status)
+	}
+	fmt.Fprintf(&b, "\n%d of %d tests regressed.\n", Regressions(changes), len(changes))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatChange(c float64) string {
+	return fmt.Sprintf("%+.1f%%", c*100)
+}
+
+func formatPercent(f float64) string {
+	return fmt.Sprintf("%g%%", f*100)
+}
@@ -0,0 +1,55 @@
+package benchmark
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// diffFixture is fixturePostgres run again with one test slower, one with
+// less throughput, one within the threshold and one left out.
+func diffFixture() []Result {
+	current := fixturePostgres()
+	current[0].P95Duration = current[0].P95Duration * 3 / 2
+	current[1].OperationsPerSec *= 0.8
+	current[2].P95Duration += current[2].P95Duration / 20
+	current[2].OperationsPerSec *= 0.95
+	return current[:4]
+}
+
+func TestDiff(t *testing.T) {
+	changes := Diff(fixturePostgres(), diffFixture(), 0.1)
+	if len(changes) != 5 {
+		t.Fatalf("got %d changes, want 5", len(changes))
+	}
+	want := []struct {
+		regressed, missing bool
+	}{{true, false}, {true, false}, {false, false}, {false, false}, {false, true}}
+	for i, w := range want {
+		if c := changes[i]; c.Regressed != w.regressed || c.Missing != w.missing {
+			t.Errorf("%s: regressed %v, missing %v; want %v, %v", c.Test, c.Regressed, c.Missing, w.regressed, w.missing)
+		}
+	}
+	if got := Regressions(changes); got != 2 {
+		t.Errorf("Regressions = %d, want 2", got)
+	}
+
+	// The latest complete run of a repeated test is the one compared.
+	rerun := diffFixture()[0]
+	rerun.P95Duration = fixturePostgres()[0].P95Duration
+	rerun.Timestamp = fixtureTime.Add(time.Hour)
+	if c := Diff(fixturePostgres(), append(diffFixture(), rerun), 0.1)[0]; c.Regressed {
+		t.Errorf("%s compared with an older run: P95 change %+.2f", c.Test, c.P95Change)
+	}
+	if n := Regressions(Diff(fixturePostgres(), diffFixture(), 0.6)); n != 0 {
+		t.Errorf("%d regressions at a 60%% threshold, want 0", n)
+	}
+}
+
+func TestDiffGolden(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteDiff(&buf, Diff(fixturePostgres(), diffFixture(), 0.1), 0.1); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "diff.md", buf.Bytes())
+}
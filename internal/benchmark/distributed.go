@@ -0,0 +1,211 @@
+package benchmark
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+var (
+	histogramsMu sync.Mutex
+	// histograms, while CaptureHistograms is on, are the encoded
+	// service-time histograms of the tests summarized since, by test name.
+	histograms map[string]string
+)
+
+// CaptureHistograms starts keeping each test's latency histogram as it is
+// summarized, for a load generator to send to the coordinator that merges
+// it with the other generators' (see MergeGenerators).
+func CaptureHistograms() {
+	histogramsMu.Lock()
+	defer histogramsMu.Unlock()
+	histograms = make(map[string]string)
+}
+
+// TakeHistograms returns the histograms captured since CaptureHistograms,
+// in HdrHistogram's compressed V2 encoding, and stops capturing.
+func TakeHistograms() map[string]string {
+	histogramsMu.Lock()
+	defer histogramsMu.Unlock()
+	taken := histograms
+	histograms = nil
+	return taken
+}
+
+// captureHistogram keeps h under testName while histograms are captured.
+func captureHistogram(testName string, h *hdrhistogram.Histogram) {
+	histogramsMu.Lock()
+	defer histogramsMu.Unlock()
+	if histograms == nil || h.TotalCount() == 0 {
+		return
+	}
+	encoded, err := h.Encode(hdrhistogram.V2CompressedEncodingCookieBase)
+	if err != nil {
+		slog.Warn("Failed to encode latency histogram", "test", testName, "err", err)
+		return
+	}
+	histograms[testName] = string(encoded)
+}
+
+// GeneratorReport is what one load generator sends back for a suite: its
+// results and the latency histogram of each, by test name.
+type GeneratorReport struct {
+	Generator  string            `json:"generator"`
+	Results    []Result          `json:"results"`
+	Histograms map[string]string `json:"histograms,omitempty"`
+}
+
+// MergeGenerators combines the results of the same suite run at once by
+// several load generators into one result per test, in the order tests
+// first appear. Counts, capacity and workers are added up and latency
+// percentiles read off the generators' merged histograms, falling back to
+// the worst generator's when one sent none. Throughput is the operations of
+// all generators over the longest any took, a lower bound when their runs
+// of a test did not fully overlap. A test only one generator failed or
+// skipped is merged from the rest.
+func MergeGenerators(reports []GeneratorReport) []Result {
+	type merging struct {
+		parts      []Result
+		histograms []string
+	}
+	var order []string
+	tests := make(map[string]*merging)
+	for _, report := range reports {
+		for _, r := range report.Results {
+			key := r.Database + "\x00" + r.TestName
+			m, ok := tests[key]
+			if !ok {
+				m = &merging{}
+				tests[key] = m
+				order = append(order, key)
+			}
+			m.parts = append(m.parts, r)
+			m.histograms = append(m.histograms, report.Histograms[r.TestName])
+		}
+	}
+
+	merged := make([]Result, 0, len(order))
+	for _, key := range order {
+		m := tests[key]
+		var parts []Result
+		var encoded []string
+		for i, r := range m.parts {
+			if r.Failure == "" && r.Skipped == "" {
+				parts = append(parts, r)
+				encoded = append(encoded, m.histograms[i])
+			}
+		}
+		if len(parts) == 0 {
+			merged = append(merged, m.parts[0])
+			continue
+		}
+		merged = append(merged, mergeParts(parts, encoded))
+	}
+	return merged
+}
+
+// mergeParts combines one test's results from several generators, with
+// encoded their histograms in the same order.
+func mergeParts(parts []Result, encoded []string) Result {
+	result := parts[0]
+	result.LoadGenerators = len(parts)
+	result.Workers = nil
+	result.ErrorsByType = nil
+	result.NumOperations, result.Concurrency, result.SuccessCount, result.ErrorCount = 0, 0, 0, 0
+	result.TimeoutCount, result.Retries, result.AssertionFailures, result.ThrottledCount = 0, 0, 0, 0
+	result.DroppedItems = 0
+	result.ConsumedRCU, result.ConsumedWCU, result.TargetOpsPerSec = 0, 0, 0
+	result.Pool = nil
+	var retryLatency, weightedAverage time.Duration
+
+	for _, p := range parts {
+		result.NumOperations += p.NumOperations
+		result.Concurrency += p.Concurrency
+		result.SuccessCount += p.SuccessCount
+		result.ErrorCount += p.ErrorCount
+		result.TimeoutCount += p.TimeoutCount
+		result.Retries += p.Retries
+		result.AssertionFailures += p.AssertionFailures
+		result.ThrottledCount += p.ThrottledCount
+		result.DroppedItems += p.DroppedItems
+		result.ConsumedRCU += p.ConsumedRCU
+		result.ConsumedWCU += p.ConsumedWCU
+		result.TargetOpsPerSec += p.TargetOpsPerSec
+		result.Partial = result.Partial || p.Partial
+		result.TotalDuration = max(result.TotalDuration, p.TotalDuration)
+		result.CorrectedP95Duration = max(result.CorrectedP95Duration, p.CorrectedP95Duration)
+		result.CorrectedP99Duration = max(result.CorrectedP99Duration, p.CorrectedP99Duration)
+		if p.Timestamp.After(result.Timestamp) {
+			result.Timestamp = p.Timestamp
+		}
+		retryLatency += p.RetryLatency * time.Duration(p.NumOperations)
+		weightedAverage += p.AverageDuration * time.Duration(p.NumOperations)
+		for kind, n := range p.ErrorsByType {
+			if result.ErrorsByType == nil {
+				result.ErrorsByType = make(map[string]int)
+			}
+			result.ErrorsByType[kind] += n
+		}
+		// Each generator has a pool of its own.
+		if p.Pool != nil {
+			if result.Pool == nil {
+				result.Pool = &PoolStats{}
+			}
+			result.Pool.WaitCount += p.Pool.WaitCount
+			result.Pool.WaitDuration += p.Pool.WaitDuration
+			result.Pool.Opened += p.Pool.Opened
+			result.Pool.MaxOpen += p.Pool.MaxOpen
+		}
+		for _, w := range p.Workers {
+			w.Worker = len(result.Workers)
+			result.Workers = append(result.Workers, w)
+		}
+	}
+
+	if result.TotalDuration > 0 {
+		result.OperationsPerSec = float64(result.NumOperations) / result.TotalDuration.Seconds()
+	}
+	if result.NumOperations > 0 {
+		result.RetriesPerOp = float64(result.Retries) / float64(result.NumOperations)
+		result.RetryLatency = retryLatency / time.Duration(result.NumOperations)
+	}
+
+	if h := mergeHistograms(encoded); h != nil {
+		result.AverageDuration = time.Duration(h.Mean() * float64(time.Microsecond))
+		result.Latency = distribution(h)
+		result.MedianDuration = result.Latency.P50
+		result.P95Duration = result.Latency.P95
+		result.P99Duration = result.Latency.P99
+		return result
+	}
+	if result.NumOperations > 0 {
+		result.AverageDuration = weightedAverage / time.Duration(result.NumOperations)
+	}
+	result.Latency = nil
+	for _, p := range parts {
+		result.MedianDuration = max(result.MedianDuration, p.MedianDuration)
+		result.P95Duration = max(result.P95Duration, p.P95Duration)
+		result.P99Duration = max(result.P99Duration, p.P99Duration)
+	}
+	return result
+}
+
+// mergeHistograms decodes and merges encoded, or returns nil when any is
+// missing or unreadable.
+func mergeHistograms(encoded []string) *hdrhistogram.Histogram {
+	merged := newHistogram()
+	for _, e := range encoded {
+		if e == "" {
+			return nil
+		}
+		h, err := hdrhistogram.Decode([]byte(e))
+		if err != nil {
+			slog.Warn("Failed to decode latency histogram", "err", err)
+			return nil
+		}
+		merged.Merge(h)
+	}
+	return merged
+}
@@ -0,0 +1,58 @@
+package benchmark
+
+import (
+	"testing"
+	"time"
+)
+
+// generatorReport summarizes n operations of latency each as one load
+// generator's run of a test, capturing its histogram.
+func generatorReport(name string, n int, latency time.Duration) GeneratorReport {
+	CaptureHistograms()
+	recorder := NewRecorder()
+	for i := 0; i < n; i++ {
+		recorder.Record(latency)
+	}
+	result := recorder.Summarize("Point Reads", "PostgreSQL", n, 10, n, 0, 2*time.Second)
+	return GeneratorReport{Generator: name, Results: []Result{result}, Histograms: TakeHistograms()}
+}
+
+func TestMergeGenerators(t *testing.T) {
+	fast := generatorReport("gen1", 900, time.Millisecond)
+	slow := generatorReport("gen2", 100, 10*time.Millisecond)
+	slow.Results[0].TotalDuration = 4 * time.Second
+	failed := GeneratorReport{Generator: "gen3", Results: []Result{{TestName: "Point Reads", Database: "PostgreSQL", Failure: "panic: connection refused"}}}
+
+	merged := MergeGenerators([]GeneratorReport{fast, slow, failed})
+	if len(merged) != 1 {
+		t.Fatalf("%d merged results, want 1", len(merged))
+	}
+	r := merged[0]
+	if r.NumOperations != 1000 || r.SuccessCount != 1000 || r.Concurrency != 20 || r.LoadGenerators != 2 {
+		t.Errorf("merged %d operations, %d successes, %d workers from %d generators; want 1000, 1000, 20 from 2",
+			r.NumOperations, r.SuccessCount, r.Concurrency, r.LoadGenerators)
+	}
+	if r.OperationsPerSec != 250 {
+		t.Errorf("throughput %.1f ops/sec, want 1000 ops over the longest 4s", r.OperationsPerSec)
+	}
+	// The slow generator's tenth of the operations sets the tail.
+	if !within(r.MedianDuration, time.Millisecond) || !within(r.P95Duration, 10*time.Millisecond) {
+		t.Errorf("median %v, P95 %v; want about 1ms and 10ms from the merged histograms", r.MedianDuration, r.P95Duration)
+	}
+	if problems := Check(r); len(problems) > 0 {
+		t.Errorf("merged result: %v", problems)
+	}
+
+	// Without every histogram the worst generator's percentiles stand in.
+	slow.Histograms = nil
+	r = MergeGenerators([]GeneratorReport{fast, slow})[0]
+	if r.P95Duration != slow.Results[0].P95Duration || r.Latency != nil {
+		t.Errorf("P95 %v without histograms, want the slow generator's %v", r.P95Duration, slow.Results[0].P95Duration)
+	}
+}
+
+// within reports whether got is within the histograms' 0.1% precision of
+// want.
+func within(got, want time.Duration) bool {
+	return got >= want*999/1000 && got <= want*1001/1000
+}
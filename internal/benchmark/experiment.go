@@ -0,0 +1,207 @@
+package benchmark
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Factors, when set, are the levels of the experiment configuration
+// (benchctl experiment) under way, by factor name. Suite.Add stamps them on
+// every result, with the run ID, so each configuration's results can be
+// told apart from other results of the same tests.
+var Factors map[string]string
+
+// Factor is one setting an experiment varies and the levels it takes.
+type Factor struct {
+	Name   string   `json:"name" yaml:"name"`
+	Levels []string `json:"levels" yaml:"levels"`
+}
+
+// Configurations is every combination of the factors' levels, as factor
+// name to level, with the first factor varying slowest.
+func Configurations(factors []Factor) []map[string]string {
+	configs := []map[string]string{{}}
+	for _, f := range factors {
+		var next []map[string]string
+		for _, config := range configs {
+			for _, level := range f.Levels {
+				c := make(map[string]string, len(config)+1)
+				for k, v := range config {
+					c[k] = v
+				}
+				c[f.Name] = level
+				next = append(next, c)
+			}
+		}
+		configs = next
+	}
+	return configs
+}
+
+// ConfigurationName names a configuration by its levels in factor order,
+// such as "isolation=serializable, pool=unpooled".
+func ConfigurationName(factors []Factor, config map[string]string) string {
+	parts := make([]string, len(factors))
+	for i, f := range factors {
+		parts[i] = f.Name + "=" + config[f.Name]
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ConfigurationLabel is ConfigurationName spelt for a saved result name,
+// such as "isolation-serializable_pool-unpooled".
+func ConfigurationLabel(factors []Factor, config map[string]string) string {
+	parts := make([]string, len(factors))
+	for i, f := range factors {
+		parts[i] = f.Name + "-" + config[f.Name]
+	}
+	return strings.Join(parts, "_")
+}
+
+// Cell is one configuration's result for a test. The changes are its
+// figures over the first configuration's, less one.
+type Cell struct {
+	Configuration    string
+	Result           Result
+	ThroughputChange float64
+	P99Change        float64
+	// Missing is set for a configuration with no result for the test.
+	Missing bool
+}
+
+// Effect is the main effect of one level of a factor on a test: its
+// throughput and P99 latency averaged over every configuration at that
+// level, and their change over the factor's first level.
+type Effect struct {
+	Factor           string
+	Level            string
+	Throughput       float64
+	P99              time.Duration
+	ThroughputChange float64
+	P99Change        float64
+}
+
+// FactorialTest is one test's results across an experiment's
+// configurations, in Configurations order, with the main effects of each
+// factor.
+type FactorialTest struct {
+	Test     string
+	Database string
+	Cells    []Cell
+	Effects  []Effect
+}
+
+// Factorial lays the results of an experiment out by database and test,
+// in the order each test first appears. When a configuration has more than
+// one result for a test the latest is used. Results without factors, and
+// partial, failed or skipped ones, are left out. A factor's effects are
+// left out of a test when one of its levels has no results for it.
+func Factorial(factors []Factor, results []Result) []FactorialTest {
+	configs := Configurations(factors)
+	names := make(map[string]int, len(configs))
+	for i, config := range configs {
+		names[ConfigurationName(factors, config)] = i
+	}
+
+	var tests []FactorialTest
+	index := make(map[[2]string]int)
+	for _, r := range results {
+		if r.Factors == nil || r.Partial || r.Failure != "" || r.Skipped != "" {
+			continue
+		}
+		c, ok := names[ConfigurationName(factors, r.Factors)]
+		if !ok {
+			continue
+		}
+		key := [2]string{r.Database, r.TestName}
+		i, ok := index[key]
+		if !ok {
+			i = len(tests)
+			index[key] = i
+			test := FactorialTest{Test: r.TestName, Database: r.Database, Cells: make([]Cell, len(configs))}
+			for j, config := range configs {
+				test.Cells[j] = Cell{Configuration: ConfigurationName(factors, config), Missing: true}
+			}
+			tests = append(tests, test)
+		}
+		cell := &tests[i].Cells[c]
+		if cell.Missing || r.Timestamp.After(cell.Result.Timestamp) {
+			cell.Result, cell.Missing = r, false
+		}
+	}
+
+	for i := range tests {
+		cells := tests[i].Cells
+		for j := range cells {
+			if cells[j].Missing || cells[0].Missing {
+				continue
+			}
+			cells[j].ThroughputChange = change(cells[j].Result.OperationsPerSec, cells[0].Result.OperationsPerSec)
+			cells[j].P99Change = change(float64(cells[j].Result.P99Duration), float64(cells[0].Result.P99Duration))
+		}
+		for _, f := range factors {
+			tests[i].Effects = append(tests[i].Effects, mainEffects(f, configs, cells)...)
+		}
+	}
+	return tests
+}
+
+// mainEffects averages the cells at each of the factor's levels, or
+// returns nil when a level has none.
+func mainEffects(f Factor, configs []map[string]string, cells []Cell) []Effect {
+	effects := make([]Effect, len(f.Levels))
+	for i, level := range f.Levels {
+		var throughput, p99 float64
+		n := 0
+		for j, config := range configs {
+			if config[f.Name] != level || cells[j].Missing {
+				continue
+			}
+			throughput += cells[j].Result.OperationsPerSec
+			p99 += float64(cells[j].Result.P99Duration)
+			n++
+		}
+		if n == 0 {
+			return nil
+		}
+		effects[i] = Effect{Factor: f.Name, Level: level, Throughput: throughput / float64(n), P99: time.Duration(p99 / float64(n))}
+		effects[i].ThroughputChange = change(effects[i].Throughput, effects[0].Throughput)
+		effects[i].P99Change = change(float64(effects[i].P99), float64(effects[0].P99))
+	}
+	return effects
+}
+
+// WriteFactorial writes each test's results by configuration, then the
+// main effect of each factor level, as Markdown.
+func WriteFactorial(w io.Writer, name string, tests []FactorialTest) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Experiment: %s\n\n", name)
+	b.WriteString("Changes are against the first configuration, and a level's main effect against its factor's first level, averaged over the other factors.\n")
+	for _, t := range tests {
+		fmt.Fprintf(&b, "\n## %s (%s)\n\n", t.Test, t.Database)
+		b.WriteString("| Configuration | ops/sec | Throughput change | Avg | P99 | P99 change |\n")
+		b.WriteString("|---|---:|---:|---:|---:|---:|\n")
+		for _, c := range t.Cells {
+			if c.Missing {
+				fmt.Fprintf(&b, "| %s | - | - | - | - | not run |\n", c.Configuration)
+				continue
+			}
+			fmt.Fprintf(&b, "| %s | %.2f | %s | %s | %s | %s |\n", c.Configuration,
+				c.Result.OperationsPerSec, formatChange(c.ThroughputChange),
+				formatLatency(c.Result.AverageDuration), formatLatency(c.Result.P99Duration), formatChange(c.P99Change))
+		}
+		if len(t.Effects) == 0 {
+			continue
+		}
+		b.WriteString("\n| Factor | Level | Mean ops/sec | Throughput effect | Mean P99 | P99 effect |\n")
+		b.WriteString("|---|---|---:|---:|---:|---:|\n")
+		for _, e := range t.Effects {
+			fmt.Fprintf(&b, "| %s | %s | %.2f | %s | %s | %s |\n", e.Factor, e.Level,
+				e.Throughput, formatChange(e.ThroughputChange), formatLatency(e.P99), formatChange(e.P99Change))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,79 @@
+package benchmark
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+var experimentFactors = []Factor{
+	{Name: "isolation", Levels: []string{"read-committed", "serializable"}},
+	{Name: "pool", Levels: []string{"pooled", "unpooled"}},
+}
+
+// experimentFixture is two tests across the four configurations of
+// experimentFactors, the second never run unpooled at serializable.
+func experimentFixture() []Result {
+	var results []Result
+	for i, r := range []struct {
+		test      string
+		opsPerSec float64
+		p99       time.Duration
+	}{
+		{"Single Transaction Inserts", 500, 5 * time.Millisecond},
+		{"Single Transaction Inserts", 400, 6 * time.Millisecond},
+		{"Single Transaction Inserts", 300, 8 * time.Millisecond},
+		{"Single Transaction Inserts", 200, 12 * time.Millisecond},
+		{"Funds Transfers", 250, 9 * time.Millisecond},
+		{"Funds Transfers", 200, 10 * time.Millisecond},
+		{"Funds Transfers", 100, 20 * time.Millisecond},
+	} {
+		result := fixtureResult("PostgreSQL", r.test, 1000, r.p99/3, r.p99, r.opsPerSec)
+		result.Factors = Configurations(experimentFactors)[i%4]
+		results = append(results, result)
+	}
+	return results
+}
+
+func TestFactorial(t *testing.T) {
+	results := experimentFixture()
+	// An earlier result of the baseline configuration, and results from
+	// outside the experiment, are left out.
+	stale := results[0]
+	stale.OperationsPerSec, stale.Timestamp = 50, fixtureTime.Add(-time.Hour)
+	results = append(results, stale, fixtureResult("PostgreSQL", "Single Transaction Inserts", 1000, time.Millisecond, time.Millisecond, 9000))
+
+	tests := Factorial(experimentFactors, results)
+	if len(tests) != 2 {
+		t.Fatalf("got %d tests, want 2", len(tests))
+	}
+
+	inserts := tests[0]
+	if got := inserts.Cells[0].Result.OperationsPerSec; got != 500 {
+		t.Errorf("baseline ops/sec = %g, want the latest result's 500", got)
+	}
+	if got := inserts.Cells[3].ThroughputChange; got != -0.6 {
+		t.Errorf("serializable unpooled throughput change = %g, want -0.6", got)
+	}
+	// isolation: read-committed averages 450 ops/sec, serializable 250.
+	if e := inserts.Effects[1]; e.Factor != "isolation" || e.Level != "serializable" || e.Throughput != 250 {
+		t.Errorf("serializable main effect = %+v, want 250 ops/sec", e)
+	}
+
+	transfers := tests[1]
+	if !transfers.Cells[3].Missing {
+		t.Error("configuration never run is not marked missing")
+	}
+	// Each level still has a result, so both factors' effects are kept.
+	if len(transfers.Effects) != 4 {
+		t.Errorf("got %d effects, want 4", len(transfers.Effects))
+	}
+}
+
+func TestFactorialGolden(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteFactorial(&b, "isolation-pooling", Factorial(experimentFactors, experimentFixture())); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "factorial.md", b.Bytes())
+}
@@ -0,0 +1,111 @@
+package benchmark
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ExportDir is where a suite's export files are written: an exports
+// directory of the results directory, under the suite's labeled name.
+func ExportDir(name string) string {
+	return filepath.Join(ResultsDir(), "exports", labeled(name))
+}
+
+// PartitionWriter writes an export whose rows are partitioned by a key, such
+// as a merchant or a currency, as one CSV file per partition value named
+// <key>=<value>.csv, each starting with the same header. Rows must arrive
+// grouped by partition: only one file is open at a time, as a
+// GROUP BY ... ORDER BY streams them.
+type PartitionWriter struct {
+	dir    string
+	key    string
+	header []string
+
+	file    *os.File
+	buf     *bufio.Writer
+	csv     *csv.Writer
+	current string
+	written map[string]bool
+
+	bytes int64
+}
+
+// NewPartitionWriter creates dir, emptying it of an earlier run's files for
+// key, and returns a writer for partitions of key with the given header.
+func NewPartitionWriter(dir, key string, header ...string) (*PartitionWriter, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create export directory: %w", err)
+	}
+	stale, _ := filepath.Glob(filepath.Join(dir, key+"=*.csv"))
+	for _, path := range stale {
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove earlier export: %w", err)
+		}
+	}
+	return &PartitionWriter{dir: dir, key: key, header: header, written: make(map[string]bool)}, nil
+}
+
+// Write appends record to partition's file, opening it, and closing the
+// previous partition's, when partition changes.
+func (w *PartitionWriter) Write(partition string, record []string) error {
+	if w.file == nil || partition != w.current {
+		if w.written[partition] {
+			return fmt.Errorf("export partition %s=%s is not contiguous", w.key, partition)
+		}
+		if err := w.closeFile(); err != nil {
+			return err
+		}
+		file, err := os.Create(filepath.Join(w.dir, fmt.Sprintf("%s=%s.csv", w.key, partition)))
+		if err != nil {
+			return fmt.Errorf("create export file: %w", err)
+		}
+		w.file, w.buf, w.current = file, bufio.NewWriter(file), partition
+		w.csv = csv.NewWriter(w.buf)
+		w.written[partition] = true
+		if err := w.csv.Write(w.header); err != nil {
+			return err
+		}
+	}
+	return w.csv.Write(record)
+}
+
+// Close closes the open partition's file.
+func (w *PartitionWriter) Close() error {
+	return w.closeFile()
+}
+
+// Files is how many partition files have been written.
+func (w *PartitionWriter) Files() int {
+	return len(w.written)
+}
+
+// Bytes is how many bytes the closed partition files hold.
+func (w *PartitionWriter) Bytes() int64 {
+	return w.bytes
+}
+
+func (w *PartitionWriter) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+	file := w.file
+	w.file = nil
+	w.csv.Flush()
+	err := w.csv.Error()
+	if err == nil {
+		err = w.buf.Flush()
+	}
+	if info, statErr := file.Stat(); statErr == nil {
+		w.bytes += info.Size()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("write export file: %w", err)
+	}
+	return nil
+}
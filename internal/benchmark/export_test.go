@@ -0,0 +1,51 @@
+package benchmark
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPartitionWriter(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "currency=JPY.csv"), []byte("stale\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewPartitionWriter(dir, "currency", "date", "merchant_id", "transactions", "amount")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range [][]string{
+		{"EUR", "2026-01-05", "m-1", "3", "120.5000"},
+		{"EUR", "2026-01-06", "m-2", "1", "9.9900"},
+		{"USD", "2026-01-05", "", "2", "40.0000"},
+	} {
+		if err := w.Write(row[0], row[1:]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Write("EUR", []string{"2026-01-07", "m-1", "1", "1.0000"}); err == nil {
+		t.Error("Write to an earlier partition succeeded, want an error")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.csv"))
+	if len(files) != 2 || w.Files() != 2 {
+		t.Fatalf("files = %v (Files() %d), want currency=EUR.csv and currency=USD.csv", files, w.Files())
+	}
+	eur, err := os.ReadFile(filepath.Join(dir, "currency=EUR.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "date,merchant_id,transactions,amount\n2026-01-05,m-1,3,120.5000\n2026-01-06,m-2,1,9.9900\n"
+	if string(eur) != want {
+		t.Errorf("currency=EUR.csv = %q, want %q", eur, want)
+	}
+	usd, _ := os.Stat(filepath.Join(dir, "currency=USD.csv"))
+	if got := int64(len(eur)) + usd.Size(); w.Bytes() != got {
+		t.Errorf("Bytes() = %d, want %d", w.Bytes(), got)
+	}
+}
@@ -0,0 +1,185 @@
+package benchmark
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// failures counts the tests Run has recorded as failed in this process.
+var failures atomic.Int64
+
+// Failures is how many tests have panicked and been recorded as failed
+// since the process started, so a command can exit non-zero after running
+// the rest of its suites.
+func Failures() int {
+	return int(failures.Load())
+}
+
+// Run runs one test and adds its result to the suite. If the test panics,
+// on an empty ID slice or a nil SDK output say, the panic is logged with
+// its stack and recorded as a failed result instead (see Result.Failure),
+// and the suite carries on with its next test. So is a panic in one of the
+// test's workers (see RunWorkers, StartWorkers and WarmUp): the worker is
+// recovered, and once the rest have finished the panic is raised again on
+// the test with the worker's stack. Panics in
+// any other goroutine the test started still end the process. A test due to
+// start after the suite's time limit (see SetMaxRuntime) is not run. The
+// work and pool meters, if set, measure the test's physical work and pool
+// use (see SetWorkMeter and SetPoolMeter).
+func (s *Suite) Run(test func() Result) {
+	s.run(test, func() []Result { return []Result{test()} })
+}
+
+// RunAll is Run for a test that reports several results.
+func (s *Suite) RunAll(test func() []Result) {
+	s.run(test, test)
+}
+
+func (s *Suite) run(test any, call func() []Result) {
+	if pastDeadline() {
+		s.Add(s.notRun(test))
+		return
+	}
+	StartTest(fmt.Sprintf("test %d", len(s.Results)+1))
+	startWork()
+	startPool()
+	results, name, failure := runRecovered(test, call)
+	addWork(results)
+	addPool(results)
+	if failure != "" {
+		failures.Add(1)
+		if name == "" {
+			name = fmt.Sprintf("test %d", len(s.Results)+1)
+		}
+		slog.Error("Test failed, continuing with the next test", "test", name, "failure", failure)
+		results = []Result{{
+			TestName:  name + " (failed)",
+			Database:  s.database(),
+			Failure:   failure,
+			Timestamp: time.Now(),
+		}}
+	}
+	s.Add(results...)
+}
+
+// runRecovered calls call and, if it panics, logs the panic with its stack
+// and returns it with the failed test's name.
+func runRecovered(test any, call func() []Result) (results []Result, name, failure string) {
+	defer func() {
+		if p := recover(); p != nil {
+			// The panicking frames are still on the stack until this
+			// returns.
+			name = failedTestName(test)
+			failure = fmt.Sprintf("panic: %v", p)
+			stack := debug.Stack()
+			if worker, ok := p.(workerPanic); ok {
+				stack = worker.stack
+			}
+			slog.Error("Test panicked", "failure", failure, "stack", string(stack))
+			results = nil
+		}
+	}()
+	return call(), "", ""
+}
+
+// workerPanic is a worker's panic raised again on the test that started it,
+// with the stack the worker panicked on.
+type workerPanic struct {
+	value any
+	stack []byte
+}
+
+func (p workerPanic) String() string {
+	return fmt.Sprint(p.value)
+}
+
+// workerPanics keeps the first panic of a test's workers until they have
+// all finished.
+type workerPanics struct {
+	mu    sync.Mutex
+	first *workerPanic
+}
+
+// catch recovers a worker's panic and keeps it if it is the first. It must
+// be deferred by the worker itself.
+func (w *workerPanics) catch() {
+	if p := recover(); p != nil {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.first == nil {
+			w.first = &workerPanic{value: p, stack: debug.Stack()}
+		}
+	}
+}
+
+// raise panics again with the first panic caught, if there was one.
+func (w *workerPanics) raise() {
+	w.mu.Lock()
+	first := w.first
+	w.mu.Unlock()
+	if first != nil {
+		panic(*first)
+	}
+}
+
+// failedTestName names a test that panicked before it could name its
+// result after the function the test closure called, such as
+// benchmarkPointReads: of the frames between the panic and the closure on
+// the panicking stack, the outermost. It must be called while that stack
+// is still there, and returns "" if the closure panicked itself.
+func failedTestName(test any) string {
+	closure := runtime.FuncForPC(reflect.ValueOf(test).Pointer())
+	if closure == nil {
+		return ""
+	}
+
+	pcs := make([]uintptr, 128)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(0, pcs)])
+	called, panicked := "", false
+	for {
+		frame, more := frames.Next()
+		switch {
+		case frame.Function == closure.Name():
+			if called == "" {
+				return ""
+			}
+			return called[strings.LastIndex(called, ".")+1:]
+		case frame.Function == "runtime.gopanic":
+			panicked = true
+		case panicked && !strings.HasPrefix(frame.Function, "runtime."):
+			called = frame.Function
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// database is the database the suite's results are for: its first result's,
+// or the one its name starts with.
+func (s *Suite) database() string {
+	for _, r := range s.Results {
+		if r.Database != "" {
+			return r.Database
+		}
+	}
+	return suiteDatabase(s.name)
+}
+
+// suiteDatabase is the database a suite is named after, or "".
+func suiteDatabase(name string) string {
+	switch {
+	case strings.HasPrefix(name, "postgres"):
+		return "PostgreSQL"
+	case strings.HasPrefix(name, "dynamodb"):
+		return "DynamoDB"
+	}
+	return ""
+}
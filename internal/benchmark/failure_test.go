@@ -0,0 +1,115 @@
+package benchmark
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func benchmarkFirstRead(ids []string) Result {
+	return fixtureResult("PostgreSQL", "First Read "+ids[0], 1, time.Millisecond, time.Millisecond, 1000)
+}
+
+func TestSuiteRunRecoversPanics(t *testing.T) {
+	before := Failures()
+	suite := &Suite{name: "postgres-read"}
+
+	suite.Run(func() Result { return benchmarkFirstRead(nil) })
+	suite.RunAll(func() []Result { panic("no output") })
+	suite.RunAll(func() []Result {
+		return []Result{benchmarkFirstRead([]string{"a"}), benchmarkFirstRead([]string{"b"})}
+	})
+
+	if got := Failures() - before; got != 2 {
+		t.Errorf("Failures went up by %d, want 2", got)
+	}
+	if len(suite.Results) != 4 {
+		t.Fatalf("got %d results, want 4: %+v", len(suite.Results), suite.Results)
+	}
+
+	failed := suite.Results[0]
+	if failed.TestName != "benchmarkFirstRead (failed)" || failed.Database != "PostgreSQL" {
+		t.Errorf("failed result is %q on %q, want benchmarkFirstRead (failed) on PostgreSQL", failed.TestName, failed.Database)
+	}
+	if !strings.HasPrefix(failed.Failure, "panic: runtime error: index out of range") {
+		t.Errorf("Failure = %q, want the index panic", failed.Failure)
+	}
+	if failed.Timestamp.IsZero() {
+		t.Error("failed result has no timestamp")
+	}
+
+	// A closure that panics itself has no function to be named after.
+	if got := suite.Results[1]; got.TestName != "test 2 (failed)" || got.Failure != "panic: no output" {
+		t.Errorf("closure failure is %q: %q, want test 2 (failed): panic: no output", got.TestName, got.Failure)
+	}
+	for _, r := range suite.Results[2:] {
+		if r.Failure != "" {
+			t.Errorf("%s: Failure = %q after a passing test", r.TestName, r.Failure)
+		}
+	}
+}
+
+func benchmarkWorkerRead(ids []string) Result {
+	ran := RunWorkers(4, func(worker int, local *Recorder, _ *rand.Rand) WorkerTally {
+		if worker == 2 {
+			_ = ids[worker]
+		}
+		local.Record(time.Millisecond)
+		return WorkerTally{Successes: 1}
+	})
+	return ran.Summarize("Worker Read", "PostgreSQL", 4, 4)
+}
+
+func benchmarkPanickingWorkload(panicOn int) Result {
+	w := &Workload{Name: "panicking", Op: func(ctx context.Context, worker, iteration int) error {
+		if iteration >= panicOn {
+			panic("bad iteration")
+		}
+		return nil
+	}}
+	result, _ := Run(context.Background(), w, 50, 2)
+	return result
+}
+
+func TestSuiteRunRecoversWorkerPanics(t *testing.T) {
+	before := Failures()
+	suite := &Suite{name: "postgres-read"}
+
+	suite.Run(func() Result { return benchmarkWorkerRead(nil) })
+	suite.Run(func() Result { return benchmarkPanickingWorkload(10) })
+	// Every worker panics and stops taking iterations; the dispatcher must
+	// not be left waiting on them.
+	suite.Run(func() Result { return benchmarkPanickingWorkload(0) })
+	suite.Run(func() Result { return benchmarkWorkerRead([]string{"a", "b", "c"}) })
+
+	if got := Failures() - before; got != 3 {
+		t.Errorf("Failures went up by %d, want 3", got)
+	}
+	if len(suite.Results) != 4 {
+		t.Fatalf("got %d results, want 4: %+v", len(suite.Results), suite.Results)
+	}
+
+	if got := suite.Results[0]; got.TestName != "benchmarkWorkerRead (failed)" ||
+		!strings.HasPrefix(got.Failure, "panic: runtime error: index out of range") {
+		t.Errorf("worker failure is %q: %q, want benchmarkWorkerRead (failed) with the index panic", got.TestName, got.Failure)
+	}
+	for _, got := range suite.Results[1:3] {
+		if got.TestName != "benchmarkPanickingWorkload (failed)" || got.Failure != "panic: bad iteration" {
+			t.Errorf("workload failure is %q: %q, want benchmarkPanickingWorkload (failed): panic: bad iteration", got.TestName, got.Failure)
+		}
+	}
+	if got := suite.Results[3]; got.Failure != "" || got.SuccessCount != 4 {
+		t.Errorf("passing run: Failure = %q, %d successes, want none and 4", got.Failure, got.SuccessCount)
+	}
+}
+
+func TestWriteResultFailure(t *testing.T) {
+	var buf bytes.Buffer
+	writeResult(&buf, Result{TestName: "benchmarkFirstRead (failed)", Database: "PostgreSQL", Failure: "panic: boom"})
+	if !strings.Contains(buf.String(), "FAILED: panic: boom") {
+		t.Errorf("summary does not show the failure:\n%s", buf.String())
+	}
+}
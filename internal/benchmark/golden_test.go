@@ -0,0 +1,347 @@
+package benchmark
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Run `go test ./internal/benchmark -update` to rewrite the golden files
+// after a deliberate change to an output format, and review the diff: the
+// JSON files are read by the comparison scripts, sinks and dashboards, and
+// the reports by people.
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// golden compares got with testdata/name, or rewrites the file with -update.
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v; run go test -update to create it", err)
+	}
+	if bytes.Equal(got, want) {
+		return
+	}
+	gotLines, wantLines := strings.Split(string(got), "\n"), strings.Split(string(want), "\n")
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		var g, w string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if g != w {
+			t.Fatalf("%s differs at line %d:\n got: %s\nwant: %s\nrun go test -update if the change is deliberate", path, i+1, g, w)
+		}
+	}
+}
+
+var fixtureTime = time.Date(2026, 1, 6, 9, 30, 0, 0, time.UTC)
+
+// schemaResult sets every field of Result, so the golden JSON pins each
+// field's name and encoding. TestSchemaFixtureComplete fails when a field
+// is added without being set here.
+func schemaResult() Result {
+	within := true
+	return Result{
+		TestName:             "Concurrent Writes (10 goroutines, 1000 ops each)",
+		Database:             "DynamoDB",
+		NumOperations:        10000,
+		Concurrency:          10,
+		Driver:               "aws-sdk-go-v2",
+		Partial:              true,
+		Failure:              "panic: runtime error: index out of range [0] with length 0",
+		Skipped:              "suite ran past its 2h0m0s limit",
+		Repeat:               2,
+		RepeatRunID:          "20260106-093000-ab12",
+		Factors:              map[string]string{"isolation": "serializable"},
+		ExperimentRunID:      "20260106-093000-ab12",
+		TotalDuration:        4 * time.Second,
+		AverageDuration:      3900 * time.Microsecond,
+		MedianDuration:       3500 * time.Microsecond,
+		P95Duration:          7 * time.Millisecond,
+		P99Duration:          12 * time.Millisecond,
+		CorrectedP95Duration: 8 * time.Millisecond,
+		CorrectedP99Duration: 15 * time.Millisecond,
+		Latency: &Distribution{
+			P50: 3500 * time.Microsecond, P90: 6 * time.Millisecond, P95: 7 * time.Millisecond,
+			P99: 12 * time.Millisecond, P999: 30 * time.Millisecond, Max: 45 * time.Millisecond,
+			Curve: []CurvePoint{{0, time.Millisecond}, {50, 3500 * time.Microsecond}, {99, 12 * time.Millisecond}, {100, 45 * time.Millisecond}},
+		},
+		OperationsPerSec:  2500,
+		TargetOpsPerSec:   3000,
+		SuccessCount:      9950,
+		ErrorCount:        30,
+		TimeoutCount:      20,
+		ErrorsByType:      map[string]int{"ProvisionedThroughputExceededException": 30, "timeout": 20},
+		Retries:           120,
+		RetriesPerOp:      0.012,
+		RetryLatency:      150 * time.Microsecond,
+		Phases:            map[string]time.Duration{"marshal": 40 * time.Microsecond, "http": 3700 * time.Microsecond, "unmarshal": 60 * time.Microsecond},
+		AssertionFailures: 2,
+		FailedAssertions:  map[string]int{"balance matches": 2},
+		Step:              "step 3",
+		Layout:            "shared",
+		Role:              "quiet",
+		KeyStrategy:       "uuidv7",
+		FillFactor:        70,
+		ConnectionLimit:   97,
+		ConnectionWait:    1200 * time.Microsecond,
+		FailureMode:       DegradedQueuing,
+		OperationMix: map[string]OperationStats{
+			OpRead:   {Operations: 950, AverageDuration: 3 * time.Millisecond, P99Duration: 9 * time.Millisecond},
+			OpUpdate: {Operations: 50, Errors: 2, AverageDuration: 6 * time.Millisecond, P99Duration: 14 * time.Millisecond},
+		},
+		PastKnee:        true,
+		SoakElapsed:     20 * time.Minute,
+		ThroughputDrift: -12.5,
+		P99Drift:        40,
+		CollectionSize:  500,
+		ThinkTime:       50 * time.Millisecond,
+		LoadGenerators:  2,
+		Workers: []WorkerStats{
+			{Worker: 0, Operations: 1000, Errors: 3, OperationsPerSec: 250, AverageDuration: 3900 * time.Microsecond, P99Duration: 12 * time.Millisecond},
+			{Worker: 1, Operations: 1000, Errors: 1, OperationsPerSec: 100, AverageDuration: 9 * time.Millisecond, P99Duration: 20 * time.Millisecond},
+		},
+		Pool:                &PoolStats{WaitCount: 40, WaitDuration: 80 * time.Millisecond, Opened: 12, MaxOpen: 100},
+		LatencyPerItem:      390 * time.Microsecond,
+		ResultSizes:         &SizeSpread{Min: 1, P50: 10, P90: 25, Max: 100, Mean: 12.5},
+		ConsumedRCU:         250.5,
+		ConsumedWCU:         10000,
+		ThrottledCount:      30,
+		DroppedItems:        4,
+		ThrottleOnset:       1500 * time.Millisecond,
+		ItemsScanned:        2000,
+		ItemsReturned:       1000,
+		FilterEfficiency:    50,
+		CapacityPredictions: []CapacityPrediction{{Operation: "PutItem", Requests: 9950, PredictedUnits: 9950, ConsumedUnits: 10000, ErrorPercent: -0.5, MeanAbsErrorPercent: 1.25}},
+		EndToEndShare:       4.5,
+		RowsScanned:         20000,
+		RowsReturned:        1000,
+		BuffersHit:          5000,
+		BuffersRead:         120,
+		BuffersWritten:      40,
+		TableSizeBytes:      1 << 30,
+		IndexSizeBytes:      1 << 26,
+		AvgLeafDensity:      89.5,
+		LeafFragmentation:   12.25,
+		WALBytes:            1 << 24,
+		DeadTuples:          125000,
+		HOTUpdatePercent:    92.5,
+		FilesWritten:        12,
+		BytesWritten:        1 << 20,
+		WithinCloseWindow:   &within,
+		Timestamp:           fixtureTime,
+	}
+}
+
+func schemaSuite() Suite {
+	return Suite{
+		RunID: "run-0001",
+		Metadata: &Metadata{
+			StartedAt: fixtureTime.Add(-time.Minute),
+			GitSHA:    "0123456789abcdef0123456789abcdef01234567",
+			GitDirty:  true,
+			GoVersion: "go1.21.5",
+			Host:      HostInfo{OS: "linux", Arch: "amd64", CPUs: 8, CPUModel: "Example CPU @ 3.00GHz", MemoryBytes: 16 << 30},
+			Database: &DatabaseInfo{
+				Name: "dynamodb", Target: "http://localhost:8000", ServerVersion: "2.5.2", Driver: "aws-sdk-go-v2",
+				RowCounts: map[string]int64{"financial-transactions": 1200000},
+			},
+			Parameters: map[string]string{"suite": "writes", "ops": "1000", "concurrency": "10"},
+		},
+		Results: []Result{schemaResult()},
+		Partial: true,
+	}
+}
+
+func TestSchemaFixtureComplete(t *testing.T) {
+	suite := schemaSuite()
+	for _, v := range []any{suite.Results[0], *suite.Metadata, suite.Metadata.Host, *suite.Metadata.Database, *suite.Results[0].Latency} {
+		value := reflect.ValueOf(v)
+		for i := 0; i < value.NumField(); i++ {
+			if field := value.Type().Field(i); field.IsExported() && value.Field(i).IsZero() {
+				t.Errorf("%s.%s is unset in the schema fixture", value.Type().Name(), field.Name)
+			}
+		}
+	}
+}
+
+func TestSuiteJSONGolden(t *testing.T) {
+	// Encoded as the file and S3 sinks write it.
+	data, err := json.MarshalIndent(schemaSuite(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "suite.json", append(data, '\n'))
+
+	// A saved suite reads back as it was written.
+	var decoded Suite
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if want := schemaSuite(); !reflect.DeepEqual(decoded, want) {
+		t.Errorf("decoded suite differs from the one encoded:\n got: %+v\nwant: %+v", decoded.Results[0], want.Results[0])
+	}
+}
+
+// fixtureResult is a test result with the fields every runner fills in.
+func fixtureResult(database, test string, ops int, avg, p99 time.Duration, opsPerSec float64) Result {
+	return Result{
+		TestName:         test,
+		Database:         database,
+		NumOperations:    ops,
+		Concurrency:      1,
+		TotalDuration:    time.Duration(float64(ops) / opsPerSec * float64(time.Second)),
+		AverageDuration:  avg,
+		MedianDuration:   avg * 9 / 10,
+		P95Duration:      p99 * 7 / 10,
+		P99Duration:      p99,
+		OperationsPerSec: opsPerSec,
+		SuccessCount:     ops,
+		Timestamp:        fixtureTime,
+	}
+}
+
+func fixturePostgres() []Result {
+	concurrent := fixtureResult("PostgreSQL", "Concurrent Writes (10 goroutines, 1000 ops each)", 10000, 2400*time.Microsecond, 9*time.Millisecond, 4100)
+	concurrent.Concurrency = 10
+	concurrent.ErrorCount, concurrent.SuccessCount = 12, 9988
+	concurrent.ErrorsByType = map[string]int{"40 transaction_rollback": 12}
+	concurrent.Retries, concurrent.RetriesPerOp, concurrent.RetryLatency = 12, 0.0012, 6*time.Microsecond
+	concurrent.BuffersHit, concurrent.BuffersRead, concurrent.BuffersWritten, concurrent.WALBytes = 51200, 310, 2400, 14_500_000
+	concurrent.Workers = []WorkerStats{
+		{Worker: 0, Operations: 1000, OperationsPerSec: 410, AverageDuration: 2400 * time.Microsecond, P99Duration: 9 * time.Millisecond},
+		{Worker: 1, Operations: 1000, Errors: 12, OperationsPerSec: 150, AverageDuration: 6500 * time.Microsecond, P99Duration: 21 * time.Millisecond},
+		{Worker: 2, Operations: 1000, OperationsPerSec: 405, AverageDuration: 2450 * time.Microsecond, P99Duration: 9 * time.Millisecond},
+	}
+
+	history := fixtureResult("PostgreSQL", "Account Transaction History (last 100 txns)", 1000, 1800*time.Microsecond, 5*time.Millisecond, 550)
+	history.RowsScanned, history.RowsReturned, history.BuffersHit, history.BuffersRead = 120000, 100000, 4200, 18
+	history.Phases = map[string]time.Duration{"acquire": 20 * time.Microsecond, "execute": 1500 * time.Microsecond, "scan": 280 * time.Microsecond}
+
+	return []Result{
+		fixtureResult("PostgreSQL", "Point Reads - transaction by ID", 1000, 420*time.Microsecond, 1100*time.Microsecond, 2350),
+		history,
+		fixtureResult("PostgreSQL", "Range Query - Last 24 hours", 100, 8*time.Millisecond, 19*time.Millisecond, 120),
+		fixtureResult("PostgreSQL", "Double-Entry Atomic Writes (1000 ops, 1 concurrent)", 1000, 3100*time.Microsecond, 7*time.Millisecond, 320),
+		concurrent,
+	}
+}
+
+func fixtureDynamoDB() []Result {
+	getItem := fixtureResult("DynamoDB", "GetItem - transaction by ID", 1000, 875*time.Microsecond, 2200*time.Microsecond, 1140)
+	getItem.ConsumedRCU, getItem.ItemsReturned = 500, 1000
+	getItem.CapacityPredictions = []CapacityPrediction{{Operation: "GetItem", Requests: 1000, PredictedUnits: 500, ConsumedUnits: 500}}
+
+	history := fixtureResult("DynamoDB", "Query Account History (last 100 items)", 1000, 2600*time.Microsecond, 6*time.Millisecond, 380)
+	history.ConsumedRCU, history.ItemsScanned, history.ItemsReturned = 2000, 100000, 100000
+	history.Latency = &Distribution{P50: 2400 * time.Microsecond, P90: 4 * time.Millisecond, P95: 4200 * time.Microsecond, P99: 6 * time.Millisecond, P999: 11 * time.Millisecond, Max: 14 * time.Millisecond}
+
+	transact := fixtureResult("DynamoDB", "TransactWriteItems (1000 ops, 1 concurrent)", 1000, 9*time.Millisecond, 21*time.Millisecond, 110)
+	transact.ConsumedWCU = 6000
+
+	concurrent := fixtureResult("DynamoDB", "Concurrent Writes (10 goroutines, 1000 ops each)", 10000, 3900*time.Microsecond, 12*time.Millisecond, 2500)
+	concurrent.Concurrency = 10
+	concurrent.ConsumedWCU, concurrent.ThrottledCount, concurrent.ThrottleOnset = 10000, 30, 1500*time.Millisecond
+	concurrent.ErrorCount, concurrent.TimeoutCount, concurrent.SuccessCount = 30, 5, 9965
+	concurrent.ErrorsByType = map[string]int{"ProvisionedThroughputExceededException": 30, "timeout": 5}
+
+	// An older run of the same test; comparisons and charts use the
+	// later one.
+	stale := fixtureResult("DynamoDB", "GetItem - transaction by ID", 1000, 2*time.Millisecond, 5*time.Millisecond, 500)
+	stale.Timestamp = fixtureTime.Add(-24 * time.Hour)
+
+	return []Result{stale, getItem, history, transact, concurrent}
+}
+
+func TestSummaryGolden(t *testing.T) {
+	// The verdict judges the suite against the other database's saved
+	// results, at the default prices.
+	dir := t.TempDir()
+	t.Setenv("BENCH_RESULTS_DIR", dir)
+	data, err := json.Marshal(Suite{Results: fixtureDynamoDB()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "dynamodb-read-results.json"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	saved := Pricing
+	t.Cleanup(func() { Pricing = saved })
+	Pricing.PostgresHourly, Pricing.DynamoDBRead, Pricing.DynamoDBWrite = 0.225, 0.125, 0.625
+
+	var buf bytes.Buffer
+	writeSummary(&buf, Suite{Results: fixturePostgres()})
+	golden(t, "summary.txt", buf.Bytes())
+}
+
+func TestResultGolden(t *testing.T) {
+	// Every metric a result can print; a failed or skipped result prints
+	// only why.
+	r := schemaResult()
+	r.Failure, r.Skipped = "", ""
+	var buf bytes.Buffer
+	writeResult(&buf, r)
+	golden(t, "result.txt", buf.Bytes())
+}
+
+func TestCompareMarkdownGolden(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, Compare(fixturePostgres(), fixtureDynamoDB())); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "compare.md", buf.Bytes())
+}
+
+func TestCompareHTMLGolden(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, Compare(fixturePostgres(), fixtureDynamoDB())); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "compare.html", buf.Bytes())
+}
+
+func TestCompareDrivers(t *testing.T) {
+	// Results saved before drivers were recorded came through pq.
+	postgres := fixturePostgres()[:1]
+	pgx := postgres[0]
+	pgx.Driver, pgx.AverageDuration = "pgx", 350*time.Microsecond
+	postgres = append(postgres, pgx)
+
+	var tests []string
+	for _, c := range Compare(postgres, fixtureDynamoDB()) {
+		tests = append(tests, c.Test)
+	}
+	want := []string{"Point read: transaction (pgx)", "Point read: transaction (pq)"}
+	if !slices.Equal(tests, want) {
+		t.Errorf("compared %q, want %q", tests, want)
+	}
+	if c := Compare(postgres[1:], fixtureDynamoDB()); len(c) != 1 || c[0].Test != "Point read: transaction" {
+		t.Errorf("single driver compared as %+v", c)
+	}
+}
+
+func TestChartsGolden(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCharts(&buf, append(fixturePostgres(), fixtureDynamoDB()...)); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "charts.html", buf.Bytes())
+}
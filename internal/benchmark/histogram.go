@@ -0,0 +1,246 @@
+package benchmark
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// Latencies are recorded in microseconds from 1µs to an hour, to three
+// significant figures: about 0.1% error at any magnitude, in a fixed few
+// tens of kilobytes however many operations are recorded.
+const (
+	histogramMin      = 1
+	histogramMax      = int64(time.Hour / time.Microsecond)
+	histogramDigits   = 3
+	curveTicksPerHalf = 5
+)
+
+// Distribution is the full latency distribution of a test.
+type Distribution struct {
+	P50  time.Duration `json:"p50_ns"`
+	P90  time.Duration `json:"p90_ns"`
+	P95  time.Duration `json:"p95_ns"`
+	P99  time.Duration `json:"p99_ns"`
+	P999 time.Duration `json:"p999_ns"`
+	Max  time.Duration `json:"max_ns"`
+	// Curve is the percentile curve, with points packed more densely
+	// towards the tail, for plotting.
+	Curve []CurvePoint `json:"curve,omitempty"`
+}
+
+// CurvePoint is one point on a latency percentile curve.
+type CurvePoint struct {
+	Percentile float64       `json:"percentile"`
+	Latency    time.Duration `json:"latency_ns"`
+}
+
+// Recorder accumulates operation latencies in an HdrHistogram instead of a
+// slice, so memory stays bounded on long or highly concurrent tests. It is
+// safe for concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	service *hdrhistogram.Histogram
+	// response holds open-loop latencies measured from each operation's
+	// intended start; it stays empty in closed-loop tests.
+	response *hdrhistogram.Histogram
+	// progress is the Recorder's line on the dashboard, when one is
+	// running.
+	progress *workerProgress
+	// thought is set once a worker recording here has paused for think
+	// time (see Think), which then counts towards the interval between
+	// its operations.
+	thought bool
+	// rng is the source of the worker recording here, which Think draws
+	// from; nil draws from Rand.
+	rng *rand.Rand
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{
+		service:  newHistogram(),
+		response: newHistogram(),
+	}
+}
+
+func newHistogram() *hdrhistogram.Histogram {
+	return hdrhistogram.New(histogramMin, histogramMax, histogramDigits)
+}
+
+// Record adds one operation's service time.
+func (r *Recorder) Record(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.service.RecordValue(toMicros(d))
+	dashboardRecorded(r, d)
+}
+
+// RecordResponse adds one open-loop operation's response time, measured from
+// when it was scheduled to start.
+func (r *Recorder) RecordResponse(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.response.RecordValue(toMicros(d))
+}
+
+// Merge adds everything recorded in other to r. Concurrent tests give each
+// worker its own Recorder and merge them at the end.
+func (r *Recorder) Merge(other *Recorder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	other.mu.Lock()
+	defer other.mu.Unlock()
+	r.service.Merge(other.service)
+	r.response.Merge(other.response)
+	r.thought = r.thought || other.thought
+}
+
+// Worker summarizes r as one worker's share of a concurrent test that ran
+// for elapsed.
+func (r *Recorder) Worker(worker, errors int, elapsed time.Duration) WorkerStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := WorkerStats{Worker: worker, Operations: int(r.service.TotalCount()), Errors: errors}
+	if elapsed > 0 {
+		stats.OperationsPerSec = float64(stats.Operations) / elapsed.Seconds()
+	}
+	if stats.Operations > 0 {
+		stats.AverageDuration = time.Duration(r.service.Mean() * float64(time.Microsecond))
+		stats.P99Duration = percentile(r.service, 99)
+	}
+	return stats
+}
+
+// Count is the number of service times recorded.
+func (r *Recorder) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return int(r.service.TotalCount())
+}
+
+// Summarize computes latency percentiles and throughput from the recorded
+// latencies, like the package-level Summarize does from a slice. A test
+// cut short by an interrupt or its suite's time limit is marked partial.
+func (r *Recorder) Summarize(testName, database string, totalOps, concurrency, success, errors int, totalDuration time.Duration) Result {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Operations in flight when the run was interrupted fail with it, so
+	// any test summarized after that is partial, as is one whose loop
+	// ended at the suite's time limit.
+	partial := Stopping()
+	if partial {
+		totalOps = min(totalOps, success+errors)
+	}
+
+	result := Result{
+		TestName:      testName,
+		Database:      database,
+		NumOperations: totalOps,
+		Concurrency:   concurrency,
+		Partial:       partial,
+		TotalDuration: totalDuration,
+		SuccessCount:  success,
+		ErrorCount:    errors,
+		Timestamp:     time.Now(),
+	}
+	if totalDuration > 0 {
+		result.OperationsPerSec = float64(totalOps) / totalDuration.Seconds()
+	}
+	result.TimeoutCount = min(takeTimeouts(), errors)
+	result.ErrorCount -= result.TimeoutCount
+	if phases := takePhases(); len(phases) > 0 && totalOps > 0 {
+		result.Phases = make(map[string]time.Duration, len(phases))
+		for phase, total := range phases {
+			result.Phases[phase] = total / time.Duration(totalOps)
+		}
+	}
+	if retried, added := takeRetries(); retried > 0 && totalOps > 0 {
+		result.Retries = retried
+		result.RetriesPerOp = float64(retried) / float64(totalOps)
+		result.RetryLatency = added / time.Duration(totalOps)
+	}
+	if r.service.TotalCount() == 0 {
+		return result
+	}
+	captureHistogram(testName, r.service)
+
+	result.AverageDuration = time.Duration(r.service.Mean() * float64(time.Microsecond))
+	result.Latency = distribution(r.service)
+	result.MedianDuration = result.Latency.P50
+	result.P95Duration = result.Latency.P95
+	result.P99Duration = result.Latency.P99
+
+	corrected := r.response
+	if corrected.TotalCount() == 0 {
+		expected := r.service.ValueAtQuantile(50)
+		if r.thought {
+			expected += int64(thinkMean / time.Microsecond)
+			result.ThinkTime = thinkMean
+		}
+		corrected = backfillOmitted(r.service, expected)
+	}
+	result.CorrectedP95Duration = percentile(corrected, 95)
+	result.CorrectedP99Duration = percentile(corrected, 99)
+	return result
+}
+
+// distribution reads the standard percentiles and the curve off h. The
+// result's median, P95 and P99 are the same figures.
+func distribution(h *hdrhistogram.Histogram) *Distribution {
+	d := &Distribution{
+		P50:  percentile(h, 50),
+		P90:  percentile(h, 90),
+		P95:  percentile(h, 95),
+		P99:  percentile(h, 99),
+		P999: percentile(h, 99.9),
+		Max:  fromMicros(h.Max()),
+	}
+	for _, bracket := range h.CumulativeDistributionWithTicks(curveTicksPerHalf) {
+		d.Curve = append(d.Curve, CurvePoint{Percentile: bracket.Quantile, Latency: fromMicros(bracket.ValueAt)})
+	}
+	return d
+}
+
+// backfillOmitted corrects closed-loop latencies for coordinated omission,
+// the way HdrHistogram's expected-interval recording does. A worker stuck on
+// an operation that took d would, on a steady schedule of one operation per
+// expected interval, have issued d/expected more in that time, each waiting
+// a little less than the last; closed-loop runs never send them, so their
+// latencies are added here: d-expected, d-2*expected, and so on down to
+// expected. The median service time, plus the think time when workers
+// paused for it, stands in for the expected interval.
+func backfillOmitted(h *hdrhistogram.Histogram, expected int64) *hdrhistogram.Histogram {
+	corrected := newHistogram()
+	for _, bar := range h.Distribution() {
+		if bar.Count == 0 {
+			continue
+		}
+		corrected.RecordValues(bar.To, bar.Count)
+		if expected <= 0 {
+			continue
+		}
+		for missed := bar.To - expected; missed >= expected; missed -= expected {
+			corrected.RecordValues(missed, bar.Count)
+		}
+	}
+	return corrected
+}
+
+// percentile is the latency at the p-th percentile (0 to 100) of h, by
+// the nearest-rank rule stats.Percentile uses for exact values.
+func percentile(h *hdrhistogram.Histogram, p float64) time.Duration {
+	return fromMicros(h.ValueAtQuantile(p))
+}
+
+func toMicros(d time.Duration) int64 {
+	return min(max(int64(d/time.Microsecond), histogramMin), histogramMax)
+}
+
+func fromMicros(v int64) time.Duration {
+	return time.Duration(v) * time.Microsecond
+}
@@ -0,0 +1,123 @@
+package benchmark
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	durations := make([]time.Duration, 0, 100)
+	for i := 1; i <= 100; i++ {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+
+	result := Summarize("test", "PostgreSQL", 100, 1, durations, 98, 2, time.Second)
+	if result.NumOperations != 100 || result.SuccessCount != 98 || result.ErrorCount != 2 {
+		t.Errorf("counts = %d ops, %d successes, %d errors, want 100, 98, 2", result.NumOperations, result.SuccessCount, result.ErrorCount)
+	}
+	if result.OperationsPerSec != 100 {
+		t.Errorf("OperationsPerSec = %v, want 100", result.OperationsPerSec)
+	}
+
+	// Three significant figures: within 0.1% of the exact percentile.
+	for _, p := range []struct {
+		name      string
+		got, want time.Duration
+	}{
+		{"average", result.AverageDuration, 50500 * time.Microsecond},
+		{"median", result.MedianDuration, 50 * time.Millisecond},
+		{"P95", result.P95Duration, 95 * time.Millisecond},
+		{"P99", result.P99Duration, 99 * time.Millisecond},
+		{"max", result.Latency.Max, 100 * time.Millisecond},
+	} {
+		if diff := p.got - p.want; diff < -p.want/1000 || diff > p.want/1000 {
+			t.Errorf("%s = %v, want %v", p.name, p.got, p.want)
+		}
+	}
+
+	curve := result.Latency.Curve
+	for i := 1; i < len(curve); i++ {
+		if curve[i].Percentile < curve[i-1].Percentile || curve[i].Latency < curve[i-1].Latency {
+			t.Fatalf("curve not monotonic at %d: %+v then %+v", i, curve[i-1], curve[i])
+		}
+	}
+}
+
+func TestSummarizeTimeouts(t *testing.T) {
+	takeTimeouts()
+	for i := 0; i < 3; i++ {
+		CountTimeout()
+	}
+
+	// Timeouts are errors too, but never more of them than errors.
+	result := Summarize("test", "DynamoDB", 10, 1, []time.Duration{time.Millisecond}, 8, 2, time.Second)
+	if result.TimeoutCount != 2 || result.ErrorCount != 0 {
+		t.Errorf("TimeoutCount, ErrorCount = %d, %d, want 2, 0", result.TimeoutCount, result.ErrorCount)
+	}
+	if n := takeTimeouts(); n != 0 {
+		t.Errorf("%d timeouts left after Summarize", n)
+	}
+}
+
+func TestSummarizePhases(t *testing.T) {
+	takePhases()
+	RecordPhase("execute", 40*time.Millisecond)
+	RecordPhase("scan", 10*time.Millisecond)
+	RecordPhase("execute", 40*time.Millisecond)
+
+	result := Summarize("test", "PostgreSQL", 4, 1, []time.Duration{time.Millisecond}, 4, 0, time.Second)
+	if result.Phases["execute"] != 20*time.Millisecond || result.Phases["scan"] != 2500*time.Microsecond {
+		t.Errorf("Phases = %v, want execute 20ms and scan 2.5ms per operation", result.Phases)
+	}
+}
+
+func TestRecorderMerge(t *testing.T) {
+	a, b := NewRecorder(), NewRecorder()
+	a.Record(time.Millisecond)
+	b.Record(3 * time.Millisecond)
+	a.Merge(b)
+
+	if a.Count() != 2 {
+		t.Errorf("Count = %d, want 2", a.Count())
+	}
+	result := a.Summarize("test", "DynamoDB", 2, 2, 2, 0, time.Second)
+	if result.AverageDuration < 1999*time.Microsecond || result.AverageDuration > 2001*time.Microsecond {
+		t.Errorf("AverageDuration = %v, want 2ms", result.AverageDuration)
+	}
+}
+
+func TestCorrectedP99(t *testing.T) {
+	// 199 operations at the expected 100µs and one stalled for 10x that.
+	durations := make([]time.Duration, 0, 200)
+	for i := 0; i < 199; i++ {
+		durations = append(durations, 100*time.Microsecond)
+	}
+	durations = append(durations, time.Millisecond)
+
+	h := newHistogram()
+	for _, d := range durations {
+		h.RecordValue(toMicros(d))
+	}
+	// The stall backfills 900µs, 800µs and so on down to 100µs itself.
+	corrected := backfillOmitted(h, 100)
+	if n := corrected.TotalCount(); n != 209 {
+		t.Errorf("corrected %d samples, want 209", n)
+	}
+	var atExpected int64
+	for _, bar := range corrected.Distribution() {
+		if bar.To == 100 {
+			atExpected += bar.Count
+		}
+	}
+	if atExpected != 200 {
+		t.Errorf("corrected %d samples at 100µs, want 200", atExpected)
+	}
+
+	result := Summarize("test", "PostgreSQL", 200, 1, durations, 200, 0, time.Second)
+	if result.P99Duration != 100*time.Microsecond {
+		t.Errorf("P99Duration = %v, want 100µs", result.P99Duration)
+	}
+	if result.CorrectedP99Duration != 800*time.Microsecond {
+		t.Errorf("CorrectedP99Duration = %v, want 800µs", result.CorrectedP99Duration)
+	}
+}
@@ -0,0 +1,189 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// IDs is the universe of keys a seeder generated. Seed saves it to a
+// sidecar file so suites can draw their test targets uniformly from the
+// whole dataset without scanning the database for them.
+type IDs struct {
+	Accounts     []string `json:"accounts"`
+	Transactions []string `json:"transactions"`
+	Merchants    []string `json:"merchants"`
+	Users        []string `json:"users"`
+
+	// Attributes SampleBy stratifies on, index-aligned with the lists above:
+	// each transaction's age in days, each account's leg count and each
+	// merchant's transaction count at seed time.
+	TransactionAgeDays   []int `json:"transaction_age_days,omitempty"`
+	AccountLegs          []int `json:"account_legs,omitempty"`
+	MerchantTransactions []int `json:"merchant_transactions,omitempty"`
+
+	// Anomalies are the transactions injected with each anomaly, by kind
+	// (see dataset.AnomalyKinds), for reconciliation jobs to be checked
+	// against.
+	Anomalies map[string][]string `json:"anomalies,omitempty"`
+
+	// Partial is set when seeding was interrupted, so the lists only hold
+	// what was written before it stopped.
+	Partial bool `json:"partial,omitempty"`
+}
+
+// Strata is how many equal-population strata stratified sampling splits
+// each attribute's range into.
+const Strata = 4
+
+var stratified bool
+
+// SetStratified turns stratified sampling of test IDs on or off.
+func SetStratified(on bool) {
+	stratified = on
+}
+
+// Stratified reports whether suites should stratify their test IDs.
+func Stratified() bool {
+	return stratified
+}
+
+// IDSources lists the names accepted by SetIDSource.
+var IDSources = []string{"auto", "file", "db"}
+
+var idSource = "auto"
+
+// SetIDSource chooses where suites get their test IDs:
+//
+//	auto  the seeder's ID file if there is one, else a sample from the database (the default)
+//	file  the ID file only; a missing file is an error
+//	db    a uniform sample from the database, ignoring any ID file
+func SetIDSource(name string) error {
+	switch name {
+	case "", "auto", "file", "db":
+	default:
+		return fmt.Errorf("unknown ID source %q (want one of %v)", name, IDSources)
+	}
+	if name == "" {
+		name = "auto"
+	}
+	idSource = name
+	return nil
+}
+
+// IDsPath is where the ID file for database ("postgres" or "dynamodb")
+// lives.
+func IDsPath(database string) string {
+	return filepath.Join("benchmarks", "data", database+"-ids.json")
+}
+
+// SaveIDs writes the ID file for database, replacing any earlier one.
+func SaveIDs(database string, ids IDs) error {
+	path := IDsPath(database)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// RemoveIDs deletes the ID file for database, for when its data is gone.
+func RemoveIDs(database string) error {
+	if err := os.Remove(IDsPath(database)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// LoadIDs reads the ID file for database. It returns nil, with no error,
+// when suites should sample from the database instead: the source is "db",
+// or it is "auto" and there is no file.
+func LoadIDs(database string) (*IDs, error) {
+	if idSource == "db" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(IDsPath(database))
+	if os.IsNotExist(err) && idSource == "auto" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ids IDs
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return nil, fmt.Errorf("%s: %w", IDsPath(database), err)
+	}
+	return &ids, nil
+}
+
+// SampleBy returns n of ids like Sample, but when stratified sampling is on
+// and attribute lines up with ids, it sorts the IDs by attribute, splits
+// them into Strata equal-population strata (quartiles: newest to oldest,
+// quietest to busiest) and takes an equal share from each. A small sample
+// then covers the whole population instead of whatever a random draw
+// happened to favor. The result is shuffled so skewed key distributions,
+// which favor the first IDs, don't all land in one stratum.
+func SampleBy(ids []string, attribute []int, n int) []string {
+	if !stratified || len(attribute) != len(ids) || len(ids) <= n {
+		return Sample(ids, n)
+	}
+
+	order := make([]int, len(ids))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return attribute[order[a]] < attribute[order[b]] })
+
+	sample := make([]string, 0, n)
+	for s := 0; s < Strata; s++ {
+		lo, hi := s*len(order)/Strata, (s+1)*len(order)/Strata
+		share := n / Strata
+		if s < n%Strata {
+			share++
+		}
+
+		stratum := make([]string, hi-lo)
+		for i, idx := range order[lo:hi] {
+			stratum[i] = ids[idx]
+		}
+		sample = append(sample, Sample(stratum, share)...)
+	}
+	Rand().Shuffle(len(sample), func(i, j int) { sample[i], sample[j] = sample[j], sample[i] })
+	return sample
+}
+
+// SampleNewest returns n of ids like SampleBy, with ageDays the age of each.
+// Under the latest key distribution the sample is ordered oldest first, so
+// the IDs Pick favors are the newest transactions.
+func SampleNewest(ids []string, ageDays []int, n int) []string {
+	sample := SampleBy(ids, ageDays, n)
+	if !pickingLatest() || len(ageDays) != len(ids) {
+		return sample
+	}
+	ages := make(map[string]int, len(ids))
+	for i, id := range ids {
+		ages[id] = ageDays[i]
+	}
+	sort.SliceStable(sample, func(a, b int) bool { return ages[sample[a]] > ages[sample[b]] })
+	return sample
+}
+
+// Sample returns n of ids chosen uniformly at random without replacement,
+// or all of them in random order when there are no more than n.
+func Sample(ids []string, n int) []string {
+	shuffled := make([]string, len(ids))
+	copy(shuffled, ids)
+	n = min(n, len(shuffled))
+	for i := 0; i < n; i++ {
+		j := i + Rand().Intn(len(shuffled)-i)
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	}
+	return shuffled[:n]
+}
@@ -0,0 +1,129 @@
+package benchmark
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// stopGrace is how long an interrupted run gets to wind down the test in
+// progress before the results already completed are saved without it.
+const stopGrace = 5 * time.Second
+
+// interruptedExitCode is the conventional exit status after SIGINT.
+const interruptedExitCode = 130
+
+var (
+	stopped, stop = context.WithCancel(context.Background())
+
+	// activeMu guards active, the journal of the suite currently running,
+	// which a forced stop publishes in place of the suite itself.
+	activeMu sync.Mutex
+	active   *journal
+
+	// saveMu guards partialSaved, so a suite stopping at Add and a forced
+	// stop never both publish.
+	saveMu       sync.Mutex
+	partialSaved bool
+)
+
+// HandleInterrupts traps SIGINT and SIGTERM for the rest of the process and
+// returns a context that is cancelled on the first one. Tests watching
+// Stopping end early and are marked partial, and the running suite is saved
+// with "partial": true at its next Add; seeders stop and save the IDs they
+// got through. If the test in progress has not finished after stopGrace, or
+// a second signal arrives, the results completed so far are saved from the
+// suite's journal and the process exits.
+func HandleInterrupts() context.Context {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		sig := <-signals
+		slog.Warn("Stopping; partial results will be saved (interrupt again to stop now)", "signal", sig)
+		stop()
+
+		select {
+		case <-signals:
+		case <-time.After(stopGrace):
+			slog.Warn("Test still running", "after", stopGrace)
+		}
+		savePartial()
+		os.Exit(interruptedExitCode)
+	}()
+	return stopped
+}
+
+// Stopping reports whether the run has been interrupted or the running
+// suite is past its time limit (see SetMaxRuntime). Long loops check it
+// between operations. It is safe to call from any goroutine.
+func Stopping() bool {
+	return interrupted() || pastDeadline()
+}
+
+// interrupted reports whether the run has been interrupted.
+func interrupted() bool {
+	return stopped.Err() != nil
+}
+
+// ExitIfStopped exits with the interrupted status once an interrupted
+// command has saved what it could.
+func ExitIfStopped() {
+	if interrupted() {
+		os.Exit(interruptedExitCode)
+	}
+}
+
+// stopIfInterrupted saves the suite as partial and exits if the run has been
+// interrupted, so the tests after the one just added never start.
+func (s *Suite) stopIfInterrupted() {
+	if !interrupted() || s.name == "" {
+		return
+	}
+
+	saveMu.Lock()
+	defer saveMu.Unlock()
+	if !partialSaved {
+		partialSaved = true
+		s.Partial = true
+		slog.Warn("Saving completed results as partial", "results", len(s.Results))
+		publish(*s, s.name)
+		PrintSummary(*s)
+	}
+	os.Exit(interruptedExitCode)
+}
+
+// setActive records j as the journal of the running suite, or clears it.
+func setActive(j *journal) {
+	activeMu.Lock()
+	defer activeMu.Unlock()
+	active = j
+}
+
+// savePartial publishes the running suite's journal, for when the suite
+// itself can't be reached because a test is still running.
+func savePartial() {
+	saveMu.Lock()
+	defer saveMu.Unlock()
+	activeMu.Lock()
+	j := active
+	activeMu.Unlock()
+	if partialSaved || j == nil {
+		return
+	}
+	partialSaved = true
+
+	suite, name, err := Recover(j.path)
+	if err != nil {
+		slog.Error("Failed to recover partial results", "err", err)
+		return
+	}
+	suite.RunID = RunID
+	suite.Partial = true
+	slog.Warn("Saving completed results as partial", "results", len(suite.Results))
+	publish(suite, name)
+}
@@ -0,0 +1,196 @@
+package benchmark
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// journalSuffix names the JSON Lines file a suite appends to while it runs.
+const journalSuffix = "-results.jsonl"
+
+// journal is the on-disk copy of a running suite: a journalHeader line,
+// then one Result per line, written and synced as each test completes, so a
+// crash or a killed run keeps every result recorded before it.
+type journal struct {
+	path string
+	file *os.File
+}
+
+// journalHeader is the journal's first line.
+type journalHeader struct {
+	RunID    string    `json:"run_id,omitempty"`
+	Metadata *Metadata `json:"metadata"`
+}
+
+// NewSuite starts a suite that journals each result added to it to
+// <results dir>/<name>-results.jsonl. Save assembles the suite document and
+// removes the journal; after a crash, Recover rebuilds the suite from it.
+// If the journal cannot be created the suite still runs in memory.
+func NewSuite(name string) Suite {
+	suite := Suite{RunID: RunID, Metadata: newMetadata(), Results: make([]Result, 0), name: labeled(name)}
+	startDeadline()
+	traceSuiteName(suite.name)
+	dashboardSuite(suite.name)
+
+	path := filepath.Join(ResultsDir(), suite.name+journalSuffix)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		slog.Error("Failed to create results journal", "err", err)
+		return suite
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		slog.Error("Failed to create results journal", "err", err)
+		return suite
+	}
+	header, err := json.Marshal(journalHeader{RunID: suite.RunID, Metadata: suite.Metadata})
+	if err == nil {
+		_, err = file.Write(append(header, '\n'))
+	}
+	if err != nil {
+		slog.Error("Failed to write results journal header", "err", err)
+	}
+	suite.journal = &journal{path: path, file: file}
+	setActive(suite.journal)
+	return suite
+}
+
+// Add appends results to the suite and to its journal. Once the run has
+// been interrupted, it saves the suite as partial and exits instead of
+// returning to start the next test (see HandleInterrupts).
+func (s *Suite) Add(results ...Result) {
+	// Capacity predictions and error types are tallied from the end of the
+	// test's warm-up, so they cover the test and whatever setup or cleanup
+	// it did.
+	capacity, errorTypes := takeCapacityPredictions(), takeErrorTypes()
+	if len(results) == 1 && results[0].CapacityPredictions == nil {
+		results[0].CapacityPredictions = capacity
+	}
+	if len(results) == 1 && results[0].ErrorsByType == nil {
+		results[0].ErrorsByType = errorTypes
+	}
+	if n := takeDropped(); len(results) == 1 {
+		results[0].DroppedItems += n
+	}
+	for i := range results {
+		if results[i].Driver == "" {
+			results[i].Driver = driver()
+		}
+	}
+	// Timeouts left over were from setup or a test that doesn't use
+	// Summarize; they must not land on the next test.
+	takeTimeouts()
+	takeRetries()
+	takePhases()
+	defer s.stopIfInterrupted()
+	s.record(results)
+}
+
+// Snapshot appends an interim result of a test still running, such as a
+// soak test's periodic snapshot, to the suite and its journal, so a soak
+// killed hours in keeps every snapshot taken before it. Unlike Add it
+// leaves the running test's tallies alone.
+func (s *Suite) Snapshot(result Result) {
+	s.record([]Result{result})
+}
+
+// record stamps results with the repeat and experiment configuration under
+// way and appends them to the suite, the dashboard, the metrics and the
+// journal.
+func (s *Suite) record(results []Result) {
+	if Repeat > 0 {
+		for i := range results {
+			results[i].Repeat, results[i].RepeatRunID = Repeat, RunID
+		}
+	}
+	if Factors != nil {
+		for i := range results {
+			results[i].Factors, results[i].ExperimentRunID = Factors, RunID
+		}
+	}
+	s.Results = append(s.Results, results...)
+	dashboardAdded(results)
+	metricsAdded(s.name, results)
+	if s.journal == nil || s.journal.file == nil {
+		return
+	}
+
+	for _, result := range results {
+		line, err := json.Marshal(result)
+		if err == nil {
+			_, err = s.journal.file.Write(append(line, '\n'))
+		}
+		if err != nil {
+			slog.Error("Failed to journal result", "test", result.TestName, "err", err)
+			return
+		}
+	}
+	if err := s.journal.file.Sync(); err != nil {
+		slog.Error("Failed to sync results journal", "err", err)
+	}
+}
+
+// finish closes the journal and, once the suite document is safely
+// published, removes it.
+func (s Suite) finish(published bool) {
+	if s.journal == nil {
+		return
+	}
+	activeMu.Lock()
+	if active == s.journal {
+		active = nil
+	}
+	activeMu.Unlock()
+	if s.journal.file != nil {
+		s.journal.file.Close()
+		s.journal.file = nil
+	}
+	if !published {
+		slog.Warn("Partial results kept", "journal", s.journal.path)
+		return
+	}
+	if err := os.Remove(s.journal.path); err != nil && !os.IsNotExist(err) {
+		slog.Error("Failed to remove results journal", "err", err)
+	}
+}
+
+// Recover rebuilds a suite from the journal a crashed run left behind and
+// returns it with the name it was being saved under. Saving the recovered
+// suite removes the journal. A truncated last line, from a write cut off
+// mid-result, is skipped.
+func Recover(path string) (Suite, string, error) {
+	name := strings.TrimSuffix(filepath.Base(path), journalSuffix)
+	if name == filepath.Base(path) {
+		return Suite{}, "", fmt.Errorf("%s is not a results journal (want *%s)", path, journalSuffix)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return Suite{}, "", err
+	}
+	defer file.Close()
+
+	suite := Suite{Results: make([]Result, 0), journal: &journal{path: path}}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if line == 1 {
+			var header journalHeader
+			if err := json.Unmarshal(scanner.Bytes(), &header); err == nil && header.Metadata != nil {
+				suite.RunID, suite.Metadata = header.RunID, header.Metadata
+				continue
+			}
+		}
+		var result Result
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			slog.Warn("Skipping journal line", "journal", path, "line", line, "err", err)
+			continue
+		}
+		suite.Results = append(suite.Results, result)
+	}
+	return suite, name, scanner.Err()
+}
@@ -0,0 +1,65 @@
+package benchmark
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// KeyDistributions lists the names accepted by SetKeyDistribution.
+var KeyDistributions = []string{"uniform", "zipf", "hotspot", "latest", "hotkey"}
+
+// keyPickers choose an index in [0, n) for each key distribution, drawing
+// from rng.
+var keyPickers = map[string]func(rng *rand.Rand, n int) int{
+	"uniform": func(rng *rand.Rand, n int) int { return rng.Intn(n) },
+	"zipf": func(rng *rand.Rand, n int) int {
+		return int(rand.NewZipf(rng, 1.1, 1, uint64(n-1)).Uint64())
+	},
+	"hotspot": func(rng *rand.Rand, n int) int {
+		hot := max(1, n/10)
+		if rng.Float64() < 0.9 {
+			return rng.Intn(hot)
+		}
+		return rng.Intn(n)
+	},
+	"latest": func(rng *rand.Rand, n int) int {
+		return n - 1 - min(n-1, int(rng.ExpFloat64()*float64(n)/10))
+	},
+	"hotkey": func(*rand.Rand, int) int { return 0 },
+}
+
+// keys is the configured key distribution, set before any test runs.
+var keys = "uniform"
+
+// SetKeyDistribution chooses how Pick spreads operations over the test IDs:
+//
+//	uniform  every ID equally likely (the default)
+//	zipf     Zipf with s=1.1, so a few IDs take most of the traffic
+//	hotspot  90% of picks land on the first 10% of IDs
+//	latest   the last ID most often, earlier ones exponentially less, like
+//	         YCSB's latest; SampleNewest lists transactions oldest first
+//	hotkey   every pick is the first ID, a single hot row or partition
+func SetKeyDistribution(name string) error {
+	if name == "" {
+		name = "uniform"
+	}
+	if _, ok := keyPickers[name]; !ok {
+		return fmt.Errorf("unknown key distribution %q (want one of %v)", name, KeyDistributions)
+	}
+	keys = name
+	return nil
+}
+
+// Pick returns an index in [0, n) following the configured key distribution,
+// drawn from rng: a worker's own source (see RunWorkers), or Rand outside
+// them. Suites use it instead of rng.Intn when choosing which account,
+// merchant or transaction an operation targets, so a skewed run stresses the
+// same hot entities throughout.
+func Pick(rng *rand.Rand, n int) int {
+	return keyPickers[keys](rng, n)
+}
+
+// pickingLatest reports whether the latest distribution is configured.
+func pickingLatest() bool {
+	return keys == "latest"
+}
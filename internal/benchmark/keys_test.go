@@ -0,0 +1,68 @@
+package benchmark
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestPick(t *testing.T) {
+	defer SetKeyDistribution("")
+	for _, tc := range []struct {
+		distribution string
+		// hot is the index range [lo, hi) that should take at least share
+		// of 10000 picks from 100 keys.
+		lo, hi int
+		share  float64
+	}{
+		{"uniform", 0, 50, 0.4},
+		{"zipf", 0, 10, 0.5},
+		{"hotspot", 0, 10, 0.85},
+		{"latest", 90, 100, 0.6},
+		{"hotkey", 0, 1, 1},
+	} {
+		if err := SetKeyDistribution(tc.distribution); err != nil {
+			t.Fatal(err)
+		}
+		hot := 0
+		for i := 0; i < 10000; i++ {
+			p := Pick(Rand(), 100)
+			if p < 0 || p >= 100 {
+				t.Fatalf("%s picked %d of 100", tc.distribution, p)
+			}
+			if p >= tc.lo && p < tc.hi {
+				hot++
+			}
+		}
+		if share := float64(hot) / 10000; share < tc.share {
+			t.Errorf("%s put %.1f%% of picks in [%d, %d), want at least %.0f%%", tc.distribution, share*100, tc.lo, tc.hi, tc.share*100)
+		}
+	}
+	if err := SetKeyDistribution("gaussian"); err == nil {
+		t.Error("distribution gaussian accepted")
+	}
+	if got := Pick(Rand(), 1); got != 0 {
+		t.Errorf("picked %d of 1", got)
+	}
+}
+
+func TestSampleNewest(t *testing.T) {
+	defer SetKeyDistribution("")
+	ids := make([]string, 50)
+	ages := make([]int, 50)
+	for i := range ids {
+		ids[i], ages[i] = strconv.Itoa(i), i
+	}
+
+	SetKeyDistribution("latest")
+	sample := SampleNewest(ids, ages, 20)
+	if len(sample) != 20 {
+		t.Fatalf("sampled %d, want 20", len(sample))
+	}
+	for i := 1; i < len(sample); i++ {
+		prev, _ := strconv.Atoi(sample[i-1])
+		cur, _ := strconv.Atoi(sample[i])
+		if prev < cur {
+			t.Fatalf("sample %v is not oldest first", sample)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package benchmark
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// logOutput is where the handler SetLogging installs writes, standard error
+// unless the dashboard has redirected it.
+var logOutput = struct {
+	sync.Mutex
+	w io.Writer
+}{w: os.Stderr}
+
+type logWriter struct{}
+
+func (logWriter) Write(p []byte) (int, error) {
+	logOutput.Lock()
+	defer logOutput.Unlock()
+	return logOutput.w.Write(p)
+}
+
+// setLogOutput points the log at w, both the handler SetLogging installs
+// and the standard logger slog falls back to without one.
+func setLogOutput(w io.Writer) {
+	logOutput.Lock()
+	logOutput.w = w
+	logOutput.Unlock()
+	log.SetOutput(w)
+}
+
+// SetLogging makes slog's default logger write records at level and above
+// in format, "text" for key=value lines or "json" for a JSON object per
+// line, to standard error.
+func SetLogging(format string, level slog.Level) error {
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch format {
+	case "", "text":
+		handler = slog.NewTextHandler(logWriter{}, opts)
+	case "json":
+		handler = slog.NewJSONHandler(logWriter{}, opts)
+	default:
+		return fmt.Errorf("unknown log format %q (want text or json)", format)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// Fatal logs msg and args at error level and exits with status 1.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,39 @@
+package benchmark
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSetLogging(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	defer setLogOutput(os.Stderr)
+
+	var buf bytes.Buffer
+	setLogOutput(&buf)
+	if err := SetLogging("json", slog.LevelWarn); err != nil {
+		t.Fatal(err)
+	}
+	slog.Info("Benchmarking", "test", "Point Reads")
+	slog.Warn("Ledger out of balance", "difference", "0.0100")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("logged %q, want only the warning", buf.String())
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("record %q is not JSON: %v", lines[0], err)
+	}
+	if record["level"] != "WARN" || record["msg"] != "Ledger out of balance" || record["difference"] != "0.0100" {
+		t.Errorf("record = %v", record)
+	}
+
+	if err := SetLogging("xml", slog.LevelInfo); err == nil {
+		t.Error("SetLogging(xml) succeeded, want an error")
+	}
+}
@@ -0,0 +1,184 @@
+package benchmark
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metadata is the context a suite ran in, so results from different
+// machines, commits and datasets can be told apart and compared fairly.
+type Metadata struct {
+	StartedAt time.Time `json:"started_at"`
+	GitSHA    string    `json:"git_sha,omitempty"`
+	// GitDirty is set when the working tree had uncommitted changes.
+	GitDirty  bool          `json:"git_dirty,omitempty"`
+	GoVersion string        `json:"go_version"`
+	Host      HostInfo      `json:"host"`
+	Database  *DatabaseInfo `json:"database,omitempty"`
+	// Parameters are the settings the suite ran with, as benchctl flags.
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// HostInfo describes the machine the benchmark client ran on.
+type HostInfo struct {
+	OS          string `json:"os"`
+	Arch        string `json:"arch"`
+	CPUs        int    `json:"cpus"`
+	CPUModel    string `json:"cpu_model,omitempty"`
+	MemoryBytes int64  `json:"memory_bytes,omitempty"`
+}
+
+// DatabaseInfo describes the database a suite ran against, as its backend
+// found it on connecting.
+type DatabaseInfo struct {
+	Name          string `json:"name"`
+	Target        string `json:"target,omitempty"`
+	ServerVersion string `json:"server_version,omitempty"`
+	// Driver is the client: pq or pgx for PostgreSQL, aws-sdk-go-v2 for
+	// DynamoDB.
+	Driver string `json:"driver,omitempty"`
+	// RowCounts are the rows per table (PostgreSQL) or items in the table
+	// (DynamoDB) at the start of the suite. Both databases only keep
+	// estimates cheaply: PostgreSQL's live-row statistics and DynamoDB's
+	// ItemCount, which is refreshed about every six hours.
+	RowCounts map[string]int64 `json:"row_counts,omitempty"`
+}
+
+var (
+	// metadataMu guards the parameters and database info the next suite
+	// started is stamped with.
+	metadataMu sync.Mutex
+	parameters map[string]string
+	database   *DatabaseInfo
+
+	// build and host don't change during a run.
+	buildOnce sync.Once
+	build     struct {
+		sha   string
+		dirty bool
+	}
+	host HostInfo
+)
+
+// SetParameters records the settings the next suites run with.
+func SetParameters(p map[string]string) {
+	metadataMu.Lock()
+	defer metadataMu.Unlock()
+	parameters = p
+}
+
+// SetDatabaseInfo records the database the next suites run against. The
+// backends call it when they connect.
+func SetDatabaseInfo(info DatabaseInfo) {
+	metadataMu.Lock()
+	defer metadataMu.Unlock()
+	database = &info
+}
+
+// driver is the client SetDatabaseInfo recorded, or "".
+func driver() string {
+	metadataMu.Lock()
+	defer metadataMu.Unlock()
+	if database == nil {
+		return ""
+	}
+	return database.Driver
+}
+
+// newMetadata describes a suite starting now.
+func newMetadata() *Metadata {
+	buildOnce.Do(func() {
+		build.sha, build.dirty = gitRevision()
+		host = hostInfo()
+	})
+
+	metadataMu.Lock()
+	defer metadataMu.Unlock()
+	return &Metadata{
+		StartedAt:  time.Now(),
+		GitSHA:     build.sha,
+		GitDirty:   build.dirty,
+		GoVersion:  runtime.Version(),
+		Host:       host,
+		Database:   database,
+		Parameters: parameters,
+	}
+}
+
+// gitRevision is the commit the binary was built from, stamped by go build,
+// or failing that the checkout's HEAD (go run doesn't stamp it).
+func gitRevision() (string, bool) {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		var sha string
+		var dirty bool
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				sha = s.Value
+			case "vcs.modified":
+				dirty = s.Value == "true"
+			}
+		}
+		if sha != "" {
+			return sha, dirty
+		}
+	}
+
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", false
+	}
+	status, err := exec.Command("git", "status", "--porcelain", "--untracked-files=no").Output()
+	return strings.TrimSpace(string(out)), err == nil && len(strings.TrimSpace(string(status))) > 0
+}
+
+// hostInfo reads the CPU model and memory size from /proc on Linux and
+// sysctl on macOS; elsewhere they are left out.
+func hostInfo() HostInfo {
+	h := HostInfo{OS: runtime.GOOS, Arch: runtime.GOARCH, CPUs: runtime.NumCPU()}
+	switch runtime.GOOS {
+	case "linux":
+		h.CPUModel = procField("/proc/cpuinfo", "model name")
+		if kb, err := strconv.ParseInt(strings.TrimSuffix(procField("/proc/meminfo", "MemTotal"), " kB"), 10, 64); err == nil {
+			h.MemoryBytes = kb * 1024
+		}
+	case "darwin":
+		h.CPUModel = sysctl("machdep.cpu.brand_string")
+		h.MemoryBytes, _ = strconv.ParseInt(sysctl("hw.memsize"), 10, 64)
+	}
+	return h
+}
+
+// procField is the value of the first "key: value" line for key in a /proc
+// file.
+func procField(path, key string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), ":")
+		if ok && strings.TrimSpace(name) == key {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+func sysctl(name string) string {
+	out, err := exec.Command("sysctl", "-n", name).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
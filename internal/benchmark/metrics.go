@@ -0,0 +1,159 @@
+package benchmark
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	metricsOn atomic.Bool
+	// runningTest is the name of the test in progress, or "" between
+	// tests.
+	runningTest atomic.Pointer[string]
+
+	metricLabels = []string{"database", "test", "op"}
+
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bench_requests_total",
+		Help: "Statements and API calls the benchmarks sent, by database, test and SQL verb or SDK operation.",
+	}, metricLabels)
+	requestErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bench_request_errors_total",
+		Help: "Statements and API calls that failed, by error type as in a result's errors_by_type.",
+	}, append(metricLabels, "error_type"))
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "bench_request_duration_seconds",
+		Help: "Latency of the statements and API calls the benchmarks sent.",
+		// 100µs to about 6.5s.
+		Buckets: prometheus.ExponentialBuckets(0.0001, 2, 17),
+	}, metricLabels)
+	testsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "bench_tests_total",
+		Help: "Tests finished, by database and suite.",
+	}, []string{"database", "suite"})
+
+	resultLabels = []string{"database", "suite", "test"}
+
+	resultThroughput = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "bench_result_operations_per_second",
+		Help: "Each test's ops/sec in the suite's latest saved run.",
+	}, resultLabels)
+	resultLatency = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "bench_result_latency_seconds",
+		Help: "Each test's average, median, P95 and P99 latency in the suite's latest saved run.",
+	}, append(resultLabels, "stat"))
+	resultErrors = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "bench_result_errors",
+		Help: "Each test's failed operations in the suite's latest saved run.",
+	}, resultLabels)
+	suiteSaved = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "bench_suite_saved_timestamp_seconds",
+		Help: "When each suite last saved its results, as a Unix time.",
+	}, []string{"database", "suite"})
+)
+
+// ServeMetrics serves Prometheus metrics for the benchmarks this process
+// runs at /metrics on addr, such as ":9464", until it exits: counters of
+// the statements and API calls the backends send and of those that fail,
+// and a histogram of their latency, each labeled with the database, the
+// running test and the SQL verb or SDK operation, plus a count of finished
+// tests per suite. Each suite saved is also reported as gauges of its
+// tests' throughput, latency and errors, replaced at its next save, so a
+// long-running process such as the daemon shows the trend of every test
+// over time. It returns once the address is listening.
+func ServeMetrics(addr string) error {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(requestsTotal, requestErrorsTotal, requestDuration, testsTotal,
+		resultThroughput, resultLatency, resultErrors, suiteSaved,
+		collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("serve metrics: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			slog.Error("Metrics server stopped", "err", err)
+		}
+	}()
+	metricsOn.Store(true)
+	slog.Info("Serving metrics", "url", fmt.Sprintf("http://%s/metrics", listener.Addr()))
+	return nil
+}
+
+// MetricsEnabled reports whether ServeMetrics is serving, so backends report
+// their requests when it is.
+func MetricsEnabled() bool {
+	return metricsOn.Load()
+}
+
+// StartTest names the test now running, as its result will be named, for
+// the metrics' test label. Until a test calls it, the label is the test's
+// place in the suite ("test 3").
+func StartTest(name string) {
+	runningTest.Store(&name)
+	reseed()
+}
+
+// metricsRequest counts one request a backend sent.
+func metricsRequest(op string, latency time.Duration, err error, errorType string) {
+	if !metricsOn.Load() {
+		return
+	}
+	database, test := "", ""
+	if name := traceSuite.Load(); name != nil {
+		database = suiteDatabase(*name)
+	}
+	if name := runningTest.Load(); name != nil {
+		test = *name
+	}
+	requestsTotal.WithLabelValues(database, test, op).Inc()
+	requestDuration.WithLabelValues(database, test, op).Observe(latency.Seconds())
+	if err != nil {
+		requestErrorsTotal.WithLabelValues(database, test, op, errorType).Inc()
+	}
+}
+
+// metricsAdded counts the tests added to a suite and clears the running
+// test's name.
+func metricsAdded(suite string, results []Result) {
+	runningTest.Store(nil)
+	if !metricsOn.Load() {
+		return
+	}
+	for _, r := range results {
+		testsTotal.WithLabelValues(r.Database, suite).Inc()
+	}
+}
+
+// metricsSaved reports the results of a suite saved under name as the
+// result gauges. Failed and skipped tests are left out.
+func metricsSaved(name string, suite Suite) {
+	if !metricsOn.Load() {
+		return
+	}
+	database := suiteDatabase(name)
+	for _, r := range suite.Results {
+		if r.Failure != "" || r.Skipped != "" {
+			continue
+		}
+		resultThroughput.WithLabelValues(r.Database, name, r.TestName).Set(r.OperationsPerSec)
+		for stat, d := range map[string]time.Duration{
+			"avg": r.AverageDuration, "median": r.MedianDuration, "p95": r.P95Duration, "p99": r.P99Duration,
+		} {
+			resultLatency.WithLabelValues(r.Database, name, r.TestName, stat).Set(d.Seconds())
+		}
+		resultErrors.WithLabelValues(r.Database, name, r.TestName).Set(float64(r.ErrorCount))
+	}
+	suiteSaved.WithLabelValues(database, name).SetToCurrentTime()
+}
@@ -0,0 +1,75 @@
+package benchmark
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsLabelRunningTest(t *testing.T) {
+	t.Setenv("BENCH_RESULTS_DIR", t.TempDir())
+	metricsOn.Store(true)
+	defer metricsOn.Store(false)
+	suite := NewSuite("postgres-write")
+
+	start := time.Now().Add(-2 * time.Millisecond)
+	suite.Run(func() Result {
+		// A test that does not name itself is labeled by its place.
+		TraceOp("INSERT", "", start, nil, "", -1)
+		return fixtureResult("PostgreSQL", "Single Transaction Inserts", 1, time.Millisecond, time.Millisecond, 1000)
+	})
+	suite.Run(func() Result {
+		StartTest("Concurrent Writes (2 goroutines, 1 ops each)")
+		TraceOp("UPDATE", "acct-1", start, nil, "", -1)
+		TraceOp("UPDATE", "acct-2", start, errors.New("serialization failure"), "40 transaction_rollback", -1)
+		return fixtureResult("PostgreSQL", "Concurrent Writes (2 goroutines, 1 ops each)", 2, time.Millisecond, time.Millisecond, 1000)
+	})
+
+	concurrent := "Concurrent Writes (2 goroutines, 1 ops each)"
+	if got := testutil.ToFloat64(requestsTotal.WithLabelValues("PostgreSQL", "test 1", "INSERT")); got != 1 {
+		t.Errorf("test 1 INSERT requests = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(requestsTotal.WithLabelValues("PostgreSQL", concurrent, "UPDATE")); got != 2 {
+		t.Errorf("UPDATE requests = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(requestErrorsTotal.WithLabelValues("PostgreSQL", concurrent, "UPDATE", "40 transaction_rollback")); got != 1 {
+		t.Errorf("UPDATE errors = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(requestDuration); got < 2 {
+		t.Errorf("latency histogram has %d series, want one per test and op", got)
+	}
+	if got := testutil.ToFloat64(testsTotal.WithLabelValues("PostgreSQL", "postgres-write")); got != 2 {
+		t.Errorf("tests finished = %v, want 2", got)
+	}
+	if runningTest.Load() != nil {
+		t.Error("running test still named after its result was added")
+	}
+}
+
+func TestMetricsSavedResults(t *testing.T) {
+	metricsOn.Store(true)
+	defer metricsOn.Store(false)
+
+	test := "Point Reads - account by ID"
+	for _, opsPerSec := range []float64{800, 1200} {
+		failed := Result{TestName: "Broken (failed)", Database: "PostgreSQL", Failure: "panic: boom"}
+		metricsSaved("postgres-read", Suite{Results: []Result{
+			fixtureResult("PostgreSQL", test, 100, time.Millisecond, 4*time.Millisecond, opsPerSec), failed,
+		}})
+	}
+
+	if got := testutil.ToFloat64(resultThroughput.WithLabelValues("PostgreSQL", "postgres-read", test)); got != 1200 {
+		t.Errorf("throughput = %v, want the latest save's 1200", got)
+	}
+	if got := testutil.ToFloat64(resultLatency.WithLabelValues("PostgreSQL", "postgres-read", test, "p99")); got != 0.004 {
+		t.Errorf("P99 = %v, want 0.004", got)
+	}
+	if got := testutil.CollectAndCount(resultThroughput); got != 1 {
+		t.Errorf("throughput has %d series, want the failed test left out", got)
+	}
+	if got := testutil.ToFloat64(suiteSaved.WithLabelValues("PostgreSQL", "postgres-read")); got == 0 {
+		t.Error("suite save time not set")
+	}
+}
@@ -0,0 +1,96 @@
+package benchmark
+
+import "time"
+
+// Options scales the built-in suites to the hardware they run on. Each
+// accessor takes the size a test uses by default and returns the override
+// if one was given, so the zero value runs every suite as written.
+type Options struct {
+	// Operations replaces each test's operation count. For batch tests it is
+	// the total number of items, split into batches of each batch size.
+	Operations int
+	// Concurrency replaces the worker counts concurrent tests and ramps are
+	// run at.
+	Concurrency []int
+	// BatchSize replaces the batch sizes batch tests are run at.
+	BatchSize []int
+	// Limit replaces the page size of range and history queries.
+	Limit int
+	// StepDuration replaces how long each step of a timed ramp is
+	// sustained.
+	StepDuration time.Duration
+	// SoakDuration replaces how long a soak test runs, and
+	// SnapshotInterval how often it snapshots its results.
+	SoakDuration     time.Duration
+	SnapshotInterval time.Duration
+}
+
+// Ops returns the operation count for a test that defaults to n.
+func (o Options) Ops(n int) int {
+	if o.Operations > 0 {
+		return o.Operations
+	}
+	return n
+}
+
+// ConcurrencyLevels returns the worker counts for a test that defaults to
+// levels.
+func (o Options) ConcurrencyLevels(levels ...int) []int {
+	if len(o.Concurrency) > 0 {
+		return o.Concurrency
+	}
+	return levels
+}
+
+// Workers returns the worker count for a test that runs at a single
+// concurrency level, n by default. With several overrides the first is used.
+func (o Options) Workers(n int) int {
+	return o.ConcurrencyLevels(n)[0]
+}
+
+// BatchSizes returns the batch sizes for a test that defaults to sizes.
+func (o Options) BatchSizes(sizes ...int) []int {
+	if len(o.BatchSize) > 0 {
+		return o.BatchSize
+	}
+	return sizes
+}
+
+// RowLimit returns the page size for a query that defaults to n.
+func (o Options) RowLimit(n int) int {
+	if o.Limit > 0 {
+		return o.Limit
+	}
+	return n
+}
+
+// Step returns how long to sustain each step of a ramp that defaults to d.
+func (o Options) Step(d time.Duration) time.Duration {
+	if o.StepDuration > 0 {
+		return o.StepDuration
+	}
+	return d
+}
+
+// Soak returns how long to run a soak test that defaults to d.
+func (o Options) Soak(d time.Duration) time.Duration {
+	if o.SoakDuration > 0 {
+		return o.SoakDuration
+	}
+	return d
+}
+
+// Snapshots returns how often a soak test that defaults to every d
+// snapshots its results.
+func (o Options) Snapshots(d time.Duration) time.Duration {
+	if o.SnapshotInterval > 0 {
+		return o.SnapshotInterval
+	}
+	return d
+}
+
+// Batches returns how many batches of size make up total items, at least
+// one.
+func Batches(total, size int) int {
+	return max(1, total/size)
+}
@@ -0,0 +1,140 @@
+package benchmark
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// traceHeader is the first row of an operation trace.
+var traceHeader = []string{"timestamp", "suite", "op", "latency_ns", "error_type", "error", "key", "consumed_capacity"}
+
+var (
+	// traceMu guards the trace file and its writer, which every worker
+	// appends to.
+	traceMu     sync.Mutex
+	traceFile   *os.File
+	traceBuf    *bufio.Writer
+	traceWriter *csv.Writer
+	traceOn     atomic.Bool
+
+	// traceSuite is the name of the suite the traced operations run under.
+	traceSuite atomic.Pointer[string]
+)
+
+// SetTrace starts writing every database operation the backends send to a
+// CSV file at path, one row each, or stops with "". Each row has when the
+// operation started, the running suite, the SDK operation or SQL verb, its
+// latency, its error type (as in Result.ErrorsByType) and error if it
+// failed, the key it addressed where the backend knows one, and for
+// DynamoDB the capacity units it consumed. A test operation that sends
+// several statements or API calls, such as a transaction, has a row for
+// each. Rows are buffered; CloseTrace writes out the rest.
+func SetTrace(path string) error {
+	if err := CloseTrace(); err != nil {
+		return err
+	}
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create trace: %w", err)
+	}
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	traceFile = file
+	traceBuf = bufio.NewWriterSize(file, 1<<20)
+	traceWriter = csv.NewWriter(traceBuf)
+	traceWriter.Write(traceHeader)
+	traceOn.Store(true)
+	return nil
+}
+
+// TraceEnabled reports whether SetTrace is writing a trace, the dashboard
+// is running or metrics are being served, any of which needs TraceOp called
+// for every request, so backends can skip it when none is.
+func TraceEnabled() bool {
+	return traceOn.Load() || DashboardEnabled() || MetricsEnabled()
+}
+
+// TraceOp adds one operation that started at start and has just finished
+// to the trace, if one is being written. errorType is the backend's name
+// for err, and consumed is negative where no capacity was reported. It is
+// safe for concurrent use. The dashboard and metrics count every operation
+// too.
+func TraceOp(op, key string, start time.Time, err error, errorType string, consumed float64) {
+	latency := time.Since(start)
+	dashboardRequest(latency, err)
+	metricsRequest(op, latency, err, errorType)
+	if !traceOn.Load() {
+		return
+	}
+
+	suite := ""
+	if name := traceSuite.Load(); name != nil {
+		suite = *name
+	}
+	message := ""
+	if err != nil {
+		message = err.Error()
+	} else {
+		errorType = ""
+	}
+	capacity := ""
+	if consumed >= 0 {
+		capacity = strconv.FormatFloat(consumed, 'f', -1, 64)
+	}
+	row := []string{start.UTC().Format(time.RFC3339Nano), suite, op, strconv.FormatInt(int64(latency), 10), errorType, message, key, capacity}
+
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	if traceWriter != nil {
+		traceWriter.Write(row)
+	}
+}
+
+// traceSuiteName records the suite operations are traced under from now on.
+func traceSuiteName(name string) {
+	traceSuite.Store(&name)
+}
+
+// flushTrace writes out the buffered trace rows, so a saved suite's
+// operations are on disk with it.
+func flushTrace() error {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	if traceWriter == nil {
+		return nil
+	}
+	traceWriter.Flush()
+	if err := traceWriter.Error(); err != nil {
+		return fmt.Errorf("write trace: %w", err)
+	}
+	if err := traceBuf.Flush(); err != nil {
+		return fmt.Errorf("write trace: %w", err)
+	}
+	return nil
+}
+
+// CloseTrace writes out and closes the trace SetTrace started, if any.
+func CloseTrace() error {
+	err := flushTrace()
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	if traceFile == nil {
+		return err
+	}
+	traceOn.Store(false)
+	if closeErr := traceFile.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("close trace: %w", closeErr)
+	}
+	traceFile, traceBuf, traceWriter = nil, nil, nil
+	return err
+}
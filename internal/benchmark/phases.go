@@ -0,0 +1,72 @@
+package benchmark
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	phasesOn bool
+
+	// phasesMu guards the time recorded in each phase since the last test
+	// was summarized or added to a suite, and the cumulative counters
+	// phases are also read from.
+	phasesMu      sync.Mutex
+	phaseTotals   = make(map[string]time.Duration)
+	phaseCounters = make(map[string]phaseCounter)
+)
+
+// phaseCounter is a running total kept elsewhere, such as the time a
+// connection pool has made callers wait, and its value when last taken.
+type phaseCounter struct {
+	total func() time.Duration
+	base  time.Duration
+}
+
+// SetPhases turns the latency breakdown on. The backends then time the
+// phases of every operation (see RecordPhase), and each result reports the
+// average time per operation spent in each.
+func SetPhases(on bool) {
+	phasesOn = on
+}
+
+// PhasesEnabled reports whether the backends should time phases.
+func PhasesEnabled() bool {
+	return phasesOn
+}
+
+// RecordPhase adds d to the time spent in phase.
+func RecordPhase(phase string, d time.Duration) {
+	phasesMu.Lock()
+	defer phasesMu.Unlock()
+	phaseTotals[phase] += d
+}
+
+// SetPhaseCounter reads phase from a running total as well, replacing any
+// counter set for it before. Only its growth from now on is counted.
+func SetPhaseCounter(phase string, total func() time.Duration) {
+	phasesMu.Lock()
+	defer phasesMu.Unlock()
+	phaseCounters[phase] = phaseCounter{total: total, base: total()}
+}
+
+// takePhases returns the time spent in each phase since the last call and
+// resets the tally.
+func takePhases() map[string]time.Duration {
+	phasesMu.Lock()
+	defer phasesMu.Unlock()
+	for phase, c := range phaseCounters {
+		now := c.total()
+		if now > c.base {
+			phaseTotals[phase] += now - c.base
+		}
+		c.base = now
+		phaseCounters[phase] = c
+	}
+	if len(phaseTotals) == 0 {
+		return nil
+	}
+	taken := phaseTotals
+	phaseTotals = make(map[string]time.Duration)
+	return taken
+}
@@ -0,0 +1,80 @@
+package benchmark
+
+import (
+	"sync"
+	"time"
+)
+
+// PoolStats is how a client's connection pool served a test's operations.
+// A pool that makes operations wait, or keeps opening connections, is what
+// a test measures instead of the database.
+type PoolStats struct {
+	// WaitCount is the operations that found no idle connection and waited
+	// for one, and WaitDuration their total wait. For DynamoDB that
+	// includes dialing a new HTTP connection.
+	WaitCount    int64         `json:"wait_count"`
+	WaitDuration time.Duration `json:"wait_duration_ns"`
+	// Opened is the connections the pool opened.
+	Opened int64 `json:"opened"`
+	// MaxOpen is the pool's limit on open connections, 0 for none.
+	MaxOpen int `json:"max_open,omitempty"`
+}
+
+var (
+	// poolMu guards the meter and its reading when the running test
+	// started, or after its warm-up.
+	poolMu    sync.Mutex
+	poolMeter func() PoolStats
+	poolBase  *PoolStats
+)
+
+// SetPoolMeter reads the pool's running totals before and after every
+// test Suite.Run runs, like SetWorkMeter, replacing any meter set before, or
+// stops with nil. The growth, less the test's warm-up, is the Pool its
+// result reports.
+func SetPoolMeter(read func() PoolStats) {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	poolMeter, poolBase = read, nil
+}
+
+// startPool reads the meter, if one is set, as the base the running test's
+// pool use is counted from.
+func startPool() {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	poolBase = nil
+	if poolMeter != nil {
+		stats := poolMeter()
+		poolBase = &stats
+	}
+}
+
+// addPool fills in the pool use since startPool on the test's result, if
+// it has just one.
+func addPool(results []Result) {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+	before := poolBase
+	poolBase = nil
+	if before == nil || poolMeter == nil || len(results) != 1 || results[0].Pool != nil {
+		return
+	}
+	after := poolMeter()
+	results[0].Pool = &PoolStats{
+		WaitCount:    max(0, after.WaitCount-before.WaitCount),
+		WaitDuration: max(0, after.WaitDuration-before.WaitDuration),
+		Opened:       max(0, after.Opened-before.Opened),
+		MaxOpen:      after.MaxOpen,
+	}
+}
+
+// restartPool moves the running test's base past its warm-up.
+func restartPool() {
+	poolMu.Lock()
+	running := poolBase != nil
+	poolMu.Unlock()
+	if running {
+		startPool()
+	}
+}
@@ -0,0 +1,33 @@
+package benchmark
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestSuiteRunMetersPool(t *testing.T) {
+	total := PoolStats{MaxOpen: 100}
+	SetPoolMeter(func() PoolStats { return total })
+	defer SetPoolMeter(nil)
+	if err := SetWarmup("10"); err != nil {
+		t.Fatal(err)
+	}
+	defer SetWarmup("0")
+
+	suite := &Suite{name: "postgres-write"}
+	suite.Run(func() Result {
+		// Connections opened while warming up are not the test's.
+		total.Opened += 10
+		WarmUp(1, func(*rand.Rand) error { return nil })
+		total.WaitCount += 4
+		total.WaitDuration += 20 * time.Millisecond
+		total.Opened += 2
+		return fixtureResult("PostgreSQL", "Single Transaction Inserts", 1000, time.Millisecond, time.Millisecond, 1000)
+	})
+
+	want := PoolStats{WaitCount: 4, WaitDuration: 20 * time.Millisecond, Opened: 2, MaxOpen: 100}
+	if got := suite.Results[0].Pool; got == nil || *got != want {
+		t.Errorf("Pool = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,45 @@
+package benchmark
+
+import (
+	"fmt"
+	"io"
+)
+
+// PreflightCheck is one thing benchctl preflight verifies before a run:
+// that the database answers, its schema is in place, the seed is there.
+// Err, when the check fails, says what is wrong and how to fix it.
+type PreflightCheck struct {
+	Name string
+	Err  error
+}
+
+// PrintPreflight writes a line per check of database to w, and the fix
+// under each failed one, and reports whether every check passed.
+func PrintPreflight(w io.Writer, database string, checks []PreflightCheck) bool {
+	fmt.Fprintf(w, "\n=== %s Preflight ===\n\n", database)
+	failed := 0
+	for _, c := range checks {
+		if c.Err == nil {
+			fmt.Fprintf(w, "  ok    %s\n", c.Name)
+			continue
+		}
+		failed++
+		fmt.Fprintf(w, "  FAIL  %s\n        %v\n", c.Name, c.Err)
+	}
+	if failed > 0 {
+		fmt.Fprintf(w, "\n%d of %d checks failed\n", failed, len(checks))
+		return false
+	}
+	fmt.Fprintf(w, "\nReady to run\n")
+	return true
+}
+
+// PreflightIDs checks that database's seeded-ID file, when suites would
+// read one, loads.
+func PreflightIDs(database string) PreflightCheck {
+	check := PreflightCheck{Name: "Seeded-ID file"}
+	if _, err := LoadIDs(database); err != nil {
+		check.Err = fmt.Errorf("%w; reseed with `benchctl seed --db=%s`, or pass --ids=db to sample IDs from the database", err, database)
+	}
+	return check
+}
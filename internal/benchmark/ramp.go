@@ -0,0 +1,77 @@
+package benchmark
+
+import (
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// KneeGain is the least a step of a concurrency ramp must raise throughput
+// over the step before it, as a fraction, to count as still scaling.
+const KneeGain = 0.1
+
+// Sustained runs op for duration on workers closed-loop workers, each
+// issuing its next operation as soon as the last one returns, and
+// summarizes every operation they completed. op is called with the
+// worker's source of random values.
+func Sustained(testName, database string, workers int, duration time.Duration, op func(rng *rand.Rand) error) Result {
+	slog.Info("Benchmarking", "test", testName, "duration", duration)
+	StartTest(testName)
+	WarmUp(workers, op)
+
+	// The deadline counts from once the workers have started, so the step
+	// runs for all of duration as RunWorkers times it.
+	var deadline time.Time
+	var once sync.Once
+	ran := RunWorkers(max(1, workers), func(worker int, local *Recorder, rng *rand.Rand) WorkerTally {
+		once.Do(func() { deadline = time.Now().Add(duration) })
+		var tally WorkerTally
+		for time.Now().Before(deadline) && !Stopping() {
+			opStart := time.Now()
+			err := op(rng)
+			local.Record(time.Since(opStart))
+			local.Think()
+			if err != nil {
+				tally.Errors++
+			} else {
+				tally.Successes++
+			}
+		}
+		return tally
+	})
+	return ran.Summarize(testName, database, ran.Successes+ran.Errors, workers)
+}
+
+// Ramp follows the steps of a concurrency ramp, run at rising worker
+// counts, to find its knee: the step past which adding workers stopped
+// paying off, because the next step raised throughput by less than
+// KneeGain or more than doubled P99.
+type Ramp struct {
+	// Knee is the last step before the first one past the knee, or the
+	// last step so far while every step has scaled.
+	Knee     Result
+	previous Result
+	past     bool
+}
+
+// Add marks step PastKnee if it, or a step before it, is past the knee,
+// and returns it.
+func (r *Ramp) Add(step Result) Result {
+	if !r.past && r.previous.NumOperations > 0 {
+		gain := change(step.OperationsPerSec, r.previous.OperationsPerSec)
+		r.past = gain < KneeGain || step.P99Duration > 2*r.previous.P99Duration
+	}
+	if !r.past {
+		r.Knee = step
+	}
+	step.PastKnee = r.past
+	r.previous = step
+	return step
+}
+
+// Saturated reports whether a step past the knee was seen, so that Knee
+// lies within the ramp rather than beyond its last step.
+func (r *Ramp) Saturated() bool {
+	return r.past
+}
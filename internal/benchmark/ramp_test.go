@@ -0,0 +1,65 @@
+package benchmark
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestRamp(t *testing.T) {
+	tests := []struct {
+		name      string
+		opsPerSec []float64
+		p99       []time.Duration
+		knee      int
+		saturated bool
+	}{
+		{"throughput flattens", []float64{100, 480, 900, 1500, 1600, 1550}, []time.Duration{2, 2, 2, 3, 5, 9}, 3, true},
+		{"latency doubles", []float64{100, 480, 900, 1200}, []time.Duration{2, 2, 3, 7}, 2, true},
+		{"scales throughout", []float64{100, 480, 900}, []time.Duration{2, 2, 3}, 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ramp Ramp
+			var steps []Result
+			for i, opsPerSec := range tt.opsPerSec {
+				step := fixtureResult("PostgreSQL", "Step Load", 1000, tt.p99[i]*time.Millisecond/2, tt.p99[i]*time.Millisecond, opsPerSec)
+				step.Concurrency = i + 1
+				steps = append(steps, ramp.Add(step))
+			}
+			if ramp.Knee.Concurrency != tt.knee+1 {
+				t.Errorf("knee at step %d, want %d", ramp.Knee.Concurrency-1, tt.knee)
+			}
+			if ramp.Saturated() != tt.saturated {
+				t.Errorf("Saturated = %v, want %v", ramp.Saturated(), tt.saturated)
+			}
+			for i, step := range steps {
+				if step.PastKnee != (i > tt.knee) {
+					t.Errorf("step %d PastKnee = %v", i, step.PastKnee)
+				}
+			}
+		})
+	}
+}
+
+func TestSustained(t *testing.T) {
+	calls := make(chan struct{}, 1<<20)
+	result := Sustained("Step Load - test", "PostgreSQL", 4, 20*time.Millisecond, func(*rand.Rand) error {
+		calls <- struct{}{}
+		time.Sleep(time.Millisecond)
+		if len(calls)%3 == 0 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	if result.NumOperations == 0 || result.NumOperations != result.SuccessCount+result.ErrorCount {
+		t.Errorf("%d operations, %d succeeded and %d failed", result.NumOperations, result.SuccessCount, result.ErrorCount)
+	}
+	if result.Concurrency != 4 || len(result.Workers) != 4 {
+		t.Errorf("concurrency %d with %d workers, want 4", result.Concurrency, len(result.Workers))
+	}
+	if result.TotalDuration < 20*time.Millisecond {
+		t.Errorf("ran for %v, want at least the step's 20ms", result.TotalDuration)
+	}
+}
@@ -0,0 +1,105 @@
+package benchmark
+
+import (
+	"log/slog"
+	"math"
+	"math/rand"
+	"time"
+)
+
+var targetRate float64
+
+// SetRate switches concurrent tests to open-loop mode at opsPerSec, or back
+// to closed-loop with 0.
+func SetRate(opsPerSec float64) {
+	targetRate = math.Max(0, opsPerSec)
+}
+
+// TargetRate is the rate set by SetRate; 0 means closed-loop.
+func TargetRate() float64 {
+	return targetRate
+}
+
+// tokenBucket hands out tokens at rate per second, holding up to capacity
+// so short scheduling hiccups are caught up rather than lost.
+type tokenBucket struct {
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	// Ten milliseconds' worth of tokens absorbs sleep granularity at high
+	// rates without allowing a visible burst.
+	return &tokenBucket{rate: rate, capacity: math.Max(1, rate/100), tokens: 1, last: time.Now()}
+}
+
+// take blocks until a token is available and returns the time it became
+// available, which is when the operation it admits was meant to start.
+func (b *tokenBucket) take() time.Time {
+	now := time.Now()
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return now
+	}
+
+	wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	time.Sleep(wait)
+	b.tokens = 0
+	b.last = now.Add(wait)
+	return b.last
+}
+
+// admission is one operation let through by the load generator, with the
+// time it was scheduled to start. Open-loop latencies are measured from
+// intended, so time spent queued behind a slow operation counts against the
+// database instead of vanishing from the stats. It is zero in closed-loop
+// runs, where Summarize estimates the correction instead.
+type admission struct {
+	iteration int
+	intended  time.Time
+}
+
+// Paced runs op ops times at the target rate, open-loop: a token bucket
+// admits operations on schedule whether or not earlier ones have finished,
+// and up to workers of them run at once. Admitted operations that find
+// every worker busy wait their turn instead of holding back the schedule,
+// so a database that cannot keep up shows falling achieved throughput
+// rather than a load generator that quietly slows down with it. op is
+// called with the worker's source of random values.
+func Paced(testName, database string, ops, workers int, op func(rng *rand.Rand) error) Result {
+	rate := targetRate
+	slog.Info("Open-loop", "target_ops_per_sec", rate, "workers", workers)
+
+	admitted := make(chan time.Time, ops)
+	bucket := newTokenBucket(rate)
+	pool := StartWorkers(max(1, workers), func(worker int, local *Recorder, rng *rand.Rand) WorkerTally {
+		var tally WorkerTally
+		for intended := range admitted {
+			opStart := time.Now()
+			err := op(rng)
+			local.Record(time.Since(opStart))
+			local.RecordResponse(time.Since(intended))
+			if err != nil {
+				tally.Errors++
+			} else {
+				tally.Successes++
+			}
+		}
+		return tally
+	})
+
+	for i := 0; i < ops && !Stopping(); i++ {
+		admitted <- bucket.take()
+	}
+	close(admitted)
+
+	result := pool.Wait().Summarize(testName, database, ops, workers)
+	result.TargetOpsPerSec = rate
+	slog.Info("Achieved rate", "ops_per_sec", result.OperationsPerSec, "target_ops_per_sec", rate,
+		"percent", result.OperationsPerSec/rate*100)
+	return result
+}
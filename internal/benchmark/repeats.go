@@ -0,0 +1,165 @@
+package benchmark
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/stats"
+)
+
+// Repeat, when set, is which run of a multi-run (benchctl run --runs) is
+// under way, counting from 1. Suite.Add stamps it on every result, with
+// the run ID, so the runs can be told apart from other results of the same
+// tests.
+var Repeat int
+
+// Significance is the p-value below which a difference between two sets
+// of runs is reported as significant.
+const Significance = 0.05
+
+// Repeated is one test's results over the runs of a multi-run, with the
+// spread of its throughput and P95 latency across them.
+type Repeated struct {
+	Test     string
+	Database string
+	Results  []Result
+	// Throughput is in ops/sec and P95 in milliseconds.
+	Throughput stats.Spread
+	P95        stats.Spread
+}
+
+// Repeats groups the results of multi-runs by database and test, in the
+// order each test first appears. When a test was part of more than one
+// multi-run, the latest is used. Results not from a multi-run, and partial,
+// failed or skipped ones, are left out.
+func Repeats(results []Result) []Repeated {
+	var repeats []Repeated
+	index := make(map[[2]string]int)
+	for _, r := range results {
+		if r.RepeatRunID == "" || r.Partial || r.Failure != "" || r.Skipped != "" {
+			continue
+		}
+		key := [2]string{r.Database, r.TestName}
+		i, ok := index[key]
+		if !ok {
+			i = len(repeats)
+			index[key] = i
+			repeats = append(repeats, Repeated{Test: r.TestName, Database: r.Database})
+		}
+		repeats[i].Results = append(repeats[i].Results, r)
+	}
+
+	for i := range repeats {
+		repeats[i].Results = latestRepeats(repeats[i].Results)
+		repeats[i].Throughput = stats.NewSpread(throughputs(repeats[i].Results))
+		repeats[i].P95 = stats.NewSpread(p95s(repeats[i].Results))
+	}
+	return repeats
+}
+
+// latestRepeats keeps the results of the multi-run with the latest result.
+func latestRepeats(results []Result) []Result {
+	latest := results[0]
+	for _, r := range results {
+		if r.Timestamp.After(latest.Timestamp) {
+			latest = r
+		}
+	}
+	var kept []Result
+	for _, r := range results {
+		if r.RepeatRunID == latest.RepeatRunID {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+func throughputs(results []Result) []float64 {
+	values := make([]float64, len(results))
+	for i, r := range results {
+		values[i] = r.OperationsPerSec
+	}
+	return values
+}
+
+func p95s(results []Result) []float64 {
+	values := make([]float64, len(results))
+	for i, r := range results {
+		values[i] = float64(r.P95Duration) / float64(time.Millisecond)
+	}
+	return values
+}
+
+// WriteRepeats writes each repeated test's mean, standard deviation and
+// 95% confidence interval of throughput and P95 latency as a Markdown
+// table.
+func WriteRepeats(w io.Writer, repeats []Repeated) error {
+	var b strings.Builder
+	b.WriteString("| Test | Database | Runs | ops/sec | ops/sec 95% CI | P95 ms | P95 95% CI |\n")
+	b.WriteString("|---|---|---:|---:|---:|---:|---:|\n")
+	for _, r := range repeats {
+		fmt.Fprintf(&b, "| %s | %s | %d | %s | %s | %s | %s |\n", r.Test, r.Database, r.Throughput.N,
+			formatSpread(r.Throughput, 2), formatCI(r.Throughput, 2), formatSpread(r.P95, 3), formatCI(r.P95, 3))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Difference is whether a test's repeated runs on PostgreSQL and DynamoDB
+// differ, by the Mann–Whitney U test, in throughput and in P95 latency.
+type Difference struct {
+	Postgres, DynamoDB    Repeated
+	ThroughputP, P95P     float64
+	ThroughputSignificant bool
+	P95Significant        bool
+}
+
+// difference tests the runs of the multi-runs the compared results came
+// from, or returns nil unless both databases have at least two.
+func difference(pg Result, postgres []Result, ddb Result, dynamodb []Result) *Difference {
+	pgRuns, ddbRuns := runsOf(pg, postgres), runsOf(ddb, dynamodb)
+	if len(pgRuns.Results) < 2 || len(ddbRuns.Results) < 2 {
+		return nil
+	}
+	d := &Difference{Postgres: pgRuns, DynamoDB: ddbRuns}
+	_, d.ThroughputP = stats.MannWhitney(throughputs(pgRuns.Results), throughputs(ddbRuns.Results))
+	_, d.P95P = stats.MannWhitney(p95s(pgRuns.Results), p95s(ddbRuns.Results))
+	d.ThroughputSignificant = d.ThroughputP < Significance
+	d.P95Significant = d.P95P < Significance
+	return d
+}
+
+// runsOf gathers the runs of the multi-run that result was part of.
+func runsOf(result Result, results []Result) Repeated {
+	if result.RepeatRunID == "" {
+		return Repeated{}
+	}
+	var runs []Result
+	for _, r := range results {
+		if r.RepeatRunID == result.RepeatRunID && r.TestName == result.TestName && r.Database == result.Database {
+			runs = append(runs, r)
+		}
+	}
+	repeats := Repeats(runs)
+	if len(repeats) == 0 {
+		return Repeated{}
+	}
+	return repeats[0]
+}
+
+func formatSpread(s stats.Spread, decimals int) string {
+	return fmt.Sprintf("%.*f ± %.*f", decimals, s.Mean, decimals, s.StdDev)
+}
+
+func formatCI(s stats.Spread, decimals int) string {
+	return fmt.Sprintf("%.*f to %.*f", decimals, s.CILow, decimals, s.CIHigh)
+}
+
+func formatSignificance(p float64, significant bool) string {
+	if significant {
+		return fmt.Sprintf("p=%.3f, significant", p)
+	}
+	return fmt.Sprintf("p=%.3f, not significant", p)
+}
@@ -0,0 +1,101 @@
+package benchmark
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// repeated is n runs of a multi-run of result, its throughput rising by
+// step ops/sec and its P95 by 100µs from run to run.
+func repeated(result Result, runID string, n int, step float64) []Result {
+	runs := make([]Result, n)
+	for i := range runs {
+		runs[i] = result
+		runs[i].Repeat, runs[i].RepeatRunID = i+1, runID
+		runs[i].OperationsPerSec += float64(i) * step
+		runs[i].P95Duration += time.Duration(i) * 100 * time.Microsecond
+		runs[i].Timestamp = result.Timestamp.Add(time.Duration(i) * time.Minute)
+	}
+	return runs
+}
+
+func TestRepeats(t *testing.T) {
+	pointRead := fixturePostgres()[0]
+	older := repeated(pointRead, "older", 3, 500)
+	for i := range older {
+		older[i].Timestamp = older[i].Timestamp.Add(-24 * time.Hour)
+	}
+	results := append(append(older, repeated(pointRead, "latest", 5, 10)...), fixturePostgres()[1])
+
+	repeats := Repeats(results)
+	if len(repeats) != 1 {
+		t.Fatalf("got %d repeated tests, want 1: %+v", len(repeats), repeats)
+	}
+	r := repeats[0]
+	if len(r.Results) != 5 || r.Results[0].RepeatRunID != "latest" {
+		t.Fatalf("kept %d results of %s, want the 5 of the latest multi-run", len(r.Results), r.Results[0].RepeatRunID)
+	}
+	if r.Throughput.N != 5 || r.Throughput.Mean != pointRead.OperationsPerSec+20 {
+		t.Errorf("throughput %+v, want 5 runs averaging %.0f", r.Throughput, pointRead.OperationsPerSec+20)
+	}
+	if !(r.Throughput.CILow < r.Throughput.Mean && r.Throughput.Mean < r.Throughput.CIHigh) || r.P95.StdDev <= 0 {
+		t.Errorf("throughput %+v, P95 %+v: want a spread around the mean", r.Throughput, r.P95)
+	}
+}
+
+// repeatedFixture is the point-read and history comparisons run five times
+// on each database: PostgreSQL's point reads clearly outpace DynamoDB's,
+// while the two databases' history query throughputs overlap.
+func repeatedFixture() (postgres, dynamodb []Result) {
+	pg, ddb := fixturePostgres(), fixtureDynamoDB()
+	history := ddb[2]
+	history.OperationsPerSec = pg[1].OperationsPerSec + 15
+	postgres = append(repeated(pg[0], "pg", 5, 20), repeated(pg[1], "pg", 5, 30)...)
+	dynamodb = append(repeated(ddb[1], "ddb", 5, 20), repeated(history, "ddb", 5, 30)...)
+	return postgres, dynamodb
+}
+
+func TestCompareRepeats(t *testing.T) {
+	postgres, dynamodb := repeatedFixture()
+	comparisons := Compare(postgres, dynamodb)
+	if len(comparisons) != 2 {
+		t.Fatalf("got %d comparisons, want 2", len(comparisons))
+	}
+	for _, c := range comparisons {
+		if c.Difference == nil {
+			t.Fatalf("%s: no difference from repeated runs", c.Test)
+		}
+	}
+	if d := comparisons[0].Difference; !d.ThroughputSignificant || d.Postgres.Throughput.N != 5 {
+		t.Errorf("%s: throughput p=%.3f over %d runs, want significant over 5", comparisons[0].Test, d.ThroughputP, d.Postgres.Throughput.N)
+	}
+	if d := comparisons[1].Difference; d.ThroughputSignificant {
+		t.Errorf("%s: overlapping throughputs significant at p=%.3f", comparisons[1].Test, d.ThroughputP)
+	}
+
+	// Single runs on one side leave the comparison without a difference.
+	if c := Compare(postgres, fixtureDynamoDB()); len(c) == 0 || c[0].Difference != nil {
+		t.Errorf("difference reported without repeated DynamoDB runs: %+v", c)
+	}
+}
+
+func TestCompareRepeatsGolden(t *testing.T) {
+	postgres, dynamodb := repeatedFixture()
+	comparisons := Compare(postgres, dynamodb)
+	for _, write := range []struct {
+		name  string
+		write func(*bytes.Buffer) error
+	}{
+		{"compare-repeats.md", func(b *bytes.Buffer) error { return WriteMarkdown(b, comparisons) }},
+		{"compare-repeats.html", func(b *bytes.Buffer) error { return WriteHTML(b, comparisons) }},
+		{"repeats.md", func(b *bytes.Buffer) error { return WriteRepeats(b, Repeats(append(postgres, dynamodb...))) }},
+	} {
+		var buf bytes.Buffer
+		if err := write.write(&buf); err != nil {
+			t.Fatal(fmt.Errorf("%s: %w", write.name, err))
+		}
+		golden(t, write.name, buf.Bytes())
+	}
+}
@@ -0,0 +1,186 @@
+package benchmark
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/trace"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	replayTrace []trace.Op
+	replaySpeed = 1.0
+)
+
+// opWrite is a trace write's key in Result.OperationMix; a read's is
+// OpRead.
+const opWrite = "write"
+
+// defaultReplayAmount is what a write posts when its trace line has no
+// amount.
+var defaultReplayAmount = decimal.NewFromInt(1)
+
+// SetReplay loads the trace the replay suites play back, at speed times
+// its recorded rate, so a bad file fails before any suite runs. An empty
+// path clears it.
+func SetReplay(path string, speed float64) error {
+	if speed <= 0 {
+		return fmt.Errorf("speed must be positive, got %g", speed)
+	}
+	if path == "" {
+		UseReplayTrace(nil, speed)
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	ops, err := trace.Read(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if len(ops) == 0 {
+		return fmt.Errorf("%s has no operations", path)
+	}
+	UseReplayTrace(ops, speed)
+	return nil
+}
+
+// UseReplayTrace sets the operations the replay suites play back, sorted
+// by time, and their speed.
+func UseReplayTrace(ops []trace.Op, speed float64) {
+	sort.SliceStable(ops, func(i, j int) bool { return ops[i].Time.Before(ops[j].Time) })
+	replayTrace, replaySpeed = ops, speed
+}
+
+// ReplayTrace is the trace set by SetReplay and its speed. The trace is
+// nil when none was given.
+func ReplayTrace() ([]trace.Op, float64) {
+	return replayTrace, replaySpeed
+}
+
+// ReplayOps are a backend's operations for each kind of trace operation,
+// on the account at an index into its seeded accounts.
+type ReplayOps struct {
+	Read  func(account int) error
+	Write func(account int, amount decimal.Decimal) error
+}
+
+// replayAdmission is one trace operation released on its schedule.
+type replayAdmission struct {
+	intended time.Time
+	kind     string
+	account  int
+	amount   decimal.Decimal
+}
+
+// Replay plays ops back open-loop at speed times their recorded rate:
+// each is released at its offset from the first, divided by speed, whether
+// or not earlier ones have finished, and up to workers run at once. Trace
+// keys map to accounts in the order they first appear, wrapping past the
+// last, so the trace's skew lands on the ledger intact. Latency is recorded
+// from when each operation was due, as with Paced, and each kind's in the
+// result's OperationMix. Writes without an amount post 1.00.
+func Replay(database string, ops []trace.Op, accounts, workers int, speed float64, impl ReplayOps) Result {
+	testName := fmt.Sprintf("Trace Replay - %d ops at %gx (%d workers)", len(ops), speed, workers)
+	slog.Info("Benchmarking", "test", testName, "operations", len(ops))
+	StartTest(testName)
+
+	schedule := make([]replayAdmission, len(ops))
+	keys := make(map[string]int)
+	offsets := make([]time.Duration, len(ops))
+	for i, op := range ops {
+		account, ok := keys[op.Key]
+		if !ok {
+			account = len(keys) % max(1, accounts)
+			keys[op.Key] = account
+		}
+		amount := op.Amount
+		if amount.IsZero() {
+			amount = defaultReplayAmount
+		}
+		schedule[i] = replayAdmission{kind: op.Kind, account: account, amount: amount}
+		offsets[i] = time.Duration(float64(op.Time.Sub(ops[0].Time)) / speed)
+	}
+
+	read := func(account int) error {
+		return Retrying(func() error { return impl.Read(account) })()
+	}
+	write := func(account int, amount decimal.Decimal) error {
+		return Retrying(func() error { return impl.Write(account, amount) })()
+	}
+	WarmUp(workers, func(rng *rand.Rand) error { return impl.Read(rng.Intn(max(1, accounts))) })
+
+	byKind := map[string]*Recorder{OpRead: NewRecorder(), opWrite: NewRecorder()}
+	errorsByKind := make(map[string]int)
+
+	admitted := make(chan replayAdmission, len(ops))
+	start := time.Now()
+	pool := StartWorkers(max(1, workers), func(worker int, local *Recorder, _ *rand.Rand) WorkerTally {
+		var tally WorkerTally
+		kinds := map[string]*Recorder{OpRead: NewRecorder(), opWrite: NewRecorder()}
+		kindErrors := make(map[string]int)
+		for a := range admitted {
+			opStart := time.Now()
+			var err error
+			if a.kind == OpRead {
+				err = read(a.account)
+			} else {
+				err = write(a.account, a.amount)
+			}
+			latency := time.Since(opStart)
+			local.Record(latency)
+			local.RecordResponse(time.Since(a.intended))
+			kinds[a.kind].Record(latency)
+			if err != nil {
+				tally.Errors++
+				kindErrors[a.kind]++
+			} else {
+				tally.Successes++
+			}
+		}
+		tally.Merge = func() {
+			for kind, r := range kinds {
+				byKind[kind].Merge(r)
+			}
+			for kind, n := range kindErrors {
+				errorsByKind[kind] += n
+			}
+		}
+		return tally
+	})
+
+	for i := 0; i < len(schedule) && !Stopping(); i++ {
+		due := start.Add(offsets[i])
+		time.Sleep(time.Until(due))
+		schedule[i].intended = due
+		admitted <- schedule[i]
+	}
+	close(admitted)
+
+	ran := pool.Wait()
+
+	result := ran.Summarize(testName, database, len(ops), workers)
+	result.Layout = "trace replay"
+	if len(ops) > 1 && offsets[len(ops)-1] > 0 {
+		result.TargetOpsPerSec = float64(len(ops)-1) / offsets[len(ops)-1].Seconds()
+	}
+	result.OperationMix = make(map[string]OperationStats)
+	for kind, r := range byKind {
+		if r.Count() == 0 {
+			continue
+		}
+		s := r.Worker(0, errorsByKind[kind], ran.Duration)
+		result.OperationMix[kind] = OperationStats{
+			Operations: s.Operations, Errors: s.Errors, AverageDuration: s.AverageDuration, P99Duration: s.P99Duration,
+		}
+	}
+	slog.Info("Achieved rate", "ops_per_sec", result.OperationsPerSec, "target_ops_per_sec", result.TargetOpsPerSec)
+	return result
+}
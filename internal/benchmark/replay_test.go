@@ -0,0 +1,65 @@
+package benchmark
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/trace"
+	"github.com/shopspring/decimal"
+)
+
+func TestReplay(t *testing.T) {
+	start := time.Now()
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+	ops := []trace.Op{
+		{Time: at(0), Kind: "read", Key: "ACCOUNT#a"},
+		{Time: at(100), Kind: "write", Key: "ACCOUNT#b", Amount: decimal.RequireFromString("12.50")},
+		{Time: at(200), Kind: "read", Key: "ACCOUNT#a"},
+		{Time: at(300), Kind: "write", Key: "ACCOUNT#c"},
+		{Time: at(400), Kind: "read", Key: "ACCOUNT#b"},
+	}
+
+	var mu sync.Mutex
+	var reads []int
+	posted := make(map[int]decimal.Decimal)
+	impl := ReplayOps{
+		Read: func(account int) error {
+			mu.Lock()
+			defer mu.Unlock()
+			reads = append(reads, account)
+			return nil
+		},
+		Write: func(account int, amount decimal.Decimal) error {
+			mu.Lock()
+			defer mu.Unlock()
+			posted[account] = posted[account].Add(amount)
+			return nil
+		},
+	}
+
+	began := time.Now()
+	result := Replay("PostgreSQL", ops, 2, 4, 2, impl)
+	elapsed := time.Since(began)
+
+	if elapsed < 190*time.Millisecond || elapsed > time.Second {
+		t.Errorf("replaying 400ms of trace at 2x took %v, want about 200ms", elapsed)
+	}
+	if result.NumOperations != 5 || result.SuccessCount != 5 {
+		t.Errorf("%d operations, %d succeeded, want 5 of 5", result.NumOperations, result.SuccessCount)
+	}
+	if result.TargetOpsPerSec < 19 || result.TargetOpsPerSec > 21 {
+		t.Errorf("target %.1f ops/sec, want 20 for an op every 50ms", result.TargetOpsPerSec)
+	}
+	if mix := result.OperationMix; mix[OpRead].Operations != 3 || mix[opWrite].Operations != 2 {
+		t.Errorf("operation mix %+v, want 3 reads and 2 writes", mix)
+	}
+
+	// a, b and c map to accounts 0, 1 and 0 again past the ledger's two.
+	if len(reads) != 3 || reads[0]+reads[1]+reads[2] != 1 {
+		t.Errorf("read accounts %v, want 0, 0 and 1", reads)
+	}
+	if !posted[1].Equal(decimal.RequireFromString("12.50")) || !posted[0].Equal(defaultReplayAmount) {
+		t.Errorf("posted %v, want 12.50 to account 1 and the default amount to account 0", posted)
+	}
+}
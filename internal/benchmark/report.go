@@ -0,0 +1,312 @@
+package benchmark
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/sink"
+)
+
+// Label, when set, is appended to every saved suite name so a suite run
+// several times in one matrix keeps a result per run.
+var Label string
+
+// ResultsDir is where result files and journals are written:
+// BENCH_RESULTS_DIR, or benchmarks/results.
+func ResultsDir() string {
+	if dir := os.Getenv("BENCH_RESULTS_DIR"); dir != "" {
+		return dir
+	}
+	return "benchmarks/results"
+}
+
+func labeled(name string) string {
+	if Label != "" {
+		return name + "-" + Label
+	}
+	return name
+}
+
+// Save publishes a suite under name to the sinks configured in the
+// environment (see sink.FromEnv), then removes the suite's journal. Failures
+// are logged rather than returned so a broken sink never discards the
+// summary printed after it; the journal is kept so the run can be recovered.
+func Save(suite Suite, name string) {
+	clearDeadline()
+	publish(suite, labeled(name))
+}
+
+// publish is Save for a name that already carries the label.
+func publish(suite Suite, name string) {
+	if err := flushTrace(); err != nil {
+		slog.Error("Failed to write the operation trace", "err", err)
+	}
+	metricsSaved(name, suite)
+	sloSaved(suite)
+	sinks, err := sink.FromEnv()
+	if err != nil {
+		slog.Error("Failed to configure result sinks", "err", err)
+		suite.finish(false)
+		return
+	}
+
+	if err := sinks.Write(context.Background(), sink.Report{Name: name, Suite: suite}); err != nil {
+		slog.Error("Failed to save results", "err", err)
+		suite.finish(false)
+		return
+	}
+	suite.finish(true)
+}
+
+// PrintSummary writes a human-readable summary of every result to stdout,
+// followed by a per-category verdict against the other database. Metrics
+// that a test did not record are left out.
+func PrintSummary(suite Suite) {
+	writeSummary(os.Stdout, suite)
+}
+
+func writeSummary(w io.Writer, suite Suite) {
+	fmt.Fprint(w, "\n=== Benchmark Summary ===\n\n")
+	for _, result := range suite.Results {
+		writeResult(w, result)
+		fmt.Fprintln(w)
+	}
+	writeVerdict(w, suite)
+}
+
+// PrintResult writes one result to stdout in the format used by
+// PrintSummary.
+func PrintResult(result Result) {
+	writeResult(os.Stdout, result)
+}
+
+func writeResult(w io.Writer, result Result) {
+	fmt.Fprintf(w, "Test: %s\n", result.TestName)
+	if result.Failure != "" {
+		fmt.Fprintf(w, "  FAILED: %s\n", result.Failure)
+		return
+	}
+	if result.Skipped != "" {
+		fmt.Fprintf(w, "  NOT RUN: %s\n", result.Skipped)
+		return
+	}
+	fmt.Fprintf(w, "  Operations: %d (Success: %d, Errors: %d", result.NumOperations, result.SuccessCount, result.ErrorCount)
+	if result.TimeoutCount > 0 {
+		fmt.Fprintf(w, ", Timeouts: %d", result.TimeoutCount)
+	}
+	if result.ThrottledCount > 0 {
+		fmt.Fprintf(w, ", Throttled: %d", result.ThrottledCount)
+	}
+	if result.DroppedItems > 0 {
+		fmt.Fprintf(w, ", Dropped items: %d", result.DroppedItems)
+	}
+	if result.AssertionFailures > 0 {
+		fmt.Fprintf(w, ", Assertion failures: %d", result.AssertionFailures)
+	}
+	fmt.Fprintln(w, ")")
+	assertions := make([]string, 0, len(result.FailedAssertions))
+	for name := range result.FailedAssertions {
+		assertions = append(assertions, name)
+	}
+	slices.Sort(assertions)
+	for _, name := range assertions {
+		fmt.Fprintf(w, "    %s: %d\n", name, result.FailedAssertions[name])
+	}
+	if len(result.ErrorsByType) > 0 {
+		types := make([]string, 0, len(result.ErrorsByType))
+		for errorType := range result.ErrorsByType {
+			types = append(types, errorType)
+		}
+		slices.Sort(types)
+		fmt.Fprint(w, "  Errors by Type:")
+		for i, errorType := range types {
+			if i > 0 {
+				fmt.Fprint(w, ",")
+			}
+			fmt.Fprintf(w, " %s %d", errorType, result.ErrorsByType[errorType])
+		}
+		fmt.Fprintln(w)
+	}
+	if p := result.Pool; p != nil && (p.WaitCount > 0 || p.Opened > 0) {
+		fmt.Fprintf(w, "  Pool: %d waits (%v total), %d connections opened\n", p.WaitCount, p.WaitDuration, p.Opened)
+	}
+	if result.Retries > 0 {
+		fmt.Fprintf(w, "  Retries: %d (%.3f per op, adding %v per op)\n", result.Retries, result.RetriesPerOp, result.RetryLatency)
+	}
+	if result.ThrottleOnset > 0 {
+		fmt.Fprintf(w, "  Throttling Began: %v into the test\n", result.ThrottleOnset)
+	}
+
+	fmt.Fprintf(w, "  Total Duration: %v\n", result.TotalDuration)
+	fmt.Fprintf(w, "  Ops/sec: %.2f\n", result.OperationsPerSec)
+	if result.TargetOpsPerSec > 0 {
+		fmt.Fprintf(w, "  Target Ops/sec: %.2f (achieved %.1f%%)\n", result.TargetOpsPerSec, result.OperationsPerSec/result.TargetOpsPerSec*100)
+	}
+	fmt.Fprintf(w, "  Avg Latency: %v\n", result.AverageDuration)
+	if result.P99Duration > 0 {
+		fmt.Fprintf(w, "  P95 Latency: %v\n", result.P95Duration)
+		fmt.Fprintf(w, "  P99 Latency: %v\n", result.P99Duration)
+		if result.Latency != nil {
+			fmt.Fprintf(w, "  P99.9 Latency: %v\n", result.Latency.P999)
+			fmt.Fprintf(w, "  Max Latency: %v\n", result.Latency.Max)
+		}
+		if result.CorrectedP99Duration > result.P99Duration {
+			fmt.Fprintf(w, "  P99 Latency (corrected for coordinated omission): %v\n", result.CorrectedP99Duration)
+		}
+	}
+
+	if len(result.Phases) > 0 {
+		writePhases(w, result.Phases)
+	}
+
+	if result.ThinkTime > 0 {
+		fmt.Fprintf(w, "  Think Time: %v mean per worker between operations\n", result.ThinkTime)
+	}
+	if result.LoadGenerators > 1 {
+		fmt.Fprintf(w, "  Load Generators: %d processes, merged\n", result.LoadGenerators)
+	}
+	if len(result.Workers) > 1 {
+		writeWorkerBalance(w, result.Workers)
+	}
+
+	if result.ConnectionLimit > 0 {
+		fmt.Fprintf(w, "  Connections: %d clients on %d connections, waiting %v per op for one", result.Concurrency, result.ConnectionLimit, result.ConnectionWait)
+		if result.FailureMode != "" {
+			fmt.Fprintf(w, "; failure mode: %s", result.FailureMode)
+		}
+		fmt.Fprintln(w)
+	}
+	if len(result.OperationMix) > 0 {
+		fmt.Fprint(w, "  Operation Mix:")
+		for i, name := range operationNames(result.OperationMix) {
+			if i > 0 {
+				fmt.Fprint(w, ";")
+			}
+			s := result.OperationMix[name]
+			fmt.Fprintf(w, " %s %d (avg %v, P99 %v, %d errors)", name, s.Operations, s.AverageDuration, s.P99Duration, s.Errors)
+		}
+		fmt.Fprintln(w)
+	}
+	if result.PastKnee {
+		fmt.Fprintln(w, "  Past the Knee: throughput stopped scaling with workers")
+	}
+	if result.ThroughputDrift != 0 || result.P99Drift != 0 {
+		fmt.Fprintf(w, "  Soak Drift: throughput %+.1f%%, P99 %+.1f%% from the first snapshot to the last\n", result.ThroughputDrift, result.P99Drift)
+	}
+	if result.CollectionSize > 0 {
+		fmt.Fprintf(w, "  Collection Size: %d legs\n", result.CollectionSize)
+	}
+	if result.ConsumedRCU > 0 || result.ConsumedWCU > 0 {
+		fmt.Fprintf(w, "  Capacity: %.2f RCU, %.2f WCU\n", result.ConsumedRCU, result.ConsumedWCU)
+	}
+	for _, p := range result.CapacityPredictions {
+		fmt.Fprintf(w, "  Predicted %s Capacity: %.2f vs %.2f consumed (%+.1f%%, mean |error| %.1f%% over %d requests)\n",
+			p.Operation, p.PredictedUnits, p.ConsumedUnits, p.ErrorPercent, p.MeanAbsErrorPercent, p.Requests)
+	}
+	if result.ResultSizes != nil {
+		s := result.ResultSizes
+		fmt.Fprintf(w, "  Result Size: min %d, P50 %d, P90 %d, max %d (mean %.1f)\n", s.Min, s.P50, s.P90, s.Max, s.Mean)
+	}
+	if result.LatencyPerItem > 0 {
+		fmt.Fprintf(w, "  Latency per Item: %v\n", result.LatencyPerItem)
+	}
+	if result.ItemsScanned > 0 || result.ItemsReturned > 0 {
+		fmt.Fprintf(w, "  Items: %d scanned, %d returned\n", result.ItemsScanned, result.ItemsReturned)
+	}
+	if result.FilterEfficiency > 0 {
+		fmt.Fprintf(w, "  Filter Efficiency: %.1f%%\n", result.FilterEfficiency)
+	}
+	if result.EndToEndShare > 0 {
+		fmt.Fprintf(w, "  Share of End-to-End Latency: %.2f%%\n", result.EndToEndShare)
+	}
+	if result.RowsScanned > 0 || result.RowsReturned > 0 {
+		fmt.Fprintf(w, "  Rows: %d scanned, %d returned\n", result.RowsScanned, result.RowsReturned)
+	}
+	if result.BuffersHit > 0 || result.BuffersRead > 0 || result.BuffersWritten > 0 {
+		fmt.Fprintf(w, "  Buffers: %d hit, %d read", result.BuffersHit, result.BuffersRead)
+		if result.BuffersWritten > 0 {
+			fmt.Fprintf(w, ", %d written", result.BuffersWritten)
+		}
+		fmt.Fprintln(w)
+	}
+	if result.IndexSizeBytes > 0 {
+		fmt.Fprintf(w, "  Index Size: %d bytes (leaf density %.1f%%, fragmentation %.1f%%)\n",
+			result.IndexSizeBytes, result.AvgLeafDensity, result.LeafFragmentation)
+	}
+	if result.DeadTuples > 0 {
+		fmt.Fprintf(w, "  Dead Tuples: %d\n", result.DeadTuples)
+	}
+	if result.WALBytes > 0 {
+		fmt.Fprintf(w, "  WAL Generated: %d bytes (%.0f per op)\n", result.WALBytes, result.WALPerOp())
+	}
+	if a := result.Amplification(); a > 0 {
+		unit := "pages"
+		if result.ConsumedRCU > 0 || result.ConsumedWCU > 0 {
+			unit = "capacity units"
+		}
+		fmt.Fprintf(w, "  Amplification: %.2f %s per op\n", a, unit)
+	}
+	if result.HOTUpdatePercent > 0 || result.FillFactor > 0 {
+		fmt.Fprintf(w, "  HOT Updates: %.1f%%", result.HOTUpdatePercent)
+		if result.FillFactor > 0 {
+			fmt.Fprintf(w, " at fillfactor %d", result.FillFactor)
+		}
+		fmt.Fprintln(w)
+	}
+	if result.FilesWritten > 0 {
+		fmt.Fprintf(w, "  Export: %d files, %d bytes\n", result.FilesWritten, result.BytesWritten)
+	}
+	if result.WithinCloseWindow != nil {
+		fmt.Fprintf(w, "  Within close window: %t\n", *result.WithinCloseWindow)
+	}
+}
+
+// writeWorkerBalance shows how evenly a concurrent test's load was spread:
+// the range of per-worker throughput and the slowest worker, which a
+// starved connection pool or SDK client shows up as.
+func writeWorkerBalance(w io.Writer, workers []WorkerStats) {
+	byRate := slices.Clone(workers)
+	slices.SortFunc(byRate, func(a, b WorkerStats) int { return cmp.Compare(a.OperationsPerSec, b.OperationsPerSec) })
+	slowest, median, fastest := byRate[0], byRate[len(byRate)/2], byRate[len(byRate)-1]
+
+	fmt.Fprintf(w, "  Per-Worker Ops/sec: min %.2f, median %.2f, max %.2f across %d workers\n",
+		slowest.OperationsPerSec, median.OperationsPerSec, fastest.OperationsPerSec, len(workers))
+	if median.OperationsPerSec > 0 && slowest.OperationsPerSec < median.OperationsPerSec/2 {
+		fmt.Fprintf(w, "  ⚠️  Worker %d ran at under half the median rate (%d ops, avg %v, P99 %v)\n",
+			slowest.Worker, slowest.Operations, slowest.AverageDuration, slowest.P99Duration)
+	}
+}
+
+// writePhases shows where the average operation's time went, in the order
+// the phases happen.
+func writePhases(w io.Writer, phases map[string]time.Duration) {
+	order := []string{"acquire", "execute", "scan", "marshal", "http", "unmarshal"}
+	var names, others []string
+	for _, phase := range order {
+		if _, ok := phases[phase]; ok {
+			names = append(names, phase)
+		}
+	}
+	for phase := range phases {
+		if !slices.Contains(order, phase) {
+			others = append(others, phase)
+		}
+	}
+	slices.Sort(others)
+	names = append(names, others...)
+
+	fmt.Fprint(w, "  Latency Phases (avg per op):")
+	for i, phase := range names {
+		if i > 0 {
+			fmt.Fprint(w, ",")
+		}
+		fmt.Fprintf(w, " %s %v", phase, phases[phase])
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,273 @@
+package benchmark
+
+import (
+	"slices"
+	"time"
+
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/stats"
+)
+
+// Result is the outcome of one benchmark test. Every runner, PostgreSQL or
+// DynamoDB, built-in or custom, reports this type so results from both
+// databases line up field for field. The latency and throughput fields are
+// always present; the remaining metrics only apply to some tests and are
+// omitted from JSON when unset.
+type Result struct {
+	TestName      string `json:"test_name"`
+	Database      string `json:"database"`
+	NumOperations int    `json:"num_operations"`
+	Concurrency   int    `json:"concurrency"`
+	// Driver is the client the database was reached through: pq or pgx for
+	// PostgreSQL, aws-sdk-go-v2 for DynamoDB, from the DatabaseInfo its
+	// backend set.
+	Driver string `json:"driver,omitempty"`
+	// Partial is set on a test cut short by an interrupt or by its
+	// suite's time limit; NumOperations is then the number it got through.
+	Partial bool `json:"partial,omitempty"`
+	// Failure is why the test never finished, for a test Suite.Run
+	// recorded as failed: the panic that stopped it. Only the name,
+	// database and timestamp are set alongside it.
+	Failure string `json:"failure,omitempty"`
+	// Skipped is why the test was not run, for a test due to start after
+	// its suite's time limit. It is set, like Failure, with only the name,
+	// database and timestamp.
+	Skipped string `json:"skipped,omitempty"`
+	// Repeat is which run of a multi-run (see Repeat) the result came
+	// from, counting from 1, and RepeatRunID the run ID all of its runs
+	// share.
+	Repeat      int    `json:"repeat,omitempty"`
+	RepeatRunID string `json:"repeat_run_id,omitempty"`
+	// Factors are the experiment configuration (see Factors) the result
+	// came from, by factor name, and ExperimentRunID the run ID all of the
+	// experiment's configurations share.
+	Factors         map[string]string `json:"factors,omitempty"`
+	ExperimentRunID string            `json:"experiment_run_id,omitempty"`
+	TotalDuration   time.Duration     `json:"total_duration_ms"`
+	AverageDuration time.Duration     `json:"avg_duration_ms"`
+	MedianDuration  time.Duration     `json:"median_duration_ms"`
+	P95Duration     time.Duration     `json:"p95_duration_ms"`
+	P99Duration     time.Duration     `json:"p99_duration_ms"`
+	// CorrectedP95Duration and CorrectedP99Duration are the tail latencies
+	// corrected for coordinated omission: measured from each operation's
+	// intended start in open-loop tests, and estimated by backfilling the
+	// operations a stalled worker never issued in closed-loop ones. The
+	// fields above are service times, which hide queueing behind stalls.
+	CorrectedP95Duration time.Duration `json:"corrected_p95_duration_ms,omitempty"`
+	CorrectedP99Duration time.Duration `json:"corrected_p99_duration_ms,omitempty"`
+	// Latency is the full service-time distribution, from the test's
+	// HdrHistogram.
+	Latency          *Distribution `json:"latency_distribution,omitempty"`
+	OperationsPerSec float64       `json:"operations_per_sec"`
+	// TargetOpsPerSec is the rate an open-loop test was asked to sustain;
+	// OperationsPerSec is what it achieved.
+	TargetOpsPerSec float64 `json:"target_ops_per_sec,omitempty"`
+	SuccessCount    int     `json:"success_count"`
+	ErrorCount      int     `json:"error_count"`
+	// TimeoutCount is operations that hit the operation timeout (see
+	// SetOpTimeout). They are not included in ErrorCount.
+	TimeoutCount int `json:"timeout_count,omitempty"`
+	// DroppedItems is items the test's DynamoDB batch writes left
+	// unprocessed after every retry (see CountDropped): writes that never
+	// happened although no request failed outright.
+	DroppedItems int `json:"dropped_items,omitempty"`
+	// ErrorsByType breaks down the failed requests the test made by
+	// DynamoDB exception name or PostgreSQL SQLSTATE class (see
+	// CountError), timeouts included. A request the backend or SDK retried
+	// counts once, with the error it finally failed with.
+	ErrorsByType map[string]int `json:"errors_by_type,omitempty"`
+	// Retries is how many times the retry layer (see SetRetryPolicy)
+	// retried the test's operations, and RetryLatency the latency those
+	// retries added per operation, averaged over every operation.
+	Retries      int           `json:"retries,omitempty"`
+	RetriesPerOp float64       `json:"retries_per_op,omitempty"`
+	RetryLatency time.Duration `json:"retry_latency_ns,omitempty"`
+	// Phases breaks the average operation's latency down by where the
+	// time went, when SetPhases is on: acquire, execute and scan for
+	// PostgreSQL; marshal, http and unmarshal for DynamoDB.
+	Phases map[string]time.Duration `json:"latency_phases_ns,omitempty"`
+	// AssertionFailures counts operations that completed without a transport
+	// error but failed at least one Expect check.
+	AssertionFailures int            `json:"assertion_failures"`
+	FailedAssertions  map[string]int `json:"failed_assertions,omitempty"`
+
+	// Labels that tell variants of the same test apart.
+	Step        string `json:"step,omitempty"`
+	Layout      string `json:"layout,omitempty"`
+	Role        string `json:"role,omitempty"`
+	KeyStrategy string `json:"key_strategy,omitempty"`
+	FillFactor  int    `json:"fillfactor,omitempty"`
+	// ConnectionLimit is how many connections a saturation test's clients
+	// shared: the server's free connection slots or the client pool's
+	// size. ConnectionWait is the average time an operation waited for
+	// one, and FailureMode how the test degraded past the limit (see
+	// FailureMode).
+	ConnectionLimit int           `json:"connection_limit,omitempty"`
+	ConnectionWait  time.Duration `json:"connection_wait_ns,omitempty"`
+	FailureMode     string        `json:"failure_mode,omitempty"`
+	// OperationMix breaks a YCSB workload's operations down by type (see
+	// RunYCSB).
+	OperationMix map[string]OperationStats `json:"operation_mix,omitempty"`
+	// PastKnee is set on each step of a concurrency ramp from the first
+	// one where adding workers stopped paying off (see Ramp).
+	PastKnee bool `json:"past_knee,omitempty"`
+	// SoakElapsed is set on each interim snapshot of a soak test (see
+	// Soak) to how far into the soak its interval ended. ThroughputDrift
+	// and P99Drift, on the whole soak's result, are the change from its
+	// first snapshot to its last, as percentages.
+	SoakElapsed     time.Duration `json:"soak_elapsed_ms,omitempty"`
+	ThroughputDrift float64       `json:"throughput_drift_percent,omitempty"`
+	P99Drift        float64       `json:"p99_drift_percent,omitempty"`
+	// CollectionSize is the number of legs stored under the account a
+	// per-account test read from.
+	CollectionSize int `json:"collection_size,omitempty"`
+	// ThinkTime is the mean pause each worker of a closed-loop test made
+	// between its operations (see SetThinkTime).
+	ThinkTime time.Duration `json:"think_time_ms,omitempty"`
+	// LoadGenerators is how many benchctl worker processes ran the test
+	// at once, for a result merged from theirs (see MergeGenerators).
+	LoadGenerators int `json:"load_generators,omitempty"`
+	// Workers breaks a concurrent test down by worker, to show whether the
+	// load was spread evenly or some workers were starved.
+	Workers []WorkerStats `json:"workers,omitempty"`
+	// Pool is how the client's connection pool served the test, where a
+	// pool meter is set (see SetPoolMeter).
+	Pool *PoolStats `json:"pool,omitempty"`
+	// LatencyPerItem and ResultSizes normalize reads whose operations
+	// return different numbers of items (see NormalizePerItem).
+	LatencyPerItem time.Duration `json:"latency_per_item_ns,omitempty"`
+	ResultSizes    *SizeSpread   `json:"result_sizes,omitempty"`
+
+	// DynamoDB capacity and item counts.
+	ConsumedRCU    float64 `json:"consumed_rcu,omitempty"`
+	ConsumedWCU    float64 `json:"consumed_wcu,omitempty"`
+	ThrottledCount int     `json:"throttled_count,omitempty"`
+	// ThrottleOnset is how far into the test the first request was
+	// throttled.
+	ThrottleOnset    time.Duration `json:"throttle_onset_ms,omitempty"`
+	ItemsScanned     int           `json:"items_scanned,omitempty"`
+	ItemsReturned    int           `json:"items_returned,omitempty"`
+	FilterEfficiency float64       `json:"filter_efficiency_percent,omitempty"`
+	// CapacityPredictions compares the capacity predicted from item sizes
+	// with what was consumed, for each kind of request the test made.
+	CapacityPredictions []CapacityPrediction `json:"capacity_predictions,omitempty"`
+	// EndToEndShare is, for a client-side step timed on its own, its
+	// average latency as a percentage of the full request it is part of.
+	EndToEndShare float64 `json:"end_to_end_share_percent,omitempty"`
+
+	// PostgreSQL row counts, buffer usage and storage.
+	RowsScanned       int64   `json:"rows_scanned,omitempty"`
+	RowsReturned      int     `json:"rows_returned,omitempty"`
+	BuffersHit        int64   `json:"buffers_hit,omitempty"`
+	BuffersRead       int64   `json:"buffers_read,omitempty"`
+	BuffersWritten    int64   `json:"buffers_written,omitempty"`
+	TableSizeBytes    int64   `json:"table_size_bytes,omitempty"`
+	IndexSizeBytes    int64   `json:"index_size_bytes,omitempty"`
+	AvgLeafDensity    float64 `json:"avg_leaf_density_percent,omitempty"`
+	LeafFragmentation float64 `json:"leaf_fragmentation_percent,omitempty"`
+	WALBytes          int64   `json:"wal_bytes,omitempty"`
+	// DeadTuples is the dead row versions left in the tables a test
+	// wrote to, not yet vacuumed.
+	DeadTuples int64 `json:"dead_tuples,omitempty"`
+	// HOTUpdatePercent is the share of a test's updates that were
+	// heap-only: written to the same page as the row they replaced,
+	// without new index entries.
+	HOTUpdatePercent float64 `json:"hot_update_percent,omitempty"`
+
+	// FilesWritten and BytesWritten are the files an export job produced,
+	// one per partition, and their total size.
+	FilesWritten int   `json:"files_written,omitempty"`
+	BytesWritten int64 `json:"bytes_written,omitempty"`
+
+	// WithinCloseWindow is set on end-of-day jobs that have a deadline.
+	WithinCloseWindow *bool `json:"within_close_window,omitempty"`
+
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Suite is a set of results published together.
+type Suite struct {
+	// RunID is the run that produced the results, matching the
+	// benchmark_run_id tag on the rows it wrote.
+	RunID string `json:"run_id,omitempty"`
+	// Metadata is the machine, commit, database and settings the suite
+	// ran with.
+	Metadata *Metadata `json:"metadata,omitempty"`
+	Results  []Result  `json:"results"`
+	// Partial is set when the run was interrupted before every test in
+	// the suite had run.
+	Partial bool `json:"partial,omitempty"`
+
+	// name is the labeled name NewSuite was given, and journal is set on
+	// suites started with NewSuite or rebuilt by Recover.
+	name    string
+	journal *journal
+}
+
+// WorkerStats is one worker's share of a concurrent test.
+type WorkerStats struct {
+	Worker           int           `json:"worker"`
+	Operations       int           `json:"operations"`
+	Errors           int           `json:"errors"`
+	OperationsPerSec float64       `json:"operations_per_sec"`
+	AverageDuration  time.Duration `json:"avg_duration_ns"`
+	P99Duration      time.Duration `json:"p99_duration_ns"`
+}
+
+// SetWorkers attaches per-worker stats, in worker order.
+func (r *Result) SetWorkers(workers []WorkerStats) {
+	slices.SortFunc(workers, func(a, b WorkerStats) int { return a.Worker - b.Worker })
+	r.Workers = workers
+}
+
+// SizeSpread is the distribution of how many items each operation of a
+// test returned.
+type SizeSpread struct {
+	Min  int     `json:"min"`
+	P50  int     `json:"p50"`
+	P90  int     `json:"p90"`
+	Max  int     `json:"max"`
+	Mean float64 `json:"mean"`
+}
+
+// NormalizePerItem records how many items each successful operation
+// returned, as a distribution, and the latency per item returned: the
+// operations' total latency over their total items. Reads that fetch
+// different amounts per key, like account histories, are only comparable
+// per item. durations and sizes are index-aligned, one entry per successful
+// operation.
+func (r *Result) NormalizePerItem(durations []time.Duration, sizes []int) {
+	if len(sizes) == 0 || len(sizes) != len(durations) {
+		return
+	}
+
+	var total time.Duration
+	items := 0
+	for i, d := range durations {
+		total += d
+		items += sizes[i]
+	}
+	if items > 0 {
+		r.LatencyPerItem = total / time.Duration(items)
+	}
+
+	sorted := slices.Clone(sizes)
+	slices.Sort(sorted)
+	r.ResultSizes = &SizeSpread{
+		Min:  sorted[0],
+		P50:  stats.Percentile(sorted, 50),
+		P90:  stats.Percentile(sorted, 90),
+		Max:  sorted[len(sorted)-1],
+		Mean: float64(items) / float64(len(sorted)),
+	}
+}
+
+// Summarize computes latency percentiles and throughput from individual
+// operation durations. Tests that run many operations record into a
+// Recorder instead of keeping every duration.
+func Summarize(testName, database string, totalOps, concurrency int, durations []time.Duration, success, errors int, totalDuration time.Duration) Result {
+	recorder := NewRecorder()
+	for _, d := range durations {
+		recorder.Record(d)
+	}
+	return recorder.Summarize(testName, database, totalOps, concurrency, success, errors, totalDuration)
+}
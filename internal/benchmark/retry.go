@@ -0,0 +1,113 @@
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// RetryPolicy is the client-side retry layer both backends share, so a
+// throttled DynamoDB request and a PostgreSQL transaction that lost a lock
+// or serialization race are retried the same way: up to MaxRetries times,
+// sleeping a random duration up to BaseDelay doubled per retry and capped
+// at MaxDelay (exponential backoff with full jitter).
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+var (
+	retryPolicy RetryPolicy
+	retryable   = isNetworkError
+
+	// retries and retryNanos tally the retries made and the time they
+	// added, from failed attempts and backoff, since the last test was
+	// summarized or added to a suite.
+	retries    atomic.Int64
+	retryNanos atomic.Int64
+)
+
+// SetRetryPolicy turns the retry layer on, or off with MaxRetries 0.
+func SetRetryPolicy(p RetryPolicy) {
+	p.MaxRetries = max(0, p.MaxRetries)
+	if p.MaxDelay < p.BaseDelay {
+		p.MaxDelay = p.BaseDelay
+	}
+	retryPolicy = p
+}
+
+// RetriesEnabled reports whether the retry layer is on. The DynamoDB
+// backend turns the SDK's own retries off when it is, so every retry goes
+// through the same policy.
+func RetriesEnabled() bool {
+	return retryPolicy.MaxRetries > 0
+}
+
+// SetRetryable sets which errors are transient and worth retrying. Each
+// backend registers its own when it connects; until one does, only network
+// errors are retried.
+func SetRetryable(transient func(error) bool) {
+	retryable = transient
+}
+
+// Retrying wraps a test's operation in the retry policy.
+func Retrying(op func() error) func() error {
+	retried := RetryingResult(func() (struct{}, error) { return struct{}{}, op() })
+	return func() error {
+		_, err := retried()
+		return err
+	}
+}
+
+// RetryingRand is Retrying for operations that draw from a worker's source
+// of random values, such as those RunWorkers hands its workers.
+func RetryingRand(op func(rng *rand.Rand) error) func(rng *rand.Rand) error {
+	return func(rng *rand.Rand) error {
+		return Retrying(func() error { return op(rng) })()
+	}
+}
+
+// RetryingResult is Retrying for operations that return a value.
+func RetryingResult[T any](op func() (T, error)) func() (T, error) {
+	return func() (T, error) {
+		start := time.Now()
+		value, err := op()
+		// added is the time up to the last attempt: the failed attempts
+		// and the backoff between them.
+		var added time.Duration
+		for attempt := 0; err != nil && attempt < retryPolicy.MaxRetries && retryable(err) && !Stopping(); attempt++ {
+			backoff := min(retryPolicy.MaxDelay, retryPolicy.BaseDelay<<min(attempt, 30))
+			time.Sleep(time.Duration(jitter.Int63n(int64(backoff) + 1)))
+			added = time.Since(start)
+			retries.Add(1)
+			value, err = op()
+		}
+		if added > 0 {
+			retryNanos.Add(int64(added))
+		}
+		return value, err
+	}
+}
+
+// RetryingResultRand is RetryingResult for operations that draw from a
+// worker's source of random values.
+func RetryingResultRand[T any](op func(rng *rand.Rand) (T, error)) func(rng *rand.Rand) (T, error) {
+	return func(rng *rand.Rand) (T, error) {
+		return RetryingResult(func() (T, error) { return op(rng) })()
+	}
+}
+
+// takeRetries returns the retries made since the last call and the latency
+// they added, and resets both.
+func takeRetries() (int, time.Duration) {
+	return int(retries.Swap(0)), time.Duration(retryNanos.Swap(0))
+}
+
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && !errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,102 @@
+package benchmark
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var (
+	errTransient = errors.New("transient")
+	errPermanent = errors.New("permanent")
+)
+
+// withRetries sets a fast retry policy that retries errTransient, and
+// restores the defaults when the test finishes.
+func withRetries(t *testing.T, maxRetries int) {
+	t.Helper()
+	SetRetryPolicy(RetryPolicy{MaxRetries: maxRetries, BaseDelay: time.Microsecond})
+	SetRetryable(func(err error) bool { return errors.Is(err, errTransient) })
+	t.Cleanup(func() {
+		SetRetryPolicy(RetryPolicy{})
+		SetRetryable(isNetworkError)
+		takeRetries()
+	})
+	takeRetries()
+}
+
+// failing returns the errors in order, then succeeds, counting its calls.
+func failing(calls *int, errs ...error) func() error {
+	return func() error {
+		*calls++
+		if *calls <= len(errs) {
+			return errs[*calls-1]
+		}
+		return nil
+	}
+}
+
+func TestRetryingRecovers(t *testing.T) {
+	withRetries(t, 3)
+	var calls int
+	if err := Retrying(failing(&calls, errTransient, errTransient))(); err != nil {
+		t.Fatalf("Retrying = %v, want success", err)
+	}
+	if calls != 3 {
+		t.Errorf("attempts = %d, want 3", calls)
+	}
+	if retried, added := takeRetries(); retried != 2 || added <= 0 {
+		t.Errorf("takeRetries = %d, %v, want 2 and some latency", retried, added)
+	}
+}
+
+func TestRetryingStopsOnPermanentError(t *testing.T) {
+	withRetries(t, 3)
+	var calls int
+	if err := Retrying(failing(&calls, errTransient, errPermanent))(); !errors.Is(err, errPermanent) {
+		t.Fatalf("Retrying = %v, want %v", err, errPermanent)
+	}
+	if calls != 2 {
+		t.Errorf("attempts = %d, want 2", calls)
+	}
+}
+
+func TestRetryingGivesUp(t *testing.T) {
+	withRetries(t, 2)
+	var calls int
+	if err := Retrying(failing(&calls, errTransient, errTransient, errTransient, errTransient))(); !errors.Is(err, errTransient) {
+		t.Fatalf("Retrying = %v, want %v", err, errTransient)
+	}
+	if calls != 3 {
+		t.Errorf("attempts = %d, want 3", calls)
+	}
+	if retried, _ := takeRetries(); retried != 2 {
+		t.Errorf("retries = %d, want 2", retried)
+	}
+}
+
+func TestRetryingOff(t *testing.T) {
+	withRetries(t, 0)
+	var calls int
+	if err := Retrying(failing(&calls, errTransient))(); !errors.Is(err, errTransient) {
+		t.Fatalf("Retrying = %v, want %v", err, errTransient)
+	}
+	if calls != 1 || RetriesEnabled() {
+		t.Errorf("attempts = %d, RetriesEnabled = %v, want 1 and false", calls, RetriesEnabled())
+	}
+}
+
+func TestRetryingResultValue(t *testing.T) {
+	withRetries(t, 1)
+	attempts := 0
+	value, err := RetryingResult(func() (int, error) {
+		attempts++
+		if attempts == 1 {
+			return 0, errTransient
+		}
+		return 42, nil
+	})()
+	if err != nil || value != 42 {
+		t.Errorf("RetryingResult = %d, %v, want 42, nil", value, err)
+	}
+}
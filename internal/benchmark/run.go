@@ -0,0 +1,15 @@
+package benchmark
+
+import "github.com/google/uuid"
+
+// RunID identifies this process's benchmark run. Suites tag every row or
+// item they add to the seeded dataset with it (benchmark_run_id in
+// PostgreSQL, BenchmarkRunID in DynamoDB), so benchmark writes can be told
+// apart from seed data, attributed to a run and cleaned up afterwards.
+var RunID = uuid.NewString()
+
+// NewRun starts a new benchmark run under a fresh RunID, for a process
+// that runs the suites again and again, such as the daemon.
+func NewRun() {
+	RunID = uuid.NewString()
+}
@@ -0,0 +1,26 @@
+package benchmark
+
+// Failure modes a saturation test can show, from FailureMode.
+const (
+	DegradedNone    = "none"
+	DegradedErrors  = "errors"
+	DegradedQueuing = "queuing"
+	DegradedLatency = "latency inflation"
+)
+
+// FailureMode says how r, a test run with more concurrent clients than its
+// database or connection pool takes, degraded against baseline, the same
+// test within the limit: by failing requests (over 1% of them), by making
+// them queue for a connection (over half the average latency spent waiting),
+// or by slowing down without either (P99 over twice the baseline's).
+func FailureMode(baseline, r Result) string {
+	switch {
+	case r.NumOperations > 0 && float64(r.ErrorCount) > float64(r.NumOperations)*0.01:
+		return DegradedErrors
+	case r.AverageDuration > 0 && r.ConnectionWait*2 > r.AverageDuration:
+		return DegradedQueuing
+	case baseline.P99Duration > 0 && r.P99Duration > 2*baseline.P99Duration:
+		return DegradedLatency
+	}
+	return DegradedNone
+}
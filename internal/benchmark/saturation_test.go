@@ -0,0 +1,29 @@
+package benchmark
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFailureMode(t *testing.T) {
+	baseline := fixtureResult("PostgreSQL", "Connection Saturation - pooled, 48 clients (97 slots)", 1000, 21*time.Millisecond, 25*time.Millisecond, 2300)
+	tests := []struct {
+		name   string
+		mutate func(*Result)
+		want   string
+	}{
+		{"within limit", func(r *Result) {}, DegradedNone},
+		{"refused", func(r *Result) { r.SuccessCount, r.ErrorCount = 900, 100 }, DegradedErrors},
+		{"queued", func(r *Result) { r.AverageDuration, r.ConnectionWait = 42*time.Millisecond, 21*time.Millisecond+1 }, DegradedQueuing},
+		{"slower", func(r *Result) { r.P99Duration = 60 * time.Millisecond }, DegradedLatency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := baseline
+			tt.mutate(&r)
+			if got := FailureMode(baseline, r); got != tt.want {
+				t.Errorf("FailureMode = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package benchmark
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// lockedSource is a rand.Source64 safe for concurrent use, as the math/rand
+// globals are.
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source64
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+}
+
+// lockedReader serializes reads from a seeded rand.Rand, whose Read keeps
+// state of its own, for uuid.SetRand.
+type lockedReader struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+func (r *lockedReader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rng.Read(p)
+}
+
+var (
+	seed   int64
+	source = &lockedSource{src: rand.NewSource(time.Now().UnixNano()).(rand.Source64)}
+	shared = rand.New(source)
+	// jitter is the source of retry backoff (see RetryingResult), apart
+	// from shared so that how often a test retries does not change what
+	// it draws from Rand.
+	jitterSource = &lockedSource{src: rand.NewSource(time.Now().UnixNano()).(rand.Source64)}
+	jitter       = rand.New(jitterSource)
+)
+
+// SetSeed seeds every random choice the suites make (keys, amounts,
+// operation mixes, generated IDs), so a run with the same seed repeats the
+// same operations, and a Postgres and a DynamoDB run can be handed an
+// identical sequence. Each test reseeds from the seed and its name when it
+// starts (see StartTest), so a test draws the same values whichever tests
+// ran before it, and each of a test's workers draws from a source of its
+// own (see WorkerRand and RunWorkers), so what a worker does does not
+// depend on how the workers are scheduled. 0 leaves the run unseeded, as
+// by default.
+func SetSeed(s int64) {
+	seed = s
+	if s == 0 {
+		source.Seed(time.Now().UnixNano())
+		jitterSource.Seed(time.Now().UnixNano())
+		uuid.SetRand(nil)
+		return
+	}
+	source.Seed(s)
+	jitterSource.Seed(testSeed(-1))
+	uuid.SetRand(&lockedReader{rng: rand.New(rand.NewSource(s))})
+}
+
+// Seed is the seed set by SetSeed; 0 means the run is unseeded.
+func Seed() int64 {
+	return seed
+}
+
+// Rand is the run's shared source of random values, safe for concurrent
+// use. Suites use it instead of the math/rand globals so --seed covers
+// them, outside workers, which have a source of their own.
+func Rand() *rand.Rand {
+	return shared
+}
+
+// WorkerRand is a source for one worker of the running test alone, not
+// safe for concurrent use, derived from the seed, the test's name and
+// worker, so each worker draws the same sequence on every seeded run
+// however the workers are scheduled. Unseeded, it is randomly seeded.
+func WorkerRand(worker int) *rand.Rand {
+	if seed == 0 {
+		return rand.New(rand.NewSource(shared.Int63()))
+	}
+	return rand.New(rand.NewSource(testSeed(worker + 1)))
+}
+
+// reseed restarts the shared source for the running test, when the run is
+// seeded.
+func reseed() {
+	if seed != 0 {
+		source.Seed(testSeed(0))
+		jitterSource.Seed(testSeed(-1))
+	}
+}
+
+// testSeed mixes the seed, the running test's name and stream, a worker
+// number, 0 for the shared source or -1 for jitter, into one seed.
+func testSeed(stream int) int64 {
+	h := fnv.New64a()
+	if name := runningTest.Load(); name != nil {
+		h.Write([]byte(*name))
+	}
+	return seed ^ int64(h.Sum64()) ^ int64(stream)*0x5851f42d4c957f2d
+}
@@ -0,0 +1,66 @@
+package benchmark
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSetSeed(t *testing.T) {
+	defer SetSeed(0)
+	defer SetKeyDistribution("")
+	SetKeyDistribution("zipf")
+
+	draw := func(test string) ([]int, []int, uuid.UUID) {
+		StartTest(test)
+		picks := make([]int, 20)
+		for i := range picks {
+			picks[i] = Pick(Rand(), 1000)
+		}
+		rng := WorkerRand(3)
+		worker := make([]int, 20)
+		for i := range worker {
+			worker[i] = rng.Intn(1000)
+		}
+		return picks, worker, uuid.New()
+	}
+
+	SetSeed(42)
+	picks, worker, id := draw("Point Reads")
+	Rand().Intn(10) // a test run before shifts nothing
+	SetSeed(42)
+	Rand().Float64()
+	again, workerAgain, idAgain := draw("Point Reads")
+	if !slices.Equal(picks, again) || !slices.Equal(worker, workerAgain) || id != idAgain {
+		t.Errorf("seed 42 drew %v, %v, %v then %v, %v, %v", picks, worker, id, again, workerAgain, idAgain)
+	}
+
+	other, otherWorker, _ := draw("Balance Updates")
+	if slices.Equal(picks, other) || slices.Equal(worker, otherWorker) {
+		t.Error("two tests drew the same sequence")
+	}
+	if WorkerRand(3).Int63() == WorkerRand(4).Int63() {
+		t.Error("two workers drew the same sequence")
+	}
+}
+
+func TestRunWorkersSeeded(t *testing.T) {
+	defer SetSeed(0)
+	SetSeed(42)
+
+	draw := func() []int {
+		StartTest("Concurrent Reads")
+		picks := make([]int, 8)
+		RunWorkers(len(picks), func(worker int, local *Recorder, rng *rand.Rand) WorkerTally {
+			picks[worker] = Pick(rng, 1000)
+			return WorkerTally{}
+		})
+		return picks
+	}
+	picks := draw()
+	if again := draw(); !slices.Equal(picks, again) {
+		t.Errorf("seed 42 gave the workers %v then %v", picks, again)
+	}
+}
@@ -0,0 +1,199 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// savedRun is one result file in the results directory, for the index.
+type savedRun struct {
+	Name     string
+	Database string
+	Tests    int
+	Failed   int
+	RunID    string
+	Started  time.Time
+	GitSHA   string
+	Partial  bool
+}
+
+// ResultsHandler serves a web view of the result files in ResultsDir,
+// reread on every request so runs saved while it serves show up: an index
+// of the saved runs at /, each run's charts at /runs/<name> and its summary
+// at /runs/<name>/summary, and the side-by-side comparison of both
+// databases at /compare.
+func ResultsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", serveIndex)
+	mux.HandleFunc("/runs/", serveRun)
+	mux.HandleFunc("/compare", serveCompare)
+	return mux
+}
+
+func serveIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	runs, err := savedRuns()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := indexHTML.Execute(w, struct {
+		Dir  string
+		Runs []savedRun
+	}{ResultsDir(), runs}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// serveRun serves /runs/<name>, the run's charts, and /runs/<name>/summary,
+// its summary as benchctl report prints it.
+func serveRun(w http.ResponseWriter, r *http.Request) {
+	name, page, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/runs/"), "/")
+	if page != "" && page != "summary" {
+		http.NotFound(w, r)
+		return
+	}
+	suite, err := readRun(name)
+	if os.IsNotExist(err) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if page == "summary" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		writeSummary(w, suite)
+		return
+	}
+	if len(suite.Results) == 0 {
+		http.Error(w, fmt.Sprintf("%s has no results", name), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := WriteCharts(w, suite.Results); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func serveCompare(w http.ResponseWriter, r *http.Request) {
+	postgres, err := LoadResults("postgres")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	dynamodb, err := LoadResults("dynamodb")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	comparisons := Compare(postgres, dynamodb)
+	if len(comparisons) == 0 {
+		http.Error(w, "no test has results for both databases; run the same suites with --db=postgres and --db=dynamodb first", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := WriteHTML(w, comparisons); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// savedRuns lists the result files in ResultsDir, most recently started
+// first.
+func savedRuns() ([]savedRun, error) {
+	files, err := filepath.Glob(filepath.Join(ResultsDir(), "*-results.json"))
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]savedRun, 0, len(files))
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), "-results.json")
+		suite, err := readRun(name)
+		if err != nil {
+			return nil, err
+		}
+		run := savedRun{Name: name, Tests: len(suite.Results), RunID: suite.RunID, Partial: suite.Partial}
+		run.Database, _, _ = strings.Cut(name, "-")
+		for _, result := range suite.Results {
+			if result.Failure != "" {
+				run.Failed++
+			}
+		}
+		if suite.Metadata != nil {
+			run.Started, run.GitSHA = suite.Metadata.StartedAt, suite.Metadata.GitSHA
+		} else if info, err := os.Stat(file); err == nil {
+			run.Started = info.ModTime()
+		}
+		runs = append(runs, run)
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].Started.After(runs[j].Started) })
+	return runs, nil
+}
+
+// readRun reads the result file saved under name. Names that are not a
+// plain file name are reported as not existing.
+func readRun(name string) (Suite, error) {
+	var suite Suite
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return suite, os.ErrNotExist
+	}
+	file := filepath.Join(ResultsDir(), name+"-results.json")
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return suite, err
+	}
+	if err := json.Unmarshal(data, &suite); err != nil {
+		return suite, fmt.Errorf("%s: %w", file, err)
+	}
+	return suite, nil
+}
+
+var indexHTML = template.Must(template.New("index").Funcs(template.FuncMap{
+	"started": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Local().Format("2006-01-02 15:04")
+	},
+	"short": func(sha string) string { return sha[:min(len(sha), 7)] },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Benchmark results</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; }
+td.num { text-align: right; }
+</style>
+</head>
+<body>
+<h1>Benchmark results</h1>
+<p>Result files in {{.Dir}}. <a href="/compare">Compare PostgreSQL and DynamoDB</a></p>
+{{- if .Runs}}
+<table>
+<tr><th>Run</th><th>Database</th><th>Started</th><th>Tests</th><th>Failed</th><th>Commit</th><th>Run ID</th><th></th></tr>
+{{- range .Runs}}
+<tr><td><a href="/runs/{{.Name}}">{{.Name}}</a>{{if .Partial}} (partial){{end}}</td><td>{{.Database}}</td><td>{{started .Started}}</td><td class="num">{{.Tests}}</td><td class="num">{{.Failed}}</td><td>{{short .GitSHA}}</td><td>{{.RunID}}</td><td><a href="/runs/{{.Name}}/summary">summary</a></td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>No results yet; run some suites with <code>benchctl run</code>.</p>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,53 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResultsHandler(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("BENCH_RESULTS_DIR", dir)
+	for name, result := range map[string]Result{
+		"postgres-write": fixtureResult("PostgreSQL", "Single Transaction Inserts", 100, 2*time.Millisecond, 5*time.Millisecond, 500),
+		"dynamodb-write": fixtureResult("DynamoDB", "PutItem Writes (with merchant GSI)", 100, 4*time.Millisecond, 9*time.Millisecond, 250),
+	} {
+		data, err := json.Marshal(Suite{RunID: "run-" + name, Results: []Result{result}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name+"-results.json"), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	handler := ResultsHandler()
+
+	for _, tc := range []struct {
+		path string
+		code int
+		want string
+	}{
+		{"/", http.StatusOK, `<a href="/runs/postgres-write">postgres-write</a>`},
+		{"/runs/dynamodb-write", http.StatusOK, "PutItem Writes (with merchant GSI)"},
+		{"/runs/postgres-write/summary", http.StatusOK, "Single Transaction Inserts"},
+		{"/compare", http.StatusOK, "Single writes"},
+		{"/runs/postgres-read", http.StatusNotFound, ""},
+		{"/runs/postgres-write/charts", http.StatusNotFound, ""},
+		{"/missing", http.StatusNotFound, ""},
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rec.Code != tc.code {
+			t.Errorf("GET %s = %d, want %d", tc.path, rec.Code, tc.code)
+		}
+		if !strings.Contains(rec.Body.String(), tc.want) {
+			t.Errorf("GET %s does not contain %q", tc.path, tc.want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package benchmark
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Isolations are the PostgreSQL transaction isolation levels SetIsolation
+// accepts; "default" leaves the server's default_transaction_isolation.
+var Isolations = []string{"default", "read-committed", "repeatable-read", "serializable"}
+
+// Consistencies are the DynamoDB read consistencies SetConsistency accepts.
+var Consistencies = []string{"eventual", "strong"}
+
+// Pools are the connection pooling modes SetPool accepts.
+var Pools = []string{"pooled", "unpooled"}
+
+// BillingModes are the DynamoDB table capacity modes SetBillingMode
+// accepts.
+var BillingModes = []string{"provisioned", "on-demand"}
+
+var (
+	isolation       string
+	consistentReads bool
+	unpooled        bool
+	onDemand        bool
+)
+
+// SetIsolation sets the isolation level every PostgreSQL transaction runs
+// at, as a session default on each connection, from one of Isolations.
+func SetIsolation(level string) error {
+	switch level {
+	case "", "default":
+		isolation = ""
+	case "read-committed", "repeatable-read", "serializable":
+		isolation = strings.ReplaceAll(level, "-", " ")
+	default:
+		return fmt.Errorf("unknown isolation %q (want %s)", level, strings.Join(Isolations, ", "))
+	}
+	return nil
+}
+
+// Isolation is the level SetIsolation set, in SQL spelling such as
+// "repeatable read", or "" for the server default.
+func Isolation() string {
+	return isolation
+}
+
+// SetConsistency sets whether DynamoDB reads that do not choose for
+// themselves are eventually or strongly consistent, from one of
+// Consistencies.
+func SetConsistency(consistency string) error {
+	switch consistency {
+	case "", "eventual":
+		consistentReads = false
+	case "strong":
+		consistentReads = true
+	default:
+		return fmt.Errorf("unknown consistency %q (want %s)", consistency, strings.Join(Consistencies, ", "))
+	}
+	return nil
+}
+
+// ConsistentReads reports whether SetConsistency asked for strongly
+// consistent reads.
+func ConsistentReads() bool {
+	return consistentReads
+}
+
+// SetPool sets whether the backends keep connections open between
+// operations, from one of Pools. Unpooled, every operation pays for a new
+// PostgreSQL connection or HTTP connection to DynamoDB.
+func SetPool(pool string) error {
+	switch pool {
+	case "", "pooled":
+		unpooled = false
+	case "unpooled":
+		unpooled = true
+	default:
+		return fmt.Errorf("unknown pool %q (want %s)", pool, strings.Join(Pools, ", "))
+	}
+	return nil
+}
+
+// Pooled reports whether connections are kept between operations.
+func Pooled() bool {
+	return !unpooled
+}
+
+// SetBillingMode sets whether the DynamoDB table is created with the
+// provisioned throughput schema.json declares or on-demand, from one of
+// BillingModes.
+func SetBillingMode(mode string) error {
+	switch mode {
+	case "", "provisioned":
+		onDemand = false
+	case "on-demand":
+		onDemand = true
+	default:
+		return fmt.Errorf("unknown billing mode %q (want %s)", mode, strings.Join(BillingModes, ", "))
+	}
+	return nil
+}
+
+// OnDemand reports whether SetBillingMode asked for an on-demand table.
+func OnDemand() bool {
+	return onDemand
+}
@@ -0,0 +1,240 @@
+package benchmark
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SLO is a service-level objective a run asserts, such as
+// "point_read.p99 < 10ms" or "double_entry.ops >= 500": a metric of every
+// test whose name matches Test, compared with Limit by Op. Test matches a
+// test whose name, lowercased with each run of other characters turned into
+// an underscore, contains it ("point_read" matches "Point Reads - Account
+// by ID"); "*" matches every test.
+type SLO struct {
+	Spec   string
+	Test   string
+	Metric string
+	Op     string
+	// Limit is in the metric's unit: nanoseconds for latencies, ops/sec
+	// for throughput and percent for the error rate.
+	Limit float64
+}
+
+// sloMetrics are the metrics an SLO can assert, by name.
+var sloMetrics = map[string]func(Result) float64{
+	"ops":           func(r Result) float64 { return r.OperationsPerSec },
+	"avg":           func(r Result) float64 { return float64(r.AverageDuration) },
+	"p50":           func(r Result) float64 { return float64(r.MedianDuration) },
+	"p95":           func(r Result) float64 { return float64(r.P95Duration) },
+	"p99":           func(r Result) float64 { return float64(r.P99Duration) },
+	"corrected_p99": func(r Result) float64 { return float64(r.CorrectedP99Duration) },
+	"error_rate": func(r Result) float64 {
+		if r.NumOperations == 0 {
+			return 0
+		}
+		return float64(r.ErrorCount+r.TimeoutCount) / float64(r.NumOperations) * 100
+	},
+}
+
+var sloPattern = regexp.MustCompile(`^\s*(.+)\.(\w+)\s*(<=|>=|<|>)\s*(\S+)\s*$`)
+
+// ParseSLO parses an SLO written as <test>.<metric> <op> <limit>, where
+// metric is ops, avg, p50, p95, p99, corrected_p99 or error_rate, op is <,
+// <=, > or >=, and limit is a duration for latencies ("10ms"), ops/sec for
+// ops and a percentage for error_rate ("0.5%").
+func ParseSLO(spec string) (SLO, error) {
+	m := sloPattern.FindStringSubmatch(spec)
+	if m == nil {
+		return SLO{}, fmt.Errorf("SLO %q is not <test>.<metric> <op> <limit>, such as point_read.p99 < 10ms", spec)
+	}
+	slo := SLO{Spec: strings.TrimSpace(spec), Test: testSlug(m[1]), Metric: m[2], Op: m[3]}
+	if strings.TrimSpace(m[1]) == "*" {
+		slo.Test = "*"
+	}
+	if slo.Test == "" {
+		return slo, fmt.Errorf("SLO %q names no test", spec)
+	}
+	if _, ok := sloMetrics[slo.Metric]; !ok {
+		return slo, fmt.Errorf("SLO %q: unknown metric %q (want ops, avg, p50, p95, p99, corrected_p99 or error_rate)", spec, slo.Metric)
+	}
+
+	var err error
+	switch slo.Metric {
+	case "ops":
+		slo.Limit, err = strconv.ParseFloat(m[4], 64)
+	case "error_rate":
+		slo.Limit, err = strconv.ParseFloat(strings.TrimSuffix(m[4], "%"), 64)
+	default:
+		var d time.Duration
+		d, err = time.ParseDuration(m[4])
+		slo.Limit = float64(d)
+	}
+	if err != nil || slo.Limit < 0 {
+		return slo, fmt.Errorf("SLO %q: bad limit %q for %s", spec, m[4], slo.Metric)
+	}
+	return slo, nil
+}
+
+// matches reports whether the SLO covers the test named name.
+func (s SLO) matches(name string) bool {
+	return s.Test == "*" || strings.Contains(testSlug(name), s.Test)
+}
+
+// met reports whether value meets the SLO.
+func (s SLO) met(value float64) bool {
+	switch s.Op {
+	case "<":
+		return value < s.Limit
+	case "<=":
+		return value <= s.Limit
+	case ">":
+		return value > s.Limit
+	default:
+		return value >= s.Limit
+	}
+}
+
+// format writes value in the SLO metric's unit.
+func (s SLO) format(value float64) string {
+	switch s.Metric {
+	case "ops":
+		return fmt.Sprintf("%.2f ops/sec", value)
+	case "error_rate":
+		return fmt.Sprintf("%.2f%%", value)
+	default:
+		return formatLatency(time.Duration(value))
+	}
+}
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// testSlug is name lowercased with each run of other characters turned
+// into an underscore, as SLOs name tests.
+func testSlug(name string) string {
+	return strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(name), "_"), "_")
+}
+
+// SLOCheck is one SLO checked against one test's result. Test is empty
+// when no result matched the SLO, which fails it.
+type SLOCheck struct {
+	SLO      SLO
+	Test     string
+	Database string
+	Measured string
+	Met      bool
+}
+
+// EvaluateSLOs checks every SLO against each result it matches. A failed
+// or skipped test misses every SLO that matches it, and an SLO that
+// matches no result at all is missed too, so a renamed test cannot pass by
+// going unchecked.
+func EvaluateSLOs(slos []SLO, results []Result) []SLOCheck {
+	var checks []SLOCheck
+	for _, slo := range slos {
+		matched := false
+		for _, r := range results {
+			if !slo.matches(r.TestName) {
+				continue
+			}
+			matched = true
+			check := SLOCheck{SLO: slo, Test: r.TestName, Database: r.Database}
+			switch {
+			case r.Failure != "":
+				check.Measured = "failed"
+			case r.Skipped != "":
+				check.Measured = "not run"
+			default:
+				value := sloMetrics[slo.Metric](r)
+				check.Measured, check.Met = slo.format(value), slo.met(value)
+			}
+			checks = append(checks, check)
+		}
+		if !matched {
+			checks = append(checks, SLOCheck{SLO: slo, Measured: "no test matched"})
+		}
+	}
+	return checks
+}
+
+var (
+	slosMu     sync.Mutex
+	slos       []SLO
+	sloResults []Result
+)
+
+// SetSLOs parses the SLOs the run asserts and starts collecting the results
+// saved from then on to check them against (see CheckSLOs).
+func SetSLOs(specs []string) error {
+	parsed := make([]SLO, 0, len(specs))
+	for _, spec := range specs {
+		slo, err := ParseSLO(spec)
+		if err != nil {
+			return err
+		}
+		parsed = append(parsed, slo)
+	}
+	slosMu.Lock()
+	defer slosMu.Unlock()
+	slos, sloResults = parsed, nil
+	return nil
+}
+
+// sloSaved keeps a saved suite's results to check the SLOs against.
+func sloSaved(suite Suite) {
+	slosMu.Lock()
+	defer slosMu.Unlock()
+	if len(slos) > 0 {
+		sloResults = append(sloResults, suite.Results...)
+	}
+}
+
+// CheckSLOs checks the SLOs set by SetSLOs against every result saved
+// since the last check, so each daemon round is checked on its own. It
+// returns nil when none were set or nothing was saved.
+func CheckSLOs() []SLOCheck {
+	slosMu.Lock()
+	defer slosMu.Unlock()
+	if len(slos) == 0 || len(sloResults) == 0 {
+		return nil
+	}
+	checks := EvaluateSLOs(slos, sloResults)
+	sloResults = nil
+	return checks
+}
+
+// SLOsMissed counts the checks that missed their SLO.
+func SLOsMissed(checks []SLOCheck) int {
+	missed := 0
+	for _, c := range checks {
+		if !c.Met {
+			missed++
+		}
+	}
+	return missed
+}
+
+// WriteSLOs writes each check, met or missed, then how many were missed.
+func WriteSLOs(w io.Writer, checks []SLOCheck) error {
+	var b strings.Builder
+	b.WriteString("\n=== SLOs ===\n\n")
+	for _, c := range checks {
+		status := "PASS"
+		if !c.Met {
+			status = "FAIL"
+		}
+		if c.Test == "" {
+			fmt.Fprintf(&b, "%s  %s: %s\n", status, c.SLO.Spec, c.Measured)
+			continue
+		}
+		fmt.Fprintf(&b, "%s  %s: %s (%s) %s\n", status, c.SLO.Spec, c.Test, c.Database, c.Measured)
+	}
+	fmt.Fprintf(&b, "\n%d of %d checks missed their SLO.\n", SLOsMissed(checks), len(checks))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
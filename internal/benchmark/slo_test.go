@@ -0,0 +1,75 @@
+package benchmark
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSLO(t *testing.T) {
+	for _, tc := range []struct {
+		spec   string
+		test   string
+		metric string
+		limit  float64
+	}{
+		{"point_read.p99 < 10ms", "point_read", "p99", float64(10 * time.Millisecond)},
+		{"Double-Entry.ops>=500", "double_entry", "ops", 500},
+		{"*.error_rate <= 0.5%", "*", "error_rate", 0.5},
+	} {
+		slo, err := ParseSLO(tc.spec)
+		if err != nil {
+			t.Errorf("%q: %v", tc.spec, err)
+			continue
+		}
+		if slo.Test != tc.test || slo.Metric != tc.metric || slo.Limit != tc.limit {
+			t.Errorf("%q parsed as %+v", tc.spec, slo)
+		}
+	}
+
+	for _, spec := range []string{"point_read < 10ms", "point_read.p42 < 10ms", "point_read.p99 < fast", "point_read.ops = 5", "-.p99 < 1ms"} {
+		if _, err := ParseSLO(spec); err == nil {
+			t.Errorf("%q accepted", spec)
+		}
+	}
+}
+
+func TestEvaluateSLOs(t *testing.T) {
+	fast := fixtureResult("PostgreSQL", "Point Reads - Account by ID", 1000, time.Millisecond, 4*time.Millisecond, 900)
+	slow := fixtureResult("DynamoDB", "Point Reads - Account by ID", 1000, time.Millisecond, 12*time.Millisecond, 600)
+	failed := Result{TestName: "Double-Entry Atomic Writes (100 ops, 10 concurrent)", Database: "PostgreSQL", Failure: "panic"}
+
+	var slos []SLO
+	for _, spec := range []string{"point_read.p99 < 10ms", "point_read.ops >= 500", "double_entry.ops >= 500", "reconciliation.p99 < 1s"} {
+		slo, err := ParseSLO(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		slos = append(slos, slo)
+	}
+	checks := EvaluateSLOs(slos, []Result{fast, slow, failed})
+
+	if len(checks) != 6 {
+		t.Fatalf("%d checks, want 2 for each point read SLO and 1 each for the others: %+v", len(checks), checks)
+	}
+	want := []bool{true, false, true, true, false, false}
+	for i, c := range checks {
+		if c.Met != want[i] {
+			t.Errorf("%s on %s (%s): met %v, want %v", c.SLO.Spec, c.Database, c.Measured, c.Met, want[i])
+		}
+	}
+	if checks[4].Measured != "failed" || checks[5].Test != "" {
+		t.Errorf("a failed test and an unmatched SLO checked as %+v and %+v", checks[4], checks[5])
+	}
+	if n := SLOsMissed(checks); n != 3 {
+		t.Errorf("%d missed, want 3", n)
+	}
+
+	var b strings.Builder
+	if err := WriteSLOs(&b, checks); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "FAIL  point_read.p99 < 10ms: Point Reads - Account by ID (DynamoDB) 12ms") {
+		t.Errorf("report does not show the missed P99:\n%s", b.String())
+	}
+}
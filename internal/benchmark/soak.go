@@ -0,0 +1,161 @@
+package benchmark
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// soakInterval is what a soak test's workers recorded between two
+// snapshots.
+type soakInterval struct {
+	latencies *Recorder
+	success   int
+	errors    int
+	// reported is how many workers have handed in their share.
+	reported int
+}
+
+// Soak runs op for duration on workers closed-loop workers, like
+// Sustained, and every interval in between summarizes the operations
+// completed since the last snapshot and passes the result to snapshot,
+// with SoakElapsed set, as soon as every worker has handed in its share.
+// The returned result covers the whole soak, with the change in throughput
+// and P99 latency from the first snapshot to the last, so degradation that
+// builds up over hours, such as table bloat or a server's growing memory,
+// shows up where a short test would miss it.
+func Soak(testName, database string, workers int, duration, interval time.Duration, op func(rng *rand.Rand) error, snapshot func(Result)) Result {
+	slog.Info("Benchmarking", "test", testName, "duration", duration, "snapshot_every", interval)
+	StartTest(testName)
+	WarmUp(workers, op)
+
+	workers = max(1, workers)
+	interval = min(max(interval, time.Second), duration)
+	intervals := int((duration + interval - 1) / interval)
+
+	var mu sync.Mutex
+	var emitMu sync.Mutex
+	pending := make([]soakInterval, intervals)
+	var snapshots []Result
+
+	start := time.Now()
+	deadline := start.Add(duration)
+
+	// hand merges a worker's share of interval i and, once every worker
+	// has handed theirs in, summarizes it as a snapshot.
+	hand := func(i int, local *Recorder, success, errs int) {
+		mu.Lock()
+		p := &pending[i]
+		if p.latencies == nil {
+			p.latencies = NewRecorder()
+		}
+		p.latencies.Merge(local)
+		p.success += success
+		p.errors += errs
+		p.reported++
+		done := p.reported == workers
+		mu.Unlock()
+		if !done {
+			return
+		}
+
+		emitMu.Lock()
+		defer emitMu.Unlock()
+		elapsed := min(time.Duration(i+1)*interval, duration)
+		took := min(time.Since(start), elapsed) - time.Duration(i)*interval
+		name := fmt.Sprintf("%s [%v]", testName, elapsed)
+		s := p.latencies.Summarize(name, database, p.success+p.errors, workers, p.success, p.errors, took)
+		s.SoakElapsed = elapsed
+		snapshots = append(snapshots, s)
+		slog.Info("Soak snapshot", "test", testName, "elapsed", elapsed,
+			"ops_per_sec", s.OperationsPerSec, "p99", s.P99Duration, "errors", s.ErrorCount)
+		if snapshot != nil {
+			snapshot(s)
+		}
+	}
+
+	ran := RunWorkers(workers, func(worker int, total *Recorder, rng *rand.Rand) WorkerTally {
+		var tally WorkerTally
+		// Each interval's recorder thinks from the worker's source, as
+		// total does.
+		newLocal := func() *Recorder {
+			r := NewRecorder()
+			r.rng = rng
+			return r
+		}
+		local := newLocal()
+		current := 0
+		success, errs := 0, 0
+		// handIn closes out every interval before upTo, the first
+		// with what the worker recorded in it and any it ran
+		// through without completing an operation empty.
+		handIn := func(upTo int) {
+			for ; current < upTo; current++ {
+				hand(current, local, success, errs)
+				total.Merge(local)
+				local = newLocal()
+				success, errs = 0, 0
+			}
+		}
+		for time.Now().Before(deadline) && !Stopping() {
+			opStart := time.Now()
+			err := op(rng)
+			local.Record(time.Since(opStart))
+			local.Think()
+			if err != nil {
+				errs++
+				tally.Errors++
+			} else {
+				success++
+				tally.Successes++
+			}
+			handIn(min(intervals-1, int(time.Since(start)/interval)))
+		}
+		handIn(min(intervals, int(time.Since(start)/interval)+1))
+		return tally
+	})
+
+	result := ran.Summarize(testName, database, ran.Successes+ran.Errors, workers)
+	result.addSnapshotTallies(snapshots)
+	if len(snapshots) > 1 {
+		first, last := snapshots[0], snapshots[len(snapshots)-1]
+		result.ThroughputDrift = change(last.OperationsPerSec, first.OperationsPerSec) * 100
+		result.P99Drift = change(float64(last.P99Duration), float64(first.P99Duration)) * 100
+	}
+	return result
+}
+
+// addSnapshotTallies adds the timeouts and retries the snapshots already
+// took, while the soak ran, to the whole soak's result.
+func (r *Result) addSnapshotTallies(snapshots []Result) {
+	var retryLatency time.Duration
+	for _, s := range snapshots {
+		r.TimeoutCount += s.TimeoutCount
+		r.ErrorCount -= s.TimeoutCount
+		r.Retries += s.Retries
+		retryLatency += s.RetryLatency * time.Duration(s.NumOperations)
+	}
+	if r.NumOperations > 0 && r.Retries > 0 {
+		retryLatency += r.RetryLatency * time.Duration(r.NumOperations)
+		r.RetriesPerOp = float64(r.Retries) / float64(r.NumOperations)
+		r.RetryLatency = retryLatency / time.Duration(r.NumOperations)
+	}
+}
+
+// SoakMix is the operation soak tests run: half point reads of accounts,
+// 30% balance updates and 20% inserted transactions, so tables grow and
+// rows churn the whole time.
+func SoakMix(ops YCSBOps) func(rng *rand.Rand) error {
+	return func(rng *rand.Rand) error {
+		switch p := rng.Float64(); {
+		case p < 0.5:
+			return ops.Read(rng)
+		case p < 0.8:
+			return ops.Update(rng)
+		default:
+			return ops.Insert(rng)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package benchmark
+
+import (
+	"errors"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSoak(t *testing.T) {
+	// Operations slow down as the soak goes on, the way a bloating table
+	// would make them.
+	start := time.Now()
+	var calls atomic.Int64
+	op := func(*rand.Rand) error {
+		if calls.Add(1)%10 == 0 {
+			return errors.New("conflict")
+		}
+		time.Sleep(time.Millisecond + time.Since(start)/1000)
+		return nil
+	}
+
+	var snapshots []Result
+	result := Soak("Soak", "PostgreSQL", 3, 2500*time.Millisecond, time.Second, op, func(s Result) {
+		snapshots = append(snapshots, s)
+	})
+
+	if len(snapshots) != 3 {
+		t.Fatalf("%d snapshots, want 3", len(snapshots))
+	}
+	ops, errs := 0, 0
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 2500 * time.Millisecond} {
+		s := snapshots[i]
+		if s.SoakElapsed != want || s.NumOperations == 0 {
+			t.Errorf("snapshot %d at %v with %d operations, want at %v", i, s.SoakElapsed, s.NumOperations, want)
+		}
+		ops += s.NumOperations
+		errs += s.ErrorCount
+	}
+	if result.NumOperations != ops || result.ErrorCount != errs {
+		t.Errorf("soak has %d operations and %d errors, snapshots %d and %d", result.NumOperations, result.ErrorCount, ops, errs)
+	}
+	if result.P99Drift <= 0 || result.ThroughputDrift >= 0 {
+		t.Errorf("drift: throughput %+.1f%%, P99 %+.1f%%, want throughput down and P99 up", result.ThroughputDrift, result.P99Drift)
+	}
+	if problems := Check(result); len(problems) > 0 {
+		t.Errorf("soak result: %v", problems)
+	}
+}
@@ -0,0 +1,64 @@
+package benchmark
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	thinkMean        time.Duration
+	thinkExponential bool
+)
+
+// SetThinkTime configures the pause Think makes between a closed-loop
+// worker's operations, so each worker models one client at a realistic
+// request rate instead of a tight loop: a fixed duration ("50ms"), an
+// exponentially distributed one with that mean ("exp:50ms"), as a Poisson
+// stream of a client's requests has, or "" or "0" for none.
+func SetThinkTime(spec string) error {
+	thinkMean, thinkExponential = 0, false
+	if spec == "" || spec == "0" {
+		return nil
+	}
+	mean, exponential := strings.CutPrefix(spec, "exp:")
+	d, err := time.ParseDuration(mean)
+	if err != nil || d < 0 {
+		return fmt.Errorf("think time %q is neither a duration nor exp:<mean duration>", spec)
+	}
+	thinkMean, thinkExponential = d, exponential
+	return nil
+}
+
+// ThinkTime is the mean pause set by SetThinkTime; 0 means none.
+func ThinkTime() time.Duration {
+	return thinkMean
+}
+
+// Think pauses a closed-loop worker recording to r for the think time
+// after an operation, waking early if the run is interrupted. The pause is
+// never part of an operation's latency, but Summarize counts it in the
+// interval it expects between a worker's operations when correcting for
+// coordinated omission. It returns at once when no think time is set.
+func (r *Recorder) Think() {
+	d := thinkMean
+	if d == 0 {
+		return
+	}
+	r.mu.Lock()
+	r.thought = true
+	r.mu.Unlock()
+	if thinkExponential {
+		rng := r.rng
+		if rng == nil {
+			rng = Rand()
+		}
+		d = time.Duration(rng.ExpFloat64() * float64(d))
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-stopped.Done():
+	}
+}
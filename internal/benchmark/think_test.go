@@ -0,0 +1,43 @@
+package benchmark
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetThinkTime(t *testing.T) {
+	defer SetThinkTime("")
+	for spec, want := range map[string]time.Duration{"": 0, "0": 0, "50ms": 50 * time.Millisecond, "exp:2s": 2 * time.Second} {
+		if err := SetThinkTime(spec); err != nil || ThinkTime() != want {
+			t.Errorf("%q: think time %v, %v; want %v", spec, ThinkTime(), err, want)
+		}
+	}
+	for _, spec := range []string{"fast", "exp:", "-5ms", "poisson:5ms"} {
+		if err := SetThinkTime(spec); err == nil {
+			t.Errorf("%q accepted", spec)
+		}
+	}
+}
+
+func TestThink(t *testing.T) {
+	defer SetThinkTime("")
+	SetThinkTime("20ms")
+
+	r := NewRecorder()
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		r.Record(time.Millisecond)
+		r.Think()
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("five 20ms pauses took %v", elapsed)
+	}
+
+	result := r.Summarize("Think", "PostgreSQL", 5, 1, 5, 0, time.Since(start))
+	if result.ThinkTime != 20*time.Millisecond {
+		t.Errorf("think time %v recorded, want 20ms", result.ThinkTime)
+	}
+	if result.P99Duration > 2*time.Millisecond || result.CorrectedP99Duration > 2*time.Millisecond {
+		t.Errorf("P99 %v, corrected %v: the pauses leaked into the latencies", result.P99Duration, result.CorrectedP99Duration)
+	}
+}
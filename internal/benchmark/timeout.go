@@ -0,0 +1,102 @@
+package benchmark
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	opTimeout time.Duration
+	// timeouts counts operations that hit opTimeout since the last test
+	// was summarized or added to a suite.
+	timeouts atomic.Int64
+
+	// errorTypesMu guards errorTypes, the failed requests by type since
+	// the last test was added to a suite.
+	errorTypesMu sync.Mutex
+	errorTypes   = make(map[string]int)
+
+	// dropped counts the items batch writes gave up on since the last test
+	// was added to a suite.
+	dropped atomic.Int64
+)
+
+// SetOpTimeout sets the deadline for each database operation, or removes
+// it with 0. The backends apply it to every statement and API call.
+func SetOpTimeout(d time.Duration) {
+	opTimeout = max(0, d)
+}
+
+// OpTimeout is the timeout set by SetOpTimeout; 0 means none.
+func OpTimeout() time.Duration {
+	return opTimeout
+}
+
+// Context is the root context every benchmark runs under. It is cancelled
+// when the run is interrupted (see HandleInterrupts), which aborts whatever
+// operations are in flight.
+func Context() context.Context {
+	return stopped
+}
+
+// OpContext derives one operation's context from ctx, with the operation
+// timeout applied when one is set.
+func OpContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if opTimeout > 0 {
+		return context.WithTimeout(ctx, opTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// CountTimeout records one operation that failed by running out of time.
+// The backends call it where they apply the timeout, so tests need not tell
+// timeouts from other errors themselves: Summarize moves the count out of
+// the test's ErrorCount into TimeoutCount.
+func CountTimeout() {
+	timeouts.Add(1)
+}
+
+// takeTimeouts returns the timeouts counted since the last call and resets
+// the count.
+func takeTimeouts() int {
+	return int(timeouts.Swap(0))
+}
+
+// CountError records one failed request by type: the DynamoDB exception
+// name or PostgreSQL SQLSTATE class, or "timeout", "canceled",
+// "connection" or "other" for failures that never got a response. The
+// backends call it where requests are sent, and Add attaches the tally to
+// the test it ran under.
+func CountError(errorType string) {
+	errorTypesMu.Lock()
+	defer errorTypesMu.Unlock()
+	errorTypes[errorType]++
+}
+
+// takeErrorTypes returns the errors counted since the last call and resets
+// the tally.
+func takeErrorTypes() map[string]int {
+	errorTypesMu.Lock()
+	defer errorTypesMu.Unlock()
+	if len(errorTypes) == 0 {
+		return nil
+	}
+	taken := errorTypes
+	errorTypes = make(map[string]int)
+	return taken
+}
+
+// CountDropped records n items a batch write gave up on, still unprocessed
+// after its retries, which are lost rather than failed. Add attaches the
+// tally to the test it ran under as DroppedItems.
+func CountDropped(n int) {
+	dropped.Add(int64(n))
+}
+
+// takeDropped returns the items dropped since the last call and resets the
+// count.
+func takeDropped() int {
+	return int(dropped.Swap(0))
+}
@@ -0,0 +1,49 @@
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSuiteAddTakesErrorTypes(t *testing.T) {
+	takeErrorTypes()
+	CountError("ProvisionedThroughputExceededException")
+	CountError("ProvisionedThroughputExceededException")
+	CountError("timeout")
+
+	var suite Suite
+	suite.Add(Result{TestName: "first"})
+	suite.Add(Result{TestName: "second"})
+
+	first, second := suite.Results[0].ErrorsByType, suite.Results[1].ErrorsByType
+	if first["ProvisionedThroughputExceededException"] != 2 || first["timeout"] != 1 {
+		t.Errorf("first ErrorsByType = %v, want 2 throttles and 1 timeout", first)
+	}
+	if second != nil {
+		t.Errorf("second ErrorsByType = %v, want none carried over", second)
+	}
+}
+
+func TestRunCountsTimeoutsApart(t *testing.T) {
+	SetOpTimeout(time.Millisecond)
+	defer SetOpTimeout(0)
+
+	// Even iterations wait out their operation context; odd ones fail
+	// outright, which is not a timeout however long it takes.
+	w := &Workload{Name: "timeouts", Op: func(ctx context.Context, worker, iteration int) error {
+		if iteration%2 == 0 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return errors.New("insufficient funds")
+	}}
+	result, err := Run(context.Background(), w, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TimeoutCount != 5 || result.ErrorCount != 5 {
+		t.Errorf("TimeoutCount, ErrorCount = %d, %d, want 5, 5", result.TimeoutCount, result.ErrorCount)
+	}
+}
@@ -0,0 +1,187 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Pricing is what the verdict charges each database, in USD. The defaults
+// are us-east-1 list prices: an on-demand db.r6g.large for PostgreSQL, and
+// on-demand request units for DynamoDB. Override them with
+// BENCH_PG_HOURLY_USD, BENCH_DDB_READ_USD and BENCH_DDB_WRITE_USD.
+var Pricing = struct {
+	// PostgresHourly is the instance price per hour. A test is charged for
+	// the instance time its measured throughput needs.
+	PostgresHourly float64
+	// DynamoDBRead and DynamoDBWrite are the prices per million read and
+	// write request units.
+	DynamoDBRead  float64
+	DynamoDBWrite float64
+}{
+	PostgresHourly: envFloat("BENCH_PG_HOURLY_USD", 0.225),
+	DynamoDBRead:   envFloat("BENCH_DDB_READ_USD", 0.125),
+	DynamoDBWrite:  envFloat("BENCH_DDB_WRITE_USD", 0.625),
+}
+
+// category groups the tests on either database that answer the same
+// question, matched by test name.
+type category struct {
+	name  string
+	tests []string
+}
+
+var categories = []category{
+	{"Point Reads", []string{"Point Reads", "GetItem -"}},
+	{"Transaction + Legs", []string{"Transaction + Legs"}},
+	{"Batch Ops", []string{"Batch Inserts", "BatchWriteItem", "BatchGetItem"}},
+	{"Transactional Writes", []string{"Double-Entry", "TransactWriteItems"}},
+	{"Analytics", []string{"Reconciliation", "Summary", "Merchant Analysis", "Top N", "Balance Verification", "JOIN", "Scan"}},
+}
+
+func (c category) matches(testName string) bool {
+	for _, test := range c.tests {
+		if strings.Contains(testName, test) {
+			return true
+		}
+	}
+	return false
+}
+
+// score is one database's standing in a category.
+type score struct {
+	database string
+	tests    int
+	// throughput is the geometric mean of ops/sec over the matched tests,
+	// so one fast test does not drown out the rest.
+	throughput float64
+	// costPerMillion is the mean USD cost of a million operations.
+	costPerMillion float64
+}
+
+// writeVerdict ends a summary with the winner of each category. Results
+// for a database the suite did not run are read from the saved result
+// files, so a PostgreSQL run is judged against the last DynamoDB run.
+// Categories without results from both databases are left out.
+func writeVerdict(w io.Writer, suite Suite) {
+	results := append(counterpartResults(suite), suite.Results...)
+
+	var lines []string
+	for _, c := range categories {
+		scores := make(map[string]*score)
+		for _, result := range results {
+			if result.OperationsPerSec <= 0 || !c.matches(result.TestName) {
+				continue
+			}
+			s, ok := scores[result.Database]
+			if !ok {
+				s = &score{database: result.Database}
+				scores[result.Database] = s
+			}
+			s.tests++
+			s.throughput += math.Log(result.OperationsPerSec)
+			s.costPerMillion += costPerMillion(result)
+		}
+		if len(scores) != 2 {
+			continue
+		}
+
+		var winner, loser *score
+		for _, s := range scores {
+			s.throughput = math.Exp(s.throughput / float64(s.tests))
+			s.costPerMillion /= float64(s.tests)
+			if winner == nil || s.throughput > winner.throughput {
+				winner, loser = s, winner
+			} else {
+				loser = s
+			}
+		}
+
+		cost := "same cost"
+		if diff := winner.costPerMillion - loser.costPerMillion; diff > 0 {
+			cost = fmt.Sprintf("$%.2f more per million ops ($%.2f vs $%.2f)", diff, winner.costPerMillion, loser.costPerMillion)
+		} else if diff < 0 {
+			cost = fmt.Sprintf("$%.2f less per million ops ($%.2f vs $%.2f)", -diff, winner.costPerMillion, loser.costPerMillion)
+		}
+		lines = append(lines, fmt.Sprintf("  %-21s %s, %.1fx the throughput (%.2f vs %.2f ops/sec over %d/%d tests), %s",
+			c.name+":", winner.database, winner.throughput/loser.throughput,
+			winner.throughput, loser.throughput, winner.tests, loser.tests, cost))
+	}
+
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprint(w, "=== Verdict ===\n\n")
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+	fmt.Fprintln(w)
+}
+
+// costPerMillion prices a million operations of a test under Pricing.
+// DynamoDB is charged for the capacity the test consumed, or an estimate of
+// one unit per operation (two for transactions, half for eventually
+// consistent reads) when it did not record capacity.
+func costPerMillion(result Result) float64 {
+	if result.Database != "DynamoDB" {
+		return Pricing.PostgresHourly / (result.OperationsPerSec * 3600) * 1e6
+	}
+
+	rcu, wcu := result.ConsumedRCU, result.ConsumedWCU
+	if rcu == 0 && wcu == 0 {
+		ops := float64(result.NumOperations)
+		switch name := result.TestName; {
+		case strings.Contains(name, "Transact"):
+			wcu = 2 * ops
+		case strings.Contains(name, "Write"), strings.Contains(name, "Put"):
+			wcu = ops
+		default:
+			rcu = 0.5 * ops
+		}
+	}
+	if result.NumOperations == 0 {
+		return 0
+	}
+	return (rcu*Pricing.DynamoDBRead + wcu*Pricing.DynamoDBWrite) / float64(result.NumOperations)
+}
+
+// counterpartResults loads the saved results of every database that does
+// not appear in suite.
+func counterpartResults(suite Suite) []Result {
+	ran := make(map[string]bool)
+	for _, result := range suite.Results {
+		ran[result.Database] = true
+	}
+
+	files, _ := filepath.Glob(filepath.Join(ResultsDir(), "*-results.json"))
+
+	var results []Result
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var saved Suite
+		if err := json.Unmarshal(data, &saved); err != nil {
+			continue
+		}
+		for _, result := range saved.Results {
+			if !ran[result.Database] {
+				results = append(results, result)
+			}
+		}
+	}
+	return results
+}
+
+func envFloat(key string, fallback float64) float64 {
+	if v, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return v
+	}
+	return fallback
+}
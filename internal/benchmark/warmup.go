@@ -0,0 +1,85 @@
+package benchmark
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	warmupOps      int
+	warmupDuration time.Duration
+)
+
+// SetWarmup configures the warm-up WarmUp runs before each measured test:
+// an operation count ("500"), a duration ("30s"), or "" or "0" for none.
+func SetWarmup(spec string) error {
+	warmupOps, warmupDuration = 0, 0
+	if spec == "" {
+		return nil
+	}
+	if n, err := strconv.Atoi(spec); err == nil && n >= 0 {
+		warmupOps = n
+		return nil
+	}
+	if d, err := time.ParseDuration(spec); err == nil && d >= 0 {
+		warmupDuration = d
+		return nil
+	}
+	return fmt.Errorf("warm-up %q is neither an operation count nor a duration", spec)
+}
+
+// WarmUp runs op across workers goroutines for the configured warm-up and
+// discards the outcome, so first-connection, TLS handshake and plan-cache
+// costs are paid before a test's clock starts. Tests call it with the same
+// operation and concurrency they are about to measure. Each goroutine
+// calls op with a source of its own, seeded from Rand rather than as the
+// test's workers are, so the warm-up does not touch the keys they will.
+func WarmUp(workers int, op func(rng *rand.Rand) error) {
+	if warmupOps == 0 && warmupDuration == 0 {
+		return
+	}
+	workers = max(1, workers)
+
+	var done, failed atomic.Int64
+	deadline := time.Now().Add(warmupDuration)
+	more := func() bool {
+		if warmupDuration > 0 {
+			return time.Now().Before(deadline)
+		}
+		return done.Add(1) <= int64(warmupOps)
+	}
+
+	var wg sync.WaitGroup
+	var panics workerPanics
+	for w := 0; w < workers; w++ {
+		rng := rand.New(rand.NewSource(Rand().Int63()))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer panics.catch()
+			for more() {
+				if err := op(rng); err != nil {
+					failed.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	panics.raise()
+	takeTimeouts()
+	takeCapacityPredictions()
+	takeErrorTypes()
+	takeRetries()
+	takePhases()
+	restartWork()
+	restartPool()
+
+	if n := failed.Load(); n > 0 {
+		slog.Warn("Warm-up operations failed", "operations", n)
+	}
+}
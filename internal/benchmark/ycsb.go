@@ -0,0 +1,254 @@
+package benchmark
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// YCSB operation types, the keys of Result.OperationMix.
+const (
+	OpRead            = "read"
+	OpUpdate          = "update"
+	OpInsert          = "insert"
+	OpScan            = "scan"
+	OpReadModifyWrite = "read-modify-write"
+)
+
+// YCSBWorkload is one of the YCSB core workloads: the share of its
+// operations of each type, summing to 1. On the ledger a read is an
+// account point read, an update a balance adjustment, an insert a
+// transaction with its two legs, a scan a short page of an account's
+// history and a read-modify-write a balance read then written back under
+// the account's version.
+type YCSBWorkload struct {
+	Name            string
+	Description     string
+	Read            float64
+	Update          float64
+	Insert          float64
+	Scan            float64
+	ReadModifyWrite float64
+	// Latest reads the most recently inserted transactions, the newest
+	// most often, instead of accounts picked by the key distribution.
+	Latest bool
+}
+
+// YCSBWorkloads are the core workloads A to F, as YCSB defines them.
+var YCSBWorkloads = []YCSBWorkload{
+	{Name: "A", Description: "update-heavy", Read: 0.5, Update: 0.5},
+	{Name: "B", Description: "read-mostly", Read: 0.95, Update: 0.05},
+	{Name: "C", Description: "read-only", Read: 1},
+	{Name: "D", Description: "read-latest", Read: 0.95, Insert: 0.05, Latest: true},
+	{Name: "E", Description: "short ranges", Scan: 0.95, Insert: 0.05},
+	{Name: "F", Description: "read-modify-write", Read: 0.5, ReadModifyWrite: 0.5},
+}
+
+var ycsbSelected []YCSBWorkload
+
+// SetYCSBWorkloads selects the workloads the ycsb suites run, by a
+// comma-separated list of names such as "B" or "a,c". Empty selects every
+// one.
+func SetYCSBWorkloads(names string) error {
+	ycsbSelected = nil
+	if names == "" {
+		return nil
+	}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		found := false
+		for _, w := range YCSBWorkloads {
+			if w.Name == name {
+				ycsbSelected = append(ycsbSelected, w)
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown workload %q (want A to F)", name)
+		}
+	}
+	return nil
+}
+
+// SelectedYCSBWorkloads are the workloads SetYCSBWorkloads selected.
+func SelectedYCSBWorkloads() []YCSBWorkload {
+	if ycsbSelected == nil {
+		return YCSBWorkloads
+	}
+	return ycsbSelected
+}
+
+// YCSBOps are a backend's operations for each YCSB operation type, each
+// called with the worker's source of random values. ReadLatest is the read
+// of workloads with Latest set.
+type YCSBOps struct {
+	Read            func(rng *rand.Rand) error
+	ReadLatest      func(rng *rand.Rand) error
+	Update          func(rng *rand.Rand) error
+	Insert          func(rng *rand.Rand) error
+	Scan            func(rng *rand.Rand) error
+	ReadModifyWrite func(rng *rand.Rand) error
+}
+
+// OperationStats is one operation type's share of a mixed workload.
+type OperationStats struct {
+	Operations      int           `json:"operations"`
+	Errors          int           `json:"errors"`
+	AverageDuration time.Duration `json:"avg_duration_ns"`
+	P99Duration     time.Duration `json:"p99_duration_ns"`
+}
+
+// ycsbOp is one operation type of a workload with its cumulative share.
+type ycsbOp struct {
+	name  string
+	upTo  float64
+	run   func(rng *rand.Rand) error
+	stats *Recorder
+}
+
+// RunYCSB runs ops operations of workload w, split over workers closed-loop
+// workers that each pick every operation's type at random in the
+// workload's proportions, and records each type's latency in the result's
+// OperationMix. Operations are retried per the retry policy.
+func RunYCSB(w YCSBWorkload, database string, ops, workers int, impl YCSBOps) Result {
+	testName := fmt.Sprintf("YCSB Workload %s - %s (%d workers)", w.Name, w.Description, workers)
+	slog.Info("Benchmarking", "test", testName, "operations", ops)
+	StartTest(testName)
+
+	read := impl.Read
+	if w.Latest {
+		read = impl.ReadLatest
+	}
+	var mix []*ycsbOp
+	var upTo float64
+	for _, op := range []struct {
+		name  string
+		share float64
+		run   func(rng *rand.Rand) error
+	}{
+		{OpRead, w.Read, read},
+		{OpUpdate, w.Update, impl.Update},
+		{OpInsert, w.Insert, impl.Insert},
+		{OpScan, w.Scan, impl.Scan},
+		{OpReadModifyWrite, w.ReadModifyWrite, impl.ReadModifyWrite},
+	} {
+		if op.share > 0 {
+			upTo += op.share
+			mix = append(mix, &ycsbOp{name: op.name, upTo: upTo, run: RetryingRand(op.run), stats: NewRecorder()})
+		}
+	}
+	pick := func(rng *rand.Rand) *ycsbOp {
+		p := rng.Float64() * upTo
+		for _, op := range mix {
+			if p < op.upTo {
+				return op
+			}
+		}
+		return mix[len(mix)-1]
+	}
+	WarmUp(workers, func(rng *rand.Rand) error { return pick(rng).run(rng) })
+
+	errorsByOp := make(map[string]int)
+
+	opsPerWorker := ops / max(1, workers)
+	ran := RunWorkers(max(1, workers), func(worker int, local *Recorder, rng *rand.Rand) WorkerTally {
+		var tally WorkerTally
+		byOp := make(map[*ycsbOp]*Recorder, len(mix))
+		opErrors := make(map[string]int)
+		for i := 0; i < opsPerWorker && !Stopping(); i++ {
+			op := pick(rng)
+			opStart := time.Now()
+			err := op.run(rng)
+			latency := time.Since(opStart)
+			local.Record(latency)
+			local.Think()
+			if byOp[op] == nil {
+				byOp[op] = NewRecorder()
+			}
+			byOp[op].Record(latency)
+			if err != nil {
+				tally.Errors++
+				opErrors[op.name]++
+			} else {
+				tally.Successes++
+			}
+		}
+
+		for op, recorded := range byOp {
+			op.stats.Merge(recorded)
+		}
+		tally.Merge = func() {
+			for name, n := range opErrors {
+				errorsByOp[name] += n
+			}
+		}
+		return tally
+	})
+
+	result := ran.Summarize(testName, database, opsPerWorker*max(1, workers), workers)
+	result.Layout = "workload " + w.Name
+	result.OperationMix = make(map[string]OperationStats, len(mix))
+	for _, op := range mix {
+		s := op.stats.Worker(0, errorsByOp[op.name], ran.Duration)
+		result.OperationMix[op.name] = OperationStats{
+			Operations: s.Operations, Errors: s.Errors, AverageDuration: s.AverageDuration, P99Duration: s.P99Duration,
+		}
+	}
+	return result
+}
+
+// Recent holds the keys most recently inserted, for reads of the latest
+// records. The zero value is empty and ready to use.
+type Recent struct {
+	mu   sync.Mutex
+	keys []string
+	next int
+}
+
+// recentKeys is how many inserted keys a Recent keeps.
+const recentKeys = 1000
+
+// Add records key as the newest inserted.
+func (r *Recent) Add(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.keys) < recentKeys {
+		r.keys = append(r.keys, key)
+		return
+	}
+	r.keys[r.next] = key
+	r.next = (r.next + 1) % recentKeys
+}
+
+// Pick returns a recently inserted key, the newest most likely: its age
+// in inserts is exponentially distributed, averaging a tenth of the keys
+// kept, drawn from rng. It reports false while none have been added.
+func (r *Recent) Pick(rng *rand.Rand) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := len(r.keys)
+	if n == 0 {
+		return "", false
+	}
+	age := min(n-1, int(rng.ExpFloat64()*float64(n)/10))
+	newest := n - 1
+	if n == recentKeys {
+		newest = (r.next + recentKeys - 1) % recentKeys
+	}
+	return r.keys[(newest-age+n)%n], true
+}
+
+// operationNames are the operation types of mix in a fixed order, for
+// printing.
+func operationNames(mix map[string]OperationStats) []string {
+	names := make([]string, 0, len(mix))
+	for name := range mix {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
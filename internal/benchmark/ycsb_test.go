@@ -0,0 +1,76 @@
+package benchmark
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSetYCSBWorkloads(t *testing.T) {
+	defer SetYCSBWorkloads("")
+	if err := SetYCSBWorkloads("b, e"); err != nil {
+		t.Fatal(err)
+	}
+	if got := SelectedYCSBWorkloads(); len(got) != 2 || got[0].Name != "B" || got[1].Name != "E" {
+		t.Errorf("selected %+v, want B and E", got)
+	}
+	if err := SetYCSBWorkloads("G"); err == nil {
+		t.Error("workload G accepted")
+	}
+	SetYCSBWorkloads("")
+	if got := SelectedYCSBWorkloads(); len(got) != len(YCSBWorkloads) {
+		t.Errorf("selected %d workloads by default, want all %d", len(got), len(YCSBWorkloads))
+	}
+}
+
+func TestRunYCSB(t *testing.T) {
+	var reads, latest, updates atomic.Int64
+	ops := YCSBOps{
+		Read:       func(*rand.Rand) error { reads.Add(1); return nil },
+		ReadLatest: func(*rand.Rand) error { latest.Add(1); return nil },
+		Update: func(*rand.Rand) error {
+			updates.Add(1)
+			return errors.New("version conflict")
+		},
+	}
+	result := RunYCSB(YCSBWorkloads[1], "PostgreSQL", 4000, 4, ops)
+
+	if result.NumOperations != 4000 || reads.Load()+updates.Load() != 4000 || latest.Load() != 0 {
+		t.Fatalf("%d operations: %d reads, %d latest reads, %d updates", result.NumOperations, reads.Load(), latest.Load(), updates.Load())
+	}
+	if share := float64(updates.Load()) / 4000; share < 0.02 || share > 0.08 {
+		t.Errorf("updates were %.1f%% of workload B, want about 5%%", share*100)
+	}
+	mix := result.OperationMix
+	if len(mix) != 2 || mix[OpRead].Operations != int(reads.Load()) || mix[OpUpdate].Errors != int(updates.Load()) {
+		t.Errorf("operation mix %+v does not match %d reads and %d failed updates", mix, reads.Load(), updates.Load())
+	}
+	if result.ErrorCount != int(updates.Load()) {
+		t.Errorf("%d errors, want the %d failed updates", result.ErrorCount, updates.Load())
+	}
+}
+
+func TestRecent(t *testing.T) {
+	var r Recent
+	if _, ok := r.Pick(Rand()); ok {
+		t.Fatal("picked from an empty Recent")
+	}
+	for i := 0; i < recentKeys+500; i++ {
+		r.Add(strconv.Itoa(i))
+	}
+	counts := make(map[int]int)
+	for i := 0; i < 10000; i++ {
+		key, _ := r.Pick(Rand())
+		n, _ := strconv.Atoi(key)
+		if n < 500 {
+			t.Fatalf("picked %d, which the newer keys pushed out", n)
+		}
+		counts[n]++
+	}
+	newest, oldest := recentKeys+499, 500
+	if counts[newest] <= counts[oldest] {
+		t.Errorf("newest key picked %d times, oldest %d", counts[newest], counts[oldest])
+	}
+}
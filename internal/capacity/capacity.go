@@ -0,0 +1,354 @@
+// Package capacity predicts the capacity units DynamoDB charges for a
+// request from the sizes of the items it reads or writes, following the
+// published sizing rules: reads are charged per 4 KB (half for eventually
+// consistent reads, double inside transactions), writes per 1 KB, and every
+// GSI an item is copied to is charged for its own write. Comparing the
+// predictions with the ConsumedCapacity DynamoDB reports shows how far
+// numbers worked out from item sizes alone can be trusted.
+package capacity
+
+import (
+	"math"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	readUnitBytes  = 4096
+	writeUnitBytes = 1024
+)
+
+// ItemSize is an item's size as DynamoDB bills it: the UTF-8 length of every
+// attribute name plus the size of its value.
+func ItemSize(item map[string]types.AttributeValue) int {
+	size := 0
+	for name, value := range item {
+		size += len(name) + valueSize(value)
+	}
+	return size
+}
+
+func valueSize(value types.AttributeValue) int {
+	switch v := value.(type) {
+	case *types.AttributeValueMemberS:
+		return len(v.Value)
+	case *types.AttributeValueMemberN:
+		return numberSize(v.Value)
+	case *types.AttributeValueMemberB:
+		return len(v.Value)
+	case *types.AttributeValueMemberBOOL, *types.AttributeValueMemberNULL:
+		return 1
+	case *types.AttributeValueMemberSS:
+		size := 0
+		for _, s := range v.Value {
+			size += len(s)
+		}
+		return size
+	case *types.AttributeValueMemberNS:
+		size := 0
+		for _, n := range v.Value {
+			size += numberSize(n)
+		}
+		return size
+	case *types.AttributeValueMemberBS:
+		size := 0
+		for _, b := range v.Value {
+			size += len(b)
+		}
+		return size
+	case *types.AttributeValueMemberL:
+		// 3 bytes for the list plus 1 per element.
+		size := 3
+		for _, element := range v.Value {
+			size += 1 + valueSize(element)
+		}
+		return size
+	case *types.AttributeValueMemberM:
+		size := 3
+		for name, element := range v.Value {
+			size += 1 + len(name) + valueSize(element)
+		}
+		return size
+	}
+	return 0
+}
+
+// numberSize is roughly one byte per two significant digits, plus one.
+func numberSize(n string) int {
+	mantissa, _, _ := strings.Cut(strings.ToLower(n), "e")
+	digits := strings.TrimLeft(strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, mantissa), "0")
+	if strings.Contains(mantissa, ".") {
+		digits = strings.TrimRight(digits, "0")
+	}
+	return (len(digits)+1)/2 + 1
+}
+
+// ReadUnits is the RCU a read of size bytes costs; reads of nothing still
+// cost one unit.
+func ReadUnits(size int, consistent bool) float64 {
+	units := math.Max(1, math.Ceil(float64(size)/readUnitBytes))
+	if !consistent {
+		units /= 2
+	}
+	return units
+}
+
+// WriteUnits is the WCU a write of size bytes costs.
+func WriteUnits(size int) float64 {
+	return math.Max(1, math.Ceil(float64(size)/writeUnitBytes))
+}
+
+// Model is what predictions need to know about the table.
+type Model struct {
+	// IndexKeys are the partition key attributes of the table's GSIs. An
+	// item carrying one is copied to that index, and the copy is charged
+	// as a write of the whole item, since the benchmark table projects
+	// ALL attributes.
+	IndexKeys []string
+}
+
+// ModelFor describes table for predictions.
+func ModelFor(table *types.TableDescription) Model {
+	var m Model
+	for _, gsi := range table.GlobalSecondaryIndexes {
+		for _, key := range gsi.KeySchema {
+			if key.KeyType == types.KeyTypeHash {
+				m.IndexKeys = append(m.IndexKeys, *key.AttributeName)
+			}
+		}
+	}
+	return m
+}
+
+// writeUnits is the WCU a write of item costs on the table and its indexes.
+// Overwrites are predicted as if the old item were no larger, and as if no
+// index key changed, which would cost an extra delete on the index.
+func (m Model) writeUnits(item map[string]types.AttributeValue) float64 {
+	units := WriteUnits(ItemSize(item))
+	copies := 1
+	for _, key := range m.IndexKeys {
+		if _, ok := item[key]; ok {
+			copies++
+		}
+	}
+	return units * float64(copies)
+}
+
+// Predict works out the capacity a request should have consumed from its
+// input and output, and returns it with what DynamoDB reported. ok is false
+// for operations the item sizes can't be known for client-side: requests
+// without ReturnConsumedCapacity, projections (which are charged for the
+// whole item), filtered or counted queries and scans (charged for items
+// never returned), updates and deletes not returning the item, and
+// batches or transactions holding any of those.
+func (m Model) Predict(input, output any) (operation string, predicted, consumed float64, ok bool) {
+	switch in := input.(type) {
+	case *dynamodb.GetItemInput:
+		out, _ := output.(*dynamodb.GetItemOutput)
+		if out == nil || out.ConsumedCapacity == nil || in.ProjectionExpression != nil {
+			return "", 0, 0, false
+		}
+		consistent := in.ConsistentRead != nil && *in.ConsistentRead
+		return "GetItem", ReadUnits(ItemSize(out.Item), consistent), units(*out.ConsumedCapacity), true
+
+	case *dynamodb.BatchGetItemInput:
+		out, _ := output.(*dynamodb.BatchGetItemOutput)
+		if out == nil || len(out.ConsumedCapacity) == 0 || len(out.UnprocessedKeys) > 0 {
+			return "", 0, 0, false
+		}
+		for table, keys := range in.RequestItems {
+			if keys.ProjectionExpression != nil {
+				return "", 0, 0, false
+			}
+			consistent := keys.ConsistentRead != nil && *keys.ConsistentRead
+			items := out.Responses[table]
+			for _, item := range items {
+				predicted += ReadUnits(ItemSize(item), consistent)
+			}
+			// Keys that matched no item are charged the minimum.
+			predicted += float64(max(0, len(keys.Keys)-len(items))) * ReadUnits(0, consistent)
+		}
+		return "BatchGetItem", predicted, units(out.ConsumedCapacity...), true
+
+	case *dynamodb.QueryInput:
+		out, _ := output.(*dynamodb.QueryOutput)
+		if out == nil || out.ConsumedCapacity == nil || in.ProjectionExpression != nil ||
+			in.Select == types.SelectCount || out.ScannedCount != out.Count {
+			return "", 0, 0, false
+		}
+		consistent := in.ConsistentRead != nil && *in.ConsistentRead
+		return "Query", ReadUnits(totalSize(out.Items), consistent), units(*out.ConsumedCapacity), true
+
+	case *dynamodb.ScanInput:
+		out, _ := output.(*dynamodb.ScanOutput)
+		if out == nil || out.ConsumedCapacity == nil || in.ProjectionExpression != nil ||
+			in.Select == types.SelectCount || out.ScannedCount != out.Count {
+			return "", 0, 0, false
+		}
+		consistent := in.ConsistentRead != nil && *in.ConsistentRead
+		return "Scan", ReadUnits(totalSize(out.Items), consistent), units(*out.ConsumedCapacity), true
+
+	case *dynamodb.TransactGetItemsInput:
+		out, _ := output.(*dynamodb.TransactGetItemsOutput)
+		if out == nil || len(out.ConsumedCapacity) == 0 {
+			return "", 0, 0, false
+		}
+		for i, get := range in.TransactItems {
+			if get.Get == nil || get.Get.ProjectionExpression != nil || i >= len(out.Responses) {
+				return "", 0, 0, false
+			}
+			predicted += 2 * ReadUnits(ItemSize(out.Responses[i].Item), true)
+		}
+		return "TransactGetItems", predicted, units(out.ConsumedCapacity...), true
+
+	case *dynamodb.PutItemInput:
+		out, _ := output.(*dynamodb.PutItemOutput)
+		if out == nil || out.ConsumedCapacity == nil {
+			return "", 0, 0, false
+		}
+		return "PutItem", m.writeUnits(in.Item), units(*out.ConsumedCapacity), true
+
+	case *dynamodb.UpdateItemInput:
+		out, _ := output.(*dynamodb.UpdateItemOutput)
+		if out == nil || out.ConsumedCapacity == nil || in.ReturnValues != types.ReturnValueAllNew {
+			return "", 0, 0, false
+		}
+		return "UpdateItem", m.writeUnits(out.Attributes), units(*out.ConsumedCapacity), true
+
+	case *dynamodb.DeleteItemInput:
+		out, _ := output.(*dynamodb.DeleteItemOutput)
+		if out == nil || out.ConsumedCapacity == nil || in.ReturnValues != types.ReturnValueAllOld || out.Attributes == nil {
+			return "", 0, 0, false
+		}
+		return "DeleteItem", m.writeUnits(out.Attributes), units(*out.ConsumedCapacity), true
+
+	case *dynamodb.BatchWriteItemInput:
+		out, _ := output.(*dynamodb.BatchWriteItemOutput)
+		if out == nil || len(out.ConsumedCapacity) == 0 || len(out.UnprocessedItems) > 0 {
+			return "", 0, 0, false
+		}
+		for _, requests := range in.RequestItems {
+			for _, request := range requests {
+				if request.PutRequest == nil {
+					return "", 0, 0, false
+				}
+				predicted += m.writeUnits(request.PutRequest.Item)
+			}
+		}
+		return "BatchWriteItem", predicted, units(out.ConsumedCapacity...), true
+
+	case *dynamodb.TransactWriteItemsInput:
+		out, _ := output.(*dynamodb.TransactWriteItemsOutput)
+		if out == nil || len(out.ConsumedCapacity) == 0 {
+			return "", 0, 0, false
+		}
+		for _, write := range in.TransactItems {
+			if write.Put == nil {
+				return "", 0, 0, false
+			}
+			predicted += 2 * m.writeUnits(write.Put.Item)
+		}
+		return "TransactWriteItems", predicted, units(out.ConsumedCapacity...), true
+	}
+	return "", 0, 0, false
+}
+
+func totalSize(items []map[string]types.AttributeValue) int {
+	size := 0
+	for _, item := range items {
+		size += ItemSize(item)
+	}
+	return size
+}
+
+func units(consumed ...types.ConsumedCapacity) float64 {
+	total := 0.0
+	for _, c := range consumed {
+		if c.CapacityUnits != nil {
+			total += *c.CapacityUnits
+		}
+	}
+	return total
+}
+
+// Consumed is the capacity units a request's output reports consuming, or
+// false when it reports none: the request didn't ask for
+// ReturnConsumedCapacity, failed, or is an operation without it.
+func Consumed(output any) (float64, bool) {
+	var consumed []types.ConsumedCapacity
+	switch out := output.(type) {
+	case *dynamodb.GetItemOutput:
+		consumed = optional(out.ConsumedCapacity)
+	case *dynamodb.PutItemOutput:
+		consumed = optional(out.ConsumedCapacity)
+	case *dynamodb.UpdateItemOutput:
+		consumed = optional(out.ConsumedCapacity)
+	case *dynamodb.DeleteItemOutput:
+		consumed = optional(out.ConsumedCapacity)
+	case *dynamodb.QueryOutput:
+		consumed = optional(out.ConsumedCapacity)
+	case *dynamodb.ScanOutput:
+		consumed = optional(out.ConsumedCapacity)
+	case *dynamodb.BatchGetItemOutput:
+		consumed = out.ConsumedCapacity
+	case *dynamodb.BatchWriteItemOutput:
+		consumed = out.ConsumedCapacity
+	case *dynamodb.TransactGetItemsOutput:
+		consumed = out.ConsumedCapacity
+	case *dynamodb.TransactWriteItemsOutput:
+		consumed = out.ConsumedCapacity
+	}
+	if len(consumed) == 0 {
+		return 0, false
+	}
+	return units(consumed...), true
+}
+
+func optional(c *types.ConsumedCapacity) []types.ConsumedCapacity {
+	if c == nil {
+		return nil
+	}
+	return []types.ConsumedCapacity{*c}
+}
+
+// RequestConsumed asks a request to return the capacity it consumes,
+// unless it already does, and reports whether it is charged write units
+// rather than read units. ok is false for operations that cannot return it.
+func RequestConsumed(input any) (write, ok bool) {
+	var returned *types.ReturnConsumedCapacity
+	switch in := input.(type) {
+	case *dynamodb.GetItemInput:
+		returned = &in.ReturnConsumedCapacity
+	case *dynamodb.QueryInput:
+		returned = &in.ReturnConsumedCapacity
+	case *dynamodb.ScanInput:
+		returned = &in.ReturnConsumedCapacity
+	case *dynamodb.BatchGetItemInput:
+		returned = &in.ReturnConsumedCapacity
+	case *dynamodb.TransactGetItemsInput:
+		returned = &in.ReturnConsumedCapacity
+	case *dynamodb.PutItemInput:
+		returned, write = &in.ReturnConsumedCapacity, true
+	case *dynamodb.UpdateItemInput:
+		returned, write = &in.ReturnConsumedCapacity, true
+	case *dynamodb.DeleteItemInput:
+		returned, write = &in.ReturnConsumedCapacity, true
+	case *dynamodb.BatchWriteItemInput:
+		returned, write = &in.ReturnConsumedCapacity, true
+	case *dynamodb.TransactWriteItemsInput:
+		returned, write = &in.ReturnConsumedCapacity, true
+	default:
+		return false, false
+	}
+	if *returned == "" || *returned == types.ReturnConsumedCapacityNone {
+		*returned = types.ReturnConsumedCapacityTotal
+	}
+	return write, true
+}
@@ -0,0 +1,140 @@
+// Package connection holds the database connection settings shared by
+// benchctl, the custom workload runner and the scenario backends.
+//
+// Every setting defaults to the docker-compose databases and can be
+// overridden with an environment variable or, in the binaries, a flag
+// registered by RegisterFlags:
+//
+//	Flag               Environment variable    Default
+//	-pg-dsn            BENCH_PG_DSN            the docker-compose PostgreSQL
+//	-pg-driver         BENCH_PG_DRIVER         pq
+//	-pg-sslmode        BENCH_PG_SSLMODE        the DSN's
+//	-pg-sslrootcert    BENCH_PG_SSLROOTCERT    the DSN's
+//	-pg-iam            BENCH_PG_IAM            false
+//	-pg-region         BENCH_PG_REGION         the RDS host name's
+//	-pg-max-open       BENCH_PG_MAX_OPEN       100
+//	-pg-max-idle       BENCH_PG_MAX_IDLE       10
+//	-pg-conn-lifetime  BENCH_PG_CONN_LIFETIME  unlimited
+//	-ddb-endpoint      BENCH_DDB_ENDPOINT      http://localhost:8000
+//	-ddb-region        BENCH_DDB_REGION        us-east-1
+//	-ddb-table         BENCH_DDB_TABLE         FinancialTransactions
+//	-aws-profile       BENCH_AWS_PROFILE       the default chain's
+//	-ddb-max-idle      BENCH_DDB_MAX_IDLE      the SDK's
+//	-ddb-max-conns     BENCH_DDB_MAX_CONNS     unlimited
+//	-ddb-timeout       BENCH_DDB_TIMEOUT       the SDK's
+//	-ddb-idle-timeout  BENCH_DDB_IDLE_TIMEOUT  the SDK's
+//
+// An empty DynamoDB endpoint (e.g. BENCH_DDB_ENDPOINT=, -ddb-endpoint= or
+// -aws) targets real DynamoDB in the configured region using the default
+// AWS credential chain. -pg-iam signs RDS IAM tokens with the same chain.
+package connection
+
+import (
+	"context"
+	"flag"
+	"os"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+var (
+	// PostgresDSN is the connection string for PostgreSQL.
+	PostgresDSN = getenv("BENCH_PG_DSN", "host=localhost port=5432 user=benchmark password=benchmark123 dbname=financial_benchmark sslmode=disable")
+	// DynamoDBEndpoint is the DynamoDB endpoint URL; empty means AWS.
+	DynamoDBEndpoint = getenv("BENCH_DDB_ENDPOINT", "http://localhost:8000")
+	// DynamoDBRegion is the AWS region DynamoDB requests are signed for.
+	DynamoDBRegion = getenv("BENCH_DDB_REGION", "us-east-1")
+	// DynamoDBTable is the single table the seeder and suites use.
+	DynamoDBTable = getenv("BENCH_DDB_TABLE", "FinancialTransactions")
+	// AWSProfile is the shared config profile AWS credentials come from;
+	// empty means the default chain's choice, such as AWS_PROFILE.
+	AWSProfile = getenv("BENCH_AWS_PROFILE", "")
+)
+
+// RegisterFlags adds the connection flags to fs, defaulting to the current
+// (environment-derived) settings.
+func RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&PostgresDSN, "pg-dsn", PostgresDSN, "PostgreSQL connection string (env BENCH_PG_DSN)")
+	fs.StringVar(&PostgresDriver, "pg-driver", PostgresDriver, "PostgreSQL client driver: pq|pgx (env BENCH_PG_DRIVER)")
+	fs.StringVar(&PostgresSSLMode, "pg-sslmode", PostgresSSLMode, "PostgreSQL sslmode, overriding the DSN's: disable|require|verify-ca|verify-full (env BENCH_PG_SSLMODE)")
+	fs.StringVar(&PostgresRootCert, "pg-sslrootcert", PostgresRootCert, "PEM root certificates to verify the PostgreSQL server with (env BENCH_PG_SSLROOTCERT)")
+	fs.BoolVar(&PostgresIAM, "pg-iam", PostgresIAM, "authenticate to RDS PostgreSQL with IAM tokens instead of a password (env BENCH_PG_IAM=true)")
+	fs.StringVar(&PostgresRegion, "pg-region", PostgresRegion, "region RDS IAM tokens are signed for, default the host name's (env BENCH_PG_REGION)")
+	fs.IntVar(&PostgresPool.MaxOpen, "pg-max-open", PostgresPool.MaxOpen, "most open PostgreSQL connections, 0 for no limit (env BENCH_PG_MAX_OPEN)")
+	fs.IntVar(&PostgresPool.MaxIdle, "pg-max-idle", PostgresPool.MaxIdle, "idle PostgreSQL connections kept for reuse (env BENCH_PG_MAX_IDLE)")
+	fs.DurationVar(&PostgresPool.MaxLifetime, "pg-conn-lifetime", PostgresPool.MaxLifetime, "close PostgreSQL connections this old, 0 for never (env BENCH_PG_CONN_LIFETIME)")
+	fs.StringVar(&DynamoDBEndpoint, "ddb-endpoint", DynamoDBEndpoint, "DynamoDB endpoint URL, empty for AWS (env BENCH_DDB_ENDPOINT)")
+	fs.StringVar(&DynamoDBRegion, "ddb-region", DynamoDBRegion, "DynamoDB region (env BENCH_DDB_REGION)")
+	fs.StringVar(&DynamoDBTable, "ddb-table", DynamoDBTable, "DynamoDB table name (env BENCH_DDB_TABLE)")
+	fs.StringVar(&AWSProfile, "aws-profile", AWSProfile, "AWS shared config profile for real DynamoDB (env BENCH_AWS_PROFILE)")
+	fs.IntVar(&DynamoDBHTTP.MaxIdle, "ddb-max-idle", DynamoDBHTTP.MaxIdle, "idle DynamoDB HTTP connections kept for reuse, 0 for the SDK's (env BENCH_DDB_MAX_IDLE)")
+	fs.IntVar(&DynamoDBHTTP.MaxConns, "ddb-max-conns", DynamoDBHTTP.MaxConns, "most open DynamoDB HTTP connections, 0 for no limit (env BENCH_DDB_MAX_CONNS)")
+	fs.DurationVar(&DynamoDBHTTP.Timeout, "ddb-timeout", DynamoDBHTTP.Timeout, "DynamoDB HTTP request timeout, 0 for the SDK's (env BENCH_DDB_TIMEOUT)")
+	fs.DurationVar(&DynamoDBHTTP.IdleTimeout, "ddb-idle-timeout", DynamoDBHTTP.IdleTimeout, "close idle DynamoDB HTTP connections after this, 0 for the SDK's (env BENCH_DDB_IDLE_TIMEOUT)")
+	fs.BoolFunc("aws", "target real DynamoDB in -ddb-region, as an empty -ddb-endpoint does", func(value string) error {
+		on, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		if on {
+			DynamoDBEndpoint = ""
+		}
+		return nil
+	})
+}
+
+// OnAWS reports whether DynamoDB requests go to AWS rather than a local
+// endpoint.
+func OnAWS() bool {
+	return DynamoDBEndpoint == ""
+}
+
+// NewDynamoDBClient returns a client for DynamoDBEndpoint. A local endpoint
+// gets static dummy credentials, which DynamoDB Local accepts; AWS uses the
+// default credential chain, from AWSProfile if set. optFns adjust the
+// client's options, as in dynamodb.NewFromConfig.
+func NewDynamoDBClient(ctx context.Context, optFns ...func(*dynamodb.Options)) (*dynamodb.Client, error) {
+	opts := []func(*config.LoadOptions) error{config.WithRegion(DynamoDBRegion)}
+	if DynamoDBEndpoint != "" {
+		opts = append(opts,
+			config.WithEndpointResolverWithOptions(aws.EndpointResolverWithOptionsFunc(
+				func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+					return aws.Endpoint{URL: DynamoDBEndpoint}, nil
+				})),
+			config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("local", "local", "")),
+		)
+	} else if AWSProfile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(AWSProfile))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return dynamodb.NewFromConfig(cfg, optFns...), nil
+}
+
+// DynamoDBTarget describes where DynamoDB requests go, for log lines and
+// run metadata.
+func DynamoDBTarget() string {
+	if OnAWS() && AWSProfile != "" {
+		return "AWS " + DynamoDBRegion + " (profile " + AWSProfile + ")"
+	}
+	if OnAWS() {
+		return "AWS " + DynamoDBRegion
+	}
+	return DynamoDBEndpoint
+}
+
+// getenv returns the variable's value if it is set, even to the empty
+// string, so an endpoint can be cleared from the environment.
+func getenv(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,38 @@
+package connection
+
+import (
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestAWSFlag(t *testing.T) {
+	defer func(endpoint string) { DynamoDBEndpoint = endpoint }(DynamoDBEndpoint)
+
+	for _, tt := range []struct {
+		args  []string
+		onAWS bool
+	}{
+		{nil, false},
+		{[]string{"-aws"}, true},
+		{[]string{"-aws=true"}, true},
+		{[]string{"-aws=false"}, false},
+	} {
+		DynamoDBEndpoint = "http://localhost:8000"
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		RegisterFlags(fs)
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		if OnAWS() != tt.onAWS {
+			t.Errorf("%v: OnAWS() = %v, want %v", tt.args, OnAWS(), tt.onAWS)
+		}
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	RegisterFlags(fs)
+	if err := fs.Parse([]string{"-aws=maybe"}); err == nil {
+		t.Error("-aws=maybe accepted")
+	}
+}
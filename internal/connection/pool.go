@@ -0,0 +1,56 @@
+package connection
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// PostgresPool sizes the database/sql pool the PostgreSQL suites share.
+var PostgresPool = struct {
+	// MaxOpen caps the open connections, 0 for no cap, and MaxIdle the
+	// idle ones kept for reuse.
+	MaxOpen int
+	MaxIdle int
+	// MaxLifetime closes connections once they are this old, 0 for never.
+	MaxLifetime time.Duration
+}{
+	MaxOpen:     getenvInt("BENCH_PG_MAX_OPEN", 100),
+	MaxIdle:     getenvInt("BENCH_PG_MAX_IDLE", 10),
+	MaxLifetime: getenvDuration("BENCH_PG_CONN_LIFETIME", 0),
+}
+
+// DynamoDBHTTP tunes the HTTP client the DynamoDB suites share. Zero
+// values keep the SDK's defaults.
+var DynamoDBHTTP = struct {
+	// MaxIdle is the idle connections kept for reuse, and MaxConns caps the
+	// open ones, 0 for no cap.
+	MaxIdle  int
+	MaxConns int
+	// Timeout bounds each HTTP request, and IdleTimeout how long an idle
+	// connection is kept.
+	Timeout     time.Duration
+	IdleTimeout time.Duration
+}{
+	MaxIdle:     getenvInt("BENCH_DDB_MAX_IDLE", 0),
+	MaxConns:    getenvInt("BENCH_DDB_MAX_CONNS", 0),
+	Timeout:     getenvDuration("BENCH_DDB_TIMEOUT", 0),
+	IdleTimeout: getenvDuration("BENCH_DDB_IDLE_TIMEOUT", 0),
+}
+
+// getenvInt is getenv for a number, keeping fallback for one that is unset
+// or does not parse.
+func getenvInt(key string, fallback int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return n
+	}
+	return fallback
+}
+
+// getenvDuration is getenvInt for a duration such as 30s.
+func getenvDuration(key string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return d
+	}
+	return fallback
+}
@@ -0,0 +1,252 @@
+package connection
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/rds/auth"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/lib/pq"
+)
+
+// PostgresDrivers are the client drivers PostgreSQL is reached through:
+// lib/pq, and pgx through its database/sql adapter.
+var PostgresDrivers = []string{"pq", "pgx"}
+
+var (
+	// PostgresDriver is the client driver, one of PostgresDrivers.
+	PostgresDriver = getenv("BENCH_PG_DRIVER", "pq")
+	// PostgresSSLMode overrides the DSN's sslmode when set: disable,
+	// require, verify-ca or verify-full.
+	PostgresSSLMode = getenv("BENCH_PG_SSLMODE", "")
+	// PostgresRootCert is a PEM file of the root certificates the server's
+	// is checked against, such as the RDS global bundle, overriding the
+	// DSN's sslrootcert when set.
+	PostgresRootCert = getenv("BENCH_PG_SSLROOTCERT", "")
+	// PostgresIAM connects with an RDS IAM authentication token, signed
+	// for the DSN's user with the default AWS credential chain, in place
+	// of a password.
+	PostgresIAM = getenv("BENCH_PG_IAM", "") == "true"
+	// PostgresRegion is the region IAM tokens are signed for; empty means
+	// the one in an RDS host name.
+	PostgresRegion = getenv("BENCH_PG_REGION", "")
+)
+
+// iamTokenLifetime is how long an IAM token is reused for new connections.
+// RDS accepts one for 15 minutes.
+const iamTokenLifetime = 10 * time.Minute
+
+// rdsHost matches RDS and Aurora endpoints, capturing their region.
+var rdsHost = regexp.MustCompile(`\.([a-z]{2}(?:-[a-z]+)+-\d)\.rds\.amazonaws\.com$`)
+
+// PostgresConnString is PostgresDSN in key=value form with PostgresSSLMode
+// and PostgresRootCert applied. It carries no IAM token; see
+// PostgresPassword.
+func PostgresConnString() (string, error) {
+	dsn := PostgresDSN
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		var err error
+		if dsn, err = pq.ParseURL(dsn); err != nil {
+			return "", err
+		}
+	}
+	if PostgresSSLMode != "" {
+		dsn += " sslmode=" + quoteDSN(PostgresSSLMode)
+	}
+	if PostgresRootCert != "" {
+		dsn += " sslrootcert=" + quoteDSN(PostgresRootCert)
+	}
+	return dsn, nil
+}
+
+// PostgresPassword is the password to connect with: a fresh IAM token with
+// PostgresIAM, otherwise "" for the DSN's own.
+func PostgresPassword(ctx context.Context) (string, error) {
+	if !PostgresIAM {
+		return "", nil
+	}
+	dsn, err := PostgresConnString()
+	if err != nil {
+		return "", err
+	}
+	return iamToken(ctx, parseDSN(dsn))
+}
+
+// PostgresConnector returns a connector for PostgresConnString through
+// PostgresDriver. With PostgresIAM each new connection authenticates with an
+// IAM token, signed again once the last is iamTokenLifetime old.
+func PostgresConnector() (driver.Connector, error) {
+	if !slices.Contains(PostgresDrivers, PostgresDriver) {
+		return nil, fmt.Errorf("unknown PostgreSQL driver %q; use one of %s", PostgresDriver, strings.Join(PostgresDrivers, ", "))
+	}
+	dsn, err := PostgresConnString()
+	if err != nil {
+		return nil, err
+	}
+	var tokens *iamTokens
+	if PostgresIAM {
+		params := parseDSN(dsn)
+		if params["sslmode"] == "disable" {
+			return nil, fmt.Errorf("RDS IAM authentication needs TLS, but sslmode is disable; set -pg-sslmode (BENCH_PG_SSLMODE) to require or verify-full")
+		}
+		if params["user"] == "" {
+			return nil, fmt.Errorf("RDS IAM authentication needs the database user in the DSN")
+		}
+		tokens = &iamTokens{params: params}
+	}
+
+	if PostgresDriver == "pgx" {
+		config, err := pgx.ParseConfig(dsn)
+		if err != nil {
+			return nil, err
+		}
+		if tokens == nil {
+			return stdlib.GetConnector(*config), nil
+		}
+		return stdlib.GetConnector(*config, stdlib.OptionBeforeConnect(func(ctx context.Context, config *pgx.ConnConfig) error {
+			var err error
+			config.Password, err = tokens.get(ctx)
+			return err
+		})), nil
+	}
+	if tokens == nil {
+		return pq.NewConnector(dsn)
+	}
+	return &iamConnector{dsn: dsn, tokens: tokens}, nil
+}
+
+// iamConnector opens lib/pq connections with an IAM token for the password.
+type iamConnector struct {
+	dsn    string
+	tokens *iamTokens
+}
+
+func (c *iamConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	token, err := c.tokens.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	connector, err := pq.NewConnector(c.dsn + " password=" + quoteDSN(token))
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(ctx)
+}
+
+func (c *iamConnector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
+
+// iamTokens signs IAM tokens for the host, port and user in params, reusing
+// each for iamTokenLifetime.
+type iamTokens struct {
+	params map[string]string
+
+	mu     sync.Mutex
+	token  string
+	signed time.Time
+}
+
+func (t *iamTokens) get(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token == "" || time.Since(t.signed) > iamTokenLifetime {
+		token, err := iamToken(ctx, t.params)
+		if err != nil {
+			return "", fmt.Errorf("sign RDS IAM token: %w", err)
+		}
+		t.token, t.signed = token, time.Now()
+	}
+	return t.token, nil
+}
+
+// iamToken signs an RDS IAM token for the host, port and user in params.
+func iamToken(ctx context.Context, params map[string]string) (string, error) {
+	host, port := params["host"], params["port"]
+	if host == "" {
+		host = "localhost"
+	}
+	if port == "" {
+		port = "5432"
+	}
+	region := PostgresRegion
+	if m := rdsHost.FindStringSubmatch(host); region == "" && m != nil {
+		region = m[1]
+	}
+	if region == "" {
+		return "", fmt.Errorf("no region in host %q; set -pg-region (BENCH_PG_REGION)", host)
+	}
+
+	opts := []func(*config.LoadOptions) error{config.WithRegion(region)}
+	if AWSProfile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(AWSProfile))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return "", err
+	}
+	return auth.BuildAuthToken(ctx, net.JoinHostPort(host, port), region, params["user"], cfg.Credentials)
+}
+
+// parseDSN reads a key=value connection string, in which values may be
+// single-quoted and backslash-escaped. A key given twice keeps its last
+// value, as libpq and lib/pq do.
+func parseDSN(dsn string) map[string]string {
+	params := make(map[string]string)
+	r := []rune(dsn)
+	for i := 0; i < len(r); {
+		for i < len(r) && r[i] == ' ' {
+			i++
+		}
+		start := i
+		for i < len(r) && r[i] != '=' && r[i] != ' ' {
+			i++
+		}
+		key := string(r[start:i])
+		for i < len(r) && r[i] == ' ' {
+			i++
+		}
+		if i >= len(r) || r[i] != '=' {
+			continue
+		}
+		i++
+		for i < len(r) && r[i] == ' ' {
+			i++
+		}
+
+		var value strings.Builder
+		quoted := i < len(r) && r[i] == '\''
+		if quoted {
+			i++
+		}
+		for ; i < len(r); i++ {
+			if quoted && r[i] == '\'' {
+				i++
+				break
+			}
+			if !quoted && r[i] == ' ' {
+				break
+			}
+			if r[i] == '\\' && i+1 < len(r) {
+				i++
+			}
+			value.WriteRune(r[i])
+		}
+		params[key] = value.String()
+	}
+	return params
+}
+
+// quoteDSN quotes a connection string value.
+func quoteDSN(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
@@ -0,0 +1,67 @@
+package connection
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParseDSN(t *testing.T) {
+	got := parseDSN(`host=db.example.com port = 5433 user=bench password='it\'s a \\secret' dbname=ledger sslmode=disable sslmode=require`)
+	want := map[string]string{
+		"host": "db.example.com", "port": "5433", "user": "bench", "password": `it's a \secret`,
+		"dbname": "ledger", "sslmode": "require",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parsed %v, want %v", got, want)
+	}
+	if value := parseDSN("password=" + quoteDSN(`a 'b' \c`))["password"]; value != `a 'b' \c` {
+		t.Errorf("quoted value read back as %q", value)
+	}
+}
+
+func TestPostgresConnString(t *testing.T) {
+	defer func(dsn, mode, cert string, iam bool) {
+		PostgresDSN, PostgresSSLMode, PostgresRootCert, PostgresIAM = dsn, mode, cert, iam
+	}(PostgresDSN, PostgresSSLMode, PostgresRootCert, PostgresIAM)
+
+	PostgresDSN = "postgres://bench@mydb.abc123.eu-west-1.rds.amazonaws.com:5432/ledger?sslmode=disable"
+	PostgresSSLMode, PostgresRootCert = "verify-full", "/etc/ssl/rds bundle.pem"
+	dsn, err := PostgresConnString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := parseDSN(dsn)
+	if params["host"] != "mydb.abc123.eu-west-1.rds.amazonaws.com" || params["user"] != "bench" ||
+		params["sslmode"] != "verify-full" || params["sslrootcert"] != "/etc/ssl/rds bundle.pem" {
+		t.Errorf("connection string %q", dsn)
+	}
+	if m := rdsHost.FindStringSubmatch(params["host"]); m == nil || m[1] != "eu-west-1" {
+		t.Errorf("region of %s read as %v", params["host"], m)
+	}
+
+	PostgresIAM, PostgresSSLMode = true, ""
+	if _, err := PostgresConnector(); err == nil {
+		t.Error("IAM authentication accepted sslmode=disable")
+	}
+}
+
+func TestPostgresConnectorDriver(t *testing.T) {
+	defer func(driver string) { PostgresDriver = driver }(PostgresDriver)
+
+	for _, tt := range []struct{ driver, want string }{{"pq", "*pq.Driver"}, {"pgx", "*stdlib.Driver"}} {
+		PostgresDriver = tt.driver
+		connector, err := PostgresConnector()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprintf("%T", connector.Driver()); got != tt.want {
+			t.Errorf("%s connector's driver is %s, want %s", tt.driver, got, tt.want)
+		}
+	}
+
+	PostgresDriver = "pgxpool"
+	if _, err := PostgresConnector(); err == nil {
+		t.Error("unknown driver accepted")
+	}
+}
@@ -0,0 +1,45 @@
+package dataset
+
+import "github.com/shopspring/decimal"
+
+// Anomalies a seeded transaction can be injected with, so that the
+// reconciliation jobs can be checked for finding every one rather than
+// only timed.
+const (
+	// Unbalanced transactions debit a cent more than they credit.
+	Unbalanced = "unbalanced"
+	// OrphanLegs are legs written without their transaction's header.
+	OrphanLegs = "orphan-legs"
+	// DuplicateKey transactions reuse another transaction's idempotency
+	// key.
+	DuplicateKey = "duplicate-key"
+)
+
+// AnomalyKinds are the anomalies Volumes.Anomalies injects, in the order
+// they take turns.
+var AnomalyKinds = []string{Unbalanced, OrphanLegs, DuplicateKey}
+
+var anomalyCent = decimal.RequireFromString("0.01")
+
+// anomaly is the anomaly the i-th transaction is injected with, if any:
+// the first Anomalies of each kind, taking turns, are the first
+// transactions.
+func (g *Generator) anomaly(i int) string {
+	if i >= g.volumes.Anomalies*len(AnomalyKinds) {
+		return ""
+	}
+	return AnomalyKinds[i%len(AnomalyKinds)]
+}
+
+// inject applies t's anomaly to it, the i-th transaction. A duplicate
+// takes the key of a transaction past the anomalous ones, which Validate
+// ensures there is.
+func (g *Generator) inject(t *Transaction, i int) {
+	switch t.Anomaly {
+	case Unbalanced:
+		t.Legs[0].Amount = t.Legs[0].Amount.Add(anomalyCent)
+	case DuplicateKey:
+		normal := g.volumes.Anomalies * len(AnomalyKinds)
+		t.IdempotencyKey = g.Transaction(normal + i/len(AnomalyKinds)%(g.volumes.Transactions-normal)).IdempotencyKey
+	}
+}
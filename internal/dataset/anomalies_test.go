@@ -0,0 +1,43 @@
+package dataset
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestAnomalies(t *testing.T) {
+	g := New(3, Volumes{Merchants: 10, Accounts: 50, Transactions: 100, Anomalies: 4})
+	keys := make(map[uuid.UUID]int)
+	count := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		txn := g.Transaction(i)
+		keys[txn.IdempotencyKey]++
+		count[txn.Anomaly]++
+		debits, credits := txn.Legs[0].Amount, txn.Legs[1].Amount
+		for _, leg := range txn.Legs[2:] {
+			credits = credits.Add(leg.Amount)
+		}
+		if unbalanced := !debits.Equal(credits); unbalanced != (txn.Anomaly == Unbalanced) {
+			t.Errorf("transaction %d with anomaly %q debits %s and credits %s", i, txn.Anomaly, debits, credits)
+		}
+		if txn.Anomaly == DuplicateKey && keys[txn.IdempotencyKey] > 1 {
+			t.Errorf("transaction %d's key was taken before it", i)
+		}
+	}
+	for _, kind := range AnomalyKinds {
+		if count[kind] != 4 {
+			t.Errorf("%d transactions are %s, want 4", count[kind], kind)
+		}
+	}
+	duplicated := 0
+	for _, n := range keys {
+		duplicated += n - 1
+	}
+	if duplicated != 4 {
+		t.Errorf("%d idempotency keys reused, want 4", duplicated)
+	}
+	if err := (Volumes{Merchants: 1, Accounts: 10, Transactions: 9, Users: 1, Statuses: DefaultStatusMix, Legs: 2, Anomalies: 3}).Validate(); err == nil {
+		t.Error("anomalies for every transaction accepted")
+	}
+}
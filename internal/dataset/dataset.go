@@ -0,0 +1,507 @@
+// Package dataset generates the seeded ledger both databases are loaded
+// with: merchants, accounts and double-entry transactions. Each record is
+// derived from the seed and its index alone, so the PostgreSQL and DynamoDB
+// seeders given the same seed write identical data, IDs included, whatever
+// order they write it in or however many writes fail, and reconciliation
+// and read benchmarks compare like for like.
+package dataset
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// MaxAgeDays bounds how old a seeded transaction is.
+const MaxAgeDays = 90
+
+// Transaction amounts are log-normal, as card and transfer amounts are:
+// most are small, with a long tail of large ones. AmountMedian is the
+// typical amount and AmountSigma the spread of its logarithm; amounts are
+// capped at MaxAmount and rounded to the cent.
+const (
+	AmountMedian = 25.0
+	AmountSigma  = 1.2
+	MaxAmount    = 100_000.0
+)
+
+// creditDraws bounds how many accounts a transaction draws looking for a
+// credit account in the debit account's currency.
+const creditDraws = 64
+
+// MaxTransactions bounds Volumes.Transactions, well past any dataset a
+// single seeder can load.
+const MaxTransactions = 1_000_000_000
+
+// Volumes is how many of each record a dataset has.
+type Volumes struct {
+	Merchants    int
+	Accounts     int
+	Transactions int
+	// Users own the accounts, each user at least one. WithDefaults makes
+	// 0 two users for every five accounts.
+	Users int
+	// Statuses is how the transactions are split between Statuses.
+	Statuses StatusMix
+	// Skew is the Zipf exponent transactions pick their merchant and
+	// accounts with: 0 spreads them evenly, 1 has the top 1% of 10,000
+	// accounts carry about half the legs, and higher values concentrate
+	// them further.
+	Skew float64
+	// Traffic is when the transactions were created.
+	Traffic Traffic
+	// Legs is the most legs a transaction has, from 2 (double entry
+	// only) to MaxLegs.
+	Legs int
+	// Anomalies is how many transactions are injected with each of
+	// AnomalyKinds.
+	Anomalies int
+}
+
+// DefaultVolumes is the dataset seeded unless flags say otherwise.
+var DefaultVolumes = Volumes{Merchants: 1000, Accounts: 10000, Transactions: 100000, Users: 4000, Statuses: DefaultStatusMix, Skew: DefaultSkew, Legs: DefaultLegs}
+
+// DefaultSkew is Volumes.Skew unless flags say otherwise. WithDefaults
+// leaves Skew alone, since 0 is a valid choice.
+const DefaultSkew = 1.0
+
+// Statuses are the states a transaction can be in, as the schemas spell
+// them.
+var Statuses = []string{"pending", "completed", "failed", "reversed"}
+
+// StatusMix weighs each of Statuses, in order: a seeded transaction is in
+// a status with its weight's share of the total.
+type StatusMix [4]float64
+
+// DefaultStatusMix is mostly settled transactions with a few in flight,
+// failed and reversed, so each status has a GSI partition and index range
+// of its own to query.
+var DefaultStatusMix = StatusMix{5, 90, 3, 2}
+
+// ParseStatusMix parses a mix such as "pending=5,completed=90,failed=3,reversed=2".
+// Statuses left out weigh 0.
+func ParseStatusMix(s string) (StatusMix, error) {
+	var mix StatusMix
+	for _, part := range strings.Split(s, ",") {
+		name, weight, ok := strings.Cut(strings.TrimSpace(part), "=")
+		i := slices.Index(Statuses, name)
+		if !ok || i < 0 {
+			return mix, fmt.Errorf("status mix entries are <status>=<weight>, with status one of %s, not %q", strings.Join(Statuses, ", "), part)
+		}
+		w, err := strconv.ParseFloat(weight, 64)
+		if err != nil || w < 0 {
+			return mix, fmt.Errorf("%s weight must be a number at least 0, not %q", name, weight)
+		}
+		mix[i] = w
+	}
+	return mix, nil
+}
+
+// String formats the mix as ParseStatusMix reads it.
+func (m StatusMix) String() string {
+	parts := make([]string, len(m))
+	for i, w := range m {
+		parts[i] = Statuses[i] + "=" + strconv.FormatFloat(w, 'g', -1, 64)
+	}
+	return strings.Join(parts, ",")
+}
+
+// WithDefaults fills each volume left at 0 from DefaultVolumes.
+func (v Volumes) WithDefaults() Volumes {
+	if v.Merchants == 0 {
+		v.Merchants = DefaultVolumes.Merchants
+	}
+	if v.Accounts == 0 {
+		v.Accounts = DefaultVolumes.Accounts
+	}
+	if v.Transactions == 0 {
+		v.Transactions = DefaultVolumes.Transactions
+	}
+	if v.Users == 0 {
+		v.Users = max(1, v.Accounts*2/5)
+	}
+	if v.Statuses == (StatusMix{}) {
+		v.Statuses = DefaultVolumes.Statuses
+	}
+	if v.Legs == 0 {
+		v.Legs = DefaultVolumes.Legs
+	}
+	return v
+}
+
+// Validate reports a volume no dataset can have: transactions need a
+// merchant and two accounts to move money between, and the suites need at
+// least one transaction to read, in some status.
+func (v Volumes) Validate() error {
+	if v.Skew < 0 || math.IsNaN(v.Skew) {
+		return fmt.Errorf("skew must be at least 0, got %g", v.Skew)
+	}
+	for _, s := range v.Traffic.Spikes {
+		if err := s.Validate(); err != nil {
+			return err
+		}
+	}
+	total := 0.0
+	for i, w := range v.Statuses {
+		if w < 0 {
+			return fmt.Errorf("%s weight must be at least 0, got %g", Statuses[i], w)
+		}
+		total += w
+	}
+	switch {
+	case v.Merchants < 1:
+		return fmt.Errorf("merchants must be at least 1, got %d", v.Merchants)
+	case v.Accounts < 2:
+		return fmt.Errorf("accounts must be at least 2, got %d", v.Accounts)
+	case v.Transactions < 1 || v.Transactions > MaxTransactions:
+		return fmt.Errorf("transactions must be between 1 and %d, got %d", MaxTransactions, v.Transactions)
+	case v.Users < 1 || v.Users > v.Accounts:
+		return fmt.Errorf("users must be between 1 and the %d accounts, got %d", v.Accounts, v.Users)
+	case v.Legs < 2 || v.Legs > MaxLegs:
+		return fmt.Errorf("legs must be between 2 and %d, got %d", MaxLegs, v.Legs)
+	case total == 0:
+		return fmt.Errorf("status mix must weigh at least one status above 0")
+	case v.Anomalies < 0 || v.Anomalies > 0 && v.Anomalies*len(AnomalyKinds) >= v.Transactions:
+		return fmt.Errorf("anomalies must be at least 0 and leave some of the %d transactions without one, got %d of each of %d kinds", v.Transactions, v.Anomalies, len(AnomalyKinds))
+	}
+	return nil
+}
+
+// Options are how a seeder loads a dataset.
+type Options struct {
+	Volumes
+	// Workers is how many writes a seeder keeps in flight, where it can
+	// write in parallel.
+	Workers int
+	// Method is how the PostgreSQL seeder loads rows, one of Methods:
+	// "copy" streams them in with COPY FROM, "insert" inserts each record
+	// in a transaction of its own.
+	Method string
+}
+
+// DefaultWorkers is Options.Workers unless flags say otherwise.
+const DefaultWorkers = 16
+
+// Methods are the Options.Method values.
+var Methods = []string{"copy", "insert"}
+
+var (
+	MerchantCategories = []string{"Restaurant", "Retail", "Gas Station", "Grocery", "Entertainment", "Travel", "Healthcare", "Utility"}
+	AccountTypes       = []string{"checking", "savings", "credit"}
+	TransactionTypes   = []string{"payment", "transfer", "refund", "fee"}
+	Currencies         = []string{"USD", "EUR", "GBP"}
+)
+
+// User is a seeded customer, who owns one or more accounts.
+type User struct {
+	ID    uuid.UUID
+	Name  string
+	Email string
+}
+
+// Merchant is a seeded merchant.
+type Merchant struct {
+	ID       uuid.UUID
+	Name     string
+	Category string
+}
+
+// Account is a seeded account, opened active.
+type Account struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	AccountType string
+	Currency    string
+	// Balance is the account's credits less its debits over every seeded
+	// transaction, so verifying balances against the legs finds them
+	// consistent. Accounts that paid out more than they took in are
+	// overdrawn.
+	Balance decimal.Decimal
+}
+
+// Transaction is a seeded transaction moving Amount from its Debit account
+// to its Credit account, in Status, one of Statuses. Split transactions
+// credit part of it to the platform's fee and tax accounts and further
+// payees instead (see Legs). Every leg's account is in Currency. It was
+// created at CreatedAt, AgeDays whole days before the seeding day.
+// Anomaly is the one of AnomalyKinds it was injected with, if any.
+type Transaction struct {
+	ID             uuid.UUID
+	IdempotencyKey uuid.UUID
+	Type           string
+	Status         string
+	MerchantID     uuid.UUID
+	Description    string
+	CreatedAt      time.Time
+	AgeDays        int
+	Amount         decimal.Decimal
+	Currency       string
+	Debit          uuid.UUID
+	Credit         uuid.UUID
+	// Legs are the postings, which balance: the debit of Amount from Debit
+	// first, then the credits.
+	Legs    []Leg
+	Anomaly string
+}
+
+// Record kinds, each drawing from a stream of its own so that changing how
+// one is generated leaves the others as they were.
+const (
+	kindMerchant uint64 = iota + 1
+	kindAccount
+	kindTransaction
+	kindUser
+)
+
+// Generator yields the dataset for one seed and volumes.
+type Generator struct {
+	seed     uint64
+	volumes  Volumes
+	calendar calendar
+	platform map[string]platformAccounts
+
+	balancesOnce sync.Once
+	balances     []decimal.Decimal
+}
+
+// New returns the generator for seed and volumes. Seed 0 draws a random
+// one, so unseeded runs still get fresh data each time. A zero status mix
+// is DefaultStatusMix. Timestamps are laid out back from the start of the
+// current day, UTC (see Traffic).
+func New(seed int64, volumes Volumes) *Generator {
+	if volumes.Statuses == (StatusMix{}) {
+		volumes.Statuses = DefaultStatusMix
+	}
+	if volumes.Legs == 0 {
+		volumes.Legs = DefaultLegs
+	}
+	if volumes.Users == 0 {
+		volumes.Users = max(1, volumes.Accounts*2/5)
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	// Scrambled, so that nearby seeds' streams do not overlap.
+	scrambled := (&stream{state: uint64(seed)}).next()
+	g := &Generator{seed: scrambled, volumes: volumes, calendar: newCalendar(volumes.Traffic, time.Now())}
+	g.platform = g.platformAccounts()
+	return g
+}
+
+// Volumes is how many of each record the generator yields.
+func (g *Generator) Volumes() Volumes {
+	return g.volumes
+}
+
+// Merchant is the i-th merchant.
+func (g *Generator) Merchant(i int) Merchant {
+	s := g.stream(kindMerchant, i)
+	return Merchant{
+		ID:       s.uuid(),
+		Name:     fmt.Sprintf("Merchant_%d", i),
+		Category: MerchantCategories[s.intn(len(MerchantCategories))],
+	}
+}
+
+// User is the i-th user.
+func (g *Generator) User(i int) User {
+	s := g.stream(kindUser, i)
+	return User{
+		ID:    s.uuid(),
+		Name:  fmt.Sprintf("User %d", i),
+		Email: fmt.Sprintf("user%d@example.com", i),
+	}
+}
+
+// Account is the i-th account. The first of every user's accounts is the
+// one with their index; the rest go to users at random. The first call
+// generates every transaction to sum the balances.
+func (g *Generator) Account(i int) Account {
+	g.balancesOnce.Do(g.sumBalances)
+	a := g.account(i)
+	if i < len(g.balances) {
+		a.Balance = g.balances[i]
+	}
+	return a
+}
+
+// account is the i-th account without its balance, which transactions
+// draw their accounts from.
+func (g *Generator) account(i int) Account {
+	s := g.stream(kindAccount, i)
+	owner := i
+	if i >= g.volumes.Users {
+		owner = s.intn(g.volumes.Users)
+	}
+	return Account{
+		ID:          s.uuid(),
+		UserID:      g.User(owner).ID,
+		AccountType: AccountTypes[s.intn(len(AccountTypes))],
+		Currency:    Currencies[s.intn(len(Currencies))],
+	}
+}
+
+// sumBalances posts every transaction's legs to their accounts, leaving
+// out those injected with an anomaly, which the seeders may not write.
+func (g *Generator) sumBalances() {
+	index := make(map[uuid.UUID]int, g.volumes.Accounts)
+	for i := 0; i < g.volumes.Accounts; i++ {
+		index[g.account(i).ID] = i
+	}
+	g.balances = make([]decimal.Decimal, g.volumes.Accounts)
+	for i := 0; i < g.volumes.Transactions; i++ {
+		t := g.Transaction(i)
+		if t.Anomaly != "" {
+			continue
+		}
+		for _, leg := range t.Legs {
+			amount := leg.Amount
+			if leg.Type == "debit" {
+				amount = amount.Neg()
+			}
+			a := index[leg.Account]
+			g.balances[a] = g.balances[a].Add(amount)
+		}
+	}
+}
+
+// Transaction is the i-th transaction, between two of the dataset's
+// accounts in one currency at one of its merchants, each picked with the
+// volumes' skew.
+func (g *Generator) Transaction(i int) Transaction {
+	s := g.stream(kindTransaction, i)
+	t := Transaction{
+		ID:             s.uuid(),
+		IdempotencyKey: s.uuid(),
+		Type:           TransactionTypes[s.intn(len(TransactionTypes))],
+		MerchantID:     g.Merchant(g.pick(s, g.volumes.Merchants)).ID,
+		Description:    fmt.Sprintf("Transaction %d", i),
+		CreatedAt:      g.calendar.draw(s.float64(), s.float64()),
+		Amount:         s.amount(),
+		Status:         g.status(s.float64()),
+	}
+	t.AgeDays = min(int(g.calendar.until.Sub(t.CreatedAt)/(24*time.Hour)), MaxAgeDays-1)
+	debit := g.account(g.pick(s, g.volumes.Accounts))
+	t.Debit, t.Currency = debit.ID, debit.Currency
+	t.Credit = g.creditAccount(s, debit)
+	t.Legs = g.legs(s, t, debit)
+	if t.Anomaly = g.anomaly(i); t.Anomaly != "" {
+		g.inject(&t, i)
+	}
+	return t
+}
+
+// creditAccount draws the account a transaction from debit credits, one in
+// debit's currency. Should creditDraws draws find none, as where only
+// debit holds its currency, the transaction moves money within debit.
+func (g *Generator) creditAccount(s *stream, debit Account) uuid.UUID {
+	for n := 0; n < creditDraws; n++ {
+		if a := g.account(g.pick(s, g.volumes.Accounts)); a.Currency == debit.Currency {
+			return a.ID
+		}
+	}
+	return debit.ID
+}
+
+// pick draws one of n records' indexes, record 0 the most likely when the
+// volumes are skewed. It inverts the continuous Zipf (bounded power-law)
+// distribution over [1, n+1) rather than tabulating the discrete one, so
+// needs no table per dataset and a draw costs the same at any n.
+func (g *Generator) pick(s *stream, n int) int {
+	skew := g.volumes.Skew
+	if skew == 0 {
+		return s.intn(n)
+	}
+	u := s.float64()
+	var x float64
+	if skew == 1 {
+		x = math.Pow(float64(n+1), u)
+	} else {
+		e := 1 - skew
+		x = math.Pow((math.Pow(float64(n+1), e)-1)*u+1, 1/e)
+	}
+	return min(int(x)-1, n-1)
+}
+
+// status is the status a draw u in [0, 1) falls on in the mix.
+func (g *Generator) status(u float64) string {
+	total := 0.0
+	for _, w := range g.volumes.Statuses {
+		total += w
+	}
+	u *= total
+	for i, w := range g.volumes.Statuses {
+		if u < w {
+			return Statuses[i]
+		}
+		u -= w
+	}
+	// Rounding left u at the total: the last status weighed above 0.
+	for i := len(Statuses) - 1; ; i-- {
+		if g.volumes.Statuses[i] > 0 || i == 0 {
+			return Statuses[i]
+		}
+	}
+}
+
+// Settled reports whether a transaction in status has finished, so has a
+// completion time.
+func Settled(status string) bool {
+	return status != "pending"
+}
+
+// stream starts the values of the index-th record of kind.
+func (g *Generator) stream(kind uint64, index int) *stream {
+	s := &stream{state: g.seed ^ kind<<56 ^ uint64(index)}
+	s.next()
+	return s
+}
+
+// stream is a SplitMix64 generator: cheap to start for every record, which
+// math/rand's sources are not.
+type stream struct {
+	state uint64
+}
+
+func (s *stream) next() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+	z := s.state
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return z ^ z>>31
+}
+
+func (s *stream) intn(n int) int {
+	return int(s.next() % uint64(n))
+}
+
+func (s *stream) float64() float64 {
+	return float64(s.next()>>11) / (1 << 53)
+}
+
+// amount is a log-normal transaction amount (see AmountMedian).
+func (s *stream) amount() decimal.Decimal {
+	// Box-Muller; 1-u keeps the logarithm's argument above 0.
+	u, v := 1-s.float64(), s.float64()
+	z := math.Sqrt(-2*math.Log(u)) * math.Cos(2*math.Pi*v)
+	a := min(AmountMedian*math.Exp(AmountSigma*z), MaxAmount)
+	return decimal.NewFromFloat(max(a, 0.01)).Round(2)
+}
+
+// uuid is a random (version 4) UUID drawn from the stream.
+func (s *stream) uuid() uuid.UUID {
+	var id uuid.UUID
+	binary.BigEndian.PutUint64(id[:8], s.next())
+	binary.BigEndian.PutUint64(id[8:], s.next())
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+	return id
+}
@@ -0,0 +1,196 @@
+package dataset
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestGeneratorDeterministic(t *testing.T) {
+	volumes := Volumes{Merchants: 10, Accounts: 50, Transactions: 100}
+	a, b := New(42, volumes), New(42, volumes)
+	for i := 0; i < 100; i++ {
+		if a.Merchant(i) != b.Merchant(i) {
+			t.Fatalf("merchant %d differs for one seed", i)
+		}
+		if x, y := a.Account(i), b.Account(i); x.ID != y.ID || !x.Balance.Equal(y.Balance) {
+			t.Fatalf("account %d differs for one seed", i)
+		}
+		if x, y := a.Transaction(i), b.Transaction(i); x.ID != y.ID || x.Debit != y.Debit || !x.Amount.Equal(y.Amount) {
+			t.Fatalf("transaction %d differs for one seed", i)
+		}
+	}
+	if New(42, volumes).Account(0).ID == New(43, volumes).Account(0).ID {
+		t.Error("seeds 42 and 43 generated the same account")
+	}
+}
+
+func TestGeneratorReferences(t *testing.T) {
+	g := New(7, Volumes{Merchants: 10, Accounts: 50, Transactions: 1000})
+	accounts := make(map[uuid.UUID]string)
+	for i := 0; i < 50; i++ {
+		a := g.Account(i)
+		accounts[a.ID] = a.Currency
+	}
+	merchants := make(map[uuid.UUID]bool)
+	for i := 0; i < 10; i++ {
+		merchants[g.Merchant(i).ID] = true
+	}
+	seen := make(map[uuid.UUID]bool)
+	for i := 0; i < 1000; i++ {
+		txn := g.Transaction(i)
+		if accounts[txn.Debit] == "" || accounts[txn.Credit] == "" || !merchants[txn.MerchantID] {
+			t.Fatalf("transaction %d references a record outside the dataset", i)
+		}
+		if accounts[txn.Debit] != txn.Currency || accounts[txn.Credit] != txn.Currency {
+			t.Fatalf("transaction %d in %s moves money between %s and %s accounts", i, txn.Currency, accounts[txn.Debit], accounts[txn.Credit])
+		}
+		if txn.ID.Version() != 4 || txn.ID.Variant() != uuid.RFC4122 {
+			t.Fatalf("transaction ID %s is not a version 4 UUID", txn.ID)
+		}
+		if seen[txn.ID] {
+			t.Fatalf("transaction ID %s generated twice", txn.ID)
+		}
+		seen[txn.ID] = true
+		if txn.AgeDays < 0 || txn.AgeDays >= MaxAgeDays || !txn.Amount.IsPositive() || txn.Amount.GreaterThan(decimal.NewFromFloat(MaxAmount)) {
+			t.Fatalf("transaction %d: age %d days, amount %s", i, txn.AgeDays, txn.Amount)
+		}
+	}
+}
+
+func TestVolumes(t *testing.T) {
+	want := Volumes{Merchants: 1000, Accounts: 5, Transactions: 100000, Users: 2, Statuses: DefaultStatusMix, Skew: 2, Legs: DefaultLegs}
+	if v := (Volumes{Accounts: 5, Skew: 2}).WithDefaults(); !reflect.DeepEqual(v, want) {
+		t.Errorf("defaults filled to %+v", v)
+	}
+	if err := DefaultVolumes.Validate(); err != nil {
+		t.Error(err)
+	}
+	mix := DefaultStatusMix
+	for _, v := range []Volumes{
+		{Merchants: 0, Accounts: 10, Transactions: 10, Users: 1, Statuses: mix, Legs: 2},
+		{Merchants: 1, Accounts: 1, Transactions: 10, Users: 1, Statuses: mix, Legs: 2},
+		{Merchants: 1, Accounts: 10, Transactions: -1, Users: 1, Statuses: mix, Legs: 2},
+		{Merchants: 1, Accounts: 10, Transactions: MaxTransactions + 1, Users: 1, Statuses: mix, Legs: 2},
+		{Merchants: 1, Accounts: 10, Transactions: 10, Users: 1, Legs: 2},
+		{Merchants: 1, Accounts: 10, Transactions: 10, Users: 1, Statuses: StatusMix{1, -1, 0, 0}, Legs: 2},
+		{Merchants: 1, Accounts: 10, Transactions: 10, Users: 1, Statuses: mix, Skew: -1, Legs: 2},
+		{Merchants: 1, Accounts: 10, Transactions: 10, Statuses: mix, Legs: 2},
+		{Merchants: 1, Accounts: 10, Transactions: 10, Users: 11, Statuses: mix, Legs: 2},
+		{Merchants: 1, Accounts: 10, Transactions: 10, Users: 1, Statuses: mix, Legs: 1},
+		{Merchants: 1, Accounts: 10, Transactions: 10, Users: 1, Statuses: mix, Legs: MaxLegs + 1},
+		{Merchants: 1, Accounts: 10, Transactions: 10, Users: 1, Statuses: mix, Traffic: Traffic{Spikes: []Spike{{MaxAgeDays + 1, 2}}}, Legs: 2},
+	} {
+		if v.Validate() == nil {
+			t.Errorf("%+v accepted", v)
+		}
+	}
+}
+
+func TestUsers(t *testing.T) {
+	g := New(5, Volumes{Merchants: 10, Accounts: 100, Transactions: 10, Users: 40})
+	users := make(map[uuid.UUID]int)
+	for i := 0; i < 40; i++ {
+		users[g.User(i).ID] = 0
+	}
+	for i := 0; i < 100; i++ {
+		owner := g.Account(i).UserID
+		if _, ok := users[owner]; !ok {
+			t.Fatalf("account %d belongs to user %s outside the dataset", i, owner)
+		}
+		users[owner]++
+	}
+	for id, n := range users {
+		if n == 0 {
+			t.Errorf("user %s owns no accounts", id)
+		}
+	}
+}
+
+func TestBalances(t *testing.T) {
+	g := New(5, Volumes{Merchants: 10, Accounts: 50, Transactions: 2000, Legs: 6})
+	want := make(map[uuid.UUID]decimal.Decimal)
+	for i := 0; i < 2000; i++ {
+		for _, leg := range g.Transaction(i).Legs {
+			if leg.Type == "debit" {
+				want[leg.Account] = want[leg.Account].Sub(leg.Amount)
+			} else {
+				want[leg.Account] = want[leg.Account].Add(leg.Amount)
+			}
+		}
+	}
+	total := decimal.Zero
+	for i := 0; i < 50; i++ {
+		a := g.Account(i)
+		if !a.Balance.Equal(want[a.ID]) {
+			t.Errorf("account %d has balance %s, its legs sum to %s", i, a.Balance, want[a.ID])
+		}
+		total = total.Add(a.Balance)
+	}
+	if !total.IsZero() {
+		t.Errorf("balances sum to %s", total)
+	}
+}
+
+func TestSkew(t *testing.T) {
+	legs := func(skew float64) int {
+		g := New(5, Volumes{Merchants: 10, Accounts: 10000, Transactions: 5000, Skew: skew})
+		top := 0
+		for i := 0; i < 5000; i++ {
+			if txn := g.Transaction(i); txn.Debit == g.Account(0).ID || txn.Debit == g.Account(1).ID {
+				top++
+			}
+		}
+		return top
+	}
+	// Two of 10,000 accounts: about 1 debit in 5,000 evenly, 1 in 8 at
+	// skew 1.
+	if even, skewed := legs(0), legs(1); even > 10 || skewed < 400 || skewed > 900 {
+		t.Errorf("top two accounts debited %d times evenly, %d at skew 1", even, skewed)
+	}
+}
+
+func TestStatusMix(t *testing.T) {
+	mix, err := ParseStatusMix("pending=1, completed=3")
+	if err != nil || mix != (StatusMix{1, 3, 0, 0}) {
+		t.Fatalf("parsed %v, %v", mix, err)
+	}
+	if again, _ := ParseStatusMix(mix.String()); again != mix {
+		t.Errorf("%s parsed back as %v", mix, again)
+	}
+	for _, bad := range []string{"settled=1", "pending", "failed=-1", "reversed=x"} {
+		if _, err := ParseStatusMix(bad); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+
+	g := New(3, Volumes{Merchants: 10, Accounts: 50, Transactions: 4000, Statuses: mix})
+	counts := make(map[string]int)
+	for i := 0; i < 4000; i++ {
+		counts[g.Transaction(i).Status]++
+	}
+	if counts["failed"] != 0 || counts["reversed"] != 0 || counts["pending"] < 800 || counts["pending"] > 1200 {
+		t.Errorf("mix %s seeded %v", mix, counts)
+	}
+}
+
+func TestAmountsLogNormal(t *testing.T) {
+	g := New(11, Volumes{Merchants: 10, Accounts: 50, Transactions: 2000})
+	median := decimal.NewFromFloat(AmountMedian)
+	below, large := 0, 0
+	for i := 0; i < 2000; i++ {
+		amount := g.Transaction(i).Amount
+		if amount.LessThan(median) {
+			below++
+		}
+		if amount.GreaterThan(median.Mul(decimal.NewFromInt(10))) {
+			large++
+		}
+	}
+	// Half fall below the median; about 3% lie past ten times it.
+	if below < 900 || below > 1100 || large == 0 || large > 150 {
+		t.Errorf("%d of 2000 amounts below %v, %d past ten times it", below, median, large)
+	}
+}
@@ -0,0 +1,111 @@
+package dataset
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// A split transaction posts more than a debit and a credit: a payment that
+// also credits the platform's fee account, then tax on the fee, then
+// shares for further payees, as marketplace payouts do. Multi-leg postings
+// are where DynamoDB's per-request item limits start to matter.
+const (
+	// MaxLegs bounds Volumes.Legs, so a transaction's header and legs fit
+	// one 25-item BatchWriteItem when seeding DynamoDB.
+	MaxLegs = 24
+	// DefaultLegs is Volumes.Legs unless flags say otherwise.
+	DefaultLegs = 4
+	// splitShare is the share of transactions that are split, when the
+	// volumes allow more than two legs.
+	splitShare = 0.3
+	// minSplit is the smallest amount split, so that every leg is at least
+	// a cent.
+	minSplit = 10
+)
+
+var (
+	feeRate = decimal.RequireFromString("0.029")
+	taxRate = decimal.RequireFromString("0.2")
+)
+
+// Leg is one posting of a transaction.
+type Leg struct {
+	ID      uuid.UUID
+	Account uuid.UUID
+	Type    string // debit or credit
+	Amount  decimal.Decimal
+}
+
+// platformAccounts are the accounts split transactions in one currency
+// credit fees and tax to.
+type platformAccounts struct {
+	fee, tax uuid.UUID
+}
+
+// platformAccounts picks, for each currency, its first two accounts as the
+// platform's fee and tax accounts. A currency with a single account uses
+// it for both; one with none has no split transactions to need them.
+func (g *Generator) platformAccounts() map[string]platformAccounts {
+	found := make(map[string][]uuid.UUID, len(Currencies))
+	for i, full := 0, 0; i < g.volumes.Accounts && full < len(Currencies); i++ {
+		a := g.account(i)
+		if len(found[a.Currency]) < 2 {
+			found[a.Currency] = append(found[a.Currency], a.ID)
+			if len(found[a.Currency]) == 2 {
+				full++
+			}
+		}
+	}
+	platform := make(map[string]platformAccounts, len(found))
+	for currency, ids := range found {
+		platform[currency] = platformAccounts{fee: ids[0], tax: ids[len(ids)-1]}
+	}
+	return platform
+}
+
+// legs posts t: the debit of t.Amount from debit, then credit legs summing
+// to it. Most transactions credit t.Credit alone; a split one of n legs
+// also credits a fee (n >= 3), tax on it (n >= 4) and n-4 further payees
+// drawn like t.Credit, who share the rest with it equally.
+func (g *Generator) legs(s *stream, t Transaction, debit Account) []Leg {
+	n := 2
+	if s.float64() < splitShare && g.volumes.Legs > 2 && t.Amount.GreaterThanOrEqual(decimal.NewFromInt(minSplit)) {
+		n = 3 + s.intn(g.volumes.Legs-2)
+	}
+	legs := make([]Leg, 0, n)
+	legs = append(legs, Leg{ID: s.uuid(), Account: t.Debit, Type: "debit", Amount: t.Amount})
+	credit := func(account uuid.UUID, amount decimal.Decimal) {
+		legs = append(legs, Leg{ID: s.uuid(), Account: account, Type: "credit", Amount: amount})
+	}
+
+	payees := []uuid.UUID{t.Credit}
+	for len(payees) < n-3 {
+		payees = append(payees, g.creditAccount(s, debit))
+	}
+	rest := t.Amount
+	var fee, tax decimal.Decimal
+	if n >= 3 {
+		fee = t.Amount.Mul(feeRate).Round(2)
+		rest = rest.Sub(fee)
+	}
+	if n >= 4 {
+		tax = fee.Mul(taxRate).Round(2)
+		rest = rest.Sub(tax)
+	}
+
+	// Equal shares to the cent, the first payee taking what rounding
+	// leaves.
+	share := rest.Div(decimal.NewFromInt(int64(len(payees)))).RoundDown(2)
+	credit(payees[0], rest.Sub(share.Mul(decimal.NewFromInt(int64(len(payees)-1)))))
+	platform := g.platform[t.Currency]
+	if n >= 3 {
+		credit(platform.fee, fee)
+	}
+	if n >= 4 {
+		credit(platform.tax, tax)
+	}
+	for _, payee := range payees[1:] {
+		credit(payee, share)
+	}
+	return legs
+}
@@ -0,0 +1,50 @@
+package dataset
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+func TestLegsBalance(t *testing.T) {
+	g := New(9, Volumes{Merchants: 10, Accounts: 200, Transactions: 3000, Legs: 8})
+	currencies := make(map[uuid.UUID]string)
+	for i := 0; i < 200; i++ {
+		a := g.Account(i)
+		currencies[a.ID] = a.Currency
+	}
+	split := 0
+	for i := 0; i < 3000; i++ {
+		txn := g.Transaction(i)
+		if len(txn.Legs) < 2 || len(txn.Legs) > 8 {
+			t.Fatalf("transaction %d has %d legs", i, len(txn.Legs))
+		}
+		if len(txn.Legs) > 2 {
+			split++
+		}
+		if first := txn.Legs[0]; first.Type != "debit" || first.Account != txn.Debit || !first.Amount.Equal(txn.Amount) {
+			t.Fatalf("transaction %d starts with %+v", i, first)
+		}
+		credits := decimal.Zero
+		for _, leg := range txn.Legs[1:] {
+			if leg.Type != "credit" || !leg.Amount.IsPositive() || currencies[leg.Account] != txn.Currency {
+				t.Fatalf("transaction %d in %s has leg %+v", i, txn.Currency, leg)
+			}
+			credits = credits.Add(leg.Amount)
+		}
+		if !credits.Equal(txn.Amount) {
+			t.Fatalf("transaction %d debits %s and credits %s", i, txn.Amount, credits)
+		}
+	}
+	if split < 500 || split > 1000 {
+		t.Errorf("%d of 3000 transactions split", split)
+	}
+
+	double := New(9, Volumes{Merchants: 10, Accounts: 200, Transactions: 100, Legs: 2})
+	for i := 0; i < 100; i++ {
+		if n := len(double.Transaction(i).Legs); n != 2 {
+			t.Fatalf("transaction %d has %d legs with at most 2", i, n)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package dataset
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Traffic is when in the MaxAgeDays before seeding the transactions were
+// created. By default they cluster the way card and transfer traffic does:
+// in business hours on weekdays, with evenings, nights and weekends
+// quieter. Hours are UTC.
+type Traffic struct {
+	// Flat spreads transactions evenly over every hour instead.
+	Flat bool
+	// Spikes multiply the traffic of single days, such as a sale or a
+	// month-end run.
+	Spikes []Spike
+}
+
+// Spike multiplies the traffic of the day DaysAgo days before seeding,
+// 1 being yesterday, by Factor.
+type Spike struct {
+	DaysAgo int
+	Factor  float64
+}
+
+// hourWeights weighs each UTC hour of the day for business traffic.
+var hourWeights = [24]float64{
+	0.2, 0.15, 0.1, 0.1, 0.1, 0.2, 0.4, 0.7, // night and early morning
+	1.2, 1.6, 1.8, 1.8, 2.0, 2.0, 1.8, 1.7, 1.6, 1.5, // business hours
+	1.3, 1.1, 0.9, 0.7, 0.5, 0.3, // evening
+}
+
+// weekdayWeights weighs each day of the week, from Sunday, for business
+// traffic.
+var weekdayWeights = [7]float64{0.45, 1, 1, 1, 1, 1.1, 0.6}
+
+// ParseSpike parses a spike such as "7:5", five times the usual traffic
+// seven days before seeding.
+func ParseSpike(s string) (Spike, error) {
+	days, factor, ok := strings.Cut(s, ":")
+	spike := Spike{}
+	var err error
+	if ok {
+		spike.DaysAgo, err = strconv.Atoi(days)
+	}
+	if ok && err == nil {
+		spike.Factor, err = strconv.ParseFloat(factor, 64)
+	}
+	if !ok || err != nil {
+		return spike, fmt.Errorf("a spike is <days ago>:<factor>, such as 7:5, not %q", s)
+	}
+	return spike, spike.Validate()
+}
+
+// Validate reports a spike outside the seeded days or one that does not
+// scale traffic by a positive factor.
+func (s Spike) Validate() error {
+	switch {
+	case s.DaysAgo < 1 || s.DaysAgo > MaxAgeDays:
+		return fmt.Errorf("spike days ago must be between 1 and %d, got %d", MaxAgeDays, s.DaysAgo)
+	case !(s.Factor > 0):
+		return fmt.Errorf("spike factor must be above 0, got %g", s.Factor)
+	}
+	return nil
+}
+
+// calendar is the hours transactions can be created in, from the oldest,
+// with the running total of their weights for drawing one.
+type calendar struct {
+	// until is when the newest hour ends: midnight UTC of the seeding day,
+	// so every database seeded that day with one seed gets the same
+	// timestamps, and none are in the future.
+	until time.Time
+	cdf   []float64
+}
+
+func newCalendar(traffic Traffic, now time.Time) calendar {
+	c := calendar{until: now.UTC().Truncate(24 * time.Hour), cdf: make([]float64, MaxAgeDays*24)}
+	spikes := make(map[int]float64)
+	for _, s := range traffic.Spikes {
+		if spikes[s.DaysAgo] == 0 {
+			spikes[s.DaysAgo] = 1
+		}
+		spikes[s.DaysAgo] *= s.Factor
+	}
+	total := 0.0
+	for i := range c.cdf {
+		start := c.hour(i)
+		w := 1.0
+		if !traffic.Flat {
+			w = weekdayWeights[start.Weekday()] * hourWeights[start.Hour()]
+		}
+		if f, ok := spikes[int(c.until.Sub(start)/(24*time.Hour))+1]; ok {
+			w *= f
+		}
+		total += w
+		c.cdf[i] = total
+	}
+	return c
+}
+
+// hour is when the i-th hour, from the oldest, starts.
+func (c calendar) hour(i int) time.Time {
+	return c.until.Add(time.Duration(i-len(c.cdf)) * time.Hour)
+}
+
+// draw is the time draws u and v in [0, 1) fall on: u picks the hour by its
+// weight, v the moment within it.
+func (c calendar) draw(u, v float64) time.Time {
+	i := sort.SearchFloat64s(c.cdf, u*c.cdf[len(c.cdf)-1])
+	i = min(i, len(c.cdf)-1)
+	return c.hour(i).Add(time.Duration(v * float64(time.Hour)))
+}
@@ -0,0 +1,56 @@
+package dataset
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTraffic(t *testing.T) {
+	now := time.Date(2024, 3, 15, 13, 30, 0, 0, time.UTC) // a Friday
+	until := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	draws := func(traffic Traffic) (business, weekend, spiked int) {
+		c := newCalendar(traffic, now)
+		s := &stream{state: 1}
+		for i := 0; i < 10000; i++ {
+			at := c.draw(s.float64(), s.float64())
+			if !at.Before(until) || at.Before(until.AddDate(0, 0, -MaxAgeDays)) {
+				t.Fatalf("%v lies outside the %d days before %v", at, MaxAgeDays, until)
+			}
+			if h := at.Hour(); h >= 9 && h < 17 {
+				business++
+			}
+			if d := at.Weekday(); d == time.Saturday || d == time.Sunday {
+				weekend++
+			}
+			if at.YearDay() == until.AddDate(0, 0, -7).YearDay() {
+				spiked++
+			}
+		}
+		return business, weekend, spiked
+	}
+
+	// Flat, a third of the hours are business hours and 2 days in 7 are a
+	// weekend.
+	business, weekend, spiked := draws(Traffic{Flat: true})
+	if business < 3000 || business > 3700 || weekend < 2550 || weekend > 3150 || spiked > 160 {
+		t.Errorf("flat traffic: %d business hours, %d weekend, %d on one day", business, weekend, spiked)
+	}
+	business, weekend, _ = draws(Traffic{})
+	if business < 5000 || weekend > 2300 {
+		t.Errorf("business traffic: %d business hours, %d weekend", business, weekend)
+	}
+	if _, _, spiked = draws(Traffic{Flat: true, Spikes: []Spike{{7, 10}}}); spiked < 800 {
+		t.Errorf("a 10x spike drew %d of 10000", spiked)
+	}
+}
+
+func TestParseSpike(t *testing.T) {
+	if s, err := ParseSpike("7:2.5"); err != nil || s != (Spike{7, 2.5}) {
+		t.Errorf("parsed %+v, %v", s, err)
+	}
+	for _, bad := range []string{"7", "x:2", "7:y", "0:2", "91:2", "7:0"} {
+		if _, err := ParseSpike(bad); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+)
+
+// DynamoDB runs scenarios against the single-table design. Reads are
+// strongly consistent GetItems issued immediately; writes are buffered and
+// committed together with TransactWriteItems, with a Version condition on
+// every balance update.
+type DynamoDB struct {
+	TableName string
+
+	client      *dynamodb.Client
+	accountIDs  []string
+	merchantIDs []string
+}
+
+// NewDynamoDB returns a backend for the configured DynamoDB table. An empty
+// TableName is resolved to connection.DynamoDBTable at Setup, after flags
+// have been parsed.
+func NewDynamoDB() *DynamoDB {
+	return &DynamoDB{}
+}
+
+func (d *DynamoDB) Name() string { return "DynamoDB" }
+
+func (d *DynamoDB) Setup(ctx context.Context) error {
+	if d.TableName == "" {
+		d.TableName = connection.DynamoDBTable
+	}
+	client, err := connection.NewDynamoDBClient(ctx)
+	if err != nil {
+		return err
+	}
+	d.client = client
+
+	if d.accountIDs, err = d.loadIDs(ctx, "Account"); err != nil {
+		return err
+	}
+	if d.merchantIDs, err = d.loadIDs(ctx, "Merchant"); err != nil {
+		return err
+	}
+	if len(d.accountIDs) < 2 || len(d.merchantIDs) == 0 {
+		return errors.New("not enough test data; run `make seed-dynamodb` first")
+	}
+	return nil
+}
+
+func (d *DynamoDB) Teardown(ctx context.Context) error {
+	return nil
+}
+
+func (d *DynamoDB) RandomParams() Params {
+	return randomParams(d.accountIDs, d.merchantIDs)
+}
+
+func (d *DynamoDB) Begin(ctx context.Context) (Tx, error) {
+	return &dynamoTx{backend: d}, nil
+}
+
+func (d *DynamoDB) loadIDs(ctx context.Context, itemType string) ([]string, error) {
+	output, err := d.client.Scan(ctx, &dynamodb.ScanInput{
+		TableName:        aws.String(d.TableName),
+		FilterExpression: aws.String("#t = :type"),
+		ExpressionAttributeNames: map[string]string{
+			"#t": "Type",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":type": &types.AttributeValueMemberS{Value: itemType},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0)
+	for _, item := range output.Items {
+		if id, ok := item["ID"].(*types.AttributeValueMemberS); ok && len(ids) < 100 {
+			ids = append(ids, id.Value)
+		}
+	}
+	return ids, nil
+}
+
+type dynamoTx struct {
+	backend *DynamoDB
+	writes  []types.TransactWriteItem
+}
+
+func (t *dynamoTx) ReadAccount(ctx context.Context, id string) (Account, error) {
+	output, err := t.backend.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(t.backend.TableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: fmt.Sprintf("ACCOUNT#%s", id)},
+			"SK": &types.AttributeValueMemberS{Value: "METADATA"},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return Account{}, err
+	}
+	if output.Item == nil {
+		return Account{}, fmt.Errorf("account %s not found", id)
+	}
+
+	account := Account{ID: id}
+	if v, ok := output.Item["Balance"].(*types.AttributeValueMemberN); ok {
+		account.Balance = decimal.RequireFromString(v.Value)
+	}
+	if v, ok := output.Item["Version"].(*types.AttributeValueMemberN); ok {
+		account.Version, _ = strconv.Atoi(v.Value)
+	}
+	if v, ok := output.Item["Currency"].(*types.AttributeValueMemberS); ok {
+		account.Currency = v.Value
+	}
+	return account, nil
+}
+
+func (t *dynamoTx) WriteTransaction(ctx context.Context, params Params) (string, error) {
+	txnID := uuid.New().String()
+	createdAt := time.Now().Format(time.RFC3339Nano)
+	pk := fmt.Sprintf("TXN#%s", txnID)
+	amount := params.Amount.String()
+
+	t.put(map[string]types.AttributeValue{
+		"PK":              &types.AttributeValueMemberS{Value: pk},
+		"SK":              &types.AttributeValueMemberS{Value: "METADATA"},
+		"GSI1PK":          &types.AttributeValueMemberS{Value: "STATUS#completed"},
+		"GSI1SK":          &types.AttributeValueMemberS{Value: fmt.Sprintf("CREATED#%s", createdAt)},
+		"GSI3PK":          &types.AttributeValueMemberS{Value: fmt.Sprintf("MERCHANT#%s", params.MerchantID)},
+		"GSI3SK":          &types.AttributeValueMemberS{Value: fmt.Sprintf("CREATED#%s", createdAt)},
+		"Type":            &types.AttributeValueMemberS{Value: "Transaction"},
+		"ID":              &types.AttributeValueMemberS{Value: txnID},
+		"TransactionType": &types.AttributeValueMemberS{Value: "payment"},
+		"Status":          &types.AttributeValueMemberS{Value: "completed"},
+		"MerchantID":      &types.AttributeValueMemberS{Value: params.MerchantID},
+		"Description":     &types.AttributeValueMemberS{Value: "Scenario transaction"},
+		"CreatedAt":       &types.AttributeValueMemberS{Value: createdAt},
+		"BenchmarkRunID":  &types.AttributeValueMemberS{Value: benchmark.RunID},
+	})
+
+	for _, role := range []Role{Debit, Credit} {
+		accountID := params.Accounts[role]
+		t.put(map[string]types.AttributeValue{
+			"PK":             &types.AttributeValueMemberS{Value: pk},
+			"SK":             &types.AttributeValueMemberS{Value: fmt.Sprintf("LEG#%s", uuid.New().String())},
+			"GSI1PK":         &types.AttributeValueMemberS{Value: fmt.Sprintf("ACCOUNT#%s", accountID)},
+			"GSI1SK":         &types.AttributeValueMemberS{Value: fmt.Sprintf("LEG#%s#%s", createdAt, txnID)},
+			"Type":           &types.AttributeValueMemberS{Value: "TransactionLeg"},
+			"TransactionID":  &types.AttributeValueMemberS{Value: txnID},
+			"AccountID":      &types.AttributeValueMemberS{Value: accountID},
+			"LegType":        &types.AttributeValueMemberS{Value: string(role)},
+			"Amount":         &types.AttributeValueMemberN{Value: amount},
+			"Currency":       &types.AttributeValueMemberS{Value: "USD"},
+			"CreatedAt":      &types.AttributeValueMemberS{Value: createdAt},
+			"BenchmarkRunID": &types.AttributeValueMemberS{Value: benchmark.RunID},
+		})
+	}
+
+	return txnID, nil
+}
+
+func (t *dynamoTx) UpdateBalance(ctx context.Context, account Account, delta decimal.Decimal) error {
+	t.writes = append(t.writes, types.TransactWriteItem{Update: &types.Update{
+		TableName: aws.String(t.backend.TableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: fmt.Sprintf("ACCOUNT#%s", account.ID)},
+			"SK": &types.AttributeValueMemberS{Value: "METADATA"},
+		},
+		UpdateExpression:    aws.String("SET Balance = Balance + :delta, Version = Version + :one, UpdatedAt = :now"),
+		ConditionExpression: aws.String("Version = :version"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":delta":   &types.AttributeValueMemberN{Value: delta.String()},
+			":one":     &types.AttributeValueMemberN{Value: "1"},
+			":version": &types.AttributeValueMemberN{Value: strconv.Itoa(account.Version)},
+			":now":     &types.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339Nano)},
+		},
+	}})
+	return nil
+}
+
+func (t *dynamoTx) Commit(ctx context.Context) error {
+	if len(t.writes) == 0 {
+		return nil
+	}
+
+	_, err := t.backend.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: t.writes,
+	})
+
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		for _, reason := range canceled.CancellationReasons {
+			if reason.Code != nil && *reason.Code == "ConditionalCheckFailed" {
+				return ErrConflict
+			}
+		}
+	}
+	return err
+}
+
+func (t *dynamoTx) Rollback(ctx context.Context) error {
+	t.writes = nil
+	return nil
+}
+
+func (t *dynamoTx) put(item map[string]types.AttributeValue) {
+	t.writes = append(t.writes, types.TransactWriteItem{Put: &types.Put{
+		TableName: aws.String(t.backend.TableName),
+		Item:      item,
+	}})
+}
@@ -0,0 +1,177 @@
+package scenario
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/shopspring/decimal"
+
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+)
+
+// Postgres runs scenarios inside a database/sql transaction. Balance updates
+// use the accounts.version column for optimistic locking, like the write
+// benchmarks.
+type Postgres struct {
+	ConnStr string
+
+	db          *sql.DB
+	accountIDs  []string
+	merchantIDs []string
+}
+
+// NewPostgres returns a backend for the configured database. An empty
+// ConnStr is resolved to connection.PostgresConnector at Setup, after flags
+// have been parsed, with its TLS and IAM settings.
+func NewPostgres() *Postgres {
+	return &Postgres{}
+}
+
+func (p *Postgres) Name() string { return "PostgreSQL" }
+
+func (p *Postgres) Setup(ctx context.Context) error {
+	var db *sql.DB
+	var err error
+	if p.ConnStr == "" {
+		var connector driver.Connector
+		if connector, err = connection.PostgresConnector(); err != nil {
+			return err
+		}
+		db = sql.OpenDB(connector)
+	} else if db, err = sql.Open("postgres", p.ConnStr); err != nil {
+		return err
+	}
+	db.SetMaxOpenConns(100)
+	db.SetMaxIdleConns(10)
+
+	if p.accountIDs, err = loadIDs(ctx, db, "SELECT id FROM accounts WHERE status = 'active' LIMIT 100"); err != nil {
+		db.Close()
+		return err
+	}
+	if p.merchantIDs, err = loadIDs(ctx, db, "SELECT id FROM merchants LIMIT 100"); err != nil {
+		db.Close()
+		return err
+	}
+	if len(p.accountIDs) < 2 || len(p.merchantIDs) == 0 {
+		db.Close()
+		return errors.New("not enough test data; run `make seed-postgres` first")
+	}
+
+	p.db = db
+	return nil
+}
+
+func (p *Postgres) Teardown(ctx context.Context) error {
+	return p.db.Close()
+}
+
+func (p *Postgres) RandomParams() Params {
+	return randomParams(p.accountIDs, p.merchantIDs)
+}
+
+func (p *Postgres) Begin(ctx context.Context) (Tx, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &postgresTx{tx: tx}, nil
+}
+
+type postgresTx struct {
+	tx *sql.Tx
+}
+
+func (t *postgresTx) ReadAccount(ctx context.Context, id string) (Account, error) {
+	account := Account{ID: id}
+	err := t.tx.QueryRowContext(ctx, "SELECT balance, version, currency FROM accounts WHERE id = $1", id).
+		Scan(&account.Balance, &account.Version, &account.Currency)
+	return account, err
+}
+
+func (t *postgresTx) WriteTransaction(ctx context.Context, params Params) (string, error) {
+	txnID := uuid.New()
+
+	_, err := t.tx.ExecContext(ctx, `
+		INSERT INTO transactions (id, idempotency_key, transaction_type, status, merchant_id, description, benchmark_run_id)
+		VALUES ($1, $2, 'payment', 'completed', $3, 'Scenario transaction', $4)
+	`, txnID, uuid.New().String(), params.MerchantID, benchmark.RunID)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = t.tx.ExecContext(ctx, `
+		INSERT INTO transaction_legs (transaction_id, account_id, leg_type, amount, currency, benchmark_run_id)
+		VALUES ($1, $2, 'debit', $3, 'USD', $5), ($1, $4, 'credit', $3, 'USD', $5)
+	`, txnID, params.Accounts[Debit], params.Amount, params.Accounts[Credit], benchmark.RunID)
+	if err != nil {
+		return "", err
+	}
+
+	return txnID.String(), nil
+}
+
+func (t *postgresTx) UpdateBalance(ctx context.Context, account Account, delta decimal.Decimal) error {
+	res, err := t.tx.ExecContext(ctx, `
+		UPDATE accounts
+		SET balance = balance + $2, version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND version = $3
+	`, account.ID, delta, account.Version)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (t *postgresTx) Commit(ctx context.Context) error {
+	return t.tx.Commit()
+}
+
+func (t *postgresTx) Rollback(ctx context.Context) error {
+	return t.tx.Rollback()
+}
+
+func loadIDs(ctx context.Context, db *sql.DB, query string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id.String())
+	}
+	return ids, rows.Err()
+}
+
+// randomParams picks two distinct accounts, a merchant and an amount.
+func randomParams(accountIDs, merchantIDs []string) Params {
+	debit := benchmark.Rand().Intn(len(accountIDs))
+	credit := (debit + 1 + benchmark.Rand().Intn(len(accountIDs)-1)) % len(accountIDs)
+
+	return Params{
+		Accounts: map[Role]string{
+			Debit:  accountIDs[debit],
+			Credit: accountIDs[credit],
+		},
+		MerchantID: merchantIDs[benchmark.Rand().Intn(len(merchantIDs))],
+		Amount:     decimal.NewFromFloat(benchmark.Rand().Float64()*100 + 1).Round(4),
+	}
+}
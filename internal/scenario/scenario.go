@@ -0,0 +1,301 @@
+// Package scenario describes multi-step financial operations once and runs
+// them against either database. A scenario is a list of steps built with a
+// small Go builder:
+//
+//	scenario.New("transfer-with-funds-check").
+//		Then(scenario.ReadAccount(scenario.Debit)).
+//		Then(scenario.If(scenario.SufficientFunds(scenario.Debit),
+//			scenario.WriteTransaction(),
+//			scenario.Expect(scenario.TransactionWritten()),
+//			scenario.UpdateBalances(),
+//		))
+//
+// Steps only use the Tx primitives, so every scenario compiles into an
+// executable benchmark workload for each Backend without bespoke code.
+package scenario
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/benchmark"
+)
+
+// ErrConflict is returned when an optimistic-locking check fails because the
+// account changed after it was read.
+var ErrConflict = errors.New("scenario: concurrent update conflict")
+
+// Role names one of the accounts taking part in an operation.
+type Role string
+
+const (
+	Debit  Role = "debit"
+	Credit Role = "credit"
+)
+
+// Account is the subset of account state scenarios reason about.
+type Account struct {
+	ID       string
+	Balance  decimal.Decimal
+	Version  int
+	Currency string
+}
+
+// Params are the randomized inputs of one scenario execution.
+type Params struct {
+	Accounts   map[Role]string
+	MerchantID string
+	Amount     decimal.Decimal
+}
+
+// State carries params and everything read so far through the steps of one
+// execution.
+type State struct {
+	Params   Params
+	Accounts map[Role]Account
+	// TransactionID is set once WriteTransaction has run.
+	TransactionID string
+}
+
+// Tx is the set of primitives a backend provides. Reads may happen
+// immediately; writes only become visible at Commit.
+type Tx interface {
+	ReadAccount(ctx context.Context, id string) (Account, error)
+	WriteTransaction(ctx context.Context, params Params) (string, error)
+	UpdateBalance(ctx context.Context, account Account, delta decimal.Decimal) error
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
+
+// Backend connects a scenario to a database.
+type Backend interface {
+	// Name is used as the Database label of results and in workload names.
+	Name() string
+	Setup(ctx context.Context) error
+	Teardown(ctx context.Context) error
+	RandomParams() Params
+	Begin(ctx context.Context) (Tx, error)
+}
+
+// Step is one stage of a scenario.
+type Step struct {
+	Name string
+	Run  func(ctx context.Context, tx Tx, st *State) error
+}
+
+// Condition gates the steps of an If.
+type Condition struct {
+	Name string
+	Fn   func(st *State) bool
+}
+
+// Scenario is an ordered list of steps.
+type Scenario struct {
+	Name  string
+	Steps []Step
+}
+
+// New starts an empty scenario.
+func New(name string) *Scenario {
+	return &Scenario{Name: name}
+}
+
+// Then appends steps to the scenario.
+func (s *Scenario) Then(steps ...Step) *Scenario {
+	s.Steps = append(s.Steps, steps...)
+	return s
+}
+
+// String renders the scenario as "step -> step -> ...".
+func (s *Scenario) String() string {
+	names := make([]string, 0, len(s.Steps))
+	for _, step := range s.Steps {
+		names = append(names, step.Name)
+	}
+	return fmt.Sprintf("%s: %s", s.Name, strings.Join(names, " -> "))
+}
+
+// ReadAccount loads the account playing role into the state.
+func ReadAccount(role Role) Step {
+	return Step{
+		Name: fmt.Sprintf("read %s account", role),
+		Run: func(ctx context.Context, tx Tx, st *State) error {
+			account, err := tx.ReadAccount(ctx, st.Params.Accounts[role])
+			if err != nil {
+				return fmt.Errorf("read %s account: %w", role, err)
+			}
+			st.Accounts[role] = account
+			return nil
+		},
+	}
+}
+
+// WriteTransaction writes a double-entry transaction (header, debit leg and
+// credit leg) for the params' amount.
+func WriteTransaction() Step {
+	return Step{
+		Name: "write transaction",
+		Run: func(ctx context.Context, tx Tx, st *State) error {
+			id, err := tx.WriteTransaction(ctx, st.Params)
+			if err != nil {
+				return fmt.Errorf("write transaction: %w", err)
+			}
+			st.TransactionID = id
+			return nil
+		},
+	}
+}
+
+// UpdateBalances moves the amount from the debit to the credit account,
+// reading either account first if an earlier step has not. Updates are
+// conditional on the version that was read.
+func UpdateBalances() Step {
+	return Step{
+		Name: "update balances",
+		Run: func(ctx context.Context, tx Tx, st *State) error {
+			for _, role := range []Role{Debit, Credit} {
+				account, ok := st.Accounts[role]
+				if !ok {
+					var err error
+					if account, err = tx.ReadAccount(ctx, st.Params.Accounts[role]); err != nil {
+						return fmt.Errorf("read %s account: %w", role, err)
+					}
+					st.Accounts[role] = account
+				}
+
+				delta := st.Params.Amount
+				if role == Debit {
+					delta = delta.Neg()
+				}
+				if err := tx.UpdateBalance(ctx, account, delta); err != nil {
+					return fmt.Errorf("update %s balance: %w", role, err)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// If runs steps only when cond holds; otherwise the execution carries on
+// (and succeeds) without them.
+func If(cond Condition, steps ...Step) Step {
+	names := make([]string, 0, len(steps))
+	for _, step := range steps {
+		names = append(names, step.Name)
+	}
+
+	return Step{
+		Name: fmt.Sprintf("if %s [%s]", cond.Name, strings.Join(names, ", ")),
+		Run: func(ctx context.Context, tx Tx, st *State) error {
+			if !cond.Fn(st) {
+				return nil
+			}
+			return runSteps(ctx, tx, st, steps)
+		},
+	}
+}
+
+// Expect checks cond at this point of the scenario. A failed check is
+// reported as an assertion failure of the operation, not as an error, and
+// the remaining steps still run.
+func Expect(cond Condition) Step {
+	return Step{
+		Name: fmt.Sprintf("expect %s", cond.Name),
+		Run: func(ctx context.Context, tx Tx, st *State) error {
+			benchmark.Expect(ctx, cond.Name, cond.Fn(st))
+			return nil
+		},
+	}
+}
+
+// Custom wraps an arbitrary function as a step.
+func Custom(name string, fn func(ctx context.Context, tx Tx, st *State) error) Step {
+	return Step{Name: name, Run: fn}
+}
+
+// SufficientFunds holds when the account playing role, which must have been
+// read already, covers the params' amount.
+func SufficientFunds(role Role) Condition {
+	return Condition{
+		Name: fmt.Sprintf("%s has sufficient funds", role),
+		Fn: func(st *State) bool {
+			account, ok := st.Accounts[role]
+			return ok && account.Balance.GreaterThanOrEqual(st.Params.Amount)
+		},
+	}
+}
+
+// TransactionWritten holds once WriteTransaction has assigned an ID.
+func TransactionWritten() Condition {
+	return Condition{
+		Name: "transaction written",
+		Fn: func(st *State) bool {
+			return st.TransactionID != ""
+		},
+	}
+}
+
+// SameCurrency holds when the debit and credit accounts, which must have been
+// read already, are denominated in the same currency.
+func SameCurrency() Condition {
+	return Condition{
+		Name: "accounts share a currency",
+		Fn: func(st *State) bool {
+			debit, ok := st.Accounts[Debit]
+			credit, ok2 := st.Accounts[Credit]
+			return ok && ok2 && debit.Currency == credit.Currency
+		},
+	}
+}
+
+// Execute runs every step of the scenario once in a single backend
+// transaction, rolling back on the first error.
+func (s *Scenario) Execute(ctx context.Context, backend Backend) error {
+	tx, err := backend.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	st := &State{
+		Params:   backend.RandomParams(),
+		Accounts: make(map[Role]Account),
+	}
+
+	if err := runSteps(ctx, tx, st, s.Steps); err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Register compiles the scenario into one benchmark workload per backend,
+// named "<scenario>/<backend>".
+func (s *Scenario) Register(backends ...Backend) []*benchmark.Workload {
+	workloads := make([]*benchmark.Workload, 0, len(backends))
+	for _, backend := range backends {
+		backend := backend
+		w := benchmark.Register(fmt.Sprintf("%s/%s", s.Name, strings.ToLower(backend.Name())),
+			backend.Setup,
+			func(ctx context.Context, worker, iteration int) error {
+				return s.Execute(ctx, backend)
+			},
+			backend.Teardown,
+		)
+		w.Database = backend.Name()
+		workloads = append(workloads, w)
+	}
+	return workloads
+}
+
+func runSteps(ctx context.Context, tx Tx, st *State, steps []Step) error {
+	for _, step := range steps {
+		if err := step.Run(ctx, tx, st); err != nil {
+			return err
+		}
+	}
+	return nil
+}
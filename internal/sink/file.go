@@ -0,0 +1,51 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// FileSink writes <Dir>/<name>-results.json, the layout the chart scripts in
+// benchmarks/results expect.
+type FileSink struct {
+	Dir string
+}
+
+func (s *FileSink) Name() string { return "file" }
+
+func (s *FileSink) Write(ctx context.Context, report Report) error {
+	data, err := json.MarshalIndent(report.Suite, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal results: %w", err)
+	}
+
+	filename := filepath.Join(s.Dir, report.Name+"-results.json")
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("write results: %w", err)
+	}
+
+	slog.Info("Results saved", "file", filename)
+	return nil
+}
+
+// StdoutSink prints the suite as indented JSON.
+type StdoutSink struct {
+	Out io.Writer
+}
+
+func (s *StdoutSink) Name() string { return "stdout" }
+
+func (s *StdoutSink) Write(ctx context.Context, report Report) error {
+	data, err := json.MarshalIndent(report.Suite, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal results: %w", err)
+	}
+
+	_, err = fmt.Fprintf(s.Out, "%s\n", data)
+	return err
+}
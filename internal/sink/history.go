@@ -0,0 +1,122 @@
+package sink
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+// HistorySink appends every result to a benchmark_history table, in a local
+// SQLite database or in PostgreSQL, so runs can be compared over time with
+// SQL. Each row is keyed by the run ID of the suite it came from and keeps
+// the full result as JSON alongside the columns most queries filter on.
+// Rows are only ever inserted, so earlier runs are never overwritten.
+type HistorySink struct {
+	// Driver is "sqlite" or "postgres".
+	Driver string
+	// DSN is the SQLite file path or the PostgreSQL connection string.
+	DSN string
+}
+
+func (s *HistorySink) Name() string { return "history" }
+
+func (s *HistorySink) Write(ctx context.Context, report Report) error {
+	runID, results, err := report.decode()
+	if err != nil {
+		return fmt.Errorf("decode results: %w", err)
+	}
+
+	db, err := sql.Open(s.Driver, s.DSN)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := s.createTable(ctx, db); err != nil {
+		return fmt.Errorf("create history table: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	insert := `
+		INSERT INTO benchmark_history (run_id, recorded_at, suite, test_name, database, result)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	if s.Driver == "postgres" {
+		insert = `
+			INSERT INTO benchmark_history (run_id, recorded_at, suite, test_name, database, result)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`
+	}
+
+	recordedAt := time.Now().UTC()
+	for _, result := range results {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, insert, runID, recordedAt, report.Name,
+			fmt.Sprint(result["test_name"]), fmt.Sprint(result["database"]), string(data))
+		if err != nil {
+			return fmt.Errorf("insert history: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	slog.Info("Recorded results in history", "results", len(results), "run_id", runID, "driver", s.Driver)
+	return nil
+}
+
+// createTable creates benchmark_history if it does not exist, adding the
+// run_id column to a table written before results were keyed by run.
+func (s *HistorySink) createTable(ctx context.Context, db *sql.DB) error {
+	create := `
+		CREATE TABLE IF NOT EXISTS benchmark_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			run_id TEXT NOT NULL DEFAULT '',
+			recorded_at TIMESTAMP NOT NULL,
+			suite TEXT NOT NULL,
+			test_name TEXT NOT NULL,
+			database TEXT NOT NULL,
+			result JSON NOT NULL
+		)
+	`
+	if s.Driver == "postgres" {
+		create = `
+			CREATE TABLE IF NOT EXISTS benchmark_history (
+				id BIGSERIAL PRIMARY KEY,
+				run_id TEXT NOT NULL DEFAULT '',
+				recorded_at TIMESTAMPTZ NOT NULL,
+				suite TEXT NOT NULL,
+				test_name TEXT NOT NULL,
+				database TEXT NOT NULL,
+				result JSONB NOT NULL
+			)
+		`
+	}
+	if _, err := db.ExecContext(ctx, create); err != nil {
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, `SELECT run_id FROM benchmark_history LIMIT 0`); err != nil {
+		if _, err := db.ExecContext(ctx, `ALTER TABLE benchmark_history ADD COLUMN run_id TEXT NOT NULL DEFAULT ''`); err != nil {
+			return err
+		}
+	}
+	_, err := db.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS benchmark_history_run_id ON benchmark_history (run_id)`)
+	return err
+}
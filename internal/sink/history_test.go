@@ -0,0 +1,73 @@
+package sink
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestHistorySinkKeepsEveryRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+
+	// A table from before results were keyed by run.
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, err = db.Exec(`
+		CREATE TABLE benchmark_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			recorded_at TIMESTAMP NOT NULL,
+			suite TEXT NOT NULL,
+			test_name TEXT NOT NULL,
+			database TEXT NOT NULL,
+			result JSON NOT NULL
+		)
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sink := &HistorySink{Driver: "sqlite", DSN: path}
+	for _, runID := range []string{"run-1", "run-2"} {
+		report := Report{Name: "postgres-read", Suite: map[string]any{
+			"run_id": runID,
+			"results": []map[string]any{
+				{"test_name": "Point Reads", "database": "PostgreSQL", "operations_per_sec": 1000},
+				{"test_name": "Range Query", "database": "PostgreSQL", "operations_per_sec": 200},
+			},
+		}}
+		if err := sink.Write(context.Background(), report); err != nil {
+			t.Fatalf("%s: %v", runID, err)
+		}
+	}
+
+	rows, err := db.Query(`SELECT run_id, COUNT(*) FROM benchmark_history GROUP BY run_id ORDER BY run_id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	got := make(map[string]int)
+	for rows.Next() {
+		var runID string
+		var n int
+		if err := rows.Scan(&runID, &n); err != nil {
+			t.Fatal(err)
+		}
+		got[runID] = n
+	}
+	if len(got) != 2 || got["run-1"] != 2 || got["run-2"] != 2 {
+		t.Errorf("rows per run = %v, want 2 for each of run-1 and run-2", got)
+	}
+
+	var throughput float64
+	err = db.QueryRow(`
+		SELECT json_extract(result, '$.operations_per_sec') FROM benchmark_history
+		WHERE run_id = 'run-2' AND test_name = 'Range Query'
+	`).Scan(&throughput)
+	if err != nil || throughput != 200 {
+		t.Errorf("run-2 Range Query ops/sec = %v (%v), want 200", throughput, err)
+	}
+}
@@ -0,0 +1,102 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PrometheusSink pushes every numeric result field as a gauge to a Prometheus
+// Pushgateway, grouped by job and suite. Duration fields (serialized in
+// nanoseconds) are converted to seconds.
+type PrometheusSink struct {
+	URL    string
+	Job    string
+	Client *http.Client
+}
+
+func (s *PrometheusSink) Name() string { return "prometheus" }
+
+func (s *PrometheusSink) Write(ctx context.Context, report Report) error {
+	results, err := report.results()
+	if err != nil {
+		return fmt.Errorf("decode results: %w", err)
+	}
+
+	body := formatMetrics(report.Name, results)
+
+	endpoint := fmt.Sprintf("%s/metrics/job/%s/suite/%s", strings.TrimRight(s.URL, "/"),
+		url.PathEscape(s.Job), url.PathEscape(report.Name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("pushgateway returned %s", resp.Status)
+	}
+
+	slog.Info("Results pushed", "endpoint", endpoint)
+	return nil
+}
+
+// formatMetrics renders results in the Prometheus text exposition format.
+func formatMetrics(suite string, results []map[string]any) []byte {
+	samples := make(map[string][]string)
+
+	for _, result := range results {
+		labels := fmt.Sprintf(`suite="%s",test="%s",database="%s"`,
+			escapeLabel(suite), escapeLabel(fmt.Sprint(result["test_name"])), escapeLabel(fmt.Sprint(result["database"])))
+
+		for key, value := range result {
+			v, ok := value.(float64)
+			if !ok {
+				continue
+			}
+
+			name := "benchmark_" + key
+			if strings.HasSuffix(key, "_duration_ms") {
+				name = "benchmark_" + strings.TrimSuffix(key, "_ms") + "_seconds"
+				v = v / float64(time.Second)
+			}
+			samples[name] = append(samples[name], fmt.Sprintf("%s{%s} %g", name, labels, v))
+		}
+	}
+
+	names := make([]string, 0, len(samples))
+	for name := range samples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&buf, "# TYPE %s gauge\n", name)
+		for _, line := range samples[name] {
+			buf.WriteString(line)
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes()
+}
+
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
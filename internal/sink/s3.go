@@ -0,0 +1,55 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3Sink uploads each suite to s3://<Bucket>/<Prefix>/<name>/<timestamp>.json
+// using the default AWS credential chain, so earlier runs are never
+// overwritten.
+type S3Sink struct {
+	Bucket string
+	Prefix string
+	Client *s3.Client
+}
+
+func (s *S3Sink) Name() string { return "s3" }
+
+func (s *S3Sink) Write(ctx context.Context, report Report) error {
+	if s.Client == nil {
+		cfg, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return fmt.Errorf("load aws config: %w", err)
+		}
+		s.Client = s3.NewFromConfig(cfg)
+	}
+
+	data, err := json.MarshalIndent(report.Suite, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal results: %w", err)
+	}
+
+	key := path.Join(s.Prefix, report.Name, time.Now().UTC().Format("20060102T150405Z")+".json")
+	_, err = s.Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("put object: %w", err)
+	}
+
+	slog.Info("Results uploaded", "url", fmt.Sprintf("s3://%s/%s", s.Bucket, key))
+	return nil
+}
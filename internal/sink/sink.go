@@ -0,0 +1,155 @@
+// Package sink delivers benchmark suites to one or more destinations.
+//
+// Every benchmark binary hands its finished suite to a ResultSink instead of
+// writing files itself, so new destinations can be added here without
+// touching benchmark code. The sinks active for a run are chosen with the
+// BENCH_SINKS environment variable (see FromEnv).
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/connection"
+)
+
+// Report is a finished benchmark suite ready to be published.
+type Report struct {
+	// Name identifies the suite, e.g. "postgres-write" or "dynamodb-scan".
+	Name string
+	// Suite is the suite value as produced by the benchmark; it must marshal
+	// to a JSON object with a "results" array.
+	Suite any
+}
+
+// ResultSink is a destination for benchmark reports.
+type ResultSink interface {
+	Name() string
+	Write(ctx context.Context, report Report) error
+}
+
+// Multi fans a report out to several sinks. Every sink is attempted even if an
+// earlier one fails; the returned error joins all failures.
+type Multi []ResultSink
+
+func (m Multi) Name() string {
+	names := make([]string, 0, len(m))
+	for _, s := range m {
+		names = append(names, s.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+func (m Multi) Write(ctx context.Context, report Report) error {
+	var errs []error
+	for _, s := range m {
+		if err := s.Write(ctx, report); err != nil {
+			errs = append(errs, fmt.Errorf("%s sink: %w", s.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FromEnv builds the sinks listed in BENCH_SINKS (comma separated, default
+// "file"). Each sink reads its own settings from the environment:
+//
+//	file        BENCH_RESULTS_DIR (default benchmarks/results)
+//	stdout      -
+//	s3          BENCH_S3_BUCKET, BENCH_S3_PREFIX
+//	prometheus  BENCH_PUSHGATEWAY_URL, BENCH_PUSHGATEWAY_JOB (default financial-benchmark)
+//	history     BENCH_HISTORY_DB: a SQLite path (default benchmarks/results/history.db),
+//	            a postgres:// URL, or "postgres" for the benchmark database
+//	webhook     BENCH_WEBHOOK_URL
+func FromEnv() (Multi, error) {
+	names := getenv("BENCH_SINKS", "file")
+
+	var sinks Multi
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		s, err := New(name)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+
+	return sinks, nil
+}
+
+// New builds a single sink by name using its environment settings.
+func New(name string) (ResultSink, error) {
+	switch name {
+	case "file":
+		return &FileSink{Dir: getenv("BENCH_RESULTS_DIR", "benchmarks/results")}, nil
+	case "stdout":
+		return &StdoutSink{Out: os.Stdout}, nil
+	case "s3":
+		bucket := os.Getenv("BENCH_S3_BUCKET")
+		if bucket == "" {
+			return nil, errors.New("s3 sink requires BENCH_S3_BUCKET")
+		}
+		return &S3Sink{Bucket: bucket, Prefix: os.Getenv("BENCH_S3_PREFIX")}, nil
+	case "prometheus":
+		url := os.Getenv("BENCH_PUSHGATEWAY_URL")
+		if url == "" {
+			return nil, errors.New("prometheus sink requires BENCH_PUSHGATEWAY_URL")
+		}
+		return &PrometheusSink{URL: url, Job: getenv("BENCH_PUSHGATEWAY_JOB", "financial-benchmark")}, nil
+	case "history":
+		dsn := getenv("BENCH_HISTORY_DB", "benchmarks/results/history.db")
+		switch {
+		case dsn == "postgres":
+			return &HistorySink{Driver: "postgres", DSN: connection.PostgresDSN}, nil
+		case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+			return &HistorySink{Driver: "postgres", DSN: dsn}, nil
+		}
+		return &HistorySink{Driver: "sqlite", DSN: dsn}, nil
+	case "webhook":
+		url := os.Getenv("BENCH_WEBHOOK_URL")
+		if url == "" {
+			return nil, errors.New("webhook sink requires BENCH_WEBHOOK_URL")
+		}
+		return &WebhookSink{URL: url}, nil
+	default:
+		return nil, fmt.Errorf("unknown result sink %q", name)
+	}
+}
+
+// results decodes the suite's results into generic rows, so sinks that need
+// individual fields work with any suite's result type.
+func (r Report) results() ([]map[string]any, error) {
+	_, results, err := r.decode()
+	return results, err
+}
+
+// decode is results with the suite's run ID, if it has one.
+func (r Report) decode() (string, []map[string]any, error) {
+	data, err := json.Marshal(r.Suite)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var suite struct {
+		RunID   string           `json:"run_id"`
+		Results []map[string]any `json:"results"`
+	}
+	if err := json.Unmarshal(data, &suite); err != nil {
+		return "", nil, err
+	}
+	return suite.RunID, suite.Results, nil
+}
+
+func getenv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,209 @@
+// Package stats holds the statistics every report in the repo shares. The
+// percentile definition: latencies go through HdrHistogram (see
+// benchmark.Recorder), which picks the value at a percentile by nearest
+// rank, and Percentile applies the same rule to exact values such as result
+// sizes and trace record sizes, so a P99 means the same thing whichever
+// file computed it. And, for repeated runs, the spread of a metric across
+// them (Spread) and whether two sets of runs differ (MannWhitney).
+package stats
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// Rank is the 1-based nearest rank of the p-th percentile (0 to 100) of n
+// values: p percent of n rounded half up, as HdrHistogram's
+// ValueAtQuantile counts, and at least 1. p outside 0 to 100 is clamped.
+// It returns 0 only when n is 0.
+func Rank(n int, p float64) int {
+	if n <= 0 {
+		return 0
+	}
+	if math.IsNaN(p) {
+		p = 0
+	}
+	p = min(max(p, 0), 100)
+	rank := int(p/100*float64(n) + 0.5)
+	return min(max(rank, 1), n)
+}
+
+// Percentile is the p-th percentile (0 to 100) of sorted, which must be in
+// ascending order: the value at Rank(len(sorted), p). It is always one of
+// the values, never an interpolation, and the zero value when sorted is
+// empty.
+func Percentile[T cmp.Ordered](sorted []T, p float64) T {
+	var zero T
+	rank := Rank(len(sorted), p)
+	if rank == 0 {
+		return zero
+	}
+	return sorted[rank-1]
+}
+
+// Spread is the mean of repeated measurements of one metric, how much they
+// vary, and the 95% confidence interval of the mean.
+type Spread struct {
+	N    int     `json:"n"`
+	Mean float64 `json:"mean"`
+	// StdDev is the sample standard deviation, 0 for a single value.
+	StdDev float64 `json:"stddev"`
+	// CILow and CIHigh bound the 95% confidence interval of the mean, from
+	// Student's t distribution; with one value they are the value itself.
+	CILow  float64 `json:"ci_low"`
+	CIHigh float64 `json:"ci_high"`
+}
+
+// NewSpread summarizes values.
+func NewSpread(values []float64) Spread {
+	s := Spread{N: len(values)}
+	if s.N == 0 {
+		return s
+	}
+	for _, v := range values {
+		s.Mean += v
+	}
+	s.Mean /= float64(s.N)
+	s.CILow, s.CIHigh = s.Mean, s.Mean
+	if s.N == 1 {
+		return s
+	}
+
+	var squares float64
+	for _, v := range values {
+		squares += (v - s.Mean) * (v - s.Mean)
+	}
+	s.StdDev = math.Sqrt(squares / float64(s.N-1))
+	margin := tCritical(s.N-1) * s.StdDev / math.Sqrt(float64(s.N))
+	s.CILow, s.CIHigh = s.Mean-margin, s.Mean+margin
+	return s
+}
+
+// tTable is the two-sided 95% critical value of Student's t for 1 to 30
+// degrees of freedom.
+var tTable = [...]float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// tCritical is the two-sided 95% critical value of Student's t for df
+// degrees of freedom; past the table, the normal distribution's is close
+// enough.
+func tCritical(df int) float64 {
+	if df >= 1 && df <= len(tTable) {
+		return tTable[df-1]
+	}
+	return 1.96
+}
+
+// exactLimit is the largest sample, on either side, whose Mann–Whitney
+// p-value is computed exactly.
+const exactLimit = 50
+
+// MannWhitney is the two-sided Mann–Whitney U test of whether a and b were
+// drawn from the same distribution, without assuming it is normal. It
+// returns U for a, the number of (a, b) pairs in which a's value is the
+// larger, ties counting half, and the p-value: exact when neither sample
+// has more than 50 values and there are no ties, otherwise from the normal
+// approximation with a tie correction. With fewer than four values across
+// both samples no difference can be significant, and p is 1.
+func MannWhitney(a, b []float64) (u, p float64) {
+	n1, n2 := len(a), len(b)
+	if n1 == 0 || n2 == 0 {
+		return 0, 1
+	}
+	ties := false
+	for _, x := range a {
+		for _, y := range b {
+			switch {
+			case x > y:
+				u++
+			case x == y:
+				u += 0.5
+				ties = true
+			}
+		}
+	}
+	if n1+n2 < 4 {
+		return u, 1
+	}
+	if !ties && n1 <= exactLimit && n2 <= exactLimit {
+		return u, exactP(n1, n2, u)
+	}
+	return u, normalP(a, b, u)
+}
+
+// exactP is the two-sided p-value of U = u for samples of n1 and n2 values
+// without ties, from the exact distribution of U.
+func exactP(n1, n2 int, u float64) float64 {
+	// counts[i][k] is the number of orderings of i values from the first
+	// sample and j from the second with U = k, built up one j at a time.
+	counts := make([][]float64, n1+1)
+	for i := range counts {
+		counts[i] = make([]float64, n1*n2+1)
+		counts[i][0] = 1
+	}
+	for j := 1; j <= n2; j++ {
+		next := make([][]float64, n1+1)
+		next[0] = make([]float64, n1*n2+1)
+		next[0][0] = 1
+		for i := 1; i <= n1; i++ {
+			next[i] = make([]float64, n1*n2+1)
+			for k := range next[i] {
+				// The largest value is either from the second sample,
+				// adding nothing to U, or from the first, beating all j.
+				next[i][k] = counts[i][k]
+				if k >= j {
+					next[i][k] += next[i-1][k-j]
+				}
+			}
+		}
+		counts = next
+	}
+
+	dist := counts[n1]
+	var total, below, above float64
+	for k, c := range dist {
+		total += c
+		if float64(k) <= u {
+			below += c
+		}
+		if float64(k) >= u {
+			above += c
+		}
+	}
+	return math.Min(1, 2*math.Min(below, above)/total)
+}
+
+// normalP is the two-sided p-value of U = u from the normal approximation,
+// with the variance corrected for ties and a continuity correction.
+func normalP(a, b []float64, u float64) float64 {
+	n1, n2 := float64(len(a)), float64(len(b))
+	n := n1 + n2
+
+	all := append(append(make([]float64, 0, len(a)+len(b)), a...), b...)
+	slices.Sort(all)
+	var tieTerm float64
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j] == all[i] {
+			j++
+		}
+		t := float64(j - i)
+		tieTerm += t*t*t - t
+		i = j
+	}
+
+	mean := n1 * n2 / 2
+	variance := n1 * n2 / 12 * ((n + 1) - tieTerm/(n*(n-1)))
+	if variance <= 0 {
+		return 1
+	}
+	z := (math.Abs(u-mean) - 0.5) / math.Sqrt(variance)
+	if z < 0 {
+		return 1
+	}
+	return math.Min(1, math.Erfc(z/math.Sqrt2))
+}
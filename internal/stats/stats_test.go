@@ -0,0 +1,183 @@
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+	"testing/quick"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// percentiles are the ones the reports print, and the maximum.
+var percentiles = []float64{50, 90, 95, 99, 99.9, 100}
+
+func sortedSample(values []uint16) []int {
+	sorted := make([]int, len(values))
+	for i, v := range values {
+		sorted[i] = int(v)
+	}
+	slices.Sort(sorted)
+	return sorted
+}
+
+func TestRankInBounds(t *testing.T) {
+	inBounds := func(n uint16, p float64) bool {
+		rank := Rank(int(n), p)
+		if n == 0 {
+			return rank == 0
+		}
+		return rank >= 1 && rank <= int(n)
+	}
+	if err := quick.Check(inBounds, nil); err != nil {
+		t.Error(err)
+	}
+	for _, p := range []float64{-1, 101, 1e300} {
+		if rank := Rank(10, p); rank < 1 || rank > 10 {
+			t.Errorf("Rank(10, %g) = %d, out of bounds", p, rank)
+		}
+	}
+}
+
+func TestPercentileProperties(t *testing.T) {
+	properties := func(values []uint16, p1, p2 float64) bool {
+		sorted := sortedSample(values)
+		if len(sorted) == 0 {
+			return Percentile(sorted, p1) == 0
+		}
+		p1, p2 = min(p1, p2), max(p1, p2)
+		lo, hi := Percentile(sorted, p1), Percentile(sorted, p2)
+		_, found := slices.BinarySearch(sorted, lo)
+		return found && lo <= hi &&
+			sorted[0] <= lo && hi <= sorted[len(sorted)-1] &&
+			Percentile(sorted, 0) == sorted[0] && Percentile(sorted, 100) == sorted[len(sorted)-1]
+	}
+	if err := quick.Check(properties, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPercentileKnownDistributions(t *testing.T) {
+	oneToN := func(n int) []int {
+		values := make([]int, n)
+		for i := range values {
+			values[i] = i + 1
+		}
+		return values
+	}
+	tests := []struct {
+		values []int
+		p      float64
+		want   int
+	}{
+		{oneToN(100), 50, 50},
+		{oneToN(100), 95, 95},
+		{oneToN(100), 99, 99},
+		{oneToN(1000), 99.9, 999},
+		{oneToN(10), 50, 5},
+		{oneToN(10), 95, 10},
+		{oneToN(10), 99, 10},
+		{oneToN(3), 50, 2},
+		{[]int{7}, 99, 7},
+		{[]int{1, 1, 1, 9}, 50, 1},
+	}
+	for _, tt := range tests {
+		if got := Percentile(tt.values, tt.p); got != tt.want {
+			t.Errorf("P%g of %d values = %d, want %d", tt.p, len(tt.values), got, tt.want)
+		}
+	}
+	if got := Percentile([]float64{}, 50); got != 0 {
+		t.Errorf("P50 of nothing = %v, want 0", got)
+	}
+}
+
+// Below 2048 a three-digit HdrHistogram stores values exactly, so it must
+// agree with Percentile value for value.
+func TestPercentileMatchesHistogram(t *testing.T) {
+	agrees := func(seed int64, n uint16) bool {
+		rng := rand.New(rand.NewSource(seed))
+		values := make([]int, int(n)%5000+1)
+		h := hdrhistogram.New(1, 2047, 3)
+		for i := range values {
+			values[i] = 1 + rng.Intn(2047)
+			h.RecordValue(int64(values[i]))
+		}
+		slices.Sort(values)
+		for _, p := range percentiles {
+			if got, want := Percentile(values, p), int(h.ValueAtQuantile(p)); got != want {
+				t.Logf("P%g of %d values: Percentile %d, HdrHistogram %d", p, len(values), got, want)
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(agrees, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNewSpread(t *testing.T) {
+	s := NewSpread([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+	// Sample standard deviation sqrt(32/7); t(7) = 2.365.
+	wantSD := math.Sqrt(32.0 / 7)
+	wantMargin := 2.365 * wantSD / math.Sqrt(8)
+	if s.N != 8 || s.Mean != 5 || !near(s.StdDev, wantSD) || !near(s.CILow, 5-wantMargin) || !near(s.CIHigh, 5+wantMargin) {
+		t.Errorf("NewSpread = %+v, want mean 5, stddev %.4f, CI 5 ± %.4f", s, wantSD, wantMargin)
+	}
+	if s := NewSpread([]float64{3}); s.StdDev != 0 || s.CILow != 3 || s.CIHigh != 3 {
+		t.Errorf("NewSpread of one value = %+v", s)
+	}
+	if s := NewSpread(nil); s != (Spread{}) {
+		t.Errorf("NewSpread of nothing = %+v", s)
+	}
+}
+
+func TestMannWhitney(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  []float64
+		wantU float64
+		wantP float64
+	}{
+		// Exact: 2 of the C(6,3) = 20 orderings are this extreme.
+		{"separated 3 vs 3", []float64{1, 2, 3}, []float64{4, 5, 6}, 0, 0.1},
+		// 2 of C(10,5) = 252.
+		{"separated 5 vs 5", []float64{6, 7, 8, 9, 10}, []float64{1, 2, 3, 4, 5}, 25, 2.0 / 252},
+		{"interleaved", []float64{1, 4, 5, 8}, []float64{2, 3, 6, 7}, 8, 1},
+		// Ties: normal approximation, z = (|3-12.5| - 0.5) / sqrt(25/12 * (11 - 24/90)).
+		{"ties", []float64{1, 2, 2, 3, 4}, []float64{2, 5, 6, 7, 8}, 3, math.Erfc(9 / math.Sqrt(25.0/12*(11-24.0/90)) / math.Sqrt2)},
+		{"too few", []float64{1}, []float64{2, 3}, 0, 1},
+		{"empty", nil, []float64{1, 2, 3}, 0, 1},
+	}
+	for _, tt := range tests {
+		u, p := MannWhitney(tt.a, tt.b)
+		if u != tt.wantU || !near(p, tt.wantP) {
+			t.Errorf("%s: U, p = %v, %.5f; want %v, %.5f", tt.name, u, p, tt.wantU, tt.wantP)
+		}
+	}
+}
+
+func TestMannWhitneyProperties(t *testing.T) {
+	properties := func(a, b []uint8) bool {
+		x, y := make([]float64, len(a)%12), make([]float64, len(b)%12)
+		for i := range x {
+			x[i] = float64(a[i])
+		}
+		for i := range y {
+			y[i] = float64(b[i])
+		}
+		u, p := MannWhitney(x, y)
+		v, q := MannWhitney(y, x)
+		// U for one side and the other add up to every pair, and the
+		// two-sided p-value doesn't care which side is which.
+		return u >= 0 && u+v == float64(len(x)*len(y)) && p > 0 && p <= 1 && near(p, q)
+	}
+	if err := quick.Check(properties, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func near(got, want float64) bool {
+	return math.Abs(got-want) <= 1e-9*math.Max(1, math.Abs(want))
+}
@@ -0,0 +1,195 @@
+// Package trace characterizes a recorded workload so it can be matched to
+// the benchmark suite that exercises the same shape of traffic.
+//
+// A trace is JSON Lines, one operation per line:
+//
+//	{"ts":"2024-03-01T12:00:00.125Z","op":"write","key":"ACCOUNT#42","amount":"25.00","bytes":310}
+//
+// op is "read" or "write"; key is whatever identifies the hot entity
+// (account, merchant, partition key); amount is what a write posts, for
+// replaying the trace, and bytes the item or row size; both may be
+// omitted. Export one from application logs, a DynamoDB Streams or
+// pg_stat_statements sampler, or a load balancer access log.
+package trace
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/praneethys/postgres-vs-dynamodb-for-financial-transactions/internal/stats"
+	"github.com/shopspring/decimal"
+)
+
+// Op is one recorded operation.
+type Op struct {
+	Time time.Time `json:"ts"`
+	Kind string    `json:"op"`
+	Key  string    `json:"key"`
+	// Amount is what a write posts to the key's balance.
+	Amount decimal.Decimal `json:"amount"`
+	Bytes  int             `json:"bytes,omitempty"`
+}
+
+// Read parses a JSON Lines trace.
+func Read(r io.Reader) ([]Op, error) {
+	var ops []Op
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var op Op
+		if err := json.Unmarshal(scanner.Bytes(), &op); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if op.Kind != "read" && op.Kind != "write" {
+			return nil, fmt.Errorf("line %d: op must be read or write, got %q", line, op.Kind)
+		}
+		ops = append(ops, op)
+	}
+	return ops, scanner.Err()
+}
+
+// Profile summarizes a trace.
+type Profile struct {
+	Operations int
+	Duration   time.Duration
+	// ReadRatio is the fraction of operations that are reads.
+	ReadRatio float64
+	// DistinctKeys is the number of keys touched. TopKeyShare is the
+	// fraction of operations that hit the busiest 1% of them (at least one
+	// key), and HottestKeyShare the fraction on the single busiest key.
+	DistinctKeys    int
+	TopKeyShare     float64
+	HottestKeyShare float64
+	HottestKey      string
+	// MeanRate and PeakRate are operations per second, over one-second
+	// buckets. Burstiness is their ratio: 1 for perfectly steady traffic.
+	MeanRate   float64
+	PeakRate   float64
+	Burstiness float64
+	// Item sizes, for operations that recorded one.
+	AvgBytes int
+	P99Bytes int
+	MaxBytes int
+}
+
+// Analyze computes the profile of a trace.
+func Analyze(ops []Op) Profile {
+	p := Profile{Operations: len(ops)}
+	if len(ops) == 0 {
+		return p
+	}
+
+	reads := 0
+	perKey := make(map[string]int)
+	perSecond := make(map[int64]int)
+	sizes := make([]int, 0, len(ops))
+	first, last := ops[0].Time, ops[0].Time
+
+	for _, op := range ops {
+		if op.Kind == "read" {
+			reads++
+		}
+		perKey[op.Key]++
+		perSecond[op.Time.Unix()]++
+		if op.Bytes > 0 {
+			sizes = append(sizes, op.Bytes)
+		}
+		if op.Time.Before(first) {
+			first = op.Time
+		}
+		if op.Time.After(last) {
+			last = op.Time
+		}
+	}
+
+	p.Duration = last.Sub(first)
+	p.ReadRatio = float64(reads) / float64(len(ops))
+
+	counts := make([]int, 0, len(perKey))
+	for key, n := range perKey {
+		counts = append(counts, n)
+		if n > perKey[p.HottestKey] || p.HottestKey == "" {
+			p.HottestKey = key
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(counts)))
+	top := int(math.Max(1, math.Ceil(float64(len(counts))*0.01)))
+	hits := 0
+	for _, n := range counts[:top] {
+		hits += n
+	}
+	p.DistinctKeys = len(counts)
+	p.TopKeyShare = float64(hits) / float64(len(ops))
+	p.HottestKeyShare = float64(counts[0]) / float64(len(ops))
+
+	seconds := math.Max(1, math.Ceil(p.Duration.Seconds()))
+	p.MeanRate = float64(len(ops)) / seconds
+	for _, n := range perSecond {
+		p.PeakRate = math.Max(p.PeakRate, float64(n))
+	}
+	p.Burstiness = p.PeakRate / p.MeanRate
+
+	if len(sizes) > 0 {
+		sort.Ints(sizes)
+		total := 0
+		for _, n := range sizes {
+			total += n
+		}
+		p.AvgBytes = total / len(sizes)
+		p.P99Bytes = stats.Percentile(sizes, 99)
+		p.MaxBytes = sizes[len(sizes)-1]
+	}
+	return p
+}
+
+// Preset is the traffic shape a benchmark suite exercises.
+type Preset struct {
+	// Suite is the benchctl suite name.
+	Suite       string
+	Description string
+	ReadRatio   float64
+	TopKeyShare float64
+	Burstiness  float64
+}
+
+// Presets are the built-in suites, described by the traffic they generate.
+var Presets = []Preset{
+	{"reads", "point lookups, range and history queries", 1.0, 0.05, 1.5},
+	{"writes", "single, batch and double-entry transaction writes", 0.0, 0.05, 1.5},
+	{"ingest", "sustained high-rate inserts spread over many keys", 0.05, 0.01, 1.2},
+	{"skew", "writes funneled into a few hot accounts", 0.0, 0.9, 1.5},
+	{"collections", "reads of a few very large per-account histories", 1.0, 0.9, 1.5},
+	{"isolation", "a few noisy merchants beside steady quiet traffic", 0.3, 0.6, 2},
+	{"close", "end-of-day batch reads on top of live OLTP", 0.8, 0.05, 6},
+}
+
+// Match is a preset and how far a profile is from it.
+type Match struct {
+	Preset   Preset
+	Distance float64
+}
+
+// Suggest ranks the presets by distance from the profile, closest first.
+// Burstiness is compared on a log scale, so doubling the peak counts the
+// same at any level.
+func Suggest(p Profile) []Match {
+	matches := make([]Match, 0, len(Presets))
+	for _, preset := range Presets {
+		burst := math.Log2(math.Max(1, p.Burstiness)) - math.Log2(preset.Burstiness)
+		distance := math.Sqrt(
+			math.Pow(p.ReadRatio-preset.ReadRatio, 2) +
+				math.Pow(p.TopKeyShare-preset.TopKeyShare, 2) +
+				math.Pow(burst/4, 2))
+		matches = append(matches, Match{preset, distance})
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Distance < matches[j].Distance })
+	return matches
+}